@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"vigilant/pkg/api"
+	"vigilant/pkg/history"
+	"vigilant/pkg/llmcache"
+	"vigilant/pkg/summarizer"
+)
+
+// analysisResult answers an on-demand analysis: the service's risk items after the cycle
+type analysisResult struct {
+	items []api.APIRiskItem
+	err   error
+}
+
+// analysisRequests queues on-demand analyses for the monitoring loop. A request wakes the loop;
+// the next cycle fetches the service's data and analyzes it bypassing change detection and the
+// LLM cache. Requests for a service arriving before that cycle share its analysis.
+type analysisRequests struct {
+	mu      sync.Mutex
+	pending map[string][]chan analysisResult
+	wake    chan struct{}
+}
+
+func newAnalysisRequests() *analysisRequests {
+	return &analysisRequests{pending: make(map[string][]chan analysisResult), wake: make(chan struct{}, 1)}
+}
+
+// Analyze requests an analysis of service and waits for the cycle that runs it
+func (a *analysisRequests) Analyze(ctx context.Context, service string) ([]api.APIRiskItem, error) {
+	done := make(chan analysisResult, 1)
+	a.mu.Lock()
+	a.pending[service] = append(a.pending[service], done)
+	a.mu.Unlock()
+
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+
+	select {
+	case res := <-done:
+		return res.items, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Wake signals pending requests
+func (a *analysisRequests) Wake() <-chan struct{} {
+	return a.wake
+}
+
+// take returns the pending requests by service for the cycle about to run
+func (a *analysisRequests) take() map[string][]chan analysisResult {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.pending) == 0 {
+		return nil
+	}
+	taken := a.pending
+	a.pending = make(map[string][]chan analysisResult)
+	return taken
+}
+
+// analyzeRequested re-analyzes the requested services among the correlations, bypassing the
+// LLM cache, and applies the analyses to uiData. It returns the error of each requested service
+// that got no fresh analysis.
+func analyzeRequested(ctx context.Context, llmCache *llmcache.LLMCache, incidentHistory *history.Store, requested map[string][]chan analysisResult, correlations []summarizer.AlertCorrelation, uiData []api.APIRiskItem) map[string]error {
+	failed := make(map[string]error)
+	var stale []summarizer.AlertCorrelation
+	for _, c := range correlations {
+		if _, ok := requested[c.Alert.Service]; ok {
+			stale = append(stale, c)
+		}
+	}
+	for service := range requested {
+		if !slices.ContainsFunc(stale, func(c summarizer.AlertCorrelation) bool { return c.Alert.Service == service }) {
+			failed[service] = fmt.Errorf("%w for service %s: no alert with a profile is firing", api.ErrNoActiveRisk, service)
+		}
+	}
+	if len(stale) == 0 {
+		return failed
+	}
+
+	fmt.Printf("[ANALYZE] Analyzing %d alerts on demand\n", len(stale))
+	summaries, err := llmCache.Refresh(ctx, stale)
+	if err != nil {
+		fmt.Println("[ANALYZE] On-demand analysis failed:", err)
+	}
+	if incidentHistory != nil && summaries != nil {
+		recordIncidents(ctx, incidentHistory, stale, summaries)
+	}
+	for _, c := range stale {
+		s, ok := summaries[c.Alert.Service]
+		if !ok || s.Fallback {
+			if err == nil {
+				err = fmt.Errorf("the LLM returned no analysis")
+			}
+			failed[c.Alert.Service] = fmt.Errorf("analysis of %s failed: %w", c.Alert.Service, err)
+			continue
+		}
+		lastSuccessfulLLMData[c.Alert.Service] = s
+	}
+	for i := range uiData {
+		if _, bad := failed[uiData[i].Service]; bad {
+			continue
+		}
+		s, ok := summaries[uiData[i].Service]
+		if !ok {
+			continue
+		}
+		uiData[i].Summary = s.Summary
+		uiData[i].Risk = s.Risk
+		uiData[i].Confidence = s.Confidence
+		uiData[i].RootCause = s.RootCause
+		uiData[i].ImmediateActions = s.ImmediateActions
+		uiData[i].Investigation = s.Investigation
+		uiData[i].Prevention = s.Prevention
+	}
+	return failed
+}
+
+// answerRequested sends every waiting request its service's items as published, or its error
+func answerRequested(requested map[string][]chan analysisResult, failed map[string]error, uiData []api.APIRiskItem) {
+	for service, waiters := range requested {
+		res := analysisResult{err: failed[service]}
+		if res.err == nil {
+			for _, item := range uiData {
+				if item.Service == service {
+					res.items = append(res.items, item)
+				}
+			}
+		}
+		for _, done := range waiters {
+			done <- res
+		}
+	}
+}
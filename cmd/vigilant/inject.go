@@ -0,0 +1,52 @@
+package main
+
+import (
+	"slices"
+	"time"
+
+	"vigilant/pkg/config"
+	"vigilant/pkg/inject"
+	"vigilant/pkg/logs"
+	"vigilant/pkg/prometheus"
+)
+
+// injectSymptoms adds the symptoms injected for service, replacing scanned ones with the same pattern
+func injectSymptoms(symptoms []logs.SymptomMatch, store *inject.Store, service string) []logs.SymptomMatch {
+	inj, ok := store.Get(service)
+	if !ok {
+		return symptoms
+	}
+	for _, sym := range inj.Symptoms {
+		symptoms = slices.DeleteFunc(symptoms, func(s logs.SymptomMatch) bool {
+			return s.Pattern == sym.Pattern && (s.Service == service || s.Service == "unknown")
+		})
+		symptoms = append(symptoms, logs.SymptomMatch{
+			Service:  service,
+			Pattern:  sym.Pattern,
+			Severity: sym.Severity,
+			Count:    sym.Count,
+			LastSeen: time.Now(),
+			Samples:  []string{"[injected] " + sym.Pattern},
+		})
+	}
+	return symptoms
+}
+
+// injectMetrics replaces the evaluations of the checks injected for service, compared with the
+// profile's thresholds like queried values
+func injectMetrics(evaluations []prometheus.MetricEvaluation, store *inject.Store, service string, profile config.ServiceProfile) []prometheus.MetricEvaluation {
+	inj, ok := store.Get(service)
+	if !ok {
+		return evaluations
+	}
+	for _, m := range inj.Metrics {
+		for _, check := range profile.GetEffectiveMetrics() {
+			if check.Name != m.Name {
+				continue
+			}
+			evaluations = slices.DeleteFunc(evaluations, func(e prometheus.MetricEvaluation) bool { return e.Check.Name == check.Name })
+			evaluations = append(evaluations, prometheus.EvaluateValue(service, check, m.Value))
+		}
+	}
+	return evaluations
+}
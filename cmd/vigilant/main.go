@@ -6,20 +6,39 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
 
+	"vigilant/dashboard"
 	"vigilant/pkg/api"
+	"vigilant/pkg/audit"
+	"vigilant/pkg/cloudwatch"
 	"vigilant/pkg/config"
+	"vigilant/pkg/datadog"
+	"vigilant/pkg/digest"
+	"vigilant/pkg/feedback"
 	"vigilant/pkg/hashutil"
+	"vigilant/pkg/history"
+	"vigilant/pkg/inject"
+	"vigilant/pkg/jira"
+	"vigilant/pkg/influxdb"
 	"vigilant/pkg/llmcache"
+	"vigilant/pkg/metrichistory"
 	"vigilant/pkg/logs"
+	"vigilant/pkg/notify"
 	"vigilant/pkg/prometheus"
 	"vigilant/pkg/risk"
+	"vigilant/pkg/riskhistory"
+	"vigilant/pkg/selfmetrics"
 	"vigilant/pkg/summarizer"
+	"vigilant/pkg/topology"
+	"vigilant/pkg/tracing"
 	"vigilant/pkg/utils"
 )
 
@@ -41,6 +60,9 @@ type StateSnapshot struct {
 // LastLLMData stores the most recent successful LLM analysis
 var lastSuccessfulLLMData = make(map[string]summarizer.RootCauseSummary)
 
+// lastShown keeps each service's last published item so it can stay listed while recovering
+var lastShown = make(map[string]api.APIRiskItem)
+
 func (s *StateSnapshot) HasChanged(other StateSnapshot) bool {
 	return s.AlertCount != other.AlertCount ||
 		s.SymptomCount != other.SymptomCount ||
@@ -57,6 +79,9 @@ func (s *StateSnapshot) ShouldForceUpdate(maxAge time.Duration) bool {
 func main() {
 	// Parse command line flags
 	enableLLM := flag.Bool("llm", true, "Enable LLM processing for root cause analysis")
+	listenFlag := flag.String("listen", "", "API listen address, e.g. :8090 or 127.0.0.1:8090 (overrides LISTEN_ADDR)")
+	dashboardListenFlag := flag.String("dashboard-listen", "", "Separate dashboard listen address (overrides DASHBOARD_LISTEN_ADDR)")
+	grpcListenFlag := flag.String("grpc-listen", "", "gRPC API listen address (overrides GRPC_LISTEN_ADDR)")
 	flag.Parse()
 	
 	// Check environment variable override
@@ -78,6 +103,70 @@ func main() {
 		promURL = "http://prometheus.local:8080"
 		fmt.Println("PROM_URL not set in env, using default:", promURL)
 	}
+	// Multi-tenant gateways (Cortex, Mimir, Thanos, VictoriaMetrics) need a path prefix and a tenant header
+	promEndpoint := prometheus.Endpoint{
+		URL:         promURL,
+		PathPrefix:  os.Getenv("PROM_PATH_PREFIX"),
+		Headers:     parseKeyValues(os.Getenv("PROM_HEADERS")),
+		QueryParams: parseKeyValues(os.Getenv("PROM_QUERY_PARAMS")),
+	}
+	if tenant := os.Getenv("PROM_TENANT_ID"); tenant != "" {
+		if promEndpoint.Headers == nil {
+			promEndpoint.Headers = make(map[string]string)
+		}
+		promEndpoint.Headers["X-Scope-OrgID"] = tenant
+	}
+	// A hanging or failing Prometheus is timed out, retried a few times and then skipped for a
+	// cooldown instead of stalling every cycle
+	promHTTP := prometheus.DefaultHTTPConfig
+	if v, err := strconv.Atoi(os.Getenv("PROM_TIMEOUT_SECONDS")); err == nil && v > 0 {
+		promHTTP.Timeout = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(os.Getenv("PROM_RETRIES")); err == nil && v >= 0 {
+		promHTTP.Retries = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("PROM_BREAKER_THRESHOLD")); err == nil && v > 0 {
+		promHTTP.FailureThreshold = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("PROM_BREAKER_COOLDOWN_SECONDS")); err == nil && v > 0 {
+		promHTTP.Cooldown = time.Duration(v) * time.Second
+	}
+	prometheus.ConfigureHTTP(promHTTP)
+	// Profiles rendering the same query share one request per cycle; PROM_QUERY_CACHE_TTL_SECONDS
+	// also reuses results across cycles
+	if v, err := strconv.Atoi(os.Getenv("PROM_QUERY_CACHE_TTL_SECONDS")); err == nil && v > 0 {
+		prometheus.ConfigureQueryCache(time.Duration(v) * time.Second)
+	}
+
+	// Metric checks can query Datadog, CloudWatch or InfluxDB instead of Prometheus:
+	// METRICS_BACKEND for every service, data_sources.metrics_backend per profile or backend per
+	// check. Alerts still come from Prometheus.
+	metricBackends := metricSources{prometheus: promEndpoint, defaultBackend: os.Getenv("METRICS_BACKEND")}
+	if metricBackends.defaultBackend == "" {
+		metricBackends.defaultBackend = "prometheus"
+	}
+	if apiKey, appKey := os.Getenv("DD_API_KEY"), os.Getenv("DD_APP_KEY"); apiKey != "" || appKey != "" {
+		if client, err := datadog.NewClient(os.Getenv("DD_SITE"), apiKey, appKey); err != nil {
+			fmt.Printf("Datadog metrics disabled: %v\n", err)
+		} else {
+			metricBackends.datadog = client
+			fmt.Printf("Datadog metrics enabled (%s)\n", client.Name())
+		}
+	}
+	if influxURL := os.Getenv("INFLUXDB_URL"); influxURL != "" {
+		if client, err := influxdb.NewClient(influxURL, os.Getenv("INFLUXDB_TOKEN"), os.Getenv("INFLUXDB_ORG")); err != nil {
+			fmt.Printf("InfluxDB metrics disabled: %v\n", err)
+		} else {
+			metricBackends.influxdb = client
+			fmt.Printf("InfluxDB metrics enabled (%s)\n", client.Name())
+		}
+	}
+	if metricBackends.defaultBackend == "datadog" && metricBackends.datadog == nil {
+		fmt.Println("METRICS_BACKEND=datadog needs DD_API_KEY and DD_APP_KEY, using Prometheus")
+	}
+	if metricBackends.defaultBackend == "influxdb" && metricBackends.influxdb == nil {
+		fmt.Println("METRICS_BACKEND=influxdb needs INFLUXDB_URL and INFLUXDB_TOKEN, using Prometheus")
+	}
 
 	// Initialize Elasticsearch client
 	esURLs := []string{os.Getenv("ELASTICSEARCH_URL")}
@@ -86,13 +175,21 @@ func main() {
 		fmt.Println("ELASTICSEARCH_URL not set in env, using default:", esURLs[0])
 	}
 
-	esClient, err := logs.NewElasticsearchClient(esURLs)
+	// LOG_BACKEND=opensearch talks to OpenSearch clusters, which the Elasticsearch client rejects
+	logBackend := os.Getenv("LOG_BACKEND")
+	var esClient *logs.ElasticsearchClient
+	var err error
+	if logBackend == "opensearch" {
+		esClient, err = logs.NewOpenSearchClient(esURLs, os.Getenv("OPENSEARCH_USERNAME"), os.Getenv("OPENSEARCH_PASSWORD"))
+	} else {
+		esClient, err = logs.NewElasticsearchClient(esURLs)
+	}
 	if err != nil {
-		fmt.Printf("Failed to initialize Elasticsearch client: %v\n", err)
+		fmt.Printf("Failed to initialize %s client: %v\n", backendName(logBackend), err)
 		fmt.Println("Falling back to file-based log scanning...")
 		esClient = nil
 	} else {
-		fmt.Println("Successfully connected to Elasticsearch")
+		fmt.Printf("Successfully connected to %s\n", backendName(logBackend))
 	}
 
 	// Default ES configuration (can be overridden per service)
@@ -106,8 +203,50 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	// API keys or JWTs guard the API and WebSocket; a broken setting must not leave them open
+	auth, err := api.AuthFromEnv()
+	if err != nil {
+		fmt.Println("Failed to configure API authentication:", err)
+		return
+	}
+	sso, err := api.OIDCFromEnv()
+	if err != nil {
+		fmt.Println("Failed to configure SSO:", err)
+		return
+	}
+	if auth == nil && sso == nil {
+		fmt.Println("Warning: API authentication disabled, set API_KEYS, JWT_SECRET/JWT_PUBLIC_KEY_FILE or OIDC_ISSUER")
+	}
+	if sso != nil {
+		fmt.Printf("SSO enabled (%s)\n", os.Getenv("OIDC_ISSUER"))
+	}
+	api.SetAuth(auth)
+	api.SetOIDC(sso)
+	api.SetPublicHealthz(os.Getenv("API_PUBLIC_HEALTHZ") == "true")
+
+	// Roles and team scopes of users and API keys; the teams of services are known once the
+	// profiles are loaded, until then scoped callers see no service
+	access, err := config.LoadAccessConfig("config/access.yml")
+	if err != nil {
+		fmt.Println("Failed to load access config:", err)
+		return
+	}
+	if access.Enabled() && auth == nil && sso == nil && len(access.APIKeys) == 0 {
+		fmt.Println("Warning: config/access.yml has no effect without API authentication")
+	}
+	api.SetAccess(access, nil)
+
+	// The dashboard embedded at build time, unless DASHBOARD_DIR points to another build
+	dashboardFiles, dashboardSource := dashboard.FileSystem(os.Getenv("DASHBOARD_DIR"))
+	fmt.Println("Serving the dashboard from", dashboardSource)
+
 	// Start REST API server (non-blocking)
-	server := api.StartServer()
+	server := api.StartServer(api.ServerConfig{
+		Addr:          listenAddr(*listenFlag, os.Getenv("LISTEN_ADDR"), ":8090"),
+		DashboardAddr: listenAddr(*dashboardListenFlag, os.Getenv("DASHBOARD_LISTEN_ADDR"), ""),
+		GRPCAddr:      listenAddr(*grpcListenFlag, os.Getenv("GRPC_LISTEN_ADDR"), ""),
+		Dashboard:     dashboardFiles,
+	})
 
 	// Create a context that can be cancelled for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -120,56 +259,550 @@ func main() {
 		
 		// Shutdown API server gracefully
 		if server != nil {
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
-			if err := server.Shutdown(ctx); err != nil {
-				fmt.Printf("API server shutdown error: %v\n", err)
-			} else {
-				fmt.Println("🛑 API server stopped gracefully")
-			}
+			api.StopServer()
 		}
 		os.Exit(0)
 	}()
 
-	tracker := risk.NewRiskTracker(2 * time.Minute)
+	// Polled alerts stay tracked this long after they were last seen
+	trackerTTL := 2 * time.Minute
+	if v, err := strconv.Atoi(os.Getenv("RISK_TTL_MINUTES")); err == nil && v > 0 {
+		trackerTTL = time.Duration(v) * time.Minute
+	}
+	tracker := risk.NewRiskTracker(trackerTTL)
+	api.SetRiskTracker(tracker)
 	
-	// Initialize LLM cache with 15-minute TTL
+	// Initialize LLM cache with 15-minute TTL, persisted across restarts unless disabled
 	llmCache := llmcache.NewLLMCache(15 * time.Minute)
+	if os.Getenv("LLM_CACHE_BACKEND") != "memory" {
+		cacheFile := os.Getenv("LLM_CACHE_FILE")
+		if cacheFile == "" {
+			cacheFile = "data/llm-cache.db"
+		}
+		backend, err := llmcache.NewBoltBackend(cacheFile)
+		if err == nil {
+			if llmCache, err = llmcache.NewPersistentLLMCache(15*time.Minute, backend); err != nil {
+				backend.Close()
+			}
+		}
+		if err != nil {
+			fmt.Printf("Failed to open persistent LLM cache: %v\n", err)
+			fmt.Println("Falling back to in-memory LLM cache...")
+			llmCache = llmcache.NewLLMCache(15 * time.Minute)
+		} else {
+			defer llmCache.Close()
+			fmt.Println("LLM cache persisted to", cacheFile)
+		}
+	}
+
+	// Optionally treat near-identical correlations (e.g. symptom count 14 vs 15) as cache hits
+	if v := os.Getenv("LLM_CACHE_SIMILARITY"); v != "" {
+		tolerance, err := strconv.ParseFloat(v, 64)
+		if err != nil || tolerance < 0 {
+			fmt.Printf("Invalid LLM_CACHE_SIMILARITY %q, similarity caching disabled\n", v)
+		} else {
+			llmCache.EnableSimilarity(tolerance)
+			fmt.Printf("LLM cache similarity matching enabled (tolerance %.0f%%)\n", tolerance*100)
+		}
+	}
+
+	// Round metric values and band counts before hashing so small fluctuations still hit the cache
+	normalizer := hashutil.Normalizer{SignificantDigits: 2, CountBands: hashutil.DefaultCountBands}
+	if v := os.Getenv("LLM_CACHE_VALUE_DIGITS"); v != "" {
+		if digits, err := strconv.Atoi(v); err == nil && digits >= 0 {
+			normalizer.SignificantDigits = digits
+		} else {
+			fmt.Printf("Invalid LLM_CACHE_VALUE_DIGITS %q, using %d\n", v, normalizer.SignificantDigits)
+		}
+	}
+	if v := os.Getenv("LLM_CACHE_COUNT_BANDS"); v != "" {
+		bands, err := parseCountBands(v)
+		if err != nil {
+			fmt.Printf("Invalid LLM_CACHE_COUNT_BANDS %q (%v), using defaults\n", v, err)
+		} else {
+			normalizer.CountBands = bands
+		}
+	}
+	llmCache.SetNormalizer(normalizer)
+
+	api.SetLLMCache(llmCache)
+
+	// Append-only audit trail of every LLM request/response
+	if os.Getenv("LLM_AUDIT_ENABLED") != "false" {
+		auditDir := os.Getenv("LLM_AUDIT_DIR")
+		if auditDir == "" {
+			auditDir = "data/audit"
+		}
+		retentionDays := 90
+		if v, err := strconv.Atoi(os.Getenv("LLM_AUDIT_RETENTION_DAYS")); err == nil {
+			retentionDays = v
+		}
+		auditLog, err := audit.NewLog(auditDir, time.Duration(retentionDays)*24*time.Hour)
+		if err != nil {
+			fmt.Printf("Failed to initialize LLM audit log: %v\n", err)
+		} else {
+			summarizer.SetAuditLog(auditLog)
+			api.SetAuditLog(auditLog)
+			fmt.Printf("LLM audit log: %s (retention %d days)\n", auditDir, retentionDays)
+		}
+	}
+
+	// Incident history used to surface similar past incidents in LLM prompts
+	historyFile := os.Getenv("HISTORY_FILE")
+	if historyFile == "" {
+		historyFile = "data/incidents.jsonl"
+	}
+	var embedder history.Embedder
+	if os.Getenv("RAG_EMBEDDER") == "openai" && os.Getenv("OPENAI_API_KEY") != "" {
+		embedder = history.NewOpenAIEmbedder(summarizer.NewOpenAIClient(os.Getenv("OPENAI_API_KEY")))
+	}
+	historyRetentionDays := 90
+	if v, err := strconv.Atoi(os.Getenv("HISTORY_RETENTION_DAYS")); err == nil && v >= 0 {
+		historyRetentionDays = v
+	}
+	incidentHistory, err := history.NewStore(historyFile, embedder, time.Duration(historyRetentionDays)*24*time.Hour)
+	if err != nil {
+		fmt.Printf("Failed to load incident history: %v\n", err)
+		fmt.Println("Continuing without similar-incident retrieval...")
+		incidentHistory = nil
+	} else {
+		ragTopK := 3
+		if v, err := strconv.Atoi(os.Getenv("RAG_TOP_K")); err == nil {
+			ragTopK = v
+		}
+		summarizer.SetIncidentHistory(incidentHistory, ragTopK)
+		api.SetIncidentHistory(incidentHistory)
+	}
+
+	// Operator feedback, fed back into prompts for the same alert
+	feedbackFile := os.Getenv("FEEDBACK_FILE")
+	if feedbackFile == "" {
+		feedbackFile = "data/feedback.jsonl"
+	}
+	feedbackStore, err := feedback.NewStore(feedbackFile)
+	if err != nil {
+		fmt.Printf("Failed to load operator feedback: %v\n", err)
+	} else {
+		summarizer.SetFeedbackStore(feedbackStore)
+		api.SetFeedbackStore(feedbackStore)
+	}
+
+	// Evaluated metric values, charted by the dashboard against their thresholds
+	metricHistoryFile := os.Getenv("METRIC_HISTORY_FILE")
+	if metricHistoryFile == "" {
+		metricHistoryFile = "data/metric_history.jsonl"
+	}
+	metricHistoryPoints, _ := strconv.Atoi(os.Getenv("METRIC_HISTORY_POINTS"))
+	metricHistory, err := metrichistory.NewStore(metricHistoryFile, metricHistoryPoints)
+	if err != nil {
+		fmt.Printf("Failed to load metric history: %v\n", err)
+		metricHistory = nil
+	} else {
+		api.SetMetricHistory(metricHistory)
+	}
+
+	// Scores and risk levels per cycle, for trends and dashboard sparklines
+	riskHistoryFile := os.Getenv("RISK_HISTORY_FILE")
+	if riskHistoryFile == "" {
+		riskHistoryFile = "data/risk_history.jsonl"
+	}
+	riskHistoryPoints, _ := strconv.Atoi(os.Getenv("RISK_HISTORY_POINTS"))
+	trendWindow := 15 * time.Minute
+	if v, err := strconv.Atoi(os.Getenv("RISK_TREND_WINDOW_MINUTES")); err == nil && v > 0 {
+		trendWindow = time.Duration(v) * time.Minute
+	}
+	riskHistory, err := riskhistory.NewStore(riskHistoryFile, riskHistoryPoints)
+	if err != nil {
+		fmt.Printf("Failed to load risk history: %v\n", err)
+		riskHistory = nil
+	} else {
+		api.SetRiskHistory(riskHistory, trendWindow)
+	}
+
+	// Scheduled daily/weekly digest of recorded incidents
+	if incidentHistory != nil {
+		period, err := digest.ParsePeriod(os.Getenv("DIGEST_SCHEDULE"))
+		if err != nil {
+			fmt.Println(err, "- using daily")
+			period = digest.Daily
+		}
+		digestHour := 8
+		if v, err := strconv.Atoi(os.Getenv("DIGEST_HOUR")); err == nil && v >= 0 && v < 24 {
+			digestHour = v
+		}
+		scheduler := digest.NewScheduler(incidentHistory, period, digestHour, *enableLLM, notify.FromEnv())
+		api.SetDigestScheduler(scheduler)
+		if os.Getenv("DIGEST_ENABLED") == "true" {
+			go scheduler.Run(ctx)
+			fmt.Printf("Scheduled %s incident digest at %02d:00\n", period, digestHour)
+		}
+	}
+
+	scoringConfig, err := config.LoadScoringConfig("config/scoring.yml")
+	if err != nil {
+		fmt.Println("Failed to load scoring config:", err)
+		return
+	}
+	scorer := risk.NewScorer(scoringConfig)
+	decay := risk.NewDecay(scoringConfig.Decay)
+
+	llmConfig, err := config.LoadLLMConfig("config/llm.yml")
+	if err != nil {
+		fmt.Println("Failed to load LLM config:", err)
+		return
+	}
+	if err := summarizer.ConfigurePrompts(llmConfig.Prompts); err != nil {
+		fmt.Println("Invalid prompt configuration:", err)
+		return
+	}
+	summarizer.ConfigureAnalysisPolicy(llmConfig.AnalysisPolicy)
+	if llmConfig.AnalysisPolicy.Enabled {
+		fmt.Printf("Severity-aware analysis policy enabled with %d tiers\n", len(llmConfig.AnalysisPolicy.Tiers))
+	}
+	if llmConfig.Prompts.Experiment.Enabled {
+		fmt.Printf("Prompt experiment enabled: %v\n", llmConfig.Prompts.Experiment.Variants)
+	} else {
+		fmt.Printf("Using prompt version %s\n", llmConfig.Prompts.Version)
+	}
 
 	profiles, err := config.LoadServiceProfiles("config/services")
 	if err != nil {
 		fmt.Println("Failed to load service configs:", err)
 		return
 	}
+	api.SetServiceTeams(config.ServiceTeams(profiles))
+	api.SetProfileFiles(config.NewProfileFiles("config/services"))
+
+	// Map alerts to service profiles by alert name, service labels and alert_pattern regexes
+	var serviceLabels []string
+	if v := os.Getenv("ALERT_SERVICE_LABELS"); v != "" {
+		serviceLabels = strings.Split(v, ",")
+	}
+	svc := buildServiceConfig(profiles, serviceLabels)
+
+	// Critical services can ask for fresher analyses than the global cache TTL
+	if ttls := config.CacheTTLOverrides(profiles); len(ttls) > 0 {
+		llmCache.SetServiceTTLs(ttls)
+		fmt.Printf("LLM cache TTL overrides: %v\n", ttls)
+	}
+
+	// Flappy batch jobs can stay tracked between runs while steady services age out quickly
+	if ttls := config.AlertTTLOverrides(profiles); len(ttls) > 0 {
+		tracker.SetServiceTTLs(ttls)
+		fmt.Printf("Alert TTL overrides: %v\n", ttls)
+	}
+
+	// Services left at high or critical risk without acknowledgement get escalated
+	escalationAfter := 30 * time.Minute
+	if v, err := strconv.Atoi(os.Getenv("ESCALATION_AFTER_MINUTES")); err == nil && v >= 0 {
+		escalationAfter = time.Duration(v) * time.Minute
+	}
+	escalationBump := 15
+	if v, err := strconv.Atoi(os.Getenv("ESCALATION_SCORE_BUMP")); err == nil && v >= 0 {
+		escalationBump = v
+	}
+	escalator := risk.NewEscalator(escalationAfter, escalationBump)
+	if delays := svc.escalationDelays; len(delays) > 0 {
+		escalator.SetServiceDelays(delays)
+		fmt.Printf("Escalation delay overrides: %v\n", delays)
+	}
+	escalationNotifiers := notify.FromEnv()
+
+	// Services alerting together are grouped along declared dependencies
+	dependencyGraph := svc.dependencyGraph
+
+	// Latency and error alerts get a summary of the service's recent error and slow traces
+	traceSources := svc.traceSources
+	traceLookback := 15 * time.Minute
+	if v, err := strconv.Atoi(os.Getenv("TRACING_LOOKBACK_MINUTES")); err == nil && v > 0 {
+		traceLookback = time.Duration(v) * time.Minute
+	}
+	traceLimit := 20
+	if v, err := strconv.Atoi(os.Getenv("TRACING_LIMIT")); err == nil && v > 0 {
+		traceLimit = v
+	}
+
+	// Alerting services are tagged victim or origin from the error rates of the mesh traffic
+	topologySource := buildTopologySource(os.Getenv("TOPOLOGY_BACKEND"), promEndpoint)
+	topologyWindow := 5 * time.Minute
+	if v, err := strconv.Atoi(os.Getenv("TOPOLOGY_WINDOW_MINUTES")); err == nil && v > 0 {
+		topologyWindow = time.Duration(v) * time.Minute
+	}
+	topologyThreshold := 0.05
+	if v, err := strconv.ParseFloat(os.Getenv("TOPOLOGY_ERROR_THRESHOLD"), 64); err == nil && v > 0 {
+		topologyThreshold = v / 100
+	}
+	meshNames := svc.meshNames
+
+	// Services sharing a failure pattern in the same cycle get one combined analysis
+	crossServiceMin := 2
+	if v, err := strconv.Atoi(os.Getenv("CROSS_SERVICE_MIN_SERVICES")); err == nil && v >= 0 {
+		crossServiceMin = v
+	}
+
+	// Alerts of a service, or of a dependency group, are handled as one incident
+	incidents := risk.NewIncidents()
+	api.SetIncidents(incidents)
 
 	// Create service mapping from loaded profiles
 	serviceMapping := logs.NewServiceMapping(profiles)
-	
-	// Create alert pattern to service name mapping
-	alertToServiceMapping := config.CreateAlertToServiceMapping(profiles)
-	
-	// Create map of valid services for alert filtering (using alert patterns)
-	validServices := make(map[string]bool)
-	for alertPattern := range alertToServiceMapping {
-		validServices[alertPattern] = true
+
+	// Each profile picks its log source (data_sources.backend, or the backend it configures);
+	// profiles without one use the deployment default chosen below
+	logRouter := logs.NewRouter()
+	logRouter.Register(logs.NewLokiClient(os.Getenv("LOKI_URL"), serviceMapping))
+	logRouter.Register(logs.NewSplunkClient(os.Getenv("SPLUNK_URL"), os.Getenv("SPLUNK_TOKEN"),
+		os.Getenv("SPLUNK_USERNAME"), os.Getenv("SPLUNK_PASSWORD"),
+		os.Getenv("SPLUNK_INSECURE_SKIP_VERIFY") == "true", serviceMapping))
+	logRouter.Register(logs.NewCloudWatchLogsClient(serviceMapping))
+	logRouter.Register(logs.NewJournaldClient(os.Getenv("JOURNALCTL_PATH")))
+	logRouter.Register(logs.NewKubernetesClient(os.Getenv("KUBE_CONTEXT"), os.Getenv("KUBE_API_URL"),
+		os.Getenv("KUBE_TOKEN_FILE"), os.Getenv("KUBE_CA_FILE")))
+	logRouter.Register(logs.NewObjectStorageClient())
+
+	// Log files in follow mode resume from these offsets after a restart
+	fileOffsetsFile := os.Getenv("FILE_OFFSETS_FILE")
+	if fileOffsetsFile == "" {
+		fileOffsetsFile = "data/file_offsets.json"
 	}
-	
-	// Also add service names themselves for backward compatibility
-	for serviceName := range profiles {
-		validServices[serviceName] = true
+	fileSource := logs.NewFileSource(fileOffsetsFile)
+	logRouter.Register(fileSource)
+	logRouter.SetDefault(fileSource.Name())
+
+	if esClient != nil {
+		// High-volume windows are paged through up to this many documents per scan
+		maxDocuments := 5000
+		if v, err := strconv.Atoi(os.Getenv("ES_MAX_DOCUMENTS")); err == nil && v > 0 {
+			maxDocuments = v
+		}
+		esClient.SetDefaults(defaultESIndexPattern, maxDocuments, serviceMapping)
+		// Fall back to the profile's log file when Elasticsearch is unreachable
+		logRouter.Register(logs.WithFallback(esClient, fileSource))
+		logRouter.SetDefault(esClient.Name())
+	}
+
+	// Streaming mode: symptom counts come from a Kafka log topic (via the Kafka REST Proxy)
+	// instead of re-querying Elasticsearch every cycle
+	kafkaEnabled := false
+	var kafkaStream *logs.KafkaStreamConsumer
+	if topic := os.Getenv("KAFKA_LOG_TOPIC"); topic != "" && os.Getenv("KAFKA_REST_URL") != "" {
+		group := os.Getenv("KAFKA_CONSUMER_GROUP")
+		if group == "" {
+			group = "vigilant"
+		}
+		messageField := os.Getenv("KAFKA_MESSAGE_FIELD")
+		if messageField == "" {
+			messageField = "message"
+		}
+		serviceFields := []string{"service", "container"}
+		if v := os.Getenv("KAFKA_SERVICE_FIELDS"); v != "" {
+			serviceFields = strings.Split(v, ",")
+		}
+		retention := time.Hour
+		if v, err := strconv.Atoi(os.Getenv("KAFKA_RETENTION_MINUTES")); err == nil && v > 0 {
+			retention = time.Duration(v) * time.Minute
+		}
+		kafkaStream = logs.NewKafkaStreamConsumer(os.Getenv("KAFKA_REST_URL"), topic, group,
+			messageField, serviceFields, retention, profiles, serviceMapping)
+		go kafkaStream.Run(ctx)
+		logRouter.Register(kafkaStream)
+		logRouter.SetDefault(kafkaStream.Name())
+		kafkaEnabled = true
+		fmt.Printf("Kafka streaming mode enabled: topic=%s, group=%s\n", topic, group)
+	}
+
+	// Embedded syslog receiver for hosts that forward logs with rsyslog/syslog-ng
+	if addr := os.Getenv("SYSLOG_LISTEN_ADDR"); addr != "" {
+		bufferSize, _ := strconv.Atoi(os.Getenv("SYSLOG_BUFFER_SIZE"))
+		syslogReceiver := logs.NewSyslogReceiver(addr, bufferSize, serviceMapping)
+		if err := syslogReceiver.Start(ctx); err != nil {
+			fmt.Println("Syslog receiver disabled:", err)
+		} else {
+			logRouter.Register(syslogReceiver)
+			// The Kafka stream stays the default when both are enabled
+			if !kafkaEnabled {
+				logRouter.SetDefault(syslogReceiver.Name())
+			}
+		}
 	}
 	
+	alertMapper := svc.alertMapper
+	
 	fmt.Printf("Loaded %d service configurations: %v\n", len(profiles), getServiceNames(profiles))
+
+	// Profiles are reloaded through POST /api/config/reload and, unless CONFIG_WATCH=false, when
+	// a file in config/services changes; the loop switches to them at the start of a cycle
+	reloader := newProfileReloader("config/services", serviceLabels, svc)
+	reloader.OnReload(func(profiles map[string]config.ServiceProfile) {
+		api.SetServiceTeams(config.ServiceTeams(profiles))
+		llmCache.SetServiceTTLs(config.CacheTTLOverrides(profiles))
+		tracker.SetServiceTTLs(config.AlertTTLOverrides(profiles))
+	})
+	reloader.OnReload(serviceMapping.Update)
+	if kafkaStream != nil {
+		reloader.OnReload(kafkaStream.SetProfiles)
+	}
+	api.SetProfileReload(reloader.Reload)
+	if os.Getenv("CONFIG_WATCH") != "false" {
+		err := config.WatchProfiles(ctx, "config/services", time.Second, func() {
+			if _, err := reloader.Reload(); err != nil {
+				fmt.Println("[RELOAD] Keeping the loaded profiles:", err)
+			}
+		})
+		if err != nil {
+			fmt.Println("Profile file watching disabled:", err)
+		}
+	}
+
+	// Ask Prometheus to parse every metric query up front, so a broken query is reported at
+	// startup instead of silently never triggering. PROM_VALIDATE_QUERIES=strict refuses to
+	// start with one, false skips the check.
+	if mode := os.Getenv("PROM_VALIDATE_QUERIES"); mode != "false" {
+		if invalid := validateMetricQueries(profiles, metricBackends); invalid > 0 && mode == "strict" {
+			fmt.Printf("Refusing to start with %d invalid metric queries\n", invalid)
+			return
+		}
+	}
 	
-	// Debug: Check what alerts are available from Prometheus
-	fmt.Println("DEBUG: Checking available alerts from Prometheus...")
-	allAlerts, err := prometheus.FetchAlerts(promURL, validServices) // Use proper validServices
+	// Inhibit rules mute downstream alerts while the upstream alert that explains them fires
+	alertingConfig, err := config.LoadAlertingConfig("config/alerting.yml")
 	if err != nil {
-		fmt.Printf("DEBUG: Error fetching all alerts: %v\n", err)
-	} else {
-		fmt.Printf("DEBUG: Found %d total alerts from Prometheus:\n", len(allAlerts))
-		for _, alert := range allAlerts {
-			fmt.Printf("DEBUG:   Alert: %s, Service: %s, Severity: %s\n", alert.Name, alert.Service, alert.Severity)
+		fmt.Println("Failed to load alerting config:", err)
+		return
+	}
+	inhibitor, err := risk.NewInhibitor(alertingConfig.InhibitRules)
+	if err != nil {
+		fmt.Println("Invalid inhibit rules:", err)
+		return
+	}
+	if len(alertingConfig.InhibitRules) > 0 {
+		fmt.Printf("Loaded %d inhibit rules\n", len(alertingConfig.InhibitRules))
+	}
+
+	// Alerts matching an Alertmanager silence (polled from ALERTMANAGER_URL) or a maintenance
+	// window created through the API are shown as silenced instead of being analyzed
+	var silenceEndpoint *prometheus.Endpoint
+	if amURL := os.Getenv("ALERTMANAGER_URL"); amURL != "" {
+		silenceEndpoint = &prometheus.Endpoint{URL: amURL}
+		fmt.Println("Alertmanager silences enabled from", amURL)
+	}
+	silences := prometheus.NewSilenceStore(silenceEndpoint)
+	api.SetSilences(silences)
+
+	// Alertmanager can push alerts instead of (or besides) polling Prometheus
+	if os.Getenv("ALERTMANAGER_WEBHOOK_ENABLED") == "true" {
+		webhookTTL := 4 * time.Hour // Alertmanager's default repeat_interval
+		if v, err := strconv.Atoi(os.Getenv("ALERTMANAGER_WEBHOOK_TTL_MINUTES")); err == nil && v > 0 {
+			webhookTTL = time.Duration(v) * time.Minute
+		}
+		api.SetAlertReceiver(tracker, reloader.ServiceFor, webhookTTL, os.Getenv("ALERTMANAGER_WEBHOOK_TOKEN"))
+		fmt.Println("Alertmanager webhook enabled at /api/webhooks/alertmanager")
+	}
+	// Synthetic alerts, symptoms and metric values for testing profiles, scoring and dashboards.
+	// Injected alerts are analyzed and notified like real ones.
+	var injections *inject.Store
+	if os.Getenv("DEBUG_INJECT_ENABLED") == "true" {
+		injections = inject.NewStore()
+		api.SetAlertInjector(injections, tracker, func(service string) (config.ServiceProfile, bool) {
+			profile, ok := reloader.Current().profiles[service]
+			return profile, ok
+		})
+		fmt.Println("Synthetic alert injection enabled at /api/debug/inject")
+	}
+	// Operators can ask for a fresh analysis of a service instead of waiting for a change
+	analyses := newAnalysisRequests()
+	if *enableLLM {
+		api.SetAnalyzer(analyses.Analyze)
+	}
+	// Monitoring can be paused, e.g. during chaos tests and migrations
+	pauses := risk.NewPauses()
+	api.SetPauses(pauses)
+	// Jira tickets for incidents, opened by operators or once an incident stays critical
+	var tickets *incidentTickets
+	if client := jira.FromEnv(); client != nil {
+		ticketsFile := os.Getenv("JIRA_TICKETS_FILE")
+		if ticketsFile == "" {
+			ticketsFile = "data/jira_tickets.jsonl"
+		}
+		resolveTransition := os.Getenv("JIRA_RESOLVE_TRANSITION")
+		if resolveTransition == "" {
+			resolveTransition = "Done"
+		}
+		criticalAfter := 30 * time.Minute
+		if v, err := strconv.Atoi(os.Getenv("JIRA_CRITICAL_MINUTES")); err == nil && v >= 0 {
+			criticalAfter = time.Duration(v) * time.Minute
+		}
+		ticketer, err := jira.NewTicketer(client, ticketsFile, resolveTransition)
+		if err != nil {
+			fmt.Printf("Failed to load Jira tickets: %v\n", err)
+		} else {
+			api.SetTicketer(ticketer)
+			tickets = newIncidentTickets(ticketer, criticalAfter)
+			fmt.Printf("Jira tickets enabled for project %s\n", client.Project)
+		}
+	}
+	// ALERT_STATES=firing,pending also tracks alerts that haven't fired yet, as an early warning
+	var alertStates []string
+	if v := os.Getenv("ALERT_STATES"); v != "" {
+		for _, state := range strings.Split(v, ",") {
+			alertStates = append(alertStates, strings.TrimSpace(state))
+		}
+	}
+	promPolling := os.Getenv("PROM_POLLING") != "false"
+	if !promPolling {
+		fmt.Println("Prometheus polling disabled")
+	}
+
+	// Teams that define their alerts in Grafana unified alerting are polled there as well
+	var grafanaAlerts *prometheus.GrafanaAlertSource
+	if grafanaURL := os.Getenv("GRAFANA_URL"); grafanaURL != "" {
+		var folders, labelFilters []string
+		if v := os.Getenv("GRAFANA_ALERT_FOLDERS"); v != "" {
+			folders = strings.Split(v, ",")
+		}
+		if v := os.Getenv("GRAFANA_ALERT_LABELS"); v != "" {
+			labelFilters = strings.Split(v, ",")
+		}
+		grafanaAlerts, err = prometheus.NewGrafanaAlertSource(grafanaURL, os.Getenv("GRAFANA_API_TOKEN"), folders, labelFilters)
+		if err != nil {
+			fmt.Println("Grafana alerting disabled:", err)
+		} else {
+			fmt.Printf("Polling Grafana alerts from %s\n", grafanaURL)
+		}
+	}
+
+	// Debug: Check what alerts are available from Prometheus
+	if promPolling {
+		fmt.Println("DEBUG: Checking available alerts from Prometheus...")
+		allAlerts, err := prometheus.FetchAlerts(promEndpoint, alertMapper.ServiceFor, alertStates)
+		if err != nil {
+			fmt.Printf("DEBUG: Error fetching all alerts: %v\n", err)
+		} else {
+			fmt.Printf("DEBUG: Found %d total alerts from Prometheus:\n", len(allAlerts))
+			for _, alert := range allAlerts {
+				fmt.Printf("DEBUG:   Alert: %s, Service: %s, Severity: %s\n", alert.Name, alert.Service, alert.Severity)
+			}
+		}
+	}
+
+	// Tracked alerts, acknowledgements and the last analyses survive restarts unless disabled
+	var stateStore *risk.StateStore
+	if os.Getenv("STATE_BACKEND") != "memory" {
+		stateFile := os.Getenv("STATE_FILE")
+		if stateFile == "" {
+			stateFile = "data/state.db"
+		}
+		store, err := risk.OpenStateStore(stateFile)
+		if err != nil {
+			fmt.Printf("Failed to open state store, tracked alerts won't survive restarts: %v\n", err)
+		} else {
+			stateStore = store
+			defer stateStore.Close()
+			if restored, err := stateStore.RestoreTracker(tracker); err != nil {
+				fmt.Printf("Failed to restore tracked alerts: %v\n", err)
+			} else if restored > 0 {
+				fmt.Printf("Restored %d tracked alerts from %s\n", restored, stateFile)
+			}
 		}
 	}
 
@@ -179,6 +812,20 @@ func main() {
 	}
 	maxLLMUpdateAge := 30 * time.Minute // Reduced frequency for forced updates
 
+	// Restore the last analyses so the dashboard isn't empty until the first cycle completes
+	if incidentHistory != nil {
+		if restored := warmStartFromHistory(incidentHistory, maxLLMUpdateAge, scorer); restored > 0 {
+			fmt.Printf("Restored %d recent analyses from incident history\n", restored)
+		}
+	}
+	// The saved snapshot and analyses spare an LLM call for every service still alerting unchanged
+	if stateStore != nil {
+		restoreState(stateStore, &lastState, escalator, incidents)
+	}
+
+	// The risk items last published per service, kept while a service is paused
+	lastPublished := make(map[string][]api.APIRiskItem)
+
 	for {
 		// Check if we should stop
 		select {
@@ -187,28 +834,74 @@ func main() {
 			return
 		default:
 		}
+		prometheus.StartQueryCycle()
+		cycleStarted := time.Now()
 
-		fmt.Println("Fetching alerts...")
-		alerts, err := prometheus.FetchAlerts(promURL, validServices)
-		if err != nil {
-			fmt.Println("Error fetching alerts:", err)
-			// Use context-aware sleep for early cancellation
+		// Profiles reloaded since the last cycle apply from this one
+		if current := reloader.Current(); current != svc {
+			svc = current
+			profiles, alertMapper, dependencyGraph = svc.profiles, svc.alertMapper, svc.dependencyGraph
+			traceSources, meshNames = svc.traceSources, svc.meshNames
+			escalator.SetServiceDelays(svc.escalationDelays)
+		}
+
+		// While all monitoring is paused nothing is fetched or analyzed; the API keeps the last risks
+		if pause, ok := pauses.GlobalPause(); ok {
+			fmt.Printf("[PAUSED] Monitoring paused by %s since %s\n", pause.By, pause.At.Format(time.RFC3339))
 			select {
 			case <-ctx.Done():
 				return
+			case <-pauses.Updates():
 			case <-time.After(30 * time.Second):
-				continue
 			}
+			continue
 		}
 
-		tracker.UpdateFromAlerts(alerts)
+		if promPolling {
+			fmt.Println("Fetching alerts...")
+			alerts, err := prometheus.FetchAlerts(promEndpoint, alertMapper.ServiceFor, alertStates)
+			if err != nil {
+				fmt.Println("Error fetching alerts:", err)
+				// Use context-aware sleep for early cancellation
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(30 * time.Second):
+					continue
+				}
+			}
+
+			selfmetrics.AlertsFetched.WithLabelValues("prometheus").Add(float64(len(alerts)))
+			tracker.UpdateFromAlerts(alerts)
+		}
+		if grafanaAlerts != nil {
+			alerts, err := grafanaAlerts.FetchAlerts(alertMapper.ServiceFor)
+			if err != nil {
+				fmt.Println("Error fetching Grafana alerts:", err)
+			} else {
+				selfmetrics.AlertsFetched.WithLabelValues("grafana").Add(float64(len(alerts)))
+				tracker.UpdateFromAlerts(alerts)
+			}
+		}
 		tracker.CleanupExpired()
+		// Webhook pushes change the tracker concurrently, so the cycle works on a snapshot
+		trackedItems := tracker.Active()
+		updateIncidents(incidents, dependencyGraph, trackedItems)
+		if incidentHistory != nil {
+			resolveIncidents(incidentHistory, trackedItems)
+		}
+		if err := silences.Refresh(); err != nil {
+			fmt.Println("Error fetching silences, using the last ones:", err)
+		}
+		activeItems, silencedItems := splitSilenced(silences, trackedItems)
+		activeItems, inhibitedItems := inhibitor.Apply(activeItems, trackedItems)
+		selfmetrics.ActiveAlerts.Set(float64(len(activeItems)))
 		
 		// Log active alerts being processed
-		if len(tracker.Items) > 0 {
-			fmt.Printf("Processing %d active alerts:\n", len(tracker.Items))
-			for _, item := range tracker.Items {
-				fmt.Printf("[ALERT] %s on %s (severity: %s)\n", item.AlertName, item.Service, item.Severity)
+		if len(activeItems) > 0 {
+			fmt.Printf("Processing %d active alerts:\n", len(activeItems))
+			for _, item := range activeItems {
+				fmt.Printf("[ALERT] %s on %s (severity: %s, state: %s)\n", item.AlertName, item.Service, item.Severity, item.State)
 			}
 		} else {
 			fmt.Println("No active alerts to process")
@@ -218,45 +911,99 @@ func main() {
 		var correlations []summarizer.AlertCorrelation
 		var uiData []api.APIRiskItem
 
+		// A service is as old as its oldest active alert
+		serviceStart := make(map[string]time.Time)
+		for _, item := range activeItems {
+			if start, ok := serviceStart[item.Service]; !ok || item.ActiveSince().Before(start) {
+				serviceStart[item.Service] = item.ActiveSince()
+			}
+		}
+		for _, item := range silencedItems {
+			fmt.Printf("[SILENCED] %s on %s (silenced by %s)\n", item.AlertName, item.Service, item.SilencedBy)
+			uiData = append(uiData, api.APIRiskItem{
+				IncidentID:       item.IncidentID,
+				Service:          item.Service,
+				Alert:            item.AlertName,
+				Severity:         item.Severity,
+				State:            "silenced",
+				SilencedBy:       item.SilencedBy,
+				StartsAt:         item.ActiveSince().Format(time.RFC3339),
+				Symptoms:         []api.APISymptom{},
+				Metrics:          []api.APIMetric{},
+				Risk:             "Unknown",
+				ImmediateActions: []string{},
+				Investigation:    []string{},
+				Runbooks:         []api.APIRunbook{},
+				Timestamp:        time.Now().Format("2006-01-02 15:04:05 UTC"),
+			})
+		}
+		for _, item := range inhibitedItems {
+			fmt.Printf("[INHIBITED] %s on %s (inhibited by %s)\n", item.AlertName, item.Service, item.InhibitedBy)
+			uiData = append(uiData, api.APIRiskItem{
+				IncidentID:       item.IncidentID,
+				Service:          item.Service,
+				Alert:            item.AlertName,
+				Severity:         item.Severity,
+				State:            "inhibited",
+				InhibitedBy:      item.InhibitedBy,
+				StartsAt:         item.ActiveSince().Format(time.RFC3339),
+				Symptoms:         []api.APISymptom{},
+				Metrics:          []api.APIMetric{},
+				Risk:             "Unknown",
+				ImmediateActions: []string{},
+				Investigation:    []string{},
+				Runbooks:         []api.APIRunbook{},
+				Timestamp:        time.Now().Format("2006-01-02 15:04:05 UTC"),
+			})
+		}
+
 		// Collections for hashing
 		var simplifiedAlerts []hashutil.SimplifiedAlert
 		var simplifiedSymptoms []hashutil.SimplifiedSymptom
 		var simplifiedMetrics []hashutil.SimplifiedMetric
 
-		currentAlertCount := len(tracker.Items)
+		currentAlertCount := len(activeItems)
 		currentSymptomCount := 0
 		currentMetricCount := 0
 
+		// Every evaluated check is recorded once per service and cycle, triggered or not
+		var metricPoints []metrichistory.Point
+		recordedServices := make(map[string]bool)
+
 		// Process alerts for hash comparison
-		for _, item := range tracker.Items {
+		for _, item := range activeItems {
 			simplifiedAlerts = append(simplifiedAlerts, hashutil.SimplifiedAlert{
 				Service:   item.Service,
 				AlertName: item.AlertName,
 				Severity:  item.Severity,
+				Pending:   item.State == "pending",
 			})
 		}
 
-		for _, item := range tracker.Items {
-			// Use new alert-to-service mapping
-			var serviceName string
-			var ok bool
-			
-			// First try direct alert pattern mapping
-			if serviceName, ok = alertToServiceMapping[item.AlertName]; ok {
-				// Found via alert pattern
-			} else if serviceName, ok = alertToServiceMapping[item.Service]; ok {
-				// Found via service field
+		var alertingServices []string
+		for _, item := range activeItems {
+			alertingServices = append(alertingServices, item.Service)
+		}
+		dependencies := dependencyGraph.Analyze(alertingServices)
+		for service, deps := range dependencies {
+			if deps.LikelyCulprit == service {
+				fmt.Printf("[DEPENDENCIES] %s is the likely culprit of %s\n", service, strings.Join(deps.Group, ", "))
+			}
+		}
+
+		var meshGraph *topology.Graph
+		if topologySource != nil && len(activeItems) > 0 {
+			if graph, err := topologySource.Fetch(topologyWindow); err != nil {
+				fmt.Printf("[TOPOLOGY] Error fetching the service graph from %s: %v\n", topologySource.Name(), err)
 			} else {
-				// Last resort: try direct profile lookup for backward compatibility
-				if _, exists := profiles[item.AlertName]; exists {
-					serviceName = item.AlertName
-				} else if _, exists := profiles[item.Service]; exists {
-					serviceName = item.Service
-				} else {
-					fmt.Printf("No profile found for alert '%s' or service '%s'\n", item.AlertName, item.Service)
-					continue
-				}
+				graph = graph.Rename(meshNames)
+				meshGraph = &graph
 			}
+		}
+
+		for _, item := range activeItems {
+			// Alerts are mapped to their service profile when fetched (see config.AlertMapper)
+			serviceName := item.Service
 			
 			if seen[serviceName] {
 				continue
@@ -268,78 +1015,33 @@ func main() {
 				fmt.Printf("No profile found for service '%s'\n", serviceName)
 				continue
 			}
+			if pause, paused := pauses.Paused(serviceName); paused {
+				fmt.Printf("[PAUSED] %s paused by %s, keeping its last analysis\n", serviceName, pause.By)
+				uiData = append(uiData, pausedItems(lastPublished[serviceName], *item)...)
+				continue
+			}
 			
 			// Use the resolved service name for processing
 			service := serviceName
 
-			// Logs - Use Elasticsearch if available, otherwise fall back to file-based
+			// Logs - Use the log source the profile selects, else the deployment default
 			var symptoms []logs.SymptomMatch
-			if esClient != nil {
-				// Get service-specific ES configuration using new accessor
-				esConfig := profile.GetEffectiveElasticsearchConfig()
-				
-				indexPattern := esConfig.IndexPattern
-				if indexPattern == "" {
-					indexPattern = defaultESIndexPattern
-				}
-				
-				scanLimit := esConfig.ScanLimit
-				if scanLimit == 0 {
-					scanLimit = 500 // default
-				}
-				
-				timeRangeMin := esConfig.TimeRangeMinutes
-				if timeRangeMin == 0 && esConfig.TimeRangeMin > 0 {
-					timeRangeMin = esConfig.TimeRangeMin // backward compatibility
-				}
-				if timeRangeMin == 0 {
-					timeRangeMin = 10 // default
-				}
-				timeRange := time.Duration(timeRangeMin) * time.Minute
-				
-				namespaceFilter := esConfig.NamespaceFilter
-				
-				fmt.Printf("ES scan for %s: index=%s, limit=%d, time=%dmin, namespace=%s\n", 
-					service, indexPattern, scanLimit, timeRangeMin, namespaceFilter)
-				
-				// Use Elasticsearch with namespace filtering
-				symptoms, err = esClient.ScanLogsAndMatchSymptomsWithFilter(
-					indexPattern,
-					scanLimit,
-					profile.LogPatterns,
-					timeRange,
-					serviceMapping,
-					namespaceFilter,
-				)
-				if err != nil {
-					fmt.Printf("Error scanning Elasticsearch logs for %s: %v\n", service, err)
-					fmt.Println("Attempting fallback to file-based scanning...")
-					
-					// Fallback to file-based if ES fails
-					logFile := profile.GetEffectiveLogFile()
-					if logFile != "" {
-						symptoms, err = logs.ScanLogsAndMatchSymptoms(logFile, scanLimit, profile.LogPatterns)
-						if err != nil {
-							fmt.Printf("File-based fallback also failed for %s: %v\n", service, err)
-						}
-					}
-				}
+			logSource := "none"
+			if source, err := logRouter.ForProfile(profile); err != nil {
+				fmt.Printf("No log source for %s: %v\n", service, err)
 			} else {
-				// Use file-based scanning
-				logFile := profile.GetEffectiveLogFile()
-				if logFile != "" {
-					esConfig := profile.GetEffectiveElasticsearchConfig()
-					scanLimit := esConfig.ScanLimit
-					if scanLimit == 0 {
-						scanLimit = 500 // default
-					}
-					symptoms, err = logs.ScanLogsAndMatchSymptoms(logFile, scanLimit, profile.LogPatterns)
-					if err != nil {
-						fmt.Printf("Error scanning file logs for %s: %v\n", service, err)
-					}
-				} else {
-					fmt.Printf("No log file configured for service %s and Elasticsearch unavailable\n", service)
+				logSource = source.Name()
+				scanStarted := time.Now()
+				symptoms, err = source.ScanSymptoms(ctx, profile, 0)
+				outcome := "success"
+				if err != nil {
+					outcome = "error"
+					fmt.Printf("Error scanning %s logs for %s: %v\n", source.Name(), service, err)
 				}
+				selfmetrics.LogQueryLatency.WithLabelValues(logSource, outcome).Observe(time.Since(scanStarted).Seconds())
+			}
+			if injections != nil {
+				symptoms = injectSymptoms(symptoms, injections, service)
 			}
 
 			// Filter symptoms for current service (important for ES which might return all services)
@@ -351,6 +1053,9 @@ func main() {
 					if sym.Service == "unknown" {
 						sym.Service = service
 					}
+					if sym.Severity == "" {
+						sym.Severity = profile.PatternSeverity(sym.Pattern)
+					}
 					serviceSymptoms = append(serviceSymptoms, sym)
 					fmt.Printf("[SYMPTOM] %s matched on %s (%d times)\n", sym.Pattern, sym.Service, sym.Count)
 					simplifiedSymptoms = append(simplifiedSymptoms, hashutil.SimplifiedSymptom{
@@ -361,50 +1066,99 @@ func main() {
 				}
 			}
 			currentSymptomCount += len(serviceSymptoms)
+			selfmetrics.SymptomsMatched.WithLabelValues(logSource).Add(float64(len(serviceSymptoms)))
 
-			// Metrics - Use new accessor method
-			var checks []prometheus.MetricCheck
-			effectiveMetrics := profile.GetEffectiveMetrics()
-			for _, check := range effectiveMetrics {
-				cloned := check
-				cloned.QueryTpl = prometheus.RenderQuery(cloned.QueryTpl, map[string]string{
-					"Service": service,
-				})
-				checks = append(checks, cloned)
+			// Metrics - Use new accessor method; queries can use the alert's namespace, pod,
+			// env and instance labels besides the profile's query_vars
+			queryVars := prometheus.QueryVars(service, item.Labels, profile.QueryVars)
+			evaluations := evaluateProfileMetrics(metricBackends, service, profile, queryVars)
+			if injections != nil {
+				evaluations = injectMetrics(evaluations, injections, service, profile)
 			}
-
-			metrics, err := prometheus.EvaluateMetricChecks(promURL, []prometheus.ServiceMetricConfig{
-				{Service: service, Checks: checks},
-			})
-			if err != nil {
-				fmt.Println("Error evaluating metrics for", service, ":", err)
-			} else {
-				currentMetricCount += len(metrics)
-				for _, m := range metrics {
-					fmt.Printf("[METRIC] %s triggered for %s: %.2f %s %.2f\n",
-						m.Check.Name, m.Service, m.Value, m.Check.Operator, m.Check.Threshold)
-					simplifiedMetrics = append(simplifiedMetrics, hashutil.SimplifiedMetric{
-						Service:   m.Service,
-						CheckName: m.Check.Name,
-						Value:     m.Value,
-						Operator:  m.Check.Operator,
-						Threshold: m.Check.Threshold,
+			profileSource := metricBackends.source(profile, metricBackends.backend(profile, ""))
+			contextMetrics := prometheus.FetchContextMetrics(profileSource, queryVars, profile.ContextMetrics)
+			slos := prometheus.EvaluateSLOs(profileSource, queryVars, profile.SLOs)
+			for _, s := range slos {
+				if s.Fast {
+					fmt.Printf("[SLO] %s is burning its error budget fast: %s\n", service, s)
+				}
+			}
+			var traces *tracing.Summary
+			if source, ok := traceSources[service]; ok && tracesAlert(profile, activeItems, service) {
+				traces = summarizeTraces(source, service, profile.DataSources.Tracing, traceLookback, traceLimit)
+			}
+			var position *topology.Position
+			if meshGraph != nil && !profile.DataSources.Mesh.Disabled {
+				if pos := topology.Classify(*meshGraph, service, topologyThreshold); !pos.Empty() {
+					if pos.Role != "" {
+						fmt.Printf("[TOPOLOGY] %s is %s\n", service, pos)
+					}
+					position = &pos
+				}
+			}
+			var metrics []prometheus.MetricResult
+			for _, e := range evaluations {
+				if e.Triggered {
+					metrics = append(metrics, e.MetricResult)
+				}
+				if e.HasValue && !e.Cached && !recordedServices[service] {
+					metricPoints = append(metricPoints, metrichistory.Point{
+						Service:   service,
+						Check:     e.Check.Name,
+						Time:      time.Now(),
+						Value:     e.Value,
+						Operator:  e.Check.Operator,
+						Threshold: e.Check.Threshold,
+						Triggered: e.Triggered,
+						Labels:    e.Labels,
 					})
 				}
 			}
+			recordedServices[service] = true
+			currentMetricCount += len(metrics)
+			for _, m := range metrics {
+				offender := ""
+				if o := m.Offender(); o != "" {
+					offender = ", worst " + o
+				}
+				fmt.Printf("[METRIC] %s triggered for %s: %.2f %s %.2f%s\n",
+					m.Check.Name, m.Service, m.Value, m.Check.Operator, m.Check.Threshold, offender)
+				simplifiedMetrics = append(simplifiedMetrics, hashutil.SimplifiedMetric{
+					Service:   m.Service,
+					CheckName: m.Check.Name,
+					Value:     m.Value,
+					Operator:  m.Check.Operator,
+					Threshold: m.Check.Threshold,
+				})
+			}
+
+			runbooks := profile.MatchingRunbooks(item.AlertName,
+				utils.ExtractPatterns(serviceSymptoms), utils.ExtractMetricNames(metrics))
 
 			correlations = append(correlations, summarizer.AlertCorrelation{
-				Alert:    *item,
-				Symptoms: serviceSymptoms, // Use filtered symptoms
-				Metrics:  metrics,
+				Alert:        *item,
+				Symptoms:     serviceSymptoms, // Use filtered symptoms
+				Metrics:      metrics,
+				Context:      contextMetrics,
+				SLOs:         slos,
+				Runbooks:     runbooks,
+				Dependencies: dependencies[service],
+				Traces:       traces,
+				Topology:     position,
 			})
 
 			uiData = append(uiData, api.APIRiskItem{
+				IncidentID:       item.IncidentID,
 				Service:          service,
 				Alert:            item.AlertName,
 				Severity:         item.Severity,
+				State:            item.State,
+				StartsAt:         serviceStart[service].Format(time.RFC3339),
 				Symptoms:         utils.ConvertSymptoms(serviceSymptoms),
 				Metrics:          utils.ConvertMetrics(metrics),
+				SLOs:             utils.ConvertSLOs(slos),
+				Traces:           api.NewAPITraces(traces),
+				Topology:         api.NewAPITopology(position),
 				Summary:          "", // will be updated after LLM
 				Risk:             "Unknown",
 				Confidence:       0.0,
@@ -412,10 +1166,37 @@ func main() {
 				ImmediateActions: []string{},
 				Investigation:    []string{},
 				Prevention:       "",
+				Runbooks:         utils.ConvertRunbooks(runbooks),
+				Dependencies:     api.NewAPIDependencies(dependencies[service]),
 				Timestamp:        time.Now().Format("2006-01-02 15:04:05 UTC"),
 			})
 		}
 
+		// Services failing the same way are analyzed together for their shared root cause
+		if crossServiceMin > 0 {
+			groups := summarizer.DetectCrossServiceGroups(correlations, crossServiceMin)
+			for i := range correlations {
+				correlations[i].CrossService = groups[correlations[i].Alert.Service]
+			}
+			for i := range uiData {
+				if group, ok := groups[uiData[i].Service]; ok {
+					uiData[i].CrossService = &api.APICrossService{Services: group.Services, Patterns: group.Patterns, Targets: group.Targets}
+				}
+			}
+			for key, group := range crossServiceKeys(groups) {
+				fmt.Printf("[CROSS-SERVICE] %s share %s\n", key, strings.Join(slices.Concat(group.Patterns, group.Targets), ", "))
+			}
+		}
+
+		if metricHistory != nil {
+			if err := metricHistory.Record(metricPoints); err != nil {
+				fmt.Println("Error recording metric history:", err)
+			}
+		}
+
+		// Analyses requested since the last cycle run on the data fetched by this one
+		requested := analyses.take()
+
 		// Create current state snapshot
 		currentState := StateSnapshot{
 			AlertCount:    currentAlertCount,
@@ -444,10 +1225,17 @@ func main() {
 		}
 
 		// Handle forced updates only if we have active alerts, significant time has passed, AND LLM is enabled
+		llmCorrelations := correlations
 		if *enableLLM && len(correlations) > 0 && !shouldCallLLM && currentState.ShouldForceUpdate(maxLLMUpdateAge) {
-			fmt.Printf("Forcing LLM update - last update was %v ago with %d active alerts\n", 
-				time.Since(lastState.LastLLMUpdate), len(correlations))
-			shouldCallLLM = true
+			// Acknowledged services keep their last analysis instead of being refreshed
+			llmCorrelations = unacknowledged(tracker, correlations)
+			if len(llmCorrelations) > 0 {
+				fmt.Printf("Forcing LLM update - last update was %v ago with %d active alerts\n",
+					time.Since(lastState.LastLLMUpdate), len(llmCorrelations))
+				shouldCallLLM = true
+			} else {
+				fmt.Println("Skipping forced LLM update - all services are acknowledged")
+			}
 		}
 
 		if shouldCallLLM {
@@ -455,7 +1243,7 @@ func main() {
 			llmCache.CleanupExpired()
 			
 			// Use cache-aware LLM call
-			summaryMap, err := llmCache.GetOrSummarize(correlations)
+			summaryMap, err := llmCache.GetOrSummarize(ctx, llmCorrelations)
 			if err != nil {
 				fmt.Println("Error generating per-service summaries:", err)
 			} else {
@@ -464,14 +1252,27 @@ func main() {
 					fmt.Printf("[%s]\nRisk: %s (%.1f%% confidence)\nRoot Cause: %s\nSummary: %s\n\n", 
 						svc, summary.Risk, summary.Confidence*100, summary.RootCause, summary.Summary)
 				}
-				// Store successful LLM data for reuse
+				// Store successful LLM data for reuse; a fallback never replaces a real analysis
+				applied := make(map[string]summarizer.RootCauseSummary, len(summaryMap))
 				for svc, summary := range summaryMap {
+					if last, ok := lastSuccessfulLLMData[svc]; ok && summary.Fallback {
+						applied[svc] = last
+						continue
+					}
 					lastSuccessfulLLMData[svc] = summary
+					applied[svc] = summary
+				}
+				if incidentHistory != nil {
+					recordIncidents(ctx, incidentHistory, llmCorrelations, summaryMap)
 				}
 				
-				// Apply LLM data to uiData 
+				// Apply LLM data to uiData; services left out of a forced update keep their last analysis
 				for i := range uiData {
-					if s, ok := summaryMap[uiData[i].Service]; ok {
+					s, ok := applied[uiData[i].Service]
+					if !ok {
+						s, ok = lastSuccessfulLLMData[uiData[i].Service]
+					}
+					if ok {
 						uiData[i].Summary = s.Summary
 						uiData[i].Risk = s.Risk
 						uiData[i].Confidence = s.Confidence
@@ -479,20 +1280,6 @@ func main() {
 						uiData[i].ImmediateActions = s.ImmediateActions
 						uiData[i].Investigation = s.Investigation
 						uiData[i].Prevention = s.Prevention
-						
-						// Calculate score based on risk level and confidence
-						score := 0
-						switch strings.ToLower(s.Risk) {
-						case "critical":
-							score = 90 + int(s.Confidence*10)
-						case "high":
-							score = 70 + int(s.Confidence*20)
-						case "medium":
-							score = 40 + int(s.Confidence*30)
-						case "low":
-							score = 10 + int(s.Confidence*30)
-						}
-						uiData[i].Score = score
 					}
 				}
 			}
@@ -518,36 +1305,773 @@ func main() {
 					uiData[i].ImmediateActions = s.ImmediateActions
 					uiData[i].Investigation = s.Investigation
 					uiData[i].Prevention = s.Prevention
-					
-					// Calculate score based on risk level and confidence
-					score := 0
-					switch strings.ToLower(s.Risk) {
-					case "critical":
-						score = 90 + int(s.Confidence*10)
-					case "high":
-						score = 70 + int(s.Confidence*20)
-					case "medium":
-						score = 40 + int(s.Confidence*30)
-					case "low":
-						score = 10 + int(s.Confidence*30)
-					}
-					uiData[i].Score = score
 				}
 			}
 		}
 
+		// Services an operator asked about get a fresh analysis, whatever changed
+		var analysisErrors map[string]error
+		if len(requested) > 0 {
+			analysisErrors = analyzeRequested(ctx, llmCache, incidentHistory, requested, correlations, uiData)
+		}
+
+		// Score every analyzed service from its severity, analysis, symptoms, metrics and age
+		for i := range uiData {
+			if uiData[i].State == "silenced" || uiData[i].State == "inhibited" || uiData[i].State == "paused" {
+				continue
+			}
+			in := scoreInput(uiData[i], time.Since(serviceStart[uiData[i].Service]))
+			uiData[i].Score = scorer.Score(in)
+			uiData[i].HealthScore = scorer.HealthScore(in)
+		}
+		uiData = applyDecay(decay, uiData, time.Now())
+		for i := range uiData {
+			uiData[i].Ack = nil
+			if ack, ok := tracker.Acknowledged(uiData[i].Service); ok {
+				uiData[i].Ack = api.NewAPIAck(ack)
+			}
+		}
+		reanalyze := llmCache
+		if !*enableLLM {
+			reanalyze = nil
+		}
+		escalateServices(ctx, escalator, reanalyze, scorer, escalationNotifiers, uiData, correlations, serviceStart)
+		if riskHistory != nil {
+			recordRiskHistory(riskHistory, uiData, trendWindow)
+		}
+
 		// Always push data to API - either fresh LLM results or cached data with current metrics
 		api.UpdateRisks(uiData)
+		answerRequested(requested, analysisErrors, uiData)
+		if tickets != nil {
+			tickets.update(ctx, incidents, uiData, time.Now())
+		}
+		clear(lastPublished)
+		for _, item := range uiData {
+			lastPublished[item.Service] = append(lastPublished[item.Service], item)
+		}
+		selfmetrics.CycleDuration.Observe(time.Since(cycleStarted).Seconds())
 
-		// Context-aware sleep for graceful shutdown
+		if stateStore != nil {
+			saveState(stateStore, tracker, lastState, escalator, incidents)
+		}
+
+		// Context-aware sleep for graceful shutdown; pushed alerts start the next cycle early
 		select {
 		case <-ctx.Done():
 			return
+		case <-tracker.Updates():
+		case <-analyses.Wake():
+		case <-pauses.Updates():
 		case <-time.After(30 * time.Second):
 		}
 	}
 }
 
+// pausedItems keeps the last published items of a paused service, or shows its alert unanalyzed
+func pausedItems(last []api.APIRiskItem, item risk.RiskItem) []api.APIRiskItem {
+	var items []api.APIRiskItem
+	for _, published := range last {
+		published.State = "paused"
+		items = append(items, published)
+	}
+	if len(items) > 0 {
+		return items
+	}
+	return []api.APIRiskItem{{
+		IncidentID:       item.IncidentID,
+		Service:          item.Service,
+		Alert:            item.AlertName,
+		Severity:         item.Severity,
+		State:            "paused",
+		StartsAt:         item.ActiveSince().Format(time.RFC3339),
+		Symptoms:         []api.APISymptom{},
+		Metrics:          []api.APIMetric{},
+		Risk:             "Unknown",
+		ImmediateActions: []string{},
+		Investigation:    []string{},
+		Runbooks:         []api.APIRunbook{},
+		Timestamp:        time.Now().Format("2006-01-02 15:04:05 UTC"),
+	}}
+}
+
+// applyDecay lets scores wind down: a falling score decays from its peak and shows as
+// recovering, and services that stopped alerting stay listed as recovering until their
+// score fades out
+func applyDecay(decay *risk.Decay, uiData []api.APIRiskItem, now time.Time) []api.APIRiskItem {
+	scored := make(map[string]bool)
+	for i := range uiData {
+		scored[uiData[i].Service] = true
+		if uiData[i].State == "silenced" || uiData[i].State == "inhibited" || uiData[i].State == "paused" {
+			continue
+		}
+		score, recovering := decay.Apply(uiData[i].Service, uiData[i].Score, now)
+		uiData[i].Score = score
+		if recovering && uiData[i].State != "pending" {
+			uiData[i].State = "recovering"
+		}
+		lastShown[uiData[i].Service] = uiData[i]
+	}
+
+	fading := decay.Fade(scored, now)
+	for service := range lastShown {
+		if !scored[service] && fading[service] == 0 {
+			delete(lastShown, service)
+		}
+	}
+	var services []string
+	for service := range fading {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	for _, service := range services {
+		item, ok := lastShown[service]
+		if !ok {
+			continue
+		}
+		fmt.Printf("[RECOVERING] %s (score %d)\n", service, fading[service])
+		item.State = "recovering"
+		item.Score = fading[service]
+		uiData = append(uiData, item)
+	}
+	return uiData
+}
+
+// escalateServices escalates the services left at high or critical risk without acknowledgement
+// past their escalation delay, bumping their score. A newly escalated service is re-analyzed
+// bypassing the LLM cache (unless reanalyze is nil) and announced to the notifiers.
+func escalateServices(ctx context.Context, escalator *risk.Escalator, reanalyze *llmcache.LLMCache, scorer *risk.Scorer, notifiers []notify.Notifier, uiData []api.APIRiskItem, correlations []summarizer.AlertCorrelation, serviceStart map[string]time.Time) {
+	now := time.Now()
+	alerting := make(map[string]bool)
+	for _, c := range correlations {
+		alerting[c.Alert.Service] = true
+	}
+	escalator.Prune(alerting)
+
+	hot := make(map[string]bool)
+	for _, item := range uiData {
+		if item.State == "silenced" || item.State == "inhibited" || item.State == "paused" || item.Ack != nil {
+			continue
+		}
+		if alerting[item.Service] && risk.Escalates(item.Risk) {
+			hot[item.Service] = true
+		}
+	}
+
+	escalated := make(map[string]risk.Escalation)
+	var newly []string
+	for service := range alerting {
+		state, isNew := escalator.Observe(service, hot[service], now)
+		if state.Escalated() {
+			escalated[service] = state
+		}
+		if isNew {
+			newly = append(newly, service)
+		}
+	}
+	sort.Strings(newly)
+
+	// The analysis that rated the service may be stale; escalate with a fresh one
+	if reanalyze != nil && len(newly) > 0 {
+		var stale []summarizer.AlertCorrelation
+		for _, c := range correlations {
+			if slices.Contains(newly, c.Alert.Service) {
+				stale = append(stale, c)
+			}
+		}
+		summaries, err := reanalyze.Refresh(ctx, stale)
+		if err != nil {
+			fmt.Println("[ESCALATION] Re-analysis failed:", err)
+		}
+		for i := range uiData {
+			s, ok := summaries[uiData[i].Service]
+			if !ok || s.Fallback {
+				continue
+			}
+			lastSuccessfulLLMData[uiData[i].Service] = s
+			uiData[i].Summary = s.Summary
+			uiData[i].Risk = s.Risk
+			uiData[i].Confidence = s.Confidence
+			uiData[i].RootCause = s.RootCause
+			uiData[i].ImmediateActions = s.ImmediateActions
+			uiData[i].Investigation = s.Investigation
+			uiData[i].Prevention = s.Prevention
+			uiData[i].Score = scorer.Score(scoreInput(uiData[i], time.Since(serviceStart[uiData[i].Service])))
+		}
+	}
+
+	for i := range uiData {
+		uiData[i].EscalatedAt = ""
+		state, ok := escalated[uiData[i].Service]
+		if !ok || uiData[i].State == "silenced" || uiData[i].State == "inhibited" || uiData[i].State == "paused" {
+			continue
+		}
+		uiData[i].EscalatedAt = state.EscalatedAt.Format(time.RFC3339)
+		uiData[i].Score = escalator.Bump(uiData[i].Score)
+	}
+
+	for _, service := range newly {
+		var item api.APIRiskItem
+		for _, candidate := range uiData {
+			if candidate.Service == service && candidate.Score > item.Score {
+				item = candidate
+			}
+		}
+		unacknowledgedFor := now.Sub(escalated[service].Since).Round(time.Minute)
+		fmt.Printf("[ESCALATION] %s at %s risk for %v without acknowledgement (score %d)\n",
+			service, item.Risk, unacknowledgedFor, item.Score)
+		notify.Broadcast(ctx, notifiers, notify.Message{
+			Title:    fmt.Sprintf("Escalated: %s at %s risk for %v without acknowledgement", service, item.Risk, unacknowledgedFor),
+			Text:     fmt.Sprintf("Score %d. Root cause: %s\n%s", item.Score, item.RootCause, item.Summary),
+			Severity: strings.ToLower(item.Risk),
+			Service:  service,
+			Kind:     "escalation",
+		})
+	}
+}
+
+// unacknowledged returns the correlations of services that aren't acknowledged or snoozed
+func unacknowledged(tracker *risk.RiskTracker, correlations []summarizer.AlertCorrelation) []summarizer.AlertCorrelation {
+	var result []summarizer.AlertCorrelation
+	for _, c := range correlations {
+		if _, ok := tracker.Acknowledged(c.Alert.Service); !ok {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// State store keys of the monitoring loop's own state
+const (
+	stateSnapshotKey    = "snapshot"
+	stateAnalysesKey    = "llm_analyses"
+	stateEscalationsKey = "escalations"
+	stateIncidentsKey   = "incidents"
+)
+
+// saveState persists the tracker, the last state snapshot, the last successful analyses, the
+// escalation clocks and the incidents
+func saveState(store *risk.StateStore, tracker *risk.RiskTracker, snapshot StateSnapshot, escalator *risk.Escalator, incidents *risk.Incidents) {
+	if err := store.SaveTracker(tracker); err != nil {
+		fmt.Println("Error saving tracked alerts:", err)
+	}
+	if err := store.Put(stateSnapshotKey, snapshot); err != nil {
+		fmt.Println("Error saving state snapshot:", err)
+	}
+	if err := store.Put(stateAnalysesKey, lastSuccessfulLLMData); err != nil {
+		fmt.Println("Error saving analyses:", err)
+	}
+	if err := store.Put(stateEscalationsKey, escalator.Snapshot()); err != nil {
+		fmt.Println("Error saving escalations:", err)
+	}
+	if err := store.Put(stateIncidentsKey, incidents.Snapshot()); err != nil {
+		fmt.Println("Error saving incidents:", err)
+	}
+}
+
+// restoreState loads the state snapshot, analyses, escalation clocks and incidents saved by the
+// previous run
+func restoreState(store *risk.StateStore, snapshot *StateSnapshot, escalator *risk.Escalator, incidents *risk.Incidents) {
+	var saved StateSnapshot
+	if ok, err := store.Get(stateSnapshotKey, &saved); err != nil {
+		fmt.Println("Error restoring state snapshot:", err)
+	} else if ok {
+		*snapshot = saved
+	}
+	analyses := make(map[string]summarizer.RootCauseSummary)
+	if ok, err := store.Get(stateAnalysesKey, &analyses); err != nil {
+		fmt.Println("Error restoring analyses:", err)
+	} else if ok {
+		for svc, summary := range analyses {
+			lastSuccessfulLLMData[svc] = summary
+		}
+		fmt.Printf("Restored the analyses of %d services\n", len(analyses))
+	}
+	var escalations map[string]risk.Escalation
+	if ok, err := store.Get(stateEscalationsKey, &escalations); err != nil {
+		fmt.Println("Error restoring escalations:", err)
+	} else if ok {
+		escalator.Restore(escalations)
+	}
+	var savedIncidents []risk.Incident
+	if ok, err := store.Get(stateIncidentsKey, &savedIncidents); err != nil {
+		fmt.Println("Error restoring incidents:", err)
+	} else if ok {
+		incidents.Restore(savedIncidents)
+	}
+}
+
+// listenAddr picks the flag, else the environment, else the fallback; a bare port such as
+// "9090" listens on all interfaces
+func listenAddr(flagValue, envValue, fallback string) string {
+	addr := flagValue
+	if addr == "" {
+		addr = envValue
+	}
+	if addr == "" {
+		return fallback
+	}
+	if _, err := strconv.Atoi(addr); err == nil {
+		return ":" + addr
+	}
+	return addr
+}
+
+// buildTraceSources returns the tracing backend of every profile: its data_sources.tracing,
+// else the deployment's TRACING_BACKEND and TRACING_URL. Profiles without a backend are left out.
+func buildTraceSources(profiles map[string]config.ServiceProfile, defaultBackend, defaultURL string) map[string]tracing.Source {
+	sources := make(map[string]tracing.Source)
+	shared := make(map[string]tracing.Source)
+	for service, profile := range profiles {
+		cfg := profile.DataSources.Tracing
+		backend, url := cfg.Backend, cfg.URL
+		if backend == "" {
+			backend = defaultBackend
+		}
+		if url == "" {
+			url = defaultURL
+		}
+		if cfg.Disabled || backend == "" {
+			continue
+		}
+
+		// Profiles without their own headers share one client per backend and URL
+		key := backend + " " + url
+		source, ok := shared[key]
+		if !ok || len(cfg.Headers) > 0 {
+			var err error
+			if source, err = tracing.NewSource(backend, url, cfg.Headers); err != nil {
+				fmt.Printf("Tracing disabled for %s: %v\n", service, err)
+				continue
+			}
+			if len(cfg.Headers) == 0 {
+				shared[key] = source
+			}
+		}
+		sources[service] = source
+	}
+	for key := range shared {
+		fmt.Printf("Tracing enabled (%s)\n", key)
+	}
+	return sources
+}
+
+// buildTopologySource returns the service graph source of TOPOLOGY_BACKEND: Kiali (KIALI_URL,
+// KIALI_TOKEN, KIALI_NAMESPACES) or the Istio metrics in Prometheus; nil when unset
+func buildTopologySource(backend string, promEndpoint prometheus.Endpoint) topology.Source {
+	if backend == "" {
+		return nil
+	}
+	var namespaces []string
+	for _, ns := range strings.Split(os.Getenv("KIALI_NAMESPACES"), ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	source, err := topology.NewSource(backend, topology.Config{
+		URL:        os.Getenv("KIALI_URL"),
+		Token:      os.Getenv("KIALI_TOKEN"),
+		Namespaces: namespaces,
+		Metrics:    promEndpoint,
+	})
+	if err != nil {
+		fmt.Printf("Topology disabled: %v\n", err)
+		return nil
+	}
+	fmt.Printf("Topology enabled (%s)\n", source.Name())
+	return source
+}
+
+// tracesAlert reports whether one of the service's active alerts gets trace context
+func tracesAlert(profile config.ServiceProfile, items []*risk.RiskItem, service string) bool {
+	for _, item := range items {
+		if item.Service == service && profile.DataSources.Tracing.TracesAlert(item.AlertName) {
+			return true
+		}
+	}
+	return false
+}
+
+// summarizeTraces summarizes the service's recent error and slow traces; nil when none was
+// found or the backend failed
+func summarizeTraces(source tracing.Source, service string, cfg config.TracingConfig, lookback time.Duration, limit int) *tracing.Summary {
+	name := service
+	if cfg.Service != "" {
+		name = cfg.Service
+	}
+	minDuration := time.Second
+	if cfg.MinDurationMs > 0 {
+		minDuration = time.Duration(cfg.MinDurationMs) * time.Millisecond
+	}
+	summary, err := tracing.Summarize(source, name, lookback, limit, minDuration)
+	if err != nil {
+		fmt.Printf("[TRACES] Error querying %s for %s: %v\n", source.Name(), service, err)
+		return nil
+	}
+	if summary.Empty() {
+		return nil
+	}
+	if summary.FailingSpan != nil {
+		fmt.Printf("[TRACES] %s fails in %s\n", service, summary.FailingSpan)
+	}
+	if summary.SlowSpan != nil {
+		fmt.Printf("[TRACES] %s is slow in %s\n", service, summary.SlowSpan)
+	}
+	return &summary
+}
+
+// crossServiceKeys returns each distinct cross-service group by its key
+func crossServiceKeys(groups map[string]summarizer.CrossServiceGroup) map[string]summarizer.CrossServiceGroup {
+	distinct := make(map[string]summarizer.CrossServiceGroup)
+	for _, group := range groups {
+		distinct[group.Key()] = group
+	}
+	return distinct
+}
+
+// updateIncidents assigns the tracked alerts to incidents, grouping services alerting along
+// their dependencies
+func updateIncidents(incidents *risk.Incidents, graph *risk.DependencyGraph, items []*risk.RiskItem) {
+	var services []string
+	for _, item := range items {
+		if !slices.Contains(services, item.Service) {
+			services = append(services, item.Service)
+		}
+	}
+	opened, resolved := incidents.Update(items, graph.Analyze(services), time.Now())
+	for _, inc := range opened {
+		fmt.Printf("[INCIDENT] %s opened for %s\n", inc.ID, inc.Service)
+	}
+	for _, inc := range resolved {
+		fmt.Printf("[INCIDENT] %s resolved (%s)\n", inc.ID, strings.Join(inc.Services, ", "))
+	}
+}
+
+// recordRiskHistory records each service's score once per cycle and sets the items' trends
+func recordRiskHistory(store *riskhistory.Store, uiData []api.APIRiskItem, window time.Duration) {
+	now := time.Now()
+	recorded := make(map[string]bool)
+	var points []riskhistory.Point
+	for _, item := range uiData {
+		if recorded[item.Service] {
+			continue
+		}
+		recorded[item.Service] = true
+		points = append(points, riskhistory.Point{
+			Service: item.Service,
+			Time:    now,
+			Score:   item.Score,
+			Risk:    item.Risk,
+			State:   item.State,
+		})
+	}
+	if err := store.Record(points); err != nil {
+		fmt.Println("Error recording risk history:", err)
+	}
+	for i := range uiData {
+		uiData[i].Trend = store.Trend(uiData[i].Service, window)
+	}
+}
+
+// recordIncidents persists fresh analyses so later prompts can reference them as similar incidents
+func recordIncidents(ctx context.Context, store *history.Store, correlations []summarizer.AlertCorrelation, summaries map[string]summarizer.RootCauseSummary) {
+	for _, c := range correlations {
+		s, ok := summaries[c.Alert.Service]
+		if !ok || s.Fallback || s.Risk == "Unknown" {
+			continue
+		}
+
+		symptomCounts := make(map[string]int)
+		for _, sym := range c.Symptoms {
+			symptomCounts[sym.Pattern] += sym.Count
+		}
+		metricValues := make(map[string]float64)
+		for _, m := range c.Metrics {
+			metricValues[m.Check.Name] = m.Value
+		}
+
+		incident := history.Incident{
+			ID:               c.Alert.IncidentID,
+			Service:          c.Alert.Service,
+			AlertName:        c.Alert.AlertName,
+			Severity:         c.Alert.Severity,
+			Symptoms:         utils.ExtractPatterns(c.Symptoms),
+			SymptomCounts:    symptomCounts,
+			Metrics:          utils.ExtractMetricNames(c.Metrics),
+			MetricValues:     metricValues,
+			Risk:             s.Risk,
+			Confidence:       s.Confidence,
+			RootCause:        s.RootCause,
+			Summary:          s.Summary,
+			ImmediateActions: s.ImmediateActions,
+			Investigation:    s.Investigation,
+			Prevention:       s.Prevention,
+			FirstSeen:        c.Alert.FirstSeen,
+			LastSeen:         c.Alert.LastSeen,
+		}
+		if err := store.Record(ctx, incident); err != nil {
+			fmt.Printf("Error recording incident history for %s: %v\n", c.Alert.Service, err)
+		}
+	}
+}
+
+// backendName returns the display name of the configured search log backend
+func backendName(logBackend string) string {
+	if logBackend == "opensearch" {
+		return "OpenSearch"
+	}
+	return "Elasticsearch"
+}
+
+// parseCountBands parses a comma-separated ascending list of band bounds; "off" disables banding
+func parseCountBands(v string) ([]int, error) {
+	if v == "off" {
+		return nil, nil
+	}
+	var bands []int
+	for _, part := range strings.Split(v, ",") {
+		band, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		if len(bands) > 0 && band <= bands[len(bands)-1] {
+			return nil, fmt.Errorf("bands must be ascending")
+		}
+		bands = append(bands, band)
+	}
+	return bands, nil
+}
+
+// parseKeyValues parses "name=value,name=value" into a map, or nil when v is empty
+func parseKeyValues(v string) map[string]string {
+	if v == "" {
+		return nil
+	}
+	values := make(map[string]string)
+	for _, pair := range strings.Split(v, ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			fmt.Printf("Ignoring malformed name=value pair %q\n", pair)
+			continue
+		}
+		values[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return values
+}
+
+// scoreInput collects what the risk score of an API item is computed from
+func scoreInput(item api.APIRiskItem, age time.Duration) risk.ScoreInput {
+	in := risk.ScoreInput{
+		Severity:   item.Severity,
+		Risk:       item.Risk,
+		Confidence: item.Confidence,
+		Age:        age,
+	}
+	for _, s := range item.Symptoms {
+		in.Symptoms = append(in.Symptoms, risk.SymptomInput{Severity: s.Severity, Count: s.Count})
+	}
+	for _, m := range item.Metrics {
+		in.MetricWeights = append(in.MetricWeights, m.Weight)
+	}
+	for _, s := range item.SLOs {
+		for _, b := range s.BurnRates {
+			in.BurnRate = max(in.BurnRate, b.BurnRate)
+		}
+	}
+	return in
+}
+
+// warmStartFromHistory reloads the latest analysis of every open incident recorded within maxAge
+// into lastSuccessfulLLMData and publishes them to the API. Returns the number of services restored.
+// The LLM cache isn't seeded: history lacks the correlations its keys hash, and the persistent
+// backend (LLM_CACHE_BACKEND) already keeps it across restarts.
+func warmStartFromHistory(store *history.Store, maxAge time.Duration, scorer *risk.Scorer) int {
+	now := time.Now()
+	latest := make(map[string]history.Incident)
+	for _, inc := range store.Between(now.Add(-maxAge), now) {
+		if inc.Resolved() {
+			continue
+		}
+		if prev, ok := latest[inc.Service]; !ok || inc.RecordedAt.After(prev.RecordedAt) {
+			latest[inc.Service] = inc
+		}
+	}
+
+	var uiData []api.APIRiskItem
+	for svc, inc := range latest {
+		s := summarizer.RootCauseSummary{
+			Risk:             inc.Risk,
+			Confidence:       inc.Confidence,
+			RootCause:        inc.RootCause,
+			ImmediateActions: inc.ImmediateActions,
+			Investigation:    inc.Investigation,
+			Prevention:       inc.Prevention,
+			Summary:          inc.Summary,
+		}
+		lastSuccessfulLLMData[svc] = s
+
+		var symptoms []api.APISymptom
+		for _, pattern := range inc.Symptoms {
+			symptoms = append(symptoms, api.APISymptom{Pattern: pattern, Count: inc.SymptomCounts[pattern]})
+		}
+		var metrics []api.APIMetric
+		for _, name := range inc.Metrics {
+			metrics = append(metrics, api.APIMetric{Name: name, Value: inc.MetricValues[name]})
+		}
+
+		item := api.APIRiskItem{
+			IncidentID:       inc.ID,
+			Service:          svc,
+			Alert:            inc.AlertName,
+			Severity:         inc.Severity,
+			Symptoms:         symptoms,
+			Metrics:          metrics,
+			Summary:          s.Summary,
+			Risk:             s.Risk,
+			Confidence:       s.Confidence,
+			RootCause:        s.RootCause,
+			ImmediateActions: s.ImmediateActions,
+			Investigation:    s.Investigation,
+			Prevention:       s.Prevention,
+			Runbooks:         []api.APIRunbook{},
+			StartsAt:         inc.FirstSeen.Format(time.RFC3339),
+			Timestamp:        inc.RecordedAt.Format("2006-01-02 15:04:05 UTC"),
+		}
+		item.Score = scorer.Score(scoreInput(item, time.Since(inc.FirstSeen)))
+		uiData = append(uiData, item)
+	}
+
+	if len(uiData) > 0 {
+		api.UpdateRisks(uiData)
+	}
+	return len(uiData)
+}
+
+// resolveIncidents marks recorded incidents whose alerts are no longer tracked as resolved
+func resolveIncidents(store *history.Store, items []*risk.RiskItem) {
+	active := make(map[string]bool)
+	for _, item := range items {
+		active[item.IncidentID] = true
+	}
+
+	for _, id := range store.OpenIncidentIDs() {
+		if active[id] {
+			continue
+		}
+		if err := store.Resolve(id, time.Now()); err != nil {
+			fmt.Printf("Error resolving incident %s: %v\n", id, err)
+		}
+	}
+}
+
+// splitSilenced separates the items matched by an active silence or maintenance window, setting
+// their SilencedBy
+func splitSilenced(silences *prometheus.SilenceStore, items []*risk.RiskItem) (active, silenced []*risk.RiskItem) {
+	for _, item := range items {
+		if silence, ok := silences.Match(item.MatchLabels()); ok {
+			item.SilencedBy = silence.ID
+			silenced = append(silenced, item)
+			continue
+		}
+		active = append(active, item)
+	}
+	return active, silenced
+}
+
+// metricSources holds the configured metric backends
+type metricSources struct {
+	prometheus     prometheus.Endpoint
+	datadog        *datadog.Client
+	influxdb       *influxdb.Client
+	defaultBackend string
+}
+
+// backend names the backend a check is queried from: its own, else the profile's, else the
+// deployment default
+func (m metricSources) backend(profile config.ServiceProfile, checkBackend string) string {
+	if checkBackend != "" {
+		return checkBackend
+	}
+	if profile.DataSources.MetricsBackend != "" {
+		return profile.DataSources.MetricsBackend
+	}
+	return m.defaultBackend
+}
+
+// source returns the MetricSource of backend for profile; backends without credentials fall
+// back to the profile's Prometheus endpoint
+func (m metricSources) source(profile config.ServiceProfile, backend string) prometheus.MetricSource {
+	switch {
+	case backend == "datadog" && m.datadog != nil:
+		return m.datadog
+	case backend == "influxdb" && m.influxdb != nil:
+		return m.influxdb
+	case backend == "cloudwatch":
+		cw := profile.DataSources.CloudWatchMetrics
+		return cloudwatch.NewClient(cw.Region, cw.RoleARN, time.Duration(cw.PeriodSeconds)*time.Second)
+	}
+	return profile.DataSources.Prometheus.Endpoint(m.prometheus)
+}
+
+// evaluateProfileMetrics evaluates the profile's metric checks, each against its backend
+func evaluateProfileMetrics(sources metricSources, service string, profile config.ServiceProfile, vars map[string]string) []prometheus.MetricEvaluation {
+	var backends []string
+	checks := make(map[string][]prometheus.MetricCheck)
+	for _, check := range profile.GetEffectiveMetrics() {
+		backend := sources.backend(profile, check.Backend)
+		if _, ok := checks[backend]; !ok {
+			backends = append(backends, backend)
+		}
+		checks[backend] = append(checks[backend], check)
+	}
+
+	var evaluations []prometheus.MetricEvaluation
+	for _, backend := range backends {
+		evaluations = append(evaluations, prometheus.EvaluateMetrics(sources.source(profile, backend), []prometheus.ServiceMetricConfig{
+			{Service: service, Checks: checks[backend], Vars: vars},
+		})...)
+	}
+	return evaluations
+}
+
+// validateMetricQueries checks every profile's rendered metric queries against its Prometheus
+// endpoint and returns how many were rejected. CloudWatch queries are only parsed, Datadog and
+// InfluxDB queries only checked as templates.
+func validateMetricQueries(profiles map[string]config.ServiceProfile, sources metricSources) int {
+	invalid := 0
+	for service, profile := range profiles {
+		endpoint := profile.DataSources.Prometheus.Endpoint(sources.prometheus)
+		for _, check := range profile.GetEffectiveMetrics() {
+			query, err := prometheus.RenderQuery(check.QueryTpl, prometheus.QueryVars(service, nil, profile.QueryVars))
+			if err != nil {
+				fmt.Printf("[CONFIG] Metric %s of %s has an invalid query template: %v\n", check.Name, service, err)
+				invalid++
+				continue
+			}
+			switch sources.backend(profile, check.Backend) {
+			case "cloudwatch":
+				if err := cloudwatch.ValidateQuery(query); err != nil {
+					fmt.Printf("[CONFIG] Metric %s of %s has an invalid query %q: %v\n", check.Name, service, query, err)
+					invalid++
+				}
+				continue
+			case "datadog", "influxdb":
+				continue
+			}
+			problem, err := prometheus.CheckQuery(endpoint, query)
+			if err != nil {
+				fmt.Printf("[CONFIG] Could not validate metric queries of %s: %v\n", service, err)
+				break
+			}
+			if problem != "" {
+				fmt.Printf("[CONFIG] Metric %s of %s has an invalid query %q: %s\n", check.Name, service, query, problem)
+				invalid++
+			}
+		}
+	}
+	return invalid
+}
+
 // getServiceNames extracts service names from profiles map for logging
 func getServiceNames(profiles map[string]config.ServiceProfile) []string {
 	var names []string
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"vigilant/pkg/config"
+	"vigilant/pkg/risk"
+	"vigilant/pkg/tracing"
+)
+
+// serviceConfig is what the monitoring loop derives from the service profiles; a reload
+// replaces it as a whole, so a cycle never mixes old and new profiles
+type serviceConfig struct {
+	profiles         map[string]config.ServiceProfile
+	alertMapper      *config.AlertMapper
+	dependencyGraph  *risk.DependencyGraph
+	traceSources     map[string]tracing.Source
+	meshNames        map[string]string
+	escalationDelays map[string]time.Duration
+}
+
+func buildServiceConfig(profiles map[string]config.ServiceProfile, serviceLabels []string) *serviceConfig {
+	return &serviceConfig{
+		profiles:         profiles,
+		alertMapper:      config.NewAlertMapper(profiles, serviceLabels),
+		dependencyGraph:  risk.NewDependencyGraph(config.ServiceDependencies(profiles)),
+		traceSources:     buildTraceSources(profiles, os.Getenv("TRACING_BACKEND"), os.Getenv("TRACING_URL")),
+		meshNames:        config.MeshNames(profiles),
+		escalationDelays: config.EscalationDelayOverrides(profiles),
+	}
+}
+
+// profileReloader loads the profiles of dir again on request. The loop switches to the new
+// service config at the start of its next cycle; components sharing the profiles with other
+// goroutines are updated through the OnReload hooks.
+type profileReloader struct {
+	dir           string
+	serviceLabels []string
+	current       atomic.Pointer[serviceConfig]
+	mu            sync.Mutex
+	hooks         []func(map[string]config.ServiceProfile)
+}
+
+func newProfileReloader(dir string, serviceLabels []string, initial *serviceConfig) *profileReloader {
+	r := &profileReloader{dir: dir, serviceLabels: serviceLabels}
+	r.current.Store(initial)
+	return r
+}
+
+// Current returns the service config of the last load
+func (r *profileReloader) Current() *serviceConfig {
+	return r.current.Load()
+}
+
+// OnReload calls hook with the profiles of every reload
+func (r *profileReloader) OnReload(hook func(map[string]config.ServiceProfile)) {
+	r.hooks = append(r.hooks, hook)
+}
+
+// ServiceFor maps alerts with the current profiles, for alerts received outside the loop
+func (r *profileReloader) ServiceFor(labels map[string]string) (string, bool) {
+	return r.Current().alertMapper.ServiceFor(labels)
+}
+
+// Reload loads the profiles and returns the loaded services. Invalid files are skipped as at
+// startup; a directory left without any valid profile keeps the loaded ones.
+func (r *profileReloader) Reload() ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	profiles, err := config.LoadServiceProfiles(r.dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(profiles) == 0 && len(r.Current().profiles) > 0 {
+		return nil, fmt.Errorf("no valid service profile in %s", r.dir)
+	}
+	svc := buildServiceConfig(profiles, r.serviceLabels)
+	for _, hook := range r.hooks {
+		hook(profiles)
+	}
+	r.current.Store(svc)
+
+	names := getServiceNames(profiles)
+	sort.Strings(names)
+	fmt.Printf("[RELOAD] Loaded %d service configurations: %v\n", len(names), names)
+	return names, nil
+}
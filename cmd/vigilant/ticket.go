@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"vigilant/pkg/api"
+	"vigilant/pkg/jira"
+	"vigilant/pkg/risk"
+)
+
+// incidentTickets opens Jira tickets for incidents staying critical past a threshold, comments
+// when their risk changes and resolves them with their incidents
+type incidentTickets struct {
+	ticketer      *jira.Ticketer
+	criticalAfter time.Duration        // Zero leaves opening tickets to operators
+	criticalSince map[string]time.Time // Incident ID -> start of its current critical streak
+}
+
+func newIncidentTickets(ticketer *jira.Ticketer, criticalAfter time.Duration) *incidentTickets {
+	return &incidentTickets{ticketer: ticketer, criticalAfter: criticalAfter, criticalSince: make(map[string]time.Time)}
+}
+
+// update runs after the cycle's risks are published, so tickets carry the current reports
+func (t *incidentTickets) update(ctx context.Context, incidents *risk.Incidents, uiData []api.APIRiskItem, now time.Time) {
+	// The worst risk of each incident; silenced, inhibited and paused items aren't assessed
+	worst := make(map[string]api.APIRiskItem)
+	for _, item := range uiData {
+		if item.IncidentID == "" || item.State == "silenced" || item.State == "inhibited" || item.State == "paused" {
+			continue
+		}
+		if risk.LevelRank(item.Risk) > risk.LevelRank(worst[item.IncidentID].Risk) {
+			worst[item.IncidentID] = item
+		}
+	}
+
+	for id := range t.criticalSince {
+		if worst[id].Risk != "Critical" {
+			delete(t.criticalSince, id)
+		}
+	}
+	for id, item := range worst {
+		if item.Risk == "Critical" {
+			if _, ok := t.criticalSince[id]; !ok {
+				t.criticalSince[id] = now
+			}
+		}
+
+		ticket, ok := t.ticketer.Get(id)
+		if !ok {
+			since, critical := t.criticalSince[id]
+			if !critical || t.criticalAfter == 0 || now.Sub(since) < t.criticalAfter {
+				continue
+			}
+			if _, _, err := api.OpenTicket(ctx, id, "vigilant"); err != nil {
+				fmt.Printf("[JIRA] Failed to open a ticket for incident %s: %v\n", id, err)
+			}
+			continue
+		}
+		if item.Risk != ticket.Risk {
+			if err := t.ticketer.UpdateRisk(ctx, id, item.Risk, item.Summary); err != nil {
+				fmt.Printf("[JIRA] Failed to update %s: %v\n", ticket.Key, err)
+			}
+		}
+	}
+
+	for _, ticket := range t.ticketer.Unresolved() {
+		inc, ok := incidents.Get(ticket.IncidentID)
+		if ok && inc.Status != "resolved" {
+			continue
+		}
+		resolvedAt := now
+		if ok {
+			resolvedAt = inc.ResolvedAt
+		}
+		if err := t.ticketer.Resolve(ctx, ticket.IncidentID, resolvedAt); err != nil {
+			fmt.Printf("[JIRA] Failed to resolve %s: %v\n", ticket.Key, err)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+// Package dashboard serves the built web dashboard. Building with -tags embeddashboard, after
+// npm run build, embeds dashboard/dist into the binary.
+package dashboard
+
+import (
+	"net/http"
+	"os"
+)
+
+// defaultDir is where npm run build puts the dashboard, relative to the repository
+const defaultDir = "./dashboard/dist"
+
+// FileSystem returns the dashboard files and where they come from: dir when set, else the
+// files embedded at build time, else ./dashboard/dist of the working directory
+func FileSystem(dir string) (http.FileSystem, string) {
+	if dir != "" {
+		return http.Dir(dir), dir
+	}
+	if files, ok := embedded(); ok {
+		return http.FS(files), "embedded"
+	}
+	if _, err := os.Stat(defaultDir); err != nil {
+		return http.Dir(defaultDir), defaultDir + " (missing, build the dashboard or set DASHBOARD_DIR)"
+	}
+	return http.Dir(defaultDir), defaultDir
+}
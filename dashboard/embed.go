@@ -0,0 +1,17 @@
+//go:build embeddashboard
+
+package dashboard
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed all:dist
+var files embed.FS
+
+// embedded returns the files of dist
+func embedded() (fs.FS, bool) {
+	dist, err := fs.Sub(files, "dist")
+	return dist, err == nil
+}
@@ -0,0 +1,10 @@
+//go:build !embeddashboard
+
+package dashboard
+
+import "io/fs"
+
+// embedded has no files without the embeddashboard build tag
+func embedded() (fs.FS, bool) {
+	return nil, false
+}
@@ -1,8 +1,70 @@
 import { useEffect, useState } from "react";
 
+interface MetricPoint {
+  time: string;
+  value: number;
+  triggered: boolean;
+}
+
+interface MetricHistory {
+  check: string;
+  operator: string;
+  threshold: number;
+  points: MetricPoint[];
+}
+
+// Sparkline of a check's recorded values with its threshold as a dashed line
+function MetricSparkline({ history }: { history: MetricHistory }) {
+  const width = 240;
+  const height = 40;
+  const values = history.points.map((p) => p.value);
+  if (values.length < 2) return null;
+  const showThreshold = !["absent", "anomaly", "rate-of-change"].includes(history.operator);
+  const all = showThreshold ? [...values, history.threshold] : values;
+  const min = Math.min(...all);
+  const max = Math.max(...all);
+  const y = (v: number) => height - 2 - ((v - min) / (max - min || 1)) * (height - 4);
+  const x = (i: number) => (i / (values.length - 1)) * width;
+  const path = values.map((v, i) => `${i === 0 ? "M" : "L"}${x(i).toFixed(1)},${y(v).toFixed(1)}`).join(" ");
+  return (
+    <svg width={width} height={height} className="mt-1">
+      {showThreshold && (
+        <line x1={0} x2={width} y1={y(history.threshold)} y2={y(history.threshold)}
+          stroke="#f87171" strokeDasharray="4 3" strokeWidth={1} />
+      )}
+      <path d={path} fill="none" stroke="#facc15" strokeWidth={1.5} />
+    </svg>
+  );
+}
+
+interface RiskPoint {
+  time: string;
+  score: number;
+  risk: string;
+}
+
+// Sparkline of a service's recorded scores on the 0-100 scale
+function ScoreSparkline({ points }: { points: RiskPoint[] }) {
+  const width = 160;
+  const height = 28;
+  if (points.length < 2) return null;
+  const y = (v: number) => height - 2 - (v / 100) * (height - 4);
+  const x = (i: number) => (i / (points.length - 1)) * width;
+  const path = points.map((p, i) => `${i === 0 ? "M" : "L"}${x(i).toFixed(1)},${y(p.score).toFixed(1)}`).join(" ");
+  return (
+    <svg width={width} height={height}>
+      <path d={path} fill="none" stroke="#60a5fa" strokeWidth={1.5} />
+    </svg>
+  );
+}
+
+const trendArrows: Record<string, string> = { worsening: "▲", improving: "▼", stable: "▶" };
+
 interface APISymptom {
   pattern: string;
+  severity?: string;
   count: number;
+  samples?: string[];
 }
 
 interface APIMetric {
@@ -10,15 +72,88 @@ interface APIMetric {
   value: number;
   operator: string;
   threshold: number;
+  labels?: Record<string, string>;
+  offenders?: number;
+  series?: number;
+}
+
+interface APIAck {
+  by: string;
+  comment?: string;
+  at: string;
+  until?: string;
+}
+
+interface APIBurnRate {
+  window: string;
+  error_ratio: number;
+  burn_rate: number;
+  budget_spent: number;
+}
+
+interface APISLO {
+  name: string;
+  target: number;
+  window: string;
+  burn_rates: APIBurnRate[];
+  fast: boolean;
+}
+
+interface APIDependencies {
+  depends_on?: string[];
+  consumed_by?: string[];
+  group?: string[];
+  likely_culprit?: string;
+  firing_upstream?: string[];
+  firing_downstream?: string[];
+  blast_radius?: string[];
+}
+
+interface APICrossService {
+  services: string[];
+  patterns?: string[];
+  targets?: string[];
+}
+
+interface APITraces {
+  error_traces: number;
+  slow_traces: number;
+  failing_span?: string;
+  slow_span?: string;
+  downstream?: string;
+  trace_ids?: string[];
+}
+
+interface APITopology {
+  role?: string;
+  inbound_request_rate: number;
+  inbound_error_rate: number;
+  outbound_request_rate: number;
+  outbound_error_rate: number;
+  failing_downstream?: string;
+  downstream_error_rate?: number;
+  error_callers?: string[];
 }
 
 interface APIRiskItem {
   service: string;
   alert: string;
   severity: string;
+  state?: string;
+  silenced_by?: string;
+  inhibited_by?: string;
+  ack?: APIAck;
+  escalated_at?: string;
+  dependencies?: APIDependencies;
+  cross_service?: APICrossService;
   score: number;
+  health_score?: number;
+  trend?: string;
   symptoms: APISymptom[];
   metrics: APIMetric[];
+  slos?: APISLO[];
+  traces?: APITraces;
+  topology?: APITopology;
   summary: string;
   risk: string;
   confidence: number;
@@ -29,11 +164,43 @@ interface APIRiskItem {
   timestamp: string;
 }
 
+interface APIMe {
+  authenticated: boolean;
+  sso: boolean;
+  user?: { sub: string; email?: string; name?: string };
+}
+
+// The API token given once as ?token=, kept for later visits
+const apiToken = (() => {
+  const params = new URLSearchParams(window.location.search);
+  const token = params.get("token");
+  if (token) {
+    localStorage.setItem("vigilantToken", token);
+    params.delete("token");
+    const query = params.toString();
+    window.history.replaceState(null, "", window.location.pathname + (query ? `?${query}` : ""));
+    return token;
+  }
+  return localStorage.getItem("vigilantToken") ?? "";
+})();
+
+const apiFetch = (url: string) =>
+  fetch(url, apiToken ? { headers: { Authorization: `Bearer ${apiToken}` } } : undefined);
 
 export default function App() {
   const [data, setData] = useState<APIRiskItem[]>([]);
   const [selected, setSelected] = useState<APIRiskItem | null>(null);
+  const [metricHistory, setMetricHistory] = useState<MetricHistory[]>([]);
+  const [riskHistory, setRiskHistory] = useState<RiskPoint[]>([]);
   const [connectionStatus, setConnectionStatus] = useState<'connecting' | 'connected' | 'disconnected'>('connecting');
+  const [me, setMe] = useState<APIMe | null>(null);
+
+  useEffect(() => {
+    apiFetch("/api/v1/me")
+      .then((res) => (res.ok ? res.json() : null))
+      .then(setMe)
+      .catch(() => setMe(null));
+  }, []);
 
   useEffect(() => {
     let ws: WebSocket;
@@ -42,7 +209,7 @@ export default function App() {
 
     const fetchDataFallback = async () => {
       try {
-        const res = await fetch("/api/risks");
+        const res = await apiFetch("/api/v1/risks");
         const json = await res.json();
         json.sort((a: APIRiskItem, b: APIRiskItem) => b.score - a.score);
         setData(json);
@@ -58,9 +225,9 @@ export default function App() {
       }
 
       const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
-      const wsUrl = `${protocol}//${window.location.host}/ws`;
+      const wsUrl = `${protocol}//${window.location.host}/ws${apiToken ? `?token=${encodeURIComponent(apiToken)}` : ""}`;
       
-      console.log('Attempting WebSocket connection to:', wsUrl);
+      console.log('Attempting WebSocket connection to:', wsUrl.replace(/token=[^&]*/, "token=***"));
       console.log('Current location:', window.location.href);
       setConnectionStatus('connecting');
       ws = new WebSocket(wsUrl);
@@ -133,6 +300,29 @@ export default function App() {
     };
   }, []);
 
+  useEffect(() => {
+    setMetricHistory([]);
+    setRiskHistory([]);
+    if (!selected) return;
+    const from = new Date(Date.now() - 6 * 60 * 60 * 1000).toISOString();
+    apiFetch(`/api/v1/risks/${encodeURIComponent(selected.service)}/metrics/history?from=${from}`)
+      .then((res) => (res.ok ? res.json() : { checks: [] }))
+      .then((json) => setMetricHistory(json.checks ?? []))
+      .catch(() => setMetricHistory([]));
+    apiFetch(`/api/v1/risks/${encodeURIComponent(selected.service)}/history?from=${from}`)
+      .then((res) => (res.ok ? res.json() : { points: [] }))
+      .then((json) => setRiskHistory(json.points ?? []))
+      .catch(() => setRiskHistory([]));
+  }, [selected?.service, selected?.timestamp]);
+
+  const getTrendColor = (trend?: string) => {
+    switch (trend) {
+      case "worsening": return "text-red-400";
+      case "improving": return "text-green-400";
+      default: return "text-zinc-400";
+    }
+  };
+
   const getRiskColor = (risk: string) => {
     switch (risk.toLowerCase()) {
       case "critical": return "bg-red-700 text-red-100";
@@ -169,6 +359,14 @@ export default function App() {
           
           {/* Connection Status */}
           <div className="flex items-center gap-2">
+            {me?.user && (
+              <span className="text-xs text-zinc-400 mr-4">
+                {me.user.name || me.user.email || me.user.sub}
+                {me.sso && (
+                  <a href="/auth/logout" className="ml-2 text-blue-400 hover:underline">Log out</a>
+                )}
+              </span>
+            )}
             <div className={`w-2 h-2 rounded-full ${
               connectionStatus === 'connected' ? 'bg-green-400' :
               connectionStatus === 'connecting' ? 'bg-yellow-400' : 'bg-red-400'
@@ -222,6 +420,43 @@ export default function App() {
                   <span className={`text-xs font-medium ${getSeverityColor(item.severity)}`}>
                     ● {item.severity.toUpperCase()}
                   </span>
+                  {item.state === 'pending' && (
+                    <span className="px-1.5 py-0.5 rounded text-xs font-medium bg-zinc-700 text-amber-300">
+                      PENDING
+                    </span>
+                  )}
+                  {item.state === 'silenced' && (
+                    <span className="px-1.5 py-0.5 rounded text-xs font-medium bg-zinc-700 text-zinc-400" title={item.silenced_by}>
+                      SILENCED
+                    </span>
+                  )}
+                  {item.state === 'inhibited' && (
+                    <span className="px-1.5 py-0.5 rounded text-xs font-medium bg-zinc-700 text-zinc-400" title={item.inhibited_by}>
+                      INHIBITED
+                    </span>
+                  )}
+                  {item.state === 'paused' && (
+                    <span className="px-1.5 py-0.5 rounded text-xs font-medium bg-zinc-700 text-zinc-400">
+                      PAUSED
+                    </span>
+                  )}
+                  {item.state === 'recovering' && (
+                    <span className="px-1.5 py-0.5 rounded text-xs font-medium bg-zinc-700 text-emerald-300">
+                      RECOVERING
+                    </span>
+                  )}
+                  {item.ack && (
+                    <span className="px-1.5 py-0.5 rounded text-xs font-medium bg-zinc-700 text-sky-300"
+                      title={`${item.ack.by}${item.ack.comment ? `: ${item.ack.comment}` : ""}`}>
+                      {item.ack.until ? `SNOOZED until ${new Date(item.ack.until).toLocaleTimeString()}` : "ACK"}
+                    </span>
+                  )}
+                  {item.escalated_at && (
+                    <span className="px-1.5 py-0.5 rounded text-xs font-medium bg-zinc-700 text-red-300"
+                      title={`Escalated at ${new Date(item.escalated_at).toLocaleTimeString()}`}>
+                      ESCALATED
+                    </span>
+                  )}
                   {item.confidence > 0 && (
                     <span className="text-xs text-zinc-400">
                       {Math.round(item.confidence * 100)}% confident
@@ -264,6 +499,15 @@ export default function App() {
                         {Math.round(selected.confidence * 100)}% confidence
                       </span>
                     )}
+                    <span className={`text-sm ${getTrendColor(selected.trend)}`} title={selected.trend}>
+                      Score {selected.score} {selected.trend && trendArrows[selected.trend]}
+                    </span>
+                    {selected.health_score !== undefined && selected.health_score !== selected.score && (
+                      <span className="text-sm text-zinc-400" title="Deterministic score without the LLM">
+                        Health {selected.health_score}
+                      </span>
+                    )}
+                    <ScoreSparkline points={riskHistory} />
                     <span className="text-xs text-zinc-500">{selected.timestamp}</span>
                   </div>
                 </div>
@@ -288,6 +532,111 @@ export default function App() {
                 </div>
               </div>
 
+              {/* Dependencies */}
+              {selected.dependencies && (
+                <div className="mb-6 bg-zinc-800 rounded-lg p-4 border border-zinc-700">
+                  <h3 className="font-semibold text-purple-400 mb-2 flex items-center gap-2">
+                    🔗 Dependencies
+                  </h3>
+                  {selected.dependencies.likely_culprit && (
+                    <p className="text-white mb-2">
+                      {selected.dependencies.likely_culprit === selected.service
+                        ? `Likely culprit: ${(selected.dependencies.group ?? []).filter((s) => s !== selected.service).join(", ")} depend on this service`
+                        : `Likely culprit: ${selected.dependencies.likely_culprit} (upstream, alerting too)`}
+                    </p>
+                  )}
+                  <div className="grid grid-cols-2 gap-4 text-sm">
+                    <div>
+                      <span className="text-zinc-400">Depends on:</span>
+                      <p className="text-white">{(selected.dependencies.depends_on ?? []).join(", ") || "—"}</p>
+                    </div>
+                    <div>
+                      <span className="text-zinc-400">Blast radius:</span>
+                      <p className="text-white">{(selected.dependencies.blast_radius ?? []).join(", ") || "—"}</p>
+                    </div>
+                  </div>
+                </div>
+              )}
+
+              {/* Cross-service correlation */}
+              {selected.cross_service && (
+                <div className="mb-6 bg-zinc-800 rounded-lg p-4 border border-zinc-700">
+                  <h3 className="font-semibold text-orange-400 mb-2 flex items-center gap-2">
+                    🧩 Shared Failure
+                  </h3>
+                  <p className="text-white mb-2">
+                    Analyzed together with {selected.cross_service.services.filter((s) => s !== selected.service).join(", ")}
+                  </p>
+                  <div className="grid grid-cols-2 gap-4 text-sm">
+                    <div>
+                      <span className="text-zinc-400">Shared patterns:</span>
+                      <p className="text-white">{(selected.cross_service.patterns ?? []).join(", ") || "—"}</p>
+                    </div>
+                    <div>
+                      <span className="text-zinc-400">Shared targets:</span>
+                      <p className="text-white">{(selected.cross_service.targets ?? []).join(", ") || "—"}</p>
+                    </div>
+                  </div>
+                </div>
+              )}
+
+              {/* Mesh topology */}
+              {selected.topology && (
+                <div className="mb-6 bg-zinc-800 rounded-lg p-4 border border-zinc-700">
+                  <h3 className="font-semibold text-teal-400 mb-2 flex items-center gap-2">
+                    🕸️ Mesh Topology
+                    {selected.topology.role && (
+                      <span className={`text-xs px-2 py-0.5 rounded ${selected.topology.role === "victim" ? "bg-yellow-900 text-yellow-300" : "bg-red-900 text-red-300"}`}>
+                        {selected.topology.role.toUpperCase()}
+                      </span>
+                    )}
+                  </h3>
+                  {selected.topology.failing_downstream && (
+                    <p className="text-white mb-2">
+                      Calls to {selected.topology.failing_downstream} failing ({((selected.topology.downstream_error_rate ?? 0) * 100).toFixed(0)}%)
+                    </p>
+                  )}
+                  <div className="grid grid-cols-2 gap-4 text-sm">
+                    <div>
+                      <span className="text-zinc-400">Inbound:</span>
+                      <p className="text-white">
+                        {selected.topology.inbound_request_rate.toFixed(1)} req/s, {(selected.topology.inbound_error_rate * 100).toFixed(1)}% errors
+                      </p>
+                    </div>
+                    <div>
+                      <span className="text-zinc-400">Outbound:</span>
+                      <p className="text-white">
+                        {selected.topology.outbound_request_rate.toFixed(1)} req/s, {(selected.topology.outbound_error_rate * 100).toFixed(1)}% errors
+                      </p>
+                    </div>
+                  </div>
+                </div>
+              )}
+
+              {/* Distributed traces */}
+              {selected.traces && (
+                <div className="mb-6 bg-zinc-800 rounded-lg p-4 border border-zinc-700">
+                  <h3 className="font-semibold text-sky-400 mb-2 flex items-center gap-2">
+                    🔎 Traces
+                  </h3>
+                  <p className="text-zinc-400 text-sm mb-2">
+                    {selected.traces.error_traces} error traces, {selected.traces.slow_traces} slow traces
+                    {selected.traces.downstream && <> · points at <span className="text-white">{selected.traces.downstream}</span></>}
+                  </p>
+                  <div className="space-y-1 text-sm">
+                    {selected.traces.failing_span && (
+                      <p className="text-white"><span className="text-zinc-400">Failing:</span> {selected.traces.failing_span}</p>
+                    )}
+                    {selected.traces.slow_span && (
+                      <p className="text-white"><span className="text-zinc-400">Slow:</span> {selected.traces.slow_span}</p>
+                    )}
+                    {selected.traces.trace_ids && selected.traces.trace_ids.length > 0 && (
+                      <p className="text-zinc-500 font-mono text-xs">{selected.traces.trace_ids.join("  ")}</p>
+                    )}
+                  </div>
+                </div>
+              )}
+
               {/* Root Cause Analysis */}
               {selected.root_cause && (
                 <div className="mb-6 bg-red-950 border border-red-800 rounded-lg p-4">
@@ -326,11 +675,18 @@ export default function App() {
                   {selected.symptoms?.length > 0 ? (
                     <ul className="space-y-2">
                       {selected.symptoms.map((s, i) => (
-                        <li key={i} className="flex items-center justify-between">
-                          <span className="text-zinc-300">{s.pattern}</span>
-                          <span className="text-red-400 font-mono text-sm bg-red-900 px-2 py-1 rounded">
-                            {s.count}x
-                          </span>
+                        <li key={i}>
+                          <div className="flex items-center justify-between">
+                            <span className="text-zinc-300">{s.pattern}</span>
+                            <span className="text-red-400 font-mono text-sm bg-red-900 px-2 py-1 rounded">
+                              {s.count}x
+                            </span>
+                          </div>
+                          {s.samples && s.samples.length > 0 && (
+                            <pre className="mt-1 text-xs text-zinc-400 bg-zinc-900 rounded p-2 overflow-x-auto whitespace-pre-wrap">
+                              {s.samples.join("\n")}
+                            </pre>
+                          )}
                         </li>
                       ))}
                     </ul>
@@ -354,6 +710,18 @@ export default function App() {
                               {m.value.toFixed(2)} {m.operator} {m.threshold}
                             </span>
                           </div>
+                          {m.series && m.series > 1 && m.labels && (
+                            <div className="text-xs text-zinc-500 font-mono">
+                              worst of {m.offenders}/{m.series} series:{' '}
+                              {Object.entries(m.labels)
+                                .filter(([k]) => k !== '__name__')
+                                .map(([k, v]) => `${k}="${v}"`)
+                                .join(', ')}
+                            </div>
+                          )}
+                          {metricHistory
+                            .filter((h) => h.check === m.name)
+                            .map((h) => <MetricSparkline key={h.check} history={h} />)}
                         </li>
                       ))}
                     </ul>
@@ -363,6 +731,32 @@ export default function App() {
                 </div>
               </div>
 
+              {/* SLO burn */}
+              {selected.slos && selected.slos.length > 0 && (
+                <div className="mb-6 bg-zinc-800 rounded-lg p-4 border border-zinc-700">
+                  <h3 className="font-semibold text-orange-400 mb-3 flex items-center gap-2">
+                    🔥 Error Budget Burn
+                  </h3>
+                  <ul className="space-y-2">
+                    {selected.slos.map((slo) => (
+                      <li key={slo.name} className="text-sm">
+                        <div className="flex items-center justify-between mb-1">
+                          <span className="text-zinc-300 font-medium">
+                            {slo.name} ({slo.target}% over {slo.window})
+                          </span>
+                          {slo.fast && <span className="text-red-400 text-xs font-semibold">BURNING FAST</span>}
+                        </div>
+                        {slo.burn_rates.map((b) => (
+                          <div key={b.window} className="text-xs text-zinc-400 font-mono">
+                            {b.window}: {b.burn_rate.toFixed(1)}x, {(b.budget_spent * 100).toFixed(1)}% of the budget burned
+                          </div>
+                        ))}
+                      </li>
+                    ))}
+                  </ul>
+                </div>
+              )}
+
               {/* Investigation Steps */}
               {selected.investigation_steps?.length > 0 && (
                 <div className="mb-6 bg-blue-950 border border-blue-800 rounded-lg p-4">
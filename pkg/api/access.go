@@ -0,0 +1,228 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"slices"
+	"sync"
+
+	"vigilant/pkg/config"
+)
+
+// Grant is what a caller may do: a role (see config.Roles) over the services of some teams
+type Grant struct {
+	Role  string   `json:"role"`
+	Teams []string `json:"teams,omitempty"` // Empty grants every service
+}
+
+// adminGrant is given when access isn't restricted, to legacy API_KEYS and to public endpoints
+var adminGrant = Grant{Role: "admin"}
+
+// Allows reports whether the grant's role includes role
+func (g Grant) Allows(role string) bool {
+	return slices.Index(config.Roles, g.Role) >= slices.Index(config.Roles, role)
+}
+
+// Unscoped reports whether the grant covers every service
+func (g Grant) Unscoped() bool {
+	return len(g.Teams) == 0
+}
+
+// Sees reports whether the grant covers service; a scoped grant doesn't see services without a team
+func (g Grant) Sees(service string) bool {
+	if g.Unscoped() {
+		return true
+	}
+	team, ok := accessPolicy.serviceTeam(service)
+	return ok && slices.Contains(g.Teams, team)
+}
+
+// merge widens the grant with another one
+func (g Grant) merge(other Grant) Grant {
+	if other.Allows(g.Role) {
+		g.Role = other.Role
+	}
+	if g.Unscoped() || other.Unscoped() {
+		g.Teams = nil
+	} else {
+		for _, team := range other.Teams {
+			if !slices.Contains(g.Teams, team) {
+				g.Teams = append(g.Teams, team)
+			}
+		}
+	}
+	return g
+}
+
+// Access resolves the grants of callers from config/access.yml
+type Access struct {
+	cfg   config.AccessConfig
+	mu    sync.RWMutex
+	teams map[string]string // Service -> team
+}
+
+var accessPolicy *Access
+
+// SetAccess restricts callers to the roles and teams of cfg; services belong to the team of
+// their profile. A config without rules leaves every caller admin.
+func SetAccess(cfg config.AccessConfig, serviceTeams map[string]string) {
+	if !cfg.Enabled() {
+		accessPolicy = nil
+		return
+	}
+	accessPolicy = &Access{cfg: cfg, teams: serviceTeams}
+}
+
+// SetServiceTeams updates the teams of the services when the profiles are (re)loaded
+func SetServiceTeams(serviceTeams map[string]string) {
+	if a := accessPolicy; a != nil {
+		a.mu.Lock()
+		a.teams = serviceTeams
+		a.mu.Unlock()
+	}
+}
+
+func (a *Access) serviceTeam(service string) (string, bool) {
+	if a == nil {
+		return "", false
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	team, ok := a.teams[service]
+	return team, ok
+}
+
+// resolve returns the grant of an authenticated user: their user rule, else the merged rules
+// of their groups, else the default role. Callers without an identity (legacy API_KEYS) are admins.
+func (a *Access) resolve(user User) (Grant, bool) {
+	if a == nil || user.Subject == "" {
+		return adminGrant, true
+	}
+	for _, id := range []string{user.Email, user.Name, user.Subject} {
+		if rule, ok := a.cfg.Users[id]; ok && id != "" {
+			return Grant(rule), true
+		}
+	}
+	var grant Grant
+	found := false
+	for _, group := range user.Groups {
+		if rule, ok := a.cfg.Groups[group]; ok {
+			if !found {
+				grant, found = Grant{Role: rule.Role, Teams: slices.Clone(rule.Teams)}, true
+				continue
+			}
+			grant = grant.merge(Grant(rule))
+		}
+	}
+	if found {
+		return grant, true
+	}
+	if a.cfg.DefaultRole != "" {
+		return Grant{Role: a.cfg.DefaultRole}, true
+	}
+	return Grant{}, false
+}
+
+// key returns the named API key matching token, with its grant
+func (a *Access) key(token string) (User, Grant, bool) {
+	if a == nil || token == "" {
+		return User{}, Grant{}, false
+	}
+	for name, key := range a.cfg.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(key.Key)) == 1 {
+			return User{Subject: "api-key:" + name, Name: name}, Grant(key.AccessGrant), true
+		}
+	}
+	return User{}, Grant{}, false
+}
+
+// hasKeys reports whether named API keys are configured, which require authentication
+func (a *Access) hasKeys() bool {
+	return a != nil && len(a.cfg.APIKeys) > 0
+}
+
+type grantKey struct{}
+
+// withCaller stores the authenticated user and their grant in the request
+func withCaller(r *http.Request, user User, grant Grant) *http.Request {
+	return r.WithContext(contextWithCaller(r.Context(), user, grant))
+}
+
+func contextWithCaller(ctx context.Context, user User, grant Grant) context.Context {
+	ctx = context.WithValue(ctx, userKey{}, user)
+	return context.WithValue(ctx, grantKey{}, grant)
+}
+
+// GrantFrom returns the caller's grant; requests that passed no authentication, because none
+// is configured or the endpoint is public, are admins
+func GrantFrom(r *http.Request) Grant {
+	return grantFromContext(r.Context())
+}
+
+func grantFromContext(ctx context.Context) Grant {
+	if grant, ok := ctx.Value(grantKey{}).(Grant); ok {
+		return grant
+	}
+	return adminGrant
+}
+
+// authorize requires role on the handler
+func authorize(role string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !GrantFrom(r).Allows(role) {
+			http.Error(w, "forbidden: requires the "+role+" role", http.StatusForbidden)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// authorizeService requires role over the {service} of the path
+func authorizeService(role string, h http.HandlerFunc) http.HandlerFunc {
+	return authorize(role, func(w http.ResponseWriter, r *http.Request) {
+		if !GrantFrom(r).Sees(r.PathValue("service")) {
+			http.Error(w, "forbidden: service not in your teams", http.StatusForbidden)
+			return
+		}
+		h(w, r)
+	})
+}
+
+// authorizeAll requires role over every service, for endpoints spanning all teams
+func authorizeAll(role string, h http.HandlerFunc) http.HandlerFunc {
+	return authorize(role, func(w http.ResponseWriter, r *http.Request) {
+		if !GrantFrom(r).Unscoped() {
+			http.Error(w, "forbidden: requires access to every team", http.StatusForbidden)
+			return
+		}
+		h(w, r)
+	})
+}
+
+// visibleRisks keeps the items of the services the grant sees
+func visibleRisks(items []APIRiskItem, grant Grant) []APIRiskItem {
+	if grant.Unscoped() {
+		return items
+	}
+	visible := make([]APIRiskItem, 0, len(items))
+	for _, item := range items {
+		if grant.Sees(item.Service) {
+			visible = append(visible, item)
+		}
+	}
+	return visible
+}
+
+// seesAny reports whether the grant sees one of the services
+func seesAny(grant Grant, services []string) bool {
+	if grant.Unscoped() {
+		return true
+	}
+	for _, service := range services {
+		if grant.Sees(service) {
+			return true
+		}
+	}
+	return false
+}
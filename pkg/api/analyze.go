@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// analyzeTimeout bounds how long a request waits for its analysis: the running cycle, the
+// next one and the LLM call
+const analyzeTimeout = 3 * time.Minute
+
+// ErrNoActiveRisk is returned by an analyzer for a service without an alert to analyze
+var ErrNoActiveRisk = errors.New("no active risk")
+
+var analyzer func(ctx context.Context, service string) ([]APIRiskItem, error)
+
+// SetAnalyzer enables POST /api/risks/{service}/analyze; analyze runs a fresh analysis of the
+// service and returns its risk items once published
+func SetAnalyzer(analyze func(ctx context.Context, service string) ([]APIRiskItem, error)) {
+	analyzer = analyze
+}
+
+// handleRiskAnalyze serves POST /api/risks/{service}/analyze: the service's data is fetched
+// again and analyzed by the LLM regardless of change detection and the analysis cache
+func handleRiskAnalyze(w http.ResponseWriter, r *http.Request) {
+	if analyzer == nil {
+		http.Error(w, "LLM analysis is disabled", http.StatusNotFound)
+		return
+	}
+
+	service := r.PathValue("service")
+	if monitorPauses != nil {
+		if _, paused := monitorPauses.Paused(service); paused {
+			http.Error(w, fmt.Sprintf("monitoring of %s is paused", service), http.StatusConflict)
+			return
+		}
+	}
+	active := false
+	riskMu.RLock()
+	for _, item := range currentAPIRisks {
+		if item.Service == service && item.State != "silenced" && item.State != "inhibited" && item.State != "paused" && item.State != "recovering" {
+			active = true
+		}
+	}
+	riskMu.RUnlock()
+	if !active {
+		http.Error(w, fmt.Sprintf("no active risk for service %s", service), http.StatusNotFound)
+		return
+	}
+
+	log.Printf("%s requested an analysis of %s", callerName(r), service)
+	ctx, cancel := context.WithTimeout(r.Context(), analyzeTimeout)
+	defer cancel()
+	items, err := analyzer(ctx, service)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		http.Error(w, "analysis did not finish in time, the result will still be published", http.StatusGatewayTimeout)
+		return
+	case errors.Is(err, ErrNoActiveRisk):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
@@ -5,32 +5,246 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+
+	"vigilant/pkg/audit"
+	"vigilant/pkg/digest"
+	"vigilant/pkg/feedback"
+	"vigilant/pkg/history"
+	"vigilant/pkg/llmcache"
+	"vigilant/pkg/metrichistory"
+	"vigilant/pkg/prometheus"
+	"vigilant/pkg/selfmetrics"
+	"vigilant/pkg/report"
+	"vigilant/pkg/risk"
+	"vigilant/pkg/riskhistory"
+	"vigilant/pkg/topology"
+	"vigilant/pkg/tracing"
 )
 
 type APIMetric struct {
-	Name      string  `json:"name"`
-	Value     float64 `json:"value"`
-	Operator  string  `json:"operator"`
-	Threshold float64 `json:"threshold"`
+	Name      string            `json:"name"`
+	Value     float64           `json:"value"`
+	Operator  string            `json:"operator"`
+	Threshold float64           `json:"threshold"`
+	Weight    int               `json:"weight,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`    // Worst offending series
+	Offenders int               `json:"offenders,omitempty"` // Series that triggered
+	Series    int               `json:"series,omitempty"`    // Series the query returned
+	Baseline  *APIBaseline      `json:"baseline,omitempty"`  // What an anomaly check compared with
+}
+
+type APIBaseline struct {
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stddev"`
+	ZScore float64 `json:"z_score"`
 }
 
 type APISymptom struct {
-	Pattern string `json:"pattern"`
-	Count   int    `json:"count"`
+	Pattern  string `json:"pattern"`
+	Severity string   `json:"severity,omitempty"`
+	Count    int      `json:"count"`
+	Samples  []string `json:"samples,omitempty"`
+}
+
+type APIRunbook struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// APIAck is an operator's acknowledgement or snooze of a service's incident
+type APIAck struct {
+	By      string `json:"by"`
+	Comment string `json:"comment,omitempty"`
+	At      string `json:"at"`              // RFC3339
+	Until   string `json:"until,omitempty"` // End of a snooze (RFC3339)
+}
+
+// NewAPIAck converts a tracker acknowledgement for the payload
+func NewAPIAck(a risk.Ack) *APIAck {
+	ack := &APIAck{By: a.By, Comment: a.Comment, At: a.At.Format(time.RFC3339)}
+	if a.Snoozed() {
+		ack.Until = a.Until.Format(time.RFC3339)
+	}
+	return ack
+}
+
+// APIIncident is an incident with the current risk items of its alerts
+type APIIncident struct {
+	ID         string        `json:"id"`
+	Service    string        `json:"service"`
+	Services   []string      `json:"services"`
+	Alerts     []string      `json:"alerts"`                // service/alert name of every alert seen
+	Status     string        `json:"status"`                // "open" or "resolved"
+	OpenedAt   string        `json:"opened_at"`             // RFC3339
+	UpdatedAt  string        `json:"updated_at"`            // RFC3339
+	ResolvedAt string        `json:"resolved_at,omitempty"` // RFC3339
+	Risks      []APIRiskItem `json:"risks"`
+}
+
+// newAPIIncident converts an incident for the payload, with the risk items carrying its ID;
+// the caller holds riskMu
+func newAPIIncident(inc risk.Incident) APIIncident {
+	item := APIIncident{
+		ID:        inc.ID,
+		Service:   inc.Service,
+		Services:  inc.Services,
+		Alerts:    inc.Alerts,
+		Status:    inc.Status,
+		OpenedAt:  inc.OpenedAt.Format(time.RFC3339),
+		UpdatedAt: inc.UpdatedAt.Format(time.RFC3339),
+		Risks:     []APIRiskItem{},
+	}
+	if !inc.ResolvedAt.IsZero() {
+		item.ResolvedAt = inc.ResolvedAt.Format(time.RFC3339)
+	}
+	for _, r := range currentAPIRisks {
+		if r.IncidentID == inc.ID {
+			item.Risks = append(item.Risks, r)
+		}
+	}
+	return item
+}
+
+// APIBurnRate is an SLO's error budget burn over one window
+type APIBurnRate struct {
+	Window      string  `json:"window"`       // e.g. "1h"
+	ErrorRatio  float64 `json:"error_ratio"`
+	BurnRate    float64 `json:"burn_rate"`
+	BudgetSpent float64 `json:"budget_spent"` // Share of the period's budget burned within the window
+}
+
+type APISLO struct {
+	Name      string        `json:"name"`
+	Target    float64       `json:"target"`
+	Window    string        `json:"window"` // Budget period, e.g. "30d"
+	BurnRates []APIBurnRate `json:"burn_rates"`
+	Fast      bool          `json:"fast"` // Every window burns at least the fast burn rate
+}
+
+// APIDependencies places a service among the other alerting services
+type APIDependencies struct {
+	DependsOn        []string `json:"depends_on,omitempty"`
+	ConsumedBy       []string `json:"consumed_by,omitempty"`
+	Group            []string `json:"group,omitempty"`             // Alerting services linked through dependencies
+	LikelyCulprit    string   `json:"likely_culprit,omitempty"`    // Most upstream alerting service of the group
+	FiringUpstream   []string `json:"firing_upstream,omitempty"`   // Alerting services this one depends on
+	FiringDownstream []string `json:"firing_downstream,omitempty"` // Alerting services depending on this one
+	BlastRadius      []string `json:"blast_radius,omitempty"`      // Every service depending on this one
+}
+
+// APICrossService names the services failing the same way as this one, analyzed together
+type APICrossService struct {
+	Services []string `json:"services"`
+	Patterns []string `json:"patterns,omitempty"` // Log patterns the services share
+	Targets  []string `json:"targets,omitempty"`  // Hosts the services' logs fail to reach
+}
+
+// APITraces summarizes the recent error and slow traces of the service
+type APITraces struct {
+	ErrorTraces int      `json:"error_traces"`
+	SlowTraces  int      `json:"slow_traces"`
+	FailingSpan string   `json:"failing_span,omitempty"` // Deepest failing span most common across the error traces
+	SlowSpan    string   `json:"slow_span,omitempty"`    // Span with the most self time across the slow traces
+	Downstream  string   `json:"downstream,omitempty"`   // Dependency the traces point at
+	TraceIDs    []string `json:"trace_ids,omitempty"`
+}
+
+// APITopology places the service in the mesh traffic
+type APITopology struct {
+	Role                string   `json:"role,omitempty"` // "victim" or "origin"; empty when the mesh shows no errors around it
+	InboundRequestRate  float64  `json:"inbound_request_rate"`
+	InboundErrorRate    float64  `json:"inbound_error_rate"` // 0 to 1
+	OutboundRequestRate float64  `json:"outbound_request_rate"`
+	OutboundErrorRate   float64  `json:"outbound_error_rate"`
+	FailingDownstream   string   `json:"failing_downstream,omitempty"`
+	DownstreamErrorRate float64  `json:"downstream_error_rate,omitempty"`
+	ErrorCallers        []string `json:"error_callers,omitempty"`
+}
+
+// NewAPITopology converts a mesh position for the payload; nil without mesh traffic
+func NewAPITopology(p *topology.Position) *APITopology {
+	if p == nil || p.Empty() {
+		return nil
+	}
+	return &APITopology{
+		Role:                p.Role,
+		InboundRequestRate:  p.InboundRequestRate,
+		InboundErrorRate:    p.InboundErrorRate,
+		OutboundRequestRate: p.OutboundRequestRate,
+		OutboundErrorRate:   p.OutboundErrorRate,
+		FailingDownstream:   p.FailingDownstream,
+		DownstreamErrorRate: p.DownstreamErrorRate,
+		ErrorCallers:        p.ErrorCallers,
+	}
+}
+
+// NewAPITraces converts a trace summary for the payload; nil without one
+func NewAPITraces(s *tracing.Summary) *APITraces {
+	if s == nil {
+		return nil
+	}
+	traces := &APITraces{
+		ErrorTraces: s.ErrorTraces,
+		SlowTraces:  s.SlowTraces,
+		Downstream:  s.Downstream(),
+		TraceIDs:    s.TraceIDs,
+	}
+	if s.FailingSpan != nil {
+		traces.FailingSpan = s.FailingSpan.String()
+	}
+	if s.SlowSpan != nil {
+		traces.SlowSpan = s.SlowSpan.String()
+	}
+	return traces
+}
+
+// NewAPIDependencies converts a dependency context for the payload; nil without dependencies
+func NewAPIDependencies(c risk.DependencyContext) *APIDependencies {
+	if c.Empty() {
+		return nil
+	}
+	return &APIDependencies{
+		DependsOn:        c.DependsOn,
+		ConsumedBy:       c.ConsumedBy,
+		Group:            c.Group,
+		LikelyCulprit:    c.LikelyCulprit,
+		FiringUpstream:   c.FiringUpstream,
+		FiringDownstream: c.FiringDownstream,
+		BlastRadius:      c.BlastRadius,
+	}
 }
 
 type APIRiskItem struct {
+	IncidentID       string       `json:"incident_id,omitempty"`
 	Service          string       `json:"service"`
 	Alert            string       `json:"alert"`
 	Severity         string       `json:"severity"`
+	State            string       `json:"state,omitempty"` // "pending" before the alert fires, "silenced" or "inhibited" while muted, "paused" while monitoring of the service is paused, "recovering" while the score winds down
+	SilencedBy       string       `json:"silenced_by,omitempty"` // ID of the silence or maintenance window
+	InhibitedBy      string       `json:"inhibited_by,omitempty"` // Source alert of the inhibit rule muting this one
+	Ack              *APIAck      `json:"ack,omitempty"`          // Set while the incident is acknowledged or snoozed
+	EscalatedAt      string       `json:"escalated_at,omitempty"` // When the service was escalated for staying at high or critical risk unacknowledged (RFC3339)
+	Dependencies     *APIDependencies `json:"dependencies,omitempty"` // Set when the profile declares dependencies
+	CrossService     *APICrossService `json:"cross_service,omitempty"` // Set when other services fail the same way this cycle
 	Score            int          `json:"score"`
+	HealthScore      int          `json:"health_score"` // Deterministic score from alerts, symptoms, metrics and SLOs, without the LLM
+	Trend            string       `json:"trend,omitempty"` // "improving", "worsening" or "stable" over the recent scores
+	StartsAt         string       `json:"starts_at,omitempty"` // When the oldest alert of the service started (RFC3339)
 	Symptoms         []APISymptom `json:"symptoms"`
 	Metrics          []APIMetric  `json:"metrics"`
+	SLOs             []APISLO     `json:"slos,omitempty"`
+	Traces           *APITraces   `json:"traces,omitempty"` // Set for latency and error alerts when tracing is configured
+	Topology         *APITopology `json:"topology,omitempty"` // Set when the service has traffic in the mesh graph
 	Summary          string       `json:"summary"`
 	Risk             string       `json:"risk"`
 	Confidence       float64      `json:"confidence"`
@@ -38,25 +252,66 @@ type APIRiskItem struct {
 	ImmediateActions []string     `json:"immediate_actions"`
 	Investigation    []string     `json:"investigation_steps"`
 	Prevention       string       `json:"prevention"`
+	Runbooks         []APIRunbook `json:"runbooks"`
 	Timestamp        string       `json:"timestamp"`
 }
 
+// WebSocketMessage carries every risk item: risks_update, or risks_snapshot with its Seq in delta mode
 type WebSocketMessage struct {
 	Type string        `json:"type"`
+	Seq  uint64        `json:"seq,omitempty"`
 	Data []APIRiskItem `json:"data"`
 }
 
+// RiskDeltaMessage is sent instead of risks_update to clients in delta mode (?mode=delta): the
+// items added, changed and removed by update Seq. A client missing a Seq resyncs.
+type RiskDeltaMessage struct {
+	Type    string        `json:"type"` // risks_delta
+	Seq     uint64        `json:"seq"`
+	Added   []APIRiskItem `json:"added"`
+	Updated []APIRiskItem `json:"updated"`
+	Removed []RiskKey     `json:"removed"`
+}
+
+// RiskKey identifies a risk item across updates
+type RiskKey struct {
+	Service string `json:"service"`
+	Alert   string `json:"alert"`
+}
+
+func keyOf(item APIRiskItem) RiskKey {
+	return RiskKey{Service: item.Service, Alert: item.Alert}
+}
+
+// hubMessage is a WebSocketMessage or a RiskDeltaMessage
+type hubMessage interface {
+	event() (name string, seq uint64)
+}
+
+func (m WebSocketMessage) event() (string, uint64) { return m.Type, m.Seq }
+func (m RiskDeltaMessage) event() (string, uint64) { return m.Type, m.Seq }
+
+// riskBroadcast is an update for the hub's clients, in full and as a delta
+type riskBroadcast struct {
+	risks []APIRiskItem
+	delta RiskDeltaMessage
+}
+
+// WebSocketClient is a subscriber of the hub: a WebSocket, or an event stream when conn is nil
 type WebSocketClient struct {
 	conn   *websocket.Conn
-	send   chan WebSocketMessage
+	send   chan hubMessage
 	hub    *WebSocketHub
+	grant  Grant // Services the client is sent
+	delta  bool  // Sent a snapshot, then deltas
 }
 
 type WebSocketHub struct {
 	clients    map[*WebSocketClient]bool
-	broadcast  chan WebSocketMessage
+	broadcast  chan riskBroadcast
 	register   chan *WebSocketClient
 	unregister chan *WebSocketClient
+	resync     chan *WebSocketClient
 	mu         sync.RWMutex
 	stop       chan struct{} 
 }
@@ -64,7 +319,9 @@ type WebSocketHub struct {
 
 var (
 	currentAPIRisks []APIRiskItem
+	riskSeq         uint64 // Number of the last update, for delta clients
 	riskMu          sync.RWMutex
+	updateMu        sync.Mutex // Keeps updates, and so their deltas, in order
 	wsHub          *WebSocketHub
 	upgrader       = websocket.Upgrader{
 		CheckOrigin: func(r *http.Request) bool {
@@ -75,14 +332,96 @@ var (
 	}
 )
 
-var server *http.Server
+var server, dashboardServer *http.Server
+
+// Optional backends for the extended endpoints, wired up by main
+var (
+	auditLog        *audit.Log
+	incidentHistory *history.Store
+	digests         *digest.Scheduler
+	feedbackStore   *feedback.Store
+	llmCache        *llmcache.LLMCache
+	alertReceiver   *webhookReceiver
+	metricHistory   *metrichistory.Store
+	riskHistory     *riskhistory.Store
+	trendWindow     time.Duration
+	silences        *prometheus.SilenceStore
+	riskTracker     *risk.RiskTracker
+	incidents       *risk.Incidents
+)
+
+// webhookReceiver feeds alerts pushed by Alertmanager into the risk tracker
+type webhookReceiver struct {
+	tracker *risk.RiskTracker
+	resolve prometheus.ServiceResolver
+	ttl     time.Duration
+	token   string
+}
+
+// SetLLMCache enables the cache inspection and control endpoints
+func SetLLMCache(c *llmcache.LLMCache) {
+	llmCache = c
+}
+
+// SetFeedbackStore enables the operator feedback endpoint
+func SetFeedbackStore(store *feedback.Store) {
+	feedbackStore = store
+}
+
+// SetDigestScheduler enables the digest endpoint
+func SetDigestScheduler(s *digest.Scheduler) {
+	digests = s
+}
+
+// SetIncidentHistory enables the incident endpoints
+func SetIncidentHistory(store *history.Store) {
+	incidentHistory = store
+}
+
+// SetAlertReceiver enables the Alertmanager webhook endpoint. Pushed alerts without an endsAt
+// are tracked for ttl; a non-empty token must be sent as a bearer token.
+func SetAlertReceiver(tracker *risk.RiskTracker, resolve prometheus.ServiceResolver, ttl time.Duration, token string) {
+	alertReceiver = &webhookReceiver{tracker: tracker, resolve: resolve, ttl: ttl, token: token}
+}
+
+// SetMetricHistory enables the metric history endpoint
+func SetMetricHistory(store *metrichistory.Store) {
+	metricHistory = store
+}
+
+// SetRiskHistory enables the risk history endpoint; trends compare scores across window
+func SetRiskHistory(store *riskhistory.Store, window time.Duration) {
+	riskHistory = store
+	trendWindow = window
+}
+
+// SetRiskTracker enables the acknowledge and snooze endpoints
+func SetRiskTracker(tracker *risk.RiskTracker) {
+	riskTracker = tracker
+}
+
+// SetIncidents enables the incident list and detail endpoints
+func SetIncidents(i *risk.Incidents) {
+	incidents = i
+}
+
+// SetSilences enables the silence and maintenance window endpoints
+func SetSilences(store *prometheus.SilenceStore) {
+	silences = store
+}
+
+// SetAuditLog enables the LLM audit query endpoint
+func SetAuditLog(log *audit.Log) {
+	auditLog = log
+}
 
 func NewWebSocketHub() *WebSocketHub {
 	return &WebSocketHub{
 		clients:    make(map[*WebSocketClient]bool),
-		broadcast:  make(chan WebSocketMessage),
+		broadcast:  make(chan riskBroadcast),
 		register:   make(chan *WebSocketClient),
 		unregister: make(chan *WebSocketClient),
+		resync:     make(chan *WebSocketClient),
 		stop:       make(chan struct{}), 
 	}
 }
@@ -101,16 +440,11 @@ func (h *WebSocketHub) Run() {
 			log.Printf("📡 WebSocket client connected (total: %d)", len(h.clients))
 			
 			// Send current data to new client
-			riskMu.RLock()
-			currentData := make([]APIRiskItem, len(currentAPIRisks))
-			copy(currentData, currentAPIRisks)
-			riskMu.RUnlock()
-			
-			select {
-			case client.send <- WebSocketMessage{Type: "risks_update", Data: currentData}:
-			default:
-				close(client.send)
-				delete(h.clients, client)
+			h.sendCurrent(client)
+
+		case client := <-h.resync:
+			if h.clients[client] {
+				h.sendCurrent(client)
 			}
 
 		case client := <-h.unregister:
@@ -122,9 +456,13 @@ func (h *WebSocketHub) Run() {
 			}
 			h.mu.Unlock()
 
-		case message := <-h.broadcast:
+		case update := <-h.broadcast:
 			h.mu.RLock()
 			for client := range h.clients {
+				var message hubMessage = WebSocketMessage{Type: "risks_update", Data: visibleRisks(update.risks, client.grant)}
+				if client.delta {
+					message = filterDelta(update.delta, client.grant)
+				}
 				select {
 				case client.send <- message:
 				default:
@@ -134,6 +472,27 @@ func (h *WebSocketHub) Run() {
 			}
 			h.mu.RUnlock()
 		}
+		selfmetrics.WebSocketClients.Set(float64(len(h.clients)))
+	}
+}
+
+// sendCurrent sends the current risk items to a client, as a snapshot in delta mode
+func (h *WebSocketHub) sendCurrent(client *WebSocketClient) {
+	riskMu.RLock()
+	message := WebSocketMessage{Type: "risks_update", Data: visibleRisks(currentAPIRisks, client.grant)}
+	if client.delta {
+		message.Type, message.Seq = "risks_snapshot", riskSeq
+	}
+	message.Data = slices.Clone(message.Data)
+	riskMu.RUnlock()
+
+	select {
+	case client.send <- message:
+	default:
+		h.mu.Lock()
+		close(client.send)
+		delete(h.clients, client)
+		h.mu.Unlock()
 	}
 }
 
@@ -185,13 +544,20 @@ func (c *WebSocketClient) readPump() {
 	})
 
 	for {
-		_, _, err := c.conn.ReadMessage()
+		_, data, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("WebSocket read error: %v", err)
 			}
 			break
 		}
+		// A delta client that missed an update asks for a new snapshot
+		var request struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal(data, &request) == nil && request.Type == "resync" && c.delta {
+			c.hub.resync <- c
+		}
 	}
 }
 
@@ -205,9 +571,11 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("WebSocket connection established with %s", r.RemoteAddr)
 	client := &WebSocketClient{
-		conn: conn,
-		send: make(chan WebSocketMessage, 256),
-		hub:  wsHub,
+		conn:  conn,
+		send:  make(chan hubMessage, 256),
+		hub:   wsHub,
+		grant: GrantFrom(r),
+		delta: r.URL.Query().Get("mode") == "delta",
 	}
 
 	client.hub.register <- client
@@ -216,39 +584,243 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	go client.readPump()
 }
 
-func StartServer() *http.Server {
+// handleEventStream serves GET /api/stream, the WebSocket's messages as Server-Sent Events for
+// networks whose proxies block WebSockets. Each event is named after the message type and
+// carries the message as JSON.
+func handleEventStream(w http.ResponseWriter, r *http.Request) {
+	if wsHub == nil {
+		http.Error(w, "event stream is not available", http.StatusServiceUnavailable)
+		return
+	}
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Keep nginx from buffering the stream
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 5000\n\n")
+	if err := rc.Flush(); err != nil {
+		log.Printf("Event stream unsupported for %s: %v", r.RemoteAddr, err)
+		return
+	}
+
+	client := &WebSocketClient{
+		send:  make(chan hubMessage, 256),
+		hub:   wsHub,
+		grant: GrantFrom(r),
+		delta: r.URL.Query().Get("mode") == "delta",
+	}
+	wsHub.register <- client
+	defer func() { wsHub.unregister <- client }()
+
+	// Comments keep proxies from closing an idle stream
+	keepalive := time.NewTicker(30 * time.Second)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case message, ok := <-client.send:
+			if !ok {
+				return // Dropped by the hub for falling behind
+			}
+			data, err := json.Marshal(message)
+			if err != nil {
+				log.Printf("Event stream encode error: %v", err)
+				continue
+			}
+			name, seq := message.event()
+			if seq > 0 {
+				fmt.Fprintf(w, "id: %d\n", seq)
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// ServerConfig sets where the server listens
+type ServerConfig struct {
+	Addr string // API listener, e.g. ":8090" or "127.0.0.1:8090"
+	// DashboardAddr serves the dashboard, with the read-only endpoints it uses, on a listener
+	// of its own; the API listener then serves the API alone. Empty serves both on Addr.
+	DashboardAddr string
+	GRPCAddr      string // gRPC API listener, e.g. ":9090"; empty disables it
+	// Dashboard serves the dashboard's files (see dashboard.FileSystem); nil serves ./dashboard/dist
+	Dashboard http.FileSystem
+}
+
+func (cfg ServerConfig) dashboardFiles() http.FileSystem {
+	if cfg.Dashboard == nil {
+		return http.Dir("./dashboard/dist")
+	}
+	return cfg.Dashboard
+}
+
+func StartServer(cfg ServerConfig) *http.Server {
 	// Initialize WebSocket hub
 	wsHub = NewWebSocketHub()
 	go wsHub.Run()
 
+	if cfg.GRPCAddr != "" {
+		var err error
+		if grpcServer, err = startGRPC(cfg.GRPCAddr); err != nil {
+			log.Printf("gRPC API disabled: %v", err)
+		} else {
+			fmt.Println("🚀 gRPC API running at:", cfg.GRPCAddr)
+		}
+	}
+
 	// Create dedicated mux for better control
 	mux := http.NewServeMux()
+	registerDashboardRoutes(mux)
+	registerAPIRoutes(mux)
+
+	if cfg.DashboardAddr == "" {
+		// Frontend handler
+		mux.Handle("/", http.FileServer(cfg.dashboardFiles()))
+		server = listen(cfg.Addr, mux)
+		fmt.Printf("🚀 API server running at: %s\n", displayURL("http", cfg.Addr, ""))
+		fmt.Println("   - Dashboard:", displayURL("http", cfg.Addr, ""))
+		fmt.Println("   - WebSocket:", displayURL("ws", cfg.Addr, "/ws"))
+		fmt.Println("   - REST API: ", displayURL("http", cfg.Addr, "/api/v1/risks"))
+		fmt.Println("   - Metrics:  ", displayURL("http", cfg.Addr, "/metrics"))
+		return server
+	}
+
+	dashboardMux := http.NewServeMux()
+	registerDashboardRoutes(dashboardMux)
+	dashboardMux.Handle("/", http.FileServer(cfg.dashboardFiles()))
+	server = listen(cfg.Addr, mux)
+	dashboardServer = listen(cfg.DashboardAddr, dashboardMux)
+	fmt.Printf("🚀 API server running at: %s\n", displayURL("http", cfg.Addr, ""))
+	fmt.Println("   - REST API: ", displayURL("http", cfg.Addr, "/api/v1/risks"))
+	fmt.Println("   - WebSocket:", displayURL("ws", cfg.Addr, "/ws"))
+	fmt.Println("   - Metrics:  ", displayURL("http", cfg.Addr, "/metrics"))
+	fmt.Println("🚀 Dashboard running at:", displayURL("http", cfg.DashboardAddr, ""))
+	return server
+}
 
+// registerDashboardRoutes adds the endpoints the dashboard reads, which its own listener serves too
+func registerDashboardRoutes(mux *http.ServeMux) {
 	// WebSocket endpoint
-	mux.HandleFunc("/ws", handleWebSocket)
-	
+	mux.HandleFunc("/ws", authorize("viewer", handleWebSocket))
+
+	// The same updates as Server-Sent Events, where proxies block WebSockets
+	handleAPI(mux, "GET /api/stream", authorize("viewer", handleEventStream))
+
 	// REST API endpoint
-	mux.HandleFunc("/api/risks", func(w http.ResponseWriter, r *http.Request) {
+	handleAPI(mux, "/api/risks", authorize("viewer", compress(func(w http.ResponseWriter, r *http.Request) {
 		riskMu.RLock()
 		defer riskMu.RUnlock()
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(currentAPIRisks)
+		json.NewEncoder(w).Encode(visibleRisks(currentAPIRisks, GrantFrom(r)))
+	})))
+
+	// Recorded metric values per service, for charting against thresholds
+	handleAPI(mux, "GET /api/risks/{service}/metrics/history", authorizeService("viewer", compress(handleMetricHistory)))
+
+	// Recorded scores and risk levels per service, for sparklines
+	handleAPI(mux, "GET /api/risks/{service}/history", authorizeService("viewer", compress(handleRiskHistory)))
+
+	// The current analysis of a service as a Markdown report to paste into tickets
+	handleAPI(mux, "GET /api/risks/{service}/report.md", authorizeService("viewer", handleRiskReport))
+
+	// Risks, incidents, symptoms and history in one query, with the fields the client asks for
+	handleAPI(mux, "POST /api/graphql", authorize("viewer", compress(graphqlHandler.ServeHTTP)))
+
+	// SSO login with the company identity provider, and who is logged in
+	mux.HandleFunc("GET /auth/login", handleLogin)
+	mux.HandleFunc("GET /auth/callback", handleCallback)
+	mux.HandleFunc("GET /auth/logout", handleLogout)
+	handleAPI(mux, "GET /api/me", handleMe)
+
+	// Liveness, optionally served without credentials (API_PUBLIC_HEALTHZ)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 	})
+}
+
+// registerAPIRoutes adds the rest of the API
+func registerAPIRoutes(mux *http.ServeMux) {
+	// LLM audit trail; its prompts and responses carry raw log lines and metric data
+	handleAPI(mux, "GET /api/audit", authorize("admin", handleAuditQuery))
+
+	// Incidents
+	handleAPI(mux, "GET /api/incidents", authorize("viewer", compress(handleIncidents)))
+	handleAPI(mux, "GET /api/incidents/{id}", authorize("viewer", compress(handleIncident)))
+	handleAPI(mux, "GET /api/incidents/history", authorize("viewer", compress(handleIncidentHistory)))
+	handleAPI(mux, "GET /api/incidents/export", authorize("viewer", compress(handleIncidentExport)))
+	handleAPI(mux, "GET /api/incidents/{id}/postmortem", authorize("viewer", handleIncidentPostmortem))
+
+	// Operator feedback on analyses
+	handleAPI(mux, "POST /api/risks/{service}/feedback", authorizeService("operator", handleRiskFeedback))
 
-	// Frontend handler
-	mux.Handle("/", http.FileServer(http.Dir("./dashboard/dist")))
+	// A fresh analysis of a service on demand, bypassing change detection and the LLM cache
+	handleAPI(mux, "POST /api/risks/{service}/analyze", authorizeService("operator", handleRiskAnalyze))
 
-	server = &http.Server{
-		Addr:    ":8090",
-		Handler: mux,
+	// Acknowledging and snoozing incidents
+	handleAPI(mux, "POST /api/risks/{service}/ack", authorizeService("operator", handleRiskAck))
+	handleAPI(mux, "DELETE /api/risks/{service}/ack", authorizeService("operator", handleRiskUnack))
+	handleAPI(mux, "POST /api/risks/{service}/snooze", authorizeService("operator", handleRiskSnooze))
+
+	// Push-based alerting from Alertmanager
+	handleAPI(mux, "POST /api/webhooks/alertmanager", authorizeAll("operator", handleAlertmanagerWebhook))
+
+	// Alertmanager silences and maintenance windows that suppress analysis of matching alerts;
+	// maintenance windows can match any service, so they need access to every team
+	handleAPI(mux, "GET /api/silences", authorize("viewer", handleSilences))
+	handleAPI(mux, "POST /api/silences", authorizeAll("operator", handleCreateMaintenance))
+	handleAPI(mux, "DELETE /api/silences/{id}", authorizeAll("operator", handleDeleteMaintenance))
+
+	// Health of the data sources, e.g. a Prometheus endpoint skipped after repeated failures
+	handleAPI(mux, "GET /api/sources", authorize("viewer", handleSources))
+
+	// Self-metrics for monitoring Vigilant itself
+	mux.Handle("GET /metrics", authorizeAll("viewer", selfmetrics.Handler().ServeHTTP))
+
+	// LLM cache inspection and control
+	handleAPI(mux, "GET /api/cache/stats", authorizeAll("viewer", handleCacheStats))
+	handleAPI(mux, "POST /api/cache/clear", authorize("admin", handleCacheClear))
+	handleAPI(mux, "DELETE /api/cache/{hash}", authorize("admin", handleCacheInvalidate))
+
+	// Daily/weekly digests
+	handleAPI(mux, "GET /api/digest", authorizeAll("viewer", handleDigest))
+
+	// Editing the service profiles in config/services
+	registerProfileRoutes(mux)
+	registerInjectRoutes(mux)
+	registerControlRoutes(mux)
+	registerTicketRoutes(mux)
+}
+
+// listen serves handler on addr in the background behind the authentication middleware
+func listen(addr string, handler http.Handler) *http.Server {
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: requireAuth(handler),
 	}
-	
-	fmt.Println("🚀 API server running at: http://localhost:8090")
-	fmt.Println("   - Dashboard: http://localhost:8090")
-	fmt.Println("   - WebSocket: ws://localhost:8090/ws") 
-	fmt.Println("   - REST API:  http://localhost:8090/api/risks")
-	go server.ListenAndServe()
-	return server
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("Server on %s failed: %v", addr, err)
+		}
+	}()
+	return srv
+}
+
+// displayURL turns a listen address into a URL to print, e.g. ":8090" into http://localhost:8090
+func displayURL(scheme, addr, path string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return scheme + "://" + addr + path
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return scheme + "://" + net.JoinHostPort(host, port) + path
 }
 
 func StopServer() {
@@ -258,23 +830,681 @@ func StopServer() {
 		server.Shutdown(ctx)
 		fmt.Println("🛑 API server stopped")
 	}
+	if dashboardServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		dashboardServer.Shutdown(ctx)
+		fmt.Println("🛑 Dashboard server stopped")
+	}
+	if grpcServer != nil {
+		// Watch streams never end on their own, so they are cut rather than drained
+		grpcServer.Stop()
+		fmt.Println("🛑 gRPC server stopped")
+	}
 
 	if wsHub != nil {
 		wsHub.Stop()
 	}
 }
 
+// writeJSON encodes v as the JSON response body
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// handleAuditQuery serves GET /api/audit?service=&from=&to=&limit=
+func handleAuditQuery(w http.ResponseWriter, r *http.Request) {
+	if auditLog == nil {
+		http.Error(w, "LLM audit log is disabled", http.StatusNotFound)
+		return
+	}
+
+	q := audit.Query{
+		Service: r.URL.Query().Get("service"),
+		Limit:   100,
+	}
+	if grant := GrantFrom(r); !grant.Unscoped() && (q.Service == "" || !grant.Sees(q.Service)) {
+		http.Error(w, "forbidden: set service to one of your teams' services", http.StatusForbidden)
+		return
+	}
+
+	var err error
+	if v := r.URL.Query().Get("from"); v != "" {
+		if q.From, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "invalid from timestamp, expected RFC3339", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		if q.To, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "invalid to timestamp, expected RFC3339", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit < 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := auditLog.Query(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// handleMetricHistory serves GET /api/risks/{service}/metrics/history?check=&from=
+func handleMetricHistory(w http.ResponseWriter, r *http.Request) {
+	if metricHistory == nil {
+		http.Error(w, "metric history is disabled", http.StatusNotFound)
+		return
+	}
+
+	var from time.Time
+	if v := r.URL.Query().Get("from"); v != "" {
+		var err error
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "invalid from timestamp, expected RFC3339", http.StatusBadRequest)
+			return
+		}
+	}
+
+	service := r.PathValue("service")
+	series := metricHistory.History(service, r.URL.Query().Get("check"), from)
+	if series == nil {
+		series = []metrichistory.Series{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"service": service,
+		"checks":  series,
+	})
+}
+
+// handleRiskHistory serves GET /api/risks/{service}/history?from=
+func handleRiskHistory(w http.ResponseWriter, r *http.Request) {
+	if riskHistory == nil {
+		http.Error(w, "risk history is disabled", http.StatusNotFound)
+		return
+	}
+
+	var from time.Time
+	if v := r.URL.Query().Get("from"); v != "" {
+		var err error
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "invalid from timestamp, expected RFC3339", http.StatusBadRequest)
+			return
+		}
+	}
+
+	service := r.PathValue("service")
+	points := riskHistory.History(service, from)
+	if points == nil {
+		points = []riskhistory.Point{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"service": service,
+		"trend":   riskHistory.Trend(service, trendWindow),
+		"points":  points,
+	})
+}
+
+// handleIncidents serves GET /api/incidents?resolved=true; recently resolved incidents follow
+// the open ones when requested
+func handleIncidents(w http.ResponseWriter, r *http.Request) {
+	if incidents == nil {
+		http.Error(w, "incidents are not available", http.StatusNotFound)
+		return
+	}
+
+	list := incidents.List(r.URL.Query().Get("resolved") == "true")
+	grant := GrantFrom(r)
+	riskMu.RLock()
+	result := make([]APIIncident, 0, len(list))
+	for _, inc := range list {
+		if seesAny(grant, inc.Services) {
+			result = append(result, newAPIIncident(inc))
+		}
+	}
+	riskMu.RUnlock()
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleIncidentHistory serves GET /api/incidents/history?from=&to=&service=&severity=&risk=&cursor=&limit=
+// from the incident history, newest first; severity and risk take comma-separated values
+func handleIncidentHistory(w http.ResponseWriter, r *http.Request) {
+	if incidentHistory == nil {
+		http.Error(w, "incident history is disabled", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	filter, err := incidentFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit := 50
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 500 {
+			http.Error(w, "limit must be between 1 and 500", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	page, next, err := incidentHistory.Page(filter, query.Get("cursor"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if page == nil {
+		page = []history.Summary{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"incidents":   page,
+		"next_cursor": next,
+	})
+}
+
+// incidentFilter reads the from, to, service, severity and risk parameters of an incident
+// history request, limited to the services the caller may see
+func incidentFilter(r *http.Request) (history.Filter, error) {
+	query := r.URL.Query()
+	filter := history.Filter{
+		Service:    query.Get("service"),
+		Severities: splitList(query.Get("severity")),
+		Risks:      splitList(query.Get("risk")),
+	}
+	for param, t := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := query.Get(param); v != "" {
+			var err error
+			if *t, err = time.Parse(time.RFC3339, v); err != nil {
+				return filter, fmt.Errorf("invalid %s timestamp, expected RFC3339", param)
+			}
+		}
+	}
+	if grant := GrantFrom(r); !grant.Unscoped() {
+		filter.Visible = func(services []string) bool { return seesAny(grant, services) }
+	}
+	return filter, nil
+}
+
+// splitList splits a comma-separated query value, nil when empty
+func splitList(v string) []string {
+	var values []string
+	for _, value := range strings.Split(v, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// handleIncident serves GET /api/incidents/{id}
+func handleIncident(w http.ResponseWriter, r *http.Request) {
+	if incidents == nil {
+		http.Error(w, "incidents are not available", http.StatusNotFound)
+		return
+	}
+
+	id := r.PathValue("id")
+	inc, ok := incidents.Get(id)
+	if !ok || !seesAny(GrantFrom(r), inc.Services) {
+		http.Error(w, fmt.Sprintf("incident %s not found", id), http.StatusNotFound)
+		return
+	}
+	riskMu.RLock()
+	result := newAPIIncident(inc)
+	riskMu.RUnlock()
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleIncidentPostmortem serves GET /api/incidents/{id}/postmortem as a Markdown download
+func handleIncidentPostmortem(w http.ResponseWriter, r *http.Request) {
+	if incidentHistory == nil {
+		http.Error(w, "incident history is disabled", http.StatusNotFound)
+		return
+	}
+
+	id := r.PathValue("id")
+	timeline := incidentHistory.Timeline(id)
+	var services []string
+	for _, entry := range timeline {
+		services = append(services, entry.Service)
+	}
+	if len(timeline) == 0 || !seesAny(GrantFrom(r), services) {
+		http.Error(w, fmt.Sprintf("incident %s not found", id), http.StatusNotFound)
+		return
+	}
+
+	doc, err := report.Postmortem(timeline)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", id+"-postmortem.md"))
+	w.Write([]byte(doc))
+}
+
+// handleDigest serves GET /api/digest?period=daily|weekly&refresh=true
+func handleDigest(w http.ResponseWriter, r *http.Request) {
+	if digests == nil {
+		http.Error(w, "incident digests are disabled", http.StatusNotFound)
+		return
+	}
+
+	period, err := digest.ParsePeriod(r.URL.Query().Get("period"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var d digest.Digest
+	if r.URL.Query().Get("refresh") == "true" {
+		d = digests.Refresh(r.Context(), period)
+	} else {
+		d = digests.Latest(r.Context(), period)
+	}
+
+	if r.URL.Query().Get("format") == "markdown" {
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Write([]byte(d.Report))
+		return
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
+// handleSources serves GET /api/sources
+func handleSources(w http.ResponseWriter, r *http.Request) {
+	statuses := prometheus.Status()
+	degraded := false
+	for _, s := range statuses {
+		degraded = degraded || s.Degraded
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"prometheus": map[string]interface{}{
+			"degraded":  degraded,
+			"endpoints": statuses,
+		},
+	})
+}
+
+// handleSilences serves GET /api/silences
+func handleSilences(w http.ResponseWriter, r *http.Request) {
+	if silences == nil {
+		http.Error(w, "silences are disabled", http.StatusNotFound)
+		return
+	}
+	list := silences.List()
+	if list == nil {
+		list = []prometheus.Silence{}
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// MaintenanceRequest is the body of POST /api/silences; the window lasts until endsAt, or
+// for duration (e.g. "2h") from startsAt
+type MaintenanceRequest struct {
+	Matchers  []prometheus.SilenceMatcher `json:"matchers"`
+	StartsAt  time.Time                   `json:"startsAt"`
+	EndsAt    time.Time                   `json:"endsAt"`
+	Duration  string                      `json:"duration"`
+	CreatedBy string                      `json:"createdBy"`
+	Comment   string                      `json:"comment"`
+}
+
+// handleCreateMaintenance serves POST /api/silences
+func handleCreateMaintenance(w http.ResponseWriter, r *http.Request) {
+	if silences == nil {
+		http.Error(w, "silences are disabled", http.StatusNotFound)
+		return
+	}
+
+	var req MaintenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	window := prometheus.Silence{
+		Matchers:  req.Matchers,
+		StartsAt:  req.StartsAt,
+		EndsAt:    req.EndsAt,
+		CreatedBy: req.CreatedBy,
+		Comment:   req.Comment,
+	}
+	if user, ok := UserFrom(r); ok {
+		window.CreatedBy = user.String()
+	}
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			http.Error(w, "invalid duration", http.StatusBadRequest)
+			return
+		}
+		if window.StartsAt.IsZero() {
+			window.StartsAt = time.Now()
+		}
+		window.EndsAt = window.StartsAt.Add(d)
+	}
+
+	window, err := silences.AddMaintenance(window)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("Maintenance window %s created by %q until %s", window.ID, window.CreatedBy, window.EndsAt.Format(time.RFC3339))
+	writeJSON(w, http.StatusCreated, window)
+}
+
+// handleDeleteMaintenance serves DELETE /api/silences/{id}; Alertmanager silences are
+// expired in Alertmanager itself
+func handleDeleteMaintenance(w http.ResponseWriter, r *http.Request) {
+	if silences == nil {
+		http.Error(w, "silences are disabled", http.StatusNotFound)
+		return
+	}
+	id := r.PathValue("id")
+	if !silences.RemoveMaintenance(id) {
+		http.Error(w, fmt.Sprintf("no maintenance window %s", id), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"removed": id})
+}
+
+// handleCacheStats serves GET /api/cache/stats
+func handleCacheStats(w http.ResponseWriter, r *http.Request) {
+	if llmCache == nil {
+		http.Error(w, "LLM cache is not available", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, llmCache.Stats())
+}
+
+// handleCacheClear serves POST /api/cache/clear
+func handleCacheClear(w http.ResponseWriter, r *http.Request) {
+	if llmCache == nil {
+		http.Error(w, "LLM cache is not available", http.StatusNotFound)
+		return
+	}
+	entries := llmCache.Stats().Entries
+	llmCache.Clear()
+	log.Printf("LLM cache cleared via API (%d entries)", entries)
+	writeJSON(w, http.StatusOK, map[string]int{"removed": entries})
+}
+
+// handleCacheInvalidate serves DELETE /api/cache/{hash}; a hash prefix or an incident ID is accepted
+func handleCacheInvalidate(w http.ResponseWriter, r *http.Request) {
+	if llmCache == nil {
+		http.Error(w, "LLM cache is not available", http.StatusNotFound)
+		return
+	}
+	hash := r.PathValue("hash")
+	removed := llmCache.Invalidate(hash)
+	if removed == 0 {
+		http.Error(w, fmt.Sprintf("no cache entry matches %s", hash), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"removed": removed})
+}
+
+// handleAlertmanagerWebhook serves POST /api/webhooks/alertmanager with standard Alertmanager
+// webhook payloads
+func handleAlertmanagerWebhook(w http.ResponseWriter, r *http.Request) {
+	if alertReceiver == nil {
+		http.Error(w, "Alertmanager webhook is disabled", http.StatusNotFound)
+		return
+	}
+	if alertReceiver.token != "" && r.Header.Get("Authorization") != "Bearer "+alertReceiver.token {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	firing, resolved, err := prometheus.ParseAlertmanagerWebhook(r.Body, alertReceiver.resolve)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	alertReceiver.tracker.Receive(firing, resolved, alertReceiver.ttl)
+	selfmetrics.AlertsFetched.WithLabelValues("webhook").Add(float64(len(firing)))
+
+	log.Printf("Alertmanager webhook: %d firing, %d resolved alerts", len(firing), len(resolved))
+	writeJSON(w, http.StatusOK, map[string]int{"firing": len(firing), "resolved": len(resolved)})
+}
+
+// FeedbackRequest is the body of POST /api/risks/{service}/feedback
+type FeedbackRequest struct {
+	Correct *bool  `json:"correct"`
+	Notes   string `json:"notes"`
+	Alert   string `json:"alert,omitempty"` // Required only when the service has several active alerts
+}
+
+// handleRiskFeedback records an operator's verdict on the current analysis of a service
+func handleRiskFeedback(w http.ResponseWriter, r *http.Request) {
+	if feedbackStore == nil {
+		http.Error(w, "feedback is disabled", http.StatusNotFound)
+		return
+	}
+
+	var req FeedbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if req.Correct == nil {
+		http.Error(w, "correct is required", http.StatusBadRequest)
+		return
+	}
+	if !*req.Correct && strings.TrimSpace(req.Notes) == "" {
+		http.Error(w, "notes are required when correct is false", http.StatusBadRequest)
+		return
+	}
+
+	service := r.PathValue("service")
+	var matches []APIRiskItem
+	riskMu.RLock()
+	for _, item := range currentAPIRisks {
+		if item.Service == service && (req.Alert == "" || item.Alert == req.Alert) {
+			matches = append(matches, item)
+		}
+	}
+	riskMu.RUnlock()
+
+	if len(matches) == 0 {
+		http.Error(w, fmt.Sprintf("no active risk for service %s", service), http.StatusNotFound)
+		return
+	}
+	if len(matches) > 1 {
+		http.Error(w, fmt.Sprintf("service %s has %d active alerts, specify alert", service, len(matches)), http.StatusBadRequest)
+		return
+	}
+
+	item := matches[0]
+	user, _ := UserFrom(r)
+	entry, err := feedbackStore.Record(feedback.Entry{
+		Service:    item.Service,
+		AlertName:  item.Alert,
+		IncidentID: item.IncidentID,
+		Correct:    *req.Correct,
+		Notes:      req.Notes,
+		RootCause:  item.RootCause,
+		By:         user.String(),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Recorded feedback for %s/%s (correct=%v)", entry.Service, entry.AlertName, entry.Correct)
+	writeJSON(w, http.StatusCreated, entry)
+}
+
+type AckRequest struct {
+	User     string `json:"user"`
+	Comment  string `json:"comment"`
+	Duration string `json:"duration,omitempty"` // Snooze length, e.g. "30m"; required by /snooze
+}
+
+// handleRiskAck serves POST /api/risks/{service}/ack
+func handleRiskAck(w http.ResponseWriter, r *http.Request) {
+	acknowledge(w, r, false)
+}
+
+// handleRiskSnooze serves POST /api/risks/{service}/snooze
+func handleRiskSnooze(w http.ResponseWriter, r *http.Request) {
+	acknowledge(w, r, true)
+}
+
+// acknowledge records an acknowledgement, or a snooze for the requested duration
+func acknowledge(w http.ResponseWriter, r *http.Request, snooze bool) {
+	if riskTracker == nil {
+		http.Error(w, "acknowledgements are disabled", http.StatusNotFound)
+		return
+	}
+
+	var req AckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	// A logged-in user acknowledges in their own name
+	if user, ok := UserFrom(r); ok {
+		req.User = user.String()
+	}
+	if strings.TrimSpace(req.User) == "" {
+		http.Error(w, "user is required", http.StatusBadRequest)
+		return
+	}
+	var duration time.Duration
+	if snooze {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			http.Error(w, "invalid duration", http.StatusBadRequest)
+			return
+		}
+		duration = d
+	}
+
+	service := r.PathValue("service")
+	ack, err := riskTracker.Acknowledge(service, req.User, req.Comment, duration)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if snooze {
+		log.Printf("%s snoozed %s until %s", ack.By, service, ack.Until.Format(time.RFC3339))
+	} else {
+		log.Printf("%s acknowledged %s", ack.By, service)
+	}
+	apiAck := NewAPIAck(ack)
+	setCurrentAck(service, apiAck)
+	writeJSON(w, http.StatusCreated, apiAck)
+}
+
+// handleRiskUnack serves DELETE /api/risks/{service}/ack, ending an acknowledgement or snooze
+func handleRiskUnack(w http.ResponseWriter, r *http.Request) {
+	if riskTracker == nil {
+		http.Error(w, "acknowledgements are disabled", http.StatusNotFound)
+		return
+	}
+	service := r.PathValue("service")
+	if !riskTracker.Unacknowledge(service) {
+		http.Error(w, fmt.Sprintf("%s is not acknowledged", service), http.StatusNotFound)
+		return
+	}
+	setCurrentAck(service, nil)
+	writeJSON(w, http.StatusOK, map[string]string{"unacknowledged": service})
+}
+
+// setCurrentAck shows an acknowledgement change right away instead of at the next cycle
+func setCurrentAck(service string, ack *APIAck) {
+	riskMu.RLock()
+	risks := make([]APIRiskItem, len(currentAPIRisks))
+	copy(risks, currentAPIRisks)
+	riskMu.RUnlock()
+
+	for i := range risks {
+		if risks[i].Service == service {
+			risks[i].Ack = ack
+		}
+	}
+	UpdateRisks(risks)
+}
+
 func UpdateRisks(newRisks []APIRiskItem) {
+	updateMu.Lock()
+	defer updateMu.Unlock()
 	riskMu.Lock()
+	riskSeq++
+	delta := diffRisks(currentAPIRisks, newRisks)
+	delta.Seq = riskSeq
 	currentAPIRisks = newRisks
 	riskMu.Unlock()
+	notifyRiskWatchers(newRisks)
 
 	// Broadcast update to all WebSocket clients
 	if wsHub != nil {
 		select {
-		case wsHub.broadcast <- WebSocketMessage{Type: "risks_update", Data: newRisks}:
+		case wsHub.broadcast <- riskBroadcast{risks: newRisks, delta: delta}:
 		default:
 			log.Printf("WebSocket broadcast channel full, skipping update")
 		}
 	}
 }
+
+// diffRisks returns the items added, changed and removed between two updates; an item whose
+// timestamp alone changed is left out
+func diffRisks(old, current []APIRiskItem) RiskDeltaMessage {
+	delta := RiskDeltaMessage{Type: "risks_delta", Added: []APIRiskItem{}, Updated: []APIRiskItem{}, Removed: []RiskKey{}}
+	previous := make(map[RiskKey]APIRiskItem, len(old))
+	for _, item := range old {
+		previous[keyOf(item)] = item
+	}
+	for _, item := range current {
+		key := keyOf(item)
+		before, ok := previous[key]
+		delete(previous, key)
+		if !ok {
+			delta.Added = append(delta.Added, item)
+			continue
+		}
+		before.Timestamp = item.Timestamp
+		if !reflect.DeepEqual(before, item) {
+			delta.Updated = append(delta.Updated, item)
+		}
+	}
+	for _, item := range old {
+		if _, ok := previous[keyOf(item)]; ok {
+			delta.Removed = append(delta.Removed, keyOf(item))
+		}
+	}
+	return delta
+}
+
+// filterDelta keeps the changes of the services the grant sees; the message is still sent
+// when nothing is left, so the client sees no gap in Seq
+func filterDelta(delta RiskDeltaMessage, grant Grant) RiskDeltaMessage {
+	if grant.Unscoped() {
+		return delta
+	}
+	filtered := RiskDeltaMessage{Type: delta.Type, Seq: delta.Seq, Added: visibleRisks(delta.Added, grant),
+		Updated: visibleRisks(delta.Updated, grant), Removed: []RiskKey{}}
+	for _, key := range delta.Removed {
+		if grant.Sees(key.Service) {
+			filtered.Removed = append(filtered.Removed, key)
+		}
+	}
+	return filtered
+}
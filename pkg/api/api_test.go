@@ -0,0 +1,110 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"vigilant/pkg/config"
+)
+
+func riskItem(service, alert string, score int, timestamp string) APIRiskItem {
+	return APIRiskItem{Service: service, Alert: alert, Score: score, Timestamp: timestamp}
+}
+
+// checkDelta compares the keys of the added, updated and removed items of a delta
+func checkDelta(t *testing.T, fn string, delta RiskDeltaMessage, wantAdded, wantUpdated, wantRemoved []RiskKey) {
+	t.Helper()
+	if delta.Added == nil || delta.Updated == nil || delta.Removed == nil {
+		t.Errorf("%s() left a nil slice, which encodes as null instead of []", fn)
+	}
+	var added, updated []RiskKey
+	for _, item := range delta.Added {
+		added = append(added, keyOf(item))
+	}
+	for _, item := range delta.Updated {
+		updated = append(updated, keyOf(item))
+	}
+	for _, field := range []struct {
+		name      string
+		got, want []RiskKey
+	}{{"added", added, wantAdded}, {"updated", updated, wantUpdated}, {"removed", delta.Removed, wantRemoved}} {
+		if len(field.got) != len(field.want) || len(field.got) > 0 && !reflect.DeepEqual(field.got, field.want) {
+			t.Errorf("%s() %s = %v, want %v", fn, field.name, field.got, field.want)
+		}
+	}
+}
+
+func TestDiffRisks(t *testing.T) {
+	api := riskItem("api", "HighCPU", 50, "10:00")
+	db := riskItem("db", "DiskFull", 70, "10:00")
+
+	tests := []struct {
+		name                                string
+		old, current                        []APIRiskItem
+		wantAdded, wantUpdated, wantRemoved []RiskKey
+	}{
+		{name: "nothing"},
+		{name: "first update", current: []APIRiskItem{api, db}, wantAdded: []RiskKey{keyOf(api), keyOf(db)}},
+		{name: "unchanged", old: []APIRiskItem{api, db}, current: []APIRiskItem{api, db}},
+		{name: "timestamp only", old: []APIRiskItem{api}, current: []APIRiskItem{riskItem("api", "HighCPU", 50, "10:01")}},
+		{name: "score changed", old: []APIRiskItem{api, db}, current: []APIRiskItem{riskItem("api", "HighCPU", 60, "10:01"), db}, wantUpdated: []RiskKey{keyOf(api)}},
+		{name: "nested field changed", old: []APIRiskItem{api}, current: []APIRiskItem{{Service: "api", Alert: "HighCPU", Score: 50, Timestamp: "10:00", Ack: &APIAck{}}}, wantUpdated: []RiskKey{keyOf(api)}},
+		{name: "removed", old: []APIRiskItem{api, db}, current: []APIRiskItem{db}, wantRemoved: []RiskKey{keyOf(api)}},
+		{name: "another alert of the service", old: []APIRiskItem{api}, current: []APIRiskItem{riskItem("api", "HighLatency", 50, "10:00")},
+			wantAdded: []RiskKey{{Service: "api", Alert: "HighLatency"}}, wantRemoved: []RiskKey{keyOf(api)}},
+		{name: "reordered", old: []APIRiskItem{api, db}, current: []APIRiskItem{db, api}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delta := diffRisks(tt.old, tt.current)
+			if delta.Type != "risks_delta" {
+				t.Errorf("diffRisks() type = %q, want risks_delta", delta.Type)
+			}
+			checkDelta(t, "diffRisks", delta, tt.wantAdded, tt.wantUpdated, tt.wantRemoved)
+		})
+	}
+}
+
+func TestFilterDelta(t *testing.T) {
+	SetAccess(config.AccessConfig{DefaultRole: "viewer"}, map[string]string{"api": "payments", "db": "storage"})
+	t.Cleanup(func() { SetAccess(config.AccessConfig{}, nil) })
+
+	delta := RiskDeltaMessage{
+		Type:    "risks_delta",
+		Seq:     7,
+		Added:   []APIRiskItem{riskItem("api", "HighCPU", 50, ""), riskItem("queue", "Backlog", 40, "")},
+		Updated: []APIRiskItem{riskItem("db", "DiskFull", 70, "")},
+		Removed: []RiskKey{{Service: "api", Alert: "HighLatency"}, {Service: "db", Alert: "SlowQueries"}},
+	}
+
+	tests := []struct {
+		name                                string
+		grant                               Grant
+		wantAdded, wantUpdated, wantRemoved []RiskKey
+	}{
+		{name: "unscoped", grant: Grant{Role: "viewer"},
+			wantAdded:   []RiskKey{{Service: "api", Alert: "HighCPU"}, {Service: "queue", Alert: "Backlog"}},
+			wantUpdated: []RiskKey{{Service: "db", Alert: "DiskFull"}},
+			wantRemoved: delta.Removed},
+		{name: "one team", grant: Grant{Role: "viewer", Teams: []string{"payments"}},
+			wantAdded:   []RiskKey{{Service: "api", Alert: "HighCPU"}},
+			wantRemoved: []RiskKey{{Service: "api", Alert: "HighLatency"}}},
+		{name: "both teams", grant: Grant{Role: "viewer", Teams: []string{"payments", "storage"}},
+			wantAdded:   []RiskKey{{Service: "api", Alert: "HighCPU"}},
+			wantUpdated: []RiskKey{{Service: "db", Alert: "DiskFull"}},
+			wantRemoved: delta.Removed},
+		{name: "team without changes", grant: Grant{Role: "viewer", Teams: []string{"search"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered := filterDelta(delta, tt.grant)
+			// Kept even when empty, so the client sees no gap in the sequence
+			if filtered.Type != delta.Type || filtered.Seq != delta.Seq {
+				t.Errorf("filterDelta() = %s #%d, want %s #%d", filtered.Type, filtered.Seq, delta.Type, delta.Seq)
+			}
+			checkDelta(t, "filterDelta", filtered, tt.wantAdded, tt.wantUpdated, tt.wantRemoved)
+		})
+	}
+}
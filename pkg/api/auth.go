@@ -0,0 +1,400 @@
+package api
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// AuthConfig sets how API and WebSocket requests authenticate
+type AuthConfig struct {
+	APIKeys      []string // Static keys, sent as a bearer token or in X-API-Key
+	JWTSecret    string   // Verifies HS256 tokens
+	JWTPublicKey string   // PEM public key verifying RS256 or ES256 tokens
+	JWTIssuer    string   // Required iss claim, when set
+	JWTAudience  string   // Required aud claim, when set
+}
+
+// Auth checks the credentials of API requests
+type Auth struct {
+	apiKeys   [][]byte
+	jwtSecret []byte
+	jwtKey    crypto.PublicKey
+	issuer    string
+	audience  string
+}
+
+// jwtLeeway tolerates clock skew when checking exp and nbf
+const jwtLeeway = 30 * time.Second
+
+var apiAuth *Auth
+
+// publicHealthz serves /healthz without credentials, whichever authentication is on
+var publicHealthz bool
+
+// SetAuth requires credentials on the API, the WebSocket and /metrics; nil leaves them open
+func SetAuth(a *Auth) {
+	apiAuth = a
+}
+
+// SetPublicHealthz serves /healthz without credentials, for liveness probes
+func SetPublicHealthz(public bool) {
+	publicHealthz = public
+}
+
+// NewAuth returns the authenticator of cfg; nil when it configures no credentials
+func NewAuth(cfg AuthConfig) (*Auth, error) {
+	a := &Auth{
+		jwtSecret: []byte(cfg.JWTSecret),
+		issuer:    cfg.JWTIssuer,
+		audience:  cfg.JWTAudience,
+	}
+	for _, key := range cfg.APIKeys {
+		if key = strings.TrimSpace(key); key != "" {
+			a.apiKeys = append(a.apiKeys, []byte(key))
+		}
+	}
+	if cfg.JWTPublicKey != "" {
+		block, _ := pem.Decode([]byte(cfg.JWTPublicKey))
+		if block == nil {
+			return nil, fmt.Errorf("JWT public key is not PEM encoded")
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse JWT public key: %w", err)
+		}
+		switch key.(type) {
+		case *rsa.PublicKey, *ecdsa.PublicKey:
+			a.jwtKey = key
+		default:
+			return nil, fmt.Errorf("JWT public key must be RSA or ECDSA, got %T", key)
+		}
+	}
+	if len(a.apiKeys) == 0 && len(a.jwtSecret) == 0 && a.jwtKey == nil {
+		return nil, nil
+	}
+	return a, nil
+}
+
+// AuthFromEnv builds the authenticator from API_KEYS, JWT_SECRET, JWT_PUBLIC_KEY_FILE,
+// JWT_ISSUER and JWT_AUDIENCE; nil when none of the credentials is set
+func AuthFromEnv() (*Auth, error) {
+	cfg := AuthConfig{
+		APIKeys:     strings.Split(os.Getenv("API_KEYS"), ","),
+		JWTSecret:   os.Getenv("JWT_SECRET"),
+		JWTIssuer:   os.Getenv("JWT_ISSUER"),
+		JWTAudience: os.Getenv("JWT_AUDIENCE"),
+	}
+	if path := os.Getenv("JWT_PUBLIC_KEY_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWT public key: %w", err)
+		}
+		cfg.JWTPublicKey = string(data)
+	}
+	return NewAuth(cfg)
+}
+
+// User is who sent a request, from a JWT or an SSO session; empty for API keys
+type User struct {
+	Subject string   `json:"sub"`
+	Email   string   `json:"email,omitempty"`
+	Name    string   `json:"name,omitempty"`
+	Groups  []string `json:"groups,omitempty"` // From the groups claim, matched by config/access.yml
+}
+
+// String names the user for attribution: name, else email, else subject
+func (u User) String() string {
+	switch {
+	case u.Name != "":
+		return u.Name
+	case u.Email != "":
+		return u.Email
+	default:
+		return u.Subject
+	}
+}
+
+type userKey struct{}
+
+// UserFrom returns the user who sent the request, when known
+func UserFrom(r *http.Request) (User, bool) {
+	user, ok := r.Context().Value(userKey{}).(User)
+	return user, ok && user.Subject != ""
+}
+
+// requireAuth rejects requests without valid credentials: an SSO session (see SetOIDC), an API
+// key or a JWT, and stores the caller's grant (see SetAccess). The dashboard's static files
+// carry no data and stay public unless SSO is on, which sends browsers to the login instead.
+// /healthz stays open with SetPublicHealthz, as does the Alertmanager webhook when it checks its
+// own token.
+func requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a, sso := apiAuth, oidcProvider
+		path := unversionedPath(r.URL.Path)
+		if (a == nil && sso == nil && !accessPolicy.hasKeys()) || strings.HasPrefix(path, "/auth/") ||
+			(path == "/healthz" && publicHealthz) ||
+			(path == "/api/webhooks/alertmanager" && alertReceiver != nil && alertReceiver.token != "") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if sso != nil {
+			if user, ok := sso.session(r); ok {
+				grant, ok := accessPolicy.resolve(user)
+				if !ok {
+					writeJSON(w, http.StatusForbidden, map[string]string{"error": errNoAccess.Error() + " to " + user.String()})
+					return
+				}
+				next.ServeHTTP(w, withCaller(r, user, grant))
+				return
+			}
+			if !protected(path) {
+				http.Redirect(w, r, "/auth/login?return="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+				return
+			}
+		}
+		if !protected(path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, grant, err := authenticate(requestToken(r))
+		if errors.Is(err, errNoAccess) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="vigilant"`)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			return
+		}
+		next.ServeHTTP(w, withCaller(r, user, grant))
+	})
+}
+
+// errNoAccess is returned for valid credentials that config/access.yml grants nothing
+var errNoAccess = errors.New("no access granted")
+
+// authenticate resolves an API key or JWT to the caller and their grant
+func authenticate(token string) (User, Grant, error) {
+	if user, grant, ok := accessPolicy.key(token); ok {
+		return user, grant, nil
+	}
+	if apiAuth == nil {
+		return User{}, Grant{}, errors.New("missing credentials")
+	}
+	user, err := apiAuth.Verify(token)
+	if err != nil {
+		return User{}, Grant{}, err
+	}
+	grant, ok := accessPolicy.resolve(user)
+	if !ok {
+		return user, Grant{}, fmt.Errorf("%w to %s", errNoAccess, user)
+	}
+	return user, grant, nil
+}
+
+// protected reports whether path serves data rather than the dashboard's static files
+func protected(path string) bool {
+	return path == "/ws" || path == "/metrics" || path == "/healthz" || strings.HasPrefix(path, "/api/")
+}
+
+// requestToken returns the credential of a request: a bearer token, an X-API-Key header, or
+// for the WebSocket upgrade and the event stream, which browsers can't add headers to, the
+// token query parameter
+func requestToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if path := unversionedPath(r.URL.Path); path == "/ws" || path == "/api/stream" {
+		return r.URL.Query().Get("token")
+	}
+	return ""
+}
+
+// Verify accepts a configured API key or a valid JWT, and returns the JWT's user
+func (a *Auth) Verify(token string) (User, error) {
+	if token == "" {
+		return User{}, errors.New("missing credentials")
+	}
+	for _, key := range a.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(token), key) == 1 {
+			return User{}, nil
+		}
+	}
+	if strings.Count(token, ".") != 2 || (len(a.jwtSecret) == 0 && a.jwtKey == nil) {
+		return User{}, errors.New("invalid credentials")
+	}
+	claims, err := verifyJWT(token, func(jwtHeader) ([]byte, crypto.PublicKey, error) {
+		return a.jwtSecret, a.jwtKey, nil
+	}, time.Now())
+	if err == nil {
+		err = claims.check(a.issuer, a.audience)
+	}
+	if err != nil {
+		return User{}, fmt.Errorf("invalid token: %w", err)
+	}
+	return claims.user(), nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Issuer            string          `json:"iss"`
+	Subject           string          `json:"sub"`
+	Audience          json.RawMessage `json:"aud"` // A string or a list of strings
+	ExpiresAt         *float64        `json:"exp"`
+	NotBefore         *float64        `json:"nbf"`
+	Nonce             string          `json:"nonce"`
+	Email             string          `json:"email"`
+	Name              string          `json:"name"`
+	PreferredUsername string          `json:"preferred_username"`
+	Groups            []string        `json:"groups"`
+}
+
+// check verifies the iss and aud claims, when expected
+func (c jwtClaims) check(issuer, audience string) error {
+	if issuer != "" && c.Issuer != issuer {
+		return fmt.Errorf("unexpected issuer %q", c.Issuer)
+	}
+	if audience != "" && !hasAudience(c.Audience, audience) {
+		return errors.New("token not issued for this audience")
+	}
+	return nil
+}
+
+func (c jwtClaims) user() User {
+	name := c.Name
+	if name == "" {
+		name = c.PreferredUsername
+	}
+	return User{Subject: c.Subject, Email: c.Email, Name: name, Groups: c.Groups}
+}
+
+// jwtKeys returns the HMAC secret or public key verifying a JWT with the given header
+type jwtKeys func(header jwtHeader) (secret []byte, key crypto.PublicKey, err error)
+
+// verifyJWT checks the signature of a compact JWT and its exp and nbf claims, and returns the claims
+func verifyJWT(token string, keys jwtKeys, now time.Time) (jwtClaims, error) {
+	var claims jwtClaims
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, errors.New("malformed token")
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return claims, fmt.Errorf("malformed header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return claims, fmt.Errorf("malformed signature: %w", err)
+	}
+	secret, key, err := keys(header)
+	if err != nil {
+		return claims, err
+	}
+	if err := verifySignature(header.Alg, parts[0]+"."+parts[1], signature, secret, key); err != nil {
+		return claims, err
+	}
+
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return claims, fmt.Errorf("malformed claims: %w", err)
+	}
+	if claims.ExpiresAt != nil && now.After(unixTime(*claims.ExpiresAt).Add(jwtLeeway)) {
+		return claims, errors.New("token expired")
+	}
+	if claims.NotBefore != nil && now.Add(jwtLeeway).Before(unixTime(*claims.NotBefore)) {
+		return claims, errors.New("token not valid yet")
+	}
+	return claims, nil
+}
+
+// verifySignature checks the signature of the signed header and claims with the secret or
+// key matching alg
+func verifySignature(alg, signed string, signature, secret []byte, publicKey crypto.PublicKey) error {
+	digest := sha256.Sum256([]byte(signed))
+	switch alg {
+	case "HS256":
+		if len(secret) == 0 {
+			break
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return errors.New("bad signature")
+		}
+		return nil
+	case "RS256":
+		key, ok := publicKey.(*rsa.PublicKey)
+		if !ok {
+			break
+		}
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return errors.New("bad signature")
+		}
+		return nil
+	case "ES256":
+		key, ok := publicKey.(*ecdsa.PublicKey)
+		if !ok {
+			break
+		}
+		// JWS encodes the signature as r and s, 32 bytes each
+		if len(signature) != 64 {
+			return errors.New("bad signature")
+		}
+		r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(key, digest[:], r, s) {
+			return errors.New("bad signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported signing algorithm %q", alg)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func unixTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+// hasAudience reports whether the aud claim, a string or a list, names audience
+func hasAudience(raw json.RawMessage, audience string) bool {
+	var single string
+	if json.Unmarshal(raw, &single) == nil {
+		return single == audience
+	}
+	var list []string
+	if json.Unmarshal(raw, &list) == nil {
+		return slices.Contains(list, audience)
+	}
+	return false
+}
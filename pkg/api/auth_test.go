@@ -0,0 +1,318 @@
+package api
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testRSAKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+	testECKey, _  = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	testSecret    = []byte("test-secret")
+)
+
+// signToken builds a compact JWT of header and claims signed with alg; key is the HMAC secret
+// or the private key, and alg "none" leaves the signature empty
+func signToken(t *testing.T, header map[string]string, claims map[string]interface{}, key interface{}) string {
+	t.Helper()
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature(t, header["alg"], signed, key))
+}
+
+func signature(t *testing.T, alg, signed string, key interface{}) []byte {
+	t.Helper()
+	digest := sha256.Sum256([]byte(signed))
+	switch alg {
+	case "HS256":
+		mac := hmac.New(sha256.New, key.([]byte))
+		mac.Write([]byte(signed))
+		return mac.Sum(nil)
+	case "RS256":
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sig
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, key.(*ecdsa.PrivateKey), digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return sig
+	}
+	return nil
+}
+
+// tamper replaces the claims of a signed token, keeping its header and signature
+func tamper(token string, claims map[string]interface{}) string {
+	parts := strings.Split(token, ".")
+	c, _ := json.Marshal(claims)
+	return parts[0] + "." + base64.RawURLEncoding.EncodeToString(c) + "." + parts[2]
+}
+
+func TestVerifyJWT(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	at := func(d time.Duration) float64 { return float64(now.Add(d).Unix()) }
+	claims := map[string]interface{}{"sub": "alice", "exp": at(time.Hour)}
+	keyPEM := publicKeyPEM(t, &testRSAKey.PublicKey)
+
+	tests := []struct {
+		name    string
+		token   string
+		secret  []byte
+		key     crypto.PublicKey
+		wantErr string
+	}{
+		{
+			name:   "HS256",
+			token:  signToken(t, map[string]string{"alg": "HS256"}, claims, testSecret),
+			secret: testSecret,
+		},
+		{
+			name:  "RS256",
+			token: signToken(t, map[string]string{"alg": "RS256"}, claims, testRSAKey),
+			key:   &testRSAKey.PublicKey,
+		},
+		{
+			name:  "ES256",
+			token: signToken(t, map[string]string{"alg": "ES256"}, claims, testECKey),
+			key:   &testECKey.PublicKey,
+		},
+		{
+			name:    "alg none",
+			token:   signToken(t, map[string]string{"alg": "none"}, claims, nil),
+			secret:  testSecret,
+			key:     &testRSAKey.PublicKey,
+			wantErr: `unsupported signing algorithm "none"`,
+		},
+		{
+			name:    "unknown alg",
+			token:   signToken(t, map[string]string{"alg": "HS512"}, claims, nil),
+			secret:  testSecret,
+			wantErr: `unsupported signing algorithm "HS512"`,
+		},
+		{
+			name:    "HS256 signed with the public key when only a public key is set",
+			token:   signToken(t, map[string]string{"alg": "HS256"}, claims, []byte(keyPEM)),
+			key:     &testRSAKey.PublicKey,
+			wantErr: `unsupported signing algorithm "HS256"`,
+		},
+		{
+			name:    "RS256 when only a secret is set",
+			token:   signToken(t, map[string]string{"alg": "RS256"}, claims, testRSAKey),
+			secret:  testSecret,
+			wantErr: `unsupported signing algorithm "RS256"`,
+		},
+		{
+			name:    "ES256 against an RSA key",
+			token:   signToken(t, map[string]string{"alg": "ES256"}, claims, testECKey),
+			key:     &testRSAKey.PublicKey,
+			wantErr: `unsupported signing algorithm "ES256"`,
+		},
+		{
+			name:    "HS256 with another secret",
+			token:   signToken(t, map[string]string{"alg": "HS256"}, claims, []byte("other-secret")),
+			secret:  testSecret,
+			wantErr: "bad signature",
+		},
+		{
+			name:    "tampered HS256 payload",
+			token:   tamper(signToken(t, map[string]string{"alg": "HS256"}, claims, testSecret), map[string]interface{}{"sub": "admin", "exp": at(time.Hour)}),
+			secret:  testSecret,
+			wantErr: "bad signature",
+		},
+		{
+			name:    "tampered RS256 payload",
+			token:   tamper(signToken(t, map[string]string{"alg": "RS256"}, claims, testRSAKey), map[string]interface{}{"sub": "admin", "exp": at(time.Hour)}),
+			key:     &testRSAKey.PublicKey,
+			wantErr: "bad signature",
+		},
+		{
+			name:    "tampered ES256 payload",
+			token:   tamper(signToken(t, map[string]string{"alg": "ES256"}, claims, testECKey), map[string]interface{}{"sub": "admin", "exp": at(time.Hour)}),
+			key:     &testECKey.PublicKey,
+			wantErr: "bad signature",
+		},
+		{
+			name:   "expired within the leeway",
+			token:  signToken(t, map[string]string{"alg": "HS256"}, map[string]interface{}{"sub": "alice", "exp": at(-jwtLeeway)}, testSecret),
+			secret: testSecret,
+		},
+		{
+			name:    "expired past the leeway",
+			token:   signToken(t, map[string]string{"alg": "HS256"}, map[string]interface{}{"sub": "alice", "exp": at(-jwtLeeway - time.Second)}, testSecret),
+			secret:  testSecret,
+			wantErr: "token expired",
+		},
+		{
+			name:   "not yet valid within the leeway",
+			token:  signToken(t, map[string]string{"alg": "HS256"}, map[string]interface{}{"sub": "alice", "nbf": at(jwtLeeway)}, testSecret),
+			secret: testSecret,
+		},
+		{
+			name:    "not yet valid past the leeway",
+			token:   signToken(t, map[string]string{"alg": "HS256"}, map[string]interface{}{"sub": "alice", "nbf": at(jwtLeeway + time.Second)}, testSecret),
+			secret:  testSecret,
+			wantErr: "token not valid yet",
+		},
+		{
+			name:    "two segments",
+			token:   "eyJhbGciOiJIUzI1NiJ9.e30",
+			secret:  testSecret,
+			wantErr: "malformed token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := verifyJWT(tt.token, func(jwtHeader) ([]byte, crypto.PublicKey, error) {
+				return tt.secret, tt.key, nil
+			}, now)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("verifyJWT() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verifyJWT() error = %v", err)
+			}
+			if got.Subject != "alice" {
+				t.Errorf("verifyJWT() subject = %q, want alice", got.Subject)
+			}
+		})
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	const signed = "header.claims"
+
+	tests := []struct {
+		name      string
+		alg       string
+		signature []byte
+		secret    []byte
+		key       crypto.PublicKey
+		wantErr   string
+	}{
+		{name: "HS256", alg: "HS256", signature: signature(t, "HS256", signed, testSecret), secret: testSecret},
+		{name: "RS256", alg: "RS256", signature: signature(t, "RS256", signed, testRSAKey), key: &testRSAKey.PublicKey},
+		{name: "ES256", alg: "ES256", signature: signature(t, "ES256", signed, testECKey), key: &testECKey.PublicKey},
+		{name: "none", alg: "none", secret: testSecret, key: &testRSAKey.PublicKey, wantErr: "unsupported"},
+		{name: "empty alg", alg: "", secret: testSecret, wantErr: "unsupported"},
+		{name: "lowercase alg", alg: "hs256", signature: signature(t, "HS256", signed, testSecret), secret: testSecret, wantErr: "unsupported"},
+		{name: "HS256 without a secret", alg: "HS256", signature: signature(t, "HS256", signed, []byte{}), key: &testRSAKey.PublicKey, wantErr: "unsupported"},
+		{name: "RS256 against an EC key", alg: "RS256", signature: signature(t, "RS256", signed, testRSAKey), key: &testECKey.PublicKey, wantErr: "unsupported"},
+		{name: "ES256 DER encoded", alg: "ES256", signature: make([]byte, 72), key: &testECKey.PublicKey, wantErr: "bad signature"},
+		{name: "empty RS256 signature", alg: "RS256", key: &testRSAKey.PublicKey, wantErr: "bad signature"},
+		{name: "empty HS256 signature", alg: "HS256", secret: testSecret, wantErr: "bad signature"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifySignature(tt.alg, signed, tt.signature, tt.secret, tt.key)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifySignature() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("verifySignature() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHasAudience(t *testing.T) {
+	tests := []struct {
+		name string
+		aud  string
+		want bool
+	}{
+		{name: "string", aud: `"vigilant"`, want: true},
+		{name: "other string", aud: `"grafana"`, want: false},
+		{name: "list", aud: `["grafana", "vigilant"]`, want: true},
+		{name: "list without audience", aud: `["grafana"]`, want: false},
+		{name: "empty list", aud: `[]`, want: false},
+		{name: "prefix", aud: `"vigilant-dev"`, want: false},
+		{name: "missing", aud: ``, want: false},
+		{name: "null", aud: `null`, want: false},
+		{name: "number", aud: `42`, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasAudience(json.RawMessage(tt.aud), "vigilant"); got != tt.want {
+				t.Errorf("hasAudience(%s) = %v, want %v", tt.aud, got, tt.want)
+			}
+		})
+	}
+}
+
+func publicKeyPEM(t *testing.T, key crypto.PublicKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestRequireAuthPublicHealthz(t *testing.T) {
+	sso := testOIDC(t, nil)
+	jwt, err := NewAuth(AuthConfig{JWTSecret: string(testSecret)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		auth       *Auth
+		sso        *OIDC
+		public     bool
+		path       string
+		wantStatus int
+	}{
+		{name: "JWT, public", auth: jwt, public: true, path: "/healthz", wantStatus: http.StatusOK},
+		{name: "JWT", auth: jwt, path: "/healthz", wantStatus: http.StatusUnauthorized},
+		{name: "SSO only, public", sso: sso, public: true, path: "/healthz", wantStatus: http.StatusOK},
+		{name: "SSO only", sso: sso, path: "/healthz", wantStatus: http.StatusUnauthorized},
+		{name: "SSO only, public, other path", sso: sso, public: true, path: "/api/risks", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiAuth, oidcProvider, publicHealthz = tt.auth, tt.sso, tt.public
+			t.Cleanup(func() { apiAuth, oidcProvider, publicHealthz = nil, nil, false })
+
+			handler := requireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("%s: status = %d, want %d", tt.path, rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAcceptedEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: ""},
+		{header: "gzip", want: "gzip"},
+		{header: "GZIP", want: "gzip"},
+		{header: "deflate", want: "deflate"},
+		{header: "deflate, gzip", want: "gzip"},
+		{header: "gzip, deflate, br", want: "gzip"},
+		{header: "br, deflate;q=0.5", want: "deflate"},
+		{header: "gzip;q=0, deflate", want: "deflate"},
+		{header: "gzip;q=0", want: ""},
+		{header: "gzip; q=0.001", want: "gzip"},
+		{header: "*", want: "gzip"},
+		{header: "gzip;q=0, *", want: ""},
+		{header: "identity", want: ""},
+		{header: "br", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := acceptedEncoding(tt.header); got != tt.want {
+				t.Errorf("acceptedEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompress(t *testing.T) {
+	body := strings.Repeat(`{"service":"api","risk":"High"}`, 100)
+	writeBody := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", "3100")
+		io.WriteString(w, body)
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		accept       string
+		handler      http.HandlerFunc
+		wantEncoding string
+		wantBody     string
+	}{
+		{name: "gzip", accept: "gzip", handler: writeBody, wantEncoding: "gzip", wantBody: body},
+		{name: "deflate", accept: "deflate", handler: writeBody, wantEncoding: "deflate", wantBody: body},
+		{name: "not accepted", accept: "br", handler: writeBody, wantBody: body},
+		// The server drops the body of HEAD responses, the recorder doesn't
+		{name: "head", method: http.MethodHead, accept: "gzip", handler: writeBody, wantBody: body},
+		{name: "error status", accept: "gzip", handler: func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unknown service", http.StatusNotFound)
+		}, wantEncoding: "gzip", wantBody: "unknown service\n"},
+		{name: "no content", accept: "gzip", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}},
+		{name: "already encoded", accept: "gzip", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Encoding", "br")
+			io.WriteString(w, "raw")
+		}, wantEncoding: "br", wantBody: "raw"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, "/api/risks", nil)
+			req.Header.Set("Accept-Encoding", tt.accept)
+			rec := httptest.NewRecorder()
+			compress(tt.handler)(rec, req)
+
+			if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", got)
+			}
+			encoding := rec.Header().Get("Content-Encoding")
+			if encoding != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", encoding, tt.wantEncoding)
+			}
+			if (encoding == "gzip" || encoding == "deflate") && rec.Header().Get("Content-Length") != "" {
+				t.Errorf("Content-Length = %s kept for the encoded body", rec.Header().Get("Content-Length"))
+			}
+
+			var reader io.Reader = rec.Body
+			var err error
+			switch encoding {
+			case "gzip":
+				reader, err = gzip.NewReader(rec.Body)
+			case "deflate":
+				reader, err = zlib.NewReader(rec.Body)
+			}
+			if err != nil {
+				t.Fatalf("failed to decode %s body: %v", encoding, err)
+			}
+			got, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("failed to decode %s body: %v", encoding, err)
+			}
+			if string(got) != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+}
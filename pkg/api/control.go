@@ -0,0 +1,145 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"vigilant/pkg/risk"
+)
+
+var monitorPauses *risk.Pauses
+
+// SetPauses enables the /api/control endpoints pausing and resuming monitoring
+func SetPauses(p *risk.Pauses) {
+	monitorPauses = p
+}
+
+// APIPause is a pause of monitoring, of every service when Service is empty
+type APIPause struct {
+	Service string `json:"service,omitempty"`
+	By      string `json:"by"`
+	Reason  string `json:"reason,omitempty"`
+	At      string `json:"at"`              // RFC3339
+	Until   string `json:"until,omitempty"` // Automatic resume (RFC3339)
+}
+
+// NewAPIPause converts a pause for the payload
+func NewAPIPause(p risk.Pause) APIPause {
+	pause := APIPause{Service: p.Service, By: p.By, Reason: p.Reason, At: p.At.Format(time.RFC3339)}
+	if !p.Until.IsZero() {
+		pause.Until = p.Until.Format(time.RFC3339)
+	}
+	return pause
+}
+
+// PauseRequest is the body of POST /api/control/pause and /resume; without a service, all
+// monitoring is paused or resumed
+type PauseRequest struct {
+	Service  string `json:"service,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	Duration string `json:"duration,omitempty"` // Resume automatically after, e.g. "2h"; empty until resumed
+}
+
+func registerControlRoutes(mux *http.ServeMux) {
+	handleAPI(mux, "GET /api/control", authorize("viewer", handleControlStatus))
+	handleAPI(mux, "POST /api/control/pause", authorize("admin", handlePause))
+	handleAPI(mux, "POST /api/control/resume", authorize("admin", handleResume))
+}
+
+// handleControlStatus serves GET /api/control, the current pauses the caller may see
+func handleControlStatus(w http.ResponseWriter, r *http.Request) {
+	if monitorPauses == nil {
+		http.Error(w, "monitoring control is disabled", http.StatusNotFound)
+		return
+	}
+	grant := GrantFrom(r)
+	status := struct {
+		Paused bool       `json:"paused"` // Every service is paused
+		Pauses []APIPause `json:"pauses"`
+	}{Pauses: []APIPause{}}
+	for _, p := range monitorPauses.List() {
+		if p.Global() {
+			status.Paused = true
+		} else if !grant.Sees(p.Service) {
+			continue
+		}
+		status.Pauses = append(status.Pauses, NewAPIPause(p))
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// handlePause serves POST /api/control/pause: data collection and LLM calls stop for the
+// service, or for all of them, while the API keeps serving the last published risks
+func handlePause(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodePauseRequest(w, r)
+	if !ok {
+		return
+	}
+	pause := risk.Pause{Service: req.Service, By: callerName(r), Reason: req.Reason, At: time.Now()}
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			http.Error(w, "invalid duration", http.StatusBadRequest)
+			return
+		}
+		pause.Until = pause.At.Add(d)
+	}
+	monitorPauses.Pause(pause)
+
+	message := pause.By + " paused monitoring"
+	if !pause.Global() {
+		message += " of " + pause.Service
+	}
+	if pause.Reason != "" {
+		message += ": " + pause.Reason
+	}
+	log.Print(message)
+	writeJSON(w, http.StatusCreated, NewAPIPause(pause))
+}
+
+// handleResume serves POST /api/control/resume, ending the pause of a service or the global one
+func handleResume(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodePauseRequest(w, r)
+	if !ok {
+		return
+	}
+	if _, ok := monitorPauses.Resume(req.Service); !ok {
+		http.Error(w, "not paused", http.StatusNotFound)
+		return
+	}
+
+	if req.Service == "" {
+		log.Printf("%s resumed monitoring", callerName(r))
+	} else {
+		log.Printf("%s resumed monitoring of %s", callerName(r), req.Service)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodePauseRequest reads an optional pause request; only callers seeing every team may pause
+// or resume all monitoring
+func decodePauseRequest(w http.ResponseWriter, r *http.Request) (PauseRequest, bool) {
+	var req PauseRequest
+	if monitorPauses == nil {
+		http.Error(w, "monitoring control is disabled", http.StatusNotFound)
+		return req, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return req, false
+	}
+	grant := GrantFrom(r)
+	if req.Service == "" && !grant.Unscoped() {
+		http.Error(w, "forbidden: requires access to every team", http.StatusForbidden)
+		return req, false
+	}
+	if req.Service != "" && !grant.Sees(req.Service) {
+		http.Error(w, "forbidden: service not in your teams", http.StatusForbidden)
+		return req, false
+	}
+	return req, true
+}
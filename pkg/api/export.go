@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/csv"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"vigilant/pkg/history"
+)
+
+// exportColumns are the CSV columns of an incident export, one row per incident
+var exportColumns = []string{
+	"id", "services", "alerts", "severity", "risk", "confidence",
+	"first_seen", "last_seen", "resolved_at", "analyses",
+	"root_cause", "summary", "immediate_actions", "investigation_steps", "prevention",
+}
+
+// handleIncidentExport serves GET /api/incidents/export?format=csv|json&from=&to=&service=&severity=&risk=,
+// every matching incident of the history with its summary and actions, oldest first
+func handleIncidentExport(w http.ResponseWriter, r *http.Request) {
+	if incidentHistory == nil {
+		http.Error(w, "incident history is disabled", http.StatusNotFound)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		http.Error(w, "format must be csv or json", http.StatusBadRequest)
+		return
+	}
+	filter, err := incidentFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	records := incidentHistory.Export(filter)
+	if records == nil {
+		records = []history.Record{}
+	}
+	filename := "incidents-" + time.Now().UTC().Format("20060102-150405") + "." + format
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	if format == "json" {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"exported_at": time.Now().UTC(),
+			"incidents":   records,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	cw := csv.NewWriter(w)
+	cw.Write(exportColumns)
+	for _, rec := range records {
+		resolved := ""
+		if !rec.ResolvedAt.IsZero() {
+			resolved = rec.ResolvedAt.UTC().Format(time.RFC3339)
+		}
+		row := []string{
+			rec.ID, strings.Join(rec.Services, "; "), strings.Join(rec.Alerts, "; "), rec.Severity, rec.Risk,
+			strconv.FormatFloat(rec.Confidence, 'f', -1, 64),
+			rec.FirstSeen.UTC().Format(time.RFC3339), rec.LastSeen.UTC().Format(time.RFC3339), resolved,
+			strconv.Itoa(rec.Analyses),
+			rec.RootCause, rec.Summary.Summary,
+			strings.Join(rec.ImmediateActions, "; "), strings.Join(rec.Investigation, "; "), rec.Prevention,
+		}
+		for i := range row {
+			row[i] = csvCell(row[i])
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		log.Printf("Failed to write incident export: %v", err)
+	}
+}
+
+// csvCell keeps a spreadsheet from evaluating text that starts like a formula, such as
+// analysis output beginning with '=' or '-'
+func csvCell(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
@@ -0,0 +1,370 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+
+	"vigilant/pkg/metrichistory"
+	"vigilant/pkg/riskhistory"
+)
+
+// graphqlSchema describes the risks, incidents, symptoms and history served by POST
+// /api/graphql, so clients fetch the fields they need in one request. Timestamps are RFC3339.
+const graphqlSchema = `
+schema {
+	query: Query
+}
+
+type Query {
+	# Risk items of the last analysis cycle, optionally for some services or from a score up
+	risks(services: [String!], minScore: Int): [Risk!]!
+	risk(service: String!): Risk
+	# Open incidents, followed by the recently resolved ones when resolved is true
+	incidents(resolved: Boolean): [Incident!]!
+	incident(id: ID!): Incident
+}
+
+type Risk {
+	incidentId: String!
+	service: String!
+	alert: String!
+	severity: String!
+	state: String!
+	silencedBy: String!
+	inhibitedBy: String!
+	ack: Ack
+	escalatedAt: String!
+	score: Int!
+	healthScore: Int!
+	trend: String!
+	startsAt: String!
+	symptoms: [Symptom!]!
+	metrics: [Metric!]!
+	summary: String!
+	risk: String!
+	confidence: Float!
+	rootCause: String!
+	immediateActions: [String!]!
+	investigationSteps: [String!]!
+	prevention: String!
+	runbooks: [Runbook!]!
+	timestamp: String!
+	incident: Incident
+	# Recorded scores of the service; null when risk history is disabled
+	history(from: String): [RiskPoint!]
+	# Recorded values of the service's metric checks; null when metric history is disabled
+	metricHistory(check: String, from: String): [MetricSeries!]
+}
+
+type Symptom {
+	pattern: String!
+	severity: String!
+	count: Int!
+	samples: [String!]!
+}
+
+type Metric {
+	name: String!
+	value: Float!
+	operator: String!
+	threshold: Float!
+	weight: Int!
+	labels: [Label!]!
+	offenders: Int!
+	series: Int!
+}
+
+type Label {
+	name: String!
+	value: String!
+}
+
+type Runbook {
+	name: String!
+	url: String!
+}
+
+type Ack {
+	by: String!
+	comment: String!
+	at: String!
+	until: String!
+}
+
+type Incident {
+	id: ID!
+	service: String!
+	services: [String!]!
+	alerts: [String!]!
+	status: String!
+	openedAt: String!
+	updatedAt: String!
+	resolvedAt: String!
+	risks: [Risk!]!
+}
+
+type RiskPoint {
+	time: String!
+	score: Int!
+	risk: String!
+	state: String!
+}
+
+type MetricSeries {
+	check: String!
+	operator: String!
+	threshold: Float!
+	points: [MetricPoint!]!
+}
+
+type MetricPoint {
+	time: String!
+	value: Float!
+	threshold: Float!
+	triggered: Boolean!
+	labels: [Label!]!
+}
+`
+
+// graphqlHandler serves the schema; the depth limit keeps risk -> incident -> risks cycles short
+var graphqlHandler = &relay.Handler{
+	Schema: graphql.MustParseSchema(graphqlSchema, &graphqlQuery{}, graphql.UseFieldResolvers(), graphql.MaxDepth(8)),
+}
+
+// graphqlQuery resolves the queries for the services the caller's grant sees
+type graphqlQuery struct{}
+
+func (q *graphqlQuery) Risks(ctx context.Context, args struct {
+	Services *[]string
+	MinScore *int32
+}) []*riskResolver {
+	riskMu.RLock()
+	items := visibleRisks(currentAPIRisks, grantFromContext(ctx))
+	riskMu.RUnlock()
+	result := make([]*riskResolver, 0, len(items))
+	for _, item := range items {
+		if args.Services != nil && !slices.Contains(*args.Services, item.Service) {
+			continue
+		}
+		if args.MinScore != nil && item.Score < int(*args.MinScore) {
+			continue
+		}
+		result = append(result, &riskResolver{item})
+	}
+	return result
+}
+
+func (q *graphqlQuery) Risk(ctx context.Context, args struct{ Service string }) *riskResolver {
+	riskMu.RLock()
+	defer riskMu.RUnlock()
+	for _, item := range visibleRisks(currentAPIRisks, grantFromContext(ctx)) {
+		if item.Service == args.Service {
+			return &riskResolver{item}
+		}
+	}
+	return nil
+}
+
+func (q *graphqlQuery) Incidents(ctx context.Context, args struct{ Resolved *bool }) ([]*incidentResolver, error) {
+	if incidents == nil {
+		return nil, fmt.Errorf("incidents are not available")
+	}
+	grant := grantFromContext(ctx)
+	list := incidents.List(args.Resolved != nil && *args.Resolved)
+	riskMu.RLock()
+	defer riskMu.RUnlock()
+	result := make([]*incidentResolver, 0, len(list))
+	for _, inc := range list {
+		if seesAny(grant, inc.Services) {
+			result = append(result, &incidentResolver{newAPIIncident(inc)})
+		}
+	}
+	return result, nil
+}
+
+func (q *graphqlQuery) Incident(ctx context.Context, args struct{ ID graphql.ID }) *incidentResolver {
+	return lookupIncident(ctx, string(args.ID))
+}
+
+// lookupIncident returns the incident if the caller sees one of its services
+func lookupIncident(ctx context.Context, id string) *incidentResolver {
+	if incidents == nil || id == "" {
+		return nil
+	}
+	inc, ok := incidents.Get(id)
+	if !ok || !seesAny(grantFromContext(ctx), inc.Services) {
+		return nil
+	}
+	riskMu.RLock()
+	defer riskMu.RUnlock()
+	return &incidentResolver{newAPIIncident(inc)}
+}
+
+// riskResolver serves a risk item, its fields resolved by name; Int fields need int32
+type riskResolver struct {
+	APIRiskItem
+}
+
+func (r *riskResolver) Score() int32       { return int32(r.APIRiskItem.Score) }
+func (r *riskResolver) HealthScore() int32 { return int32(r.APIRiskItem.HealthScore) }
+
+func (r *riskResolver) InvestigationSteps() []string { return r.Investigation }
+
+func (r *riskResolver) Symptoms() []*symptomResolver {
+	result := make([]*symptomResolver, 0, len(r.APIRiskItem.Symptoms))
+	for _, s := range r.APIRiskItem.Symptoms {
+		result = append(result, &symptomResolver{s})
+	}
+	return result
+}
+
+func (r *riskResolver) Metrics() []*metricResolver {
+	result := make([]*metricResolver, 0, len(r.APIRiskItem.Metrics))
+	for _, m := range r.APIRiskItem.Metrics {
+		result = append(result, &metricResolver{m})
+	}
+	return result
+}
+
+func (r *riskResolver) Runbooks() []*APIRunbook {
+	result := make([]*APIRunbook, 0, len(r.APIRiskItem.Runbooks))
+	for i := range r.APIRiskItem.Runbooks {
+		result = append(result, &r.APIRiskItem.Runbooks[i])
+	}
+	return result
+}
+
+func (r *riskResolver) Incident(ctx context.Context) *incidentResolver {
+	return lookupIncident(ctx, r.IncidentID)
+}
+
+func (r *riskResolver) History(args struct{ From *string }) (*[]*riskPointResolver, error) {
+	if riskHistory == nil {
+		return nil, nil
+	}
+	from, err := parseGraphQLTime(args.From)
+	if err != nil {
+		return nil, err
+	}
+	points := riskHistory.History(r.Service, from)
+	result := make([]*riskPointResolver, 0, len(points))
+	for _, p := range points {
+		result = append(result, &riskPointResolver{p})
+	}
+	return &result, nil
+}
+
+func (r *riskResolver) MetricHistory(args struct {
+	Check *string
+	From  *string
+}) (*[]*metricSeriesResolver, error) {
+	if metricHistory == nil {
+		return nil, nil
+	}
+	from, err := parseGraphQLTime(args.From)
+	if err != nil {
+		return nil, err
+	}
+	check := ""
+	if args.Check != nil {
+		check = *args.Check
+	}
+	series := metricHistory.History(r.Service, check, from)
+	result := make([]*metricSeriesResolver, 0, len(series))
+	for _, s := range series {
+		result = append(result, &metricSeriesResolver{s})
+	}
+	return &result, nil
+}
+
+// parseGraphQLTime parses an optional RFC3339 argument, the zero time when absent
+func parseGraphQLTime(v *string) (time.Time, error) {
+	if v == nil || *v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, *v)
+	if err != nil {
+		return t, fmt.Errorf("invalid from timestamp, expected RFC3339")
+	}
+	return t, nil
+}
+
+type symptomResolver struct {
+	APISymptom
+}
+
+func (s *symptomResolver) Count() int32 { return int32(s.APISymptom.Count) }
+
+type metricResolver struct {
+	APIMetric
+}
+
+func (m *metricResolver) Weight() int32    { return int32(m.APIMetric.Weight) }
+func (m *metricResolver) Offenders() int32 { return int32(m.APIMetric.Offenders) }
+func (m *metricResolver) Series() int32    { return int32(m.APIMetric.Series) }
+func (m *metricResolver) Labels() []*labelResolver {
+	return newLabelResolvers(m.APIMetric.Labels)
+}
+
+type labelResolver struct {
+	Name  string
+	Value string
+}
+
+// newLabelResolvers lists labels sorted by name
+func newLabelResolvers(labels map[string]string) []*labelResolver {
+	result := make([]*labelResolver, 0, len(labels))
+	for name, value := range labels {
+		result = append(result, &labelResolver{Name: name, Value: value})
+	}
+	slices.SortFunc(result, func(a, b *labelResolver) int { return cmp.Compare(a.Name, b.Name) })
+	return result
+}
+
+type incidentResolver struct {
+	APIIncident
+}
+
+func (i *incidentResolver) ID() graphql.ID { return graphql.ID(i.APIIncident.ID) }
+
+func (i *incidentResolver) Risks() []*riskResolver {
+	result := make([]*riskResolver, 0, len(i.APIIncident.Risks))
+	for _, item := range i.APIIncident.Risks {
+		result = append(result, &riskResolver{item})
+	}
+	return result
+}
+
+type riskPointResolver struct {
+	riskhistory.Point
+}
+
+func (p *riskPointResolver) Time() string { return p.Point.Time.Format(time.RFC3339) }
+func (p *riskPointResolver) Score() int32 { return int32(p.Point.Score) }
+
+type metricSeriesResolver struct {
+	metrichistory.Series
+}
+
+func (s *metricSeriesResolver) Points() []*metricPointResolver {
+	result := make([]*metricPointResolver, 0, len(s.Series.Points))
+	for _, p := range s.Series.Points {
+		result = append(result, &metricPointResolver{p})
+	}
+	return result
+}
+
+type metricPointResolver struct {
+	metrichistory.Point
+}
+
+func (p *metricPointResolver) Time() string { return p.Point.Time.Format(time.RFC3339) }
+func (p *metricPointResolver) Labels() []*labelResolver {
+	return newLabelResolvers(p.Point.Labels)
+}
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"vigilant/pkg/api/pb"
+)
+
+// grpcAPI serves the risks and incidents to machine consumers over gRPC (see pb/vigilant.proto)
+type grpcAPI struct {
+	pb.UnimplementedVigilantServer
+}
+
+var grpcServer *grpc.Server
+
+// startGRPC serves the gRPC API on addr in the background, behind the same credentials and
+// access rules as the HTTP API
+func startGRPC(addr string) (*grpc.Server, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			ctx, err := grpcCaller(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := grpcCaller(ss.Context())
+			if err != nil {
+				return err
+			}
+			return handler(srv, &callerStream{ServerStream: ss, ctx: ctx})
+		}),
+	)
+	pb.RegisterVigilantServer(srv, &grpcAPI{})
+	go func() {
+		if err := srv.Serve(lis); err != nil {
+			log.Printf("gRPC server on %s failed: %v", addr, err)
+		}
+	}()
+	return srv, nil
+}
+
+// grpcCaller authenticates the call from its "authorization" or "x-api-key" metadata and
+// stores the caller's grant; every method reads, so viewers may call them all. gRPC has no SSO
+// session, so with SSO as the only authentication every call is rejected.
+func grpcCaller(ctx context.Context) (context.Context, error) {
+	if apiAuth == nil && oidcProvider == nil && !accessPolicy.hasKeys() {
+		return ctx, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	var token string
+	if values := md.Get("authorization"); len(values) > 0 {
+		token, _ = strings.CutPrefix(values[0], "Bearer ")
+	} else if values := md.Get("x-api-key"); len(values) > 0 {
+		token = values[0]
+	}
+	user, grant, err := authenticate(strings.TrimSpace(token))
+	if err != nil {
+		if errors.Is(err, errNoAccess) {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if !grant.Allows("viewer") {
+		return nil, status.Error(codes.PermissionDenied, "requires the viewer role")
+	}
+	return contextWithCaller(ctx, user, grant), nil
+}
+
+// callerStream carries the caller's grant to stream handlers
+type callerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *callerStream) Context() context.Context {
+	return s.ctx
+}
+
+// ListRisks returns the risk items of the last cycle
+func (g *grpcAPI) ListRisks(ctx context.Context, req *pb.ListRisksRequest) (*pb.ListRisksResponse, error) {
+	riskMu.RLock()
+	items := selectRisks(currentAPIRisks, grantFromContext(ctx), req.GetServices())
+	riskMu.RUnlock()
+	return &pb.ListRisksResponse{Risks: items}, nil
+}
+
+// WatchRisks sends the current risk items, then those of every cycle until the client leaves
+func (g *grpcAPI) WatchRisks(req *pb.WatchRisksRequest, stream pb.Vigilant_WatchRisksServer) error {
+	grant := grantFromContext(stream.Context())
+	updates, unsubscribe := subscribeRisks()
+	defer unsubscribe()
+
+	riskMu.RLock()
+	items := selectRisks(currentAPIRisks, grant, req.GetServices())
+	riskMu.RUnlock()
+	if err := stream.Send(&pb.RisksUpdate{Risks: items}); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case risks := <-updates:
+			if err := stream.Send(&pb.RisksUpdate{Risks: selectRisks(risks, grant, req.GetServices())}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ListIncidents returns the open or resolved incidents with a service the caller sees
+func (g *grpcAPI) ListIncidents(ctx context.Context, req *pb.ListIncidentsRequest) (*pb.ListIncidentsResponse, error) {
+	if incidents == nil {
+		return nil, status.Error(codes.Unavailable, "incidents are not tracked")
+	}
+	grant := grantFromContext(ctx)
+	list := incidents.List(req.GetResolved())
+	riskMu.RLock()
+	defer riskMu.RUnlock()
+	result := make([]*pb.Incident, 0, len(list))
+	for _, inc := range list {
+		if seesAny(grant, inc.Services) {
+			result = append(result, newPBIncident(newAPIIncident(inc)))
+		}
+	}
+	return &pb.ListIncidentsResponse{Incidents: result}, nil
+}
+
+// GetIncident returns an incident by ID
+func (g *grpcAPI) GetIncident(ctx context.Context, req *pb.GetIncidentRequest) (*pb.Incident, error) {
+	if incidents == nil {
+		return nil, status.Error(codes.Unavailable, "incidents are not tracked")
+	}
+	inc, ok := incidents.Get(req.GetId())
+	if !ok || !seesAny(grantFromContext(ctx), inc.Services) {
+		return nil, status.Errorf(codes.NotFound, "incident %q not found", req.GetId())
+	}
+	riskMu.RLock()
+	defer riskMu.RUnlock()
+	return newPBIncident(newAPIIncident(inc)), nil
+}
+
+// selectRisks converts the items the grant sees, limited to services when any are given
+func selectRisks(items []APIRiskItem, grant Grant, services []string) []*pb.RiskItem {
+	result := make([]*pb.RiskItem, 0, len(items))
+	for _, item := range visibleRisks(items, grant) {
+		if len(services) == 0 || slices.Contains(services, item.Service) {
+			result = append(result, newPBRiskItem(item))
+		}
+	}
+	return result
+}
+
+var (
+	riskWatchers   = make(map[chan []APIRiskItem]struct{})
+	riskWatchersMu sync.Mutex
+)
+
+// subscribeRisks returns a channel receiving the risk items of every update; a slow watcher
+// only gets the latest items
+func subscribeRisks() (<-chan []APIRiskItem, func()) {
+	ch := make(chan []APIRiskItem, 1)
+	riskWatchersMu.Lock()
+	riskWatchers[ch] = struct{}{}
+	riskWatchersMu.Unlock()
+	return ch, func() {
+		riskWatchersMu.Lock()
+		delete(riskWatchers, ch)
+		riskWatchersMu.Unlock()
+	}
+}
+
+// notifyRiskWatchers passes an update to the gRPC watchers
+func notifyRiskWatchers(items []APIRiskItem) {
+	riskWatchersMu.Lock()
+	defer riskWatchersMu.Unlock()
+	for ch := range riskWatchers {
+		select {
+		case <-ch: // Replace the update the watcher hasn't taken yet
+		default:
+		}
+		ch <- items
+	}
+}
+
+func newPBRiskItem(item APIRiskItem) *pb.RiskItem {
+	result := &pb.RiskItem{
+		IncidentId:         item.IncidentID,
+		Service:            item.Service,
+		Alert:              item.Alert,
+		Severity:           item.Severity,
+		State:              item.State,
+		SilencedBy:         item.SilencedBy,
+		InhibitedBy:        item.InhibitedBy,
+		EscalatedAt:        item.EscalatedAt,
+		Score:              int32(item.Score),
+		HealthScore:        int32(item.HealthScore),
+		Trend:              item.Trend,
+		StartsAt:           item.StartsAt,
+		Summary:            item.Summary,
+		Risk:               item.Risk,
+		Confidence:         item.Confidence,
+		RootCause:          item.RootCause,
+		ImmediateActions:   item.ImmediateActions,
+		InvestigationSteps: item.Investigation,
+		Prevention:         item.Prevention,
+		Timestamp:          item.Timestamp,
+	}
+	if item.Ack != nil {
+		result.Ack = &pb.Ack{By: item.Ack.By, Comment: item.Ack.Comment, At: item.Ack.At, Until: item.Ack.Until}
+	}
+	for _, s := range item.Symptoms {
+		result.Symptoms = append(result.Symptoms, &pb.Symptom{
+			Pattern:  s.Pattern,
+			Severity: s.Severity,
+			Count:    int32(s.Count),
+			Samples:  s.Samples,
+		})
+	}
+	for _, m := range item.Metrics {
+		result.Metrics = append(result.Metrics, &pb.Metric{
+			Name:      m.Name,
+			Value:     m.Value,
+			Operator:  m.Operator,
+			Threshold: m.Threshold,
+			Weight:    int32(m.Weight),
+			Labels:    m.Labels,
+			Offenders: int32(m.Offenders),
+			Series:    int32(m.Series),
+		})
+	}
+	for _, r := range item.Runbooks {
+		result.Runbooks = append(result.Runbooks, &pb.Runbook{Name: r.Name, Url: r.URL})
+	}
+	return result
+}
+
+func newPBIncident(inc APIIncident) *pb.Incident {
+	result := &pb.Incident{
+		Id:         inc.ID,
+		Service:    inc.Service,
+		Services:   inc.Services,
+		Alerts:     inc.Alerts,
+		Status:     inc.Status,
+		OpenedAt:   inc.OpenedAt,
+		UpdatedAt:  inc.UpdatedAt,
+		ResolvedAt: inc.ResolvedAt,
+	}
+	for _, item := range inc.Risks {
+		result.Risks = append(result.Risks, newPBRiskItem(item))
+	}
+	return result
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"vigilant/pkg/config"
+	"vigilant/pkg/inject"
+	"vigilant/pkg/prometheus"
+	"vigilant/pkg/risk"
+)
+
+// maxInjectDuration bounds how long injected data stays, so forgotten tests clean up after themselves
+const maxInjectDuration = 24 * time.Hour
+
+var alertInjector *injector
+
+// injector feeds synthetic alerts into the risk tracker and keeps their symptoms and metrics
+// for the monitoring loop
+type injector struct {
+	store   *inject.Store
+	tracker *risk.RiskTracker
+	profile func(service string) (config.ServiceProfile, bool)
+}
+
+// SetAlertInjector enables the /api/debug/inject endpoints; profile looks up the current profile
+// of a service
+func SetAlertInjector(store *inject.Store, tracker *risk.RiskTracker, profile func(service string) (config.ServiceProfile, bool)) {
+	alertInjector = &injector{store: store, tracker: tracker, profile: profile}
+}
+
+// InjectRequest is the body of POST /api/debug/inject
+type InjectRequest struct {
+	Service  string            `json:"service"`
+	Alert    string            `json:"alert"`              // Default: SyntheticAlert
+	Severity string            `json:"severity"`           // Default: warning
+	Labels   map[string]string `json:"labels,omitempty"`   // Extra alert labels
+	Symptoms []inject.Symptom  `json:"symptoms,omitempty"` // Matched log patterns
+	Metrics  []inject.Metric   `json:"metrics,omitempty"`  // Values of the profile's metric checks
+	Duration string            `json:"duration,omitempty"` // How long the alert and data stay, default 15m, at most 24h
+}
+
+func registerInjectRoutes(mux *http.ServeMux) {
+	handleAPI(mux, "GET /api/debug/inject", authorize("admin", handleInjectList))
+	handleAPI(mux, "POST /api/debug/inject", authorize("admin", handleInject))
+	handleAPI(mux, "DELETE /api/debug/inject", authorize("admin", handleInjectClear))
+}
+
+// handleInject serves POST /api/debug/inject: the alert is tracked like a pushed one, and the
+// symptoms and metric values are used instead of fetched ones while it is analyzed
+func handleInject(w http.ResponseWriter, r *http.Request) {
+	if alertInjector == nil {
+		http.Error(w, "alert injection is disabled", http.StatusNotFound)
+		return
+	}
+
+	var req InjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if req.Service == "" {
+		http.Error(w, "service is required", http.StatusBadRequest)
+		return
+	}
+	if !GrantFrom(r).Sees(req.Service) {
+		http.Error(w, "forbidden: service not in your teams", http.StatusForbidden)
+		return
+	}
+	profile, ok := alertInjector.profile(req.Service)
+	if !ok {
+		http.Error(w, fmt.Sprintf("service %s has no profile", req.Service), http.StatusBadRequest)
+		return
+	}
+	if req.Alert == "" {
+		req.Alert = "SyntheticAlert"
+	}
+	if req.Severity == "" {
+		req.Severity = "warning"
+	}
+	duration := 15 * time.Minute
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 || d > maxInjectDuration {
+			http.Error(w, "duration must be a positive duration of at most 24h, e.g. \"30m\"", http.StatusBadRequest)
+			return
+		}
+		duration = d
+	}
+	for _, sym := range req.Symptoms {
+		if strings.TrimSpace(sym.Pattern) == "" || sym.Count < 1 {
+			http.Error(w, "every symptom needs a pattern and a count of at least 1", http.StatusBadRequest)
+			return
+		}
+	}
+	checks := make(map[string]bool)
+	for _, check := range profile.GetEffectiveMetrics() {
+		checks[check.Name] = true
+	}
+	for _, m := range req.Metrics {
+		if !checks[m.Name] {
+			http.Error(w, fmt.Sprintf("service %s has no metric check %q", req.Service, m.Name), http.StatusBadRequest)
+			return
+		}
+	}
+
+	expires := time.Now().Add(duration)
+	injection := alertInjector.store.Add(inject.Injection{
+		Service:  req.Service,
+		Alerts:   []string{req.Alert},
+		Symptoms: req.Symptoms,
+		Metrics:  req.Metrics,
+		Expires:  expires,
+	})
+	alert := inject.Alert(req.Service, req.Alert, req.Severity, req.Labels, expires)
+	alertInjector.tracker.Receive([]prometheus.Alert{alert}, nil, duration)
+
+	log.Printf("[INJECT] %s injected %s on %s for %s (%d symptoms, %d metrics)",
+		callerName(r), req.Alert, req.Service, duration, len(req.Symptoms), len(req.Metrics))
+	writeJSON(w, http.StatusCreated, injection)
+}
+
+// handleInjectList serves GET /api/debug/inject, the data injected per service
+func handleInjectList(w http.ResponseWriter, r *http.Request) {
+	if alertInjector == nil {
+		http.Error(w, "alert injection is disabled", http.StatusNotFound)
+		return
+	}
+	grant := GrantFrom(r)
+	visible := []inject.Injection{}
+	for _, inj := range alertInjector.store.List() {
+		if grant.Sees(inj.Service) {
+			visible = append(visible, inj)
+		}
+	}
+	writeJSON(w, http.StatusOK, visible)
+}
+
+// handleInjectClear serves DELETE /api/debug/inject?service=, resolving the injected alerts of
+// the service, or of every service the caller sees
+func handleInjectClear(w http.ResponseWriter, r *http.Request) {
+	if alertInjector == nil {
+		http.Error(w, "alert injection is disabled", http.StatusNotFound)
+		return
+	}
+	grant := GrantFrom(r)
+	services := []string{r.URL.Query().Get("service")}
+	if services[0] == "" {
+		services = nil
+		for _, inj := range alertInjector.store.List() {
+			if grant.Sees(inj.Service) {
+				services = append(services, inj.Service)
+			}
+		}
+	} else if !grant.Sees(services[0]) {
+		http.Error(w, "forbidden: service not in your teams", http.StatusForbidden)
+		return
+	}
+
+	var resolved []prometheus.Alert
+	cleared := []string{}
+	for _, service := range services {
+		for _, inj := range alertInjector.store.Clear(service) {
+			for _, name := range inj.Alerts {
+				resolved = append(resolved, prometheus.Alert{Fingerprint: inject.Fingerprint(inj.Service, name), Service: inj.Service, Name: name})
+			}
+			cleared = append(cleared, inj.Service)
+		}
+	}
+	if len(resolved) > 0 {
+		alertInjector.tracker.Receive(nil, resolved, 0)
+	}
+	log.Printf("[INJECT] %s cleared injected data of %v", callerName(r), cleared)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"cleared": cleared})
+}
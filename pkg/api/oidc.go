@@ -0,0 +1,493 @@
+package api
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OIDCConfig connects the dashboard and API to an OpenID Connect identity provider
+type OIDCConfig struct {
+	Issuer        string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string        // This server's /auth/callback as registered with the provider
+	Scopes        []string      // Requested besides openid (default: profile, email)
+	SessionSecret string        // Signs session cookies; random when empty, so a restart logs everyone out
+	SessionTTL    time.Duration // How long a login lasts (default: 12h)
+}
+
+// OIDC logs users in with the authorization code flow and keeps them in a signed session cookie
+type OIDC struct {
+	cfg           OIDCConfig
+	authURL       string
+	tokenURL      string
+	jwksURL       string
+	endSessionURL string
+	sessionKey    []byte
+	secure        bool // Cookies only over HTTPS, when the redirect URL uses it
+	httpClient    *http.Client
+
+	keys      map[string]crypto.PublicKey // Provider signing keys by kid
+	keysFetch time.Time
+	keysMu    sync.Mutex
+}
+
+// Cookies of the login flow and the session
+const (
+	sessionCookie = "vigilant_session"
+	loginCookie   = "vigilant_login"
+)
+
+// loginTTL bounds how long a login may take at the provider
+const loginTTL = 10 * time.Minute
+
+// jwksRefreshInterval limits how often an unknown kid refetches the provider's keys
+const jwksRefreshInterval = time.Minute
+
+var oidcProvider *OIDC
+
+// SetOIDC requires an SSO session, or the credentials of SetAuth, on the dashboard and API
+func SetOIDC(o *OIDC) {
+	oidcProvider = o
+}
+
+// NewOIDC discovers the provider's endpoints from its issuer URL
+func NewOIDC(cfg OIDCConfig) (*OIDC, error) {
+	if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, errors.New("OIDC needs an issuer, a client ID and a redirect URL")
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"profile", "email"}
+	}
+	if cfg.SessionTTL <= 0 {
+		cfg.SessionTTL = 12 * time.Hour
+	}
+	o := &OIDC{
+		cfg:        cfg,
+		secure:     strings.HasPrefix(cfg.RedirectURL, "https://"),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	if cfg.SessionSecret != "" {
+		o.sessionKey = []byte(cfg.SessionSecret)
+	} else {
+		o.sessionKey = make([]byte, 32)
+		if _, err := rand.Read(o.sessionKey); err != nil {
+			return nil, err
+		}
+	}
+
+	var discovery struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		JWKSURI               string `json:"jwks_uri"`
+		EndSessionEndpoint    string `json:"end_session_endpoint"`
+	}
+	if err := o.getJSON(strings.TrimRight(cfg.Issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, errors.New("OIDC discovery document lacks the authorization, token or JWKS endpoint")
+	}
+	// ID tokens carry the issuer exactly as the provider publishes it
+	if discovery.Issuer != "" {
+		o.cfg.Issuer = discovery.Issuer
+	}
+	o.authURL = discovery.AuthorizationEndpoint
+	o.tokenURL = discovery.TokenEndpoint
+	o.jwksURL = discovery.JWKSURI
+	o.endSessionURL = discovery.EndSessionEndpoint
+	return o, nil
+}
+
+// OIDCFromEnv builds the provider from OIDC_ISSUER, OIDC_CLIENT_ID, OIDC_CLIENT_SECRET,
+// OIDC_REDIRECT_URL, OIDC_SCOPES, OIDC_SESSION_SECRET and OIDC_SESSION_HOURS; nil without
+// an issuer
+func OIDCFromEnv() (*OIDC, error) {
+	if os.Getenv("OIDC_ISSUER") == "" {
+		return nil, nil
+	}
+	cfg := OIDCConfig{
+		Issuer:        os.Getenv("OIDC_ISSUER"),
+		ClientID:      os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret:  os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:   os.Getenv("OIDC_REDIRECT_URL"),
+		SessionSecret: os.Getenv("OIDC_SESSION_SECRET"),
+	}
+	for _, scope := range strings.Split(os.Getenv("OIDC_SCOPES"), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" && scope != "openid" {
+			cfg.Scopes = append(cfg.Scopes, scope)
+		}
+	}
+	if v, err := strconv.Atoi(os.Getenv("OIDC_SESSION_HOURS")); err == nil && v > 0 {
+		cfg.SessionTTL = time.Duration(v) * time.Hour
+	}
+	return NewOIDC(cfg)
+}
+
+// loginState is what the login cookie remembers between /auth/login and /auth/callback
+type loginState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"` // PKCE code verifier
+	Return   string `json:"return"`   // Dashboard page to go back to
+	Expires  int64  `json:"exp"`
+}
+
+// session is the content of the session cookie
+type session struct {
+	User
+	Expires int64 `json:"exp"`
+}
+
+// handleLogin serves GET /auth/login?return=, sending the browser to the provider
+func handleLogin(w http.ResponseWriter, r *http.Request) {
+	o := oidcProvider
+	if o == nil {
+		http.Error(w, "SSO is disabled", http.StatusNotFound)
+		return
+	}
+
+	login := loginState{
+		State:    randomString(),
+		Nonce:    randomString(),
+		Verifier: randomString(),
+		Return:   localPath(r.URL.Query().Get("return")),
+		Expires:  time.Now().Add(loginTTL).Unix(),
+	}
+	o.setCookie(w, loginCookie, o.sign(login), loginTTL)
+
+	challenge := sha256.Sum256([]byte(login.Verifier))
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", o.cfg.ClientID)
+	params.Set("redirect_uri", o.cfg.RedirectURL)
+	params.Set("scope", strings.Join(append([]string{"openid"}, o.cfg.Scopes...), " "))
+	params.Set("state", login.State)
+	params.Set("nonce", login.Nonce)
+	params.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(o.authURL, "?") {
+		separator = "&"
+	}
+	http.Redirect(w, r, o.authURL+separator+params.Encode(), http.StatusFound)
+}
+
+// handleCallback serves GET /auth/callback: it redeems the authorization code, verifies the
+// ID token and starts the session
+func handleCallback(w http.ResponseWriter, r *http.Request) {
+	o := oidcProvider
+	if o == nil {
+		http.Error(w, "SSO is disabled", http.StatusNotFound)
+		return
+	}
+	query := r.URL.Query()
+	if msg := query.Get("error"); msg != "" {
+		http.Error(w, "login failed: "+msg+" "+query.Get("error_description"), http.StatusUnauthorized)
+		return
+	}
+
+	var login loginState
+	cookie, err := r.Cookie(loginCookie)
+	if err != nil || !o.verify(cookie.Value, &login) || time.Now().Unix() > login.Expires {
+		http.Error(w, "login expired, try again", http.StatusBadRequest)
+		return
+	}
+	if query.Get("state") == "" || !hmac.Equal([]byte(query.Get("state")), []byte(login.State)) {
+		http.Error(w, "invalid login state", http.StatusBadRequest)
+		return
+	}
+	o.setCookie(w, loginCookie, "", -1)
+
+	idToken, err := o.exchange(query.Get("code"), login.Verifier)
+	if err != nil {
+		log.Printf("OIDC code exchange failed: %v", err)
+		http.Error(w, "login failed", http.StatusBadGateway)
+		return
+	}
+	user, err := o.verifyIDToken(idToken, login.Nonce)
+	if err != nil {
+		log.Printf("OIDC ID token rejected: %v", err)
+		http.Error(w, "login failed", http.StatusUnauthorized)
+		return
+	}
+
+	o.setCookie(w, sessionCookie, o.sign(session{User: user, Expires: time.Now().Add(o.cfg.SessionTTL).Unix()}), o.cfg.SessionTTL)
+	log.Printf("%s logged in", user)
+	http.Redirect(w, r, login.Return, http.StatusFound)
+}
+
+// handleLogout serves GET /auth/logout, ending the session here and, when the provider
+// supports it, at the provider
+func handleLogout(w http.ResponseWriter, r *http.Request) {
+	o := oidcProvider
+	if o == nil {
+		http.Error(w, "SSO is disabled", http.StatusNotFound)
+		return
+	}
+	o.setCookie(w, sessionCookie, "", -1)
+	target := "/"
+	if o.endSessionURL != "" {
+		params := url.Values{"client_id": {o.cfg.ClientID}}
+		if u, err := url.Parse(o.cfg.RedirectURL); err == nil {
+			params.Set("post_logout_redirect_uri", u.Scheme+"://"+u.Host+"/")
+		}
+		target = o.endSessionURL + "?" + params.Encode()
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// handleMe serves GET /api/me, the user behind the request
+func handleMe(w http.ResponseWriter, r *http.Request) {
+	user, ok := UserFrom(r)
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"authenticated": r.Context().Value(userKey{}) != nil, "sso": oidcProvider != nil})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"authenticated": true, "sso": oidcProvider != nil, "user": user, "access": GrantFrom(r)})
+}
+
+// session returns the user of a valid session cookie
+func (o *OIDC) session(r *http.Request) (User, bool) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return User{}, false
+	}
+	var s session
+	if !o.verify(cookie.Value, &s) || time.Now().Unix() > s.Expires || s.Subject == "" {
+		return User{}, false
+	}
+	return s.User, true
+}
+
+// exchange redeems an authorization code for the ID token
+func (o *OIDC) exchange(code, verifier string) (string, error) {
+	if code == "" {
+		return "", errors.New("no authorization code")
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", o.cfg.RedirectURL)
+	form.Set("code_verifier", verifier)
+	form.Set("client_id", o.cfg.ClientID)
+	if o.cfg.ClientSecret != "" {
+		form.Set("client_secret", o.cfg.ClientSecret)
+	}
+
+	resp, err := o.httpClient.PostForm(o.tokenURL, form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return "", fmt.Errorf("failed to parse token response: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return "", errors.New("token response has no id_token")
+	}
+	return tokens.IDToken, nil
+}
+
+// verifyIDToken checks the ID token's signature against the provider's keys, its issuer,
+// audience and nonce, and returns its user
+func (o *OIDC) verifyIDToken(token, nonce string) (User, error) {
+	claims, err := verifyJWT(token, func(header jwtHeader) ([]byte, crypto.PublicKey, error) {
+		key, err := o.key(header.Kid)
+		return nil, key, err
+	}, time.Now())
+	if err != nil {
+		return User{}, err
+	}
+	if err := claims.check(o.cfg.Issuer, o.cfg.ClientID); err != nil {
+		return User{}, err
+	}
+	if claims.Nonce != nonce {
+		return User{}, errors.New("nonce mismatch")
+	}
+	if claims.Subject == "" {
+		return User{}, errors.New("ID token has no subject")
+	}
+	return claims.user(), nil
+}
+
+// key returns the provider's signing key with the given kid, refetching the key set when the
+// kid is unknown, e.g. after a key rotation
+func (o *OIDC) key(kid string) (crypto.PublicKey, error) {
+	o.keysMu.Lock()
+	defer o.keysMu.Unlock()
+
+	if key, ok := o.lookupKeyLocked(kid); ok {
+		return key, nil
+	}
+	if time.Since(o.keysFetch) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	o.keysFetch = time.Now()
+	keys, err := o.fetchKeys()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the provider's keys: %w", err)
+	}
+	o.keys = keys
+	if key, ok := o.lookupKeyLocked(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKeyLocked finds the key of kid; a token without kid matches a single-key set
+func (o *OIDC) lookupKeyLocked(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(o.keys) == 1 {
+		for _, key := range o.keys {
+			return key, true
+		}
+	}
+	key, ok := o.keys[kid]
+	return key, ok
+}
+
+// fetchKeys loads the RSA and P-256 keys of the provider's JWKS
+func (o *OIDC) fetchKeys() (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := o.getJSON(o.jwksURL, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch {
+		case k.Kty == "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case k.Kty == "EC" && k.Crv == "P-256":
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	return keys, nil
+}
+
+func (o *OIDC) getJSON(u string, v interface{}) error {
+	resp, err := o.httpClient.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned HTTP %d", u, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// sign encodes v as a cookie value with an HMAC of the session key
+func (o *OIDC) sign(v interface{}) string {
+	data, _ := json.Marshal(v)
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	mac := hmac.New(sha256.New, o.sessionKey)
+	mac.Write([]byte(payload))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verify decodes a cookie value made by sign into v, reporting whether its HMAC is valid
+func (o *OIDC) verify(value string, v interface{}) bool {
+	payload, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return false
+	}
+	got, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, o.sessionKey)
+	mac.Write([]byte(payload))
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return false
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	return err == nil && json.Unmarshal(data, v) == nil
+}
+
+// setCookie sets a cookie for ttl; a negative ttl deletes it
+func (o *OIDC) setCookie(w http.ResponseWriter, name, value string, ttl time.Duration) {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   o.secure,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if ttl < 0 {
+		cookie.MaxAge = -1
+	} else {
+		cookie.MaxAge = int(ttl.Seconds())
+	}
+	http.SetCookie(w, cookie)
+}
+
+func randomString() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// localPath keeps redirects after login on this server
+func localPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+	return path
+}
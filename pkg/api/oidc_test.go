@@ -0,0 +1,205 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testOIDC returns a provider whose JWKS endpoint serves keys
+func testOIDC(t *testing.T, keys []map[string]string) *OIDC {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	t.Cleanup(srv.Close)
+	return &OIDC{
+		cfg:        OIDCConfig{Issuer: "https://idp.example.com", ClientID: "vigilant", SessionTTL: time.Hour},
+		jwksURL:    srv.URL,
+		sessionKey: []byte("session-key"),
+		httpClient: srv.Client(),
+	}
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) map[string]string {
+	size := (key.Curve.Params().BitSize + 7) / 8
+	return map[string]string{
+		"kty": "EC",
+		"kid": kid,
+		"crv": key.Curve.Params().Name,
+		"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, size))),
+		"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, size))),
+	}
+}
+
+func TestFetchKeys(t *testing.T) {
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	encryption := rsaJWK("enc", &testRSAKey.PublicKey)
+	encryption["use"] = "enc"
+	malformed := rsaJWK("malformed", &testRSAKey.PublicKey)
+	malformed["n"] = "not base64!"
+
+	o := testOIDC(t, []map[string]string{
+		rsaJWK("rsa", &testRSAKey.PublicKey),
+		ecJWK("ec", &testECKey.PublicKey),
+		ecJWK("p384", &p384.PublicKey),
+		encryption,
+		malformed,
+		{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"},
+	})
+	keys, err := o.fetchKeys()
+	if err != nil {
+		t.Fatalf("fetchKeys() error = %v", err)
+	}
+
+	tests := []struct {
+		kid  string
+		want interface{}
+	}{
+		{kid: "rsa", want: &testRSAKey.PublicKey},
+		{kid: "ec", want: &testECKey.PublicKey},
+		{kid: "p384"},
+		{kid: "enc"},
+		{kid: "malformed"},
+		{kid: "hmac"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kid, func(t *testing.T) {
+			key, ok := keys[tt.kid]
+			switch want := tt.want.(type) {
+			case nil:
+				if ok {
+					t.Errorf("fetchKeys() kept key %q", tt.kid)
+				}
+			case *rsa.PublicKey:
+				if !want.Equal(key) {
+					t.Errorf("fetchKeys() key %q = %v, want %v", tt.kid, key, want)
+				}
+			case *ecdsa.PublicKey:
+				if !want.Equal(key) {
+					t.Errorf("fetchKeys() key %q = %v, want %v", tt.kid, key, want)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	o := testOIDC(t, []map[string]string{rsaJWK("rsa", &testRSAKey.PublicKey), ecJWK("ec", &testECKey.PublicKey)})
+	claims := func(override map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"iss":   "https://idp.example.com",
+			"sub":   "alice",
+			"aud":   "vigilant",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"nonce": "nonce-1",
+		}
+		for k, v := range override {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+	rs256 := map[string]string{"alg": "RS256", "kid": "rsa"}
+
+	tests := []struct {
+		name    string
+		token   string
+		nonce   string
+		wantErr string
+	}{
+		{name: "RS256", token: signToken(t, rs256, claims(nil), testRSAKey), nonce: "nonce-1"},
+		{name: "ES256", token: signToken(t, map[string]string{"alg": "ES256", "kid": "ec"}, claims(nil), testECKey), nonce: "nonce-1"},
+		{name: "aud list", token: signToken(t, rs256, claims(map[string]interface{}{"aud": []string{"grafana", "vigilant"}}), testRSAKey), nonce: "nonce-1"},
+		{name: "aud list without client", token: signToken(t, rs256, claims(map[string]interface{}{"aud": []string{"grafana"}}), testRSAKey), nonce: "nonce-1", wantErr: "audience"},
+		{name: "other aud", token: signToken(t, rs256, claims(map[string]interface{}{"aud": "grafana"}), testRSAKey), nonce: "nonce-1", wantErr: "audience"},
+		{name: "other issuer", token: signToken(t, rs256, claims(map[string]interface{}{"iss": "https://evil.example.com"}), testRSAKey), nonce: "nonce-1", wantErr: "unexpected issuer"},
+		{name: "nonce mismatch", token: signToken(t, rs256, claims(nil), testRSAKey), nonce: "nonce-2", wantErr: "nonce mismatch"},
+		{name: "missing nonce", token: signToken(t, rs256, claims(map[string]interface{}{"nonce": nil}), testRSAKey), nonce: "nonce-1", wantErr: "nonce mismatch"},
+		{name: "missing subject", token: signToken(t, rs256, claims(map[string]interface{}{"sub": nil}), testRSAKey), nonce: "nonce-1", wantErr: "no subject"},
+		{name: "expired", token: signToken(t, rs256, claims(map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()}), testRSAKey), nonce: "nonce-1", wantErr: "token expired"},
+		{name: "tampered payload", token: tamper(signToken(t, rs256, claims(nil), testRSAKey), claims(map[string]interface{}{"sub": "admin"})), nonce: "nonce-1", wantErr: "bad signature"},
+		{name: "key of another kid", token: signToken(t, map[string]string{"alg": "RS256", "kid": "ec"}, claims(nil), testRSAKey), nonce: "nonce-1", wantErr: "unsupported signing algorithm"},
+		{name: "HS256", token: signToken(t, map[string]string{"alg": "HS256", "kid": "rsa"}, claims(nil), o.sessionKey), nonce: "nonce-1", wantErr: "unsupported signing algorithm"},
+		{name: "alg none", token: signToken(t, map[string]string{"alg": "none", "kid": "rsa"}, claims(nil), nil), nonce: "nonce-1", wantErr: "unsupported signing algorithm"},
+		{name: "unknown kid", token: signToken(t, map[string]string{"alg": "RS256", "kid": "rotated"}, claims(nil), testRSAKey), nonce: "nonce-1", wantErr: "unknown signing key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := o.verifyIDToken(tt.token, tt.nonce)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("verifyIDToken() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verifyIDToken() error = %v", err)
+			}
+			if user.Subject != "alice" {
+				t.Errorf("verifyIDToken() subject = %q, want alice", user.Subject)
+			}
+		})
+	}
+}
+
+func TestSessionCookie(t *testing.T) {
+	o := testOIDC(t, nil)
+	other := testOIDC(t, nil)
+	other.sessionKey = []byte("other-key")
+
+	valid := o.sign(session{User: User{Subject: "alice"}, Expires: time.Now().Add(time.Hour).Unix()})
+	_, signature, _ := strings.Cut(valid, ".")
+	forged, _ := json.Marshal(session{User: User{Subject: "admin"}, Expires: time.Now().Add(time.Hour).Unix()})
+
+	tests := []struct {
+		name   string
+		cookie string
+		want   bool
+	}{
+		{name: "valid", cookie: valid, want: true},
+		{name: "forged payload", cookie: base64.RawURLEncoding.EncodeToString(forged) + "." + signature},
+		{name: "signed with another key", cookie: other.sign(session{User: User{Subject: "admin"}, Expires: time.Now().Add(time.Hour).Unix()})},
+		{name: "unsigned", cookie: base64.RawURLEncoding.EncodeToString(forged)},
+		{name: "empty signature", cookie: base64.RawURLEncoding.EncodeToString(forged) + "."},
+		{name: "expired", cookie: o.sign(session{User: User{Subject: "alice"}, Expires: time.Now().Add(-time.Minute).Unix()})},
+		{name: "no subject", cookie: o.sign(session{Expires: time.Now().Add(time.Hour).Unix()})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/risks", nil)
+			r.AddCookie(&http.Cookie{Name: sessionCookie, Value: tt.cookie})
+			user, ok := o.session(r)
+			if ok != tt.want {
+				t.Fatalf("session() ok = %v, want %v", ok, tt.want)
+			}
+			if ok && user.Subject != "alice" {
+				t.Errorf("session() subject = %q, want alice", user.Subject)
+			}
+		})
+	}
+}
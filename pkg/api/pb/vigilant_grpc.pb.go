@@ -0,0 +1,260 @@
+// gRPC API of Vigilant, for machine consumers of the risks and incidents the HTTP API and
+// WebSocket serve. Regenerate the Go code with:
+//   protoc --go_out=. --go_opt=paths=source_relative \
+//     --go-grpc_out=. --go-grpc_opt=paths=source_relative pkg/api/pb/vigilant.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: pkg/api/pb/vigilant.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Vigilant_ListRisks_FullMethodName     = "/vigilant.v1.Vigilant/ListRisks"
+	Vigilant_WatchRisks_FullMethodName    = "/vigilant.v1.Vigilant/WatchRisks"
+	Vigilant_ListIncidents_FullMethodName = "/vigilant.v1.Vigilant/ListIncidents"
+	Vigilant_GetIncident_FullMethodName   = "/vigilant.v1.Vigilant/GetIncident"
+)
+
+// VigilantClient is the client API for Vigilant service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Vigilant serves the analyzed risks and incidents. Calls need the same credentials as the
+// HTTP API, an API key or JWT in the "authorization" ("Bearer <token>") or "x-api-key"
+// metadata, and see the services of the caller's teams only.
+type VigilantClient interface {
+	// ListRisks returns the risk items of the last analysis cycle
+	ListRisks(ctx context.Context, in *ListRisksRequest, opts ...grpc.CallOption) (*ListRisksResponse, error)
+	// WatchRisks sends the current risk items, then the items of every new cycle
+	WatchRisks(ctx context.Context, in *WatchRisksRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RisksUpdate], error)
+	// ListIncidents returns the open incidents, or the resolved ones
+	ListIncidents(ctx context.Context, in *ListIncidentsRequest, opts ...grpc.CallOption) (*ListIncidentsResponse, error)
+	// GetIncident returns an incident by ID
+	GetIncident(ctx context.Context, in *GetIncidentRequest, opts ...grpc.CallOption) (*Incident, error)
+}
+
+type vigilantClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewVigilantClient(cc grpc.ClientConnInterface) VigilantClient {
+	return &vigilantClient{cc}
+}
+
+func (c *vigilantClient) ListRisks(ctx context.Context, in *ListRisksRequest, opts ...grpc.CallOption) (*ListRisksResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListRisksResponse)
+	err := c.cc.Invoke(ctx, Vigilant_ListRisks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vigilantClient) WatchRisks(ctx context.Context, in *WatchRisksRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RisksUpdate], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Vigilant_ServiceDesc.Streams[0], Vigilant_WatchRisks_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRisksRequest, RisksUpdate]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Vigilant_WatchRisksClient = grpc.ServerStreamingClient[RisksUpdate]
+
+func (c *vigilantClient) ListIncidents(ctx context.Context, in *ListIncidentsRequest, opts ...grpc.CallOption) (*ListIncidentsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListIncidentsResponse)
+	err := c.cc.Invoke(ctx, Vigilant_ListIncidents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vigilantClient) GetIncident(ctx context.Context, in *GetIncidentRequest, opts ...grpc.CallOption) (*Incident, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Incident)
+	err := c.cc.Invoke(ctx, Vigilant_GetIncident_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// VigilantServer is the server API for Vigilant service.
+// All implementations must embed UnimplementedVigilantServer
+// for forward compatibility.
+//
+// Vigilant serves the analyzed risks and incidents. Calls need the same credentials as the
+// HTTP API, an API key or JWT in the "authorization" ("Bearer <token>") or "x-api-key"
+// metadata, and see the services of the caller's teams only.
+type VigilantServer interface {
+	// ListRisks returns the risk items of the last analysis cycle
+	ListRisks(context.Context, *ListRisksRequest) (*ListRisksResponse, error)
+	// WatchRisks sends the current risk items, then the items of every new cycle
+	WatchRisks(*WatchRisksRequest, grpc.ServerStreamingServer[RisksUpdate]) error
+	// ListIncidents returns the open incidents, or the resolved ones
+	ListIncidents(context.Context, *ListIncidentsRequest) (*ListIncidentsResponse, error)
+	// GetIncident returns an incident by ID
+	GetIncident(context.Context, *GetIncidentRequest) (*Incident, error)
+	mustEmbedUnimplementedVigilantServer()
+}
+
+// UnimplementedVigilantServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedVigilantServer struct{}
+
+func (UnimplementedVigilantServer) ListRisks(context.Context, *ListRisksRequest) (*ListRisksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRisks not implemented")
+}
+func (UnimplementedVigilantServer) WatchRisks(*WatchRisksRequest, grpc.ServerStreamingServer[RisksUpdate]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchRisks not implemented")
+}
+func (UnimplementedVigilantServer) ListIncidents(context.Context, *ListIncidentsRequest) (*ListIncidentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListIncidents not implemented")
+}
+func (UnimplementedVigilantServer) GetIncident(context.Context, *GetIncidentRequest) (*Incident, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetIncident not implemented")
+}
+func (UnimplementedVigilantServer) mustEmbedUnimplementedVigilantServer() {}
+func (UnimplementedVigilantServer) testEmbeddedByValue()                  {}
+
+// UnsafeVigilantServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to VigilantServer will
+// result in compilation errors.
+type UnsafeVigilantServer interface {
+	mustEmbedUnimplementedVigilantServer()
+}
+
+func RegisterVigilantServer(s grpc.ServiceRegistrar, srv VigilantServer) {
+	// If the following call pancis, it indicates UnimplementedVigilantServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Vigilant_ServiceDesc, srv)
+}
+
+func _Vigilant_ListRisks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRisksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VigilantServer).ListRisks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Vigilant_ListRisks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VigilantServer).ListRisks(ctx, req.(*ListRisksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Vigilant_WatchRisks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRisksRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(VigilantServer).WatchRisks(m, &grpc.GenericServerStream[WatchRisksRequest, RisksUpdate]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Vigilant_WatchRisksServer = grpc.ServerStreamingServer[RisksUpdate]
+
+func _Vigilant_ListIncidents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListIncidentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VigilantServer).ListIncidents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Vigilant_ListIncidents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VigilantServer).ListIncidents(ctx, req.(*ListIncidentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Vigilant_GetIncident_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetIncidentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VigilantServer).GetIncident(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Vigilant_GetIncident_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VigilantServer).GetIncident(ctx, req.(*GetIncidentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Vigilant_ServiceDesc is the grpc.ServiceDesc for Vigilant service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Vigilant_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "vigilant.v1.Vigilant",
+	HandlerType: (*VigilantServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListRisks",
+			Handler:    _Vigilant_ListRisks_Handler,
+		},
+		{
+			MethodName: "ListIncidents",
+			Handler:    _Vigilant_ListIncidents_Handler,
+		},
+		{
+			MethodName: "GetIncident",
+			Handler:    _Vigilant_GetIncident_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchRisks",
+			Handler:       _Vigilant_WatchRisks_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "pkg/api/pb/vigilant.proto",
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+
+	"vigilant/pkg/config"
+)
+
+// maxProfileSize bounds the YAML accepted by the profile endpoints
+const maxProfileSize = 1 << 20
+
+var (
+	profileFiles   *config.ProfileFiles
+	reloadProfiles func() ([]string, error)
+)
+
+// SetProfileFiles enables the endpoints managing the service profiles; saved profiles take
+// effect on the next reload, or restart when reloading is disabled
+func SetProfileFiles(files *config.ProfileFiles) {
+	profileFiles = files
+}
+
+// SetProfileReload enables POST /api/config/reload; reload loads the profiles into the running
+// monitoring loop and returns the loaded services
+func SetProfileReload(reload func() ([]string, error)) {
+	reloadProfiles = reload
+}
+
+// registerProfileRoutes adds the service profile endpoints; writes need the admin role over the
+// service and, for a scoped admin, a team of theirs in the profile
+func registerProfileRoutes(mux *http.ServeMux) {
+	handleAPI(mux, "GET /api/config/services", authorize("viewer", handleListProfiles))
+	handleAPI(mux, "GET /api/config/services/{service}", authorizeService("viewer", handleGetProfile))
+	handleAPI(mux, "POST /api/config/services/{service}", authorizeService("admin", handleSaveProfile))
+	handleAPI(mux, "PUT /api/config/services/{service}", authorizeService("admin", handleSaveProfile))
+	handleAPI(mux, "DELETE /api/config/services/{service}", authorizeService("admin", handleDeleteProfile))
+	handleAPI(mux, "POST /api/config/reload", authorizeAll("admin", handleReloadProfiles))
+}
+
+// handleListProfiles serves GET /api/config/services, the profiles the caller sees
+func handleListProfiles(w http.ResponseWriter, r *http.Request) {
+	if profileFiles == nil {
+		http.Error(w, "service profile management is disabled", http.StatusNotFound)
+		return
+	}
+	files, err := profileFiles.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	grant := GrantFrom(r)
+	visible := make([]config.ProfileFile, 0, len(files))
+	for _, f := range files {
+		if grant.Sees(f.Name) {
+			visible = append(visible, f)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"services": visible})
+}
+
+// handleGetProfile serves GET /api/config/services/{service}, the profile's YAML as stored on
+// disk, with environment variables unexpanded
+func handleGetProfile(w http.ResponseWriter, r *http.Request) {
+	if profileFiles == nil {
+		http.Error(w, "service profile management is disabled", http.StatusNotFound)
+		return
+	}
+	data, err := profileFiles.Read(r.PathValue("service"))
+	if err != nil {
+		writeProfileError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(data)
+}
+
+// handleSaveProfile serves POST (create) and PUT (replace) /api/config/services/{service} with
+// the YAML profile as body
+func handleSaveProfile(w http.ResponseWriter, r *http.Request) {
+	if profileFiles == nil {
+		http.Error(w, "service profile management is disabled", http.StatusNotFound)
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxProfileSize))
+	if err != nil {
+		http.Error(w, "failed to read profile: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	service := r.PathValue("service")
+	if grant := GrantFrom(r); !grant.Unscoped() {
+		profile, err := config.ParseServiceProfile(service, data)
+		if err != nil {
+			writeProfileError(w, fmt.Errorf("%w: %v", config.ErrInvalidProfile, err))
+			return
+		}
+		if !slices.Contains(grant.Teams, profile.Metadata.Team) {
+			http.Error(w, "forbidden: the profile's team must be one of yours", http.StatusForbidden)
+			return
+		}
+	}
+	save, status, action := profileFiles.Update, http.StatusOK, "updated"
+	if r.Method == http.MethodPost {
+		save, status, action = profileFiles.Create, http.StatusCreated, "created"
+	}
+	if _, err := save(service, data); err != nil {
+		writeProfileError(w, err)
+		return
+	}
+	log.Printf("Service profile %s %s by %s", service, action, callerName(r))
+	writeJSON(w, status, reloadAfterWrite(map[string]interface{}{"service": service, "action": action}))
+}
+
+// handleDeleteProfile serves DELETE /api/config/services/{service}
+func handleDeleteProfile(w http.ResponseWriter, r *http.Request) {
+	if profileFiles == nil {
+		http.Error(w, "service profile management is disabled", http.StatusNotFound)
+		return
+	}
+	service := r.PathValue("service")
+	if err := profileFiles.Delete(service); err != nil {
+		writeProfileError(w, err)
+		return
+	}
+	log.Printf("Service profile %s deleted by %s", service, callerName(r))
+	writeJSON(w, http.StatusOK, reloadAfterWrite(map[string]interface{}{"service": service, "action": "deleted"}))
+}
+
+// handleReloadProfiles serves POST /api/config/reload
+func handleReloadProfiles(w http.ResponseWriter, r *http.Request) {
+	if reloadProfiles == nil {
+		http.Error(w, "profile reload is disabled", http.StatusNotFound)
+		return
+	}
+	services, err := reloadProfiles()
+	if err != nil {
+		http.Error(w, "reload failed, keeping the loaded profiles: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Service profiles reloaded by %s", callerName(r))
+	writeJSON(w, http.StatusOK, map[string]interface{}{"services": services})
+}
+
+// reloadAfterWrite applies a saved profile right away when reloading is enabled, else the
+// response tells that it waits for a restart
+func reloadAfterWrite(result map[string]interface{}) map[string]interface{} {
+	if reloadProfiles == nil {
+		result["restart_required"] = true
+		return result
+	}
+	if _, err := reloadProfiles(); err != nil {
+		result["reload_error"] = err.Error()
+		return result
+	}
+	result["reloaded"] = true
+	return result
+}
+
+// writeProfileError maps profile errors to statuses
+func writeProfileError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, config.ErrInvalidProfile):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, config.ErrProfileNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, config.ErrProfileExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, fmt.Sprintf("failed to access profile: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// callerName names the caller in logs
+func callerName(r *http.Request) string {
+	if user, ok := UserFrom(r); ok {
+		return user.String()
+	}
+	return "anonymous"
+}
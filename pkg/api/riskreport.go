@@ -0,0 +1,107 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"vigilant/pkg/report"
+)
+
+// handleRiskReport serves GET /api/risks/{service}/report.md?alert=, the current analysis of a
+// service as Markdown for tickets and chats; every active alert gets a section unless one is named
+func handleRiskReport(w http.ResponseWriter, r *http.Request) {
+	service := r.PathValue("service")
+	alert := r.URL.Query().Get("alert")
+	var items []APIRiskItem
+	riskMu.RLock()
+	for _, item := range currentAPIRisks {
+		if item.Service == service && (alert == "" || item.Alert == alert) {
+			items = append(items, item)
+		}
+	}
+	riskMu.RUnlock()
+
+	if len(items) == 0 {
+		http.Error(w, fmt.Sprintf("no active risk for service %s", service), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", service+"-report.md"))
+	w.Write([]byte(renderRiskReport(service, items, time.Now())))
+}
+
+// renderRiskReport renders the correlation and analysis of a service's risk items
+func renderRiskReport(service string, items []APIRiskItem, now time.Time) string {
+	analyses := make([]report.RiskAnalysis, 0, len(items))
+	for _, item := range items {
+		analyses = append(analyses, riskAnalysis(item))
+	}
+	return report.RiskReport(service, analyses, now)
+}
+
+// riskAnalysis converts a risk item for the report
+func riskAnalysis(item APIRiskItem) report.RiskAnalysis {
+	a := report.RiskAnalysis{
+		Alert:            item.Alert,
+		Severity:         item.Severity,
+		Risk:             item.Risk,
+		Score:            item.Score,
+		HealthScore:      item.HealthScore,
+		Confidence:       item.Confidence,
+		Trend:            item.Trend,
+		State:            item.State,
+		IncidentID:       item.IncidentID,
+		StartsAt:         parseTime(item.StartsAt),
+		AnalyzedAt:       parseTime(item.Timestamp),
+		EscalatedAt:      parseTime(item.EscalatedAt),
+		Summary:          item.Summary,
+		RootCause:        item.RootCause,
+		ImmediateActions: item.ImmediateActions,
+		Investigation:    item.Investigation,
+		Prevention:       item.Prevention,
+	}
+	if item.Ack != nil {
+		a.Ack = &report.Ack{By: item.Ack.By, At: parseTime(item.Ack.At), Until: parseTime(item.Ack.Until)}
+	}
+	for _, s := range item.Symptoms {
+		a.Symptoms = append(a.Symptoms, report.Symptom{Pattern: s.Pattern, Severity: s.Severity, Count: s.Count})
+	}
+	for _, m := range item.Metrics {
+		a.Metrics = append(a.Metrics, report.Metric{
+			Name:      m.Name,
+			Value:     m.Value,
+			Operator:  m.Operator,
+			Threshold: m.Threshold,
+			Offenders: m.Offenders,
+			Series:    m.Series,
+		})
+	}
+	for _, slo := range item.SLOs {
+		converted := report.SLO{Name: slo.Name, Target: slo.Target, Window: slo.Window}
+		for _, br := range slo.BurnRates {
+			converted.BurnRates = append(converted.BurnRates, report.BurnRate{Window: br.Window, Rate: br.BurnRate})
+		}
+		a.SLOs = append(a.SLOs, converted)
+	}
+	if d := item.Dependencies; d != nil {
+		a.LikelyCulprit, a.FiringUpstream, a.FiringDownstream = d.LikelyCulprit, d.FiringUpstream, d.FiringDownstream
+	}
+	if c := item.CrossService; c != nil {
+		a.SameFailure = c.Services
+	}
+	if t := item.Traces; t != nil {
+		a.Traces = &report.Traces{Errors: t.ErrorTraces, Slow: t.SlowTraces, Downstream: t.Downstream}
+	}
+	for _, rb := range item.Runbooks {
+		a.Runbooks = append(a.Runbooks, report.Runbook{Name: rb.Name, URL: rb.URL})
+	}
+	return a
+}
+
+// parseTime parses an RFC3339 payload timestamp, zero when empty or malformed
+func parseTime(v string) time.Time {
+	t, _ := time.Parse(time.RFC3339, v)
+	return t
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"vigilant/pkg/jira"
+	"vigilant/pkg/report"
+	"vigilant/pkg/risk"
+)
+
+// ErrIncidentNotFound is returned when opening a ticket for an unknown incident
+var ErrIncidentNotFound = errors.New("incident not found")
+
+var tickets *jira.Ticketer
+
+// SetTicketer enables the Jira tickets of incidents
+func SetTicketer(t *jira.Ticketer) {
+	tickets = t
+}
+
+// APITicket is the Jira ticket of an incident
+type APITicket struct {
+	IncidentID string `json:"incident_id"`
+	Key        string `json:"key"`
+	URL        string `json:"url"`
+	Service    string `json:"service"`
+	Risk       string `json:"risk"`
+	By         string `json:"by"`
+	CreatedAt  string `json:"created_at"`            // RFC3339
+	UpdatedAt  string `json:"updated_at"`            // RFC3339
+	ResolvedAt string `json:"resolved_at,omitempty"` // RFC3339
+}
+
+// NewAPITicket converts a ticket for the payload
+func NewAPITicket(t jira.Ticket) APITicket {
+	ticket := APITicket{
+		IncidentID: t.IncidentID,
+		Key:        t.Key,
+		URL:        t.URL,
+		Service:    t.Service,
+		Risk:       t.Risk,
+		By:         t.By,
+		CreatedAt:  t.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:  t.UpdatedAt.Format(time.RFC3339),
+	}
+	if t.Resolved() {
+		ticket.ResolvedAt = t.ResolvedAt.Format(time.RFC3339)
+	}
+	return ticket
+}
+
+func registerTicketRoutes(mux *http.ServeMux) {
+	handleAPI(mux, "GET /api/incidents/{id}/ticket", authorize("viewer", handleIncidentTicket))
+	handleAPI(mux, "POST /api/incidents/{id}/ticket", authorize("operator", handleCreateTicket))
+}
+
+// handleIncidentTicket serves GET /api/incidents/{id}/ticket
+func handleIncidentTicket(w http.ResponseWriter, r *http.Request) {
+	if tickets == nil {
+		http.Error(w, "Jira tickets are disabled", http.StatusNotFound)
+		return
+	}
+	id := r.PathValue("id")
+	ticket, ok := tickets.Get(id)
+	if !ok || !GrantFrom(r).Sees(ticket.Service) {
+		http.Error(w, fmt.Sprintf("incident %s has no ticket", id), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, NewAPITicket(ticket))
+}
+
+// handleCreateTicket serves POST /api/incidents/{id}/ticket: the incident's Jira ticket is
+// opened (201), or its current report is added to the existing one as a comment (200)
+func handleCreateTicket(w http.ResponseWriter, r *http.Request) {
+	if tickets == nil || incidents == nil {
+		http.Error(w, "Jira tickets are disabled", http.StatusNotFound)
+		return
+	}
+	id := r.PathValue("id")
+	if inc, ok := incidents.Get(id); !ok || !seesAny(GrantFrom(r), inc.Services) {
+		http.Error(w, fmt.Sprintf("incident %s not found", id), http.StatusNotFound)
+		return
+	}
+
+	ticket, created, err := OpenTicket(r.Context(), id, callerName(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+		log.Printf("%s opened %s for incident %s", callerName(r), ticket.Key, id)
+	}
+	writeJSON(w, status, NewAPITicket(ticket))
+}
+
+// OpenTicket opens the Jira ticket of an incident, or comments on the one it has, with the
+// incident's postmortem-style report
+func OpenTicket(ctx context.Context, id, by string) (jira.Ticket, bool, error) {
+	if tickets == nil || incidents == nil {
+		return jira.Ticket{}, false, errors.New("Jira tickets are disabled")
+	}
+	inc, ok := incidents.Get(id)
+	if !ok {
+		return jira.Ticket{}, false, ErrIncidentNotFound
+	}
+
+	// The incident's worst current risk names the ticket
+	var worst APIRiskItem
+	var items []APIRiskItem
+	riskMu.RLock()
+	for _, item := range currentAPIRisks {
+		if item.IncidentID != id {
+			continue
+		}
+		items = append(items, item)
+		if risk.LevelRank(item.Risk) > risk.LevelRank(worst.Risk) {
+			worst = item
+		}
+	}
+	riskMu.RUnlock()
+	if worst.Service == "" {
+		worst.Service, worst.Risk = inc.Service, "Unknown"
+		if len(inc.Alerts) > 0 {
+			worst.Alert = strings.TrimPrefix(inc.Alerts[0], inc.Service+"/")
+		}
+	}
+
+	return tickets.Open(ctx, jira.Request{
+		IncidentID: id,
+		Service:    worst.Service,
+		Services:   inc.Services,
+		Alert:      worst.Alert,
+		Risk:       worst.Risk,
+		Report:     incidentReport(id, worst.Service, items),
+		By:         by,
+	})
+}
+
+// incidentReport is the postmortem of the incident's recorded timeline, else the report of its
+// current risk items
+func incidentReport(id, service string, items []APIRiskItem) string {
+	if incidentHistory != nil {
+		if timeline := incidentHistory.Timeline(id); len(timeline) > 0 {
+			if doc, err := report.Postmortem(timeline); err == nil {
+				return doc
+			}
+		}
+	}
+	if len(items) == 0 {
+		return fmt.Sprintf("No analysis of incident %s is available yet.", id)
+	}
+	return renderRiskReport(service, items, time.Now())
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// APIVersion is the version of the HTTP API served under /api/v1. The JSON types of this
+// package (APIRiskItem, APIIncident, ...) are its payloads: fields may be added, while a change
+// that would break clients gets new types under /api/v2, next to an unchanged /api/v1.
+const APIVersion = "v1"
+
+// handleAPI registers an /api route under /api/v1, and under its unversioned path for the
+// dashboards and scripts that use it. Unversioned responses link to the versioned path.
+func handleAPI(mux *http.ServeMux, pattern string, h http.HandlerFunc) {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		method, path = "", pattern
+	}
+	versioned := strings.TrimSpace(method + " " + versionedPath(path))
+	mux.HandleFunc(versioned, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("API-Version", APIVersion)
+		h(w, r)
+	})
+	mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("API-Version", APIVersion)
+		w.Header().Set("Link", "<"+versionedPath(r.URL.Path)+`>; rel="successor-version"`)
+		h(w, r)
+	})
+}
+
+// versionedPath maps an unversioned /api path to /api/v1
+func versionedPath(path string) string {
+	if rest, ok := strings.CutPrefix(path, "/api/"); ok {
+		return "/api/" + APIVersion + "/" + rest
+	}
+	return path
+}
+
+// unversionedPath maps an /api/v1 path to the unversioned one, for checks on the path
+func unversionedPath(path string) string {
+	if rest, ok := strings.CutPrefix(path, "/api/"+APIVersion+"/"); ok {
+		return "/api/" + rest
+	}
+	return path
+}
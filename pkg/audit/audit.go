@@ -0,0 +1,223 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry records one exchange with an LLM provider
+type Entry struct {
+	Timestamp     time.Time `json:"timestamp"`
+	Provider      string    `json:"provider"`
+	Model         string    `json:"model"`
+	PromptVersion string    `json:"prompt_version,omitempty"`
+	Services      []string  `json:"services"`
+	SystemPrompt  string    `json:"system_prompt"`
+	UserPrompt    string    `json:"user_prompt"`
+	Response      string    `json:"response"`
+	LatencyMs     int64     `json:"latency_ms"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// Query filters entries returned by Log.Query
+type Query struct {
+	From    time.Time
+	To      time.Time
+	Service string
+	Limit   int
+}
+
+// Log is an append-only JSONL audit trail split into one file per day
+type Log struct {
+	dir       string
+	retention time.Duration
+	mu        sync.Mutex
+
+	lastRetentionCheck time.Time
+}
+
+const fileDateLayout = "2006-01-02"
+
+// NewLog creates the audit directory; retention of 0 keeps entries forever
+func NewLog(dir string, retention time.Duration) (*Log, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+	l := &Log{dir: dir, retention: retention}
+	l.EnforceRetention()
+	return l, nil
+}
+
+// Record appends an entry to the current day's file
+func (l *Log) Record(entry Entry) error {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if time.Since(l.lastRetentionCheck) > time.Hour {
+		l.enforceRetention()
+	}
+
+	file, err := os.OpenFile(l.fileFor(entry.Timestamp), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	return nil
+}
+
+// Query returns entries matching q, newest first
+func (l *Log) Query(q Query) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	files, err := l.files()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, f := range files {
+		day, err := time.ParseInLocation(fileDateLayout, f.date, time.Local)
+		if err != nil {
+			continue
+		}
+		// Skip whole files outside the requested range
+		if !q.From.IsZero() && day.Add(24*time.Hour).Before(q.From) {
+			continue
+		}
+		if !q.To.IsZero() && day.After(q.To) {
+			continue
+		}
+
+		fileEntries, err := readEntries(f.path)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range fileEntries {
+			if !q.From.IsZero() && e.Timestamp.Before(q.From) {
+				continue
+			}
+			if !q.To.IsZero() && e.Timestamp.After(q.To) {
+				continue
+			}
+			if q.Service != "" && !containsService(e.Services, q.Service) {
+				continue
+			}
+			entries = append(entries, e)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Timestamp.After(entries[j].Timestamp)
+	})
+	if q.Limit > 0 && len(entries) > q.Limit {
+		entries = entries[:q.Limit]
+	}
+	return entries, nil
+}
+
+// EnforceRetention deletes day files older than the retention period
+func (l *Log) EnforceRetention() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.enforceRetention()
+}
+
+// enforceRetention does the work of EnforceRetention. Caller must hold l.mu.
+func (l *Log) enforceRetention() {
+	l.lastRetentionCheck = time.Now()
+	if l.retention <= 0 {
+		return
+	}
+
+	files, err := l.files()
+	if err != nil {
+		fmt.Printf("[AUDIT] Retention check failed: %v\n", err)
+		return
+	}
+
+	cutoff := time.Now().Add(-l.retention)
+	for _, f := range files {
+		day, err := time.ParseInLocation(fileDateLayout, f.date, time.Local)
+		if err != nil || !day.Add(24*time.Hour).Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(f.path); err != nil {
+			fmt.Printf("[AUDIT] Failed to remove expired file %s: %v\n", f.path, err)
+			continue
+		}
+		fmt.Printf("[AUDIT] Removed expired audit file %s\n", f.path)
+	}
+}
+
+type auditFile struct {
+	path string
+	date string
+}
+
+func (l *Log) fileFor(t time.Time) string {
+	return filepath.Join(l.dir, "llm-audit-"+t.Format(fileDateLayout)+".jsonl")
+}
+
+func (l *Log) files() ([]auditFile, error) {
+	paths, err := filepath.Glob(filepath.Join(l.dir, "llm-audit-*.jsonl"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit files: %w", err)
+	}
+
+	var files []auditFile
+	for _, p := range paths {
+		date := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(p), "llm-audit-"), ".jsonl")
+		files = append(files, auditFile{path: p, date: date})
+	}
+	return files, nil
+}
+
+func readEntries(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit file: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+func containsService(services []string, service string) bool {
+	for _, s := range services {
+		if s == service {
+			return true
+		}
+	}
+	return false
+}
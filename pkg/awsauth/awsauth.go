@@ -0,0 +1,138 @@
+package awsauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// Configs resolved so far, keyed by region and role, so assumed-role credentials are cached
+var (
+	configs   = make(map[string]aws.Config)
+	configsMu sync.Mutex
+)
+
+var (
+	signer     = v4.NewSigner()
+	httpClient = &http.Client{Timeout: 60 * time.Second}
+
+	// S3 signs the path exactly as sent instead of escaping it a second time
+	s3Signer = v4.NewSigner(func(o *v4.SignerOptions) {
+		o.DisableURIPathEscaping = true
+	})
+)
+
+// emptyPayloadHash is the SHA-256 of an empty request body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// Config resolves credentials from the default chain (env, shared config, IRSA, instance role)
+// for region, assuming roleARN on top of them when set
+func Config(ctx context.Context, region, roleARN string) (aws.Config, error) {
+	key := region + "|" + roleARN
+
+	configsMu.Lock()
+	defer configsMu.Unlock()
+
+	if cfg, ok := configs[key]; ok {
+		return cfg, nil
+	}
+
+	var opts []func(*config.LoadOptions) error
+	if region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	if cfg.Region == "" {
+		return aws.Config{}, fmt.Errorf("no AWS region configured (set AWS_REGION or region in the profile)")
+	}
+
+	if roleARN != "" {
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), roleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = "vigilant"
+		})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+
+	configs[key] = cfg
+	return cfg, nil
+}
+
+// CallJSON invokes an action of an AWS JSON-protocol API (CloudWatch Logs, CloudWatch, ...)
+// such as target "Logs_20140328.StartQuery", decoding the response into out
+func CallJSON(ctx context.Context, cfg aws.Config, service, jsonVersion, target string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", target, err)
+	}
+
+	endpoint := fmt.Sprintf("https://%s.%s.amazonaws.com/", service, cfg.Region)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build %s request: %w", target, err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-"+jsonVersion)
+	req.Header.Set("X-Amz-Target", target)
+
+	creds, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve AWS credentials: %w", err)
+	}
+	hash := sha256.Sum256(body)
+	if err := signer.SignHTTP(ctx, creds, req, hex.EncodeToString(hash[:]), service, cfg.Region, time.Now()); err != nil {
+		return fmt.Errorf("failed to sign %s request: %w", target, err)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", target, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s failed: %s: %s", target, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", target, err)
+	}
+	return nil
+}
+
+// GetS3 sends a signed GET to an S3 (or S3-compatible) URL. The caller closes the body
+// and checks the status code.
+func GetS3(ctx context.Context, cfg aws.Config, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build s3 request: %w", err)
+	}
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+
+	creds, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve AWS credentials: %w", err)
+	}
+	if err := s3Signer.SignHTTP(ctx, creds, req, emptyPayloadHash, "s3", cfg.Region, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to sign s3 request: %w", err)
+	}
+	return httpClient.Do(req)
+}
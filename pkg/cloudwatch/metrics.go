@@ -0,0 +1,320 @@
+package cloudwatch
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"vigilant/pkg/awsauth"
+	"vigilant/pkg/prometheus"
+)
+
+// defaultPeriod is the aggregation period of queries that set none
+const defaultPeriod = 60 * time.Second
+
+// queryTimeout bounds one GetMetricData call, including its pages
+const queryTimeout = 30 * time.Second
+
+// Client runs metric check queries through the CloudWatch GetMetricData API. It implements
+// prometheus.MetricSource; see parseQuery for the query syntax.
+type Client struct {
+	region  string
+	roleARN string
+	period  time.Duration
+}
+
+// NewClient returns a client for region (AWS_REGION when empty), assuming roleARN when set.
+// period is the default aggregation period.
+func NewClient(region, roleARN string, period time.Duration) *Client {
+	if period <= 0 {
+		period = defaultPeriod
+	}
+	return &Client{region: region, roleARN: roleARN, period: period}
+}
+
+// Name identifies the client by its region and role
+func (c *Client) Name() string {
+	name := "cloudwatch:" + c.region
+	if c.roleARN != "" {
+		name += ":" + c.roleARN
+	}
+	return name
+}
+
+// metricQuery is a parsed metric check query: either a single metric statistic or a metric
+// math, SEARCH() or Metrics Insights expression
+type metricQuery struct {
+	Namespace  string
+	MetricName string
+	Stat       string
+	Dimensions []dimension
+	Expression string
+	Period     time.Duration
+}
+
+type dimension struct {
+	Name  string `json:"Name"`
+	Value string `json:"Value"`
+}
+
+// parseQuery reads a query made of key=value pairs, e.g.
+//
+//	namespace=AWS/ApplicationELB metric=TargetResponseTime stat=p95 period=60 LoadBalancer=app/web/123
+//
+// where every key besides namespace, metric, stat and period is a dimension and values with
+// spaces are double-quoted. Any other query is sent as an expression, e.g.
+// SEARCH('{AWS/EC2,InstanceId} MetricName="CPUUtilization"', 'Average').
+func parseQuery(query string) (metricQuery, error) {
+	fields, ok := splitPairs(query)
+	if !ok || fields == nil {
+		return metricQuery{Expression: strings.TrimSpace(query)}, nil
+	}
+
+	var q metricQuery
+	for _, f := range fields {
+		switch f[0] {
+		case "namespace":
+			q.Namespace = f[1]
+		case "metric":
+			q.MetricName = f[1]
+		case "stat":
+			q.Stat = f[1]
+		case "period":
+			seconds, err := strconv.Atoi(f[1])
+			if err != nil || seconds <= 0 {
+				return metricQuery{}, fmt.Errorf("invalid period %q (expected seconds)", f[1])
+			}
+			q.Period = time.Duration(seconds) * time.Second
+		default:
+			q.Dimensions = append(q.Dimensions, dimension{Name: f[0], Value: f[1]})
+		}
+	}
+	switch {
+	case q.Namespace == "":
+		return metricQuery{}, fmt.Errorf("query %q has no namespace", query)
+	case q.MetricName == "":
+		return metricQuery{}, fmt.Errorf("query %q has no metric", query)
+	case q.Stat == "":
+		q.Stat = "Average"
+	}
+	return q, nil
+}
+
+// splitPairs splits query into key=value pairs at spaces outside double quotes; ok is false
+// when any token is not a pair or no namespace is given
+func splitPairs(query string) (pairs [][2]string, ok bool) {
+	var tokens []string
+	var current strings.Builder
+	quoted := false
+	for _, r := range query {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			current.WriteRune(r)
+		case (r == ' ' || r == '\t' || r == '\n') && !quoted:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+
+	namespace := false
+	for _, token := range tokens {
+		key, value, found := strings.Cut(token, "=")
+		if !found || key == "" || strings.ContainsAny(key, "(\"'") {
+			return nil, false
+		}
+		if strings.HasPrefix(value, `"`) {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, false
+			}
+			value = unquoted
+		}
+		namespace = namespace || key == "namespace"
+		pairs = append(pairs, [2]string{key, value})
+	}
+	return pairs, namespace
+}
+
+// ValidateQuery reports syntax errors of a rendered query
+func ValidateQuery(query string) error {
+	q, err := parseQuery(query)
+	if err != nil {
+		return err
+	}
+	if q.Expression == "" && q.MetricName == "" {
+		return fmt.Errorf("empty query")
+	}
+	return nil
+}
+
+// Query returns the latest point of every series over the last few periods; CloudWatch
+// publishes with a delay, so the newest period is often still empty
+func (c *Client) Query(query string) ([]prometheus.Series, error) {
+	now := time.Now()
+	window := 5 * time.Minute
+	if p := c.queryPeriod(query, 0); 3*p > window {
+		window = 3 * p
+	}
+	series, err := c.QueryRange(query, now.Add(-window), now, 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := range series {
+		series[i].Values = series[i].Values[len(series[i].Values)-1:]
+	}
+	return series, nil
+}
+
+// queryPeriod is the period query is aggregated by: its own, else step rounded up to whole
+// minutes, else the client's default
+func (c *Client) queryPeriod(query string, step time.Duration) time.Duration {
+	if q, err := parseQuery(query); err == nil && q.Period > 0 {
+		return q.Period
+	}
+	if step > 0 {
+		return time.Duration(math.Ceil(step.Minutes())) * time.Minute
+	}
+	return c.period
+}
+
+type metricDataQuery struct {
+	ID         string      `json:"Id"`
+	Expression string      `json:"Expression,omitempty"`
+	MetricStat *metricStat `json:"MetricStat,omitempty"`
+	Period     int         `json:"Period,omitempty"`
+	ReturnData bool        `json:"ReturnData"`
+}
+
+type metricStat struct {
+	Metric struct {
+		Namespace  string      `json:"Namespace"`
+		MetricName string      `json:"MetricName"`
+		Dimensions []dimension `json:"Dimensions,omitempty"`
+	} `json:"Metric"`
+	Period int    `json:"Period"`
+	Stat   string `json:"Stat"`
+}
+
+type getMetricDataRequest struct {
+	MetricDataQueries []metricDataQuery `json:"MetricDataQueries"`
+	StartTime         int64             `json:"StartTime"`
+	EndTime           int64             `json:"EndTime"`
+	ScanBy            string            `json:"ScanBy"`
+	NextToken         string            `json:"NextToken,omitempty"`
+}
+
+type getMetricDataResponse struct {
+	MetricDataResults []struct {
+		ID         string    `json:"Id"`
+		Label      string    `json:"Label"`
+		Timestamps []float64 `json:"Timestamps"`
+		Values     []float64 `json:"Values"`
+		StatusCode string    `json:"StatusCode"`
+	} `json:"MetricDataResults"`
+	Messages []struct {
+		Code  string `json:"Code"`
+		Value string `json:"Value"`
+	} `json:"Messages"`
+	NextToken string `json:"NextToken"`
+}
+
+// QueryRange returns every series' points between start and end, aggregated by the query's
+// period or, without one, by step
+func (c *Client) QueryRange(query string, start, end time.Time, step time.Duration) ([]prometheus.Series, error) {
+	q, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	period := int(c.queryPeriod(query, step).Seconds())
+
+	dataQuery := metricDataQuery{ID: "m0", ReturnData: true}
+	if q.Expression != "" {
+		dataQuery.Expression = q.Expression
+		dataQuery.Period = period
+	} else {
+		stat := &metricStat{Period: period, Stat: q.Stat}
+		stat.Metric.Namespace = q.Namespace
+		stat.Metric.MetricName = q.MetricName
+		stat.Metric.Dimensions = q.Dimensions
+		dataQuery.MetricStat = stat
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+	awsCfg, err := awsauth.Config(ctx, c.region, c.roleARN)
+	if err != nil {
+		return nil, err
+	}
+
+	// A SEARCH() expression returns one result per matching metric, told apart by label
+	var order []string
+	byLabel := make(map[string]*prometheus.Series)
+	request := getMetricDataRequest{
+		MetricDataQueries: []metricDataQuery{dataQuery},
+		StartTime:         start.Unix(),
+		EndTime:           end.Unix(),
+		ScanBy:            "TimestampAscending",
+	}
+	for {
+		var response getMetricDataResponse
+		err := awsauth.CallJSON(ctx, awsCfg, "monitoring", "1.0", "GraniteServiceVersion20100801.GetMetricData", request, &response)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range response.Messages {
+			fmt.Printf("[METRIC] CloudWatch %s: %s\n", m.Code, m.Value)
+		}
+		for _, result := range response.MetricDataResults {
+			if result.StatusCode == "Forbidden" || result.StatusCode == "InternalError" {
+				return nil, fmt.Errorf("cloudwatch query %q failed: %s", query, result.StatusCode)
+			}
+			s, ok := byLabel[result.Label]
+			if !ok {
+				s = &prometheus.Series{Labels: seriesLabels(q, result.Label)}
+				byLabel[result.Label] = s
+				order = append(order, result.Label)
+			}
+			for _, v := range result.Values {
+				if !math.IsNaN(v) && !math.IsInf(v, 0) {
+					s.Values = append(s.Values, v)
+				}
+			}
+		}
+		if response.NextToken == "" {
+			break
+		}
+		request.NextToken = response.NextToken
+	}
+
+	var series []prometheus.Series
+	for _, label := range order {
+		if s := byLabel[label]; len(s.Values) > 0 {
+			series = append(series, *s)
+		}
+	}
+	return series, nil
+}
+
+// seriesLabels are a statistic's dimensions, or the result label of an expression
+func seriesLabels(q metricQuery, label string) map[string]string {
+	labels := make(map[string]string)
+	if q.Expression != "" {
+		labels["label"] = label
+		return labels
+	}
+	for _, d := range q.Dimensions {
+		labels[d.Name] = d.Value
+	}
+	return labels
+}
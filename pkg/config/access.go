@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Roles lists the access roles, each allowing what the previous one does: viewers read,
+// operators also acknowledge, snooze, give feedback and manage maintenance windows, and
+// admins also control the LLM cache
+var Roles = []string{"viewer", "operator", "admin"}
+
+// AccessGrant gives a role over the services of some teams; no teams means every service
+type AccessGrant struct {
+	Role  string   `yaml:"role"`
+	Teams []string `yaml:"teams,omitempty"`
+}
+
+// APIKeyGrant is a named API key with its own grant
+type APIKeyGrant struct {
+	Key         string `yaml:"key"` // Usually ${ENV_VAR}
+	AccessGrant `yaml:",inline"`
+}
+
+// AccessConfig maps the users and API keys of the API to roles and teams. Services belong
+// to the team named in their profile.
+type AccessConfig struct {
+	DefaultRole string                 `yaml:"default_role,omitempty"` // Role of users without a rule, over every service; empty denies them
+	Users       map[string]AccessGrant `yaml:"users,omitempty"`        // By email, name or subject
+	Groups      map[string]AccessGrant `yaml:"groups,omitempty"`       // By the groups claim of JWTs and SSO logins
+	APIKeys     map[string]APIKeyGrant `yaml:"api_keys,omitempty"`     // By key name
+}
+
+// Enabled reports whether the config restricts access at all
+func (c AccessConfig) Enabled() bool {
+	return c.DefaultRole != "" || len(c.Users) > 0 || len(c.Groups) > 0 || len(c.APIKeys) > 0
+}
+
+// LoadAccessConfig loads access rules from path, returning no rules if the file does not exist
+func LoadAccessConfig(path string) (AccessConfig, error) {
+	cfg := AccessConfig{}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	content := expandEnvironmentVariables(string(data))
+	if err := yaml.Unmarshal([]byte(content), &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid YAML in %s: %w", path, err)
+	}
+
+	if cfg.DefaultRole != "" && !slices.Contains(Roles, cfg.DefaultRole) {
+		return cfg, fmt.Errorf("invalid configuration in %s: unknown default_role %q (expected one of %v)", path, cfg.DefaultRole, Roles)
+	}
+	for kind, grants := range map[string]map[string]AccessGrant{"user": cfg.Users, "group": cfg.Groups} {
+		for name, grant := range grants {
+			if !slices.Contains(Roles, grant.Role) {
+				return cfg, fmt.Errorf("invalid configuration in %s: %s %q has unknown role %q (expected one of %v)", path, kind, name, grant.Role, Roles)
+			}
+		}
+	}
+	for name, key := range cfg.APIKeys {
+		if key.Key == "" {
+			return cfg, fmt.Errorf("invalid configuration in %s: api key %q has no key", path, name)
+		}
+		if !slices.Contains(Roles, key.Role) {
+			return cfg, fmt.Errorf("invalid configuration in %s: api key %q has unknown role %q (expected one of %v)", path, name, key.Role, Roles)
+		}
+	}
+	return cfg, nil
+}
+
+// ServiceTeams maps every service with a team to it
+func ServiceTeams(profiles map[string]ServiceProfile) map[string]string {
+	teams := make(map[string]string)
+	for serviceName, profile := range profiles {
+		if profile.Metadata.Team != "" {
+			teams[serviceName] = profile.Metadata.Team
+		}
+	}
+	return teams
+}
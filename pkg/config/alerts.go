@@ -0,0 +1,139 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"vigilant/pkg/prometheus"
+)
+
+// DefaultServiceLabels are the alert labels that name the service, tried in order
+var DefaultServiceLabels = []string{"service", "app", "job", "namespace"}
+
+// AlertMapper resolves which service profile an alert belongs to
+type AlertMapper struct {
+	exact    map[string]string // alert_pattern -> service
+	labels   []string
+	names    map[string]string // lowercased service name or alias -> service
+	patterns []alertPatternRule
+}
+
+type alertPatternRule struct {
+	service string
+	regex   *regexp.Regexp
+}
+
+// NewAlertMapper builds the mapping rules of the profiles; serviceLabels defaults to
+// DefaultServiceLabels when empty
+func NewAlertMapper(profiles map[string]ServiceProfile, serviceLabels []string) *AlertMapper {
+	if len(serviceLabels) == 0 {
+		serviceLabels = DefaultServiceLabels
+	}
+	m := &AlertMapper{
+		exact:  CreateAlertToServiceMapping(profiles),
+		labels: serviceLabels,
+		names:  make(map[string]string),
+	}
+
+	// Sorted, so an alert matching several patterns always maps to the same service
+	services := make([]string, 0, len(profiles))
+	for service := range profiles {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	for _, service := range services {
+		profile := profiles[service]
+		m.names[strings.ToLower(service)] = service
+		for _, alias := range profile.AlertMatching.ServiceAliases {
+			m.names[strings.ToLower(alias)] = service
+		}
+		if pattern := profile.AlertMatching.AlertPattern; pattern != "" {
+			re, err := regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				fmt.Printf("Invalid alert_pattern for %s, matching it exactly: %v\n", service, err)
+				continue
+			}
+			m.patterns = append(m.patterns, alertPatternRule{service: service, regex: re})
+		}
+	}
+	return m
+}
+
+// ServiceFor returns the service an alert with these labels belongs to, trying in order: an
+// alert_pattern equal to the alert name, the service labels (matched against service names
+// and aliases), alert_pattern regexes, and a service named like the alert. ok is false when
+// no service matches.
+func (m *AlertMapper) ServiceFor(labels map[string]string) (service string, ok bool) {
+	alertName := labels["alertname"]
+	if service, ok := m.exact[alertName]; ok {
+		return service, true
+	}
+	for _, label := range m.labels {
+		if value := labels[label]; value != "" {
+			if service, ok := m.names[strings.ToLower(value)]; ok {
+				return service, true
+			}
+		}
+	}
+	for _, rule := range m.patterns {
+		if rule.regex.MatchString(alertName) {
+			return rule.service, true
+		}
+	}
+	if service, ok := m.names[strings.ToLower(alertName)]; ok {
+		return service, true
+	}
+	return "", false
+}
+
+// InhibitRule mutes target alerts while a source alert is firing, like Alertmanager's
+// inhibit_rules: e.g. per-pod alerts while NodeDown fires for their node. Matchers use the
+// Alertmanager syntax (alertname="NodeDown", severity=~"warning|info").
+type InhibitRule struct {
+	Name           string   `yaml:"name,omitempty"`
+	SourceMatchers []string `yaml:"source_matchers"`
+	TargetMatchers []string `yaml:"target_matchers"`
+	Equal          []string `yaml:"equal,omitempty"` // Labels the source and target must share
+}
+
+// AlertingConfig holds deployment-wide alert handling settings
+type AlertingConfig struct {
+	InhibitRules []InhibitRule `yaml:"inhibit_rules,omitempty"`
+}
+
+// LoadAlertingConfig loads alert handling settings from path, returning no rules if the file
+// does not exist
+func LoadAlertingConfig(path string) (AlertingConfig, error) {
+	cfg := AlertingConfig{}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	content := expandEnvironmentVariables(string(data))
+	if err := yaml.Unmarshal([]byte(content), &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid YAML in %s: %w", path, err)
+	}
+
+	for i, rule := range cfg.InhibitRules {
+		if len(rule.SourceMatchers) == 0 || len(rule.TargetMatchers) == 0 {
+			return cfg, fmt.Errorf("invalid configuration in %s: inhibit rule %d needs source_matchers and target_matchers", path, i)
+		}
+		for _, matcher := range append(append([]string{}, rule.SourceMatchers...), rule.TargetMatchers...) {
+			if _, err := prometheus.ParseMatcher(matcher); err != nil {
+				return cfg, fmt.Errorf("invalid configuration in %s: inhibit rule %d: %w", path, i, err)
+			}
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PromptVariant is one arm of a prompt A/B experiment
+type PromptVariant struct {
+	Version string `yaml:"version"`
+	Weight  int    `yaml:"weight"`
+}
+
+// PromptExperiment splits LLM cycles across several prompt versions
+type PromptExperiment struct {
+	Enabled  bool            `yaml:"enabled"`
+	Variants []PromptVariant `yaml:"variants,omitempty"`
+}
+
+// PromptConfig selects the system prompt version used for analysis
+type PromptConfig struct {
+	Version    string            `yaml:"version,omitempty"`
+	Experiment PromptExperiment  `yaml:"experiment,omitempty"`
+	Custom     map[string]string `yaml:"custom,omitempty"` // Additional prompt versions defined inline
+}
+
+// AnalysisTier sets how deeply alerts of the listed severities are analyzed
+type AnalysisTier struct {
+	Name       string   `yaml:"name"`
+	Severities []string `yaml:"severities"`
+	Model      string   `yaml:"model,omitempty"`      // Defaults to OPENAI_MODEL / gpt-4o
+	MaxTokens  int      `yaml:"max_tokens,omitempty"` // Defaults to 1500
+	Depth      string   `yaml:"depth,omitempty"`      // "full" (default) or "brief"
+}
+
+// AnalysisPolicy maps alert severity to an analysis tier; the first matching tier wins
+type AnalysisPolicy struct {
+	Enabled bool           `yaml:"enabled"`
+	Tiers   []AnalysisTier `yaml:"tiers,omitempty"`
+}
+
+// LLMConfig holds settings for the LLM analysis pipeline
+type LLMConfig struct {
+	Prompts        PromptConfig   `yaml:"prompts,omitempty"`
+	AnalysisPolicy AnalysisPolicy `yaml:"analysis_policy,omitempty"`
+}
+
+// LoadLLMConfig loads LLM settings from path, returning defaults if the file does not exist
+func LoadLLMConfig(path string) (LLMConfig, error) {
+	cfg := LLMConfig{}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return applyLLMDefaults(cfg), nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	content := expandEnvironmentVariables(string(data))
+	if err := yaml.Unmarshal([]byte(content), &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid YAML in %s: %w", path, err)
+	}
+
+	if err := validateLLMConfig(cfg); err != nil {
+		return cfg, fmt.Errorf("invalid configuration in %s: %w", path, err)
+	}
+
+	return applyLLMDefaults(cfg), nil
+}
+
+// validateLLMConfig validates the LLM configuration
+func validateLLMConfig(cfg LLMConfig) error {
+	if cfg.Prompts.Experiment.Enabled {
+		if len(cfg.Prompts.Experiment.Variants) < 2 {
+			return fmt.Errorf("prompt experiment needs at least two variants")
+		}
+		total := 0
+		for i, v := range cfg.Prompts.Experiment.Variants {
+			if v.Version == "" {
+				return fmt.Errorf("prompt variant %d is missing version", i)
+			}
+			if v.Weight < 0 {
+				return fmt.Errorf("prompt variant %d (%s) has negative weight", i, v.Version)
+			}
+			total += v.Weight
+		}
+		if total == 0 {
+			return fmt.Errorf("prompt experiment variants must have a positive total weight")
+		}
+	}
+	for i, tier := range cfg.AnalysisPolicy.Tiers {
+		if len(tier.Severities) == 0 {
+			return fmt.Errorf("analysis tier %d (%s) has no severities", i, tier.Name)
+		}
+		if tier.Depth != "" && tier.Depth != "full" && tier.Depth != "brief" {
+			return fmt.Errorf("analysis tier %d (%s) has invalid depth %q (use full or brief)", i, tier.Name, tier.Depth)
+		}
+		if tier.MaxTokens < 0 {
+			return fmt.Errorf("analysis tier %d (%s) has negative max_tokens", i, tier.Name)
+		}
+	}
+	return nil
+}
+
+// applyLLMDefaults sets reasonable defaults for missing LLM configuration
+func applyLLMDefaults(cfg LLMConfig) LLMConfig {
+	if cfg.Prompts.Version == "" {
+		cfg.Prompts.Version = "v1"
+	}
+	for i := range cfg.AnalysisPolicy.Tiers {
+		tier := &cfg.AnalysisPolicy.Tiers[i]
+		if tier.Depth == "" {
+			tier.Depth = "full"
+		}
+		if tier.MaxTokens == 0 {
+			tier.MaxTokens = 1500
+		}
+		if tier.Name == "" {
+			tier.Name = fmt.Sprintf("tier-%d", i+1)
+		}
+	}
+	return cfg
+}
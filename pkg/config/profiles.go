@@ -0,0 +1,183 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrProfileNotFound is returned for a service without a profile file
+	ErrProfileNotFound = errors.New("service profile not found")
+	// ErrProfileExists is returned when creating a profile that already exists
+	ErrProfileExists = errors.New("service profile already exists")
+	// ErrInvalidProfile wraps the reason a profile was rejected
+	ErrInvalidProfile = errors.New("invalid service profile")
+)
+
+// profileNamePattern keeps service names usable as file names inside the profile directory
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ProfileFile is the file holding the profile of a service
+type ProfileFile struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// ProfileFiles reads and writes the service profiles of a directory such as config/services.
+// Writes are validated like LoadServiceProfiles and take effect when the profiles are next loaded.
+type ProfileFiles struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewProfileFiles manages the profiles in dir
+func NewProfileFiles(dir string) *ProfileFiles {
+	return &ProfileFiles{dir: dir}
+}
+
+// List returns the profile files by service name; a file without a name field is named after the file
+func (p *ProfileFiles) List() ([]ProfileFile, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.list()
+}
+
+func (p *ProfileFiles) list() ([]ProfileFile, error) {
+	var files []string
+	for _, ext := range []string{"*.yml", "*.yaml"} {
+		matches, err := filepath.Glob(filepath.Join(p.dir, ext))
+		if err != nil {
+			return nil, fmt.Errorf("failed to glob %s files: %w", ext, err)
+		}
+		files = append(files, matches...)
+	}
+
+	result := make([]ProfileFile, 0, len(files))
+	for _, file := range files {
+		base := filepath.Base(file)
+		name := base[:len(base)-len(filepath.Ext(base))]
+		// Only the name is needed, so a profile failing validation can still be fixed
+		if data, err := os.ReadFile(file); err == nil {
+			var meta ServiceMetadata
+			if yaml.Unmarshal(data, &meta) == nil && meta.Name != "" {
+				name = meta.Name
+			}
+		}
+		result = append(result, ProfileFile{Name: name, Path: file})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// find returns the file of a service; the first one wins like in LoadServiceProfiles
+func (p *ProfileFiles) find(name string) (string, error) {
+	files, err := p.list()
+	if err != nil {
+		return "", err
+	}
+	for _, f := range files {
+		if f.Name == name {
+			return f.Path, nil
+		}
+	}
+	return "", ErrProfileNotFound
+}
+
+// Read returns the raw YAML of a service's profile, without environment variables expanded
+func (p *ProfileFiles) Read(name string) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	path, err := p.find(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// Create validates data and writes it to <name>.yml
+func (p *ProfileFiles) Create(name string, data []byte) (ServiceProfile, error) {
+	profile, err := p.validate(name, data)
+	if err != nil {
+		return profile, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := p.find(name); err == nil {
+		return profile, ErrProfileExists
+	} else if !errors.Is(err, ErrProfileNotFound) {
+		return profile, err
+	}
+	path := filepath.Join(p.dir, name+".yml")
+	if _, err := os.Stat(path); err == nil {
+		return profile, fmt.Errorf("%w: %s is used by another service", ErrProfileExists, path)
+	}
+	return profile, writeFileAtomic(path, data)
+}
+
+// Update validates data and replaces the profile file of a service
+func (p *ProfileFiles) Update(name string, data []byte) (ServiceProfile, error) {
+	profile, err := p.validate(name, data)
+	if err != nil {
+		return profile, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	path, err := p.find(name)
+	if err != nil {
+		return profile, err
+	}
+	return profile, writeFileAtomic(path, data)
+}
+
+// Delete removes the profile file of a service
+func (p *ProfileFiles) Delete(name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	path, err := p.find(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// validate parses data as the profile of name, whose name field may be omitted but not differ
+func (p *ProfileFiles) validate(name string, data []byte) (ServiceProfile, error) {
+	if !profileNamePattern.MatchString(name) {
+		return ServiceProfile{}, fmt.Errorf("%w: service name %q may only contain letters, digits, '.', '_' and '-'", ErrInvalidProfile, name)
+	}
+	profile, err := ParseServiceProfile(name, data)
+	if err != nil {
+		return profile, fmt.Errorf("%w: %v", ErrInvalidProfile, err)
+	}
+	if profile.Metadata.Name != name {
+		return profile, fmt.Errorf("%w: name %q does not match service %q", ErrInvalidProfile, profile.Metadata.Name, name)
+	}
+	return profile, nil
+}
+
+// writeFileAtomic replaces path through a temporary file, so readers never see a partial profile
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".profile-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LLMRiskPoints scores an LLM risk level: Base points plus up to Confidence more at full
+// confidence
+type LLMRiskPoints struct {
+	Base       int `yaml:"base"`
+	Confidence int `yaml:"confidence"`
+}
+
+// AgeScoring sets how alert age raises the score
+type AgeScoring struct {
+	Curve       string `yaml:"curve,omitempty"`        // "log", "linear" or "off"
+	FullMinutes int    `yaml:"full_minutes,omitempty"` // Age at which the full points apply
+	MaxPoints   int    `yaml:"max_points,omitempty"`
+}
+
+// DecayScoring sets how fast a score winds down once its causes stop: it halves every
+// HalfLifeMinutes, and a service that is no longer alerting disappears below MinScore
+type DecayScoring struct {
+	HalfLifeMinutes int `yaml:"half_life_minutes,omitempty"`
+	MinScore        int `yaml:"min_score,omitempty"`
+}
+
+// SLOBurnScoring sets how error budget burn raises the score: from nothing at a burn rate of 1
+// to MaxPoints at FullBurnRate
+type SLOBurnScoring struct {
+	FullBurnRate float64 `yaml:"full_burn_rate,omitempty"`
+	MaxPoints    int     `yaml:"max_points,omitempty"`
+}
+
+// HealthScoring sets the points of the health score, which stands in for the LLM risk until an
+// analysis rated it: Severity maps alert severity labels, DefaultPoints scores others, and
+// MetricWeightPoints replaces metric_weight_points
+type HealthScoring struct {
+	Severity           map[string]int `yaml:"severity,omitempty"`
+	DefaultPoints      int            `yaml:"default_points,omitempty"`
+	MetricWeightPoints int            `yaml:"metric_weight_points,omitempty"`
+}
+
+// AgeCurves lists the supported age curves
+var AgeCurves = []string{"log", "linear", "off"}
+
+// ScoringConfig holds the rules that turn an analyzed service into its 0-100 risk score. The
+// score starts from the higher of the alert severity and LLM risk points; symptoms, triggered
+// metrics and alert age then each fill part of the headroom left below 100.
+type ScoringConfig struct {
+	Severity           map[string]int           `yaml:"severity,omitempty"` // Alert severity label -> points
+	LLMRisk            map[string]LLMRiskPoints `yaml:"llm_risk,omitempty"` // LLM risk level -> points
+	SymptomWeights     map[string]float64       `yaml:"symptom_weights,omitempty"`
+	MetricWeightPoints int                      `yaml:"metric_weight_points,omitempty"` // Points per unit of triggered metric weight
+	Age                AgeScoring               `yaml:"age,omitempty"`
+	Decay              DecayScoring             `yaml:"decay,omitempty"`
+	SLOBurn            SLOBurnScoring           `yaml:"slo_burn,omitempty"`
+	Health             HealthScoring            `yaml:"health,omitempty"`
+}
+
+// DefaultScoringConfig is used for everything scoring.yml leaves unset
+var DefaultScoringConfig = ScoringConfig{
+	Severity: map[string]int{},
+	LLMRisk: map[string]LLMRiskPoints{
+		"critical": {Base: 90, Confidence: 10},
+		"high":     {Base: 70, Confidence: 20},
+		"medium":   {Base: 40, Confidence: 30},
+		"low":      {Base: 10, Confidence: 30},
+	},
+	SymptomWeights: map[string]float64{
+		"critical": 30,
+		"error":    15,
+		"warning":  5,
+		"info":     1,
+	},
+	Age:     AgeScoring{Curve: "log", FullMinutes: 120, MaxPoints: 20},
+	Decay:   DecayScoring{HalfLifeMinutes: 10, MinScore: 5},
+	SLOBurn: SLOBurnScoring{FullBurnRate: 14.4, MaxPoints: 30},
+	Health: HealthScoring{
+		Severity: map[string]int{
+			"critical": 50,
+			"error":    40,
+			"warning":  25,
+			"info":     10,
+		},
+		DefaultPoints:      20,
+		MetricWeightPoints: 5,
+	},
+}
+
+// LoadScoringConfig loads the scoring rules from path, returning the defaults if the file
+// does not exist. SCORE_AGE_CURVE, SCORE_AGE_FULL_MINUTES and SCORE_AGE_MAX_POINTS override
+// the age settings.
+func LoadScoringConfig(path string) (ScoringConfig, error) {
+	cfg := ScoringConfig{}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return cfg, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err == nil {
+		content := expandEnvironmentVariables(string(data))
+		if err := yaml.Unmarshal([]byte(content), &cfg); err != nil {
+			return cfg, fmt.Errorf("invalid YAML in %s: %w", path, err)
+		}
+	}
+
+	cfg = applyScoringDefaults(cfg)
+	if v := os.Getenv("SCORE_AGE_CURVE"); v != "" {
+		cfg.Age.Curve = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("SCORE_AGE_FULL_MINUTES")); err == nil && v > 0 {
+		cfg.Age.FullMinutes = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("SCORE_AGE_MAX_POINTS")); err == nil {
+		cfg.Age.MaxPoints = v
+	}
+
+	if err := validateScoringConfig(cfg); err != nil {
+		return cfg, fmt.Errorf("invalid scoring configuration in %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// applyScoringDefaults fills the sections left unset from DefaultScoringConfig
+func applyScoringDefaults(cfg ScoringConfig) ScoringConfig {
+	if cfg.Severity == nil {
+		cfg.Severity = DefaultScoringConfig.Severity
+	}
+	if cfg.LLMRisk == nil {
+		cfg.LLMRisk = DefaultScoringConfig.LLMRisk
+	}
+	if cfg.SymptomWeights == nil {
+		cfg.SymptomWeights = DefaultScoringConfig.SymptomWeights
+	}
+	if cfg.Age.Curve == "" {
+		cfg.Age.Curve = DefaultScoringConfig.Age.Curve
+	}
+	if cfg.Age.FullMinutes == 0 {
+		cfg.Age.FullMinutes = DefaultScoringConfig.Age.FullMinutes
+	}
+	if cfg.Age.MaxPoints == 0 {
+		cfg.Age.MaxPoints = DefaultScoringConfig.Age.MaxPoints
+	}
+	if cfg.Decay.HalfLifeMinutes == 0 {
+		cfg.Decay.HalfLifeMinutes = DefaultScoringConfig.Decay.HalfLifeMinutes
+	}
+	if cfg.Decay.MinScore == 0 {
+		cfg.Decay.MinScore = DefaultScoringConfig.Decay.MinScore
+	}
+	if cfg.SLOBurn.FullBurnRate == 0 {
+		cfg.SLOBurn.FullBurnRate = DefaultScoringConfig.SLOBurn.FullBurnRate
+	}
+	if cfg.SLOBurn.MaxPoints == 0 {
+		cfg.SLOBurn.MaxPoints = DefaultScoringConfig.SLOBurn.MaxPoints
+	}
+	if cfg.Health.Severity == nil {
+		cfg.Health.Severity = DefaultScoringConfig.Health.Severity
+	}
+	if cfg.Health.DefaultPoints == 0 {
+		cfg.Health.DefaultPoints = DefaultScoringConfig.Health.DefaultPoints
+	}
+	if cfg.Health.MetricWeightPoints == 0 {
+		cfg.Health.MetricWeightPoints = DefaultScoringConfig.Health.MetricWeightPoints
+	}
+	return cfg
+}
+
+// validateScoringConfig keeps every rule within the 0-100 score range
+func validateScoringConfig(cfg ScoringConfig) error {
+	for severity, points := range cfg.Severity {
+		if points < 0 || points > 100 {
+			return fmt.Errorf("severity %s: points must be between 0 and 100", severity)
+		}
+	}
+	for risk, points := range cfg.LLMRisk {
+		if points.Base < 0 || points.Confidence < 0 || points.Base+points.Confidence > 100 {
+			return fmt.Errorf("llm_risk %s: base plus confidence must be between 0 and 100", risk)
+		}
+	}
+	for severity, weight := range cfg.SymptomWeights {
+		if weight < 0 {
+			return fmt.Errorf("symptom_weights %s must not be negative", severity)
+		}
+	}
+	if cfg.MetricWeightPoints < 0 {
+		return fmt.Errorf("metric_weight_points must not be negative")
+	}
+	if !slices.Contains(AgeCurves, cfg.Age.Curve) {
+		return fmt.Errorf("unknown age curve %q (expected one of %v)", cfg.Age.Curve, AgeCurves)
+	}
+	if cfg.Age.FullMinutes < 0 || cfg.Age.MaxPoints < 0 || cfg.Age.MaxPoints > 100 {
+		return fmt.Errorf("age full_minutes must be positive and max_points between 0 and 100")
+	}
+	if cfg.Decay.HalfLifeMinutes < 0 || cfg.Decay.MinScore < 0 || cfg.Decay.MinScore > 100 {
+		return fmt.Errorf("decay half_life_minutes must be positive and min_score between 0 and 100")
+	}
+	if cfg.SLOBurn.FullBurnRate <= 1 || cfg.SLOBurn.MaxPoints < 0 || cfg.SLOBurn.MaxPoints > 100 {
+		return fmt.Errorf("slo_burn full_burn_rate must be above 1 and max_points between 0 and 100")
+	}
+	for severity, points := range cfg.Health.Severity {
+		if points < 0 || points > 100 {
+			return fmt.Errorf("health severity %s: points must be between 0 and 100", severity)
+		}
+	}
+	if cfg.Health.DefaultPoints < 0 || cfg.Health.DefaultPoints > 100 {
+		return fmt.Errorf("health default_points must be between 0 and 100")
+	}
+	if cfg.Health.MetricWeightPoints < 0 {
+		return fmt.Errorf("health metric_weight_points must not be negative")
+	}
+	return nil
+}
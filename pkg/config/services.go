@@ -5,9 +5,12 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"time"
 
 	"gopkg.in/yaml.v3"
 	"vigilant/pkg/prometheus"
+	"vigilant/pkg/tracing"
 )
 
 // ServiceMetadata holds descriptive information about the service
@@ -17,12 +20,14 @@ type ServiceMetadata struct {
 	Version     string   `yaml:"version,omitempty"`
 	Tags        []string `yaml:"tags,omitempty"`
 	Maintainer  string   `yaml:"maintainer,omitempty"`
+	Team        string   `yaml:"team,omitempty"` // Team owning the service, which scopes API access (config/access.yml)
 }
 
 // AlertMatching defines how alerts are matched to this service
 type AlertMatching struct {
-	AlertPattern    string   `yaml:"alert_pattern"`
+	AlertPattern    string   `yaml:"alert_pattern"` // Alert name, or a regex matching whole alert names
 	SeverityLevels  []string `yaml:"severity_levels,omitempty"`
+	ServiceAliases  []string `yaml:"service_aliases,omitempty"` // Other names of the service in alert labels
 }
 
 // LogPattern defines symptom detection patterns with enhanced metadata
@@ -31,22 +36,328 @@ type LogPattern struct {
 	Description string `yaml:"description,omitempty"`
 	Regex       string `yaml:"regex"`
 	Severity    string `yaml:"severity,omitempty"`
+	ESQuery     string `yaml:"es_query,omitempty"` // Lucene query used for server-side matching when the regex can't be translated
 	
 	// Backward compatibility
 	Label string `yaml:"label,omitempty"`
 }
 
+// AnomalyDetection groups log lines no pattern matched into templates and flags templates
+// that are new or surging, catching failures nobody wrote a pattern for
+type AnomalyDetectionConfig struct {
+	Enabled      bool    `yaml:"enabled"`
+	WarmupScans  int     `yaml:"warmup_scans,omitempty"`  // Scans that only learn templates (default: 3)
+	MinCount     int     `yaml:"min_count,omitempty"`     // Lines a template needs in one scan to be flagged (default: 5)
+	SurgeFactor  float64 `yaml:"surge_factor,omitempty"`  // Count over the template's baseline that counts as a surge (default: 5)
+	Similarity   float64 `yaml:"similarity,omitempty"`    // Share of equal tokens for a line to join a template (default: 0.5)
+	MaxTemplates int     `yaml:"max_templates,omitempty"` // Templates kept per service (default: 1000)
+}
+
 // DataSources defines where to fetch observability data
 type DataSources struct {
-	Elasticsearch ElasticsearchConfig `yaml:"elasticsearch,omitempty"`
-	LogFile       string             `yaml:"log_file,omitempty"`
+	// Backend names the log source explicitly (see LogBackends); when empty the first
+	// configured backend below is used, else the deployment default
+	Backend        string               `yaml:"backend,omitempty"`
+	Elasticsearch  ElasticsearchConfig  `yaml:"elasticsearch,omitempty"`
+	Loki           LokiConfig           `yaml:"loki,omitempty"`
+	Splunk         SplunkConfig         `yaml:"splunk,omitempty"`
+	CloudWatchLogs CloudWatchLogsConfig `yaml:"cloudwatch_logs,omitempty"`
+	Journald       JournaldConfig       `yaml:"journald,omitempty"`
+	Kubernetes     KubernetesLogsConfig `yaml:"kubernetes,omitempty"`
+	ObjectStorage  ObjectStorageConfig  `yaml:"object_storage,omitempty"`
+	LogFile        LogFiles             `yaml:"log_file,omitempty"`
+	LogFileMode    string               `yaml:"log_file_mode,omitempty"` // "tail" (default), "follow" or "head"
+	Multiline      MultilineConfig      `yaml:"multiline,omitempty"`
+	Timestamp      TimestampConfig      `yaml:"timestamp,omitempty"`
+	Fields         FieldMappings        `yaml:"field_mappings,omitempty"`
+	// ServiceExtraction replaces the name heuristics that attribute log entries to services
+	ServiceExtraction ServiceExtractionConfig `yaml:"service_extraction,omitempty"`
+	// Prometheus overrides the deployment's Prometheus endpoint for this service's metrics
+	Prometheus PrometheusConfig `yaml:"prometheus,omitempty"`
+	// MetricsBackend selects where metric checks are queried (see MetricsBackends); when empty
+	// the deployment default applies
+	MetricsBackend string `yaml:"metrics_backend,omitempty"`
+	// CloudWatchMetrics configures the cloudwatch metrics backend
+	CloudWatchMetrics CloudWatchMetricsConfig `yaml:"cloudwatch_metrics,omitempty"`
+	// Tracing overrides where traces of this service are looked up (TRACING_BACKEND, TRACING_URL)
+	Tracing TracingConfig `yaml:"tracing,omitempty"`
+	// Mesh names the service in the service mesh graph (TOPOLOGY_BACKEND)
+	Mesh MeshConfig `yaml:"mesh,omitempty"`
+}
+
+// PrometheusConfig points metric queries at another Prometheus-compatible endpoint or tenant
+type PrometheusConfig struct {
+	URL         string            `yaml:"url,omitempty"`
+	PathPrefix  string            `yaml:"path_prefix,omitempty"`  // e.g. /prometheus (Mimir) or /select/0/prometheus (VictoriaMetrics)
+	Headers     map[string]string `yaml:"headers,omitempty"`      // e.g. X-Scope-OrgID: team-a
+	QueryParams map[string]string `yaml:"query_params,omitempty"` // e.g. dedup: "true" (Thanos)
+}
+
+// Endpoint returns the deployment endpoint with this config's settings applied
+func (p PrometheusConfig) Endpoint(defaults prometheus.Endpoint) prometheus.Endpoint {
+	return defaults.WithOverrides(prometheus.Endpoint{
+		URL:         p.URL,
+		PathPrefix:  p.PathPrefix,
+		Headers:     p.Headers,
+		QueryParams: p.QueryParams,
+	})
+}
+
+// CloudWatchMetricsConfig sets where metric checks query CloudWatch GetMetricData
+type CloudWatchMetricsConfig struct {
+	Region        string `yaml:"region,omitempty"`         // Defaults to AWS_REGION
+	RoleARN       string `yaml:"role_arn,omitempty"`       // Assumed before querying, for cross-account metrics
+	PeriodSeconds int    `yaml:"period_seconds,omitempty"` // Default aggregation period (default: 60)
+}
+
+// TracingConfig sets where and when the recent error and slow traces of a service are summarized
+// for its latency and error alerts
+type TracingConfig struct {
+	Backend       string            `yaml:"backend,omitempty"`         // jaeger or tempo
+	URL           string            `yaml:"url,omitempty"`             // Jaeger query service or Tempo URL
+	Service       string            `yaml:"service,omitempty"`         // Service name in the traces (default: the profile's)
+	Headers       map[string]string `yaml:"headers,omitempty"`         // e.g. X-Scope-OrgID: team-a
+	AlertPatterns []string          `yaml:"alert_patterns,omitempty"`  // Alert name regexes getting trace context (default: latency and error alerts)
+	MinDurationMs int               `yaml:"min_duration_ms,omitempty"` // Traces at least this long count as slow (default: 1000)
+	Disabled      bool              `yaml:"disabled,omitempty"`
+}
+
+// TracesAlert reports whether alerts named alertName get trace context
+func (t TracingConfig) TracesAlert(alertName string) bool {
+	if len(t.AlertPatterns) == 0 {
+		return tracing.DefaultAlertPattern.MatchString(alertName)
+	}
+	for _, pattern := range t.AlertPatterns {
+		if re, err := regexp.Compile(pattern); err == nil && re.MatchString(alertName) {
+			return true
+		}
+	}
+	return false
+}
+
+// MeshConfig maps a service to its node in the service mesh graph
+type MeshConfig struct {
+	Service  string `yaml:"service,omitempty"` // App name in the mesh (default: the profile's)
+	Disabled bool   `yaml:"disabled,omitempty"`
+}
+
+// MeshNames maps the mesh app names that differ from their profile's to the profile
+func MeshNames(profiles map[string]ServiceProfile) map[string]string {
+	names := make(map[string]string)
+	for serviceName, profile := range profiles {
+		if mesh := profile.DataSources.Mesh.Service; mesh != "" && mesh != serviceName {
+			names[mesh] = serviceName
+		}
+	}
+	return names
+}
+
+// ServiceExtractionConfig names the service of a log entry explicitly. The fields are tried in
+// order, then the regex; the heuristics (service, container, "name |" prefix) come last.
+type ServiceExtractionConfig struct {
+	Fields    []string          `yaml:"fields,omitempty"`    // Dotted paths into the entry, e.g. kubernetes.labels.app
+	Regex     string            `yaml:"regex,omitempty"`     // Matched against the message; its first group is the service
+	Overrides map[string]string `yaml:"overrides,omitempty"` // Extracted name -> service, used as-is
+}
+
+// Enabled reports whether any extraction rule is set
+func (s ServiceExtractionConfig) Enabled() bool {
+	return len(s.Fields) > 0 || s.Regex != "" || len(s.Overrides) > 0
+}
+
+// FieldMappings names the fields of structured (JSON) log entries, as dotted paths into
+// nested objects such as message.text or kubernetes.container_name
+type FieldMappings struct {
+	MessageField   string `yaml:"message_field,omitempty"`   // default: message
+	LevelField     string `yaml:"level_field,omitempty"`     // default: level
+	ServiceField   string `yaml:"service_field,omitempty"`   // default: service
+	ContainerField string `yaml:"container_field,omitempty"` // default: container
+}
+
+// LogFiles is a log file path or glob (e.g. /var/log/app/*.log), or a list of them
+type LogFiles []string
+
+// UnmarshalYAML accepts a single path as well as a list
+func (l *LogFiles) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		var path string
+		if err := value.Decode(&path); err != nil {
+			return err
+		}
+		*l = nil
+		if path != "" {
+			*l = LogFiles{path}
+		}
+		return nil
+	}
+	var paths []string
+	if err := value.Decode(&paths); err != nil {
+		return err
+	}
+	*l = paths
+	return nil
+}
+
+// MarshalYAML writes a single path as a plain string
+func (l LogFiles) MarshalYAML() (interface{}, error) {
+	if len(l) == 1 {
+		return l[0], nil
+	}
+	return []string(l), nil
+}
+
+// MultilineConfig merges multi-line events such as stack traces before pattern matching
+// (Elasticsearch and log file sources)
+type MultilineConfig struct {
+	StartPattern        string `yaml:"start_pattern,omitempty"`        // A line matching this starts a new event
+	ContinuationPattern string `yaml:"continuation_pattern,omitempty"` // A line matching this belongs to the previous event
+	MaxLines            int    `yaml:"max_lines,omitempty"`            // Lines per merged event (default: 200)
+}
+
+// Enabled reports whether multi-line events are merged
+func (m MultilineConfig) Enabled() bool {
+	return m.StartPattern != "" || m.ContinuationPattern != ""
+}
+
+// TimestampConfig reads the time of plain-text log file lines, so counts and last-seen times
+// reflect when lines were logged rather than when they were read
+type TimestampConfig struct {
+	Regex    string `yaml:"regex,omitempty"`    // Finds the timestamp: its first group, else the whole match
+	Layout   string `yaml:"layout,omitempty"`   // Go time layout, or unix / unix_ms (default: RFC3339)
+	Timezone string `yaml:"timezone,omitempty"` // Zone of layouts without one (default: local time)
+}
+
+// LogBackends lists the log source names a profile can select with data_sources.backend
+var LogBackends = []string{
+	"elasticsearch", "file", "loki", "splunk", "cloudwatch_logs",
+	"journald", "kubernetes", "object_storage", "kafka", "syslog",
+}
+
+// MetricsBackends lists the metric sources a profile can select with data_sources.metrics_backend
+var MetricsBackends = []string{"prometheus", "datadog", "cloudwatch", "influxdb"}
+
+// LogBackend returns the log source the profile selects: the explicit backend, else the first
+// backend with configuration, else "" (use the deployment default)
+func (d DataSources) LogBackend() string {
+	switch {
+	case d.Backend != "":
+		return d.Backend
+	case d.Loki.Enabled():
+		return "loki"
+	case d.Splunk.Enabled():
+		return "splunk"
+	case d.CloudWatchLogs.Enabled():
+		return "cloudwatch_logs"
+	case d.Journald.Enabled():
+		return "journald"
+	case d.Kubernetes.Enabled():
+		return "kubernetes"
+	case d.ObjectStorage.Enabled():
+		return "object_storage"
+	}
+	return ""
+}
+
+// LokiConfig selects Grafana Loki as the log source for a service
+type LokiConfig struct {
+	URL              string   `yaml:"url,omitempty"`   // Defaults to LOKI_URL
+	Query            string   `yaml:"query,omitempty"` // LogQL selector, {{.Service}} is replaced with the service name
+	TimeRangeMinutes int      `yaml:"time_range_minutes,omitempty"`
+	ScanLimit        int      `yaml:"scan_limit,omitempty"`
+	ServiceLabels    []string `yaml:"service_labels,omitempty"` // Stream labels checked, in order, for the service name
+	TenantID         string   `yaml:"tenant_id,omitempty"`      // Sent as X-Scope-OrgID for multi-tenant Loki
+}
+
+// Enabled reports whether the profile reads its logs from Loki
+func (l LokiConfig) Enabled() bool {
+	return l.Query != ""
+}
+
+// SplunkConfig selects Splunk as the log source for a service
+type SplunkConfig struct {
+	URL              string   `yaml:"url,omitempty"`    // Management API, defaults to SPLUNK_URL
+	Search           string   `yaml:"search,omitempty"` // SPL, {{.Service}} is replaced with the service name
+	TimeRangeMinutes int      `yaml:"time_range_minutes,omitempty"`
+	ScanLimit        int      `yaml:"scan_limit,omitempty"`
+	MessageField     string   `yaml:"message_field,omitempty"`  // Field matched against log patterns
+	ServiceFields    []string `yaml:"service_fields,omitempty"` // Fields checked, in order, for the service name
+}
+
+// Enabled reports whether the profile reads its logs from Splunk
+func (s SplunkConfig) Enabled() bool {
+	return s.Search != ""
+}
+
+// CloudWatchLogsConfig selects CloudWatch Logs Insights as the log source for a service
+type CloudWatchLogsConfig struct {
+	LogGroups        []string `yaml:"log_groups,omitempty"`
+	Query            string   `yaml:"query,omitempty"`    // Logs Insights query, {{.Service}} is replaced with the service name
+	Region           string   `yaml:"region,omitempty"`   // Defaults to AWS_REGION
+	RoleARN          string   `yaml:"role_arn,omitempty"` // Assumed before querying, for cross-account log groups
+	TimeRangeMinutes int      `yaml:"time_range_minutes,omitempty"`
+	ScanLimit        int      `yaml:"scan_limit,omitempty"`
+	MessageField     string   `yaml:"message_field,omitempty"`
+	ServiceFields    []string `yaml:"service_fields,omitempty"` // Result fields checked, in order, for the service name
+}
+
+// Enabled reports whether the profile reads its logs from CloudWatch Logs
+func (c CloudWatchLogsConfig) Enabled() bool {
+	return len(c.LogGroups) > 0
+}
+
+// JournaldConfig selects the systemd journal as the log source for a service on bare-metal/VM hosts
+type JournaldConfig struct {
+	Units            []string `yaml:"units,omitempty"`        // systemd units whose entries are scanned
+	Directory        string   `yaml:"directory,omitempty"`    // Journal directory, e.g. a mounted /var/log/journal
+	MaxPriority      string   `yaml:"max_priority,omitempty"` // Only entries at this priority or more severe (e.g. "warning")
+	TimeRangeMinutes int      `yaml:"time_range_minutes,omitempty"`
+	ScanLimit        int      `yaml:"scan_limit,omitempty"`
+}
+
+// Enabled reports whether the profile reads its logs from journald
+func (j JournaldConfig) Enabled() bool {
+	return len(j.Units) > 0
+}
+
+// KubernetesLogsConfig selects pod logs read through the Kubernetes API as the log source
+type KubernetesLogsConfig struct {
+	Namespace        string `yaml:"namespace,omitempty"`      // Defaults to Vigilant's own namespace
+	LabelSelector    string `yaml:"label_selector,omitempty"` // e.g. app={{.Service}}
+	Container        string `yaml:"container,omitempty"`      // Required for multi-container pods
+	TimeRangeMinutes int    `yaml:"time_range_minutes,omitempty"`
+	ScanLimit        int    `yaml:"scan_limit,omitempty"` // Lines read per pod
+	MaxPods          int    `yaml:"max_pods,omitempty"`
+}
+
+// Enabled reports whether the profile reads its logs from the Kubernetes API
+func (k KubernetesLogsConfig) Enabled() bool {
+	return k.LabelSelector != ""
+}
+
+// ObjectStorageConfig selects archived log objects in S3 (or an S3-compatible store) as the log source
+type ObjectStorageConfig struct {
+	Bucket           string `yaml:"bucket,omitempty"`
+	Prefix           string `yaml:"prefix,omitempty"`   // {{.Service}} is replaced with the service name
+	Endpoint         string `yaml:"endpoint,omitempty"` // e.g. https://storage.googleapis.com for GCS, defaults to AWS S3
+	Region           string `yaml:"region,omitempty"`   // Defaults to AWS_REGION ("auto" for GCS)
+	RoleARN          string `yaml:"role_arn,omitempty"`
+	TimeRangeMinutes int    `yaml:"time_range_minutes,omitempty"`
+	MaxObjects       int    `yaml:"max_objects,omitempty"` // Newest objects read per scan
+	ScanLimit        int    `yaml:"scan_limit,omitempty"`  // Lines read per scan across objects
+}
+
+// Enabled reports whether the profile reads archived logs from object storage
+func (o ObjectStorageConfig) Enabled() bool {
+	return o.Bucket != ""
 }
 
 // ElasticsearchConfig with enhanced configuration
 type ElasticsearchConfig struct {
 	IndexPattern     string   `yaml:"index_pattern,omitempty"`
 	TimeRangeMinutes int      `yaml:"time_range_minutes,omitempty"`
-	ScanLimit        int      `yaml:"scan_limit,omitempty"`
+	ScanLimit        int      `yaml:"scan_limit,omitempty"`    // Documents per page
+	MaxDocuments     int      `yaml:"max_documents,omitempty"` // Documents scanned per window across pages
+	MatchMode        string   `yaml:"match_mode,omitempty"`    // "client" (default) or "server" to match and count in Elasticsearch
 	ServiceFields    []string `yaml:"service_fields,omitempty"`
 	NamespaceFilter  string   `yaml:"namespace_filter,omitempty"`
 	RequiredFields   []string `yaml:"required_fields,omitempty"`
@@ -70,20 +381,39 @@ type AnalysisContext struct {
 	EscalationPath string   `yaml:"escalation_path,omitempty"`
 }
 
+// Runbook links remediation documentation to the alerts and symptoms it applies to
+type Runbook struct {
+	Name     string   `yaml:"name"`
+	URL      string   `yaml:"url"`
+	Patterns []string `yaml:"patterns,omitempty"` // Regexes matched against alert, log pattern and metric names; empty matches all
+}
+
 // ServiceProfile represents the complete service configuration
+
 type ServiceProfile struct {
 	// New enhanced structure
-	Metadata        ServiceMetadata       `yaml:",inline,omitempty"`
-	AlertMatching   AlertMatching         `yaml:",inline,omitempty"`
-	DataSources     DataSources           `yaml:"data_sources,omitempty"`
-	LogPatterns     []LogPattern          `yaml:"log_patterns,omitempty"`
-	Metrics         []EnhancedMetricCheck `yaml:"metrics,omitempty"`
-	AnalysisContext AnalysisContext       `yaml:"analysis_context,omitempty"`
-	
+	Metadata         ServiceMetadata           `yaml:",inline,omitempty"`
+	AlertMatching    AlertMatching             `yaml:",inline,omitempty"`
+	DataSources      DataSources               `yaml:"data_sources,omitempty"`
+	LogPatterns      []LogPattern              `yaml:"log_patterns,omitempty"`
+	ExcludePatterns  []string                  `yaml:"exclude_patterns,omitempty"` // Regexes for known-benign lines skipped before matching
+	AnomalyDetection AnomalyDetectionConfig    `yaml:"anomaly_detection,omitempty"`
+	Metrics          []EnhancedMetricCheck     `yaml:"metrics,omitempty"`
+	ContextMetrics   []prometheus.ContextQuery `yaml:"context_metrics,omitempty"` // Fetched for every analysis, without thresholds
+	SLOs             []prometheus.SLO          `yaml:"slos,omitempty"`            // Error budgets whose burn rate is tracked every cycle
+	AnalysisContext  AnalysisContext           `yaml:"analysis_context,omitempty"`
+	Runbooks         []Runbook                 `yaml:"runbooks,omitempty"`
+	CacheTTLMinutes  int                       `yaml:"cache_ttl_minutes,omitempty"` // Overrides the global LLM cache TTL
+	AlertTTLMinutes  int                       `yaml:"alert_ttl_minutes,omitempty"` // Overrides how long an alert stays tracked after it was last seen
+	EscalateAfterMinutes int                   `yaml:"escalate_after_minutes,omitempty"` // Overrides how long high or critical risk may go unacknowledged
+	DependsOn        []string                  `yaml:"depends_on,omitempty"`        // Services this one calls or needs
+	ConsumedBy       []string                  `yaml:"consumed_by,omitempty"`       // Services calling this one
+	QueryVars        map[string]string         `yaml:"query_vars,omitempty"`        // Extra metric query template variables, e.g. Cluster
+
 	// Backward compatibility fields
-	LogFile        string                   `yaml:"log_file,omitempty"`
-	Elasticsearch  ElasticsearchConfig      `yaml:"elasticsearch,omitempty"`
-	LegacyMetrics  []prometheus.MetricCheck `yaml:"-"` // Populated during migration
+	LogFile       string                   `yaml:"log_file,omitempty"`
+	Elasticsearch ElasticsearchConfig      `yaml:"elasticsearch,omitempty"`
+	LegacyMetrics []prometheus.MetricCheck `yaml:"-"` // Populated during migration
 }
 
 
@@ -114,27 +444,12 @@ func LoadServiceProfiles(dir string) (map[string]ServiceProfile, error) {
 			continue
 		}
 
-		// Perform environment variable substitution
-		content := expandEnvironmentVariables(string(data))
-
-		var profile ServiceProfile
-		if err := yaml.Unmarshal([]byte(content), &profile); err != nil {
-			fmt.Printf("Warning: invalid YAML in %s: %v\n", file, err)
-			continue
-		}
-
-		// Migrate legacy format to new format
-		profile = migrateLegacyConfig(profile, service)
-		
-		// Validate configuration
-		if err := validateServiceProfile(profile, service); err != nil {
-			fmt.Printf("Warning: invalid configuration in %s: %v\n", file, err)
+		profile, err := ParseServiceProfile(service, data)
+		if err != nil {
+			fmt.Printf("Warning: %v in %s\n", err, file)
 			continue
 		}
 
-		// Apply defaults
-		profile = applyDefaults(profile)
-
 		// Use the name field as the primary service identifier
 		serviceName := profile.Metadata.Name
 		if serviceName == "" {
@@ -155,6 +470,67 @@ func LoadServiceProfiles(dir string) (map[string]ServiceProfile, error) {
 	return profiles, nil
 }
 
+// ParseServiceProfile reads a profile the way LoadServiceProfiles does: environment variables
+// are expanded, the legacy format migrated, the result validated and defaulted. service is the
+// fallback name, normally the file name without extension.
+func ParseServiceProfile(service string, data []byte) (ServiceProfile, error) {
+	var profile ServiceProfile
+	if err := yaml.Unmarshal([]byte(expandEnvironmentVariables(string(data))), &profile); err != nil {
+		return profile, fmt.Errorf("invalid YAML: %w", err)
+	}
+	profile = migrateLegacyConfig(profile, service)
+	if err := validateServiceProfile(profile, service); err != nil {
+		return profile, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return applyDefaults(profile), nil
+}
+
+// CacheTTLOverrides returns the LLM cache TTL of every service that overrides the default
+func CacheTTLOverrides(profiles map[string]ServiceProfile) map[string]time.Duration {
+	overrides := make(map[string]time.Duration)
+	for serviceName, profile := range profiles {
+		if profile.CacheTTLMinutes > 0 {
+			overrides[serviceName] = time.Duration(profile.CacheTTLMinutes) * time.Minute
+		}
+	}
+	return overrides
+}
+
+// AlertTTLOverrides returns the alert tracking TTL of every service that overrides the default
+func AlertTTLOverrides(profiles map[string]ServiceProfile) map[string]time.Duration {
+	overrides := make(map[string]time.Duration)
+	for serviceName, profile := range profiles {
+		if profile.AlertTTLMinutes > 0 {
+			overrides[serviceName] = time.Duration(profile.AlertTTLMinutes) * time.Minute
+		}
+	}
+	return overrides
+}
+
+// EscalationDelayOverrides returns the escalation delay of every service that overrides the default
+func EscalationDelayOverrides(profiles map[string]ServiceProfile) map[string]time.Duration {
+	overrides := make(map[string]time.Duration)
+	for serviceName, profile := range profiles {
+		if profile.EscalateAfterMinutes > 0 {
+			overrides[serviceName] = time.Duration(profile.EscalateAfterMinutes) * time.Minute
+		}
+	}
+	return overrides
+}
+
+// ServiceDependencies returns the dependencies of every service, merging each profile's
+// depends_on with the consumed_by of the profiles it consumes
+func ServiceDependencies(profiles map[string]ServiceProfile) map[string][]string {
+	deps := make(map[string][]string)
+	for serviceName, profile := range profiles {
+		deps[serviceName] = append(deps[serviceName], profile.DependsOn...)
+		for _, consumer := range profile.ConsumedBy {
+			deps[consumer] = append(deps[consumer], serviceName)
+		}
+	}
+	return deps
+}
+
 // CreateAlertToServiceMapping creates a mapping from alert patterns to service names
 func CreateAlertToServiceMapping(profiles map[string]ServiceProfile) map[string]string {
 	mapping := make(map[string]string)
@@ -200,8 +576,8 @@ func expandEnvironmentVariables(content string) string {
 // migrateLegacyConfig converts legacy format to new enhanced format
 func migrateLegacyConfig(profile ServiceProfile, serviceName string) ServiceProfile {
 	// If using legacy structure, migrate to new format
-	if profile.LogFile != "" && profile.DataSources.LogFile == "" {
-		profile.DataSources.LogFile = profile.LogFile
+	if profile.LogFile != "" && len(profile.DataSources.LogFile) == 0 {
+		profile.DataSources.LogFile = LogFiles{profile.LogFile}
 	}
 	
 	// Migrate elasticsearch config
@@ -245,6 +621,10 @@ func validateServiceProfile(profile ServiceProfile, serviceName string) error {
 		return fmt.Errorf("service name is required")
 	}
 	
+	if _, err := regexp.Compile("^(?:" + profile.AlertMatching.AlertPattern + ")$"); err != nil {
+		return fmt.Errorf("invalid alert_pattern: %v", err)
+	}
+	
 	// Validate log patterns
 	for i, pattern := range profile.LogPatterns {
 		if pattern.Regex == "" {
@@ -257,6 +637,12 @@ func validateServiceProfile(profile ServiceProfile, serviceName string) error {
 		}
 	}
 	
+	for i, exclude := range profile.ExcludePatterns {
+		if _, err := regexp.Compile(exclude); err != nil {
+			return fmt.Errorf("invalid regex in exclude pattern %d: %v", i, err)
+		}
+	}
+	
 	// Validate metrics
 	for i, metric := range profile.Metrics {
 		if metric.Name == "" {
@@ -265,6 +651,146 @@ func validateServiceProfile(profile ServiceProfile, serviceName string) error {
 		if metric.QueryTpl == "" {
 			return fmt.Errorf("metric %d (%s) is missing query template", i, metric.Name)
 		}
+		if _, err := prometheus.ValidateQueryTemplate(metric.QueryTpl, profile.QueryVars); err != nil {
+			return fmt.Errorf("metric %d (%s): %v", i, metric.Name, err)
+		}
+		if !slices.Contains(prometheus.Operators, metric.Operator) {
+			return fmt.Errorf("metric %d (%s) has unknown operator %q (expected one of %v)", i, metric.Name, metric.Operator, prometheus.Operators)
+		}
+		if metric.Interval != "" {
+			if d, err := time.ParseDuration(metric.Interval); err != nil || d < 0 {
+				return fmt.Errorf("metric %d (%s) has invalid interval %q", i, metric.Name, metric.Interval)
+			}
+		}
+		if metric.Backend != "" && !slices.Contains(MetricsBackends, metric.Backend) {
+			return fmt.Errorf("metric %d (%s) has unknown backend %q (expected one of %v)", i, metric.Name, metric.Backend, MetricsBackends)
+		}
+		if !slices.Contains(prometheus.BaselineModes, metric.Baseline.Mode) {
+			return fmt.Errorf("metric %d (%s) has unknown baseline mode %q (expected rolling or seasonal)", i, metric.Name, metric.Baseline.Mode)
+		}
+		if !slices.Contains(prometheus.BaselineDirections, metric.Baseline.Direction) {
+			return fmt.Errorf("metric %d (%s) has unknown baseline direction %q (expected above, below or both)", i, metric.Name, metric.Baseline.Direction)
+		}
+	}
+	
+	for i, metric := range profile.ContextMetrics {
+		if metric.Name == "" {
+			return fmt.Errorf("context metric %d is missing name", i)
+		}
+		if _, err := prometheus.ValidateQueryTemplate(metric.QueryTpl, profile.QueryVars); err != nil {
+			return fmt.Errorf("context metric %d (%s): %v", i, metric.Name, err)
+		}
+	}
+	
+	for i, slo := range profile.SLOs {
+		if slo.Name == "" {
+			return fmt.Errorf("slo %d is missing name", i)
+		}
+		if slo.Target <= 0 || slo.Target >= 100 {
+			return fmt.Errorf("slo %d (%s): target must be a percentage between 0 and 100, e.g. 99.9", i, slo.Name)
+		}
+		if slo.WindowDays < 0 || slo.FastBurnRate < 0 {
+			return fmt.Errorf("slo %d (%s): window_days and fast_burn_rate must not be negative", i, slo.Name)
+		}
+		if _, err := slo.Windows(); err != nil {
+			return fmt.Errorf("slo %d (%s): %v", i, slo.Name, err)
+		}
+		vars := map[string]string{"Window": "1h"}
+		for k, v := range profile.QueryVars {
+			vars[k] = v
+		}
+		if _, err := prometheus.ValidateQueryTemplate(slo.ErrorRatioTpl, vars); err != nil {
+			return fmt.Errorf("slo %d (%s): %v", i, slo.Name, err)
+		}
+	}
+
+	if profile.CacheTTLMinutes < 0 {
+		return fmt.Errorf("cache_ttl_minutes must not be negative")
+	}
+	if profile.AlertTTLMinutes < 0 {
+		return fmt.Errorf("alert_ttl_minutes must not be negative")
+	}
+	if profile.EscalateAfterMinutes < 0 {
+		return fmt.Errorf("escalate_after_minutes must not be negative")
+	}
+	for _, dep := range append(append([]string{}, profile.DependsOn...), profile.ConsumedBy...) {
+		if dep == "" || dep == serviceName {
+			return fmt.Errorf("depends_on and consumed_by must name other services")
+		}
+	}
+	
+	for field, pattern := range map[string]string{
+		"start_pattern":        profile.DataSources.Multiline.StartPattern,
+		"continuation_pattern": profile.DataSources.Multiline.ContinuationPattern,
+	} {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid multiline %s: %v", field, err)
+		}
+	}
+	
+	if _, err := regexp.Compile(profile.DataSources.ServiceExtraction.Regex); err != nil {
+		return fmt.Errorf("invalid service_extraction regex: %v", err)
+	}
+	
+	if _, err := regexp.Compile(profile.DataSources.Timestamp.Regex); err != nil {
+		return fmt.Errorf("invalid timestamp regex: %v", err)
+	}
+	if tz := profile.DataSources.Timestamp.Timezone; tz != "" {
+		if _, err := time.LoadLocation(tz); err != nil {
+			return fmt.Errorf("invalid timestamp timezone %q: %v", tz, err)
+		}
+	}
+	
+	if mode := profile.DataSources.LogFileMode; mode != "" && mode != "tail" && mode != "follow" && mode != "head" {
+		return fmt.Errorf("unknown log_file_mode %q (expected tail, follow or head)", mode)
+	}
+	
+	if mode := profile.GetEffectiveElasticsearchConfig().MatchMode; mode != "" && mode != "client" && mode != "server" {
+		return fmt.Errorf("unknown elasticsearch match_mode %q (expected client or server)", mode)
+	}
+	
+	if backend := profile.DataSources.MetricsBackend; backend != "" && !slices.Contains(MetricsBackends, backend) {
+		return fmt.Errorf("unknown data_sources.metrics_backend %q (expected one of %v)", backend, MetricsBackends)
+	}
+	if period := profile.DataSources.CloudWatchMetrics.PeriodSeconds; period < 0 || (period > 60 && period%60 != 0) {
+		return fmt.Errorf("data_sources.cloudwatch_metrics.period_seconds must be a multiple of 60, got %d", period)
+	}
+	tracingConfig := profile.DataSources.Tracing
+	if tracingConfig.Backend != "" && !slices.Contains(tracing.Backends, tracingConfig.Backend) {
+		return fmt.Errorf("unknown data_sources.tracing.backend %q (expected one of %v)", tracingConfig.Backend, tracing.Backends)
+	}
+	for _, pattern := range tracingConfig.AlertPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid data_sources.tracing.alert_patterns regex %q: %w", pattern, err)
+		}
+	}
+	if tracingConfig.MinDurationMs < 0 {
+		return fmt.Errorf("data_sources.tracing.min_duration_ms must not be negative")
+	}
+	
+	if backend := profile.DataSources.Backend; backend != "" {
+		known := false
+		for _, name := range LogBackends {
+			if backend == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown data_sources.backend %q (expected one of %v)", backend, LogBackends)
+		}
+	}
+	
+	// Validate runbooks
+	for i, runbook := range profile.Runbooks {
+		if runbook.Name == "" || runbook.URL == "" {
+			return fmt.Errorf("runbook %d requires both name and url", i)
+		}
+		for _, pattern := range runbook.Patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("invalid pattern in runbook %d (%s): %v", i, runbook.Name, err)
+			}
+		}
 	}
 	
 	return nil
@@ -290,6 +816,125 @@ func applyDefaults(profile ServiceProfile) ServiceProfile {
 		profile.DataSources.Elasticsearch.RequiredFields = []string{"@timestamp", "log", "kubernetes.container_name"}
 	}
 	
+	// Default field mappings (the fields ESLogEntry used to be hardcoded to)
+	fields := &profile.DataSources.Fields
+	if fields.MessageField == "" {
+		fields.MessageField = "message"
+	}
+	if fields.LevelField == "" {
+		fields.LevelField = "level"
+	}
+	if fields.ServiceField == "" {
+		fields.ServiceField = "service"
+	}
+	if fields.ContainerField == "" {
+		fields.ContainerField = "container"
+	}
+	
+	// Default anomaly detection settings (only used when enabled)
+	if ad := &profile.AnomalyDetection; ad.Enabled {
+		if ad.WarmupScans == 0 {
+			ad.WarmupScans = 3
+		}
+		if ad.MinCount == 0 {
+			ad.MinCount = 5
+		}
+		if ad.SurgeFactor == 0 {
+			ad.SurgeFactor = 5
+		}
+		if ad.Similarity == 0 {
+			ad.Similarity = 0.5
+		}
+		if ad.MaxTemplates == 0 {
+			ad.MaxTemplates = 1000
+		}
+	}
+	
+	// Default Loki configuration (only used when a query is configured)
+	if profile.DataSources.Loki.Enabled() {
+		if profile.DataSources.Loki.TimeRangeMinutes == 0 {
+			profile.DataSources.Loki.TimeRangeMinutes = 15
+		}
+		if profile.DataSources.Loki.ScanLimit == 0 {
+			profile.DataSources.Loki.ScanLimit = 500
+		}
+		if len(profile.DataSources.Loki.ServiceLabels) == 0 {
+			profile.DataSources.Loki.ServiceLabels = []string{"service_name", "app", "container", "job"}
+		}
+	}
+	
+	// Default Splunk configuration (only used when a search is configured)
+	if profile.DataSources.Splunk.Enabled() {
+		if profile.DataSources.Splunk.TimeRangeMinutes == 0 {
+			profile.DataSources.Splunk.TimeRangeMinutes = 15
+		}
+		if profile.DataSources.Splunk.ScanLimit == 0 {
+			profile.DataSources.Splunk.ScanLimit = 500
+		}
+		if profile.DataSources.Splunk.MessageField == "" {
+			profile.DataSources.Splunk.MessageField = "_raw"
+		}
+		if len(profile.DataSources.Splunk.ServiceFields) == 0 {
+			profile.DataSources.Splunk.ServiceFields = []string{"service", "app", "sourcetype", "host"}
+		}
+	}
+	
+	// Default journald configuration (only used when units are configured)
+	if profile.DataSources.Journald.Enabled() {
+		if profile.DataSources.Journald.TimeRangeMinutes == 0 {
+			profile.DataSources.Journald.TimeRangeMinutes = 15
+		}
+		if profile.DataSources.Journald.ScanLimit == 0 {
+			profile.DataSources.Journald.ScanLimit = 500
+		}
+	}
+	
+	// Default Kubernetes pod log configuration (only used when a label selector is configured)
+	if profile.DataSources.Kubernetes.Enabled() {
+		if profile.DataSources.Kubernetes.TimeRangeMinutes == 0 {
+			profile.DataSources.Kubernetes.TimeRangeMinutes = 15
+		}
+		if profile.DataSources.Kubernetes.ScanLimit == 0 {
+			profile.DataSources.Kubernetes.ScanLimit = 500
+		}
+		if profile.DataSources.Kubernetes.MaxPods == 0 {
+			profile.DataSources.Kubernetes.MaxPods = 10
+		}
+	}
+	
+	// Default object storage configuration (only used when a bucket is configured)
+	if profile.DataSources.ObjectStorage.Enabled() {
+		if profile.DataSources.ObjectStorage.TimeRangeMinutes == 0 {
+			profile.DataSources.ObjectStorage.TimeRangeMinutes = 60
+		}
+		if profile.DataSources.ObjectStorage.MaxObjects == 0 {
+			profile.DataSources.ObjectStorage.MaxObjects = 20
+		}
+		if profile.DataSources.ObjectStorage.ScanLimit == 0 {
+			profile.DataSources.ObjectStorage.ScanLimit = 5000
+		}
+	}
+	
+	// Default CloudWatch Logs configuration (only used when log groups are configured)
+	if profile.DataSources.CloudWatchLogs.Enabled() {
+		cw := &profile.DataSources.CloudWatchLogs
+		if cw.Query == "" {
+			cw.Query = "fields @timestamp, @message, @logStream | sort @timestamp desc"
+		}
+		if cw.TimeRangeMinutes == 0 {
+			cw.TimeRangeMinutes = 15
+		}
+		if cw.ScanLimit == 0 {
+			cw.ScanLimit = 500
+		}
+		if cw.MessageField == "" {
+			cw.MessageField = "@message"
+		}
+		if len(cw.ServiceFields) == 0 {
+			cw.ServiceFields = []string{"service", "@logStream"}
+		}
+	}
+	
 	// Default severity levels
 	if len(profile.AlertMatching.SeverityLevels) == 0 {
 		profile.AlertMatching.SeverityLevels = []string{"warning", "critical"}
@@ -312,12 +957,15 @@ func (p *ServiceProfile) GetEffectiveElasticsearchConfig() ElasticsearchConfig {
 	return p.Elasticsearch
 }
 
-// GetEffectiveLogFile returns the active log file path
-func (p *ServiceProfile) GetEffectiveLogFile() string {
-	if p.DataSources.LogFile != "" {
+// GetEffectiveLogFiles returns the active log file paths and globs
+func (p *ServiceProfile) GetEffectiveLogFiles() []string {
+	if len(p.DataSources.LogFile) > 0 {
 		return p.DataSources.LogFile
 	}
-	return p.LogFile
+	if p.LogFile != "" {
+		return []string{p.LogFile}
+	}
+	return nil
 }
 
 // GetEffectiveMetrics returns metrics in the standard format
@@ -331,3 +979,49 @@ func (p *ServiceProfile) GetEffectiveMetrics() []prometheus.MetricCheck {
 	
 	return metrics
 }
+
+// PatternSeverity returns the severity of the log pattern with the given name (or legacy label)
+func (p *ServiceProfile) PatternSeverity(name string) string {
+	for _, pattern := range p.LogPatterns {
+		if pattern.Name == name || (pattern.Name == "" && pattern.Label == name) {
+			return pattern.Severity
+		}
+	}
+	return ""
+}
+
+// MatchingRunbooks returns the runbooks applicable to an alert and its matched symptom/metric names
+func (p *ServiceProfile) MatchingRunbooks(alertName string, symptoms []string, metrics []string) []Runbook {
+	var matched []Runbook
+
+	names := append([]string{alertName}, symptoms...)
+	names = append(names, metrics...)
+
+	for _, runbook := range p.Runbooks {
+		if len(runbook.Patterns) == 0 {
+			matched = append(matched, runbook)
+			continue
+		}
+		if runbookMatches(runbook, names) {
+			matched = append(matched, runbook)
+		}
+	}
+
+	return matched
+}
+
+// runbookMatches reports whether any runbook pattern matches any of the names
+func runbookMatches(runbook Runbook, names []string) bool {
+	for _, pattern := range runbook.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			continue
+		}
+		for _, name := range names {
+			if re.MatchString(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
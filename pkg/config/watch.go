@@ -0,0 +1,60 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatchProfiles calls onChange once the profile files in dir have stopped changing for
+// debounce, until ctx is done. Files replaced through a rename, as editors and ProfileFiles
+// do, are seen as well.
+func WatchProfiles(ctx context.Context, dir string, debounce time.Duration, onChange func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+
+	go func() {
+		defer watcher.Close()
+		var settled <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op != fsnotify.Chmod && isProfileFile(event.Name) {
+					settled = time.After(debounce)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				fmt.Printf("Warning: watching %s: %v\n", dir, err)
+			case <-settled:
+				settled = nil
+				onChange()
+			}
+		}
+	}()
+	return nil
+}
+
+// isProfileFile reports whether LoadServiceProfiles reads the file; hidden files such as the
+// temporary files of ProfileFiles are skipped
+func isProfileFile(path string) bool {
+	name := filepath.Base(path)
+	ext := filepath.Ext(name)
+	return !strings.HasPrefix(name, ".") && (ext == ".yml" || ext == ".yaml")
+}
@@ -0,0 +1,138 @@
+package datadog
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"vigilant/pkg/prometheus"
+)
+
+// currentWindow is how far back a query for the current value looks; Datadog has no instant
+// queries, so the latest point in this window counts
+const currentWindow = 5 * time.Minute
+
+// Client runs metric check queries against the Datadog metrics API, e.g.
+// avg:trace.http.request.duration{service:{{.Service}}}. It implements prometheus.MetricSource.
+type Client struct {
+	site       string
+	apiKey     string
+	appKey     string
+	httpClient *http.Client
+}
+
+// NewClient returns a client for a Datadog site such as datadoghq.com or datadoghq.eu
+func NewClient(site, apiKey, appKey string) (*Client, error) {
+	if apiKey == "" || appKey == "" {
+		return nil, fmt.Errorf("datadog needs both an API key and an application key")
+	}
+	if site == "" {
+		site = "datadoghq.com"
+	}
+	return &Client{
+		site:       site,
+		apiKey:     apiKey,
+		appKey:     appKey,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Name identifies the client by its site
+func (c *Client) Name() string {
+	return "datadog:" + c.site
+}
+
+// Query returns the latest point of every series over the last few minutes
+func (c *Client) Query(query string) ([]prometheus.Series, error) {
+	now := time.Now()
+	series, err := c.QueryRange(query, now.Add(-currentWindow), now, 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := range series {
+		series[i].Values = series[i].Values[len(series[i].Values)-1:]
+	}
+	return series, nil
+}
+
+// QueryRange returns every series' points between start and end. Datadog chooses the
+// resolution itself, so step is ignored; append .rollup() to the query to control it.
+func (c *Client) QueryRange(query string, start, end time.Time, step time.Duration) ([]prometheus.Series, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("from", strconv.FormatInt(start.Unix(), 10))
+	params.Set("to", strconv.FormatInt(end.Unix(), 10))
+
+	req, err := http.NewRequest(http.MethodGet, "https://api."+c.site+"/api/v1/query?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("DD-API-KEY", c.apiKey)
+	req.Header.Set("DD-APPLICATION-KEY", c.appKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("datadog query failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var data struct {
+		Status string   `json:"status"`
+		Error  string   `json:"error"`
+		Errors []string `json:"errors"`
+		Series []struct {
+			Scope     string       `json:"scope"`
+			TagSet    []string     `json:"tag_set"`
+			PointList [][]*float64 `json:"pointlist"`
+		} `json:"series"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode datadog response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || data.Status == "error" {
+		msg := data.Error
+		if msg == "" {
+			msg = strings.Join(data.Errors, "; ")
+		}
+		return nil, fmt.Errorf("datadog query failed (HTTP %d): %s", resp.StatusCode, msg)
+	}
+
+	var series []prometheus.Series
+	for _, s := range data.Series {
+		var values []float64
+		for _, point := range s.PointList {
+			// Points are [timestamp, value] with null values for gaps
+			if len(point) < 2 || point[1] == nil || math.IsNaN(*point[1]) || math.IsInf(*point[1], 0) {
+				continue
+			}
+			values = append(values, *point[1])
+		}
+		if len(values) > 0 {
+			series = append(series, prometheus.Series{Labels: tagLabels(s.TagSet, s.Scope), Values: values})
+		}
+	}
+	return series, nil
+}
+
+// tagLabels turns a series' tags (host:a, service:b) into labels; queries without a group by
+// have no tag set and fall back to the scope
+func tagLabels(tags []string, scope string) map[string]string {
+	if len(tags) == 0 && scope != "" && scope != "*" {
+		tags = strings.Split(scope, ",")
+	}
+	labels := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		key, value, found := strings.Cut(tag, ":")
+		if !found {
+			value = ""
+		}
+		labels[key] = value
+	}
+	return labels
+}
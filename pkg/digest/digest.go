@@ -0,0 +1,384 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"vigilant/pkg/history"
+	"vigilant/pkg/notify"
+	"vigilant/pkg/risk"
+	"vigilant/pkg/summarizer"
+)
+
+// Period is the time window a digest covers
+type Period string
+
+const (
+	Daily  Period = "daily"
+	Weekly Period = "weekly"
+)
+
+// Window returns the length of the period
+func (p Period) Window() time.Duration {
+	if p == Weekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// ParsePeriod accepts "daily"/"24h" and "weekly"/"7d"
+func ParsePeriod(s string) (Period, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "daily", "24h", "1d":
+		return Daily, nil
+	case "weekly", "7d":
+		return Weekly, nil
+	}
+	return "", fmt.Errorf("unknown digest period %q (use daily or weekly)", s)
+}
+
+// RiskItem is one of the highest-risk incidents of the period
+type RiskItem struct {
+	IncidentID string `json:"incident_id"`
+	Service    string `json:"service"`
+	AlertName  string `json:"alert_name"`
+	Risk       string `json:"risk"`
+	RootCause  string `json:"root_cause"`
+	Resolved   bool   `json:"resolved"`
+}
+
+// Pattern is a log symptom seen across several incidents
+type Pattern struct {
+	Pattern     string   `json:"pattern"`
+	Incidents   int      `json:"incidents"`
+	Occurrences int      `json:"occurrences"`
+	Services    []string `json:"services"`
+}
+
+// ServiceNoise counts how many incidents a service raised
+type ServiceNoise struct {
+	Service   string `json:"service"`
+	Incidents int    `json:"incidents"`
+	Alerts    int    `json:"distinct_alerts"`
+}
+
+// Digest summarizes all incidents of one period
+type Digest struct {
+	Period            Period         `json:"period"`
+	From              time.Time      `json:"from"`
+	To                time.Time      `json:"to"`
+	GeneratedAt       time.Time      `json:"generated_at"`
+	IncidentCount     int            `json:"incident_count"`
+	OpenCount         int            `json:"open_count"`
+	TopRisks          []RiskItem     `json:"top_risks"`
+	RecurringPatterns []Pattern      `json:"recurring_patterns"`
+	NoisyServices     []ServiceNoise `json:"noisy_services"`
+	Report            string         `json:"report"` // Markdown, written by the LLM when enabled
+	Fallback          bool           `json:"fallback,omitempty"`
+}
+
+const (
+	maxTopRisks      = 5
+	maxPatterns      = 10
+	maxNoisyServices = 5
+)
+
+// Generate builds the digest for the period ending at `to`. With useLLM the report
+// is written by the configured LLM provider, otherwise (or on failure) it is rendered locally.
+func Generate(ctx context.Context, store *history.Store, period Period, to time.Time, useLLM bool) Digest {
+	from := to.Add(-period.Window())
+	incidents := store.Between(from, to)
+
+	d := Digest{
+		Period:            period,
+		From:              from,
+		To:                to,
+		GeneratedAt:       time.Now(),
+		IncidentCount:     len(incidents),
+		TopRisks:          topRisks(incidents),
+		RecurringPatterns: recurringPatterns(incidents),
+		NoisyServices:     noisyServices(incidents),
+	}
+	for _, inc := range incidents {
+		if !inc.Resolved() {
+			d.OpenCount++
+		}
+	}
+
+	if useLLM && len(incidents) > 0 {
+		report, err := summarizer.GenerateText(ctx, digestSystemPrompt, buildDigestPrompt(d, incidents), 2000)
+		if err == nil && strings.TrimSpace(report) != "" {
+			d.Report = report
+			return d
+		}
+		fmt.Printf("[DIGEST] LLM report failed, using local report: %v\n", err)
+	}
+
+	d.Report = renderReport(d)
+	d.Fallback = useLLM && len(incidents) > 0
+	return d
+}
+
+func topRisks(incidents []history.Incident) []RiskItem {
+	sorted := append([]history.Incident(nil), incidents...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ri, rj := risk.LevelRank(sorted[i].Risk), risk.LevelRank(sorted[j].Risk)
+		if ri != rj {
+			return ri > rj
+		}
+		return sorted[i].FirstSeen.After(sorted[j].FirstSeen)
+	})
+	if len(sorted) > maxTopRisks {
+		sorted = sorted[:maxTopRisks]
+	}
+
+	items := make([]RiskItem, 0, len(sorted))
+	for _, inc := range sorted {
+		items = append(items, RiskItem{
+			IncidentID: inc.ID,
+			Service:    inc.Service,
+			AlertName:  inc.AlertName,
+			Risk:       inc.Risk,
+			RootCause:  inc.RootCause,
+			Resolved:   inc.Resolved(),
+		})
+	}
+	return items
+}
+
+// recurringPatterns returns symptoms that appeared in more than one incident
+func recurringPatterns(incidents []history.Incident) []Pattern {
+	byPattern := make(map[string]*Pattern)
+	services := make(map[string]map[string]bool)
+	for _, inc := range incidents {
+		for _, symptom := range inc.Symptoms {
+			p, ok := byPattern[symptom]
+			if !ok {
+				p = &Pattern{Pattern: symptom}
+				byPattern[symptom] = p
+				services[symptom] = make(map[string]bool)
+			}
+			p.Incidents++
+			p.Occurrences += inc.SymptomCounts[symptom]
+			services[symptom][inc.Service] = true
+		}
+	}
+
+	var patterns []Pattern
+	for symptom, p := range byPattern {
+		if p.Incidents < 2 {
+			continue
+		}
+		for svc := range services[symptom] {
+			p.Services = append(p.Services, svc)
+		}
+		sort.Strings(p.Services)
+		patterns = append(patterns, *p)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if patterns[i].Incidents != patterns[j].Incidents {
+			return patterns[i].Incidents > patterns[j].Incidents
+		}
+		return patterns[i].Occurrences > patterns[j].Occurrences
+	})
+	if len(patterns) > maxPatterns {
+		patterns = patterns[:maxPatterns]
+	}
+	return patterns
+}
+
+func noisyServices(incidents []history.Incident) []ServiceNoise {
+	counts := make(map[string]int)
+	alerts := make(map[string]map[string]bool)
+	for _, inc := range incidents {
+		counts[inc.Service]++
+		if alerts[inc.Service] == nil {
+			alerts[inc.Service] = make(map[string]bool)
+		}
+		alerts[inc.Service][inc.AlertName] = true
+	}
+
+	noisy := make([]ServiceNoise, 0, len(counts))
+	for svc, n := range counts {
+		noisy = append(noisy, ServiceNoise{Service: svc, Incidents: n, Alerts: len(alerts[svc])})
+	}
+	sort.Slice(noisy, func(i, j int) bool {
+		if noisy[i].Incidents != noisy[j].Incidents {
+			return noisy[i].Incidents > noisy[j].Incidents
+		}
+		return noisy[i].Service < noisy[j].Service
+	})
+	if len(noisy) > maxNoisyServices {
+		noisy = noisy[:maxNoisyServices]
+	}
+	return noisy
+}
+
+const digestSystemPrompt = `You are a senior SRE writing a periodic operations digest for an engineering team.
+You receive statistics and a list of incidents from the reporting period.
+Write a concise Markdown report with these sections:
+## Overview - 2-3 sentences on overall stability in the period
+## Top Risks - the most severe incidents and why they matter
+## Recurring Patterns - symptoms or root causes that keep coming back
+## Noisy Services - services that alerted most, and whether the alerts look actionable
+## Recommendations - 3-5 concrete follow-ups ranked by impact
+Only use facts present in the input. Do not wrap the report in a code block.`
+
+func buildDigestPrompt(d Digest, incidents []history.Incident) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "PERIOD: %s (%s to %s)\n", d.Period, d.From.Format(time.RFC3339), d.To.Format(time.RFC3339))
+	fmt.Fprintf(&b, "INCIDENTS: %d total, %d still open\n\n", d.IncidentCount, d.OpenCount)
+
+	b.WriteString("NOISY_SERVICES:\n")
+	for _, s := range d.NoisyServices {
+		fmt.Fprintf(&b, "- %s: %d incidents, %d distinct alerts\n", s.Service, s.Incidents, s.Alerts)
+	}
+
+	b.WriteString("\nRECURRING_PATTERNS:\n")
+	if len(d.RecurringPatterns) == 0 {
+		b.WriteString("- none\n")
+	}
+	for _, p := range d.RecurringPatterns {
+		fmt.Fprintf(&b, "- %q in %d incidents (%d occurrences) on %s\n", p.Pattern, p.Incidents, p.Occurrences, strings.Join(p.Services, ", "))
+	}
+
+	b.WriteString("\nINCIDENTS:\n")
+	for _, inc := range incidents {
+		status := "open"
+		if inc.Resolved() {
+			status = "resolved after " + inc.ResolvedAt.Sub(inc.FirstSeen).Round(time.Minute).String()
+		}
+		fmt.Fprintf(&b, "- [%s] %s / %s severity=%s risk=%s status=%s\n  root cause: %s\n",
+			inc.ID, inc.Service, inc.AlertName, inc.Severity, inc.Risk, status, inc.RootCause)
+	}
+	return b.String()
+}
+
+// renderReport produces a Markdown report from the digest statistics alone
+func renderReport(d Digest) string {
+	var b strings.Builder
+	title := "Daily"
+	if d.Period == Weekly {
+		title = "Weekly"
+	}
+	fmt.Fprintf(&b, "# %s Incident Digest\n\n", title)
+	fmt.Fprintf(&b, "%s to %s\n\n", d.From.Format("2006-01-02 15:04 MST"), d.To.Format("2006-01-02 15:04 MST"))
+
+	if d.IncidentCount == 0 {
+		b.WriteString("No incidents were recorded in this period.\n")
+		return b.String()
+	}
+
+	b.WriteString("## Overview\n\n")
+	fmt.Fprintf(&b, "%d incidents recorded, %d still open.\n\n", d.IncidentCount, d.OpenCount)
+
+	b.WriteString("## Top Risks\n\n")
+	for _, r := range d.TopRisks {
+		status := "open"
+		if r.Resolved {
+			status = "resolved"
+		}
+		fmt.Fprintf(&b, "- **%s** %s / %s (%s): %s\n", r.Risk, r.Service, r.AlertName, status, r.RootCause)
+	}
+
+	b.WriteString("\n## Recurring Patterns\n\n")
+	if len(d.RecurringPatterns) == 0 {
+		b.WriteString("No symptom appeared in more than one incident.\n")
+	}
+	for _, p := range d.RecurringPatterns {
+		fmt.Fprintf(&b, "- `%s` in %d incidents on %s\n", p.Pattern, p.Incidents, strings.Join(p.Services, ", "))
+	}
+
+	b.WriteString("\n## Noisy Services\n\n")
+	for _, s := range d.NoisyServices {
+		fmt.Fprintf(&b, "- %s: %d incidents across %d alerts\n", s.Service, s.Incidents, s.Alerts)
+	}
+	return b.String()
+}
+
+// Scheduler generates digests on a daily or weekly schedule and keeps the latest of each period
+type Scheduler struct {
+	store     *history.Store
+	period    Period
+	hour      int
+	useLLM    bool
+	notifiers []notify.Notifier
+
+	latest map[Period]Digest
+	mu     sync.RWMutex
+}
+
+// NewScheduler creates a scheduler that publishes a digest of the given period at hour (local time).
+// Weekly digests are published on Mondays.
+func NewScheduler(store *history.Store, period Period, hour int, useLLM bool, notifiers []notify.Notifier) *Scheduler {
+	return &Scheduler{
+		store:     store,
+		period:    period,
+		hour:      hour,
+		useLLM:    useLLM,
+		notifiers: notifiers,
+		latest:    make(map[Period]Digest),
+	}
+}
+
+// Run blocks until ctx is cancelled, generating and sending a digest at every scheduled time
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		next := s.nextRun(time.Now())
+		fmt.Printf("[DIGEST] Next %s digest at %s\n", s.period, next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		d := s.Refresh(ctx, s.period)
+		fmt.Printf("[DIGEST] Generated %s digest covering %d incidents\n", d.Period, d.IncidentCount)
+		notify.Broadcast(ctx, s.notifiers, notify.Message{
+			Title: fmt.Sprintf("Vigilant %s digest: %d incidents", d.Period, d.IncidentCount),
+			Text:  d.Report,
+			Kind:  "digest",
+		})
+	}
+}
+
+// Latest returns the most recent digest of a period, generating one if none exists yet
+func (s *Scheduler) Latest(ctx context.Context, period Period) Digest {
+	s.mu.RLock()
+	d, ok := s.latest[period]
+	s.mu.RUnlock()
+	if ok {
+		return d
+	}
+	return s.Refresh(ctx, period)
+}
+
+// Refresh generates a new digest for the period ending now and stores it as the latest
+func (s *Scheduler) Refresh(ctx context.Context, period Period) Digest {
+	d := Generate(ctx, s.store, period, time.Now(), s.useLLM)
+
+	s.mu.Lock()
+	s.latest[period] = d
+	s.mu.Unlock()
+	return d
+}
+
+func (s *Scheduler) nextRun(now time.Time) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), s.hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	if s.period == Weekly {
+		for next.Weekday() != time.Monday {
+			next = next.AddDate(0, 0, 1)
+		}
+	}
+	return next
+}
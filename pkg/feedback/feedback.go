@@ -0,0 +1,142 @@
+package feedback
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"vigilant/pkg/hashutil"
+)
+
+// Entry is an operator's verdict on one LLM analysis
+type Entry struct {
+	Fingerprint string    `json:"fingerprint"`
+	Service     string    `json:"service"`
+	AlertName   string    `json:"alert_name"`
+	IncidentID  string    `json:"incident_id,omitempty"`
+	Correct     bool      `json:"correct"`
+	Notes       string    `json:"notes,omitempty"`
+	RootCause   string    `json:"root_cause,omitempty"` // The analysis the feedback refers to
+	By          string    `json:"by,omitempty"`         // Logged-in user who gave the feedback
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// IsCorrection reports whether the entry rejects an analysis and explains what was wrong
+func (e Entry) IsCorrection() bool {
+	return !e.Correct && strings.TrimSpace(e.Notes) != ""
+}
+
+// Fingerprint identifies an alert independently of the occurrence, so feedback
+// given on one incident applies to every later firing of the same alert
+func Fingerprint(service, alertName string) string {
+	return hashutil.HashData([]string{service, alertName})[:12]
+}
+
+// Store keeps feedback in memory and appends every entry to a JSONL file
+type Store struct {
+	path          string
+	byFingerprint map[string][]Entry // Oldest first
+	mu            sync.RWMutex
+}
+
+// NewStore loads previously recorded feedback from path (created if missing)
+func NewStore(path string) (*Store, error) {
+	s := &Store{
+		path:          path,
+		byFingerprint: make(map[string][]Entry),
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create feedback directory: %w", err)
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open feedback file: %w", err)
+	}
+	defer file.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			fmt.Printf("[FEEDBACK] Skipping malformed record: %v\n", err)
+			continue
+		}
+		s.byFingerprint[e.Fingerprint] = append(s.byFingerprint[e.Fingerprint], e)
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read feedback file: %w", err)
+	}
+
+	fmt.Printf("[FEEDBACK] Loaded %d feedback entries from %s\n", count, path)
+	return s, nil
+}
+
+// Record persists a feedback entry, filling in its fingerprint and timestamp
+func (s *Store) Record(e Entry) (Entry, error) {
+	e.Fingerprint = Fingerprint(e.Service, e.AlertName)
+	e.Notes = strings.TrimSpace(e.Notes)
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return e, fmt.Errorf("failed to encode feedback: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return e, fmt.Errorf("failed to open feedback file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return e, fmt.Errorf("failed to write feedback: %w", err)
+	}
+
+	s.byFingerprint[e.Fingerprint] = append(s.byFingerprint[e.Fingerprint], e)
+	return e, nil
+}
+
+// ForFingerprint returns all feedback for an alert fingerprint, newest first
+func (s *Store) ForFingerprint(fingerprint string) []Entry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := s.byFingerprint[fingerprint]
+	result := make([]Entry, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		result = append(result, entries[i])
+	}
+	return result
+}
+
+// Corrections returns up to limit corrections for an alert fingerprint, newest first
+func (s *Store) Corrections(fingerprint string, limit int) []Entry {
+	var corrections []Entry
+	for _, e := range s.ForFingerprint(fingerprint) {
+		if !e.IsCorrection() {
+			continue
+		}
+		corrections = append(corrections, e)
+		if limit > 0 && len(corrections) >= limit {
+			break
+		}
+	}
+	return corrections
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/md5"
 	"encoding/json"
 	"fmt"
+	"math"
 )
 
 // HashData creates an MD5 hash of the given data by marshaling it to JSON
@@ -44,4 +45,39 @@ type SimplifiedAlert struct {
 	Service   string
 	AlertName string
 	Severity  string
-}
\ No newline at end of file
+	Pending   bool `json:",omitempty"` // Left out when false, so firing alerts hash as before
+}
+// Normalizer coarsens volatile numbers before hashing so that insignificant
+// fluctuations (CPU 0.8132 vs 0.8140, 14 vs 15 errors) produce the same hash
+type Normalizer struct {
+	SignificantDigits int   // Metric values are rounded to this many significant digits, 0 keeps them exact
+	CountBands        []int // Ascending band lower bounds; a count is replaced by the band it falls in, empty keeps counts exact
+}
+
+// DefaultCountBands groups counts on a roughly logarithmic scale
+var DefaultCountBands = []int{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 5000, 10000}
+
+// Value rounds v to the configured number of significant digits
+func (n Normalizer) Value(v float64) float64 {
+	if n.SignificantDigits <= 0 || v == 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return v
+	}
+	magnitude := math.Ceil(math.Log10(math.Abs(v)))
+	scale := math.Pow(10, float64(n.SignificantDigits)-magnitude)
+	return math.Round(v*scale) / scale
+}
+
+// Count maps c to the lower bound of its band
+func (n Normalizer) Count(c int) int {
+	if len(n.CountBands) == 0 || c < n.CountBands[0] {
+		return c
+	}
+	band := n.CountBands[0]
+	for _, b := range n.CountBands {
+		if c < b {
+			break
+		}
+		band = b
+	}
+	return band
+}
@@ -0,0 +1,67 @@
+package hashutil
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNormalizerCount(t *testing.T) {
+	tests := []struct {
+		name  string
+		bands []int
+		count int
+		want  int
+	}{
+		{name: "no bands", count: 17, want: 17},
+		{name: "below the first band", bands: []int{5, 10}, count: 3, want: 3},
+		{name: "zero", bands: DefaultCountBands, count: 0, want: 0},
+		{name: "first band", bands: DefaultCountBands, count: 1, want: 1},
+		{name: "band lower bound", bands: DefaultCountBands, count: 10, want: 10},
+		{name: "inside a band", bands: DefaultCountBands, count: 14, want: 10},
+		{name: "just below the next band", bands: DefaultCountBands, count: 19, want: 10},
+		{name: "next band", bands: DefaultCountBands, count: 20, want: 20},
+		{name: "past the last band", bands: DefaultCountBands, count: 123456, want: 10000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := Normalizer{CountBands: tt.bands}
+			if got := n.Count(tt.count); got != tt.want {
+				t.Errorf("Count(%d) = %d, want %d", tt.count, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizerValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		digits int
+		value  float64
+		want   float64
+	}{
+		{name: "exact", digits: 0, value: 0.8132, want: 0.8132},
+		{name: "two digits", digits: 2, value: 0.8132, want: 0.81},
+		{name: "two digits rounds up", digits: 2, value: 0.8172, want: 0.82},
+		{name: "large", digits: 2, value: 1234, want: 1200},
+		{name: "negative", digits: 2, value: -0.8172, want: -0.82},
+		{name: "power of ten", digits: 2, value: 100, want: 100},
+		{name: "zero", digits: 2, value: 0, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := Normalizer{SignificantDigits: tt.digits}
+			if got := n.Value(tt.value); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Value(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+
+	n := Normalizer{SignificantDigits: 2}
+	for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if got := n.Value(v); !math.IsNaN(v) && got != v || math.IsNaN(v) && !math.IsNaN(got) {
+			t.Errorf("Value(%v) = %v, want it unchanged", v, got)
+		}
+	}
+}
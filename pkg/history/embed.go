@@ -0,0 +1,115 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Embedder turns incident text into a vector for similarity search
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float64, error)
+}
+
+// HashingEmbedder is a local, network-free embedder based on token feature hashing
+type HashingEmbedder struct {
+	dims int
+}
+
+func NewHashingEmbedder(dims int) *HashingEmbedder {
+	return &HashingEmbedder{dims: dims}
+}
+
+// Embed hashes word unigrams and bigrams into a fixed-size normalized vector
+func (e *HashingEmbedder) Embed(ctx context.Context, text string) ([]float64, error) {
+	vec := make([]float64, e.dims)
+	tokens := tokenize(text)
+
+	add := func(token string) {
+		h := fnv.New32a()
+		h.Write([]byte(token))
+		vec[h.Sum32()%uint32(e.dims)]++
+	}
+
+	for i, token := range tokens {
+		add(token)
+		if i > 0 {
+			add(tokens[i-1] + " " + token)
+		}
+	}
+
+	return normalize(vec), nil
+}
+
+// OpenAIEmbedder uses the OpenAI embeddings API
+type OpenAIEmbedder struct {
+	client *openai.Client
+	model  openai.EmbeddingModel
+}
+
+func NewOpenAIEmbedder(client *openai.Client) *OpenAIEmbedder {
+	return &OpenAIEmbedder{client: client, model: openai.SmallEmbedding3}
+}
+
+func (e *OpenAIEmbedder) Embed(ctx context.Context, text string) ([]float64, error) {
+	resp, err := e.client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
+		Input: []string{text},
+		Model: e.model,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("embedding request failed: %w", err)
+	}
+	if len(resp.Data) == 0 {
+		return nil, fmt.Errorf("embedding response was empty")
+	}
+
+	vec := make([]float64, len(resp.Data[0].Embedding))
+	for i, v := range resp.Data[0].Embedding {
+		vec[i] = float64(v)
+	}
+	return normalize(vec), nil
+}
+
+// CosineSimilarity returns the cosine similarity of two vectors (0 if dimensions differ)
+func CosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func normalize(vec []float64) []float64 {
+	var norm float64
+	for _, v := range vec {
+		norm += v * v
+	}
+	if norm == 0 {
+		return vec
+	}
+	norm = math.Sqrt(norm)
+	for i := range vec {
+		vec[i] /= norm
+	}
+	return vec
+}
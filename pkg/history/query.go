@@ -0,0 +1,206 @@
+package history
+
+import (
+	"encoding/base64"
+	"errors"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"vigilant/pkg/risk"
+)
+
+// Summary condenses the snapshots of an incident across all of its services
+type Summary struct {
+	ID         string    `json:"id"`
+	Services   []string  `json:"services"`
+	Alerts     []string  `json:"alerts"`   // service/alert name of every analyzed alert
+	Severity   string    `json:"severity"` // Worst alert severity
+	Risk       string    `json:"risk"`     // Peak risk of the analyses
+	RootCause  string    `json:"root_cause,omitempty"`
+	Summary    string    `json:"summary,omitempty"`
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+	ResolvedAt time.Time `json:"resolved_at,omitempty"` // Zero while the incident is open
+	Analyses   int       `json:"analyses"`              // Snapshots recorded
+}
+
+// Filter selects incidents; empty fields match everything
+type Filter struct {
+	From       time.Time // Incidents still active at or after From
+	To         time.Time // Incidents started at or before To
+	Service    string
+	Severities []string                     // Any of these alert severities, case-insensitive
+	Risks      []string                     // Any of these peak risks, case-insensitive
+	Visible    func(services []string) bool // Incidents the caller may see, nil for all
+}
+
+// Record is an incident with the actions of its latest root cause analysis, for exports
+type Record struct {
+	Summary
+	Confidence       float64  `json:"confidence,omitempty"`
+	ImmediateActions []string `json:"immediate_actions"`
+	Investigation    []string `json:"investigation_steps"`
+	Prevention       string   `json:"prevention,omitempty"`
+}
+
+// ErrInvalidCursor is returned for a cursor not issued by Page
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+var severityOrder = map[string]int{"info": 1, "low": 1, "warning": 2, "medium": 2, "high": 3, "error": 3, "critical": 4, "page": 4}
+
+
+// Page returns up to limit incidents matching the filter, newest first, starting after cursor
+// (empty for the first page), and the cursor of the next page, empty on the last one
+func (s *Store) Page(f Filter, cursor string, limit int) ([]Summary, string, error) {
+	after, afterID, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.mu.RLock()
+	var matches []Summary
+	for id, timeline := range s.timeline {
+		summary := summarize(id, timeline)
+		if f.matches(summary) {
+			matches = append(matches, summary)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		return newer(matches[i].FirstSeen, matches[i].ID, matches[j].FirstSeen, matches[j].ID)
+	})
+	if cursor != "" {
+		start := sort.Search(len(matches), func(i int) bool {
+			return newer(after, afterID, matches[i].FirstSeen, matches[i].ID)
+		})
+		matches = matches[start:]
+	}
+	if len(matches) <= limit {
+		return matches, "", nil
+	}
+	last := matches[limit-1]
+	return matches[:limit], encodeCursor(last.FirstSeen, last.ID), nil
+}
+
+// Export returns every incident matching the filter, oldest first
+func (s *Store) Export(f Filter) []Record {
+	s.mu.RLock()
+	var records []Record
+	for id, timeline := range s.timeline {
+		summary := summarize(id, timeline)
+		if !f.matches(summary) {
+			continue
+		}
+		record := Record{Summary: summary, ImmediateActions: []string{}, Investigation: []string{}}
+		for _, snap := range timeline {
+			if snap.RootCause != "" {
+				record.Confidence, record.Prevention = snap.Confidence, snap.Prevention
+				record.ImmediateActions = append([]string{}, snap.ImmediateActions...)
+				record.Investigation = append([]string{}, snap.Investigation...)
+			}
+		}
+		records = append(records, record)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(records, func(i, j int) bool {
+		return newer(records[j].FirstSeen, records[j].ID, records[i].FirstSeen, records[i].ID)
+	})
+	return records
+}
+
+// newer orders incidents by start time, newest first, then by ID
+func newer(a time.Time, aID string, b time.Time, bID string) bool {
+	if !a.Equal(b) {
+		return a.After(b)
+	}
+	return aID > bID
+}
+
+func (f Filter) matches(s Summary) bool {
+	end := s.LastSeen
+	if !s.ResolvedAt.IsZero() {
+		end = s.ResolvedAt
+	}
+	if !f.From.IsZero() && end.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && s.FirstSeen.After(f.To) {
+		return false
+	}
+	if f.Service != "" && !slices.Contains(s.Services, f.Service) {
+		return false
+	}
+	if len(f.Severities) > 0 && !containsFold(f.Severities, s.Severity) {
+		return false
+	}
+	if len(f.Risks) > 0 && !containsFold(f.Risks, s.Risk) {
+		return false
+	}
+	return f.Visible == nil || f.Visible(s.Services)
+}
+
+func containsFold(values []string, v string) bool {
+	return slices.ContainsFunc(values, func(candidate string) bool { return strings.EqualFold(candidate, v) })
+}
+
+// summarize condenses the timeline of an incident, which is resolved once its last snapshot is
+func summarize(id string, timeline []Incident) Summary {
+	summary := Summary{ID: id, Analyses: len(timeline)}
+	for _, snap := range timeline {
+		if !slices.Contains(summary.Services, snap.Service) {
+			summary.Services = append(summary.Services, snap.Service)
+		}
+		if alert := snap.Service + "/" + snap.AlertName; !slices.Contains(summary.Alerts, alert) {
+			summary.Alerts = append(summary.Alerts, alert)
+		}
+		if severityOrder[strings.ToLower(snap.Severity)] > severityOrder[strings.ToLower(summary.Severity)] || summary.Severity == "" {
+			summary.Severity = snap.Severity
+		}
+		if risk.LevelRank(snap.Risk) > risk.LevelRank(summary.Risk) || summary.Risk == "" {
+			summary.Risk = snap.Risk
+		}
+		if snap.RootCause != "" {
+			summary.RootCause, summary.Summary = snap.RootCause, snap.Summary
+		}
+		if summary.FirstSeen.IsZero() || (!snap.FirstSeen.IsZero() && snap.FirstSeen.Before(summary.FirstSeen)) {
+			summary.FirstSeen = snap.FirstSeen
+		}
+		seen := snap.LastSeen
+		if seen.IsZero() {
+			seen = snap.RecordedAt
+		}
+		if seen.After(summary.LastSeen) {
+			summary.LastSeen = seen
+		}
+	}
+	if len(timeline) > 0 {
+		summary.ResolvedAt = timeline[len(timeline)-1].ResolvedAt
+	}
+	return summary
+}
+
+// encodeCursor makes an opaque cursor pointing after an incident
+func encodeCursor(firstSeen time.Time, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(firstSeen.UnixNano(), 10) + "|" + id))
+}
+
+func decodeCursor(cursor string) (time.Time, string, error) {
+	if cursor == "" {
+		return time.Time{}, "", nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	nanos, id, ok := strings.Cut(string(data), "|")
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if !ok || err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	return time.Unix(0, n), id, nil
+}
@@ -0,0 +1,337 @@
+package history
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Incident is a snapshot of an analyzed alert of an incident (see risk.Incidents). Every
+// analysis appends a new snapshot with the incident's ID, so the sequence of snapshots forms
+// the incident timeline.
+type Incident struct {
+	ID               string             `json:"id"`
+	Service          string             `json:"service"`
+	AlertName        string             `json:"alert_name"`
+	Severity         string             `json:"severity"`
+	Symptoms         []string           `json:"symptoms,omitempty"`
+	SymptomCounts    map[string]int     `json:"symptom_counts,omitempty"`
+	Metrics          []string           `json:"metrics,omitempty"`
+	MetricValues     map[string]float64 `json:"metric_values,omitempty"`
+	Risk             string             `json:"risk"`
+	Confidence       float64            `json:"confidence,omitempty"`
+	RootCause        string             `json:"root_cause"`
+	Summary          string             `json:"summary,omitempty"`
+	ImmediateActions []string           `json:"immediate_actions,omitempty"`
+	Investigation    []string           `json:"investigation_steps,omitempty"`
+	Prevention       string             `json:"prevention,omitempty"`
+	FirstSeen        time.Time          `json:"first_seen"`
+	LastSeen         time.Time          `json:"last_seen,omitempty"`
+	ResolvedAt       time.Time          `json:"resolved_at,omitempty"`
+	RecordedAt       time.Time          `json:"recorded_at"`
+	Embedding        []float64          `json:"embedding,omitempty"`
+}
+
+// Resolved reports whether the incident's alert has stopped firing
+func (i Incident) Resolved() bool {
+	return !i.ResolvedAt.IsZero()
+}
+
+// Match is a past incident together with its similarity to the query
+type Match struct {
+	Incident Incident
+	Score    float64
+}
+
+// Store keeps incidents in memory and appends every change to a JSONL file. The file is
+// compacted at startup and whenever it doubled in size since: incidents that ended longer than
+// the retention ago are dropped, and only the latest snapshot of an incident keeps its embedding.
+type Store struct {
+	path      string
+	embedder  Embedder
+	retention time.Duration         // Zero keeps incidents forever
+	incidents map[string]*Incident  // Latest snapshot per incident, with its embedding
+	timeline  map[string][]Incident // All snapshots per incident without embeddings, oldest first
+	lines     int                   // Lines in the file
+	compacted int                   // Lines in the file after the last compaction
+	mu        sync.RWMutex
+}
+
+// NewStore loads previously recorded incidents from path (created if missing), keeping them for
+// retention after they ended
+func NewStore(path string, embedder Embedder, retention time.Duration) (*Store, error) {
+	if embedder == nil {
+		embedder = NewHashingEmbedder(256)
+	}
+
+	s := &Store{
+		path:      path,
+		embedder:  embedder,
+		retention: retention,
+		incidents: make(map[string]*Incident),
+		timeline:  make(map[string][]Incident),
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var inc Incident
+		if err := json.Unmarshal(scanner.Bytes(), &inc); err != nil {
+			fmt.Printf("[HISTORY] Skipping malformed record: %v\n", err)
+			continue
+		}
+		// Later lines are updates of the same incident, last write wins
+		s.incidents[inc.ID] = &inc
+		s.timeline[inc.ID] = append(s.timeline[inc.ID], withoutEmbedding(inc))
+		s.lines++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+	file.Close()
+
+	if err := s.compactLocked(time.Now()); err != nil {
+		return nil, err
+	}
+	fmt.Printf("[HISTORY] Loaded %d past incidents from %s\n", len(s.incidents), path)
+	return s, nil
+}
+
+// Record embeds the incident and persists it as the latest snapshot of its timeline
+func (s *Store) Record(ctx context.Context, inc Incident) error {
+	if inc.RecordedAt.IsZero() {
+		inc.RecordedAt = time.Now()
+	}
+
+	embedding, err := s.embedder.Embed(ctx, IncidentText(inc))
+	if err != nil {
+		return fmt.Errorf("failed to embed incident %s: %w", inc.ID, err)
+	}
+	inc.Embedding = embedding
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.appendLocked(inc)
+}
+
+// Resolve marks an open incident as resolved at the given time
+func (s *Store) Resolve(id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	latest, ok := s.incidents[id]
+	if !ok {
+		return fmt.Errorf("unknown incident %s", id)
+	}
+	if latest.Resolved() {
+		return nil
+	}
+
+	resolved := *latest
+	resolved.ResolvedAt = at
+	resolved.RecordedAt = at
+	return s.appendLocked(resolved)
+}
+
+// appendLocked writes a snapshot to disk and memory. Caller must hold s.mu.
+func (s *Store) appendLocked(inc Incident) error {
+	line, err := json.Marshal(inc)
+	if err != nil {
+		return fmt.Errorf("failed to encode incident %s: %w", inc.ID, err)
+	}
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write incident %s: %w", inc.ID, err)
+	}
+
+	s.incidents[inc.ID] = &inc
+	s.timeline[inc.ID] = append(s.timeline[inc.ID], withoutEmbedding(inc))
+	s.lines++
+
+	if s.lines > 2*s.compacted {
+		return s.compactLocked(time.Now())
+	}
+	return nil
+}
+
+// compactLocked drops the incidents past retention and rewrites the file with the remaining
+// snapshots, the embedding on the latest one of each incident only. Caller must hold s.mu.
+func (s *Store) compactLocked(now time.Time) error {
+	if s.retention > 0 {
+		cutoff := now.Add(-s.retention)
+		for id, inc := range s.incidents {
+			if incidentEnd(*inc).Before(cutoff) {
+				delete(s.incidents, id)
+				delete(s.timeline, id)
+			}
+		}
+	}
+
+	tmp := s.path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to compact history: %w", err)
+	}
+	writer := bufio.NewWriter(file)
+	lines := 0
+	for id, timeline := range s.timeline {
+		for i, snap := range timeline {
+			if i == len(timeline)-1 {
+				snap = *s.incidents[id]
+			}
+			line, _ := json.Marshal(snap)
+			writer.Write(append(line, '\n'))
+			lines++
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to compact history: %w", err)
+	}
+	file.Close()
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to compact history: %w", err)
+	}
+	s.lines, s.compacted = lines, lines
+	return nil
+}
+
+// incidentEnd is when an incident was resolved, else when it was last recorded
+func incidentEnd(inc Incident) time.Time {
+	if inc.Resolved() {
+		return inc.ResolvedAt
+	}
+	return inc.RecordedAt
+}
+
+// Similar returns up to k past incidents most similar to text, excluding the given IDs
+func (s *Store) Similar(ctx context.Context, text string, k int, minScore float64, exclude map[string]bool) ([]Match, error) {
+	if k <= 0 {
+		return nil, nil
+	}
+
+	query, err := s.embedder.Embed(ctx, text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	s.mu.RLock()
+	var matches []Match
+	for id, inc := range s.incidents {
+		if exclude[id] || len(inc.Embedding) == 0 {
+			continue
+		}
+		score := CosineSimilarity(query, inc.Embedding)
+		if score < minScore {
+			continue
+		}
+		matches = append(matches, Match{Incident: *inc, Score: score})
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches, nil
+}
+
+// Get returns the incident with the given ID
+func (s *Store) Get(id string) (Incident, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	inc, ok := s.incidents[id]
+	if !ok {
+		return Incident{}, false
+	}
+	return *inc, true
+}
+
+// Timeline returns all recorded snapshots of an incident, oldest first
+func (s *Store) Timeline(id string) []Incident {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Incident(nil), s.timeline[id]...)
+}
+
+// OpenIncidentIDs returns the IDs of incidents that have not been resolved
+func (s *Store) OpenIncidentIDs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var ids []string
+	for id, inc := range s.incidents {
+		if !inc.Resolved() {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Between returns the latest snapshot of every incident active during [from, to], oldest first
+func (s *Store) Between(from, to time.Time) []Incident {
+	s.mu.RLock()
+	var incidents []Incident
+	for _, inc := range s.incidents {
+		if inc.FirstSeen.After(to) || incidentEnd(*inc).Before(from) {
+			continue
+		}
+		incidents = append(incidents, withoutEmbedding(*inc))
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(incidents, func(i, j int) bool {
+		return incidents[i].FirstSeen.Before(incidents[j].FirstSeen)
+	})
+	return incidents
+}
+
+func withoutEmbedding(inc Incident) Incident {
+	inc.Embedding = nil
+	return inc
+}
+
+// IncidentText builds the text representation used for embedding an incident
+func IncidentText(inc Incident) string {
+	return QueryText(inc.Service, inc.AlertName, inc.Severity, inc.Symptoms, inc.Metrics) + " " + inc.RootCause
+}
+
+// QueryText builds the text representation of a live correlation for similarity search
+func QueryText(service, alertName, severity string, symptoms, metrics []string) string {
+	text := fmt.Sprintf("service %s alert %s severity %s", service, alertName, severity)
+	for _, s := range symptoms {
+		text += " symptom " + s
+	}
+	for _, m := range metrics {
+		text += " metric " + m
+	}
+	return text
+}
@@ -0,0 +1,213 @@
+package history
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestStore(t *testing.T, retention time.Duration, incidents ...Incident) *Store {
+	t.Helper()
+	store, err := NewStore(filepath.Join(t.TempDir(), "history.jsonl"), nil, retention)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, inc := range incidents {
+		if err := store.Record(context.Background(), inc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+// readSnapshots returns the snapshots in the store's file
+func readSnapshots(t *testing.T, path string) []Incident {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var snapshots []Incident
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var inc Incident
+		if err := json.Unmarshal(scanner.Bytes(), &inc); err != nil {
+			t.Fatal(err)
+		}
+		snapshots = append(snapshots, inc)
+	}
+	return snapshots
+}
+
+func TestStoreCompaction(t *testing.T) {
+	store := newTestStore(t, 0)
+	for i, risk := range []string{"LOW", "MEDIUM", "HIGH"} {
+		inc := Incident{ID: "inc-1", Service: "api", AlertName: "HighCPU", Risk: risk, FirstSeen: testNow, RecordedAt: testNow.Add(time.Duration(i) * time.Minute)}
+		if err := store.Record(context.Background(), inc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshots := readSnapshots(t, store.path)
+	if len(snapshots) != 3 {
+		t.Fatalf("file has %d snapshots, want 3", len(snapshots))
+	}
+	for i, snap := range snapshots {
+		if latest := i == len(snapshots)-1; (len(snap.Embedding) > 0) != latest {
+			t.Errorf("snapshot %d (%s) has embedding = %v, want %v", i, snap.Risk, len(snap.Embedding) > 0, latest)
+		}
+	}
+
+	reopened, err := NewStore(store.path, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeline := reopened.Timeline("inc-1")
+	if len(timeline) != 3 {
+		t.Fatalf("Timeline() has %d snapshots after reopening, want 3", len(timeline))
+	}
+	for i, want := range []string{"LOW", "MEDIUM", "HIGH"} {
+		if timeline[i].Risk != want || len(timeline[i].Embedding) > 0 {
+			t.Errorf("Timeline()[%d] = %s with %d embedding values, want %s without", i, timeline[i].Risk, len(timeline[i].Embedding), want)
+		}
+	}
+	if latest, ok := reopened.Get("inc-1"); !ok || latest.Risk != "HIGH" || len(latest.Embedding) == 0 {
+		t.Errorf("Get() = %s, %v with %d embedding values, want the embedded HIGH snapshot", latest.Risk, ok, len(latest.Embedding))
+	}
+}
+
+func TestStoreRetention(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		incident Incident
+		wantKept bool
+	}{
+		{name: "recent open", incident: Incident{RecordedAt: now.Add(-time.Hour)}, wantKept: true},
+		{name: "old open", incident: Incident{RecordedAt: now.Add(-72 * time.Hour)}},
+		{name: "recently resolved", incident: Incident{RecordedAt: now.Add(-72 * time.Hour), ResolvedAt: now.Add(-time.Hour)}, wantKept: true},
+		{name: "resolved long ago", incident: Incident{RecordedAt: now.Add(-72 * time.Hour), ResolvedAt: now.Add(-48 * time.Hour)}},
+		{name: "recorded after an old resolution", incident: Incident{RecordedAt: now.Add(-time.Hour), ResolvedAt: now.Add(-48 * time.Hour)}},
+	}
+
+	var incidents []Incident
+	for _, tt := range tests {
+		inc := tt.incident
+		inc.ID = tt.name
+		incidents = append(incidents, inc)
+	}
+	// Records without retention, so that only reopening drops incidents
+	path := newTestStore(t, 0, incidents...).path
+
+	store, err := NewStore(path, nil, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshots := readSnapshots(t, path)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, kept := store.Get(tt.name); kept != tt.wantKept {
+				t.Errorf("Get() found = %v, want %v", kept, tt.wantKept)
+			}
+			inFile := false
+			for _, snap := range snapshots {
+				inFile = inFile || snap.ID == tt.name
+			}
+			if inFile != tt.wantKept {
+				t.Errorf("file has incident = %v, want %v", inFile, tt.wantKept)
+			}
+		})
+	}
+}
+
+func TestStoreBetween(t *testing.T) {
+	hour := func(h int) time.Time { return testNow.Add(time.Duration(h) * time.Hour) }
+	store := newTestStore(t, 0,
+		Incident{ID: "open", FirstSeen: hour(-5), RecordedAt: hour(-1)},
+		Incident{ID: "resolved", FirstSeen: hour(-10), RecordedAt: hour(-9), ResolvedAt: hour(-8)},
+		Incident{ID: "later", FirstSeen: hour(2), RecordedAt: hour(3)},
+	)
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     []string
+	}{
+		{name: "everything", from: hour(-24), to: hour(24), want: []string{"resolved", "open", "later"}},
+		{name: "before the first", from: hour(-24), to: hour(-11)},
+		{name: "while resolved was firing", from: hour(-9), to: hour(-8), want: []string{"resolved"}},
+		{name: "window bounds inclusive", from: hour(-8), to: hour(-5), want: []string{"resolved", "open"}},
+		{name: "after the last recording of open", from: hour(0), to: hour(1)},
+		{name: "ends when later starts", from: hour(0), to: hour(2), want: []string{"later"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, inc := range store.Between(tt.from, tt.to) {
+				if len(inc.Embedding) > 0 {
+					t.Errorf("Between() returned %s with its embedding", inc.ID)
+				}
+				got = append(got, inc.ID)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Between() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStoreResolve(t *testing.T) {
+	store := newTestStore(t, 0, Incident{ID: "inc-1", Service: "api", FirstSeen: testNow, RecordedAt: testNow})
+	resolvedAt := testNow.Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		id      string
+		at      time.Time
+		wantErr string
+	}{
+		{name: "unknown incident", id: "inc-2", at: resolvedAt, wantErr: "unknown incident inc-2"},
+		{name: "open incident", id: "inc-1", at: resolvedAt},
+		{name: "already resolved", id: "inc-1", at: resolvedAt.Add(time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := store.Resolve(tt.id, tt.at)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Resolve() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+		})
+	}
+
+	inc, _ := store.Get("inc-1")
+	if !inc.ResolvedAt.Equal(resolvedAt) || !inc.RecordedAt.Equal(resolvedAt) {
+		t.Errorf("resolved incident has ResolvedAt %v and RecordedAt %v, want both %v", inc.ResolvedAt, inc.RecordedAt, resolvedAt)
+	}
+	if len(inc.Embedding) == 0 {
+		t.Error("resolved incident lost its embedding")
+	}
+	if timeline := store.Timeline("inc-1"); len(timeline) != 2 {
+		t.Errorf("Timeline() has %d snapshots, want the recorded and the resolved one", len(timeline))
+	}
+	if ids := store.OpenIncidentIDs(); len(ids) != 0 {
+		t.Errorf("OpenIncidentIDs() = %v, want none", ids)
+	}
+}
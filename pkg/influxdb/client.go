@@ -0,0 +1,199 @@
+package influxdb
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"vigilant/pkg/prometheus"
+)
+
+// currentWindow is the time range of a query for the current value; the last point of every
+// table in it counts
+const currentWindow = 5 * time.Minute
+
+// Client runs Flux metric check queries against the InfluxDB 2.x query API. It implements
+// prometheus.MetricSource.
+//
+// Every query is prefixed with an option v = {timeRangeStart, timeRangeStop, windowPeriod},
+// like the InfluxDB UI, so queries can follow the evaluated range:
+//
+//	from(bucket: "telegraf")
+//	  |> range(start: v.timeRangeStart, stop: v.timeRangeStop)
+//	  |> filter(fn: (r) => r._measurement == "http" and r.service == "{{.Service}}")
+//	  |> aggregateWindow(every: v.windowPeriod, fn: mean)
+type Client struct {
+	url        string
+	token      string
+	org        string
+	httpClient *http.Client
+}
+
+// NewClient returns a client for the InfluxDB at baseURL, authenticating with an API token
+func NewClient(baseURL, token, org string) (*Client, error) {
+	if baseURL == "" || token == "" {
+		return nil, fmt.Errorf("influxdb needs a URL and a token")
+	}
+	return &Client{
+		url:        strings.TrimRight(baseURL, "/"),
+		token:      token,
+		org:        org,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Name identifies the client by its URL
+func (c *Client) Name() string {
+	return "influxdb:" + c.url
+}
+
+// Query returns the last value of every table over the last few minutes
+func (c *Client) Query(query string) ([]prometheus.Series, error) {
+	now := time.Now()
+	series, err := c.QueryRange(query, now.Add(-currentWindow), now, time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	for i := range series {
+		series[i].Values = series[i].Values[len(series[i].Values)-1:]
+	}
+	return series, nil
+}
+
+// QueryRange runs the query with v.timeRangeStart, v.timeRangeStop and v.windowPeriod set to
+// start, end and step, returning one series per result table
+func (c *Client) QueryRange(query string, start, end time.Time, step time.Duration) ([]prometheus.Series, error) {
+	if step <= 0 {
+		step = time.Minute
+	}
+	flux := fmt.Sprintf("option v = {timeRangeStart: %s, timeRangeStop: %s, windowPeriod: %s}\n%s",
+		start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339), step, query)
+
+	body, err := json.Marshal(map[string]interface{}{
+		"query":   flux,
+		"type":    "flux",
+		"dialect": map[string]interface{}{"header": true, "annotations": []string{}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	if c.org != "" {
+		params.Set("org", c.org)
+	}
+	req, err := http.NewRequest(http.MethodPost, c.url+"/api/v2/query?"+params.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Token "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/csv")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("influxdb query failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read influxdb response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return nil, fmt.Errorf("influxdb query failed (HTTP %d): %s", resp.StatusCode, apiErr.Message)
+	}
+	return parseCSV(data)
+}
+
+// ignoredColumns are the columns of a Flux result that don't identify a series
+var ignoredColumns = map[string]bool{"": true, "result": true, "table": true, "_start": true, "_stop": true, "_time": true, "_value": true}
+
+// parseCSV reads a Flux CSV response: blocks separated by blank lines, each starting with its
+// header, with one series per table number
+func parseCSV(data []byte) ([]prometheus.Series, error) {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+
+	var order []string
+	tables := make(map[string]*prometheus.Series)
+	for _, block := range bytes.Split(data, []byte("\n\n")) {
+		reader := csv.NewReader(bytes.NewReader(block))
+		reader.FieldsPerRecord = -1
+		rows, err := reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse influxdb response: %w", err)
+		}
+		if len(rows) < 2 {
+			continue
+		}
+
+		header := make(map[string]int, len(rows[0]))
+		for i, name := range rows[0] {
+			header[name] = i
+		}
+		// Errors during execution come back as a table with error and reference columns
+		if i, ok := header["error"]; ok && i < len(rows[1]) {
+			return nil, fmt.Errorf("influxdb query failed: %s", rows[1][i])
+		}
+		valueCol, ok := header["_value"]
+		if !ok {
+			continue
+		}
+
+		for _, row := range rows[1:] {
+			if valueCol >= len(row) {
+				continue
+			}
+			value, err := strconv.ParseFloat(row[valueCol], 64)
+			if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+				continue
+			}
+			table := ""
+			if i, ok := header["table"]; ok && i < len(row) {
+				table = row[i]
+			}
+			// Table numbers restart in every result of a multi-yield query
+			if i, ok := header["result"]; ok && i < len(row) {
+				table = row[i] + "/" + table
+			}
+			s, ok := tables[table]
+			if !ok {
+				s = &prometheus.Series{Labels: rowLabels(rows[0], row)}
+				tables[table] = s
+				order = append(order, table)
+			}
+			s.Values = append(s.Values, value)
+		}
+	}
+
+	series := make([]prometheus.Series, 0, len(order))
+	for _, table := range order {
+		series = append(series, *tables[table])
+	}
+	return series, nil
+}
+
+// rowLabels are a row's group key columns, such as _measurement, _field and tags
+func rowLabels(header, row []string) map[string]string {
+	labels := make(map[string]string)
+	for i, name := range header {
+		if !ignoredColumns[name] && i < len(row) && row[i] != "" {
+			labels[name] = row[i]
+		}
+	}
+	return labels
+}
@@ -0,0 +1,167 @@
+package inject
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"vigilant/pkg/prometheus"
+)
+
+// Label marks the alerts injected for testing
+const Label = "vigilant_injected"
+
+// Symptom is a log pattern reported as matched Count times
+type Symptom struct {
+	Pattern  string `json:"pattern"`
+	Count    int    `json:"count"`
+	Severity string `json:"severity,omitempty"` // Default: the profile's severity for the pattern
+}
+
+// Metric is a value for one of the service profile's metric checks, compared with its threshold
+type Metric struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+}
+
+// Injection is the synthetic data of a service, used by the monitoring loop like fetched data
+// until it expires
+type Injection struct {
+	Service  string    `json:"service"`
+	Alerts   []string  `json:"alerts"`
+	Symptoms []Symptom `json:"symptoms"`
+	Metrics  []Metric  `json:"metrics"`
+	Expires  time.Time `json:"expires"`
+}
+
+// Store holds the injected data per service
+type Store struct {
+	mu       sync.Mutex
+	services map[string]*Injection
+}
+
+func NewStore() *Store {
+	return &Store{services: make(map[string]*Injection)}
+}
+
+// Add merges inj into the service's injected data: symptoms with the same pattern and metrics
+// with the same name are replaced, and the expiry is extended
+func (s *Store) Add(inj Injection) Injection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropExpiredLocked(time.Now())
+
+	current, ok := s.services[inj.Service]
+	if !ok {
+		current = &Injection{Service: inj.Service, Alerts: []string{}, Symptoms: []Symptom{}, Metrics: []Metric{}}
+		s.services[inj.Service] = current
+	}
+	for _, alert := range inj.Alerts {
+		if !slices.Contains(current.Alerts, alert) {
+			current.Alerts = append(current.Alerts, alert)
+		}
+	}
+	for _, sym := range inj.Symptoms {
+		current.Symptoms = replaceWhere(current.Symptoms, sym, func(o Symptom) bool { return o.Pattern == sym.Pattern })
+	}
+	for _, m := range inj.Metrics {
+		current.Metrics = replaceWhere(current.Metrics, m, func(o Metric) bool { return o.Name == m.Name })
+	}
+	if inj.Expires.After(current.Expires) {
+		current.Expires = inj.Expires
+	}
+	return copyInjection(current)
+}
+
+// Get returns the unexpired data injected for a service
+func (s *Store) Get(service string) (Injection, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropExpiredLocked(time.Now())
+	inj, ok := s.services[service]
+	if !ok {
+		return Injection{}, false
+	}
+	return copyInjection(inj), true
+}
+
+// List returns the unexpired injections by service
+func (s *Store) List() []Injection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropExpiredLocked(time.Now())
+	list := make([]Injection, 0, len(s.services))
+	for _, inj := range s.services {
+		list = append(list, copyInjection(inj))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Service < list[j].Service })
+	return list
+}
+
+// Clear removes the data injected for a service, or for every service when service is empty,
+// and returns what was removed
+func (s *Store) Clear(service string) []Injection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var removed []Injection
+	for name, inj := range s.services {
+		if service == "" || name == service {
+			removed = append(removed, copyInjection(inj))
+			delete(s.services, name)
+		}
+	}
+	return removed
+}
+
+func (s *Store) dropExpiredLocked(now time.Time) {
+	for name, inj := range s.services {
+		if now.After(inj.Expires) {
+			delete(s.services, name)
+		}
+	}
+}
+
+// Alert builds the alert injected for a service; its fingerprint keeps it apart from real alerts
+func Alert(service, name, severity string, labels map[string]string, expires time.Time) prometheus.Alert {
+	merged := map[string]string{"alertname": name, "severity": severity, Label: "true"}
+	for k, v := range labels {
+		if _, reserved := merged[k]; !reserved {
+			merged[k] = v
+		}
+	}
+	return prometheus.Alert{
+		Fingerprint: Fingerprint(service, name),
+		Name:        name,
+		Instance:    "injected",
+		Severity:    severity,
+		Service:     service,
+		State:       "firing",
+		StartsAt:    time.Now(),
+		EndsAt:      expires,
+		Labels:      merged,
+	}
+}
+
+// Fingerprint identifies the injected alert name of a service in the risk tracker
+func Fingerprint(service, name string) string {
+	return "injected:" + service + "/" + name
+}
+
+func copyInjection(inj *Injection) Injection {
+	c := *inj
+	c.Alerts = append([]string{}, inj.Alerts...)
+	c.Symptoms = append([]Symptom{}, inj.Symptoms...)
+	c.Metrics = append([]Metric{}, inj.Metrics...)
+	return c
+}
+
+func replaceWhere[T any](values []T, v T, same func(T) bool) []T {
+	for i := range values {
+		if same(values[i]) {
+			values[i] = v
+			return values
+		}
+	}
+	return append(values, v)
+}
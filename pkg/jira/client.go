@@ -0,0 +1,148 @@
+package jira
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Client creates, comments on and transitions issues through the Jira REST API v2, which
+// takes wiki markup and is served by both Jira Cloud and Jira Server/Data Center
+type Client struct {
+	BaseURL   string // e.g. https://example.atlassian.net
+	User      string // Account email for Jira Cloud API tokens; empty sends Token as a bearer token
+	Token     string
+	Project   string // Project key, e.g. OPS
+	IssueType string // e.g. Bug, Incident
+	http      *http.Client
+}
+
+// FromEnv builds a client from JIRA_URL, JIRA_USER, JIRA_API_TOKEN, JIRA_PROJECT and
+// JIRA_ISSUE_TYPE; nil unless the URL and project are set
+func FromEnv() *Client {
+	baseURL := strings.TrimRight(os.Getenv("JIRA_URL"), "/")
+	project := os.Getenv("JIRA_PROJECT")
+	if baseURL == "" || project == "" {
+		return nil
+	}
+	issueType := os.Getenv("JIRA_ISSUE_TYPE")
+	if issueType == "" {
+		issueType = "Bug"
+	}
+	return &Client{
+		BaseURL:   baseURL,
+		User:      os.Getenv("JIRA_USER"),
+		Token:     os.Getenv("JIRA_API_TOKEN"),
+		Project:   project,
+		IssueType: issueType,
+		http:      &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// IssueURL is where people open an issue
+func (c *Client) IssueURL(key string) string {
+	return c.BaseURL + "/browse/" + key
+}
+
+// CreateIssue opens an issue in the project and returns its key
+func (c *Client) CreateIssue(ctx context.Context, summary, description string, labels []string) (string, error) {
+	payload := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"project":     map[string]string{"key": c.Project},
+			"issuetype":   map[string]string{"name": c.IssueType},
+			"summary":     summary,
+			"description": description,
+			"labels":      labels,
+		},
+	}
+	var created struct {
+		Key string `json:"key"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/rest/api/2/issue", payload, &created); err != nil {
+		return "", fmt.Errorf("failed to create issue: %w", err)
+	}
+	return created.Key, nil
+}
+
+// Comment adds a comment to an issue
+func (c *Client) Comment(ctx context.Context, key, body string) error {
+	if err := c.do(ctx, http.MethodPost, "/rest/api/2/issue/"+key+"/comment", map[string]string{"body": body}, nil); err != nil {
+		return fmt.Errorf("failed to comment on %s: %w", key, err)
+	}
+	return nil
+}
+
+// Transition moves an issue through the workflow transition with the given name, e.g. "Done"
+func (c *Client) Transition(ctx context.Context, key, name string) error {
+	var available struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"transitions"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/rest/api/2/issue/"+key+"/transitions", nil, &available); err != nil {
+		return fmt.Errorf("failed to list transitions of %s: %w", key, err)
+	}
+	for _, t := range available.Transitions {
+		if strings.EqualFold(t.Name, name) {
+			payload := map[string]interface{}{"transition": map[string]string{"id": t.ID}}
+			if err := c.do(ctx, http.MethodPost, "/rest/api/2/issue/"+key+"/transitions", payload, nil); err != nil {
+				return fmt.Errorf("failed to transition %s to %s: %w", key, name, err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%s has no transition %q in its current status", key, name)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, payload, result interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.User != "" {
+		req.SetBasicAuth(c.User, c.Token)
+	} else if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	client := c.http
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("bad response: %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package jira
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	headingPattern   = regexp.MustCompile(`^(#{1,6}) +(.*)$`)
+	separatorPattern = regexp.MustCompile(`^\|[\s:|-]+\|$`)
+	listPattern      = regexp.MustCompile(`^( *)- (\[[ xX]\] )?(.*)$`)
+	codePattern      = regexp.MustCompile("`([^`]+)`")
+	boldPattern      = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	linkPattern      = regexp.MustCompile(`\[([^\]]+)\]\(([^)]+)\)`)
+)
+
+// Markup converts the Markdown of Vigilant's reports to Jira wiki markup: headings, tables,
+// lists and checklists, bold text, inline code and links
+func Markup(markdown string) string {
+	lines := strings.Split(markdown, "\n")
+	out := make([]string, 0, len(lines))
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case separatorPattern.MatchString(trimmed):
+			continue
+		case strings.HasPrefix(trimmed, "|") && i+1 < len(lines) && separatorPattern.MatchString(strings.TrimSpace(lines[i+1])):
+			out = append(out, headerRow(inline(trimmed)))
+		case headingPattern.MatchString(line):
+			m := headingPattern.FindStringSubmatch(line)
+			out = append(out, "h"+string(rune('0'+len(m[1])))+". "+inline(m[2]))
+		case listPattern.MatchString(line):
+			m := listPattern.FindStringSubmatch(line)
+			out = append(out, strings.Repeat("*", len(m[1])/2+1)+" "+inline(m[3]))
+		default:
+			out = append(out, inline(line))
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// headerRow turns a table row into a header row, keeping escaped pipes inside cells
+func headerRow(row string) string {
+	cells := splitCells(strings.TrimSuffix(strings.TrimPrefix(row, "|"), "|"))
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return "||" + strings.Join(cells, "||") + "||"
+}
+
+func splitCells(row string) []string {
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(row); i++ {
+		if row[i] == '\\' && i+1 < len(row) && row[i+1] == '|' {
+			cell.WriteString(`\|`)
+			i++
+			continue
+		}
+		if row[i] == '|' {
+			cells = append(cells, cell.String())
+			cell.Reset()
+			continue
+		}
+		cell.WriteByte(row[i])
+	}
+	return append(cells, cell.String())
+}
+
+func inline(s string) string {
+	s = codePattern.ReplaceAllString(s, "{{$1}}")
+	s = boldPattern.ReplaceAllString(s, "*$1*")
+	return linkPattern.ReplaceAllString(s, "[$1|$2]")
+}
@@ -0,0 +1,209 @@
+package jira
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Ticket is the Jira issue opened for an incident
+type Ticket struct {
+	IncidentID string    `json:"incident_id"`
+	Key        string    `json:"key"`
+	URL        string    `json:"url"`
+	Service    string    `json:"service"`
+	Risk       string    `json:"risk"` // Risk of the incident when the ticket was last updated
+	By         string    `json:"by"`   // User who asked for it, or "vigilant" when opened automatically
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	ResolvedAt time.Time `json:"resolved_at,omitempty"` // Zero while the incident is open
+}
+
+// Resolved reports whether the ticket's incident was resolved
+func (t Ticket) Resolved() bool {
+	return !t.ResolvedAt.IsZero()
+}
+
+// Request describes the incident a ticket is opened or updated for
+type Request struct {
+	IncidentID string
+	Service    string
+	Services   []string
+	Alert      string
+	Risk       string
+	Report     string // Markdown report of the incident, used as description or comment
+	By         string
+}
+
+// Ticketer keeps one ticket per incident. Tickets are appended to a JSONL file, so an incident
+// keeps its ticket across restarts.
+type Ticketer struct {
+	client            *Client
+	path              string
+	resolveTransition string // Workflow transition for resolved incidents; empty only comments
+	tickets           map[string]Ticket
+	mu                sync.Mutex // Held across Jira calls, so an incident never gets two tickets
+}
+
+// NewTicketer loads the tickets recorded at path (created if missing)
+func NewTicketer(client *Client, path, resolveTransition string) (*Ticketer, error) {
+	t := &Ticketer{client: client, path: path, resolveTransition: resolveTransition, tickets: make(map[string]Ticket)}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create ticket directory: %w", err)
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ticket file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var ticket Ticket
+		if err := json.Unmarshal(scanner.Bytes(), &ticket); err != nil {
+			fmt.Printf("[JIRA] Skipping malformed record: %v\n", err)
+			continue
+		}
+		t.tickets[ticket.IncidentID] = ticket // Later records update earlier ones
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ticket file: %w", err)
+	}
+	fmt.Printf("[JIRA] Loaded %d tickets from %s\n", len(t.tickets), path)
+	return t, nil
+}
+
+// Get returns the ticket of an incident
+func (t *Ticketer) Get(incidentID string) (Ticket, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ticket, ok := t.tickets[incidentID]
+	return ticket, ok
+}
+
+// Unresolved returns the tickets of incidents not resolved yet
+func (t *Ticketer) Unresolved() []Ticket {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var open []Ticket
+	for _, ticket := range t.tickets {
+		if !ticket.Resolved() {
+			open = append(open, ticket)
+		}
+	}
+	sort.Slice(open, func(i, j int) bool { return open[i].CreatedAt.Before(open[j].CreatedAt) })
+	return open
+}
+
+// Open creates the incident's ticket with the report as description, or adds the report as a
+// comment to the ticket it already has; created reports which
+func (t *Ticketer) Open(ctx context.Context, req Request) (ticket Ticket, created bool, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	if existing, ok := t.tickets[req.IncidentID]; ok {
+		body := fmt.Sprintf("Update requested by %s, risk is %s:\n\n%s", req.By, req.Risk, Markup(req.Report))
+		if err := t.client.Comment(ctx, existing.Key, body); err != nil {
+			return existing, false, err
+		}
+		existing.Risk, existing.UpdatedAt = req.Risk, now
+		return existing, false, t.saveLocked(existing)
+	}
+
+	summary := fmt.Sprintf("%s risk: %s on %s (%s)", req.Risk, req.Alert, req.Service, req.IncidentID)
+	description := Markup(req.Report) + fmt.Sprintf("\n\n----\nOpened from Vigilant incident %s by %s.", req.IncidentID, req.By)
+	labels := []string{"vigilant"}
+	for _, service := range req.Services {
+		labels = append(labels, strings.ReplaceAll(service, " ", "_"))
+	}
+	key, err := t.client.CreateIssue(ctx, summary, description, labels)
+	if err != nil {
+		return Ticket{}, false, err
+	}
+	ticket = Ticket{
+		IncidentID: req.IncidentID,
+		Key:        key,
+		URL:        t.client.IssueURL(key),
+		Service:    req.Service,
+		Risk:       req.Risk,
+		By:         req.By,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	fmt.Printf("[JIRA] Opened %s for incident %s\n", key, req.IncidentID)
+	return ticket, true, t.saveLocked(ticket)
+}
+
+// UpdateRisk comments on the incident's open ticket when its risk changed since the last update
+func (t *Ticketer) UpdateRisk(ctx context.Context, incidentID, risk, detail string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ticket, ok := t.tickets[incidentID]
+	if !ok || ticket.Resolved() || ticket.Risk == risk {
+		return nil
+	}
+	body := fmt.Sprintf("Risk changed from %s to %s.", ticket.Risk, risk)
+	if detail != "" {
+		body += "\n\n" + Markup(detail)
+	}
+	if err := t.client.Comment(ctx, ticket.Key, body); err != nil {
+		return err
+	}
+	ticket.Risk, ticket.UpdatedAt = risk, time.Now()
+	return t.saveLocked(ticket)
+}
+
+// Resolve records that the incident was resolved on its ticket and moves the ticket through
+// the resolve transition
+func (t *Ticketer) Resolve(ctx context.Context, incidentID string, at time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ticket, ok := t.tickets[incidentID]
+	if !ok || ticket.Resolved() {
+		return nil
+	}
+	body := fmt.Sprintf("Incident %s resolved at %s.", incidentID, at.Format(time.RFC3339))
+	if err := t.client.Comment(ctx, ticket.Key, body); err != nil {
+		return err
+	}
+	ticket.ResolvedAt, ticket.UpdatedAt = at, time.Now()
+	// The comment went through; a failed transition must not post it again next cycle
+	saveErr := t.saveLocked(ticket)
+	if t.resolveTransition != "" {
+		if err := t.client.Transition(ctx, ticket.Key, t.resolveTransition); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("[JIRA] Resolved %s for incident %s\n", ticket.Key, incidentID)
+	return saveErr
+}
+
+// saveLocked records a ticket in memory and appends it to the file
+func (t *Ticketer) saveLocked(ticket Ticket) error {
+	t.tickets[ticket.IncidentID] = ticket
+	line, err := json.Marshal(ticket)
+	if err != nil {
+		return fmt.Errorf("failed to encode ticket: %w", err)
+	}
+	file, err := os.OpenFile(t.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open ticket file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write ticket: %w", err)
+	}
+	return nil
+}
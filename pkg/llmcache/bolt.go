@@ -0,0 +1,86 @@
+package llmcache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Backend persists cache entries so analyses survive restarts
+type Backend interface {
+	Load() ([]*CachedSummary, error)
+	Put(entry *CachedSummary) error
+	Delete(hash string) error
+	Close() error
+}
+
+var summariesBucket = []byte("summaries")
+
+// BoltBackend stores cache entries in a bbolt database file, one key per input hash
+type BoltBackend struct {
+	db *bolt.DB
+}
+
+// NewBoltBackend opens (or creates) the database at path
+func NewBoltBackend(path string) (*BoltBackend, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// Fail fast instead of blocking forever if another instance holds the file lock
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cache database %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(summariesBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create cache bucket: %w", err)
+	}
+
+	return &BoltBackend{db: db}, nil
+}
+
+func (b *BoltBackend) Load() ([]*CachedSummary, error) {
+	var entries []*CachedSummary
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(summariesBucket).ForEach(func(k, v []byte) error {
+			var entry CachedSummary
+			if err := json.Unmarshal(v, &entry); err != nil {
+				fmt.Printf("[LLM CACHE] Skipping unreadable entry %s: %v\n", k, err)
+				return nil
+			}
+			entries = append(entries, &entry)
+			return nil
+		})
+	})
+	return entries, err
+}
+
+func (b *BoltBackend) Put(entry *CachedSummary) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(summariesBucket).Put([]byte(entry.InputHash), data)
+	})
+}
+
+func (b *BoltBackend) Delete(hash string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(summariesBucket).Delete([]byte(hash))
+	})
+}
+
+func (b *BoltBackend) Close() error {
+	return b.db.Close()
+}
@@ -1,11 +1,21 @@
 package llmcache
 
 import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
 	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"vigilant/pkg/hashutil"
+	"vigilant/pkg/selfmetrics"
 	"vigilant/pkg/summarizer"
 )
 
@@ -14,12 +24,56 @@ type CachedSummary struct {
 	InputHash string
 	Timestamp time.Time
 	TTL       time.Duration
+	Signature CorrelationSignature
+
+	// Incidents of the analyzed alerts, so an incident's analyses can be invalidated together
+	IncidentIDs []string
+
+	// Rough prompt+response size in tokens, counted as saved on every hit
+	EstimatedTokens int
+}
+
+// Stats is a point-in-time view of cache usage
+type Stats struct {
+	Entries              int     `json:"entries"`
+	Hits                 int64   `json:"hits"`
+	SimilarityHits       int64   `json:"similarity_hits"`
+	Misses               int64   `json:"misses"`
+	Expirations          int64   `json:"expirations"`
+	HitRate              float64 `json:"hit_rate"`
+	OldestAgeSeconds     float64 `json:"oldest_age_seconds"`
+	EstimatedTokensSaved int64   `json:"estimated_tokens_saved"`
+}
+
+// CorrelationSignature describes a correlation set for fuzzy (near-identical) matching.
+// Identity holds everything that must match exactly; Values holds the numbers allowed to drift.
+type CorrelationSignature struct {
+	Identity string
+	Values   map[string]float64
 }
 
 type LLMCache struct {
 	cache map[string]*CachedSummary
 	mu    sync.RWMutex
 	defaultTTL time.Duration
+
+	// Relative tolerance for similarity hits, 0 disables the similarity layer
+	similarityTolerance float64
+
+	// Bucketing applied to counts and metric values before hashing
+	normalizer hashutil.Normalizer
+
+	// Per-service TTL overrides; a correlation set uses the tightest TTL among its services
+	serviceTTLs map[string]time.Duration
+
+	// Optional on-disk copy of the cache, nil for in-memory only
+	backend Backend
+
+	hits           atomic.Int64
+	similarityHits atomic.Int64
+	misses         atomic.Int64
+	expirations    atomic.Int64
+	tokensSaved    atomic.Int64
 }
 
 func NewLLMCache(defaultTTL time.Duration) *LLMCache {
@@ -29,23 +83,169 @@ func NewLLMCache(defaultTTL time.Duration) *LLMCache {
 	}
 }
 
+// NewPersistentLLMCache creates a cache backed by backend, loading all entries that
+// have not expired yet. Expired entries are removed from the backend while loading.
+func NewPersistentLLMCache(defaultTTL time.Duration, backend Backend) (*LLMCache, error) {
+	c := NewLLMCache(defaultTTL)
+	c.backend = backend
+
+	entries, err := backend.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load persisted cache: %w", err)
+	}
+
+	expired := 0
+	for _, entry := range entries {
+		if time.Since(entry.Timestamp) >= entry.TTL {
+			c.deleteFromBackend(entry.InputHash)
+			expired++
+			continue
+		}
+		c.cache[entry.InputHash] = entry
+	}
+
+	selfmetrics.CacheEntries.Set(float64(len(c.cache)))
+	fmt.Printf("[LLM CACHE] Loaded %d persisted entries (%d expired)\n", len(c.cache), expired)
+	return c, nil
+}
+
+// Close releases the persistent backend, if any
+func (c *LLMCache) Close() error {
+	if c.backend == nil {
+		return nil
+	}
+	return c.backend.Close()
+}
+
+// EnableSimilarity lets correlations whose counts and metric values differ by at most
+// tolerance (relative, e.g. 0.2 = 20%) from a cached entry reuse that entry's summary
+func (c *LLMCache) EnableSimilarity(tolerance float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.similarityTolerance = tolerance
+}
+
+// SetNormalizer configures how counts and metric values are coarsened before hashing
+func (c *LLMCache) SetNormalizer(n hashutil.Normalizer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.normalizer = n
+}
+
+// cacheKey is the part of a correlation that determines its analysis; timestamps are
+// left out so a still-firing alert with unchanged symptoms maps to the same entry
+type cacheKey struct {
+	Alert    hashutil.SimplifiedAlert
+	Symptoms []hashutil.SimplifiedSymptom
+	Metrics  []hashutil.SimplifiedMetric
+	Upstream []string `json:",omitempty"` // Alerting upstream services, which change the analysis
+	Group    []string `json:",omitempty"` // Services analyzed together for a shared failure
+	Traces   string   `json:",omitempty"` // Spans the traces blame
+	Role     string   `json:",omitempty"` // Victim of a downstream, or origin, in the mesh traffic
+}
+
+// hashCorrelations hashes the normalized, order-independent content of correlations
+func (c *LLMCache) hashCorrelations(correlations []summarizer.AlertCorrelation) string {
+	c.mu.RLock()
+	n := c.normalizer
+	c.mu.RUnlock()
+
+	keys := make([]cacheKey, 0, len(correlations))
+	for _, corr := range correlations {
+		key := cacheKey{Alert: hashutil.SimplifiedAlert{
+			Service:   corr.Alert.Service,
+			AlertName: corr.Alert.AlertName,
+			Severity:  corr.Alert.Severity,
+			Pending:   corr.Alert.State == "pending",
+		}, Upstream: corr.Dependencies.FiringUpstream, Group: corr.CrossService.Services}
+		if corr.Traces != nil {
+			key.Traces = corr.Traces.Key()
+		}
+		if corr.Topology != nil && corr.Topology.Role != "" {
+			key.Role = corr.Topology.Role + " " + corr.Topology.FailingDownstream
+		}
+		for _, s := range corr.Symptoms {
+			key.Symptoms = append(key.Symptoms, hashutil.SimplifiedSymptom{
+				Service: s.Service,
+				Pattern: s.Pattern,
+				Count:   n.Count(s.Count),
+			})
+		}
+		for _, m := range corr.Metrics {
+			key.Metrics = append(key.Metrics, hashutil.SimplifiedMetric{
+				Service:   m.Service,
+				CheckName: m.Check.Name,
+				Value:     n.Value(m.Value),
+				Operator:  m.Check.Operator,
+				Threshold: m.Check.Threshold,
+			})
+		}
+		// Correlations are gathered from maps, so every field orders, or equal data could hash differently
+		slices.SortFunc(key.Symptoms, func(a, b hashutil.SimplifiedSymptom) int {
+			return cmp.Or(cmp.Compare(a.Service, b.Service), cmp.Compare(a.Pattern, b.Pattern), cmp.Compare(a.Count, b.Count))
+		})
+		slices.SortFunc(key.Metrics, func(a, b hashutil.SimplifiedMetric) int {
+			return cmp.Or(cmp.Compare(a.Service, b.Service), cmp.Compare(a.CheckName, b.CheckName),
+				cmp.Compare(a.Operator, b.Operator), cmp.Compare(a.Threshold, b.Threshold), cmp.Compare(a.Value, b.Value))
+		})
+		keys = append(keys, key)
+	}
+
+	// A service can have several correlations of one alert, e.g. per fingerprint; their
+	// encoding breaks the tie
+	slices.SortFunc(keys, func(a, b cacheKey) int {
+		if c := cmp.Or(cmp.Compare(a.Alert.Service, b.Alert.Service), cmp.Compare(a.Alert.AlertName, b.Alert.AlertName),
+			cmp.Compare(a.Alert.Severity, b.Alert.Severity)); c != 0 {
+			return c
+		}
+		encodedA, _ := json.Marshal(a)
+		encodedB, _ := json.Marshal(b)
+		return bytes.Compare(encodedA, encodedB)
+	})
+
+	return hashutil.HashData(keys)
+}
+
+// SetServiceTTLs overrides the default TTL for the given services
+func (c *LLMCache) SetServiceTTLs(ttls map[string]time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.serviceTTLs = ttls
+}
+
+// ttlFor returns the tightest TTL among the correlated services. Caller must hold c.mu.
+func (c *LLMCache) ttlFor(correlations []summarizer.AlertCorrelation) time.Duration {
+	ttl := c.defaultTTL
+	for _, corr := range correlations {
+		if override, ok := c.serviceTTLs[corr.Alert.Service]; ok && override < ttl {
+			ttl = override
+		}
+	}
+	return ttl
+}
+
 // GetOrSummarize checks cache first, calls LLM only if needed
-func (c *LLMCache) GetOrSummarize(correlations []summarizer.AlertCorrelation) (map[string]summarizer.RootCauseSummary, error) {
+func (c *LLMCache) GetOrSummarize(ctx context.Context, correlations []summarizer.AlertCorrelation) (map[string]summarizer.RootCauseSummary, error) {
 	// Early return for empty correlations - no LLM call needed
 	if len(correlations) == 0 {
 		fmt.Println("[LLM CACHE] No correlations - skipping LLM call")
 		return make(map[string]summarizer.RootCauseSummary), nil
 	}
 
-	// Generate hash based on correlation content
-	inputHash := hashutil.HashData(correlations)
+	// Generate hash based on normalized correlation content
+	inputHash := c.hashCorrelations(correlations)
 	
 	// Check cache first
 	c.mu.RLock()
+	ttl := c.ttlFor(correlations)
 	if cached, exists := c.cache[inputHash]; exists {
-		// Check if cache entry is still valid
-		if time.Since(cached.Timestamp) < cached.TTL {
+		// Check if cache entry is still valid (TTL overrides may have tightened since it was stored)
+		if time.Since(cached.Timestamp) < min(cached.TTL, ttl) {
 			c.mu.RUnlock()
+			c.hits.Add(1)
+			c.tokensSaved.Add(int64(cached.EstimatedTokens))
+			selfmetrics.CacheHits.WithLabelValues("exact").Inc()
+			selfmetrics.CacheTokensSaved.Add(float64(cached.EstimatedTokens))
 			fmt.Printf("[LLM CACHE] Cache hit for hash %s - skipping LLM call\n", 
 				hashutil.SafeHashDisplay(inputHash))
 			return cached.Summary, nil
@@ -54,26 +254,77 @@ func (c *LLMCache) GetOrSummarize(correlations []summarizer.AlertCorrelation) (m
 		fmt.Printf("[LLM CACHE] Cache expired for hash %s\n", 
 			hashutil.SafeHashDisplay(inputHash))
 	}
+	signature := NewCorrelationSignature(correlations)
+	if c.similarityTolerance > 0 {
+		if cached := c.findSimilar(signature, ttl); cached != nil {
+			c.mu.RUnlock()
+			c.similarityHits.Add(1)
+			c.tokensSaved.Add(int64(cached.EstimatedTokens))
+			selfmetrics.CacheHits.WithLabelValues("similarity").Inc()
+			selfmetrics.CacheTokensSaved.Add(float64(cached.EstimatedTokens))
+			fmt.Printf("[LLM CACHE] Similarity hit for hash %s (matches %s) - skipping LLM call\n",
+				hashutil.SafeHashDisplay(inputHash), hashutil.SafeHashDisplay(cached.InputHash))
+			return cached.Summary, nil
+		}
+	}
 	c.mu.RUnlock()
 	
 	// Cache miss or expired - call LLM
+	c.misses.Add(1)
+	selfmetrics.CacheMisses.Inc()
 	fmt.Printf("[LLM CACHE] Cache miss for hash %s - calling LLM\n", 
 		hashutil.SafeHashDisplay(inputHash))
 	
-	summary, err := summarizer.SummarizeMany(correlations)
+	return c.summarize(ctx, correlations, inputHash, signature, ttl)
+}
+
+// Refresh analyzes correlations with the LLM even when a valid cached analysis exists, and
+// caches the fresh analysis in its place
+func (c *LLMCache) Refresh(ctx context.Context, correlations []summarizer.AlertCorrelation) (map[string]summarizer.RootCauseSummary, error) {
+	if len(correlations) == 0 {
+		return make(map[string]summarizer.RootCauseSummary), nil
+	}
+
+	inputHash := c.hashCorrelations(correlations)
+	c.mu.RLock()
+	ttl := c.ttlFor(correlations)
+	c.mu.RUnlock()
+
+	fmt.Printf("[LLM CACHE] Refreshing hash %s - calling LLM\n", hashutil.SafeHashDisplay(inputHash))
+	return c.summarize(ctx, correlations, inputHash, NewCorrelationSignature(correlations), ttl)
+}
+
+// summarize calls the LLM and caches its analysis under inputHash
+func (c *LLMCache) summarize(ctx context.Context, correlations []summarizer.AlertCorrelation, inputHash string, signature CorrelationSignature, ttl time.Duration) (map[string]summarizer.RootCauseSummary, error) {
+	started := time.Now()
+	summary, err := summarizer.SummarizeMany(ctx, correlations)
 	if err != nil {
+		selfmetrics.LLMLatency.WithLabelValues("error").Observe(time.Since(started).Seconds())
 		return nil, fmt.Errorf("LLM call failed: %w", err)
 	}
+	selfmetrics.LLMLatency.WithLabelValues("success").Observe(time.Since(started).Seconds())
 	
 	// Store successful result in cache
-	c.mu.Lock()
-	c.cache[inputHash] = &CachedSummary{
+	entry := &CachedSummary{
 		Summary:   summary,
 		InputHash: inputHash,
 		Timestamp: time.Now(),
-		TTL:       c.defaultTTL,
+		TTL:       ttl,
+		Signature: signature,
+
+		IncidentIDs:     incidentIDs(correlations),
+		EstimatedTokens: estimateTokens(correlations, summary),
 	}
+	c.mu.Lock()
+	c.cache[inputHash] = entry
+	selfmetrics.CacheEntries.Set(float64(len(c.cache)))
 	c.mu.Unlock()
+
+	if c.backend != nil {
+		if err := c.backend.Put(entry); err != nil {
+			fmt.Printf("[LLM CACHE] Failed to persist hash %s: %v\n", hashutil.SafeHashDisplay(inputHash), err)
+		}
+	}
 	
 	fmt.Printf("[LLM CACHE] Cached new result for hash %s\n", 
 		hashutil.SafeHashDisplay(inputHash))
@@ -81,6 +332,94 @@ func (c *LLMCache) GetOrSummarize(correlations []summarizer.AlertCorrelation) (m
 	return summary, nil
 }
 
+// findSimilar returns a cache entry near-identical to signature that is still valid under
+// ttl, the correlations' current TTL. Caller must hold c.mu.
+func (c *LLMCache) findSimilar(signature CorrelationSignature, ttl time.Duration) *CachedSummary {
+	for _, cached := range c.cache {
+		if time.Since(cached.Timestamp) >= min(cached.TTL, ttl) {
+			continue
+		}
+		if cached.Signature.Identity != signature.Identity {
+			continue
+		}
+		if withinTolerance(cached.Signature.Values, signature.Values, c.similarityTolerance) {
+			return cached
+		}
+	}
+	return nil
+}
+
+// NewCorrelationSignature splits correlations into exact identity (the cacheKey fields but
+// counts and values, e.g. alerts, patterns, metric names) and drifting values (symptom counts,
+// metric values)
+func NewCorrelationSignature(correlations []summarizer.AlertCorrelation) CorrelationSignature {
+	var identity []string
+	values := make(map[string]float64)
+
+	for _, corr := range correlations {
+		prefix := alertIdentity(corr)
+		identity = append(identity, prefix)
+		for _, s := range corr.Symptoms {
+			key := prefix + "|symptom|" + s.Pattern
+			identity = append(identity, key)
+			values[key] += float64(s.Count)
+		}
+		for _, m := range corr.Metrics {
+			key := prefix + "|metric|" + m.Check.Name
+			identity = append(identity, key)
+			values[key] = m.Value
+		}
+	}
+
+	sort.Strings(identity)
+	return CorrelationSignature{
+		Identity: hashutil.HashData(identity),
+		Values:   values,
+	}
+}
+
+// alertIdentity is the part of a correlation's cacheKey outside its symptoms and metrics
+func alertIdentity(corr summarizer.AlertCorrelation) string {
+	identity := corr.Alert.Service + "|" + corr.Alert.AlertName + "|" + corr.Alert.Severity
+	if corr.Alert.State == "pending" {
+		identity += "|pending"
+	}
+	if len(corr.Dependencies.FiringUpstream) > 0 {
+		identity += "|upstream:" + strings.Join(corr.Dependencies.FiringUpstream, ",")
+	}
+	if !corr.CrossService.Empty() {
+		identity += "|group:" + corr.CrossService.Key()
+	}
+	if corr.Traces != nil {
+		identity += "|traces:" + corr.Traces.Key()
+	}
+	if corr.Topology != nil && corr.Topology.Role != "" {
+		identity += "|" + corr.Topology.Role + " " + corr.Topology.FailingDownstream
+	}
+	return identity
+}
+
+// withinTolerance reports whether every value in b is within the relative tolerance of a
+func withinTolerance(a, b map[string]float64, tolerance float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, va := range a {
+		vb, ok := b[key]
+		if !ok {
+			return false
+		}
+		scale := math.Max(math.Abs(va), math.Abs(vb))
+		if scale == 0 {
+			continue
+		}
+		if math.Abs(va-vb)/scale > tolerance {
+			return false
+		}
+	}
+	return true
+}
+
 // CleanupExpired removes expired cache entries
 func (c *LLMCache) CleanupExpired() {
 	c.mu.Lock()
@@ -92,9 +431,13 @@ func (c *LLMCache) CleanupExpired() {
 	for hash, cached := range c.cache {
 		if now.Sub(cached.Timestamp) > cached.TTL {
 			delete(c.cache, hash)
+			c.deleteFromBackend(hash)
 			expired++
 		}
 	}
+	c.expirations.Add(int64(expired))
+	selfmetrics.CacheExpirations.Add(float64(expired))
+	selfmetrics.CacheEntries.Set(float64(len(c.cache)))
 	
 	if expired > 0 {
 		fmt.Printf("[LLM CACHE] Cleaned up %d expired entries\n", expired)
@@ -122,10 +465,86 @@ func (c *LLMCache) GetStats() (entries int, oldestAge time.Duration) {
 	return entries, oldestAge
 }
 
+// Stats returns entry count, hit/miss counters and the estimated tokens saved since startup
+func (c *LLMCache) Stats() Stats {
+	entries, oldestAge := c.GetStats()
+	stats := Stats{
+		Entries:              entries,
+		Hits:                 c.hits.Load(),
+		SimilarityHits:       c.similarityHits.Load(),
+		Misses:               c.misses.Load(),
+		Expirations:          c.expirations.Load(),
+		OldestAgeSeconds:     oldestAge.Seconds(),
+		EstimatedTokensSaved: c.tokensSaved.Load(),
+	}
+	if lookups := stats.Hits + stats.SimilarityHits + stats.Misses; lookups > 0 {
+		stats.HitRate = float64(stats.Hits+stats.SimilarityHits) / float64(lookups)
+	}
+	return stats
+}
+
+// incidentIDs returns the distinct incident IDs of the correlated alerts
+func incidentIDs(correlations []summarizer.AlertCorrelation) []string {
+	var ids []string
+	for _, corr := range correlations {
+		if id := corr.Alert.IncidentID; id != "" && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Invalidate removes every entry whose input hash starts with hash (the 8-character
+// display form is accepted) or that analyzed the incident with that ID, and returns how many
+// entries were removed
+func (c *LLMCache) Invalidate(hash string) int {
+	if hash == "" {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for key, entry := range c.cache {
+		if strings.HasPrefix(key, hash) || slices.Contains(entry.IncidentIDs, hash) {
+			delete(c.cache, key)
+			c.deleteFromBackend(key)
+			removed++
+		}
+	}
+	selfmetrics.CacheEntries.Set(float64(len(c.cache)))
+	if removed > 0 {
+		fmt.Printf("[LLM CACHE] Invalidated %d entries matching %s\n", removed, hash)
+	}
+	return removed
+}
+
 // Clear removes all cache entries (useful for testing)
 func (c *LLMCache) Clear() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	for hash := range c.cache {
+		c.deleteFromBackend(hash)
+	}
 	c.cache = make(map[string]*CachedSummary)
+	selfmetrics.CacheEntries.Set(0)
 	fmt.Println("[LLM CACHE] Cache cleared")
+}
+
+// estimateTokens approximates the tokens an LLM call for these correlations costs (~4 bytes per token)
+func estimateTokens(correlations []summarizer.AlertCorrelation, summary map[string]summarizer.RootCauseSummary) int {
+	input, _ := json.Marshal(correlations)
+	output, _ := json.Marshal(summary)
+	return (len(input) + len(output)) / 4
+}
+
+// deleteFromBackend removes an entry from persistent storage, logging failures
+func (c *LLMCache) deleteFromBackend(hash string) {
+	if c.backend == nil {
+		return
+	}
+	if err := c.backend.Delete(hash); err != nil {
+		fmt.Printf("[LLM CACHE] Failed to delete persisted hash %s: %v\n", hashutil.SafeHashDisplay(hash), err)
+	}
 }
\ No newline at end of file
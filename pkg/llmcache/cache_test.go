@@ -0,0 +1,143 @@
+package llmcache
+
+import (
+	"testing"
+	"time"
+
+	"vigilant/pkg/hashutil"
+	"vigilant/pkg/logs"
+	"vigilant/pkg/prometheus"
+	"vigilant/pkg/risk"
+	"vigilant/pkg/summarizer"
+	"vigilant/pkg/topology"
+	"vigilant/pkg/tracing"
+)
+
+func correlation(service, alert string, symptoms []logs.SymptomMatch, metrics []prometheus.MetricResult) summarizer.AlertCorrelation {
+	return summarizer.AlertCorrelation{
+		Alert:    risk.RiskItem{Service: service, AlertName: alert, Severity: "critical", State: "firing"},
+		Symptoms: symptoms,
+		Metrics:  metrics,
+	}
+}
+
+func metric(service, check string, value float64) prometheus.MetricResult {
+	return prometheus.MetricResult{Service: service, Check: prometheus.MetricCheck{Name: check, Operator: ">", Threshold: 0.5}, Value: value}
+}
+
+func TestHashCorrelationsOrder(t *testing.T) {
+	symptoms := []logs.SymptomMatch{
+		{Service: "api", Pattern: "timeout", Count: 3},
+		{Service: "db", Pattern: "timeout", Count: 7},
+		{Service: "api", Pattern: "refused", Count: 1},
+	}
+	metrics := []prometheus.MetricResult{
+		metric("api", "cpu", 0.9),
+		metric("api", "cpu", 0.7), // Another series of the same check
+		metric("api", "memory", 0.6),
+	}
+	first := correlation("api", "HighLatency", symptoms, metrics)
+	second := correlation("api", "HighLatency", symptoms[:1], metrics[:1]) // Same alert, another fingerprint
+	other := correlation("db", "DiskFull", nil, nil)
+
+	reversedSymptoms := []logs.SymptomMatch{symptoms[2], symptoms[1], symptoms[0]}
+	reversedMetrics := []prometheus.MetricResult{metrics[2], metrics[1], metrics[0]}
+	reordered := correlation("api", "HighLatency", reversedSymptoms, reversedMetrics)
+
+	tests := []struct {
+		name string
+		a, b []summarizer.AlertCorrelation
+	}{
+		{name: "correlations", a: []summarizer.AlertCorrelation{first, other}, b: []summarizer.AlertCorrelation{other, first}},
+		{name: "symptoms and metrics", a: []summarizer.AlertCorrelation{first}, b: []summarizer.AlertCorrelation{reordered}},
+		{name: "correlations of one alert", a: []summarizer.AlertCorrelation{first, second, other}, b: []summarizer.AlertCorrelation{second, other, reordered}},
+	}
+
+	c := NewLLMCache(time.Hour)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if a, b := c.hashCorrelations(tt.a), c.hashCorrelations(tt.b); a != b {
+				t.Errorf("hashCorrelations() = %s and %s for the same correlations in another order", a, b)
+			}
+		})
+	}
+}
+
+func TestHashCorrelationsNormalization(t *testing.T) {
+	base := correlation("api", "HighCPU",
+		[]logs.SymptomMatch{{Service: "api", Pattern: "timeout", Count: 14}},
+		[]prometheus.MetricResult{metric("api", "cpu", 0.8132)})
+
+	tests := []struct {
+		name     string
+		change   func(c *summarizer.AlertCorrelation)
+		wantSame bool
+	}{
+		{name: "count within its band", change: func(c *summarizer.AlertCorrelation) { c.Symptoms[0].Count = 19 }, wantSame: true},
+		{name: "count in the next band", change: func(c *summarizer.AlertCorrelation) { c.Symptoms[0].Count = 20 }},
+		{name: "value rounding alike", change: func(c *summarizer.AlertCorrelation) { c.Metrics[0].Value = 0.8140 }, wantSame: true},
+		{name: "value rounding apart", change: func(c *summarizer.AlertCorrelation) { c.Metrics[0].Value = 0.86 }},
+		{name: "timestamps", change: func(c *summarizer.AlertCorrelation) { c.Alert.LastSeen = time.Now() }, wantSame: true},
+		{name: "pending", change: func(c *summarizer.AlertCorrelation) { c.Alert.State = "pending" }},
+		{name: "severity", change: func(c *summarizer.AlertCorrelation) { c.Alert.Severity = "warning" }},
+		{name: "threshold", change: func(c *summarizer.AlertCorrelation) { c.Metrics[0].Check.Threshold = 0.9 }},
+		{name: "pattern", change: func(c *summarizer.AlertCorrelation) { c.Symptoms[0].Pattern = "refused" }},
+	}
+
+	c := NewLLMCache(time.Hour)
+	c.SetNormalizer(hashutil.Normalizer{SignificantDigits: 2, CountBands: hashutil.DefaultCountBands})
+	want := c.hashCorrelations([]summarizer.AlertCorrelation{base})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := base
+			changed.Symptoms = append([]logs.SymptomMatch(nil), base.Symptoms...)
+			changed.Metrics = append([]prometheus.MetricResult(nil), base.Metrics...)
+			tt.change(&changed)
+			if got := c.hashCorrelations([]summarizer.AlertCorrelation{changed}); (got == want) != tt.wantSame {
+				t.Errorf("hashCorrelations() same = %v, want %v", got == want, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestNewCorrelationSignature(t *testing.T) {
+	base := correlation("api", "HighCPU",
+		[]logs.SymptomMatch{{Service: "api", Pattern: "timeout", Count: 14}},
+		[]prometheus.MetricResult{metric("api", "cpu", 0.8132)})
+
+	tests := []struct {
+		name         string
+		change       func(c *summarizer.AlertCorrelation)
+		wantIdentity bool // Whether the identity stays the same
+	}{
+		{name: "count", change: func(c *summarizer.AlertCorrelation) { c.Symptoms[0].Count = 400 }, wantIdentity: true},
+		{name: "value", change: func(c *summarizer.AlertCorrelation) { c.Metrics[0].Value = 0.2 }, wantIdentity: true},
+		{name: "severity", change: func(c *summarizer.AlertCorrelation) { c.Alert.Severity = "warning" }},
+		{name: "pattern", change: func(c *summarizer.AlertCorrelation) { c.Symptoms[0].Pattern = "refused" }},
+		{name: "pending", change: func(c *summarizer.AlertCorrelation) { c.Alert.State = "pending" }},
+		{name: "upstream firing", change: func(c *summarizer.AlertCorrelation) { c.Dependencies.FiringUpstream = []string{"db"} }},
+		{name: "shared failure", change: func(c *summarizer.AlertCorrelation) { c.CrossService.Services = []string{"api", "worker"} }},
+		{name: "traces", change: func(c *summarizer.AlertCorrelation) {
+			c.Traces = &tracing.Summary{Service: "api", FailingSpan: &tracing.SpanStat{Service: "db", Operation: "query"}}
+		}},
+		{name: "victim", change: func(c *summarizer.AlertCorrelation) {
+			c.Topology = &topology.Position{Role: topology.RoleVictim, FailingDownstream: "db"}
+		}},
+		{name: "origin", change: func(c *summarizer.AlertCorrelation) { c.Topology = &topology.Position{Role: topology.RoleOrigin} }},
+		{name: "no role", change: func(c *summarizer.AlertCorrelation) { c.Topology = &topology.Position{} }, wantIdentity: true},
+	}
+
+	want := NewCorrelationSignature([]summarizer.AlertCorrelation{base})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := base
+			changed.Symptoms = append([]logs.SymptomMatch(nil), base.Symptoms...)
+			changed.Metrics = append([]prometheus.MetricResult(nil), base.Metrics...)
+			tt.change(&changed)
+			got := NewCorrelationSignature([]summarizer.AlertCorrelation{changed})
+			if (got.Identity == want.Identity) != tt.wantIdentity {
+				t.Errorf("NewCorrelationSignature() same identity = %v, want %v", got.Identity == want.Identity, tt.wantIdentity)
+			}
+		})
+	}
+}
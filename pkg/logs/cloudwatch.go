@@ -0,0 +1,154 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"vigilant/pkg/awsauth"
+	"vigilant/pkg/config"
+)
+
+// CloudWatchLogsClient runs CloudWatch Logs Insights queries per service
+type CloudWatchLogsClient struct {
+	serviceMapping *ServiceMapping
+	pollInterval   time.Duration
+}
+
+func NewCloudWatchLogsClient(serviceMapping *ServiceMapping) *CloudWatchLogsClient {
+	return &CloudWatchLogsClient{
+		serviceMapping: serviceMapping,
+		pollInterval:   time.Second,
+	}
+}
+
+func (c *CloudWatchLogsClient) Name() string { return "cloudwatch_logs" }
+
+type insightsStartQueryRequest struct {
+	LogGroupNames []string `json:"logGroupNames"`
+	StartTime     int64    `json:"startTime"`
+	EndTime       int64    `json:"endTime"`
+	QueryString   string   `json:"queryString"`
+	Limit         int      `json:"limit,omitempty"`
+}
+
+type insightsStartQueryResponse struct {
+	QueryID string `json:"queryId"`
+}
+
+type insightsField struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+type insightsQueryResults struct {
+	Status  string            `json:"status"`
+	Results [][]insightsField `json:"results"`
+}
+
+// ScanSymptoms runs the profile's Logs Insights query over the window (the profile's
+// time_range_minutes when window is 0), waits for it to complete and matches the messages
+func (c *CloudWatchLogsClient) ScanSymptoms(ctx context.Context, profile config.ServiceProfile, window time.Duration) ([]SymptomMatch, error) {
+	cfg := profile.DataSources.CloudWatchLogs
+	if !cfg.Enabled() {
+		return nil, fmt.Errorf("no cloudwatch log groups configured for service %s", profile.Metadata.Name)
+	}
+	if window <= 0 {
+		window = time.Duration(cfg.TimeRangeMinutes) * time.Minute
+	}
+
+	query, err := renderServiceQuery(cfg.Query, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Insights queries usually finish in seconds; don't let a slow one stall the cycle
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	awsCfg, err := awsauth.Config(ctx, cfg.Region, cfg.RoleARN)
+	if err != nil {
+		return nil, err
+	}
+
+	end := time.Now()
+	var started insightsStartQueryResponse
+	err = awsauth.CallJSON(ctx, awsCfg, "logs", "1.1", "Logs_20140328.StartQuery", insightsStartQueryRequest{
+		LogGroupNames: cfg.LogGroups,
+		StartTime:     end.Add(-window).Unix(),
+		EndTime:       end.Unix(),
+		QueryString:   query,
+		Limit:         cfg.ScanLimit,
+	}, &started)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := c.waitForResults(ctx, awsCfg, started.QueryID)
+	if err != nil {
+		return nil, err
+	}
+
+	counter := newSymptomCounter(profile)
+	for _, row := range results {
+		fields := make(map[string]string, len(row))
+		for _, f := range row {
+			fields[f.Field] = f.Value
+		}
+		message := fields[cfg.MessageField]
+		if message == "" {
+			continue
+		}
+		ts, err := time.Parse("2006-01-02 15:04:05.000", fields["@timestamp"])
+		if err != nil {
+			ts = time.Now()
+		}
+		counter.Add(c.serviceFromFields(fields, cfg.ServiceFields), message, ts)
+	}
+
+	fmt.Printf("CLOUDWATCH DEBUG: Scanned %d events from %v for %s\n", len(results), cfg.LogGroups, profile.Metadata.Name)
+	return counter.Results(), nil
+}
+
+// waitForResults polls GetQueryResults until the query finishes or ctx is done
+func (c *CloudWatchLogsClient) waitForResults(ctx context.Context, awsCfg aws.Config, queryID string) ([][]insightsField, error) {
+	for {
+		var results insightsQueryResults
+		err := awsauth.CallJSON(ctx, awsCfg, "logs", "1.1", "Logs_20140328.GetQueryResults",
+			map[string]string{"queryId": queryID}, &results)
+		if err != nil {
+			return nil, err
+		}
+
+		switch results.Status {
+		case "Complete":
+			return results.Results, nil
+		case "Failed", "Cancelled", "Timeout":
+			return nil, fmt.Errorf("logs insights query %s ended with status %s", queryID, results.Status)
+		}
+
+		select {
+		case <-ctx.Done():
+			// Don't leave the query running (and billing) after we give up on it
+			awsauth.CallJSON(context.Background(), awsCfg, "logs", "1.1", "Logs_20140328.StopQuery",
+				map[string]string{"queryId": queryID}, nil)
+			return nil, ctx.Err()
+		case <-time.After(c.pollInterval):
+		}
+	}
+}
+
+// serviceFromFields returns the configured service named by the first present field
+func (c *CloudWatchLogsClient) serviceFromFields(fields map[string]string, serviceFields []string) string {
+	for _, name := range serviceFields {
+		if value := fields[name]; value != "" {
+			if c.serviceMapping == nil {
+				return value
+			}
+			return c.serviceMapping.normalizeServiceName(value)
+		}
+	}
+	return "unknown"
+}
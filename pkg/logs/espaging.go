@@ -0,0 +1,125 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// pitKeepAlive only needs to cover the gap between two page requests
+const pitKeepAlive = "1m"
+
+// scanPages runs query page by page with search_after, calling fn for every _source, until
+// a page comes back short (the window is exhausted) or maxDocuments have been read. On
+// Elasticsearch the pages share a point in time so they see a consistent view of the index;
+// OpenSearch pages without one. truncated reports whether the budget ran out first.
+func (es *ElasticsearchClient) scanPages(ctx context.Context, indexPattern string, query map[string]interface{},
+	pageSize, maxDocuments int, fn func(source json.RawMessage)) (scanned int, truncated bool, err error) {
+	if pageSize <= 0 || pageSize > maxDocuments {
+		pageSize = maxDocuments
+	}
+
+	pitID := ""
+	if es.openSearch == nil && maxDocuments > pageSize {
+		pitID, err = es.openPointInTime(ctx, indexPattern)
+		if err != nil {
+			// Older clusters or missing privileges: search_after still works without a PIT
+			fmt.Printf("ES DEBUG: Paging without point in time: %v\n", err)
+		}
+	}
+	if pitID != "" {
+		defer func() { es.closePointInTime(pitID) }()
+	}
+
+	sort := query["sort"]
+	if base, ok := sort.([]map[string]interface{}); ok && pitID != "" {
+		// _shard_doc breaks ties between documents with the same timestamp
+		sort = append(append([]map[string]interface{}{}, base...), map[string]interface{}{"_shard_doc": "asc"})
+	}
+
+	var searchAfter []interface{}
+	for scanned < maxDocuments {
+		size := pageSize
+		if remaining := maxDocuments - scanned; remaining < size {
+			size = remaining
+		}
+
+		page := make(map[string]interface{}, len(query)+3)
+		for k, v := range query {
+			page[k] = v
+		}
+		page["size"] = size
+		page["sort"] = sort
+		page["track_total_hits"] = false
+		if pitID != "" {
+			page["pit"] = map[string]interface{}{"id": pitID, "keep_alive": pitKeepAlive}
+		}
+		if searchAfter != nil {
+			page["search_after"] = searchAfter
+		}
+
+		response, err := es.searchPage(ctx, indexPattern, page)
+		if err != nil {
+			if scanned == 0 {
+				return 0, false, err
+			}
+			// Keep what the earlier pages found rather than dropping the whole scan
+			fmt.Printf("ES DEBUG: Stopping after %d documents, page request failed: %v\n", scanned, err)
+			return scanned, false, nil
+		}
+		if response.PitID != "" {
+			pitID = response.PitID
+		}
+
+		hits := response.Hits.Hits
+		for _, hit := range hits {
+			fn(hit.Source)
+		}
+		scanned += len(hits)
+
+		if len(hits) < size {
+			return scanned, false, nil
+		}
+		searchAfter = hits[len(hits)-1].Sort
+		if searchAfter == nil {
+			return scanned, false, nil
+		}
+	}
+	return scanned, true, nil
+}
+
+func (es *ElasticsearchClient) openPointInTime(ctx context.Context, indexPattern string) (string, error) {
+	req := esapi.OpenPointInTimeRequest{
+		Index:     []string{indexPattern},
+		KeepAlive: pitKeepAlive,
+	}
+	res, err := req.Do(ctx, es.client)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return "", fmt.Errorf("elasticsearch error: %s", res.String())
+	}
+
+	var pit struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&pit); err != nil {
+		return "", fmt.Errorf("failed to decode point in time: %w", err)
+	}
+	return pit.ID, nil
+}
+
+// closePointInTime releases the PIT right away instead of waiting for its keep-alive
+func (es *ElasticsearchClient) closePointInTime(id string) {
+	body, _ := json.Marshal(map[string]string{"id": id})
+	req := esapi.ClosePointInTimeRequest{Body: bytes.NewReader(body)}
+	res, err := req.Do(context.Background(), es.client)
+	if err == nil {
+		res.Body.Close()
+	}
+}
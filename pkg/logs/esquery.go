@@ -0,0 +1,376 @@
+package logs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp/syntax"
+	"strings"
+	"time"
+
+	"vigilant/pkg/config"
+)
+
+// patternQueries translates every pattern into an Elasticsearch query keyed by its label.
+// ok is false when any pattern can't be expressed, in which case the caller matches client-side.
+func patternQueries(patterns []config.LogPattern, field string) (queries map[string]interface{}, ok bool) {
+	queries = make(map[string]interface{})
+	for _, p := range patterns {
+		label := p.Name
+		if label == "" {
+			label = p.Label
+		}
+		if p.ESQuery != "" {
+			queries[label] = map[string]interface{}{
+				"query_string": map[string]interface{}{
+					"query":         p.ESQuery,
+					"default_field": field,
+				},
+			}
+			continue
+		}
+		q, ok := regexToQuery(p.Regex, field)
+		if !ok {
+			return nil, false
+		}
+		queries[label] = q
+	}
+	return queries, true
+}
+
+// excludeQueries translates the exclude patterns for a must_not clause; ok is false when
+// any of them can't be expressed
+func excludeQueries(excludes []string, field string) (queries []interface{}, ok bool) {
+	queries = []interface{}{}
+	for _, exclude := range excludes {
+		q, ok := regexToQuery(exclude, field)
+		if !ok {
+			return nil, false
+		}
+		queries = append(queries, q)
+	}
+	return queries, true
+}
+
+// regexToQuery translates the regexes that have a query equivalent: literals become
+// match_phrase, literals joined by .* become ordered intervals, and alternations of those
+// become a bool should. Matching is token based, so it is close to but not exactly the regex.
+func regexToQuery(regex, field string) (map[string]interface{}, bool) {
+	regex = stripOuterGroup(regex)
+
+	// Top-level branches are parsed one by one, since the parser factors out common prefixes
+	branches := splitTopLevelAlternation(regex)
+	if len(branches) > 1 {
+		var should []interface{}
+		for _, branch := range branches {
+			q, ok := regexToQuery(branch, field)
+			if !ok {
+				return nil, false
+			}
+			should = append(should, q)
+		}
+		return boolShould(should), true
+	}
+
+	re, err := syntax.Parse(regex, syntax.Perl)
+	if err != nil {
+		return nil, false
+	}
+	return translateRegex(re.Simplify(), field)
+}
+
+func translateRegex(re *syntax.Regexp, field string) (map[string]interface{}, bool) {
+	switch re.Op {
+	case syntax.OpCapture:
+		return translateRegex(re.Sub[0], field)
+	case syntax.OpLiteral:
+		return matchPhrase(field, literalText(re)), true
+	case syntax.OpAlternate:
+		var should []interface{}
+		for _, sub := range re.Sub {
+			q, ok := translateRegex(sub, field)
+			if !ok {
+				return nil, false
+			}
+			should = append(should, q)
+		}
+		return boolShould(should), true
+	case syntax.OpConcat:
+		// literal .* literal .* ... keeps the literals in order with anything in between
+		var phrases []string
+		for _, sub := range re.Sub {
+			if sub.Op == syntax.OpCapture {
+				sub = sub.Sub[0]
+			}
+			switch {
+			case sub.Op == syntax.OpLiteral:
+				phrases = append(phrases, literalText(sub))
+			case (sub.Op == syntax.OpStar || sub.Op == syntax.OpPlus) &&
+				(sub.Sub[0].Op == syntax.OpAnyChar || sub.Sub[0].Op == syntax.OpAnyCharNotNL):
+				continue
+			default:
+				return nil, false
+			}
+		}
+		if len(phrases) == 0 {
+			return nil, false
+		}
+		if len(phrases) == 1 {
+			return matchPhrase(field, phrases[0]), true
+		}
+		var intervals []interface{}
+		for _, phrase := range phrases {
+			intervals = append(intervals, map[string]interface{}{
+				"match": map[string]interface{}{"query": phrase, "ordered": true, "max_gaps": 0},
+			})
+		}
+		return map[string]interface{}{
+			"intervals": map[string]interface{}{
+				field: map[string]interface{}{
+					"all_of": map[string]interface{}{"ordered": true, "intervals": intervals},
+				},
+			},
+		}, true
+	}
+	return nil, false
+}
+
+// literalText returns a literal's text, lowercased when it is case-insensitive
+func literalText(re *syntax.Regexp) string {
+	if re.Flags&syntax.FoldCase != 0 {
+		return strings.ToLower(string(re.Rune))
+	}
+	return string(re.Rune)
+}
+
+// stripOuterGroup turns "(?i)(a|b)" into "(?i)a|b" so the branches inside can be split
+func stripOuterGroup(regex string) string {
+	flags := ""
+	if strings.HasPrefix(regex, "(?") {
+		if end := strings.IndexByte(regex, ')'); end > 0 && !strings.Contains(regex[:end], ":") {
+			flags, regex = regex[:end+1], regex[end+1:]
+		}
+	}
+	if !strings.HasPrefix(regex, "(") || strings.HasPrefix(regex, "(?") || !strings.HasSuffix(regex, ")") {
+		return flags + regex
+	}
+	// The opening parenthesis must close at the very end, not e.g. in "(a)|(b)"
+	depth := 0
+	for i := 0; i < len(regex); i++ {
+		switch regex[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 && i != len(regex)-1 {
+				return flags + regex
+			}
+		}
+	}
+	return flags + regex[1:len(regex)-1]
+}
+
+// splitTopLevelAlternation splits regex at | outside groups, character classes and escapes
+func splitTopLevelAlternation(regex string) []string {
+	var branches []string
+	depth, inClass, start := 0, false, 0
+	for i := 0; i < len(regex); i++ {
+		switch c := regex[i]; {
+		case c == '\\':
+			i++
+		case inClass:
+			if c == ']' {
+				inClass = false
+			}
+		case c == '[':
+			inClass = true
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == '|' && depth == 0:
+			branches = append(branches, regex[start:i])
+			start = i + 1
+		}
+	}
+	branches = append(branches, regex[start:])
+
+	// A leading flag group such as (?i) applies to every branch
+	if len(branches) > 1 && strings.HasPrefix(branches[0], "(?") {
+		if end := strings.IndexByte(branches[0], ')'); end > 0 && !strings.Contains(branches[0][:end], ":") {
+			flags := branches[0][:end+1]
+			for i := 1; i < len(branches); i++ {
+				branches[i] = flags + branches[i]
+			}
+		}
+	}
+	return branches
+}
+
+func matchPhrase(field, phrase string) map[string]interface{} {
+	return map[string]interface{}{
+		"match_phrase": map[string]interface{}{field: strings.TrimSpace(phrase)},
+	}
+}
+
+func boolShould(should []interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"bool": map[string]interface{}{"should": should, "minimum_should_match": 1},
+	}
+}
+
+// maxServiceBuckets bounds how many services one aggregation reports
+const maxServiceBuckets = 200
+
+// esMissingService is the terms key of documents that lack the service field
+const esMissingService = "__missing__"
+
+// esPatternBuckets is the per-pattern filters aggregation with its last_seen date and
+// newest sample documents
+type esPatternBuckets struct {
+	Buckets map[string]struct {
+		DocCount int `json:"doc_count"`
+		LastSeen struct {
+			Value *float64 `json:"value"`
+		} `json:"last_seen"`
+		Samples struct {
+			Hits struct {
+				Hits []struct {
+					Source json.RawMessage `json:"_source"`
+				} `json:"hits"`
+			} `json:"hits"`
+		} `json:"samples"`
+	} `json:"buckets"`
+}
+
+// esServiceBuckets is one level of the service terms aggregation; documents missing the field
+// are broken down by the next service field, if any
+type esServiceBuckets struct {
+	Buckets []struct {
+		Key      string            `json:"key"`
+		Patterns esPatternBuckets  `json:"patterns"`
+		Next     *esServiceBuckets `json:"next"`
+	} `json:"buckets"`
+}
+
+// serviceTermsAggregation nests a terms aggregation per service field, each bucket counting
+// every pattern with its last occurrence and newest messages
+func serviceTermsAggregation(fields []string, queries map[string]interface{}, messageField string) map[string]interface{} {
+	aggs := map[string]interface{}{
+		"patterns": map[string]interface{}{
+			"filters": map[string]interface{}{"filters": queries},
+			"aggs": map[string]interface{}{
+				"last_seen": map[string]interface{}{"max": map[string]interface{}{"field": "@timestamp"}},
+				"samples": map[string]interface{}{
+					"top_hits": map[string]interface{}{
+						"size":    maxSamples,
+						"sort":    []interface{}{map[string]interface{}{"@timestamp": "desc"}},
+						"_source": map[string]interface{}{"includes": []string{messageField}},
+					},
+				},
+			},
+		},
+	}
+	if len(fields) > 1 {
+		aggs["next"] = serviceTermsAggregation(fields[1:], queries, messageField)
+	}
+	return map[string]interface{}{
+		"terms": map[string]interface{}{
+			"field":   fields[0],
+			"size":    maxServiceBuckets,
+			"missing": esMissingService,
+		},
+		"aggs": aggs,
+	}
+}
+
+// aggregateSymptoms counts each pattern's matches and last occurrence per service in
+// Elasticsearch itself, instead of downloading raw documents. Services are read from the
+// keyword fields in serviceFields, in order, and normalized like the client-side path.
+func (es *ElasticsearchClient) aggregateSymptoms(ctx context.Context, indexPattern string, serviceFields []string, messageField string,
+	queries map[string]interface{}, excludes []interface{}, timeRange time.Duration, namespaceFilter string) ([]SymptomMatch, error) {
+	window := buildQueryWithNamespace(timeRange, 0, namespaceFilter)
+
+	query := map[string]interface{}{
+		"size": 0,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter":   window["query"],
+				"must_not": excludes,
+			},
+		},
+		"aggs": map[string]interface{}{
+			"services": serviceTermsAggregation(serviceFields, queries, messageField),
+		},
+	}
+
+	response, err := es.searchPage(ctx, indexPattern, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate symptoms: %w", err)
+	}
+	var aggs struct {
+		Services esServiceBuckets `json:"services"`
+	}
+	if err := json.Unmarshal(response.Aggregations, &aggs); err != nil {
+		return nil, fmt.Errorf("failed to decode aggregations: %w", err)
+	}
+
+	matches := map[string]*SymptomMatch{}
+	es.collectServiceBuckets(aggs.Services, matches, messageField)
+
+	var result []SymptomMatch
+	for _, v := range matches {
+		result = append(result, *v)
+	}
+	fmt.Printf("ES DEBUG: Aggregated %d patterns server-side into %d symptoms in index %s\n",
+		len(queries), len(result), indexPattern)
+	return result, nil
+}
+
+// collectServiceBuckets merges the pattern counts of every service bucket into matches
+func (es *ElasticsearchClient) collectServiceBuckets(services esServiceBuckets, matches map[string]*SymptomMatch, messageField string) {
+	for _, bucket := range services.Buckets {
+		service := "unknown"
+		switch {
+		case bucket.Key == esMissingService && bucket.Next != nil:
+			es.collectServiceBuckets(*bucket.Next, matches, messageField)
+			continue
+		case bucket.Key == esMissingService:
+		case es.serviceMapping == nil:
+			service = bucket.Key
+		default:
+			service = es.serviceMapping.normalizeServiceName(bucket.Key)
+		}
+
+		for label, p := range bucket.Patterns.Buckets {
+			if p.DocCount == 0 {
+				continue
+			}
+			lastSeen := time.Now()
+			if p.LastSeen.Value != nil {
+				lastSeen = time.UnixMilli(int64(*p.LastSeen.Value))
+			}
+			// Several raw names (e.g. pod containers) can normalize to the same service
+			key := service + "::" + label
+			m, exists := matches[key]
+			if !exists {
+				m = &SymptomMatch{Service: service, Pattern: label, LastSeen: lastSeen}
+				matches[key] = m
+			}
+			m.Count += p.DocCount
+			if lastSeen.After(m.LastSeen) {
+				m.LastSeen = lastSeen
+			}
+			// top_hits returns the newest first
+			hits := p.Samples.Hits.Hits
+			for i := len(hits) - 1; i >= 0; i-- {
+				entry, err := decodeLogEntry(hits[i].Source, config.FieldMappings{MessageField: messageField})
+				if err == nil && entry.Message != "" {
+					m.Samples = appendRecent(m.Samples, entry.Message)
+				}
+			}
+		}
+	}
+}
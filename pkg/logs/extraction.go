@@ -0,0 +1,72 @@
+package logs
+
+import (
+	"fmt"
+	"regexp"
+
+	"vigilant/pkg/config"
+)
+
+// serviceRules are a profile's compiled service_extraction rules
+type serviceRules struct {
+	fields    []string
+	regex     *regexp.Regexp
+	overrides map[string]string
+}
+
+// newServiceRules compiles the rules, or returns nil when none are configured
+func newServiceRules(cfg config.ServiceExtractionConfig) *serviceRules {
+	if !cfg.Enabled() {
+		return nil
+	}
+	rules := &serviceRules{fields: cfg.Fields, overrides: cfg.Overrides}
+	if cfg.Regex != "" {
+		re, err := regexp.Compile(cfg.Regex)
+		if err != nil {
+			fmt.Printf("Invalid service_extraction regex, ignoring it: %v\n", err)
+		} else {
+			rules.regex = re
+		}
+	}
+	return rules
+}
+
+// rawService returns the name the fields or the regex give, or "" when neither does
+func (r *serviceRules) rawService(log ESLogEntry) string {
+	for _, field := range r.fields {
+		if value := nestedFieldString(log.doc, field); value != "" {
+			return value
+		}
+	}
+	if r.regex != nil {
+		if match := r.regex.FindStringSubmatch(log.Message); match != nil {
+			if len(match) > 1 {
+				return match[1]
+			}
+			return match[0]
+		}
+	}
+	return ""
+}
+
+// resolveService attributes a log entry to a service by the profile's rules (nil for none),
+// falling back to the name heuristics. Overrides map the extracted name directly; other names
+// are normalized against the configured services.
+func (sm *ServiceMapping) resolveService(rules *serviceRules, log ESLogEntry) string {
+	raw := ""
+	if rules != nil {
+		raw = rules.rawService(log)
+	}
+	if raw == "" {
+		raw = rawServiceName(log)
+	}
+	if raw == "" {
+		return "unknown"
+	}
+	if rules != nil {
+		if service, ok := rules.overrides[raw]; ok {
+			return service
+		}
+	}
+	return sm.normalizeServiceName(raw)
+}
@@ -0,0 +1,68 @@
+package logs
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"vigilant/pkg/config"
+)
+
+// defaultFieldMappings are the fields used when a profile has no field_mappings
+var defaultFieldMappings = config.FieldMappings{
+	MessageField:   "message",
+	LevelField:     "level",
+	ServiceField:   "service",
+	ContainerField: "container",
+}
+
+// withDefaults fills the unset fields of f from defaultFieldMappings
+func withDefaults(f config.FieldMappings) config.FieldMappings {
+	if f.MessageField == "" {
+		f.MessageField = defaultFieldMappings.MessageField
+	}
+	if f.LevelField == "" {
+		f.LevelField = defaultFieldMappings.LevelField
+	}
+	if f.ServiceField == "" {
+		f.ServiceField = defaultFieldMappings.ServiceField
+	}
+	if f.ContainerField == "" {
+		f.ContainerField = defaultFieldMappings.ContainerField
+	}
+	return f
+}
+
+// decodeLogEntry reads a structured log document (an ES _source or a JSON log line)
+// through the profile's field mappings
+func decodeLogEntry(source []byte, fields config.FieldMappings) (ESLogEntry, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(source, &doc); err != nil {
+		return ESLogEntry{}, err
+	}
+	fields = withDefaults(fields)
+
+	entry := ESLogEntry{
+		Message:   nestedFieldString(doc, fields.MessageField),
+		Level:     nestedFieldString(doc, fields.LevelField),
+		Service:   nestedFieldString(doc, fields.ServiceField),
+		Container: nestedFieldString(doc, fields.ContainerField),
+		doc:       doc,
+	}
+	if ts, err := time.Parse(time.RFC3339Nano, nestedFieldString(doc, "@timestamp")); err == nil {
+		entry.Timestamp = ts
+	}
+	return entry, nil
+}
+
+// parseJSONLine decodes a log file line that is a JSON object; ok is false for plain text
+func parseJSONLine(line string, fields config.FieldMappings) (entry ESLogEntry, ok bool) {
+	if !strings.HasPrefix(strings.TrimSpace(line), "{") {
+		return ESLogEntry{}, false
+	}
+	entry, err := decodeLogEntry([]byte(line), fields)
+	if err != nil || entry.Message == "" {
+		return ESLogEntry{}, false
+	}
+	return entry, true
+}
@@ -0,0 +1,253 @@
+package logs
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fingerprintSize is how many leading bytes identify a file across rotations
+const fingerprintSize = 256
+
+// timedLine is a log line with the time it was written (or read, for plain files)
+type timedLine struct {
+	Text string
+	Time time.Time
+}
+
+// tailLines returns up to n complete lines from the end of the file
+func tailLines(path string, n int) ([]string, error) {
+	if strings.HasSuffix(path, ".gz") {
+		return tailCompressed(path, n)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read backwards in blocks until the tail holds n line breaks (plus the one before them)
+	const blockSize = 64 * 1024
+	end := info.Size()
+	var tail []byte
+	for end > 0 && bytes.Count(tail, []byte("\n")) <= n {
+		start := end - blockSize
+		if start < 0 {
+			start = 0
+		}
+		block := make([]byte, end-start)
+		if _, err := file.ReadAt(block, start); err != nil && err != io.EOF {
+			return nil, err
+		}
+		tail = append(block, tail...)
+		end = start
+	}
+
+	lines := bytes.Split(bytes.TrimRight(tail, "\n"), []byte("\n"))
+	if end > 0 && len(lines) > 0 {
+		lines = lines[1:] // The first line is cut off
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		result = append(result, strings.TrimRight(string(line), "\r"))
+	}
+	return result, nil
+}
+
+// fileOffset is where following a file left off, with the fingerprint of the file it
+// belongs to so rotations can be detected
+type fileOffset struct {
+	Offset      int64  `json:"offset"`
+	Fingerprint string `json:"fingerprint"`
+	PrintSize   int    `json:"fingerprint_size"`
+}
+
+// fileFollower reads files incrementally from persisted offsets and keeps the recently read
+// lines of each file in memory
+type fileFollower struct {
+	mu        sync.Mutex
+	statePath string
+	offsets   map[string]fileOffset
+	buffers   map[string][]timedLine
+}
+
+func newFileFollower(statePath string) *fileFollower {
+	f := &fileFollower{
+		statePath: statePath,
+		offsets:   make(map[string]fileOffset),
+		buffers:   make(map[string][]timedLine),
+	}
+	if statePath == "" {
+		return f
+	}
+	if data, err := os.ReadFile(statePath); err == nil {
+		if err := json.Unmarshal(data, &f.offsets); err != nil {
+			fmt.Printf("Ignoring unreadable file offsets in %s: %v\n", statePath, err)
+		}
+	}
+	return f
+}
+
+// fingerprint hashes up to size leading bytes of the file
+func fingerprint(file *os.File, size int) (string, int, error) {
+	buf := make([]byte, size)
+	n, err := file.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return "", 0, err
+	}
+	sum := sha256.Sum256(buf[:n])
+	return hex.EncodeToString(sum[:]), n, nil
+}
+
+// Read reads the lines appended to path since the last call, keeps the newest maxLines in
+// memory and returns the buffered lines read within window
+func (f *fileFollower) Read(path string, maxLines int, window time.Duration) ([]timedLine, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	state, known := f.offsets[path]
+
+	if !known {
+		// A file seen for the first time starts from its last maxLines lines, not from the top
+		tail, err := tailLines(path, maxLines)
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		for _, text := range tail {
+			f.buffers[path] = append(f.buffers[path], timedLine{Text: text, Time: now})
+		}
+		state.Offset = info.Size()
+	} else {
+		current, _, err := fingerprint(file, state.PrintSize)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case current != state.Fingerprint:
+			// Rotated: finish the previous file if it was renamed to path.1, then start over
+			f.readRotated(path, state, maxLines)
+			fmt.Printf("FILE DEBUG: %s was rotated, reading from the start\n", path)
+			state = fileOffset{}
+		case info.Size() < state.Offset:
+			fmt.Printf("FILE DEBUG: %s was truncated, reading from the start\n", path)
+			state = fileOffset{}
+		}
+	}
+
+	read, offset, err := readFrom(file, state.Offset)
+	if err != nil {
+		return nil, err
+	}
+	f.buffer(path, read, maxLines)
+
+	state.Offset = offset
+	if state.PrintSize < fingerprintSize {
+		// Grow the fingerprint while the file is still shorter than fingerprintSize
+		if state.Fingerprint, state.PrintSize, err = fingerprint(file, fingerprintSize); err != nil {
+			return nil, err
+		}
+	}
+	f.offsets[path] = state
+	f.save()
+
+	cutoff := time.Now().Add(-window)
+	var recent []timedLine
+	for _, line := range f.buffers[path] {
+		if !line.Time.Before(cutoff) {
+			recent = append(recent, line)
+		}
+	}
+	return recent, nil
+}
+
+// readRotated buffers the rest of path.1 (or path.1.gz, when compressed right away) when it
+// is the file path was rotated from
+func (f *fileFollower) readRotated(path string, state fileOffset, maxLines int) {
+	for _, rotated := range []string{path + ".1", path + ".1.gz"} {
+		if read, ok := readRotatedRest(rotated, state); ok {
+			f.buffer(path, read, maxLines)
+			return
+		}
+	}
+}
+
+func (f *fileFollower) buffer(path string, lines []timedLine, maxLines int) {
+	buffered := append(f.buffers[path], lines...)
+	if len(buffered) > maxLines {
+		buffered = buffered[len(buffered)-maxLines:]
+	}
+	f.buffers[path] = buffered
+}
+
+// readFrom reads the complete lines after offset, returning the offset after the last one
+func readFrom(file *os.File, offset int64) ([]timedLine, int64, error) {
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, offset, err
+	}
+	now := time.Now()
+	reader := bufio.NewReaderSize(file, 64*1024)
+	var lines []timedLine
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// A partial last line is read again once it is complete
+			if err == io.EOF {
+				return lines, offset, nil
+			}
+			return lines, offset, err
+		}
+		offset += int64(len(line))
+		lines = append(lines, timedLine{Text: strings.TrimRight(line, "\r\n"), Time: now})
+	}
+}
+
+// save persists the offsets so a restart resumes where the last scan stopped
+func (f *fileFollower) save() {
+	if f.statePath == "" {
+		return
+	}
+	data, err := json.Marshal(f.offsets)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(f.statePath), 0755); err != nil {
+		fmt.Printf("Error saving file offsets: %v\n", err)
+		return
+	}
+	tmp := f.statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		fmt.Printf("Error saving file offsets: %v\n", err)
+		return
+	}
+	if err := os.Rename(tmp, f.statePath); err != nil {
+		fmt.Printf("Error saving file offsets: %v\n", err)
+	}
+}
@@ -1,14 +1,15 @@
 package logs
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/elastic/go-elasticsearch/v8"
@@ -20,8 +21,10 @@ import (
 type SymptomMatch struct {
 	Service  string
 	Pattern  string
+	Severity string // The pattern's configured severity, set when symptoms are correlated
 	Count    int
 	LastSeen time.Time
+	Samples  []string // The first and last few matched lines (see maxSamples)
 }
 
 // PatternDef defines a symptom label and regex
@@ -33,6 +36,14 @@ type PatternDef struct {
 // ElasticsearchClient wraps the ES client with our methods
 type ElasticsearchClient struct {
 	client *elasticsearch.Client
+
+	// Set for OpenSearch clusters, see NewOpenSearchClient
+	openSearch *openSearchTransport
+
+	// Used by ScanSymptoms, see SetDefaults
+	defaultIndexPattern string
+	defaultMaxDocuments int
+	serviceMapping      *ServiceMapping
 }
 
 // NewElasticsearchClient creates a new ES client
@@ -49,22 +60,104 @@ func NewElasticsearchClient(addresses []string) (*ElasticsearchClient, error) {
 	return &ElasticsearchClient{client: client}, nil
 }
 
+// SetDefaults sets the index pattern and document budget used when a profile has none, and
+// the mapping used to attribute log entries to services in ScanSymptoms
+func (es *ElasticsearchClient) SetDefaults(indexPattern string, maxDocuments int, serviceMapping *ServiceMapping) {
+	es.defaultIndexPattern = indexPattern
+	es.defaultMaxDocuments = maxDocuments
+	es.serviceMapping = serviceMapping
+}
+
+func (es *ElasticsearchClient) Name() string { return "elasticsearch" }
+
+// ScanSymptoms scans the profile's index over the window (the profile's time_range_minutes,
+// 10 minutes by default, when window is 0), filtered to its namespace
+func (es *ElasticsearchClient) ScanSymptoms(ctx context.Context, profile config.ServiceProfile, window time.Duration) ([]SymptomMatch, error) {
+	esConfig := profile.GetEffectiveElasticsearchConfig()
+
+	indexPattern := esConfig.IndexPattern
+	if indexPattern == "" {
+		indexPattern = es.defaultIndexPattern
+	}
+
+	scanLimit := esConfig.ScanLimit
+	if scanLimit == 0 {
+		scanLimit = 500 // default
+	}
+
+	maxDocuments := esConfig.MaxDocuments
+	if maxDocuments == 0 {
+		maxDocuments = es.defaultMaxDocuments
+	}
+	if maxDocuments < scanLimit {
+		maxDocuments = scanLimit
+	}
+
+	if window <= 0 {
+		timeRangeMin := esConfig.TimeRangeMinutes
+		if timeRangeMin == 0 && esConfig.TimeRangeMin > 0 {
+			timeRangeMin = esConfig.TimeRangeMin // backward compatibility
+		}
+		if timeRangeMin == 0 {
+			timeRangeMin = 10 // default
+		}
+		window = time.Duration(timeRangeMin) * time.Minute
+	}
+
+	fields := withDefaults(profile.DataSources.Fields)
+
+	// Server-side matching only works when every pattern has a query equivalent; otherwise,
+	// or when the aggregation fails, the documents are matched client-side. Aggregations count
+	// single documents, so multi-line merging also needs the client-side path, and so do
+	// service_extraction rules, which read whole documents.
+	if esConfig.MatchMode == "server" && !profile.DataSources.Multiline.Enabled() && !profile.DataSources.ServiceExtraction.Enabled() {
+		queries, ok := patternQueries(profile.LogPatterns, fields.MessageField)
+		excludes, excludesOK := excludeQueries(profile.ExcludePatterns, fields.MessageField)
+		if ok && excludesOK {
+			fmt.Printf("ES aggregation for %s: index=%s, patterns=%d, time=%s, namespace=%s\n",
+				profile.Metadata.Name, indexPattern, len(queries), window, esConfig.NamespaceFilter)
+			serviceFields := esConfig.ServiceFields
+			if len(serviceFields) == 0 {
+				serviceFields = []string{fields.ServiceField + ".keyword", fields.ContainerField + ".keyword"}
+			}
+			symptoms, err := es.aggregateSymptoms(ctx, indexPattern, serviceFields, fields.MessageField, queries, excludes, window, esConfig.NamespaceFilter)
+			if err == nil {
+				return symptoms, nil
+			}
+			fmt.Printf("ES DEBUG: Aggregation failed for %s, matching client-side: %v\n", profile.Metadata.Name, err)
+		} else {
+			fmt.Printf("ES DEBUG: Some patterns of %s have no query equivalent, matching client-side\n", profile.Metadata.Name)
+		}
+	}
+
+	fmt.Printf("ES scan for %s: index=%s, page=%d, budget=%d, time=%s, namespace=%s\n",
+		profile.Metadata.Name, indexPattern, scanLimit, maxDocuments, window, esConfig.NamespaceFilter)
+
+	return es.scanWithFilter(ctx, indexPattern, scanLimit, maxDocuments, profile, window, es.serviceMapping, esConfig.NamespaceFilter)
+}
+
 // ESLogEntry represents a log entry from Elasticsearch
 type ESLogEntry struct {
 	Timestamp time.Time `json:"@timestamp"`
 	Message   string    `json:"message"`
+	Level     string    `json:"level,omitempty"`
 	Service   string    `json:"service,omitempty"`
 	Container string    `json:"container,omitempty"`
+
+	doc map[string]interface{} // The decoded document, for service_extraction fields
 }
 
 // ESSearchResponse represents the Elasticsearch search response
 type ESSearchResponse struct {
-	Hits struct {
+	PitID        string          `json:"pit_id,omitempty"`
+	Aggregations json.RawMessage `json:"aggregations,omitempty"`
+	Hits         struct {
 		Total struct {
 			Value int `json:"value"`
 		} `json:"total"`
 		Hits []struct {
-			Source ESLogEntry `json:"_source"`
+			Source json.RawMessage `json:"_source"` // Decoded with the profile's field mappings
+			Sort   []interface{}   `json:"sort,omitempty"`
 		} `json:"hits"`
 	} `json:"hits"`
 }
@@ -89,83 +182,93 @@ func (es *ElasticsearchClient) ScanLogsAndMatchSymptomsWithFilter(
 	serviceMapping *ServiceMapping,
 	namespaceFilter string,
 ) ([]SymptomMatch, error) {
-	
-	// Compile regex patterns
-	compiled := []PatternDef{}
-	for _, p := range patterns {
-		re, err := regexp.Compile(p.Regex)
-		if err != nil {
-			continue
-		}
-		compiled = append(compiled, PatternDef{
-			Label: p.Label,
-			Regex: re,
-		})
-	}
+	return es.scanWithFilter(context.Background(), indexPattern, limit, limit, config.ServiceProfile{LogPatterns: patterns},
+		timeRange, serviceMapping, namespaceFilter)
+}
 
-	// Build Elasticsearch query
-	query := buildQueryWithNamespace(timeRange, limit, namespaceFilter)
-	
-	// Execute search
-	logs, err := es.searchLogs(indexPattern, query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to search logs: %w", err)
-	}
+// scanWithFilter pages through the window pageSize documents at a time (see scanPages)
+// until maxDocuments have been matched or the window is exhausted, matching them with the
+// profile's patterns, exclusions, field mappings and multi-line settings
+func (es *ElasticsearchClient) scanWithFilter(
+	ctx context.Context,
+	indexPattern string,
+	pageSize int,
+	maxDocuments int,
+	profile config.ServiceProfile,
+	timeRange time.Duration,
+	serviceMapping *ServiceMapping,
+	namespaceFilter string,
+) ([]SymptomMatch, error) {
 	
-	fmt.Printf("ES DEBUG: Found %d logs in index %s\n", len(logs), indexPattern)
+	// Build Elasticsearch query
+	query := buildQueryWithNamespace(timeRange, pageSize, namespaceFilter)
 
-	// Process logs and match patterns
-	matches := map[string]*SymptomMatch{}
+	// Process logs and match patterns, merging multi-line events per service first
+	counter := newSymptomCounter(profile)
+	merger := newMultilineMerger(profile.DataSources.Multiline, true, counter.Add)
+	fields := profile.DataSources.Fields
+	rules := newServiceRules(profile.DataSources.ServiceExtraction)
 	serviceCount := make(map[string]int)
 	
-	for _, log := range logs {
-		service := serviceMapping.extractServiceFromLog(log)
-		serviceCount[service]++
-		
-		for _, p := range compiled {
-			if p.Regex.MatchString(log.Message) {
-				key := service + "::" + p.Label
-				if _, exists := matches[key]; !exists {
-					matches[key] = &SymptomMatch{
-						Service:  service,
-						Pattern:  p.Label,
-						Count:    1,
-						LastSeen: log.Timestamp,
-					}
-				} else {
-					matches[key].Count++
-					if log.Timestamp.After(matches[key].LastSeen) {
-						matches[key].LastSeen = log.Timestamp
-					}
-				}
-			}
+	scanned, truncated, err := es.scanPages(ctx, indexPattern, query, pageSize, maxDocuments, func(source json.RawMessage) {
+		log, err := decodeLogEntry(source, fields)
+		if err != nil {
+			return
 		}
+		service := serviceMapping.resolveService(rules, log)
+		serviceCount[service]++
+		merger.Add(service, log.Message, log.Timestamp)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search logs: %w", err)
 	}
+	merger.Flush()
 	
+	fmt.Printf("ES DEBUG: Found %d logs in index %s\n", scanned, indexPattern)
+	if truncated {
+		fmt.Printf("ES DEBUG: Document budget of %d reached, older logs in the window were not scanned\n", maxDocuments)
+	}
 	fmt.Printf("ES DEBUG: Service distribution: %v\n", serviceCount)
 
-	// Convert map to slice
-	var result []SymptomMatch
-	for _, v := range matches {
-		result = append(result, *v)
+	return counter.Results(), nil
+}
+
+// searchLogs executes the Elasticsearch query
+func (es *ElasticsearchClient) searchLogs(ctx context.Context, indexPattern string, query map[string]interface{}) ([]ESLogEntry, error) {
+	response, err := es.searchPage(ctx, indexPattern, query)
+	if err != nil {
+		return nil, err
 	}
 
-	return result, nil
+	var logs []ESLogEntry
+	for _, hit := range response.Hits.Hits {
+		if log, err := decodeLogEntry(hit.Source, defaultFieldMappings); err == nil {
+			logs = append(logs, log)
+		}
+	}
+
+	return logs, nil
 }
 
-// searchLogs executes the Elasticsearch query
-func (es *ElasticsearchClient) searchLogs(indexPattern string, query map[string]interface{}) ([]ESLogEntry, error) {
+// searchPage executes one search request; queries against a point in time carry no index
+func (es *ElasticsearchClient) searchPage(ctx context.Context, indexPattern string, query map[string]interface{}) (*ESSearchResponse, error) {
+	if es.openSearch != nil {
+		return es.openSearch.search(ctx, indexPattern, query)
+	}
+
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(query); err != nil {
 		return nil, fmt.Errorf("failed to encode query: %w", err)
 	}
 
 	req := esapi.SearchRequest{
-		Index: []string{indexPattern},
-		Body:  &buf,
+		Body: &buf,
+	}
+	if _, hasPIT := query["pit"]; !hasPIT {
+		req.Index = []string{indexPattern}
 	}
 
-	res, err := req.Do(context.Background(), es.client)
+	res, err := req.Do(ctx, es.client)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute search: %w", err)
 	}
@@ -179,13 +282,7 @@ func (es *ElasticsearchClient) searchLogs(indexPattern string, query map[string]
 	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
-
-	var logs []ESLogEntry
-	for _, hit := range response.Hits.Hits {
-		logs = append(logs, hit.Source)
-	}
-
-	return logs, nil
+	return &response, nil
 }
 
 // buildQuery creates the Elasticsearch query (backward compatibility)
@@ -263,59 +360,227 @@ func buildQueryWithNamespace(timeRange time.Duration, limit int, namespaceFilter
 
 // Original file-based scanning function (kept for backward compatibility and fallback)
 func ScanLogsAndMatchSymptoms(logFilePath string, limit int, patterns []config.LogPattern) ([]SymptomMatch, error) {
-	file, err := os.Open(logFilePath)
+	return scanLogFile(logFilePath, limit, config.ServiceProfile{LogPatterns: patterns})
+}
+
+// scanLogFile matches the first limit lines of the file
+func scanLogFile(logFilePath string, limit int, profile config.ServiceProfile) ([]SymptomMatch, error) {
+	lines, err := headLines(logFilePath, limit)
+	if err != nil {
+		return nil, err
+	}
+	counter := newSymptomCounter(profile)
+	matchFileLines(counter, lines, profile, 0)
+	return counter.Results(), nil
+}
+
+// headLines returns the first limit lines of the file
+func headLines(logFilePath string, limit int) ([]timedLine, error) {
+	file, err := openLogFile(logFilePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open log file: %w", err)
+		return nil, err
 	}
 	defer file.Close()
 
-	matches := map[string]*SymptomMatch{}
-	scanner := bufio.NewScanner(file)
-	linesScanned := 0
-	compiled := []PatternDef{}
+	var lines []timedLine
+	scanner := newLineScanner(file)
+	for scanner.Scan() {
+		if limit > 0 && len(lines) >= limit {
+			break
+		}
+		lines = append(lines, timedLine{Text: scanner.Text(), Time: time.Now()})
+	}
+	return lines, nil
+}
 
-	for _, p := range patterns {
-		re, err := regexp.Compile(p.Regex)
+// matchFileLines adds the lines of one file to counter, merging multi-line events first.
+// JSON lines are read through the profile's field mappings and plain lines through its
+// timestamp config; lines logged before the window are skipped (window 0 keeps every line).
+func matchFileLines(counter *symptomCounter, lines []timedLine, profile config.ServiceProfile, window time.Duration) {
+	// Plain text is one stream whose service comes from the first line of each event;
+	// JSON lines name their service
+	rules := newServiceRules(profile.DataSources.ServiceExtraction)
+	mapping := &ServiceMapping{ConfiguredServices: make(map[string]bool)}
+	merger := newMultilineMerger(profile.DataSources.Multiline, false, func(service, event string, ts time.Time) {
+		switch {
+		case service != "":
+		case rules != nil:
+			service = mapping.resolveService(rules, ESLogEntry{Message: event})
+		default:
+			service = extractService(event)
+		}
+		counter.Add(service, event, ts)
+	})
+	parser := newTimestampParser(profile.DataSources.Timestamp)
+	var cutoff time.Time
+	if window > 0 {
+		cutoff = time.Now().Add(-window)
+	}
+
+	// Lines without a timestamp (e.g. stack trace frames) belong to the last line that had one
+	var logged time.Time
+	for _, line := range lines {
+		entry, isJSON := parseJSONLine(line.Text, profile.DataSources.Fields)
+		switch {
+		case isJSON && !entry.Timestamp.IsZero():
+			logged = entry.Timestamp
+		case !isJSON && parser != nil:
+			if ts, ok := parser.Parse(line.Text); ok {
+				logged = ts
+			}
+		}
+		ts := line.Time
+		if !logged.IsZero() {
+			ts = logged
+			if ts.Before(cutoff) {
+				continue
+			}
+		}
+
+		if isJSON {
+			merger.Add(mapping.resolveService(rules, entry), entry.Message, ts)
+			continue
+		}
+		merger.Add("", line.Text, ts)
+	}
+	merger.Flush()
+}
+
+// FileSource scans the profile's local log files
+type FileSource struct {
+	follower *fileFollower
+}
+
+// NewFileSource creates the file log source; follow mode persists its read offsets to
+// offsetsFile (not persisted when empty)
+func NewFileSource(offsetsFile string) *FileSource {
+	return &FileSource{follower: newFileFollower(offsetsFile)}
+}
+
+func (f *FileSource) Name() string { return "file" }
+
+// ScanSymptoms scans every log file of the profile according to its log_file_mode: the last
+// scan_limit lines (tail), the lines appended within the window (follow), or the first
+// scan_limit lines (head). Symptoms are aggregated per service across the files.
+func (f *FileSource) ScanSymptoms(ctx context.Context, profile config.ServiceProfile, window time.Duration) ([]SymptomMatch, error) {
+	if len(profile.GetEffectiveLogFiles()) == 0 {
+		return nil, fmt.Errorf("no log file configured for service %s", profile.Metadata.Name)
+	}
+	scanLimit := profile.GetEffectiveElasticsearchConfig().ScanLimit
+	if scanLimit == 0 {
+		scanLimit = 500 // default
+	}
+	if window <= 0 {
+		window = 15 * time.Minute
+	}
+
+	paths, err := expandLogFiles(profile.GetEffectiveLogFiles(), window)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no log file of service %s was modified within %s", profile.Metadata.Name, window)
+	}
+
+	counter := newSymptomCounter(profile)
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		lines, err := f.readLines(path, profile.DataSources.LogFileMode, scanLimit, window)
 		if err != nil {
+			// One unreadable file shouldn't hide the symptoms of the others
+			if len(paths) == 1 {
+				return nil, err
+			}
+			fmt.Printf("FILE DEBUG: skipping %s: %v\n", path, err)
 			continue
 		}
-		compiled = append(compiled, PatternDef{
-			Label: p.Label,
-			Regex: re,
-		})
+		matchFileLines(counter, lines, profile, window)
 	}
+	fmt.Printf("FILE DEBUG: Scanned %d log files for %s\n", len(paths), profile.Metadata.Name)
+	return counter.Results(), nil
+}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		linesScanned++
-		if limit > 0 && linesScanned > limit {
-			break
+// readLines reads the lines of one file that a scan in mode covers
+func (f *FileSource) readLines(path, mode string, scanLimit int, window time.Duration) ([]timedLine, error) {
+	switch mode {
+	case "head":
+		return headLines(path, scanLimit)
+	case "follow":
+		lines, err := f.follower.Read(path, scanLimit, window)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("FILE DEBUG: %d lines of %s within %s\n", len(lines), path, window)
+		return lines, nil
+	default:
+		tail, err := tailLines(path, scanLimit)
+		if err != nil {
+			return nil, err
+		}
+		// A file rotated shortly before the scan holds few lines; the rest of the window is
+		// in its rotations
+		for _, rotated := range rotatedFiles(path, time.Now().Add(-window)) {
+			if len(tail) >= scanLimit {
+				break
+			}
+			older, err := tailLines(rotated, scanLimit-len(tail))
+			if err != nil {
+				fmt.Printf("FILE DEBUG: skipping rotated %s: %v\n", rotated, err)
+				continue
+			}
+			tail = append(older, tail...)
 		}
+		now := time.Now()
+		lines := make([]timedLine, 0, len(tail))
+		for _, text := range tail {
+			lines = append(lines, timedLine{Text: text, Time: now})
+		}
+		return lines, nil
+	}
+}
 
-		service := extractService(line)
-		for _, p := range compiled {
-			if p.Regex.MatchString(line) {
-				key := service + "::" + p.Label
-				if _, exists := matches[key]; !exists {
-					matches[key] = &SymptomMatch{
-						Service:  service,
-						Pattern:  p.Label,
-						Count:    1,
-						LastSeen: time.Now(),
-					}
-				} else {
-					matches[key].Count++
-					matches[key].LastSeen = time.Now()
-				}
+// expandLogFiles resolves the configured paths and globs into files. Plain paths are always
+// scanned; glob matches that weren't modified within the window are skipped, and so are
+// rotations of a matched file, which are read together with it.
+func expandLogFiles(patterns []string, window time.Duration) ([]string, error) {
+	cutoff := time.Now().Add(-window)
+	seen := make(map[string]bool)
+	var paths []string
+	for _, pattern := range patterns {
+		if !hasGlobMeta(pattern) {
+			if !seen[pattern] {
+				seen[pattern] = true
+				paths = append(paths, pattern)
 			}
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid log_file glob %q: %w", pattern, err)
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil || info.IsDir() || info.ModTime().Before(cutoff) || seen[match] {
+				continue
+			}
+			seen[match] = true
+			paths = append(paths, match)
 		}
 	}
 
-	var result []SymptomMatch
-	for _, v := range matches {
-		result = append(result, *v)
+	active := paths[:0]
+	for _, path := range paths {
+		if !isRotation(path, seen) {
+			active = append(active, path)
+		}
 	}
-	return result, nil
+	return active, nil
+}
+
+func hasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, `*?[\`)
 }
 
 func extractService(line string) string {
@@ -331,47 +596,69 @@ func extractService(line string) string {
 
 // ServiceMapping holds service name mappings from config
 type ServiceMapping struct {
-	ConfiguredServices map[string]bool
+	ConfiguredServices map[string]bool // Replaced, never modified, by Update
+	mu                 sync.RWMutex
 }
 
 
 func NewServiceMapping(profiles map[string]config.ServiceProfile) *ServiceMapping {
+	sm := &ServiceMapping{}
+	sm.Update(profiles)
+	return sm
+}
+
+// Update maps logs to the services of reloaded profiles
+func (sm *ServiceMapping) Update(profiles map[string]config.ServiceProfile) {
 	services := make(map[string]bool)
 	for serviceName := range profiles {
 		services[serviceName] = true
 	}
-	return &ServiceMapping{ConfiguredServices: services}
+	sm.mu.Lock()
+	sm.ConfiguredServices = services
+	sm.mu.Unlock()
+}
+
+// services returns the configured services
+func (sm *ServiceMapping) services() map[string]bool {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.ConfiguredServices
 }
 
 
 func (sm *ServiceMapping) extractServiceFromLog(log ESLogEntry) string {
+	return sm.resolveService(nil, log)
+}
+
+// rawServiceName guesses the unnormalized service name of a log entry
+func rawServiceName(log ESLogEntry) string {
 
 	if log.Service != "" {
-		return sm.normalizeServiceName(log.Service)
+		return log.Service
 	}
 	
 
 	if log.Container != "" {
-		return sm.normalizeServiceName(log.Container)
+		return log.Container
 	}
 
 	if parts := strings.SplitN(log.Message, "|", 2); len(parts) == 2 {
-		container := strings.TrimSpace(parts[0])
-		return sm.normalizeServiceName(container)
+		return strings.TrimSpace(parts[0])
 	}
 	
-	return "unknown"
+	return ""
 }
 
 // normalizeServiceName tries to match container/service names to configured services
 func (sm *ServiceMapping) normalizeServiceName(rawName string) string {
+	configured := sm.services()
 
-	if sm.ConfiguredServices[rawName] {
+	if configured[rawName] {
 		return rawName
 	}
 	
 
-	for configuredService := range sm.ConfiguredServices {
+	for configuredService := range configured {
 
 		if strings.Contains(strings.ToLower(rawName), strings.ToLower(configuredService)) {
 			return configuredService
@@ -387,12 +674,12 @@ func (sm *ServiceMapping) normalizeServiceName(rawName string) string {
 	cleanName := cleanContainerName(rawName)
 	
 	
-	if sm.ConfiguredServices[cleanName] {
+	if configured[cleanName] {
 		return cleanName
 	}
 	
 	
-	for configuredService := range sm.ConfiguredServices {
+	for configuredService := range configured {
 		if strings.Contains(strings.ToLower(cleanName), strings.ToLower(configuredService)) {
 			return configuredService
 		}
@@ -466,21 +753,11 @@ func (es *ElasticsearchClient) ScanLogsWithFilters(
 	serviceMapping *ServiceMapping,
 ) ([]SymptomMatch, error) {
 	
-	compiled := []PatternDef{}
-	for _, p := range patterns {
-		re, err := regexp.Compile(p.Regex)
-		if err != nil {
-			continue
-		}
-		compiled = append(compiled, PatternDef{
-			Label: p.Label,
-			Regex: re,
-		})
-	}
+	compiled := compilePatterns(patterns)
 
 	query := buildAdvancedQuery(timeRange, limit, serviceFilter, logLevel)
 	
-	logs, err := es.searchLogs(indexPattern, query)
+	logs, err := es.searchLogs(context.Background(), indexPattern, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search logs: %w", err)
 	}
@@ -0,0 +1,110 @@
+package logs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"vigilant/pkg/config"
+)
+
+// JournaldClient reads the systemd journal through journalctl, so it works without cgo
+// and against journal directories mounted from other hosts
+type JournaldClient struct {
+	journalctl string
+}
+
+// NewJournaldClient creates a journald log source; journalctl is the binary to run
+// (looked up in PATH when empty)
+func NewJournaldClient(journalctl string) *JournaldClient {
+	if journalctl == "" {
+		journalctl = "journalctl"
+	}
+	return &JournaldClient{journalctl: journalctl}
+}
+
+func (j *JournaldClient) Name() string { return "journald" }
+
+// ScanSymptoms reads the newest entries of the profile's units over the window (the
+// profile's time_range_minutes when window is 0) and matches their messages
+func (j *JournaldClient) ScanSymptoms(ctx context.Context, profile config.ServiceProfile, window time.Duration) ([]SymptomMatch, error) {
+	cfg := profile.DataSources.Journald
+	if !cfg.Enabled() {
+		return nil, fmt.Errorf("no journald units configured for service %s", profile.Metadata.Name)
+	}
+	if window <= 0 {
+		window = time.Duration(cfg.TimeRangeMinutes) * time.Minute
+	}
+
+	args := []string{
+		"--output=json",
+		"--no-pager",
+		"--since=" + time.Now().Add(-window).Format("2006-01-02 15:04:05"),
+		"--lines=" + strconv.Itoa(cfg.ScanLimit),
+	}
+	for _, unit := range cfg.Units {
+		args = append(args, "--unit="+unit)
+	}
+	if cfg.Directory != "" {
+		args = append(args, "--directory="+cfg.Directory)
+	}
+	if cfg.MaxPriority != "" {
+		args = append(args, "--priority="+cfg.MaxPriority)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, j.journalctl, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("journalctl failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	// Every entry belongs to one of the profile's units, so matches go to the profile's service
+	counter := newSymptomCounter(profile)
+	entries := 0
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		message := journalMessage(entry["MESSAGE"])
+		if message == "" {
+			continue
+		}
+		ts := time.Now()
+		if usec, err := strconv.ParseInt(fieldString(entry, "__REALTIME_TIMESTAMP"), 10, 64); err == nil {
+			ts = time.UnixMicro(usec)
+		}
+		counter.Add(profile.Metadata.Name, message, ts)
+		entries++
+	}
+
+	fmt.Printf("JOURNALD DEBUG: Scanned %d entries from units %v for %s\n", entries, cfg.Units, profile.Metadata.Name)
+	return counter.Results(), nil
+}
+
+// journalMessage decodes MESSAGE, which journalctl emits as a byte array when it isn't valid UTF-8
+func journalMessage(v interface{}) string {
+	switch m := v.(type) {
+	case string:
+		return m
+	case []interface{}:
+		b := make([]byte, 0, len(m))
+		for _, c := range m {
+			if n, ok := c.(float64); ok {
+				b = append(b, byte(n))
+			}
+		}
+		return string(b)
+	}
+	return ""
+}
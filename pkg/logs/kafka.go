@@ -0,0 +1,338 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"vigilant/pkg/config"
+)
+
+const kafkaContentType = "application/vnd.kafka.v2+json"
+
+// KafkaStreamConsumer consumes structured logs from a Kafka topic and keeps rolling
+// per-minute symptom counts, so scans read from memory instead of re-querying a log store.
+// It talks to the Kafka REST Proxy consumer API (Confluent REST Proxy v2, also served
+// by Redpanda's HTTP proxy).
+type KafkaStreamConsumer struct {
+	proxyURL       string
+	topic          string
+	group          string
+	messageField   string
+	serviceFields  []string
+	retention      time.Duration
+	httpClient     *http.Client
+	serviceMapping *ServiceMapping
+
+	mu       sync.RWMutex
+	patterns map[string][]PatternDef     // service -> compiled patterns
+	excludes map[string][]*regexp.Regexp // service -> compiled exclude patterns
+	counts   map[string]map[string]*rollingCount // service -> pattern -> counts
+	consumed int
+}
+
+// rollingCount holds match counts bucketed by minute
+type rollingCount struct {
+	buckets  map[int64]int // unix minute -> matches
+	lastSeen time.Time
+	samples  []string // newest matched lines
+}
+
+type kafkaRecord struct {
+	Topic     string          `json:"topic"`
+	Value     json.RawMessage `json:"value"`
+	Partition int             `json:"partition"`
+	Offset    int64           `json:"offset"`
+}
+
+// NewKafkaStreamConsumer creates a consumer for topic through the REST proxy at proxyURL.
+// Records are JSON log events; messageField holds the log line and serviceFields
+// (dotted paths allowed) name the service. Counts older than retention are dropped.
+func NewKafkaStreamConsumer(proxyURL, topic, group, messageField string, serviceFields []string, retention time.Duration,
+	profiles map[string]config.ServiceProfile, serviceMapping *ServiceMapping) *KafkaStreamConsumer {
+	k := &KafkaStreamConsumer{
+		proxyURL:       strings.TrimSuffix(proxyURL, "/"),
+		topic:          topic,
+		group:          group,
+		messageField:   messageField,
+		serviceFields:  serviceFields,
+		retention:      retention,
+		httpClient:     &http.Client{Timeout: 30 * time.Second},
+		serviceMapping: serviceMapping,
+		counts:         make(map[string]map[string]*rollingCount),
+	}
+	k.SetProfiles(profiles)
+	return k
+}
+
+// SetProfiles counts the log patterns of reloaded profiles; counts of removed patterns age out
+func (k *KafkaStreamConsumer) SetProfiles(profiles map[string]config.ServiceProfile) {
+	patterns := make(map[string][]PatternDef)
+	excludes := make(map[string][]*regexp.Regexp)
+	for name, profile := range profiles {
+		patterns[name] = compilePatterns(profile.LogPatterns)
+		excludes[name] = compileExcludes(profile.ExcludePatterns)
+	}
+	k.mu.Lock()
+	k.patterns, k.excludes = patterns, excludes
+	k.mu.Unlock()
+}
+
+func (k *KafkaStreamConsumer) Name() string { return "kafka" }
+
+// Run consumes the topic until ctx is done, recreating the consumer instance after errors
+func (k *KafkaStreamConsumer) Run(ctx context.Context) {
+	hostname, _ := os.Hostname()
+	instance := fmt.Sprintf("vigilant-%s-%d", hostname, time.Now().Unix())
+
+	for {
+		err := k.consume(ctx, instance)
+		if ctx.Err() != nil {
+			return
+		}
+		fmt.Printf("[KAFKA] Consumer for topic %s stopped: %v, reconnecting in 10s\n", k.topic, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
+
+// consume creates and subscribes a consumer instance, then polls records until an error
+func (k *KafkaStreamConsumer) consume(ctx context.Context, instance string) error {
+	groupURL := k.proxyURL + "/consumers/" + url.PathEscape(k.group)
+	instanceURL := groupURL + "/instances/" + url.PathEscape(instance)
+
+	err := k.request(ctx, http.MethodPost, groupURL, map[string]string{
+		"name":              instance,
+		"format":            "json",
+		"auto.offset.reset": "latest",
+	}, nil)
+	// 409 means the instance survived a previous error and can be reused
+	if err != nil && !strings.Contains(err.Error(), "409") {
+		return fmt.Errorf("failed to create consumer instance: %w", err)
+	}
+	defer func() {
+		// The proxy keeps idle instances around for minutes; release ours right away
+		k.request(context.Background(), http.MethodDelete, instanceURL, nil, nil)
+	}()
+
+	if err := k.request(ctx, http.MethodPost, instanceURL+"/subscription", map[string][]string{
+		"topics": {k.topic},
+	}, nil); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", k.topic, err)
+	}
+	fmt.Printf("[KAFKA] Consuming logs from topic %s (group %s)\n", k.topic, k.group)
+
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var records []kafkaRecord
+		if err := k.request(ctx, http.MethodGet, instanceURL+"/records?timeout=5000", nil, &records); err != nil {
+			return fmt.Errorf("failed to fetch records: %w", err)
+		}
+		for _, record := range records {
+			k.handleRecord(record)
+		}
+		k.prune(time.Now())
+	}
+}
+
+// handleRecord matches one log event against its service's patterns
+func (k *KafkaStreamConsumer) handleRecord(record kafkaRecord) {
+	var event map[string]interface{}
+	if err := json.Unmarshal(record.Value, &event); err != nil {
+		// Plain string values are treated as bare log lines
+		var line string
+		if json.Unmarshal(record.Value, &line) != nil {
+			return
+		}
+		event = map[string]interface{}{k.messageField: line}
+	}
+
+	message := nestedFieldString(event, k.messageField)
+	if message == "" {
+		return
+	}
+	service := k.serviceFromEvent(event)
+	k.mu.RLock()
+	patterns, ok := k.patterns[service]
+	excludes := k.excludes[service]
+	k.mu.RUnlock()
+	if !ok || isExcluded(excludes, message) {
+		return
+	}
+
+	ts := time.Now()
+	for _, field := range []string{"@timestamp", "timestamp", "time"} {
+		if parsed, err := time.Parse(time.RFC3339Nano, nestedFieldString(event, field)); err == nil {
+			ts = parsed
+			break
+		}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.consumed++
+	for _, p := range patterns {
+		if !p.Regex.MatchString(message) {
+			continue
+		}
+		if k.counts[service] == nil {
+			k.counts[service] = make(map[string]*rollingCount)
+		}
+		rc, exists := k.counts[service][p.Label]
+		if !exists {
+			rc = &rollingCount{buckets: make(map[int64]int)}
+			k.counts[service][p.Label] = rc
+		}
+		rc.buckets[ts.Unix()/60]++
+		if ts.After(rc.lastSeen) {
+			rc.lastSeen = ts
+		}
+		rc.samples = appendRecent(rc.samples, message)
+	}
+}
+
+// prune drops buckets older than the retention window
+func (k *KafkaStreamConsumer) prune(now time.Time) {
+	cutoff := now.Add(-k.retention).Unix() / 60
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for service, byPattern := range k.counts {
+		for pattern, rc := range byPattern {
+			for minute := range rc.buckets {
+				if minute < cutoff {
+					delete(rc.buckets, minute)
+				}
+			}
+			if len(rc.buckets) == 0 {
+				delete(byPattern, pattern)
+			}
+		}
+		if len(byPattern) == 0 {
+			delete(k.counts, service)
+		}
+	}
+}
+
+// ScanSymptoms returns the rolling counts for the profile's service over the window
+// (10 minutes when window is 0, capped at the retention window)
+func (k *KafkaStreamConsumer) ScanSymptoms(ctx context.Context, profile config.ServiceProfile, window time.Duration) ([]SymptomMatch, error) {
+	if window <= 0 {
+		window = 10 * time.Minute
+	}
+	if window > k.retention {
+		window = k.retention
+	}
+	cutoff := time.Now().Add(-window).Unix() / 60
+	service := profile.Metadata.Name
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	var result []SymptomMatch
+	for pattern, rc := range k.counts[service] {
+		count := 0
+		for minute, n := range rc.buckets {
+			if minute >= cutoff {
+				count += n
+			}
+		}
+		if count > 0 {
+			result = append(result, SymptomMatch{
+				Service:  service,
+				Pattern:  pattern,
+				Count:    count,
+				LastSeen: rc.lastSeen,
+				Samples:  append([]string{}, rc.samples...),
+			})
+		}
+	}
+
+	fmt.Printf("KAFKA DEBUG: %d symptoms for %s from %d consumed events\n", len(result), service, k.consumed)
+	return result, nil
+}
+
+func (k *KafkaStreamConsumer) serviceFromEvent(event map[string]interface{}) string {
+	for _, field := range k.serviceFields {
+		if value := nestedFieldString(event, field); value != "" {
+			if k.serviceMapping == nil {
+				return value
+			}
+			return k.serviceMapping.normalizeServiceName(value)
+		}
+	}
+	return "unknown"
+}
+
+func (k *KafkaStreamConsumer) request(ctx context.Context, method, endpoint string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", kafkaContentType)
+	}
+	if method == http.MethodGet {
+		req.Header.Set("Accept", "application/vnd.kafka.json.v2+json")
+	} else {
+		req.Header.Set("Accept", kafkaContentType)
+	}
+
+	resp, err := k.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("kafka rest proxy error: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// nestedFieldString looks up field, falling back to a dotted path such as kubernetes.container.name
+func nestedFieldString(event map[string]interface{}, field string) string {
+	if value := fieldString(event, field); value != "" {
+		return value
+	}
+	parts := strings.Split(field, ".")
+	current := event
+	for i, part := range parts {
+		if i == len(parts)-1 {
+			return fieldString(current, part)
+		}
+		next, ok := current[part].(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		current = next
+	}
+	return ""
+}
@@ -0,0 +1,171 @@
+package logs
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"vigilant/pkg/config"
+)
+
+const namespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// KubernetesClient reads pod logs straight from the Kubernetes API server, so small
+// clusters don't need a log store at all
+type KubernetesClient struct {
+	clientset        kubernetes.Interface // Nil when no cluster is configured
+	configErr        error                // Why no cluster is configured
+	defaultNamespace string
+}
+
+// NewKubernetesClient creates a pod log source. In a pod it uses the service account; elsewhere,
+// or when KUBECONFIG or apiURL is set, the kubeconfig (KUBECONFIG, else ~/.kube/config) with the
+// given context, empty for the current one. Client certificates and exec and OIDC auth plugins
+// work as with kubectl. apiURL, tokenFile and caFile override the kubeconfig's server and
+// credentials, and without a kubeconfig are enough on their own.
+func NewKubernetesClient(kubeContext, apiURL, tokenFile, caFile string) *KubernetesClient {
+	k := &KubernetesClient{defaultNamespace: "default"}
+	cfg, namespace, err := kubernetesConfig(kubeContext, apiURL, tokenFile, caFile)
+	if err != nil {
+		k.configErr = err
+		return k
+	}
+	cfg.Timeout = 30 * time.Second
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		k.configErr = err
+		return k
+	}
+	k.clientset = clientset
+	if namespace != "" {
+		k.defaultNamespace = namespace
+	}
+	return k
+}
+
+// kubernetesConfig returns the in-cluster configuration, else the kubeconfig's, and the
+// namespace profiles without one read from
+func kubernetesConfig(kubeContext, apiURL, tokenFile, caFile string) (*rest.Config, string, error) {
+	if os.Getenv("KUBECONFIG") == "" && apiURL == "" {
+		if cfg, err := rest.InClusterConfig(); err == nil {
+			namespace := ""
+			if ns, err := os.ReadFile(namespaceFile); err == nil {
+				namespace = strings.TrimSpace(string(ns))
+			}
+			return cfg, namespace, nil
+		}
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+	overrides.ClusterInfo.Server = apiURL
+	overrides.ClusterInfo.CertificateAuthority = caFile
+	overrides.AuthInfo.TokenFile = tokenFile
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+	cfg, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	namespace, _, err := clientConfig.Namespace()
+	if err != nil {
+		namespace = ""
+	}
+	return cfg, namespace, nil
+}
+
+func (k *KubernetesClient) Name() string { return "kubernetes" }
+
+// ScanSymptoms reads the recent logs of the pods selected by the profile over the window
+// (the profile's time_range_minutes when window is 0) and matches them
+func (k *KubernetesClient) ScanSymptoms(ctx context.Context, profile config.ServiceProfile, window time.Duration) ([]SymptomMatch, error) {
+	cfg := profile.DataSources.Kubernetes
+	if !cfg.Enabled() {
+		return nil, fmt.Errorf("no kubernetes label selector configured for service %s", profile.Metadata.Name)
+	}
+	if k.clientset == nil {
+		return nil, fmt.Errorf("no kubernetes cluster configured (run in-cluster or set KUBECONFIG): %w", k.configErr)
+	}
+	if window <= 0 {
+		window = time.Duration(cfg.TimeRangeMinutes) * time.Minute
+	}
+
+	selector, err := renderServiceQuery(cfg.LabelSelector, profile)
+	if err != nil {
+		return nil, err
+	}
+	namespace := cfg.Namespace
+	if namespace == "" {
+		namespace = k.defaultNamespace
+	}
+
+	pods, err := k.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	counter := newSymptomCounter(profile)
+	scanned, lines := 0, 0
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning && pod.Status.Phase != corev1.PodFailed {
+			continue
+		}
+		if scanned >= cfg.MaxPods {
+			break
+		}
+		scanned++
+
+		sinceSeconds, tailLines := int64(window.Seconds()), int64(cfg.ScanLimit)
+		opts := &corev1.PodLogOptions{
+			Container:    cfg.Container,
+			SinceSeconds: &sinceSeconds,
+			TailLines:    &tailLines,
+			Timestamps:   true,
+		}
+		n, err := k.scanPodLogs(ctx, namespace, pod.Name, opts, func(line string, ts time.Time) {
+			counter.Add(profile.Metadata.Name, line, ts)
+		})
+		lines += n
+		if err != nil {
+			// One pod failing (e.g. still starting) shouldn't hide the others
+			fmt.Printf("Error reading logs of pod %s/%s: %v\n", namespace, pod.Name, err)
+		}
+	}
+
+	fmt.Printf("KUBERNETES DEBUG: Scanned %d lines from %d pods (%s in %s) for %s\n",
+		lines, scanned, selector, namespace, profile.Metadata.Name)
+	return counter.Results(), nil
+}
+
+// scanPodLogs streams a pod's log lines to add and returns how many were read
+func (k *KubernetesClient) scanPodLogs(ctx context.Context, namespace, pod string, opts *corev1.PodLogOptions, add func(line string, ts time.Time)) (int, error) {
+	stream, err := k.clientset.CoreV1().Pods(namespace).GetLogs(pod, opts).Stream(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer stream.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		// With timestamps every line starts with an RFC3339 timestamp
+		ts, line := time.Now(), scanner.Text()
+		if stamp, rest, ok := strings.Cut(line, " "); ok {
+			if parsed, err := time.Parse(time.RFC3339Nano, stamp); err == nil {
+				ts, line = parsed, rest
+			}
+		}
+		add(line, ts)
+		lines++
+	}
+	return lines, scanner.Err()
+}
@@ -0,0 +1,138 @@
+package logs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"vigilant/pkg/config"
+)
+
+// LokiClient queries Grafana Loki's query_range API and matches symptom patterns
+type LokiClient struct {
+	baseURL        string
+	httpClient     *http.Client
+	serviceMapping *ServiceMapping
+}
+
+// NewLokiClient creates a client for the Loki instance at baseURL (e.g. http://loki:3100).
+// Profiles may override the URL with data_sources.loki.url.
+func NewLokiClient(baseURL string, serviceMapping *ServiceMapping) *LokiClient {
+	return &LokiClient{
+		baseURL:        strings.TrimSuffix(baseURL, "/"),
+		httpClient:     &http.Client{Timeout: 30 * time.Second},
+		serviceMapping: serviceMapping,
+	}
+}
+
+func (l *LokiClient) Name() string { return "loki" }
+
+// lokiQueryResponse is the subset of the query_range response for stream results
+type lokiQueryResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"` // [unix nanoseconds, log line]
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// ScanSymptoms runs the profile's LogQL query over the window (the profile's
+// time_range_minutes when window is 0) and matches the returned lines
+func (l *LokiClient) ScanSymptoms(ctx context.Context, profile config.ServiceProfile, window time.Duration) ([]SymptomMatch, error) {
+	cfg := profile.DataSources.Loki
+	if !cfg.Enabled() {
+		return nil, fmt.Errorf("no loki query configured for service %s", profile.Metadata.Name)
+	}
+	if window <= 0 {
+		window = time.Duration(cfg.TimeRangeMinutes) * time.Minute
+	}
+
+	query, err := renderServiceQuery(cfg.Query, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := l.baseURL
+	if cfg.URL != "" {
+		baseURL = strings.TrimSuffix(cfg.URL, "/")
+	}
+	if baseURL == "" {
+		return nil, fmt.Errorf("no loki URL configured (set LOKI_URL or data_sources.loki.url)")
+	}
+
+	end := time.Now()
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", strconv.FormatInt(end.Add(-window).UnixNano(), 10))
+	params.Set("end", strconv.FormatInt(end.UnixNano(), 10))
+	params.Set("direction", "backward")
+	if cfg.ScanLimit > 0 {
+		params.Set("limit", strconv.Itoa(cfg.ScanLimit))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/loki/api/v1/query_range?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build loki request: %w", err)
+	}
+	if cfg.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", cfg.TenantID)
+	}
+
+	resp, err := l.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query loki: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("loki error: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var result lokiQueryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode loki response: %w", err)
+	}
+	if result.Data.ResultType != "streams" {
+		return nil, fmt.Errorf("loki query must return log streams, got %q (use a log query, not a metric query)", result.Data.ResultType)
+	}
+
+	counter := newSymptomCounter(profile)
+	lines := 0
+	for _, stream := range result.Data.Result {
+		service := l.serviceFromLabels(stream.Stream, cfg.ServiceLabels)
+		for _, v := range stream.Values {
+			ns, err := strconv.ParseInt(v[0], 10, 64)
+			if err != nil {
+				continue
+			}
+			counter.Add(service, v[1], time.Unix(0, ns))
+			lines++
+		}
+	}
+
+	fmt.Printf("LOKI DEBUG: Scanned %d lines from %d streams for %s\n", lines, len(result.Data.Result), profile.Metadata.Name)
+	return counter.Results(), nil
+}
+
+// serviceFromLabels returns the configured service named by the first present label
+func (l *LokiClient) serviceFromLabels(labels map[string]string, serviceLabels []string) string {
+	for _, name := range serviceLabels {
+		if value := labels[name]; value != "" {
+			if l.serviceMapping == nil {
+				return value
+			}
+			return l.serviceMapping.normalizeServiceName(value)
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,137 @@
+package logs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"vigilant/pkg/config"
+)
+
+// multilineMerger joins the lines of one event (e.g. a stack trace) before pattern matching,
+// so a trace counts once and patterns can span its lines. Lines are grouped per key, since
+// the documents of different services interleave.
+type multilineMerger struct {
+	start        *regexp.Regexp
+	continuation *regexp.Regexp
+	maxLines     int
+	newestFirst  bool
+	pending      map[string]*multilineEvent
+	emit         func(key, message string, ts time.Time)
+}
+
+type multilineEvent struct {
+	lines []string
+	ts    time.Time
+}
+
+// newMultilineMerger returns a merger handing complete events to emit; when cfg is not enabled
+// it passes lines straight through. newestFirst is set for sources that return lines in
+// descending time order, such as Elasticsearch.
+func newMultilineMerger(cfg config.MultilineConfig, newestFirst bool, emit func(key, message string, ts time.Time)) *multilineMerger {
+	if !cfg.Enabled() {
+		return &multilineMerger{emit: emit}
+	}
+	m := &multilineMerger{
+		maxLines:    cfg.MaxLines,
+		newestFirst: newestFirst,
+		pending:     make(map[string]*multilineEvent),
+		emit:        emit,
+	}
+	if m.maxLines <= 0 {
+		m.maxLines = 200
+	}
+	var err error
+	if cfg.StartPattern != "" {
+		if m.start, err = regexp.Compile(cfg.StartPattern); err != nil {
+			fmt.Printf("Invalid multiline start_pattern, merging disabled: %v\n", err)
+			return &multilineMerger{emit: emit}
+		}
+	}
+	if cfg.ContinuationPattern != "" {
+		if m.continuation, err = regexp.Compile(cfg.ContinuationPattern); err != nil {
+			fmt.Printf("Invalid multiline continuation_pattern, merging disabled: %v\n", err)
+			return &multilineMerger{emit: emit}
+		}
+	}
+	return m
+}
+
+// isContinuation reports whether line belongs to the previous event: it matches the
+// continuation pattern, or, without one, doesn't match the start pattern
+func (m *multilineMerger) isContinuation(line string) bool {
+	if m.continuation != nil {
+		return m.continuation.MatchString(line)
+	}
+	return m.start != nil && !m.start.MatchString(line)
+}
+
+// Add feeds the next line of key
+func (m *multilineMerger) Add(key, line string, ts time.Time) {
+	if m.pending == nil {
+		m.emit(key, line, ts)
+		return
+	}
+	event := m.pending[key]
+	cont := m.isContinuation(line)
+
+	if m.newestFirst {
+		// Continuation lines arrive before the line that starts their event
+		if !cont {
+			if event == nil {
+				m.emit(key, line, ts)
+				return
+			}
+			event.lines = append(event.lines, line)
+			m.flush(key)
+			return
+		}
+		if event != nil && len(event.lines) >= m.maxLines {
+			m.flush(key)
+			event = nil
+		}
+	} else {
+		if cont && event != nil && len(event.lines) < m.maxLines {
+			event.lines = append(event.lines, line)
+			if ts.After(event.ts) {
+				event.ts = ts
+			}
+			return
+		}
+		if event != nil {
+			m.flush(key)
+		}
+		event = nil
+	}
+
+	if event == nil {
+		event = &multilineEvent{ts: ts}
+		m.pending[key] = event
+	}
+	event.lines = append(event.lines, line)
+	if ts.After(event.ts) {
+		event.ts = ts
+	}
+}
+
+// Flush emits the events still being assembled; call it after the last line
+func (m *multilineMerger) Flush() {
+	for key := range m.pending {
+		m.flush(key)
+	}
+}
+
+func (m *multilineMerger) flush(key string) {
+	event := m.pending[key]
+	delete(m.pending, key)
+	if event == nil {
+		return
+	}
+	if m.newestFirst {
+		for i, j := 0, len(event.lines)-1; i < j; i, j = i+1, j-1 {
+			event.lines[i], event.lines[j] = event.lines[j], event.lines[i]
+		}
+	}
+	m.emit(key, strings.Join(event.lines, "\n"), event.ts)
+}
@@ -0,0 +1,180 @@
+package logs
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"vigilant/pkg/awsauth"
+	"vigilant/pkg/config"
+)
+
+// ObjectStorageClient scans archived (optionally gzipped) log objects in an S3 bucket,
+// or any S3-compatible store such as GCS (with HMAC keys) or MinIO
+type ObjectStorageClient struct{}
+
+func NewObjectStorageClient() *ObjectStorageClient {
+	return &ObjectStorageClient{}
+}
+
+func (o *ObjectStorageClient) Name() string { return "object_storage" }
+
+// maxListPages bounds how many ListObjectsV2 pages (1000 keys each) are read per scan
+const maxListPages = 10
+
+type s3Object struct {
+	Key          string    `xml:"Key"`
+	LastModified time.Time `xml:"LastModified"`
+	Size         int64     `xml:"Size"`
+}
+
+type s3ListResult struct {
+	Contents              []s3Object `xml:"Contents"`
+	IsTruncated           bool       `xml:"IsTruncated"`
+	NextContinuationToken string     `xml:"NextContinuationToken"`
+}
+
+// ScanSymptoms reads the newest objects under the profile's prefix that were written within
+// the window (the profile's time_range_minutes when window is 0) and matches their lines
+func (o *ObjectStorageClient) ScanSymptoms(ctx context.Context, profile config.ServiceProfile, window time.Duration) ([]SymptomMatch, error) {
+	cfg := profile.DataSources.ObjectStorage
+	if !cfg.Enabled() {
+		return nil, fmt.Errorf("no object storage bucket configured for service %s", profile.Metadata.Name)
+	}
+	if window <= 0 {
+		window = time.Duration(cfg.TimeRangeMinutes) * time.Minute
+	}
+
+	prefix, err := renderServiceQuery(cfg.Prefix, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	awsCfg, err := awsauth.Config(ctx, cfg.Region, cfg.RoleARN)
+	if err != nil {
+		return nil, err
+	}
+	bucketURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com", cfg.Bucket, awsCfg.Region)
+	if cfg.Endpoint != "" {
+		// Custom endpoints use path-style addressing
+		bucketURL = strings.TrimSuffix(cfg.Endpoint, "/") + "/" + cfg.Bucket
+	}
+
+	objects, err := o.listRecent(ctx, awsCfg, bucketURL, prefix, time.Now().Add(-window))
+	if err != nil {
+		return nil, err
+	}
+	if len(objects) > cfg.MaxObjects {
+		objects = objects[:cfg.MaxObjects]
+	}
+
+	counter := newSymptomCounter(profile)
+	lines := 0
+	for _, obj := range objects {
+		if lines >= cfg.ScanLimit {
+			break
+		}
+		n, err := o.scanObject(ctx, awsCfg, bucketURL, obj, counter, profile.Metadata.Name, cfg.ScanLimit-lines)
+		if err != nil {
+			fmt.Printf("Error reading object %s: %v\n", obj.Key, err)
+		}
+		lines += n
+	}
+
+	fmt.Printf("OBJECT STORAGE DEBUG: Scanned %d lines from %d objects under %s/%s for %s\n",
+		lines, len(objects), cfg.Bucket, prefix, profile.Metadata.Name)
+	return counter.Results(), nil
+}
+
+// listRecent lists the objects under prefix modified after since, newest first
+func (o *ObjectStorageClient) listRecent(ctx context.Context, awsCfg aws.Config, bucketURL, prefix string, since time.Time) ([]s3Object, error) {
+	var recent []s3Object
+	token := ""
+	for page := 0; page < maxListPages; page++ {
+		params := url.Values{}
+		params.Set("list-type", "2")
+		params.Set("prefix", prefix)
+		if token != "" {
+			params.Set("continuation-token", token)
+		}
+
+		resp, err := awsauth.GetS3(ctx, awsCfg, bucketURL+"/?"+params.Encode())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+		var result s3ListResult
+		err = decodeS3Response(resp, func(body io.Reader) error {
+			return xml.NewDecoder(body).Decode(&result)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		for _, obj := range result.Contents {
+			if obj.LastModified.After(since) && obj.Size > 0 {
+				recent = append(recent, obj)
+			}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+
+	sort.Slice(recent, func(i, j int) bool {
+		return recent[i].LastModified.After(recent[j].LastModified)
+	})
+	return recent, nil
+}
+
+// scanObject matches up to limit lines of one object, decompressing gzip content
+func (o *ObjectStorageClient) scanObject(ctx context.Context, awsCfg aws.Config, bucketURL string, obj s3Object,
+	counter *symptomCounter, service string, limit int) (int, error) {
+	resp, err := awsauth.GetS3(ctx, awsCfg, bucketURL+"/"+(&url.URL{Path: obj.Key}).EscapedPath())
+	if err != nil {
+		return 0, err
+	}
+
+	lines := 0
+	err = decodeS3Response(resp, func(body io.Reader) error {
+		reader := bufio.NewReader(body)
+		// Detect gzip by its magic bytes; archives aren't always named .gz
+		if magic, err := reader.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+			gz, err := gzip.NewReader(reader)
+			if err != nil {
+				return err
+			}
+			defer gz.Close()
+			reader = bufio.NewReader(gz)
+		}
+
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() && lines < limit {
+			// Archived lines carry their own timestamps in varying formats; use the object's
+			counter.Add(service, scanner.Text(), obj.LastModified)
+			lines++
+		}
+		return scanner.Err()
+	})
+	return lines, err
+}
+
+func decodeS3Response(resp *http.Response, read func(io.Reader) error) error {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("s3 error: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return read(resp.Body)
+}
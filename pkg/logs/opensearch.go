@@ -0,0 +1,91 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// openSearchTransport sends searches straight to the REST API. The go-elasticsearch v8
+// client refuses to talk to OpenSearch clusters because of its product check.
+type openSearchTransport struct {
+	addresses  []string
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+// NewOpenSearchClient creates a log client for OpenSearch (including AWS OpenSearch Service
+// with fine-grained access control). Username and password are optional.
+func NewOpenSearchClient(addresses []string, username, password string) (*ElasticsearchClient, error) {
+	if len(addresses) == 0 || addresses[0] == "" {
+		return nil, fmt.Errorf("no opensearch address configured")
+	}
+	for _, addr := range addresses {
+		if _, err := url.Parse(addr); err != nil {
+			return nil, fmt.Errorf("invalid opensearch address %q: %w", addr, err)
+		}
+	}
+
+	return &ElasticsearchClient{
+		openSearch: &openSearchTransport{
+			addresses:  addresses,
+			username:   username,
+			password:   password,
+			httpClient: &http.Client{Timeout: 30 * time.Second},
+		},
+	}, nil
+}
+
+// search runs query against indexPattern, trying each address until one answers
+func (t *openSearchTransport) search(ctx context.Context, indexPattern string, query map[string]interface{}) (*ESSearchResponse, error) {
+	body, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode query: %w", err)
+	}
+
+	var lastErr error
+	for _, addr := range t.addresses {
+		response, err := t.searchAddress(ctx, addr, indexPattern, body)
+		if err == nil {
+			return response, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func (t *openSearchTransport) searchAddress(ctx context.Context, addr, indexPattern string, body []byte) (*ESSearchResponse, error) {
+	endpoint := strings.TrimSuffix(addr, "/") + "/" + url.PathEscape(indexPattern) + "/_search"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.username != "" {
+		req.SetBasicAuth(t.username, t.password)
+	}
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute search: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("opensearch error: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var response ESSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &response, nil
+}
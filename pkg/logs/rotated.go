@@ -0,0 +1,166 @@
+package logs
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// rotationSuffix matches what logrotate and similar tools append to a rotated file:
+// app.log.1, app.log.2.gz, app.log-20240101, app.log-20240101.gz
+var rotationSuffix = regexp.MustCompile(`^[.-][0-9]+(-[0-9]+)?(\.gz)?$`)
+
+// openLogFile opens a log file, decompressing it when it is gzipped
+func openLogFile(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return file, nil
+	}
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to decompress log file %s: %w", path, err)
+	}
+	return &gzipFile{Reader: gz, file: file}, nil
+}
+
+// gzipFile closes the decompressor together with the file under it
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (g *gzipFile) Close() error {
+	g.Reader.Close()
+	return g.file.Close()
+}
+
+// newLineScanner returns a scanner that accepts long lines such as stack trace frames
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return scanner
+}
+
+// tailCompressed returns up to n lines from the end of a gzipped file. A gzip stream can't
+// be read backwards, so the whole file is decompressed.
+func tailCompressed(path string, n int) ([]string, error) {
+	file, err := openLogFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := newLineScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) > 2*n {
+			lines = append(lines[:0], lines[len(lines)-n:]...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
+
+// rotatedFiles returns the rotations of path modified at or after cutoff, newest first. A
+// rotated file is no longer written, so one modified before cutoff holds no line of the window.
+func rotatedFiles(path string, cutoff time.Time) []string {
+	dir, base := filepath.Split(path)
+	entries, err := os.ReadDir(filepath.Clean(dir + "."))
+	if err != nil {
+		return nil
+	}
+
+	type rotated struct {
+		path    string
+		modTime time.Time
+	}
+	var found []rotated
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, base) || !rotationSuffix.MatchString(name[len(base):]) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().Before(cutoff) {
+			continue
+		}
+		found = append(found, rotated{path: filepath.Join(dir, name), modTime: info.ModTime()})
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].modTime.After(found[j].modTime) })
+
+	paths := make([]string, 0, len(found))
+	for _, r := range found {
+		paths = append(paths, r.path)
+	}
+	return paths
+}
+
+// isRotation reports whether path is a rotation of one of the files; rotations are read
+// together with their active file rather than on their own
+func isRotation(path string, files map[string]bool) bool {
+	dir, name := filepath.Split(path)
+	for i := len(name) - 1; i > 0; i-- {
+		if (name[i] == '.' || name[i] == '-') && rotationSuffix.MatchString(name[i:]) && files[dir+name[:i]] {
+			return true
+		}
+	}
+	return false
+}
+
+// readRotatedRest reads the lines of a rotated (possibly gzipped) file after offset, if its
+// leading bytes still match the fingerprint saved for the active file
+func readRotatedRest(path string, state fileOffset) ([]timedLine, bool) {
+	file, err := openLogFile(path)
+	if err != nil {
+		return nil, false
+	}
+	defer file.Close()
+
+	head := make([]byte, state.PrintSize)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, false
+	}
+	sum := sha256.Sum256(head[:n])
+	if hex.EncodeToString(sum[:]) != state.Fingerprint {
+		return nil, false
+	}
+	var rest io.Reader = file
+	if skip := state.Offset - int64(n); skip >= 0 {
+		if _, err := io.CopyN(io.Discard, file, skip); err != nil {
+			return nil, false
+		}
+	} else {
+		// The offset lies within the fingerprinted bytes
+		rest = io.MultiReader(bytes.NewReader(head[state.Offset:n]), file)
+	}
+
+	// The rotated file won't grow, so a last line without a line break is complete
+	now := time.Now()
+	var lines []timedLine
+	scanner := newLineScanner(rest)
+	for scanner.Scan() {
+		lines = append(lines, timedLine{Text: strings.TrimRight(scanner.Text(), "\r"), Time: now})
+	}
+	return lines, true
+}
@@ -0,0 +1,252 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"text/template"
+	"time"
+
+	"vigilant/pkg/config"
+)
+
+// LogSource is a log backend that can be scanned for a service's symptom patterns
+type LogSource interface {
+	Name() string
+	ScanSymptoms(ctx context.Context, profile config.ServiceProfile, window time.Duration) ([]SymptomMatch, error)
+}
+
+// Router picks the log source for each profile so one deployment can mix backends
+type Router struct {
+	sources        map[string]LogSource
+	defaultBackend string
+}
+
+func NewRouter() *Router {
+	return &Router{sources: make(map[string]LogSource)}
+}
+
+// Register makes source available under its name
+func (r *Router) Register(source LogSource) {
+	r.sources[source.Name()] = source
+}
+
+// SetDefault names the source used by profiles that don't select a backend
+func (r *Router) SetDefault(name string) {
+	r.defaultBackend = name
+}
+
+// ForProfile returns the source the profile selects (see config.DataSources.LogBackend),
+// falling back to the default source
+func (r *Router) ForProfile(profile config.ServiceProfile) (LogSource, error) {
+	name := profile.DataSources.LogBackend()
+	if name == "" {
+		name = r.defaultBackend
+	}
+	source, ok := r.sources[name]
+	if !ok {
+		return nil, fmt.Errorf("log backend %q is not available in this deployment", name)
+	}
+	return source, nil
+}
+
+// fallbackSource tries a second source when the first one fails
+type fallbackSource struct {
+	primary  LogSource
+	fallback LogSource
+}
+
+// WithFallback returns primary, retrying with fallback when primary returns an error
+func WithFallback(primary, fallback LogSource) LogSource {
+	return &fallbackSource{primary: primary, fallback: fallback}
+}
+
+func (f *fallbackSource) Name() string { return f.primary.Name() }
+
+func (f *fallbackSource) ScanSymptoms(ctx context.Context, profile config.ServiceProfile, window time.Duration) ([]SymptomMatch, error) {
+	symptoms, err := f.primary.ScanSymptoms(ctx, profile, window)
+	if err == nil {
+		return symptoms, nil
+	}
+	fmt.Printf("Error scanning %s logs for %s: %v, falling back to %s\n", f.primary.Name(), profile.Metadata.Name, err, f.fallback.Name())
+	symptoms, fallbackErr := f.fallback.ScanSymptoms(ctx, profile, window)
+	if fallbackErr != nil {
+		return nil, fmt.Errorf("%w (%s fallback: %v)", err, f.fallback.Name(), fallbackErr)
+	}
+	return symptoms, nil
+}
+
+// compilePatterns compiles the profile's log patterns, skipping invalid regexes
+func compilePatterns(patterns []config.LogPattern) []PatternDef {
+	compiled := []PatternDef{}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			continue
+		}
+		label := p.Name
+		if label == "" {
+			label = p.Label
+		}
+		compiled = append(compiled, PatternDef{
+			Label: label,
+			Regex: re,
+		})
+	}
+	return compiled
+}
+
+// compileExcludes compiles the profile's exclude patterns, skipping invalid regexes
+func compileExcludes(excludes []string) []*regexp.Regexp {
+	var compiled []*regexp.Regexp
+	for _, exclude := range excludes {
+		if re, err := regexp.Compile(exclude); err == nil {
+			compiled = append(compiled, re)
+		}
+	}
+	return compiled
+}
+
+// isExcluded reports whether line is known-benign noise
+func isExcluded(excludes []*regexp.Regexp, line string) bool {
+	for _, re := range excludes {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// maxSamples is how many of the first, and of the last, matched lines a symptom keeps
+const maxSamples = 3
+
+// maxSampleLength truncates long sample lines such as merged stack traces
+const maxSampleLength = 500
+
+// sampleBuffer keeps the first and last maxSamples lines matched by one symptom
+type sampleBuffer struct {
+	first []string
+	last  []string
+}
+
+func (b *sampleBuffer) add(line string) {
+	if len(b.first) < maxSamples {
+		b.first = append(b.first, truncateSample(line))
+		return
+	}
+	b.last = appendRecent(b.last, line)
+}
+
+func (b *sampleBuffer) samples() []string {
+	return append(append([]string{}, b.first...), b.last...)
+}
+
+// appendRecent appends line to samples, keeping only the newest maxSamples
+func appendRecent(samples []string, line string) []string {
+	samples = append(samples, truncateSample(line))
+	if len(samples) > maxSamples {
+		samples = samples[len(samples)-maxSamples:]
+	}
+	return samples
+}
+
+func truncateSample(line string) string {
+	if len(line) > maxSampleLength {
+		return line[:maxSampleLength] + "…"
+	}
+	return line
+}
+
+// symptomCounter aggregates pattern matches per service
+type symptomCounter struct {
+	service  string
+	patterns []PatternDef
+	excludes []*regexp.Regexp
+	miner    *templateMiner // Learns the profile's unmatched lines when anomaly detection is on
+	matches  map[string]*SymptomMatch
+	samples  map[string]*sampleBuffer
+}
+
+// newSymptomCounter counts the profile's log patterns, skipping its exclude patterns
+func newSymptomCounter(profile config.ServiceProfile) *symptomCounter {
+	c := &symptomCounter{
+		service:  profile.Metadata.Name,
+		patterns: compilePatterns(profile.LogPatterns),
+		excludes: compileExcludes(profile.ExcludePatterns),
+		matches:  make(map[string]*SymptomMatch),
+		samples:  make(map[string]*sampleBuffer),
+	}
+	if profile.AnomalyDetection.Enabled && c.service != "" {
+		c.miner = templateMinerFor(c.service, profile.AnomalyDetection)
+	}
+	return c
+}
+
+// Add matches one log line of service against every pattern, unless it is excluded
+func (c *symptomCounter) Add(service, line string, ts time.Time) {
+	if isExcluded(c.excludes, line) {
+		return
+	}
+	matched := false
+	for _, p := range c.patterns {
+		if !p.Regex.MatchString(line) {
+			continue
+		}
+		matched = true
+		key := service + "::" + p.Label
+		if m, exists := c.matches[key]; exists {
+			m.Count++
+			if ts.After(m.LastSeen) {
+				m.LastSeen = ts
+			}
+			c.samples[key].add(line)
+			continue
+		}
+		c.matches[key] = &SymptomMatch{
+			Service:  service,
+			Pattern:  p.Label,
+			Count:    1,
+			LastSeen: ts,
+		}
+		c.samples[key] = &sampleBuffer{}
+		c.samples[key].add(line)
+	}
+
+	// Lines no pattern knows about feed the template miner
+	if !matched && c.miner != nil && (service == c.service || service == "unknown") {
+		c.miner.Observe(line, ts)
+	}
+}
+
+// Results returns the aggregated matches, plus anomalies when anomaly detection is on.
+// Call it once per scan.
+func (c *symptomCounter) Results() []SymptomMatch {
+	var result []SymptomMatch
+	for key, v := range c.matches {
+		match := *v
+		match.Samples = c.samples[key].samples()
+		result = append(result, match)
+	}
+	if c.miner != nil {
+		result = append(result, c.miner.EndScan(c.service)...)
+	}
+	return result
+}
+
+// renderServiceQuery fills {{.Service}} (and {{.Namespace}}) placeholders in a backend query
+func renderServiceQuery(tpl string, profile config.ServiceProfile) (string, error) {
+	t, err := template.New("query").Parse(tpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid query template: %w", err)
+	}
+	var buf bytes.Buffer
+	err = t.Execute(&buf, map[string]string{
+		"Service":   profile.Metadata.Name,
+		"Namespace": profile.GetEffectiveElasticsearchConfig().NamespaceFilter,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render query: %w", err)
+	}
+	return buf.String(), nil
+}
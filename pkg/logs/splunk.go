@@ -0,0 +1,173 @@
+package logs
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"vigilant/pkg/config"
+)
+
+// SplunkClient runs SPL searches through Splunk's REST search jobs API in oneshot mode, which
+// returns the results in the response without leaving a search job behind
+type SplunkClient struct {
+	baseURL        string
+	token          string
+	username       string
+	password       string
+	httpClient     *http.Client
+	serviceMapping *ServiceMapping
+}
+
+// NewSplunkClient creates a client for the Splunk management API (e.g. https://splunk:8089).
+// A token takes precedence over username/password. insecure skips TLS verification for
+// the self-signed certificates Splunk ships with.
+func NewSplunkClient(baseURL, token, username, password string, insecure bool, serviceMapping *ServiceMapping) *SplunkClient {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &SplunkClient{
+		baseURL:        strings.TrimSuffix(baseURL, "/"),
+		token:          token,
+		username:       username,
+		password:       password,
+		httpClient:     &http.Client{Timeout: 60 * time.Second, Transport: transport},
+		serviceMapping: serviceMapping,
+	}
+}
+
+func (s *SplunkClient) Name() string { return "splunk" }
+
+type splunkResultsResponse struct {
+	Results []map[string]interface{} `json:"results"`
+}
+
+// ScanSymptoms runs the profile's SPL search over the window
+// (the profile's time_range_minutes when window is 0) and matches the returned events
+func (s *SplunkClient) ScanSymptoms(ctx context.Context, profile config.ServiceProfile, window time.Duration) ([]SymptomMatch, error) {
+	cfg := profile.DataSources.Splunk
+	if !cfg.Enabled() {
+		return nil, fmt.Errorf("no splunk search configured for service %s", profile.Metadata.Name)
+	}
+	if window <= 0 {
+		window = time.Duration(cfg.TimeRangeMinutes) * time.Minute
+	}
+
+	search, err := renderServiceQuery(cfg.Search, profile)
+	if err != nil {
+		return nil, err
+	}
+	// The jobs API requires a generating command; plain SPL starts with an implicit "search"
+	if !strings.HasPrefix(strings.TrimSpace(search), "search ") && !strings.HasPrefix(strings.TrimSpace(search), "|") {
+		search = "search " + search
+	}
+
+	baseURL := s.baseURL
+	if cfg.URL != "" {
+		baseURL = strings.TrimSuffix(cfg.URL, "/")
+	}
+	if baseURL == "" {
+		return nil, fmt.Errorf("no splunk URL configured (set SPLUNK_URL or data_sources.splunk.url)")
+	}
+
+	results, err := s.search(ctx, baseURL, search, window, cfg.ScanLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	counter := newSymptomCounter(profile)
+	for _, event := range results {
+		message := fieldString(event, cfg.MessageField)
+		if message == "" {
+			continue
+		}
+		ts, err := time.Parse("2006-01-02T15:04:05.000-07:00", fieldString(event, "_time"))
+		if err != nil {
+			ts = time.Now()
+		}
+		counter.Add(s.serviceFromEvent(event, cfg.ServiceFields), message, ts)
+	}
+
+	fmt.Printf("SPLUNK DEBUG: Scanned %d events for %s\n", len(results), profile.Metadata.Name)
+	return counter.Results(), nil
+}
+
+// search runs a oneshot search: Splunk answers with the results once the search has finished
+// and keeps no job, so nothing piles up in the search user's job quota
+func (s *SplunkClient) search(ctx context.Context, baseURL, search string, window time.Duration, limit int) ([]map[string]interface{}, error) {
+	form := url.Values{}
+	form.Set("search", search)
+	form.Set("exec_mode", "oneshot")
+	form.Set("earliest_time", strconv.FormatInt(time.Now().Add(-window).Unix(), 10))
+	form.Set("latest_time", "now")
+	form.Set("output_mode", "json")
+	form.Set("count", strconv.Itoa(limit))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/services/search/jobs", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build splunk search request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var results splunkResultsResponse
+	if err := s.do(req, &results); err != nil {
+		return nil, fmt.Errorf("failed to run splunk search: %w", err)
+	}
+	return results.Results, nil
+}
+
+func (s *SplunkClient) do(req *http.Request, out interface{}) error {
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	} else if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("splunk error: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// serviceFromEvent returns the configured service named by the first present field
+func (s *SplunkClient) serviceFromEvent(event map[string]interface{}, serviceFields []string) string {
+	for _, field := range serviceFields {
+		if value := fieldString(event, field); value != "" {
+			if s.serviceMapping == nil {
+				return value
+			}
+			return s.serviceMapping.normalizeServiceName(value)
+		}
+	}
+	return "unknown"
+}
+
+// fieldString returns a result field as a string; multi-value fields use their first value
+func fieldString(event map[string]interface{}, field string) string {
+	switch v := event[field].(type) {
+	case string:
+		return v
+	case []interface{}:
+		if len(v) > 0 {
+			if s, ok := v[0].(string); ok {
+				return s
+			}
+		}
+	}
+	return ""
+}
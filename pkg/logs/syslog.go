@@ -0,0 +1,345 @@
+package logs
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"vigilant/pkg/config"
+)
+
+// SyslogReceiver is an embedded syslog server (RFC 3164 and RFC 5424 over UDP and TCP)
+// that keeps the recent messages of each service in memory for pattern matching
+type SyslogReceiver struct {
+	addr           string
+	bufferSize     int
+	serviceMapping *ServiceMapping
+
+	mu       sync.RWMutex
+	messages map[string][]syslogMessage // service -> newest bufferSize messages
+	received int
+
+	connsMu sync.Mutex
+	conns   map[net.Conn]struct{} // Open TCP connections, closed on shutdown
+	closed  bool
+}
+
+// A TCP frame may be at most maxSyslogFrame bytes, and a connection sending none for
+// syslogIdleTimeout is dropped
+const (
+	maxSyslogFrame    = 1024 * 1024
+	syslogIdleTimeout = 5 * time.Minute
+)
+
+type syslogMessage struct {
+	Timestamp time.Time
+	Message   string
+}
+
+// NewSyslogReceiver creates a receiver for addr (e.g. ":5514") keeping up to bufferSize
+// messages per service. The service is taken from the APP-NAME/tag, then the hostname.
+func NewSyslogReceiver(addr string, bufferSize int, serviceMapping *ServiceMapping) *SyslogReceiver {
+	if bufferSize <= 0 {
+		bufferSize = 1000
+	}
+	return &SyslogReceiver{
+		addr:           addr,
+		bufferSize:     bufferSize,
+		serviceMapping: serviceMapping,
+		messages:       make(map[string][]syslogMessage),
+		conns:          make(map[net.Conn]struct{}),
+	}
+}
+
+func (s *SyslogReceiver) Name() string { return "syslog" }
+
+// Start listens on addr over both UDP and TCP until ctx is done
+func (s *SyslogReceiver) Start(ctx context.Context) error {
+	udp, err := net.ListenPacket("udp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for syslog on udp %s: %w", s.addr, err)
+	}
+	tcp, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		udp.Close()
+		return fmt.Errorf("failed to listen for syslog on tcp %s: %w", s.addr, err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		udp.Close()
+		tcp.Close()
+		s.closeConns()
+	}()
+	go s.serveUDP(udp)
+	go s.serveTCP(tcp)
+
+	fmt.Printf("[SYSLOG] Receiving syslog on %s (udp/tcp)\n", s.addr)
+	return nil
+}
+
+func (s *SyslogReceiver) serveUDP(conn net.PacketConn) {
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		// A datagram may carry several newline-separated messages
+		for _, line := range strings.Split(string(buf[:n]), "\n") {
+			s.handle(line)
+		}
+	}
+}
+
+func (s *SyslogReceiver) serveTCP(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// serveConn reads octet-counted (RFC 6587) or newline-delimited frames from one connection,
+// dropping it on a frame over maxSyslogFrame or after syslogIdleTimeout without one
+func (s *SyslogReceiver) serveConn(conn net.Conn) {
+	defer conn.Close()
+	if !s.track(conn) {
+		return
+	}
+	defer s.untrack(conn)
+
+	reader := bufio.NewReaderSize(conn, 64*1024)
+	for {
+		conn.SetReadDeadline(time.Now().Add(syslogIdleTimeout))
+		first, err := reader.Peek(1)
+		if err != nil {
+			return
+		}
+		if first[0] >= '0' && first[0] <= '9' {
+			// ReadSlice fails once the length field fills the buffer, so it can't grow unbounded
+			lenField, err := reader.ReadSlice(' ')
+			if err != nil {
+				return
+			}
+			size, err := strconv.Atoi(strings.TrimSpace(string(lenField)))
+			if err != nil || size <= 0 || size > maxSyslogFrame {
+				return
+			}
+			frame := make([]byte, size)
+			if _, err := io.ReadFull(reader, frame); err != nil {
+				return
+			}
+			s.handle(string(frame))
+			continue
+		}
+		line, err := readSyslogLine(reader)
+		s.handle(line)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// readSyslogLine reads up to and including the next newline, failing once the line grows
+// past maxSyslogFrame
+func readSyslogLine(reader *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if len(line)+len(chunk) > maxSyslogFrame {
+			return "", fmt.Errorf("syslog line exceeds %d bytes", maxSyslogFrame)
+		}
+		line = append(line, chunk...)
+		if err != bufio.ErrBufferFull {
+			return string(line), err
+		}
+	}
+}
+
+// track registers an accepted connection, reporting false when the receiver is shutting down
+func (s *SyslogReceiver) track(conn net.Conn) bool {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+func (s *SyslogReceiver) untrack(conn net.Conn) {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	delete(s.conns, conn)
+}
+
+// closeConns closes the open connections, whose readers then return
+func (s *SyslogReceiver) closeConns() {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func (s *SyslogReceiver) handle(raw string) {
+	raw = strings.TrimRight(raw, "\r\n\x00")
+	if raw == "" {
+		return
+	}
+	msg, ok := parseSyslog(raw)
+	if !ok {
+		return
+	}
+
+	service := "unknown"
+	for _, candidate := range []string{msg.AppName, msg.Hostname} {
+		if candidate == "" || candidate == "-" {
+			continue
+		}
+		if s.serviceMapping == nil {
+			service = candidate
+			break
+		}
+		if normalized := s.serviceMapping.normalizeServiceName(candidate); s.serviceMapping.services()[normalized] {
+			service = normalized
+			break
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.received++
+	buffered := append(s.messages[service], syslogMessage{Timestamp: msg.Timestamp, Message: msg.Message})
+	if len(buffered) > s.bufferSize {
+		buffered = buffered[len(buffered)-s.bufferSize:]
+	}
+	s.messages[service] = buffered
+}
+
+// ScanSymptoms matches the profile's patterns against the buffered messages of its service
+// received within the window (15 minutes when window is 0)
+func (s *SyslogReceiver) ScanSymptoms(ctx context.Context, profile config.ServiceProfile, window time.Duration) ([]SymptomMatch, error) {
+	if window <= 0 {
+		window = 15 * time.Minute
+	}
+	cutoff := time.Now().Add(-window)
+	service := profile.Metadata.Name
+
+	s.mu.RLock()
+	buffered := s.messages[service]
+	s.mu.RUnlock()
+
+	counter := newSymptomCounter(profile)
+	scanned := 0
+	for _, m := range buffered {
+		if m.Timestamp.Before(cutoff) {
+			continue
+		}
+		counter.Add(service, m.Message, m.Timestamp)
+		scanned++
+	}
+
+	fmt.Printf("SYSLOG DEBUG: Scanned %d buffered messages for %s\n", scanned, service)
+	return counter.Results(), nil
+}
+
+type parsedSyslog struct {
+	Timestamp time.Time
+	Hostname  string
+	AppName   string
+	Message   string
+}
+
+// parseSyslog parses an RFC 5424 or RFC 3164 message. Timestamps that can't be parsed
+// (or RFC 3164's year-less ones from the future) fall back to the receive time.
+func parseSyslog(raw string) (parsedSyslog, bool) {
+	if !strings.HasPrefix(raw, "<") {
+		return parsedSyslog{}, false
+	}
+	end := strings.IndexByte(raw, '>')
+	if end < 2 || end > 4 {
+		return parsedSyslog{}, false
+	}
+	if _, err := strconv.Atoi(raw[1:end]); err != nil {
+		return parsedSyslog{}, false
+	}
+	rest := raw[end+1:]
+	now := time.Now()
+
+	// RFC 5424: VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	if strings.HasPrefix(rest, "1 ") {
+		fields := strings.SplitN(rest, " ", 7)
+		if len(fields) < 7 {
+			return parsedSyslog{}, false
+		}
+		msg := parsedSyslog{Timestamp: now, Hostname: fields[2], AppName: fields[3]}
+		if ts, err := time.Parse(time.RFC3339Nano, fields[1]); err == nil {
+			msg.Timestamp = ts
+		}
+		msg.Message = strings.TrimPrefix(skipStructuredData(fields[6]), "\ufeff")
+		return msg, true
+	}
+
+	// RFC 3164: Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG
+	msg := parsedSyslog{Timestamp: now}
+	if len(rest) > 16 && rest[15] == ' ' {
+		if ts, err := time.ParseInLocation(time.Stamp, rest[:15], time.Local); err == nil {
+			ts = ts.AddDate(now.Year(), 0, 0)
+			if ts.After(now.Add(time.Hour)) {
+				ts = ts.AddDate(-1, 0, 0)
+			}
+			msg.Timestamp = ts
+			rest = rest[16:]
+			if host, after, ok := strings.Cut(rest, " "); ok {
+				msg.Hostname = host
+				rest = after
+			}
+		}
+	}
+	if tag, after, ok := strings.Cut(rest, ": "); ok && !strings.Contains(tag, " ") {
+		if i := strings.IndexByte(tag, '['); i >= 0 {
+			tag = tag[:i]
+		}
+		msg.AppName = tag
+		rest = after
+	}
+	msg.Message = rest
+	return msg, true
+}
+
+// skipStructuredData strips RFC 5424 STRUCTURED-DATA ("-" or one or more [...] elements)
+func skipStructuredData(s string) string {
+	if strings.HasPrefix(s, "-") {
+		return strings.TrimPrefix(s[1:], " ")
+	}
+	for strings.HasPrefix(s, "[") {
+		i, escaped := 1, false
+		for ; i < len(s); i++ {
+			if escaped {
+				escaped = false
+				continue
+			}
+			if s[i] == '\\' {
+				escaped = true
+			} else if s[i] == ']' {
+				break
+			}
+		}
+		if i >= len(s) {
+			return ""
+		}
+		s = s[i+1:]
+	}
+	return strings.TrimPrefix(s, " ")
+}
@@ -0,0 +1,90 @@
+package logs
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serve runs serveConn on one end of a pipe and returns the other end and a channel closed
+// once serveConn returns
+func serve(t *testing.T, s *SyslogReceiver) (net.Conn, chan struct{}) {
+	t.Helper()
+	client, server := net.Pipe()
+	t.Cleanup(func() { client.Close() })
+	done := make(chan struct{})
+	go func() {
+		s.serveConn(server)
+		close(done)
+	}()
+	return client, done
+}
+
+func waitDone(t *testing.T, done chan struct{}) {
+	t.Helper()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("serveConn didn't return")
+	}
+}
+
+func TestSyslogServeConn(t *testing.T) {
+	line := "<13>1 2024-05-01T10:00:00Z host api - - - connection refused"
+	octet := "<13>1 2024-05-01T10:00:01Z host api - - - timeout"
+
+	tests := []struct {
+		name         string
+		input        string
+		wantMessages []string
+	}{
+		{name: "newline delimited", input: line + "\n", wantMessages: []string{"connection refused"}},
+		{name: "octet counted", input: "49 " + octet, wantMessages: []string{"timeout"}},
+		{name: "both", input: "49 " + octet + line + "\n", wantMessages: []string{"timeout", "connection refused"}},
+		{name: "line past the frame limit", input: line + strings.Repeat("x", maxSyslogFrame) + "\n"},
+		{name: "octet count past the frame limit", input: "1048577 " + octet},
+		{name: "endless length field", input: strings.Repeat("1", 128*1024)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSyslogReceiver("", 10, nil)
+			client, done := serve(t, s)
+			go func() {
+				client.Write([]byte(tt.input))
+				client.Close()
+			}()
+			waitDone(t, done)
+
+			var got []string
+			for _, m := range s.messages["api"] {
+				got = append(got, m.Message)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.wantMessages, "|") {
+				t.Errorf("messages = %q, want %q", got, tt.wantMessages)
+			}
+		})
+	}
+}
+
+func TestSyslogCloseConns(t *testing.T) {
+	s := NewSyslogReceiver("", 10, nil)
+	_, done := serve(t, s)
+	for {
+		s.connsMu.Lock()
+		open := len(s.conns)
+		s.connsMu.Unlock()
+		if open == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	s.closeConns()
+	waitDone(t, done)
+
+	// Connections accepted while shutting down are closed right away
+	_, done = serve(t, s)
+	waitDone(t, done)
+}
@@ -0,0 +1,168 @@
+package logs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"vigilant/pkg/config"
+)
+
+// anomalyPatternPrefix names the symptoms raised for unrecognized log templates
+const anomalyPatternPrefix = "unrecognized anomaly: "
+
+// baselineAlpha is the weight of the latest scan in a template's moving average
+const baselineAlpha = 0.3
+
+// variableToken matches tokens that vary between otherwise identical lines
+var variableToken = regexp.MustCompile(`^(0x[0-9a-fA-F]+|[0-9a-fA-F]{8}-[0-9a-fA-F-]{27}|\d+(\.\d+)*[a-zA-Z%]*|[0-9a-fA-F]{12,}|\d{1,3}(\.\d{1,3}){3}(:\d+)?)$`)
+
+// templateMiner clusters the lines of one service into templates (a simplified Drain: lines
+// with the same token count and mostly equal tokens share a template, differing tokens become
+// <*>) and tracks each template's count per scan against a moving baseline
+type templateMiner struct {
+	mu        sync.Mutex
+	cfg       config.AnomalyDetectionConfig
+	clusters  map[string][]*logTemplate // token count and first token -> templates
+	templates int
+	scans     int
+}
+
+type logTemplate struct {
+	tokens    []string
+	firstScan int
+	baseline  float64 // Moving average of lines per scan
+	seen      bool    // Matched in an earlier scan
+	current   int
+	lastSeen  time.Time
+	samples   []string
+}
+
+var (
+	templateMinersMu sync.Mutex
+	templateMiners   = make(map[string]*templateMiner)
+)
+
+// templateMinerFor returns the miner that learns service's templates across scans
+func templateMinerFor(service string, cfg config.AnomalyDetectionConfig) *templateMiner {
+	templateMinersMu.Lock()
+	defer templateMinersMu.Unlock()
+	miner, ok := templateMiners[service]
+	if !ok {
+		miner = &templateMiner{clusters: make(map[string][]*logTemplate)}
+		templateMiners[service] = miner
+	}
+	miner.cfg = cfg
+	return miner
+}
+
+func tokenizeLine(line string) []string {
+	tokens := strings.Fields(line)
+	for i, token := range tokens {
+		if variableToken.MatchString(strings.Trim(token, ",;:()[]{}\"'")) {
+			tokens[i] = "<*>"
+		}
+	}
+	return tokens
+}
+
+// Observe adds one line no configured pattern matched to the current scan
+func (m *templateMiner) Observe(line string, ts time.Time) {
+	tokens := tokenizeLine(line)
+	if len(tokens) == 0 {
+		return
+	}
+	key := fmt.Sprintf("%d %s", len(tokens), tokens[0])
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var best *logTemplate
+	bestScore := 0.0
+	for _, t := range m.clusters[key] {
+		if score := templateSimilarity(t.tokens, tokens); score >= m.cfg.Similarity && score > bestScore {
+			best, bestScore = t, score
+		}
+	}
+	if best == nil {
+		if m.templates >= m.cfg.MaxTemplates {
+			return
+		}
+		best = &logTemplate{tokens: tokens, firstScan: m.scans}
+		m.clusters[key] = append(m.clusters[key], best)
+		m.templates++
+	} else {
+		for i, token := range tokens {
+			if best.tokens[i] != token {
+				best.tokens[i] = "<*>"
+			}
+		}
+	}
+
+	best.current++
+	if ts.After(best.lastSeen) {
+		best.lastSeen = ts
+	}
+	best.samples = appendRecent(best.samples, line)
+}
+
+// templateSimilarity is the share of positions where the template and the line agree
+func templateSimilarity(template, tokens []string) float64 {
+	equal := 0
+	for i, token := range template {
+		if token == tokens[i] || token == "<*>" {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(template))
+}
+
+// EndScan closes the current scan and returns anomaly symptoms for service: templates first
+// seen after the warm-up, and templates whose count jumped to surge_factor times their baseline
+func (m *templateMiner) EndScan(service string) []SymptomMatch {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var anomalies []SymptomMatch
+	warm := m.scans >= m.cfg.WarmupScans
+	for _, templates := range m.clusters {
+		for _, t := range templates {
+			novel := !t.seen && t.firstScan >= m.cfg.WarmupScans
+			surging := t.seen && t.current >= int(m.cfg.SurgeFactor*t.baseline)
+			if warm && t.current >= m.cfg.MinCount && (novel || surging) {
+				kind := "new"
+				if surging {
+					kind = "surging"
+				}
+				anomalies = append(anomalies, SymptomMatch{
+					Service:  service,
+					Pattern:  anomalyPatternPrefix + truncateTemplate(strings.Join(t.tokens, " ")),
+					Severity: "warning",
+					Count:    t.current,
+					LastSeen: t.lastSeen,
+					Samples:  t.samples,
+				})
+				fmt.Printf("[ANOMALY] %s log template for %s (%d lines, baseline %.1f): %s\n",
+					kind, service, t.current, t.baseline, strings.Join(t.tokens, " "))
+			}
+
+			t.baseline = baselineAlpha*float64(t.current) + (1-baselineAlpha)*t.baseline
+			if t.current > 0 {
+				t.seen = true
+			}
+			t.current = 0
+			t.samples = nil
+		}
+	}
+	m.scans++
+	return anomalies
+}
+
+func truncateTemplate(template string) string {
+	if len(template) > 120 {
+		return template[:120] + "…"
+	}
+	return template
+}
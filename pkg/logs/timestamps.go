@@ -0,0 +1,78 @@
+package logs
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"vigilant/pkg/config"
+)
+
+// timestampParser reads the timestamp of a plain-text log line
+type timestampParser struct {
+	re       *regexp.Regexp
+	layout   string
+	location *time.Location
+}
+
+// newTimestampParser returns the profile's parser, or nil when no timestamp regex is set
+func newTimestampParser(cfg config.TimestampConfig) *timestampParser {
+	if cfg.Regex == "" {
+		return nil
+	}
+	re, err := regexp.Compile(cfg.Regex)
+	if err != nil {
+		fmt.Printf("Invalid timestamp regex, using read times: %v\n", err)
+		return nil
+	}
+	p := &timestampParser{re: re, layout: cfg.Layout, location: time.Local}
+	if p.layout == "" {
+		p.layout = time.RFC3339Nano // Also accepts RFC3339 without fractional seconds
+	}
+	if cfg.Timezone != "" {
+		if p.location, err = time.LoadLocation(cfg.Timezone); err != nil {
+			fmt.Printf("Unknown timestamp timezone %q, using local time: %v\n", cfg.Timezone, err)
+			p.location = time.Local
+		}
+	}
+	return p
+}
+
+// Parse returns the timestamp of line; ok is false when it has none
+func (p *timestampParser) Parse(line string) (ts time.Time, ok bool) {
+	match := p.re.FindStringSubmatch(line)
+	if match == nil {
+		return time.Time{}, false
+	}
+	text := match[0]
+	if len(match) > 1 {
+		text = match[1]
+	}
+
+	switch p.layout {
+	case "unix", "unix_ms":
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		if p.layout == "unix_ms" {
+			return time.UnixMilli(n), true
+		}
+		return time.Unix(n, 0), true
+	}
+
+	ts, err := time.ParseInLocation(p.layout, text, p.location)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if ts.Year() == 0 {
+		// Layouts such as syslog's "Jan _2 15:04:05" have no year; a date after today is from last year
+		now := time.Now().In(p.location)
+		ts = ts.AddDate(now.Year(), 0, 0)
+		if ts.After(now.AddDate(0, 0, 1)) {
+			ts = ts.AddDate(-1, 0, 0)
+		}
+	}
+	return ts, true
+}
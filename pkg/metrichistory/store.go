@@ -0,0 +1,75 @@
+package metrichistory
+
+import (
+	"sort"
+	"time"
+
+	"vigilant/pkg/ringstore"
+)
+
+// Point is one evaluation of a metric check, triggered or not
+type Point struct {
+	Service   string            `json:"service"`
+	Check     string            `json:"check"`
+	Time      time.Time         `json:"time"`
+	Value     float64           `json:"value"`
+	Operator  string            `json:"operator"`
+	Threshold float64           `json:"threshold"`
+	Triggered bool              `json:"triggered"`
+	Labels    map[string]string `json:"labels,omitempty"` // Series the value came from
+}
+
+// Series is the recorded history of one check, oldest first
+type Series struct {
+	Check     string  `json:"check"`
+	Operator  string  `json:"operator"`
+	Threshold float64 `json:"threshold"` // As of the latest point
+	Points    []Point `json:"points"`
+}
+
+// Store keeps the newest points of every service's checks in ring buffers, persisted to a
+// JSONL file (see ringstore.Store)
+type Store struct {
+	points *ringstore.Store[Point]
+}
+
+// NewStore loads previously recorded points from path (created if missing), keeping up to
+// capacity points per check
+func NewStore(path string, capacity int) (*Store, error) {
+	points, err := ringstore.Open("metric history", path, capacity, func(p Point) (string, string) {
+		return p.Service, p.Check
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{points: points}, nil
+}
+
+// Record persists a batch of points, e.g. one cycle's evaluations
+func (s *Store) Record(points []Point) error {
+	return s.points.Record(points)
+}
+
+// History returns the points recorded for service since the given time (all when zero),
+// per check and sorted by check name; check limits the result to one check when non-empty
+func (s *Store) History(service, check string, since time.Time) []Series {
+	var result []Series
+	s.points.Read(service, func(name string, points []Point) {
+		if check != "" && name != check {
+			return
+		}
+		start := sort.Search(len(points), func(i int) bool { return !points[i].Time.Before(since) })
+		if start == len(points) {
+			return
+		}
+		latest := points[len(points)-1]
+		result = append(result, Series{
+			Check:     name,
+			Operator:  latest.Operator,
+			Threshold: latest.Threshold,
+			Points:    append([]Point(nil), points[start:]...),
+		})
+	})
+	sort.Slice(result, func(i, j int) bool { return result[i].Check < result[j].Check })
+	return result
+}
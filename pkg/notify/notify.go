@@ -0,0 +1,99 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Message is a notification sent to every configured channel
+type Message struct {
+	Title    string `json:"title"`
+	Text     string `json:"text"`
+	Severity string `json:"severity,omitempty"`
+	Service  string `json:"service,omitempty"`
+	Kind     string `json:"kind"` // e.g. "digest", "escalation"
+}
+
+// Notifier delivers messages to one channel
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, msg Message) error
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// FromEnv builds notifiers for every channel configured in the environment
+func FromEnv() []Notifier {
+	var notifiers []Notifier
+	if url := os.Getenv("NOTIFY_SLACK_WEBHOOK_URL"); url != "" {
+		notifiers = append(notifiers, &SlackNotifier{WebhookURL: url})
+	}
+	if url := os.Getenv("NOTIFY_WEBHOOK_URL"); url != "" {
+		notifiers = append(notifiers, &WebhookNotifier{URL: url})
+	}
+	return notifiers
+}
+
+// Broadcast sends msg to all notifiers, logging (not returning) individual failures
+func Broadcast(ctx context.Context, notifiers []Notifier, msg Message) {
+	for _, n := range notifiers {
+		if err := n.Notify(ctx, msg); err != nil {
+			fmt.Printf("[NOTIFY] %s delivery failed: %v\n", n.Name(), err)
+			continue
+		}
+		fmt.Printf("[NOTIFY] Sent %s notification via %s\n", msg.Kind, n.Name())
+	}
+}
+
+// SlackNotifier posts to a Slack incoming webhook
+type SlackNotifier struct {
+	WebhookURL string
+}
+
+func (n *SlackNotifier) Name() string { return "slack" }
+
+func (n *SlackNotifier) Notify(ctx context.Context, msg Message) error {
+	return postJSON(ctx, n.WebhookURL, map[string]string{
+		"text": fmt.Sprintf("*%s*\n%s", msg.Title, msg.Text),
+	})
+}
+
+// WebhookNotifier posts the raw message as JSON to a generic HTTP endpoint
+type WebhookNotifier struct {
+	URL string
+}
+
+func (n *WebhookNotifier) Name() string { return "webhook" }
+
+func (n *WebhookNotifier) Notify(ctx context.Context, msg Message) error {
+	return postJSON(ctx, n.URL, msg)
+}
+
+func postJSON(ctx context.Context, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("bad response: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package prometheus
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BaselineConfig configures an "anomaly" check, which compares the current value with the
+// metric's own history instead of a static threshold; the check's threshold is the number of
+// standard deviations from the baseline mean that counts as anomalous
+type BaselineConfig struct {
+	Mode          string `yaml:"mode,omitempty"`           // "rolling" (default) or "seasonal"
+	WindowMinutes int    `yaml:"window_minutes,omitempty"` // Rolling: history before now (default 1440). Seasonal: window around the same time of week (default 60)
+	Weeks         int    `yaml:"weeks,omitempty"`          // Seasonal: previous weeks compared (default 4)
+	Direction     string `yaml:"direction,omitempty"`      // "above", "below" or "both" (default)
+}
+
+// BaselineStats is what a triggered anomaly check compared the current value with
+type BaselineStats struct {
+	Mean    float64
+	StdDev  float64
+	ZScore  float64 // Standard deviations between the current value and the mean
+	Samples int
+}
+
+// BaselineModes and BaselineDirections are the accepted baseline settings
+var (
+	BaselineModes      = []string{"", "rolling", "seasonal"}
+	BaselineDirections = []string{"", "above", "below", "both"}
+)
+
+const (
+	defaultAnomalyThreshold = 3
+	defaultRollingMinutes   = 24 * 60
+	defaultSeasonalMinutes  = 60
+	defaultSeasonalWeeks    = 4
+	// minBaselineSamples is how much history a series needs before it can be anomalous
+	minBaselineSamples = 10
+	// recentExclusion keeps the last minutes, likely the incident itself, out of a rolling baseline
+	recentExclusion = 10 * time.Minute
+)
+
+// evaluateAnomaly compares every series' current value with its baseline and reports the
+// series furthest from normal
+func evaluateAnomaly(source MetricSource, query string, check MetricCheck) MetricEvaluation {
+	current, ok := querySeries(source, query, 0, "")
+	if !ok || len(current) == 0 {
+		return MetricEvaluation{}
+	}
+	history, ok := baselineHistory(source, query, check)
+	if !ok {
+		return MetricEvaluation{}
+	}
+
+	threshold := check.Threshold
+	if threshold <= 0 {
+		threshold = defaultAnomalyThreshold
+	}
+
+	evaluation := MetricEvaluation{MetricResult: MetricResult{Series: len(current)}}
+	var closest MetricResult
+	for _, s := range current {
+		samples := history[labelKey(s.Labels)]
+		if len(samples) < minBaselineSamples {
+			continue
+		}
+		val := s.Values[len(s.Values)-1]
+		stats := baselineStats(samples, val)
+		if !evaluation.HasValue || math.Abs(stats.ZScore) > math.Abs(closest.Baseline.ZScore) {
+			closest = MetricResult{Value: val, Labels: s.Labels, Baseline: &stats}
+		}
+		evaluation.HasValue = true
+		if !deviates(stats.ZScore, threshold, check.Baseline.Direction) {
+			continue
+		}
+		if evaluation.Offenders == 0 || math.Abs(stats.ZScore) > math.Abs(evaluation.Baseline.ZScore) {
+			evaluation.Value = val
+			evaluation.Labels = s.Labels
+			evaluation.Baseline = &stats
+		}
+		evaluation.Offenders++
+	}
+	evaluation.Triggered = evaluation.Offenders > 0
+	if !evaluation.Triggered {
+		evaluation.Value, evaluation.Labels, evaluation.Baseline = closest.Value, closest.Labels, closest.Baseline
+	}
+	return evaluation
+}
+
+// baselineHistory returns the historical samples of every series, keyed by labelKey: the
+// window before now for rolling baselines, or the same time of week in previous weeks for
+// seasonal ones
+func baselineHistory(source MetricSource, query string, check MetricCheck) (map[string][]float64, bool) {
+	cfg := check.Baseline
+	now := time.Now()
+
+	type span struct{ start, end time.Time }
+	var spans []span
+	var window time.Duration
+	if cfg.Mode == "seasonal" {
+		window = minutesOr(cfg.WindowMinutes, defaultSeasonalMinutes)
+		weeks := cfg.Weeks
+		if weeks <= 0 {
+			weeks = defaultSeasonalWeeks
+		}
+		for w := 1; w <= weeks; w++ {
+			at := now.Add(-time.Duration(w) * 7 * 24 * time.Hour)
+			spans = append(spans, span{at.Add(-window / 2), at.Add(window / 2)})
+		}
+	} else {
+		window = minutesOr(cfg.WindowMinutes, defaultRollingMinutes)
+		spans = append(spans, span{now.Add(-window), now.Add(-recentExclusion)})
+	}
+
+	step := window / 60
+	if check.Step != "" {
+		if d, err := time.ParseDuration(check.Step); err == nil && d > 0 {
+			step = d
+		}
+	}
+	if step < 15*time.Second {
+		step = 15 * time.Second
+	}
+
+	history := make(map[string][]float64)
+	queried := false
+	for _, sp := range spans {
+		series, err := source.QueryRange(query, sp.start, sp.end, step)
+		if err != nil {
+			continue // Older weeks may be beyond retention
+		}
+		queried = true
+		for _, s := range series {
+			key := labelKey(s.Labels)
+			history[key] = append(history[key], s.Values...)
+		}
+	}
+	return history, queried
+}
+
+func minutesOr(minutes, fallback int) time.Duration {
+	if minutes <= 0 {
+		minutes = fallback
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// baselineStats computes the mean and standard deviation of samples and val's z-score. A flat
+// baseline gets a small floor on its deviation, so any clear departure from it still scores.
+func baselineStats(samples []float64, val float64) BaselineStats {
+	var sum float64
+	for _, s := range samples {
+		sum += s
+	}
+	mean := sum / float64(len(samples))
+	var variance float64
+	for _, s := range samples {
+		variance += (s - mean) * (s - mean)
+	}
+	stddev := math.Sqrt(variance / float64(len(samples)))
+
+	floor := 0.01 * math.Max(math.Abs(mean), 1)
+	z := (val - mean) / math.Max(stddev, floor)
+	return BaselineStats{Mean: mean, StdDev: stddev, ZScore: z, Samples: len(samples)}
+}
+
+func deviates(z, threshold float64, direction string) bool {
+	switch direction {
+	case "above":
+		return z > threshold
+	case "below":
+		return z < -threshold
+	}
+	return math.Abs(z) > threshold
+}
+
+// labelKey identifies a series by its sorted label set
+func labelKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, key := range keys {
+		sb.WriteString(key + "=" + labels[key] + "\xff")
+	}
+	return sb.String()
+}
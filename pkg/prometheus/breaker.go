@@ -0,0 +1,156 @@
+package prometheus
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"vigilant/pkg/selfmetrics"
+)
+
+// HTTPConfig bounds how long Prometheus calls may take and when an unhealthy endpoint is
+// skipped instead of waited on
+type HTTPConfig struct {
+	Timeout          time.Duration // Per attempt
+	Retries          int           // Extra attempts after transport errors, 429s and 5xx responses
+	FailureThreshold int           // Consecutive failed calls that open the circuit
+	Cooldown         time.Duration // How long an open circuit rejects calls before trying again
+}
+
+// DefaultHTTPConfig is used until ConfigureHTTP is called
+var DefaultHTTPConfig = HTTPConfig{
+	Timeout:          10 * time.Second,
+	Retries:          2,
+	FailureThreshold: 5,
+	Cooldown:         time.Minute,
+}
+
+// ErrCircuitOpen is returned without calling an endpoint that failed repeatedly
+var ErrCircuitOpen = errors.New("prometheus endpoint is degraded, skipping call")
+
+var (
+	httpConfig = DefaultHTTPConfig
+	breakersMu sync.Mutex
+	breakers   = make(map[string]*circuitBreaker)
+)
+
+// ConfigureHTTP sets the timeout, retry and circuit breaker settings of all Prometheus calls;
+// zero fields keep their defaults
+func ConfigureHTTP(cfg HTTPConfig) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultHTTPConfig.Timeout
+	}
+	if cfg.Retries < 0 {
+		cfg.Retries = 0
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = DefaultHTTPConfig.FailureThreshold
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = DefaultHTTPConfig.Cooldown
+	}
+	httpConfig = cfg
+	endpointClient = &http.Client{Timeout: cfg.Timeout}
+}
+
+// circuitBreaker tracks one endpoint: after FailureThreshold consecutive failed calls it opens
+// for Cooldown, then lets a single trial call through (half-open) that closes it on success
+type circuitBreaker struct {
+	mu        sync.Mutex
+	endpoint  string
+	failures  int
+	openUntil time.Time
+	trial     bool // A half-open trial call is in flight
+	lastError string
+}
+
+func breakerFor(endpoint string) *circuitBreaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	b, ok := breakers[endpoint]
+	if !ok {
+		b = &circuitBreaker{endpoint: endpoint}
+		breakers[endpoint] = b
+	}
+	return b
+}
+
+// allow reports whether a call may go through now
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < httpConfig.FailureThreshold {
+		return nil
+	}
+	if time.Now().Before(b.openUntil) || b.trial {
+		return fmt.Errorf("%w (%s: %s)", ErrCircuitOpen, b.endpoint, b.lastError)
+	}
+	b.trial = true
+	return nil
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures >= httpConfig.FailureThreshold {
+		fmt.Printf("[PROMETHEUS] %s recovered, circuit closed\n", b.endpoint)
+	}
+	b.failures, b.trial, b.lastError = 0, false, ""
+	selfmetrics.PrometheusDegraded.WithLabelValues(b.endpoint).Set(0)
+}
+
+func (b *circuitBreaker) failure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.trial = false
+	b.lastError = err.Error()
+	if b.failures >= httpConfig.FailureThreshold {
+		if b.failures == httpConfig.FailureThreshold {
+			fmt.Printf("[PROMETHEUS] %s failed %d calls in a row, skipping it for %s: %v\n",
+				b.endpoint, b.failures, httpConfig.Cooldown, err)
+		}
+		b.openUntil = time.Now().Add(httpConfig.Cooldown)
+		selfmetrics.PrometheusDegraded.WithLabelValues(b.endpoint).Set(1)
+	}
+}
+
+// SourceStatus is the health of one Prometheus endpoint as seen by its circuit breaker
+type SourceStatus struct {
+	Endpoint            string    `json:"endpoint"`
+	Degraded            bool      `json:"degraded"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	LastError           string    `json:"last_error,omitempty"`
+	RetryAt             time.Time `json:"retry_at,omitempty"`
+}
+
+// Status returns the health of every endpoint called so far
+func Status() []SourceStatus {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	statuses := make([]SourceStatus, 0, len(breakers))
+	for _, b := range breakers {
+		b.mu.Lock()
+		status := SourceStatus{
+			Endpoint:            b.endpoint,
+			Degraded:            b.failures >= httpConfig.FailureThreshold,
+			ConsecutiveFailures: b.failures,
+			LastError:           b.lastError,
+		}
+		if status.Degraded {
+			status.RetryAt = b.openUntil
+		}
+		b.mu.Unlock()
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Endpoint < statuses[j].Endpoint })
+	return statuses
+}
+
+// retryable reports whether a response is worth another attempt
+func retryable(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
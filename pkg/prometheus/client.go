@@ -3,24 +3,45 @@ package prometheus
 import (
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"io/ioutil"
-	"net/http"
+	"sort"
 	"strings"
 	"time"
 )
 
 // Alert represents a simplified version of a Prometheus alert
 type Alert struct {
-	Name     string
-	Instance string
-	Severity string
-	Service  string
-	StartsAt time.Time
+	Fingerprint string // Identifies the alert by its full label set
+	Name        string
+	Instance    string
+	Severity    string
+	Service     string
+	State       string // "firing" or "pending"
+	StartsAt    time.Time
+	EndsAt      time.Time // Set by Alertmanager webhooks; zero when polled
+	Labels      map[string]string
 }
 
-// FetchAlerts fetches firing alerts from Prometheus, filtered by configured services
-func FetchAlerts(promURL string, validServices map[string]bool) ([]Alert, error) {
-	resp, err := http.Get(fmt.Sprintf("%s/api/v1/alerts", promURL))
+// DefaultAlertStates are the Prometheus alert states tracked unless configured otherwise
+var DefaultAlertStates = []string{"firing"}
+
+// ServiceResolver names the service an alert with the given labels belongs to; ok is false
+// when it belongs to no configured service
+type ServiceResolver func(labels map[string]string) (service string, ok bool)
+
+// FetchAlerts fetches the alerts in states (firing and/or pending, DefaultAlertStates when
+// empty) from Prometheus that resolve to a configured service
+func FetchAlerts(endpoint Endpoint, resolve ServiceResolver, states []string) ([]Alert, error) {
+	if len(states) == 0 {
+		states = DefaultAlertStates
+	}
+	tracked := make(map[string]bool)
+	for _, state := range states {
+		tracked[state] = true
+	}
+
+	resp, err := endpoint.get("/api/v1/alerts", nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch alerts: %w", err)
 	}
@@ -52,17 +73,10 @@ func FetchAlerts(promURL string, validServices map[string]bool) ([]Alert, error)
 
 	var alerts []Alert
 	for _, a := range raw.Data.Alerts {
-		if a.State == "firing" {
-			alert := Alert{
-				Name:     getLabel(a.Labels, "alertname"),
-				Instance: getLabel(a.Labels, "instance"),
-				Severity: getLabel(a.Labels, "severity"),
-				Service:  extractServiceFromLabels(a.Labels, validServices),
-				StartsAt: a.StartsAt,
-			}
-			
-			// Only include alerts that match configured service files
-			if len(validServices) == 0 || validServices[alert.Name] {
+		if tracked[a.State] {
+			// Only include alerts that map to a configured service
+			if alert, ok := newAlert(a.Labels, a.StartsAt, resolve); ok {
+				alert.State = a.State
 				alerts = append(alerts, alert)
 			}
 		}
@@ -71,6 +85,45 @@ func FetchAlerts(promURL string, validServices map[string]bool) ([]Alert, error)
 	return alerts, nil
 }
 
+// newAlert builds an alert from its labels; ok is false when resolve maps it to no service.
+// Without a resolver every alert is kept, with service "unknown".
+func newAlert(labels map[string]string, startsAt time.Time, resolve ServiceResolver) (Alert, bool) {
+	alert := Alert{
+		Fingerprint: LabelsFingerprint(labels),
+		Name:        getLabel(labels, "alertname"),
+		Instance:    getLabel(labels, "instance"),
+		Severity:    getLabel(labels, "severity"),
+		Service:     "unknown",
+		State:       "firing",
+		StartsAt:    startsAt,
+		Labels:      labels,
+	}
+	if resolve == nil {
+		return alert, true
+	}
+	service, ok := resolve(labels)
+	alert.Service = service
+	return alert, ok
+}
+
+// LabelsFingerprint hashes a label set the way Prometheus and Alertmanager fingerprint alerts
+// (FNV-1a over the labels sorted by name), so polled and pushed alerts get the same key
+func LabelsFingerprint(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := fnv.New64a()
+	for _, name := range names {
+		h.Write([]byte(name))
+		h.Write([]byte{255})
+		h.Write([]byte(labels[name]))
+		h.Write([]byte{255})
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
 
 func getLabel(labels map[string]string, key string) string {
 	if val, ok := labels[key]; ok {
@@ -79,17 +132,6 @@ func getLabel(labels map[string]string, key string) string {
 	return ""
 }
 
-func extractServiceFromLabels(labels map[string]string, validServices map[string]bool) string {
-	alertname := getLabel(labels, "alertname")
-	
-	// Simple logic: if alertname matches a service filename, use it
-	if validServices[alertname] {
-		return alertname
-	}
-	
-	return "unknown"
-}
-
 
 // cleanServiceName cleans up service names
 func cleanServiceName(service string) string {
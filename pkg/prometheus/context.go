@@ -0,0 +1,72 @@
+package prometheus
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ContextQuery is a metric fetched for every analysis of a service regardless of thresholds,
+// so the analysis sees baseline numbers such as the pod count or request rate
+type ContextQuery struct {
+	Name        string `yaml:"name"`
+	QueryTpl    string `yaml:"query_tpl"`
+	Unit        string `yaml:"unit,omitempty"`
+	Description string `yaml:"description,omitempty"`
+}
+
+// ContextValue is the current value of a context query, one per series it returned
+type ContextValue struct {
+	Query  ContextQuery
+	Series []SeriesValue
+}
+
+// SeriesValue is the current value of one series
+type SeriesValue struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// maxContextSeries bounds the series kept per context query, so a per-pod query doesn't
+// flood the prompt
+const maxContextSeries = 10
+
+// FetchContextMetrics renders and runs the context queries of a service with vars (see
+// QueryVars); queries that fail or return no data are left out
+func FetchContextMetrics(source MetricSource, vars map[string]string, queries []ContextQuery) []ContextValue {
+	source = withQueryCache(source)
+	var values []ContextValue
+	for _, q := range queries {
+		query, err := RenderQuery(q.QueryTpl, vars)
+		if err != nil {
+			fmt.Printf("[METRIC] Skipping context metric %s for %s: %v\n", q.Name, vars["Service"], err)
+			continue
+		}
+		series, ok := querySeries(source, query, 0, "")
+		if !ok || len(series) == 0 {
+			continue
+		}
+		value := ContextValue{Query: q}
+		for i, s := range series {
+			if i == maxContextSeries {
+				break
+			}
+			value.Series = append(value.Series, SeriesValue{Labels: s.Labels, Value: s.Values[len(s.Values)-1]})
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+// String formats the value for prompts, e.g. "pod_count: 4 pods" or
+// `request_rate: 12.5 req/s {pod="api-1"}, 9.8 req/s {pod="api-2"}`
+func (c ContextValue) String() string {
+	parts := make([]string, 0, len(c.Series))
+	for _, s := range c.Series {
+		part := strings.TrimSpace(fmt.Sprintf("%.3g %s", s.Value, c.Query.Unit))
+		if len(c.Series) > 1 {
+			part += " " + formatLabels(s.Labels)
+		}
+		parts = append(parts, part)
+	}
+	return c.Query.Name + ": " + strings.Join(parts, ", ")
+}
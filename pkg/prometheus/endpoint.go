@@ -0,0 +1,114 @@
+package prometheus
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"vigilant/pkg/selfmetrics"
+)
+
+// Endpoint is a Prometheus-compatible HTTP API. Multi-tenant gateways (Cortex, Mimir, Thanos,
+// VictoriaMetrics) serve it under a path prefix and select the tenant with a header such as
+// X-Scope-OrgID; QueryParams are added to every query (e.g. Thanos' dedup or
+// max_source_resolution).
+type Endpoint struct {
+	URL         string
+	PathPrefix  string
+	Headers     map[string]string
+	QueryParams map[string]string
+}
+
+// WithOverrides returns the endpoint with the non-empty fields of other applied; headers and
+// query params are merged, other's winning
+func (e Endpoint) WithOverrides(other Endpoint) Endpoint {
+	if other.URL != "" {
+		e.URL = other.URL
+	}
+	if other.PathPrefix != "" {
+		e.PathPrefix = other.PathPrefix
+	}
+	e.Headers = mergeStrings(e.Headers, other.Headers)
+	e.QueryParams = mergeStrings(e.QueryParams, other.QueryParams)
+	return e
+}
+
+func mergeStrings(base, overrides map[string]string) map[string]string {
+	if len(overrides) == 0 {
+		return base
+	}
+	merged := make(map[string]string, len(base)+len(overrides))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range overrides {
+		merged[k] = v
+	}
+	return merged
+}
+
+// apiURL joins the base URL, path prefix and API path, e.g. http://mimir/prometheus/api/v1/query
+func (e Endpoint) apiURL(path string) string {
+	base := strings.TrimRight(e.URL, "/")
+	if prefix := strings.Trim(e.PathPrefix, "/"); prefix != "" {
+		base += "/" + prefix
+	}
+	return base + path
+}
+
+var endpointClient = &http.Client{Timeout: DefaultHTTPConfig.Timeout}
+
+// get calls an API path with params plus the endpoint's query params and headers. Transport
+// errors, 429s and 5xx responses are retried with backoff; an endpoint that keeps failing is
+// skipped with ErrCircuitOpen until its cooldown passes.
+func (e Endpoint) get(path string, params url.Values) (*http.Response, error) {
+	breaker := breakerFor(e.Name())
+	if err := breaker.allow(); err != nil {
+		return nil, err
+	}
+	if params == nil {
+		params = url.Values{}
+	}
+	for k, v := range e.QueryParams {
+		params.Set(k, v)
+	}
+	target := e.apiURL(path)
+	if len(params) > 0 {
+		target += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range e.Headers {
+		req.Header.Set(k, v)
+	}
+
+	started := time.Now()
+	for attempt := 0; ; attempt++ {
+		resp, err := endpointClient.Do(req)
+		if err == nil && !retryable(resp) {
+			breaker.success()
+			selfmetrics.PrometheusLatency.WithLabelValues("success").Observe(time.Since(started).Seconds())
+			return resp, nil
+		}
+		if attempt >= httpConfig.Retries {
+			selfmetrics.PrometheusLatency.WithLabelValues("error").Observe(time.Since(started).Seconds())
+			if err != nil {
+				err = fmt.Errorf("request to %s failed: %w", e.apiURL(path), err)
+				breaker.failure(err)
+				return nil, err
+			}
+			// The caller still gets the response to report its status
+			breaker.failure(fmt.Errorf("%s returned %s", e.apiURL(path), resp.Status))
+			return resp, nil
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		time.Sleep(time.Duration(250<<attempt) * time.Millisecond)
+	}
+}
@@ -2,94 +2,379 @@ package prometheus
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
-	"net/http"
-	"strconv"
+	"math"
+	"sort"
+	"strings"
+	"sync"
 	"text/template"
+	"time"
 )
 
 // metric-based rule to check against Prometheus
 type MetricCheck struct {
     Name      string  `yaml:"name"`
     QueryTpl  string  `yaml:"query_tpl"`  
-    Operator  string  `yaml:"operator"`
+    Operator  string  `yaml:"operator"` // One of Operators
     Threshold float64 `yaml:"threshold"`
     Weight    int     `yaml:"weight"`
+    // RangeMinutes evaluates the query over the last minutes with a range query and compares
+    // its most extreme value (the highest for upper bounds, the lowest for lower bounds)
+    // instead of the latest
+    RangeMinutes int            `yaml:"range_minutes,omitempty"`
+    Step         string         `yaml:"step,omitempty"`     // Range query resolution (default: range / 60, at least 15s)
+    Baseline     BaselineConfig `yaml:"baseline,omitempty"` // History the "anomaly" operator compares with
+    // Interval evaluates the check at most this often (e.g. "5m"); in between, the last result
+    // is reused. Empty evaluates it every cycle.
+    Interval string `yaml:"interval,omitempty"`
+    // Backend queries this check from another metrics backend than the profile's
+    // data_sources.metrics_backend, e.g. "influxdb" for a Flux query
+    Backend string `yaml:"backend,omitempty"`
 }
 
 // ties a service to its metric checks
 type ServiceMetricConfig struct {
 	Service string
 	Checks  []MetricCheck
+	Vars    map[string]string // Template variables besides Service, see QueryVars
 }
 
-//  holds one triggered check result
+// MetricResult holds one triggered check result; when the query returns several series (e.g. one per
+// pod), Value and Labels are those of the worst offending series
 type MetricResult struct {
-	Service string
-	Check   MetricCheck
-	Value   float64
+	Service   string
+	Check     MetricCheck
+	Value     float64
+	Labels    map[string]string // Labels of the worst offending series
+	Offenders int               // Series that triggered the check
+	Series    int               // Series the query returned
+	Baseline  *BaselineStats    // Set by "anomaly" checks
+}
+
+// Offender describes the worst offending series when the query returned several, e.g.
+// `{pod="api-7"} (3 of 12 series)`; it is empty for single-series results
+func (m MetricResult) Offender() string {
+	if m.Series <= 1 || len(m.Labels) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s (%d of %d series)", formatLabels(m.Labels), m.Offenders, m.Series)
 }
 
+// formatLabels formats a label set like PromQL, e.g. {namespace="prod", pod="api-7"}
+func formatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		if key != "__name__" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", key, labels[key]))
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+
+// Operators compared against a threshold; "absent" triggers when the query returns no data
+// (e.g. a dead exporter), "rate-of-change" when the value moved by more than threshold
+// percent over the check's range and "anomaly" when it is more than threshold standard
+// deviations from its baseline
+var Operators = []string{">", "<", ">=", "<=", "==", "!=", "absent", "rate-of-change", "anomaly"}
+
+// defaultChangeRangeMinutes is the range a rate-of-change check compares without range_minutes
+const defaultChangeRangeMinutes = 5
+
+// MetricEvaluation is the outcome of one check, triggered or not; untriggered evaluations carry
+// the value closest to the threshold so it can be recorded
+type MetricEvaluation struct {
+	MetricResult
+	Triggered   bool
+	HasValue    bool // False when the query failed or no series had a value to compare
+	EvaluatedAt time.Time
+	Cached      bool // Reused from an earlier cycle because the check's interval hasn't passed
+}
+
+var (
+	evaluationsMu        sync.Mutex
+	scheduledEvaluations = make(map[string]MetricEvaluation) // Latest evaluation of checks with an interval
+)
+
 // EvaluateMetricChecks renders and evaluates all checks per service
-func EvaluateMetricChecks(promURL string, configs []ServiceMetricConfig) ([]MetricResult, error) {
+func EvaluateMetricChecks(source MetricSource, configs []ServiceMetricConfig) ([]MetricResult, error) {
 	var allResults []MetricResult
+	for _, evaluation := range EvaluateMetrics(source, configs) {
+		if evaluation.Triggered {
+			allResults = append(allResults, evaluation.MetricResult)
+		}
+	}
+	return allResults, nil
+}
+
+// EvaluateMetrics renders and evaluates all checks per service, returning every evaluation
+func EvaluateMetrics(source MetricSource, configs []ServiceMetricConfig) []MetricEvaluation {
+	var evaluations []MetricEvaluation
+	source = withQueryCache(source)
 
 	for _, cfg := range configs {
-		for _, check := range cfg.Checks {
-			query := RenderQuery(check.QueryTpl, map[string]string{
-				"Service": cfg.Service,
-			})
+		vars := make(map[string]string, len(cfg.Vars)+1)
+		for k, v := range cfg.Vars {
+			vars[k] = v
+		}
+		vars["Service"] = cfg.Service
 
-			url := fmt.Sprintf("%s/api/v1/query?query=%s", promURL, query)
-			resp, err := http.Get(url)
+		for _, check := range cfg.Checks {
+			query, err := RenderQuery(check.QueryTpl, vars)
 			if err != nil {
+				fmt.Printf("[METRIC] Skipping %s for %s: %v\n", check.Name, cfg.Service, err)
 				continue
 			}
-			defer resp.Body.Close()
-
-			var data struct {
-				Data struct {
-					Result []struct {
-						Value []interface{} `json:"value"`
-					} `json:"result"`
-				} `json:"data"`
-			}
 
-			if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-				continue
+			evaluations = append(evaluations, evaluateScheduled(source, query, cfg.Service, check))
+		}
+	}
+
+	return evaluations
+}
+
+// evaluateScheduled evaluates a check, or returns its previous evaluation while the check's
+// interval hasn't passed since
+func evaluateScheduled(source MetricSource, query, service string, check MetricCheck) MetricEvaluation {
+	interval, _ := time.ParseDuration(check.Interval)
+	key := strings.Join([]string{source.Name(), service, check.Name, query}, "\x00")
+	if interval > 0 {
+		evaluationsMu.Lock()
+		previous, ok := scheduledEvaluations[key]
+		evaluationsMu.Unlock()
+		if ok && time.Since(previous.EvaluatedAt) < interval {
+			previous.Cached = true
+			return previous
+		}
+	}
+
+	evaluation := evaluateCheck(source, query, check)
+	evaluation.Service = service
+	evaluation.Check = check
+	evaluation.EvaluatedAt = time.Now()
+	// Failed queries are retried next cycle
+	if interval > 0 && evaluation.HasValue {
+		evaluationsMu.Lock()
+		scheduledEvaluations[key] = evaluation
+		evaluationsMu.Unlock()
+	}
+	return evaluation
+}
+
+// evaluateCheck runs a check's query and evaluates every series it returns, reporting the
+// worst offender, or without one the series closest to triggering
+func evaluateCheck(source MetricSource, query string, check MetricCheck) MetricEvaluation {
+	if check.Operator == "anomaly" {
+		return evaluateAnomaly(source, query, check)
+	}
+	rangeMinutes := check.RangeMinutes
+	if check.Operator == "rate-of-change" && rangeMinutes <= 0 {
+		rangeMinutes = defaultChangeRangeMinutes
+	}
+	series, ok := querySeries(source, query, rangeMinutes, check.Step)
+	if !ok {
+		return MetricEvaluation{}
+	}
+	if check.Operator == "absent" {
+		// A failed request says nothing about the metric, only an empty result does; the
+		// recorded value is the number of series
+		return MetricEvaluation{
+			MetricResult: MetricResult{Value: float64(len(series)), Series: len(series)},
+			Triggered:    len(series) == 0,
+			HasValue:     true,
+		}
+	}
+
+	evaluation := MetricEvaluation{MetricResult: MetricResult{Series: len(series)}}
+	var closest MetricResult
+	for _, s := range series {
+		val, valid, triggered := evaluateSeries(s.Values, check)
+		if !valid {
+			continue
+		}
+		if !evaluation.HasValue || worseValue(val, closest.Value, check.Operator) {
+			closest.Value, closest.Labels = val, s.Labels
+		}
+		evaluation.HasValue = true
+		if !triggered {
+			continue
+		}
+		if evaluation.Offenders == 0 || worseValue(val, evaluation.Value, check.Operator) {
+			evaluation.Value = val
+			evaluation.Labels = s.Labels
+		}
+		evaluation.Offenders++
+	}
+	evaluation.Triggered = evaluation.Offenders > 0
+	if !evaluation.Triggered {
+		evaluation.Value, evaluation.Labels = closest.Value, closest.Labels
+	}
+	return evaluation
+}
+
+// EvaluateValue evaluates a check against a value given instead of queried, such as injected
+// test data: "absent" takes it as the number of series, "anomaly" as the z-score and
+// "rate-of-change" as the change in percent
+func EvaluateValue(service string, check MetricCheck, value float64) MetricEvaluation {
+	evaluation := MetricEvaluation{
+		MetricResult: MetricResult{Service: service, Check: check, Value: value, Series: 1},
+		HasValue:     true,
+		EvaluatedAt:  time.Now(),
+	}
+	switch check.Operator {
+	case "absent":
+		evaluation.Triggered = value == 0
+	case "anomaly":
+		threshold := check.Threshold
+		if threshold <= 0 {
+			threshold = defaultAnomalyThreshold
+		}
+		evaluation.Baseline = &BaselineStats{ZScore: value}
+		evaluation.Triggered = deviates(value, threshold, check.Baseline.Direction)
+	case "rate-of-change":
+		evaluation.Triggered = math.Abs(value) > check.Threshold
+	default:
+		evaluation.Triggered = compareThreshold(value, check.Operator, check.Threshold)
+	}
+	if evaluation.Triggered {
+		evaluation.Offenders = 1
+	}
+	return evaluation
+}
+
+// evaluateSeries compares one series' values and reports the value it compared; valid is
+// false when the series has nothing to compare
+func evaluateSeries(samples []float64, check MetricCheck) (val float64, valid, triggered bool) {
+	if len(samples) == 0 {
+		return 0, false, false
+	}
+	if check.Operator == "rate-of-change" {
+		if len(samples) < 2 || samples[0] == 0 {
+			return 0, false, false // No baseline to compare with
+		}
+		change := (samples[len(samples)-1] - samples[0]) / math.Abs(samples[0]) * 100
+		return change, true, math.Abs(change) > check.Threshold
+	}
+
+	val = samples[len(samples)-1]
+	if check.RangeMinutes > 0 {
+		// Over a range the most extreme value counts: the highest for upper bounds, the lowest for lower bounds
+		for _, sample := range samples {
+			switch check.Operator {
+			case ">", ">=":
+				val = math.Max(val, sample)
+			case "<", "<=":
+				val = math.Min(val, sample)
 			}
+		}
+	}
+	return val, true, compareThreshold(val, check.Operator, check.Threshold)
+}
+
+// worseValue reports whether a triggered value is further past the threshold than current;
+// for == and != every offender is equally bad and the first one is kept
+func worseValue(val, current float64, operator string) bool {
+	switch operator {
+	case ">", ">=":
+		return val > current
+	case "<", "<=":
+		return val < current
+	case "rate-of-change":
+		return math.Abs(val) > math.Abs(current)
+	}
+	return false
+}
+
+func compareThreshold(val float64, operator string, threshold float64) bool {
+	switch operator {
+	case ">":
+		return val > threshold
+	case "<":
+		return val < threshold
+	case ">=":
+		return val >= threshold
+	case "<=":
+		return val <= threshold
+	case "==":
+		return val == threshold
+	case "!=":
+		return val != threshold
+	}
+	return false
+}
 
-			if len(data.Data.Result) > 0 {
-				raw := data.Data.Result[0].Value[1].(string)
-				val, _ := strconv.ParseFloat(raw, 64)
-
-				triggered := false
-				switch check.Operator {
-				case ">":
-					triggered = val > check.Threshold
-				case "<":
-					triggered = val < check.Threshold
-				}
-
-				if triggered {
-					allResults = append(allResults, MetricResult{
-						Service: cfg.Service,
-						Check:   check,
-						Value:   val,
-					})
-				}
+// querySeries returns every series with a valid value: its current value, or the samples of
+// the last rangeMinutes when it is set. ok is false when the query failed; a query without
+// data returns no series.
+func querySeries(source MetricSource, query string, rangeMinutes int, stepSetting string) ([]Series, bool) {
+	if rangeMinutes > 0 {
+		rangeDuration := time.Duration(rangeMinutes) * time.Minute
+		step := rangeDuration / 60
+		if stepSetting != "" {
+			if d, err := time.ParseDuration(stepSetting); err == nil && d > 0 {
+				step = d
 			}
 		}
+		if step < 15*time.Second {
+			step = 15 * time.Second
+		}
+		now := time.Now()
+		series, err := source.QueryRange(query, now.Add(-rangeDuration), now, step)
+		return series, err == nil
 	}
+	series, err := source.Query(query)
+	return series, err == nil
+}
 
-	return allResults, nil
+// QueryVariables are the template variables every query can use: the service, and the
+// namespace, pod, environment and instance labels of the alert (empty when it has none)
+var QueryVariables = []string{"Service", "Namespace", "Pod", "Env", "Instance"}
+
+// alertLabelVars maps alert labels to the variables they fill, the first present label winning
+var alertLabelVars = map[string][]string{
+	"Namespace": {"namespace", "kubernetes_namespace"},
+	"Pod":       {"pod", "pod_name", "kubernetes_pod_name"},
+	"Env":       {"env", "environment"},
+	"Instance":  {"instance"},
+}
+
+// QueryVars returns the template variables for a service's queries: the profile's own
+// variables, overridden by the built-in ones taken from the alert's labels
+func QueryVars(service string, alertLabels, profileVars map[string]string) map[string]string {
+	vars := make(map[string]string, len(profileVars)+len(QueryVariables))
+	for _, name := range QueryVariables {
+		vars[name] = ""
+	}
+	for k, v := range profileVars {
+		vars[k] = v
+	}
+	for name, labels := range alertLabelVars {
+		for _, label := range labels {
+			if v := alertLabels[label]; v != "" {
+				vars[name] = v
+				break
+			}
+		}
+	}
+	vars["Service"] = service
+	return vars
 }
 
-// RenderQuery replaces template variables like {{.Service}} with values
-func RenderQuery(tpl string, vars map[string]string) string {
-	t := template.Must(template.New("query").Parse(tpl))
+// RenderQuery replaces template variables like {{.Service}} with values; referencing a
+// variable that isn't set is an error
+func RenderQuery(tpl string, vars map[string]string) (string, error) {
+	t, err := template.New("query").Option("missingkey=error").Parse(tpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid query template: %w", err)
+	}
 	var buf bytes.Buffer
-	t.Execute(&buf, vars)
-	return buf.String()
+	if err := t.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("invalid query template: %w", err)
+	}
+	return buf.String(), nil
 }
@@ -0,0 +1,105 @@
+package prometheus
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"vigilant/pkg/selfmetrics"
+)
+
+// Query results are shared by every check and context metric that renders the same query: within
+// a cycle always, and across cycles while they are younger than the configured TTL
+var (
+	queryCacheMu    sync.Mutex
+	queryCache      = make(map[string]queryCacheEntry)
+	queryCacheCycle int
+	queryCacheTTL   time.Duration
+)
+
+type queryCacheEntry struct {
+	series  []Series
+	fetched time.Time
+	cycle   int
+}
+
+// ConfigureQueryCache sets how long query results are reused across cycles; 0 only
+// deduplicates queries within a cycle
+func ConfigureQueryCache(ttl time.Duration) {
+	queryCacheMu.Lock()
+	defer queryCacheMu.Unlock()
+	queryCacheTTL = ttl
+}
+
+// StartQueryCycle starts a new analysis cycle, dropping the results that are no longer reused
+func StartQueryCycle() {
+	queryCacheMu.Lock()
+	defer queryCacheMu.Unlock()
+	queryCacheCycle++
+	for key, entry := range queryCache {
+		if !entry.valid() {
+			delete(queryCache, key)
+		}
+	}
+	selfmetrics.MetricQueryCacheEntries.Set(float64(len(queryCache)))
+}
+
+// valid reports whether the entry can still be reused; the caller holds queryCacheMu
+func (e queryCacheEntry) valid() bool {
+	return e.cycle == queryCacheCycle || time.Since(e.fetched) < queryCacheTTL
+}
+
+// cachedSource answers repeated queries of its source from the query cache
+type cachedSource struct {
+	MetricSource
+}
+
+// withQueryCache wraps source in the query cache
+func withQueryCache(source MetricSource) MetricSource {
+	if _, ok := source.(cachedSource); ok {
+		return source
+	}
+	return cachedSource{source}
+}
+
+func (c cachedSource) Query(query string) ([]Series, error) {
+	key := c.Name() + "\x00" + query
+	return cachedFetch(key, func() ([]Series, error) {
+		return c.MetricSource.Query(query)
+	})
+}
+
+// QueryRange keys ranges by their length, distance from now and step, so the same range
+// check or baseline window asked a moment later in the cycle still hits
+func (c cachedSource) QueryRange(query string, start, end time.Time, step time.Duration) ([]Series, error) {
+	key := fmt.Sprintf("%s\x00%s\x00%s\x00%s\x00%s", c.Name(), query,
+		end.Sub(start).Round(time.Second), time.Since(end).Round(time.Minute), step)
+	return cachedFetch(key, func() ([]Series, error) {
+		return c.MetricSource.QueryRange(query, start, end, step)
+	})
+}
+
+// cachedFetch returns the cached result of key or fetches and caches it; failed queries
+// aren't cached
+func cachedFetch(key string, fetch func() ([]Series, error)) ([]Series, error) {
+	queryCacheMu.Lock()
+	entry, ok := queryCache[key]
+	if ok && entry.valid() {
+		queryCacheMu.Unlock()
+		selfmetrics.MetricQueryCacheHits.Inc()
+		return entry.series, nil
+	}
+	queryCacheMu.Unlock()
+
+	selfmetrics.MetricQueryCacheMisses.Inc()
+	series, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	queryCacheMu.Lock()
+	queryCache[key] = queryCacheEntry{series: series, fetched: time.Now(), cycle: queryCacheCycle}
+	selfmetrics.MetricQueryCacheEntries.Set(float64(len(queryCache)))
+	queryCacheMu.Unlock()
+	return series, nil
+}
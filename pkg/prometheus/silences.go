@@ -0,0 +1,255 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SilenceMatcher is one label matcher of a silence, as in the Alertmanager API
+type SilenceMatcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+	IsEqual *bool  `json:"isEqual,omitempty"` // Negates the matcher when false; absent before Alertmanager 0.22
+}
+
+// Silence suppresses the alerts whose labels match all its matchers between StartsAt and
+// EndsAt: an Alertmanager silence, or a maintenance window created in Vigilant
+type Silence struct {
+	ID        string           `json:"id"`
+	Matchers  []SilenceMatcher `json:"matchers"`
+	StartsAt  time.Time        `json:"startsAt"`
+	EndsAt    time.Time        `json:"endsAt"`
+	CreatedBy string           `json:"createdBy"`
+	Comment   string           `json:"comment"`
+	Source    string           `json:"source"` // "alertmanager" or "maintenance"
+}
+
+// Active reports whether the silence applies at t
+func (s Silence) Active(t time.Time) bool {
+	return !t.Before(s.StartsAt) && t.Before(s.EndsAt)
+}
+
+// Matches reports whether the alert labels match every matcher
+func (s Silence) Matches(labels map[string]string) bool {
+	if len(s.Matchers) == 0 {
+		return false
+	}
+	for _, m := range s.Matchers {
+		if !m.Matches(labels[m.Name]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches reports whether a label value satisfies the matcher; a missing label is empty
+func (m SilenceMatcher) Matches(value string) bool {
+	matched := value == m.Value
+	if m.IsRegex {
+		re, err := regexp.Compile("^(?:" + m.Value + ")$")
+		if err != nil {
+			return false
+		}
+		matched = re.MatchString(value)
+	}
+	if m.IsEqual != nil && !*m.IsEqual {
+		matched = !matched
+	}
+	return matched
+}
+
+// ParseMatcher parses a matcher in Alertmanager syntax: name="value", name!="value",
+// name=~"regex" or name!~"regex" (the quotes are optional)
+func ParseMatcher(s string) (SilenceMatcher, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexAny(s, "=!")
+	if i <= 0 {
+		return SilenceMatcher{}, fmt.Errorf("invalid matcher %q", s)
+	}
+	m := SilenceMatcher{Name: strings.TrimSpace(s[:i])}
+	op, value := s[i:], ""
+	for _, candidate := range []string{"=~", "!~", "!=", "="} {
+		if strings.HasPrefix(op, candidate) {
+			op, value = candidate, strings.TrimSpace(s[i+len(candidate):])
+			break
+		}
+	}
+	switch op {
+	case "=":
+	case "!=":
+		m.IsEqual = new(bool)
+	case "=~":
+		m.IsRegex = true
+	case "!~":
+		m.IsRegex, m.IsEqual = true, new(bool)
+	default:
+		return SilenceMatcher{}, fmt.Errorf("invalid matcher %q", s)
+	}
+	if strings.HasPrefix(value, `"`) {
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return SilenceMatcher{}, fmt.Errorf("invalid value in matcher %q: %w", s, err)
+		}
+		value = unquoted
+	}
+	m.Value = value
+	if m.IsRegex {
+		if _, err := regexp.Compile(value); err != nil {
+			return SilenceMatcher{}, fmt.Errorf("invalid regex in matcher %q: %w", s, err)
+		}
+	}
+	return m, nil
+}
+
+// Validate checks that the silence has matchers, valid regexes and a time range
+func (s Silence) Validate() error {
+	if len(s.Matchers) == 0 {
+		return fmt.Errorf("a silence needs at least one matcher")
+	}
+	for _, m := range s.Matchers {
+		if m.Name == "" {
+			return fmt.Errorf("matcher without a label name")
+		}
+		if m.IsRegex {
+			if _, err := regexp.Compile(m.Value); err != nil {
+				return fmt.Errorf("invalid regex for %s: %w", m.Name, err)
+			}
+		}
+	}
+	if !s.EndsAt.After(s.StartsAt) {
+		return fmt.Errorf("endsAt must be after startsAt")
+	}
+	return nil
+}
+
+// FetchSilences returns the active silences of the Alertmanager at endpoint
+func FetchSilences(endpoint Endpoint) ([]Silence, error) {
+	resp, err := endpoint.get("/api/v2/silences", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch silences: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("bad response from Alertmanager: %s", resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var raw []struct {
+		Silence
+		Status struct {
+			State string `json:"state"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse Alertmanager silences: %w", err)
+	}
+
+	var silences []Silence
+	for _, s := range raw {
+		if s.Status.State == "active" {
+			s.Silence.Source = "alertmanager"
+			silences = append(silences, s.Silence)
+		}
+	}
+	return silences, nil
+}
+
+// SilenceStore holds the silences alerts are checked against: those polled from Alertmanager
+// and maintenance windows created through the API, which live in memory only
+type SilenceStore struct {
+	mu          sync.Mutex
+	endpoint    *Endpoint
+	polled      []Silence
+	maintenance map[string]Silence
+	nextID      int
+}
+
+// NewSilenceStore returns a store polling the Alertmanager at endpoint, or holding only
+// maintenance windows when endpoint is nil
+func NewSilenceStore(endpoint *Endpoint) *SilenceStore {
+	return &SilenceStore{endpoint: endpoint, maintenance: make(map[string]Silence)}
+}
+
+// Refresh re-reads the Alertmanager silences; on failure the previous ones are kept
+func (s *SilenceStore) Refresh() error {
+	if s.endpoint == nil {
+		return nil
+	}
+	silences, err := FetchSilences(*s.endpoint)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.polled = silences
+	s.mu.Unlock()
+	return nil
+}
+
+// AddMaintenance records a maintenance window and returns it with its ID
+func (s *SilenceStore) AddMaintenance(window Silence) (Silence, error) {
+	if window.StartsAt.IsZero() {
+		window.StartsAt = time.Now()
+	}
+	if err := window.Validate(); err != nil {
+		return Silence{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	window.ID = "maintenance-" + strconv.Itoa(s.nextID)
+	window.Source = "maintenance"
+	s.maintenance[window.ID] = window
+	return window, nil
+}
+
+// RemoveMaintenance ends a maintenance window; ok is false when there is none with id
+func (s *SilenceStore) RemoveMaintenance(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.maintenance[id]
+	delete(s.maintenance, id)
+	return ok
+}
+
+// List returns the silences and maintenance windows that haven't ended, maintenance
+// windows that have are dropped
+func (s *SilenceStore) List() []Silence {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	silences := append([]Silence{}, s.polled...)
+	for id, window := range s.maintenance {
+		if !now.Before(window.EndsAt) {
+			delete(s.maintenance, id)
+			continue
+		}
+		silences = append(silences, window)
+	}
+	sort.Slice(silences, func(i, j int) bool { return silences[i].EndsAt.Before(silences[j].EndsAt) })
+	return silences
+}
+
+// Match returns the active silence that matches the alert labels, if any
+func (s *SilenceStore) Match(labels map[string]string) (Silence, bool) {
+	now := time.Now()
+	for _, silence := range s.List() {
+		if silence.Active(now) && silence.Matches(labels) {
+			return silence, true
+		}
+	}
+	return Silence{}, false
+}
@@ -0,0 +1,164 @@
+package prometheus
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SLO is a service level objective whose error budget burn is computed every cycle. The
+// error ratio query returns the share of bad events over {{.Window}}, rendered as a
+// Prometheus duration such as 1h for every burn window.
+type SLO struct {
+	Name          string   `yaml:"name"`
+	Target        float64  `yaml:"target"`                   // Percent of good events, e.g. 99.9
+	WindowDays    int      `yaml:"window_days,omitempty"`    // Error budget period (default 30)
+	ErrorRatioTpl string   `yaml:"error_ratio_query"`        // Bad events / all events over {{.Window}}
+	BurnWindows   []string `yaml:"burn_windows,omitempty"`   // Windows burn rates are computed over (default 1h and 6h)
+	FastBurnRate  float64  `yaml:"fast_burn_rate,omitempty"` // Burn rate from which the budget burns fast (default 14.4)
+}
+
+// DefaultBurnWindows and DefaultFastBurnRate follow the multiwindow alerting of the Google SRE
+// workbook: a burn rate of 14.4 spends 2% of a 30-day budget in one hour
+var (
+	DefaultBurnWindows  = []string{"1h", "6h"}
+	DefaultFastBurnRate = 14.4
+)
+
+// Budget returns the error budget: the share of events allowed to fail
+func (s SLO) Budget() float64 {
+	return 1 - s.Target/100
+}
+
+// Period returns the error budget period
+func (s SLO) Period() time.Duration {
+	days := s.WindowDays
+	if days <= 0 {
+		days = 30
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// Windows returns the parsed burn windows
+func (s SLO) Windows() ([]time.Duration, error) {
+	names := s.BurnWindows
+	if len(names) == 0 {
+		names = DefaultBurnWindows
+	}
+	windows := make([]time.Duration, 0, len(names))
+	for _, name := range names {
+		window, err := time.ParseDuration(name)
+		if err != nil || window < time.Minute {
+			return nil, fmt.Errorf("invalid burn window %q (expected a duration like 1h or 30m)", name)
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// fastBurnRate returns the configured or default fast burn rate
+func (s SLO) fastBurnRate() float64 {
+	if s.FastBurnRate > 0 {
+		return s.FastBurnRate
+	}
+	return DefaultFastBurnRate
+}
+
+// BurnRate is how fast an SLO spent its error budget over one window
+type BurnRate struct {
+	Window      time.Duration
+	ErrorRatio  float64
+	Rate        float64 // Error ratio divided by the budget; 1 spends the budget exactly over the period
+	BudgetSpent float64 // Share of the whole period's budget spent within the window
+}
+
+// SLOStatus is the current burn of an SLO
+type SLOStatus struct {
+	SLO       SLO
+	BurnRates []BurnRate
+	Fast      bool // Every window burns at least the fast burn rate
+}
+
+// MaxBurnRate returns the highest burn rate over the windows
+func (s SLOStatus) MaxBurnRate() float64 {
+	highest := 0.0
+	for _, b := range s.BurnRates {
+		highest = max(highest, b.Rate)
+	}
+	return highest
+}
+
+// EvaluateSLOs computes the burn rates of a service's SLOs with vars (see QueryVars); windows
+// that fail or return no data are left out
+func EvaluateSLOs(source MetricSource, vars map[string]string, slos []SLO) []SLOStatus {
+	source = withQueryCache(source)
+	var statuses []SLOStatus
+	for _, slo := range slos {
+		windows, err := slo.Windows()
+		if err != nil || slo.Budget() <= 0 {
+			continue
+		}
+		status := SLOStatus{SLO: slo, Fast: true}
+		for _, window := range windows {
+			windowVars := make(map[string]string, len(vars)+1)
+			for k, v := range vars {
+				windowVars[k] = v
+			}
+			windowVars["Window"] = PromDuration(window)
+			query, err := RenderQuery(slo.ErrorRatioTpl, windowVars)
+			if err != nil {
+				fmt.Printf("[SLO] Skipping %s for %s: %v\n", slo.Name, vars["Service"], err)
+				break
+			}
+			series, ok := querySeries(source, query, 0, "")
+			if !ok || len(series) == 0 {
+				continue
+			}
+			ratio := series[0].Values[len(series[0].Values)-1]
+			rate := ratio / slo.Budget()
+			status.BurnRates = append(status.BurnRates, BurnRate{
+				Window:      window,
+				ErrorRatio:  ratio,
+				Rate:        rate,
+				BudgetSpent: rate * window.Hours() / slo.Period().Hours(),
+			})
+			if rate < slo.fastBurnRate() {
+				status.Fast = false
+			}
+		}
+		if len(status.BurnRates) > 0 {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// String formats the status for prompts, e.g. "availability (99.9% over 30d): burn rate
+// 14.4x over 1h, 2.0% of the budget burned in 1h; 3.1x over 6h, 2.6% of the budget burned in 6h"
+func (s SLOStatus) String() string {
+	parts := make([]string, 0, len(s.BurnRates))
+	for _, b := range s.BurnRates {
+		window := PromDuration(b.Window)
+		parts = append(parts, fmt.Sprintf("burn rate %.1fx over %s, %.1f%% of the budget burned in %s",
+			b.Rate, window, b.BudgetSpent*100, window))
+	}
+	status := fmt.Sprintf("%s (%g%% over %s): %s", s.SLO.Name, s.SLO.Target, PromDuration(s.SLO.Period()), strings.Join(parts, "; "))
+	if s.Fast {
+		status += " (burning fast)"
+	}
+	return status
+}
+
+// PromDuration formats d as a Prometheus duration, e.g. 1h, 30m or 30d
+func PromDuration(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+}
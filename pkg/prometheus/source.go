@@ -0,0 +1,133 @@
+package prometheus
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MetricSource runs the queries of metric checks. Prometheus-compatible endpoints implement
+// it here; other backends (e.g. Datadog) implement it in their own packages.
+type MetricSource interface {
+	// Name identifies the source, e.g. its base URL
+	Name() string
+	// Query returns every series' current value
+	Query(query string) ([]Series, error)
+	// QueryRange returns every series' samples between start and end, step apart
+	QueryRange(query string, start, end time.Time, step time.Duration) ([]Series, error)
+}
+
+// Series is one result series with its valid values, oldest first
+type Series struct {
+	Labels map[string]string
+	Values []float64
+}
+
+// Name identifies the endpoint by its API base URL, query params and headers, so tenants of one
+// gateway don't share cached results or circuit breakers. Header values are hashed, as they may
+// carry credentials, e.g. http://mimir/prometheus?dedup=true [X-Scope-OrgID 3f2a9c1d].
+func (e Endpoint) Name() string {
+	name := e.apiURL("")
+	if len(e.QueryParams) > 0 {
+		params := url.Values{}
+		for k, v := range e.QueryParams {
+			params.Set(k, v)
+		}
+		name += "?" + params.Encode()
+	}
+	if len(e.Headers) > 0 {
+		headers := make([]string, 0, len(e.Headers))
+		for k := range e.Headers {
+			headers = append(headers, k)
+		}
+		sort.Strings(headers)
+		hash := sha256.New()
+		for _, k := range headers {
+			fmt.Fprintf(hash, "%s\x00%s\x00", k, e.Headers[k])
+		}
+		name += fmt.Sprintf(" [%s %x]", strings.Join(headers, ","), hash.Sum(nil)[:4])
+	}
+	return name
+}
+
+// Query returns every series' current value
+func (e Endpoint) Query(query string) ([]Series, error) {
+	return e.fetchSeries("/api/v1/query", url.Values{"query": {query}})
+}
+
+// QueryRange returns every series' samples between start and end
+func (e Endpoint) QueryRange(query string, start, end time.Time, step time.Duration) ([]Series, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", strconv.FormatInt(start.Unix(), 10))
+	params.Set("end", strconv.FormatInt(end.Unix(), 10))
+	params.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+	return e.fetchSeries("/api/v1/query_range", params)
+}
+
+// fetchSeries runs an instant or range query and returns the series with valid values
+func (e Endpoint) fetchSeries(path string, params url.Values) ([]Series, error) {
+	resp, err := e.get(path, params)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("bad response from Prometheus: %s", resp.Status)
+	}
+
+	var data struct {
+		Status string `json:"status"`
+		Data   struct {
+			Result []struct {
+				Metric map[string]string `json:"metric"`
+				Value  []interface{}     `json:"value"`
+				Values [][]interface{}   `json:"values"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode query response: %w", err)
+	}
+	if data.Status != "success" {
+		return nil, fmt.Errorf("query failed with status %q", data.Status)
+	}
+
+	var series []Series
+	for _, result := range data.Data.Result {
+		samples := result.Values
+		if len(samples) == 0 {
+			samples = [][]interface{}{result.Value}
+		}
+		var values []float64
+		for _, sample := range samples {
+			if val, ok := sampleValue(sample); ok {
+				values = append(values, val)
+			}
+		}
+		if len(values) > 0 {
+			series = append(series, Series{Labels: result.Metric, Values: values})
+		}
+	}
+	return series, nil
+}
+
+// sampleValue parses a [timestamp, "value"] sample
+func sampleValue(sample []interface{}) (float64, bool) {
+	if len(sample) < 2 {
+		return 0, false
+	}
+	raw, ok := sample[1].(string)
+	if !ok {
+		return 0, false
+	}
+	val, err := strconv.ParseFloat(raw, 64)
+	return val, err == nil && !math.IsNaN(val) && !math.IsInf(val, 0)
+}
+
@@ -0,0 +1,60 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEndpointName(t *testing.T) {
+	gateway := Endpoint{URL: "http://mimir:8080/", PathPrefix: "/prometheus"}
+	tenant := func(org string) Endpoint {
+		return gateway.WithOverrides(Endpoint{Headers: map[string]string{"X-Scope-OrgID": org}})
+	}
+	params := func(p map[string]string) Endpoint {
+		return gateway.WithOverrides(Endpoint{QueryParams: p})
+	}
+
+	tests := []struct {
+		name     string
+		a, b     Endpoint
+		wantSame bool
+	}{
+		{name: "same endpoint", a: tenant("team-a"), b: tenant("team-a"), wantSame: true},
+		{name: "tenants", a: tenant("team-a"), b: tenant("team-b")},
+		{name: "tenant and none", a: tenant("team-a"), b: gateway},
+		{name: "query params", a: params(map[string]string{"dedup": "true"}), b: params(map[string]string{"dedup": "false"})},
+		{name: "query params and none", a: params(map[string]string{"dedup": "true"}), b: gateway},
+		{
+			name:     "header order",
+			a:        Endpoint{URL: "http://mimir", Headers: map[string]string{"A": "1", "B": "2", "C": "3"}},
+			b:        Endpoint{URL: "http://mimir", Headers: map[string]string{"C": "3", "B": "2", "A": "1"}},
+			wantSame: true,
+		},
+		{
+			name: "value moved between headers",
+			a:    Endpoint{URL: "http://mimir", Headers: map[string]string{"A": "1", "B": ""}},
+			b:    Endpoint{URL: "http://mimir", Headers: map[string]string{"A": "", "B": "1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if a, b := tt.a.Name(), tt.b.Name(); (a == b) != tt.wantSame {
+				t.Errorf("Name() = %q and %q, want same = %v", a, b, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestEndpointNameHidesHeaderValues(t *testing.T) {
+	e := Endpoint{URL: "http://mimir", Headers: map[string]string{"Authorization": "Bearer s3cret", "X-Scope-OrgID": "team-a"}}
+	name := e.Name()
+	for _, value := range e.Headers {
+		if strings.Contains(name, value) {
+			t.Errorf("Name() = %q shows the header value %q", name, value)
+		}
+	}
+	if !strings.HasPrefix(name, "http://mimir [Authorization,X-Scope-OrgID ") {
+		t.Errorf("Name() = %q, want the URL and header names", name)
+	}
+}
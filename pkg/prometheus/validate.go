@@ -0,0 +1,86 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// ValidateQueryTemplate renders a query_tpl with placeholders for QueryVariables and the
+// profile's own variables and checks that the result is balanced PromQL, returning the
+// rendered query. Templates that reference unknown variables fail here instead of at runtime.
+func ValidateQueryTemplate(tpl string, profileVars map[string]string) (string, error) {
+	vars := QueryVars("example", nil, profileVars)
+	for _, name := range QueryVariables {
+		vars[name] = "example"
+	}
+	query, err := RenderQuery(tpl, vars)
+	if err != nil {
+		return "", err
+	}
+	if err := checkBalanced(query); err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+// checkBalanced catches the common PromQL syntax slips locally: unclosed brackets and quotes
+func checkBalanced(query string) error {
+	closing := map[rune]rune{')': '(', ']': '[', '}': '{'}
+	var open []rune
+	var quote rune
+	escaped := false
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if escaped {
+				escaped = false
+			} else if r == '\\' && quote != '`' {
+				escaped = true
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'' || r == '`':
+			quote = r
+		case r == '(' || r == '[' || r == '{':
+			open = append(open, r)
+		case closing[r] != 0:
+			if len(open) == 0 || open[len(open)-1] != closing[r] {
+				return fmt.Errorf("unbalanced %q in query %q", r, query)
+			}
+			open = open[:len(open)-1]
+		}
+	}
+	if quote != 0 {
+		return fmt.Errorf("unterminated string in query %q", query)
+	}
+	if len(open) > 0 {
+		return fmt.Errorf("unclosed %q in query %q", open[len(open)-1], query)
+	}
+	return nil
+}
+
+// CheckQuery asks the endpoint to evaluate query once and returns Prometheus' parse error
+// when it rejects it. err is set when the endpoint couldn't be asked, in which case nothing
+// is known about the query.
+func CheckQuery(endpoint Endpoint, query string) (problem string, err error) {
+	resp, err := endpoint.get("/api/v1/query", url.Values{"query": {query}})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Status    string `json:"status"`
+		ErrorType string `json:"errorType"`
+		Error     string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("unexpected response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	// bad_data is a query that doesn't parse; other errors (timeouts, overload) say nothing about it
+	if result.Status == "error" && result.ErrorType == "bad_data" {
+		return result.Error, nil
+	}
+	return "", nil
+}
@@ -0,0 +1,47 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// AlertmanagerWebhook is the payload Alertmanager POSTs to a webhook receiver
+type AlertmanagerWebhook struct {
+	Version  string `json:"version"`
+	GroupKey string `json:"groupKey"`
+	Status   string `json:"status"`
+	Receiver string `json:"receiver"`
+	Alerts   []struct {
+		Status      string            `json:"status"`
+		Labels      map[string]string `json:"labels"`
+		Annotations map[string]string `json:"annotations"`
+		StartsAt    time.Time         `json:"startsAt"`
+		EndsAt      time.Time         `json:"endsAt"`
+		Fingerprint string            `json:"fingerprint"`
+	} `json:"alerts"`
+}
+
+// ParseAlertmanagerWebhook decodes a webhook payload into its firing and resolved alerts that
+// resolve to a configured service
+func ParseAlertmanagerWebhook(body io.Reader, resolve ServiceResolver) (firing, resolved []Alert, err error) {
+	var payload AlertmanagerWebhook
+	if err := json.NewDecoder(body).Decode(&payload); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse Alertmanager webhook: %w", err)
+	}
+
+	for _, a := range payload.Alerts {
+		alert, ok := newAlert(a.Labels, a.StartsAt, resolve)
+		if !ok {
+			continue
+		}
+		alert.EndsAt = a.EndsAt
+		if a.Status == "resolved" {
+			resolved = append(resolved, alert)
+		} else {
+			firing = append(firing, alert)
+		}
+	}
+	return firing, resolved, nil
+}
@@ -0,0 +1,201 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"vigilant/pkg/history"
+	"vigilant/pkg/risk"
+)
+
+const timeLayout = "2006-01-02 15:04:05 MST"
+
+// Postmortem renders a Markdown postmortem from an incident's recorded timeline (oldest first)
+func Postmortem(timeline []history.Incident) (string, error) {
+	if len(timeline) == 0 {
+		return "", fmt.Errorf("incident has no recorded snapshots")
+	}
+
+	latest := timeline[len(timeline)-1]
+	analysis := latestAnalysis(timeline)
+
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("# Postmortem: %s - %s on %s\n\n", latest.ID, latest.AlertName, latest.Service))
+
+	status := "Ongoing"
+	end := latest.LastSeen
+	if latest.Resolved() {
+		status = "Resolved"
+		end = latest.ResolvedAt
+	}
+	if end.IsZero() {
+		end = latest.RecordedAt
+	}
+
+	sb.WriteString("| | |\n|---|---|\n")
+	sb.WriteString(fmt.Sprintf("| **Status** | %s |\n", status))
+	sb.WriteString(fmt.Sprintf("| **Service** | %s |\n", latest.Service))
+	sb.WriteString(fmt.Sprintf("| **Alert** | %s (%s) |\n", latest.AlertName, latest.Severity))
+	sb.WriteString(fmt.Sprintf("| **Peak risk** | %s |\n", peakRisk(timeline)))
+	sb.WriteString(fmt.Sprintf("| **Started** | %s |\n", latest.FirstSeen.Format(timeLayout)))
+	if latest.Resolved() {
+		sb.WriteString(fmt.Sprintf("| **Resolved** | %s |\n", latest.ResolvedAt.Format(timeLayout)))
+	}
+	sb.WriteString(fmt.Sprintf("| **Duration** | %s |\n\n", end.Sub(latest.FirstSeen).Round(time.Second)))
+
+	// Summary
+	sb.WriteString("## Summary\n\n")
+	if analysis.Summary != "" {
+		sb.WriteString(analysis.Summary + "\n\n")
+	} else {
+		sb.WriteString(analysis.RootCause + "\n\n")
+	}
+
+	// Impact
+	sb.WriteString("## Impact\n\n")
+	sb.WriteString(fmt.Sprintf("- `%s` alerted with severity **%s** for %s\n",
+		latest.Service, latest.Severity, end.Sub(latest.FirstSeen).Round(time.Second)))
+	for _, pattern := range sortedKeys(peakSymptoms(timeline)) {
+		sb.WriteString(fmt.Sprintf("- Log symptom `%s` peaked at %d occurrences\n", pattern, peakSymptoms(timeline)[pattern]))
+	}
+	for _, name := range latest.Metrics {
+		sb.WriteString(fmt.Sprintf("- Metric `%s` breached its threshold\n", name))
+	}
+	sb.WriteString("\n")
+
+	// Timeline
+	sb.WriteString("## Timeline\n\n")
+	sb.WriteString("| Time | Event |\n|------|-------|\n")
+	sb.WriteString(fmt.Sprintf("| %s | Alert `%s` started firing |\n", latest.FirstSeen.Format(timeLayout), latest.AlertName))
+	for _, snap := range timeline {
+		if snap.Resolved() {
+			continue
+		}
+		event := fmt.Sprintf("Analysis: risk **%s**", snap.Risk)
+		if snap.Confidence > 0 {
+			event += fmt.Sprintf(" (%.0f%% confidence)", snap.Confidence*100)
+		}
+		if len(snap.SymptomCounts) > 0 {
+			var parts []string
+			for _, pattern := range sortedKeys(snap.SymptomCounts) {
+				parts = append(parts, fmt.Sprintf("%s x%d", pattern, snap.SymptomCounts[pattern]))
+			}
+			event += "; symptoms: " + strings.Join(parts, ", ")
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s |\n", snap.RecordedAt.Format(timeLayout), escapeCell(event)))
+	}
+	if latest.Resolved() {
+		sb.WriteString(fmt.Sprintf("| %s | Alert resolved |\n", latest.ResolvedAt.Format(timeLayout)))
+	}
+	sb.WriteString("\n")
+
+	// Metric timeline
+	if metrics := metricNames(timeline); len(metrics) > 0 {
+		sb.WriteString("### Metric Timeline\n\n")
+		sb.WriteString("| Time | " + strings.Join(metrics, " | ") + " |\n")
+		sb.WriteString("|------" + strings.Repeat("|------", len(metrics)) + "|\n")
+		for _, snap := range timeline {
+			if snap.Resolved() || len(snap.MetricValues) == 0 {
+				continue
+			}
+			row := []string{snap.RecordedAt.Format(timeLayout)}
+			for _, name := range metrics {
+				if v, ok := snap.MetricValues[name]; ok {
+					row = append(row, fmt.Sprintf("%.3f", v))
+				} else {
+					row = append(row, "-")
+				}
+			}
+			sb.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	// Root cause
+	sb.WriteString("## Root Cause\n\n")
+	sb.WriteString(analysis.RootCause + "\n\n")
+
+	// Action items
+	sb.WriteString("## Action Items\n\n")
+	for _, action := range analysis.ImmediateActions {
+		sb.WriteString(fmt.Sprintf("- [ ] %s\n", action))
+	}
+	for _, step := range analysis.Investigation {
+		sb.WriteString(fmt.Sprintf("- [ ] Investigate: %s\n", step))
+	}
+	if analysis.Prevention != "" {
+		sb.WriteString(fmt.Sprintf("- [ ] Prevention: %s\n", analysis.Prevention))
+	}
+	sb.WriteString("\n")
+
+	sb.WriteString(fmt.Sprintf("_Generated by Vigilant on %s_\n", time.Now().Format(timeLayout)))
+
+	return sb.String(), nil
+}
+
+// latestAnalysis returns the last snapshot that carries an analysis
+func latestAnalysis(timeline []history.Incident) history.Incident {
+	for i := len(timeline) - 1; i >= 0; i-- {
+		if timeline[i].RootCause != "" {
+			return timeline[i]
+		}
+	}
+	return timeline[len(timeline)-1]
+}
+
+func peakRisk(timeline []history.Incident) string {
+	peak := ""
+	for _, snap := range timeline {
+		if risk.LevelRank(snap.Risk) > risk.LevelRank(peak) {
+			peak = snap.Risk
+		}
+	}
+	if peak == "" {
+		return "Unknown"
+	}
+	return peak
+}
+
+func peakSymptoms(timeline []history.Incident) map[string]int {
+	peak := make(map[string]int)
+	for _, snap := range timeline {
+		for pattern, count := range snap.SymptomCounts {
+			if count > peak[pattern] {
+				peak[pattern] = count
+			}
+		}
+	}
+	return peak
+}
+
+func metricNames(timeline []history.Incident) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, snap := range timeline {
+		for name := range snap.MetricValues {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// escapeCell keeps pipes in free text from breaking Markdown tables
+func escapeCell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
@@ -0,0 +1,225 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RiskAnalysis is the current analysis of one alert of a service, with the correlation behind it
+type RiskAnalysis struct {
+	Alert       string
+	Severity    string
+	Risk        string
+	Score       int
+	HealthScore int
+	Confidence  float64
+	Trend       string
+	State       string
+	IncidentID  string
+	StartsAt    time.Time // Zero when unknown
+	AnalyzedAt  time.Time
+	Ack         *Ack
+	EscalatedAt time.Time // Zero unless escalated
+
+	Symptoms []Symptom
+	Metrics  []Metric
+	SLOs     []SLO
+
+	LikelyCulprit    string
+	FiringUpstream   []string
+	FiringDownstream []string
+	SameFailure      []string // Services failing the same way
+	Traces           *Traces
+
+	Summary          string
+	RootCause        string
+	ImmediateActions []string
+	Investigation    []string
+	Prevention       string
+	Runbooks         []Runbook
+}
+
+// Ack is an acknowledgement, or a snooze when Until is set
+type Ack struct {
+	By    string
+	At    time.Time
+	Until time.Time
+}
+
+type Symptom struct {
+	Pattern  string
+	Severity string
+	Count    int
+}
+
+type Metric struct {
+	Name      string
+	Value     float64
+	Operator  string
+	Threshold float64
+	Offenders int // Series that triggered
+	Series    int // Series the query returned, 0 when not reported
+}
+
+type SLO struct {
+	Name      string
+	Target    float64 // Percentage
+	Window    string
+	BurnRates []BurnRate
+}
+
+type BurnRate struct {
+	Window string
+	Rate   float64
+}
+
+type Traces struct {
+	Errors     int
+	Slow       int
+	Downstream string
+}
+
+type Runbook struct {
+	Name string
+	URL  string
+}
+
+// RiskReport renders the correlation and analysis of a service's current alerts as Markdown
+func RiskReport(service string, analyses []RiskAnalysis, now time.Time) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Risk Report: %s\n\n", service))
+	sb.WriteString(fmt.Sprintf("_Generated by Vigilant on %s_\n\n", now.Format(timeLayout)))
+
+	for _, a := range analyses {
+		sb.WriteString(fmt.Sprintf("## %s (%s)\n\n", a.Alert, a.Severity))
+
+		sb.WriteString("| | |\n|---|---|\n")
+		sb.WriteString(fmt.Sprintf("| **Risk** | %s (score %d, health %d) |\n", a.Risk, a.Score, a.HealthScore))
+		if a.Confidence > 0 {
+			sb.WriteString(fmt.Sprintf("| **Confidence** | %.0f%% |\n", a.Confidence*100))
+		}
+		if a.Trend != "" {
+			sb.WriteString(fmt.Sprintf("| **Trend** | %s |\n", a.Trend))
+		}
+		if a.State != "" {
+			sb.WriteString(fmt.Sprintf("| **State** | %s |\n", a.State))
+		}
+		if a.IncidentID != "" {
+			sb.WriteString(fmt.Sprintf("| **Incident** | %s |\n", a.IncidentID))
+		}
+		if !a.StartsAt.IsZero() {
+			sb.WriteString(fmt.Sprintf("| **Alert started** | %s |\n", a.StartsAt.Format(timeLayout)))
+		}
+		sb.WriteString(fmt.Sprintf("| **Analyzed** | %s |\n", a.AnalyzedAt.Format(timeLayout)))
+		if a.Ack != nil {
+			ack := "by " + a.Ack.By + " at " + a.Ack.At.Format(timeLayout)
+			if !a.Ack.Until.IsZero() {
+				ack = "snoozed " + ack + " until " + a.Ack.Until.Format(timeLayout)
+			}
+			sb.WriteString(fmt.Sprintf("| **Acknowledged** | %s |\n", escapeCell(ack)))
+		}
+		if !a.EscalatedAt.IsZero() {
+			sb.WriteString(fmt.Sprintf("| **Escalated** | %s |\n", a.EscalatedAt.Format(timeLayout)))
+		}
+		sb.WriteString("\n")
+
+		if a.Summary != "" {
+			sb.WriteString("### Summary\n\n" + a.Summary + "\n\n")
+		}
+		if a.RootCause != "" {
+			sb.WriteString("### Root Cause\n\n" + a.RootCause + "\n\n")
+		}
+		writeCorrelation(&sb, service, a)
+
+		if len(a.ImmediateActions)+len(a.Investigation) > 0 || a.Prevention != "" {
+			sb.WriteString("### Actions\n\n")
+			for _, action := range a.ImmediateActions {
+				sb.WriteString(fmt.Sprintf("- [ ] %s\n", action))
+			}
+			for _, step := range a.Investigation {
+				sb.WriteString(fmt.Sprintf("- [ ] Investigate: %s\n", step))
+			}
+			if a.Prevention != "" {
+				sb.WriteString(fmt.Sprintf("- [ ] Prevention: %s\n", a.Prevention))
+			}
+			sb.WriteString("\n")
+		}
+
+		if len(a.Runbooks) > 0 {
+			sb.WriteString("### Runbooks\n\n")
+			for _, rb := range a.Runbooks {
+				sb.WriteString(fmt.Sprintf("- [%s](%s)\n", rb.Name, rb.URL))
+			}
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// writeCorrelation renders the symptoms, metrics, SLOs and related services behind an analysis
+func writeCorrelation(sb *strings.Builder, service string, a RiskAnalysis) {
+	if len(a.Symptoms) > 0 {
+		sb.WriteString("### Log Symptoms\n\n| Pattern | Severity | Count |\n|---------|----------|-------|\n")
+		for _, s := range a.Symptoms {
+			sb.WriteString(fmt.Sprintf("| `%s` | %s | %d |\n", escapeCell(s.Pattern), s.Severity, s.Count))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(a.Metrics) > 0 {
+		sb.WriteString("### Metrics\n\n| Metric | Value | Threshold | Offending series |\n|--------|-------|-----------|------------------|\n")
+		for _, m := range a.Metrics {
+			offenders := "-"
+			if m.Series > 0 {
+				offenders = fmt.Sprintf("%d of %d", m.Offenders, m.Series)
+			}
+			sb.WriteString(fmt.Sprintf("| %s | %.3f | %s %.3f | %s |\n", escapeCell(m.Name), m.Value, m.Operator, m.Threshold, offenders))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(a.SLOs) > 0 {
+		sb.WriteString("### SLOs\n\n| SLO | Target | Burn rates |\n|-----|--------|------------|\n")
+		for _, slo := range a.SLOs {
+			var rates []string
+			for _, br := range slo.BurnRates {
+				rates = append(rates, fmt.Sprintf("%s: %.1fx", br.Window, br.Rate))
+			}
+			sb.WriteString(fmt.Sprintf("| %s | %g%% over %s | %s |\n", escapeCell(slo.Name), slo.Target, slo.Window, strings.Join(rates, ", ")))
+		}
+		sb.WriteString("\n")
+	}
+
+	var related []string
+	if a.LikelyCulprit != "" && a.LikelyCulprit != service {
+		related = append(related, fmt.Sprintf("Likely culprit: `%s`", a.LikelyCulprit))
+	}
+	if len(a.FiringUpstream) > 0 {
+		related = append(related, "Alerting upstream: "+codeList(a.FiringUpstream))
+	}
+	if len(a.FiringDownstream) > 0 {
+		related = append(related, "Alerting downstream: "+codeList(a.FiringDownstream))
+	}
+	if len(a.SameFailure) > 0 {
+		related = append(related, "Failing the same way: "+codeList(a.SameFailure))
+	}
+	if t := a.Traces; t != nil {
+		line := fmt.Sprintf("Traces: %d with errors, %d slow", t.Errors, t.Slow)
+		if t.Downstream != "" {
+			line += fmt.Sprintf(", pointing at `%s`", t.Downstream)
+		}
+		related = append(related, line)
+	}
+	if len(related) > 0 {
+		sb.WriteString("### Related Services\n\n")
+		for _, line := range related {
+			sb.WriteString("- " + line + "\n")
+		}
+		sb.WriteString("\n")
+	}
+}
+
+func codeList(values []string) string {
+	return "`" + strings.Join(values, "`, `") + "`"
+}
@@ -0,0 +1,164 @@
+package ringstore
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DefaultCapacity is the number of records kept per key when none is given: a day at one
+// record every 30s cycle
+const DefaultCapacity = 2880
+
+// Store keeps the newest records of every key in ring buffers and appends every record to a
+// JSONL file, which is compacted to the buffered records once it doubles in size. Records are
+// keyed by service and an optional sub-key, e.g. a metric check; "" when there is none.
+type Store[T any] struct {
+	name     string // e.g. "metric history", for logs and errors
+	path     string
+	capacity int
+	key      func(T) (service, sub string)
+	buffers  map[string]map[string][]T // service -> sub-key -> records, oldest first
+	appended int                       // Lines written since the file was last compacted
+	held     int                       // Records held across all buffers
+	mu       sync.RWMutex
+}
+
+// Open loads previously recorded records from path (created if missing), keeping up to
+// capacity records per key
+func Open[T any](name, path string, capacity int, key func(T) (service, sub string)) (*Store[T], error) {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	s := &Store[T]{
+		name:     name,
+		path:     path,
+		capacity: capacity,
+		key:      key,
+		buffers:  make(map[string]map[string][]T),
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s directory: %w", name, err)
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s file: %w", name, err)
+	}
+	defer file.Close()
+
+	tag := strings.ToUpper(name)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record T
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			fmt.Printf("[%s] Skipping malformed record: %v\n", tag, err)
+			continue
+		}
+		s.add(record)
+		s.appended++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s file: %w", name, err)
+	}
+
+	fmt.Printf("[%s] Loaded %d points from %s\n", tag, s.held, path)
+	return s, nil
+}
+
+// add appends record to its ring buffer, dropping the oldest record when it is full
+func (s *Store[T]) add(record T) {
+	service, sub := s.key(record)
+	subs, ok := s.buffers[service]
+	if !ok {
+		subs = make(map[string][]T)
+		s.buffers[service] = subs
+	}
+	records := append(subs[sub], record)
+	if len(records) > s.capacity {
+		records = records[len(records)-s.capacity:]
+	} else {
+		s.held++
+	}
+	subs[sub] = records
+}
+
+// Record persists a batch of records, e.g. one cycle's
+func (s *Store[T]) Record(records []T) error {
+	if len(records) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s file: %w", s.name, err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s record: %w", s.name, err)
+		}
+		writer.Write(append(line, '\n'))
+		s.add(record)
+		s.appended++
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", s.name, err)
+	}
+
+	if s.appended > 2*s.held {
+		return s.compactLocked()
+	}
+	return nil
+}
+
+// compactLocked rewrites the file with only the buffered records
+func (s *Store[T]) compactLocked() error {
+	tmp := s.path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to compact %s: %w", s.name, err)
+	}
+	writer := bufio.NewWriter(file)
+	for _, subs := range s.buffers {
+		for _, records := range subs {
+			for _, record := range records {
+				line, _ := json.Marshal(record)
+				writer.Write(append(line, '\n'))
+			}
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to compact %s: %w", s.name, err)
+	}
+	file.Close()
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to compact %s: %w", s.name, err)
+	}
+	s.appended = s.held
+	return nil
+}
+
+// Read calls fn with each buffer of service, oldest record first, under the read lock; fn must
+// neither modify nor keep the records
+func (s *Store[T]) Read(service string, fn func(sub string, records []T)) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for sub, records := range s.buffers[service] {
+		fn(sub, records)
+	}
+}
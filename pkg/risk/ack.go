@@ -0,0 +1,84 @@
+package risk
+
+import (
+	"fmt"
+	"time"
+)
+
+// Ack records that an operator is handling a service's incident. An acknowledgement lasts
+// until the service's alerts resolve; a snooze also ends at Until.
+type Ack struct {
+	Service string
+	By      string
+	Comment string
+	At      time.Time
+	Until   time.Time // Zero for acknowledgements
+}
+
+// Snoozed reports whether the ack is a snooze
+func (a Ack) Snoozed() bool {
+	return !a.Until.IsZero()
+}
+
+// Acknowledge acknowledges the service's incident, or snoozes it for snooze when positive.
+// It fails when the service has no tracked alerts.
+func (rt *RiskTracker) Acknowledge(service, by, comment string, snooze time.Duration) (Ack, error) {
+	rt.Mutex.Lock()
+	defer rt.Mutex.Unlock()
+
+	if !rt.hasServiceLocked(service) {
+		return Ack{}, fmt.Errorf("no active alerts for %s", service)
+	}
+	ack := Ack{Service: service, By: by, Comment: comment, At: time.Now()}
+	if snooze > 0 {
+		ack.Until = ack.At.Add(snooze)
+	}
+	if rt.acks == nil {
+		rt.acks = make(map[string]Ack)
+	}
+	rt.acks[service] = ack
+	return ack, nil
+}
+
+// Unacknowledge removes the service's acknowledgement or snooze; ok is false when it had none
+func (rt *RiskTracker) Unacknowledge(service string) bool {
+	rt.Mutex.Lock()
+	defer rt.Mutex.Unlock()
+	_, ok := rt.acks[service]
+	delete(rt.acks, service)
+	return ok
+}
+
+// Acknowledged returns the service's current acknowledgement, dropping an ended snooze
+func (rt *RiskTracker) Acknowledged(service string) (Ack, bool) {
+	rt.Mutex.Lock()
+	defer rt.Mutex.Unlock()
+
+	ack, ok := rt.acks[service]
+	if ok && ack.Snoozed() && !time.Now().Before(ack.Until) {
+		fmt.Printf("[INFO] Snooze of %s ended\n", service)
+		delete(rt.acks, service)
+		return Ack{}, false
+	}
+	return ack, ok
+}
+
+// hasServiceLocked reports whether any tracked alert belongs to service; the caller holds the mutex
+func (rt *RiskTracker) hasServiceLocked(service string) bool {
+	for _, item := range rt.Items {
+		if item.Service == service {
+			return true
+		}
+	}
+	return false
+}
+
+// dropResolvedAcksLocked forgets the acknowledgements of services without tracked alerts, so
+// a new incident isn't born acknowledged; the caller holds the mutex
+func (rt *RiskTracker) dropResolvedAcksLocked() {
+	for service := range rt.acks {
+		if !rt.hasServiceLocked(service) {
+			delete(rt.acks, service)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package risk
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"vigilant/pkg/config"
+)
+
+// Decay lets scores wind down instead of dropping: a service whose score fell keeps its
+// earlier peak, halved every half life, until the new score catches up. Services that are no
+// longer analyzed at all keep fading the same way until they drop below the minimum score.
+type Decay struct {
+	mu       sync.Mutex
+	halfLife time.Duration
+	minScore int
+	peaks    map[string]decayPeak
+}
+
+type decayPeak struct {
+	score int
+	at    time.Time
+}
+
+// NewDecay returns a decay following cfg
+func NewDecay(cfg config.DecayScoring) *Decay {
+	return &Decay{
+		halfLife: time.Duration(cfg.HalfLifeMinutes) * time.Minute,
+		minScore: cfg.MinScore,
+		peaks:    make(map[string]decayPeak),
+	}
+}
+
+// decayed is the peak's score after the time since it was reached
+func (d *Decay) decayed(peak decayPeak, now time.Time) int {
+	if d.halfLife <= 0 {
+		return 0
+	}
+	halvings := now.Sub(peak.at).Seconds() / d.halfLife.Seconds()
+	return int(math.Round(float64(peak.score) * math.Pow(0.5, halvings)))
+}
+
+// Apply returns the score to show for a freshly scored service: the fresh score, or the
+// decaying earlier peak while that is still higher, in which case recovering is true
+func (d *Decay) Apply(key string, score int, now time.Time) (shown int, recovering bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if peak, ok := d.peaks[key]; ok {
+		if decayed := d.decayed(peak, now); decayed > score && decayed >= d.minScore {
+			return decayed, true
+		}
+	}
+	d.peaks[key] = decayPeak{score: score, at: now}
+	return score, false
+}
+
+// Fade returns the decayed scores of the services not scored this cycle, forgetting those
+// that fell below the minimum score
+func (d *Decay) Fade(scored map[string]bool, now time.Time) map[string]int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	fading := make(map[string]int)
+	for key, peak := range d.peaks {
+		if scored[key] {
+			continue
+		}
+		decayed := d.decayed(peak, now)
+		if decayed < d.minScore || decayed == 0 {
+			delete(d.peaks, key)
+			continue
+		}
+		fading[key] = decayed
+	}
+	return fading
+}
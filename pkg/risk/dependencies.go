@@ -0,0 +1,184 @@
+package risk
+
+import (
+	"slices"
+	"sort"
+)
+
+// DependencyContext places an alerting service in the dependency graph: what it is wired to,
+// which of its upstreams and downstreams alert with it, and which service most likely started
+// the failure of the group they form
+type DependencyContext struct {
+	DependsOn        []string // Direct dependencies
+	ConsumedBy       []string // Direct dependents
+	Group            []string // Alerting services connected to this one through dependencies, itself included
+	LikelyCulprit    string   // Most upstream alerting service of the group; empty when alerting alone
+	FiringUpstream   []string // Alerting services this one depends on, directly or not
+	FiringDownstream []string // Alerting services depending on this one, directly or not
+	BlastRadius      []string // Every service depending on this one, directly or not
+}
+
+// Empty reports whether the service has no dependencies configured
+func (c DependencyContext) Empty() bool {
+	return len(c.DependsOn) == 0 && len(c.ConsumedBy) == 0
+}
+
+// DependencyGraph links services to the services they depend on
+type DependencyGraph struct {
+	dependsOn  map[string][]string
+	consumedBy map[string][]string
+}
+
+// NewDependencyGraph builds the graph from each service's dependencies
+func NewDependencyGraph(dependsOn map[string][]string) *DependencyGraph {
+	g := &DependencyGraph{
+		dependsOn:  make(map[string][]string),
+		consumedBy: make(map[string][]string),
+	}
+	for service, deps := range dependsOn {
+		for _, dep := range deps {
+			if dep == service || slices.Contains(g.dependsOn[service], dep) {
+				continue
+			}
+			g.dependsOn[service] = append(g.dependsOn[service], dep)
+			g.consumedBy[dep] = append(g.consumedBy[dep], service)
+		}
+	}
+	for _, edges := range []map[string][]string{g.dependsOn, g.consumedBy} {
+		for _, services := range edges {
+			sort.Strings(services)
+		}
+	}
+	return g
+}
+
+// Empty reports whether no dependencies are configured
+func (g *DependencyGraph) Empty() bool {
+	return len(g.dependsOn) == 0
+}
+
+// Analyze returns the dependency context of every alerting service. Alerting services
+// connected through dependencies, even via services that aren't alerting, form a group whose
+// likely culprit is the member with no alerting upstream that most other members depend on.
+func (g *DependencyGraph) Analyze(alerting []string) map[string]DependencyContext {
+	firing := make(map[string]bool)
+	for _, service := range alerting {
+		firing[service] = true
+	}
+
+	upstream := make(map[string][]string)
+	downstream := make(map[string][]string)
+	for service := range firing {
+		upstream[service] = filter(reachable(g.dependsOn, service), firing)
+		downstream[service] = filter(reachable(g.consumedBy, service), firing)
+	}
+
+	// Group the alerting services linked by an upstream relation
+	groupOf := make(map[string]int)
+	var groups [][]string
+	for _, service := range sortedKeys(firing) {
+		if _, ok := groupOf[service]; ok {
+			continue
+		}
+		id := len(groups)
+		var members []string
+		queue := []string{service}
+		groupOf[service] = id
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			members = append(members, current)
+			for _, next := range append(append([]string{}, upstream[current]...), downstream[current]...) {
+				if _, ok := groupOf[next]; !ok {
+					groupOf[next] = id
+					queue = append(queue, next)
+				}
+			}
+		}
+		sort.Strings(members)
+		groups = append(groups, members)
+	}
+
+	culprits := make([]string, len(groups))
+	for id, members := range groups {
+		if len(members) > 1 {
+			culprits[id] = culprit(members, upstream, downstream)
+		}
+	}
+
+	contexts := make(map[string]DependencyContext)
+	for service := range firing {
+		group := groups[groupOf[service]]
+		contexts[service] = DependencyContext{
+			DependsOn:        g.dependsOn[service],
+			ConsumedBy:       g.consumedBy[service],
+			Group:            group,
+			LikelyCulprit:    culprits[groupOf[service]],
+			FiringUpstream:   upstream[service],
+			FiringDownstream: downstream[service],
+			BlastRadius:      reachable(g.consumedBy, service),
+		}
+	}
+	return contexts
+}
+
+// culprit picks the group member without alerting upstreams that the most members depend on;
+// in a dependency cycle every member qualifies
+func culprit(members []string, upstream, downstream map[string][]string) string {
+	candidates := make([]string, 0, len(members))
+	for _, member := range members {
+		if len(upstream[member]) == 0 {
+			candidates = append(candidates, member)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = members
+	}
+	best := candidates[0]
+	for _, candidate := range candidates[1:] {
+		if len(downstream[candidate]) > len(downstream[best]) {
+			best = candidate
+		}
+	}
+	return best
+}
+
+// reachable returns the services reachable from start along edges, sorted and without start
+func reachable(edges map[string][]string, start string) []string {
+	visited := map[string]bool{start: true}
+	queue := []string{start}
+	var result []string
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range edges[current] {
+			if !visited[next] {
+				visited[next] = true
+				result = append(result, next)
+				queue = append(queue, next)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// filter keeps the services in set
+func filter(services []string, set map[string]bool) []string {
+	var result []string
+	for _, service := range services {
+		if set[service] {
+			result = append(result, service)
+		}
+	}
+	return result
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
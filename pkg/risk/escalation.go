@@ -0,0 +1,111 @@
+package risk
+
+import (
+	"strings"
+	"time"
+)
+
+// Escalation tracks a service's time at high or critical risk without acknowledgement
+type Escalation struct {
+	Since       time.Time `json:"since"`        // When the service reached high or critical risk
+	EscalatedAt time.Time `json:"escalated_at"` // Zero until the service is escalated
+}
+
+// Escalated reports whether the service has been escalated
+func (e Escalation) Escalated() bool {
+	return !e.EscalatedAt.IsZero()
+}
+
+// Escalator escalates services left at high or critical risk without acknowledgement for
+// longer than their escalation delay. Escalated services get their score bumped until they
+// calm down or are acknowledged.
+type Escalator struct {
+	after     time.Duration
+	bump      int
+	overrides map[string]time.Duration
+	services  map[string]Escalation
+}
+
+// NewEscalator escalates after the given delay (0 disables escalation for services without an
+// override) and bumps escalated scores by bump points
+func NewEscalator(after time.Duration, bump int) *Escalator {
+	return &Escalator{
+		after:    after,
+		bump:     bump,
+		services: make(map[string]Escalation),
+	}
+}
+
+// SetServiceDelays overrides the escalation delay for the given services
+func (e *Escalator) SetServiceDelays(delays map[string]time.Duration) {
+	e.overrides = delays
+}
+
+// delayFor returns the escalation delay of service; 0 never escalates it
+func (e *Escalator) delayFor(service string) time.Duration {
+	if delay, ok := e.overrides[service]; ok {
+		return delay
+	}
+	return e.after
+}
+
+// Escalates reports whether a risk level counts towards escalation
+func Escalates(riskLevel string) bool {
+	switch strings.ToLower(riskLevel) {
+	case "high", "critical":
+		return true
+	}
+	return false
+}
+
+// Observe records whether service is at high or critical risk without acknowledgement this
+// cycle and returns its escalation state; newly is true in the cycle it gets escalated. A
+// service that calms down or is acknowledged starts over.
+func (e *Escalator) Observe(service string, hot bool, now time.Time) (state Escalation, newly bool) {
+	delay := e.delayFor(service)
+	if !hot || delay <= 0 {
+		delete(e.services, service)
+		return Escalation{}, false
+	}
+
+	state, ok := e.services[service]
+	if !ok {
+		state = Escalation{Since: now}
+	}
+	if !state.Escalated() && now.Sub(state.Since) >= delay {
+		state.EscalatedAt = now
+		newly = true
+	}
+	e.services[service] = state
+	return state, newly
+}
+
+// Prune forgets the services that are no longer alerting
+func (e *Escalator) Prune(alerting map[string]bool) {
+	for service := range e.services {
+		if !alerting[service] {
+			delete(e.services, service)
+		}
+	}
+}
+
+// Bump raises an escalated service's score, capped at 100
+func (e *Escalator) Bump(score int) int {
+	return min(100, score+e.bump)
+}
+
+// Snapshot returns the tracked services, e.g. to persist them across restarts
+func (e *Escalator) Snapshot() map[string]Escalation {
+	snapshot := make(map[string]Escalation, len(e.services))
+	for service, state := range e.services {
+		snapshot[service] = state
+	}
+	return snapshot
+}
+
+// Restore reloads services saved with Snapshot
+func (e *Escalator) Restore(snapshot map[string]Escalation) {
+	for service, state := range snapshot {
+		e.services[service] = state
+	}
+}
@@ -0,0 +1,201 @@
+package risk
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"vigilant/pkg/hashutil"
+)
+
+// Incident statuses
+const (
+	IncidentOpen     = "open"
+	IncidentResolved = "resolved"
+)
+
+// resolvedIncidentsKept is how many resolved incidents stay available after they close
+const resolvedIncidentsKept = 100
+
+// Incident groups the alerts of a service, or of services alerting together along their
+// dependencies, from the first alert until none is left. Its ID stays the same for its whole
+// lifetime and is shared by the API, the LLM cache and the incident history.
+type Incident struct {
+	ID         string    `json:"id"`
+	Service    string    `json:"service"`  // Service whose alert opened the incident
+	Services   []string  `json:"services"` // Every service that alerted during the incident
+	Alerts     []string  `json:"alerts"`   // Every alert seen, as service/alert name
+	Status     string    `json:"status"`
+	OpenedAt   time.Time `json:"opened_at"`
+	UpdatedAt  time.Time `json:"updated_at"`  // When the last service or alert joined
+	ResolvedAt time.Time `json:"resolved_at"` // Zero while open
+}
+
+// Incidents opens, updates and resolves incidents from the tracked alerts
+type Incidents struct {
+	open      map[string]*Incident // ID -> open incident
+	byService map[string]string    // Service -> ID of its open incident
+	resolved  []Incident           // Most recently resolved last
+	mu        sync.RWMutex
+}
+
+func NewIncidents() *Incidents {
+	return &Incidents{
+		open:      make(map[string]*Incident),
+		byService: make(map[string]string),
+	}
+}
+
+// incidentID derives the ID of an incident from the service that opened it and when
+func incidentID(service string, openedAt time.Time) string {
+	hash := hashutil.HashData([]interface{}{service, openedAt.UnixNano()})
+	return "INC-" + hash[:6]
+}
+
+// Update assigns every item to an incident and sets its IncidentID. A service joins the open
+// incident of an alerting service in its dependency group (see DependencyGraph.Analyze) or
+// opens its own; incidents without alerting services are resolved. Returns the incidents
+// opened and resolved.
+func (inc *Incidents) Update(items []*RiskItem, groups map[string]DependencyContext, now time.Time) (opened, resolved []Incident) {
+	inc.mu.Lock()
+	defer inc.mu.Unlock()
+
+	byService := make(map[string][]*RiskItem)
+	for _, item := range items {
+		byService[item.Service] = append(byService[item.Service], item)
+	}
+	services := make([]string, 0, len(byService))
+	for service := range byService {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	alerting := make(map[string]bool)
+	for _, service := range services {
+		incident := inc.incidentForLocked(service, groups[service].Group)
+		if incident == nil {
+			incident = &Incident{
+				ID:       incidentID(service, now),
+				Service:  service,
+				Status:   IncidentOpen,
+				OpenedAt: now,
+			}
+			inc.open[incident.ID] = incident
+			opened = append(opened, *incident)
+		}
+		inc.byService[service] = incident.ID
+		alerting[incident.ID] = true
+
+		if !slices.Contains(incident.Services, service) {
+			incident.Services = append(incident.Services, service)
+			incident.UpdatedAt = now
+		}
+		for _, item := range byService[service] {
+			alert := service + "/" + item.AlertName
+			if !slices.Contains(incident.Alerts, alert) {
+				incident.Alerts = append(incident.Alerts, alert)
+				incident.UpdatedAt = now
+			}
+			item.IncidentID = incident.ID
+		}
+	}
+
+	for id, incident := range inc.open {
+		if alerting[id] {
+			continue
+		}
+		incident.Status = IncidentResolved
+		incident.ResolvedAt = now
+		delete(inc.open, id)
+		for _, service := range incident.Services {
+			if inc.byService[service] == id {
+				delete(inc.byService, service)
+			}
+		}
+		inc.resolved = append(inc.resolved, *incident)
+		resolved = append(resolved, *incident)
+	}
+	if len(inc.resolved) > resolvedIncidentsKept {
+		inc.resolved = inc.resolved[len(inc.resolved)-resolvedIncidentsKept:]
+	}
+	return opened, resolved
+}
+
+// incidentForLocked returns the open incident of service, else that of a member of its group
+func (inc *Incidents) incidentForLocked(service string, group []string) *Incident {
+	if id, ok := inc.byService[service]; ok {
+		return inc.open[id]
+	}
+	for _, member := range group {
+		if id, ok := inc.byService[member]; ok {
+			return inc.open[id]
+		}
+	}
+	return nil
+}
+
+// Get returns the incident with the given ID, open or recently resolved
+func (inc *Incidents) Get(id string) (Incident, bool) {
+	inc.mu.RLock()
+	defer inc.mu.RUnlock()
+
+	if incident, ok := inc.open[id]; ok {
+		return copyIncident(*incident), true
+	}
+	for i := len(inc.resolved) - 1; i >= 0; i-- {
+		if inc.resolved[i].ID == id {
+			return copyIncident(inc.resolved[i]), true
+		}
+	}
+	return Incident{}, false
+}
+
+// List returns the open incidents, oldest first, followed by the recently resolved ones when
+// resolved is set, most recent first
+func (inc *Incidents) List(resolved bool) []Incident {
+	inc.mu.RLock()
+	defer inc.mu.RUnlock()
+
+	list := make([]Incident, 0, len(inc.open))
+	for _, incident := range inc.open {
+		list = append(list, copyIncident(*incident))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].OpenedAt.Before(list[j].OpenedAt) })
+	if resolved {
+		for i := len(inc.resolved) - 1; i >= 0; i-- {
+			list = append(list, copyIncident(inc.resolved[i]))
+		}
+	}
+	return list
+}
+
+// copyIncident detaches the slices of an incident from the tracked one
+func copyIncident(incident Incident) Incident {
+	incident.Services = slices.Clone(incident.Services)
+	incident.Alerts = slices.Clone(incident.Alerts)
+	return incident
+}
+
+// Snapshot returns the open and recently resolved incidents, e.g. to persist them across restarts
+func (inc *Incidents) Snapshot() []Incident {
+	return inc.List(true)
+}
+
+// Restore reloads incidents saved with Snapshot
+func (inc *Incidents) Restore(incidents []Incident) {
+	inc.mu.Lock()
+	defer inc.mu.Unlock()
+
+	for i := len(incidents) - 1; i >= 0; i-- {
+		incident := incidents[i]
+		if incident.Status == IncidentResolved {
+			inc.resolved = append(inc.resolved, incident)
+			continue
+		}
+		inc.open[incident.ID] = &incident
+		for _, service := range incident.Services {
+			inc.byService[service] = incident.ID
+		}
+	}
+}
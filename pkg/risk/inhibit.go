@@ -0,0 +1,114 @@
+package risk
+
+import (
+	"fmt"
+
+	"vigilant/pkg/config"
+	"vigilant/pkg/prometheus"
+)
+
+// Inhibitor applies inhibit rules to the tracked alerts before they are correlated, so one
+// upstream failure is analyzed once instead of through each of its downstream alerts
+type Inhibitor struct {
+	rules []inhibitRule
+}
+
+type inhibitRule struct {
+	name   string
+	source []prometheus.SilenceMatcher
+	target []prometheus.SilenceMatcher
+	equal  []string
+}
+
+// NewInhibitor compiles the rules; LoadAlertingConfig has already validated their matchers
+func NewInhibitor(rules []config.InhibitRule) (*Inhibitor, error) {
+	inhibitor := &Inhibitor{}
+	for i, rule := range rules {
+		compiled := inhibitRule{name: rule.Name, equal: rule.Equal}
+		if compiled.name == "" {
+			compiled.name = fmt.Sprintf("rule %d", i)
+		}
+		for _, s := range rule.SourceMatchers {
+			m, err := prometheus.ParseMatcher(s)
+			if err != nil {
+				return nil, err
+			}
+			compiled.source = append(compiled.source, m)
+		}
+		for _, s := range rule.TargetMatchers {
+			m, err := prometheus.ParseMatcher(s)
+			if err != nil {
+				return nil, err
+			}
+			compiled.target = append(compiled.target, m)
+		}
+		inhibitor.rules = append(inhibitor.rules, compiled)
+	}
+	return inhibitor, nil
+}
+
+// Apply separates the targets muted by a firing source alert, setting their InhibitedBy.
+// Sources are looked up in all, which can include silenced alerts: like in Alertmanager, a
+// silenced source still inhibits. An alert never inhibits itself.
+func (in *Inhibitor) Apply(items, all []*RiskItem) (active, inhibited []*RiskItem) {
+	if in == nil || len(in.rules) == 0 {
+		return items, nil
+	}
+
+	var sources [][]*RiskItem
+	for _, rule := range in.rules {
+		var matching []*RiskItem
+		for _, item := range all {
+			if item.State != "pending" && matchAll(rule.source, item.MatchLabels()) {
+				matching = append(matching, item)
+			}
+		}
+		sources = append(sources, matching)
+	}
+
+	for _, item := range items {
+		if by := in.inhibitedBy(item, sources); by != "" {
+			item.InhibitedBy = by
+			inhibited = append(inhibited, item)
+			continue
+		}
+		active = append(active, item)
+	}
+	return active, inhibited
+}
+
+// inhibitedBy names the first source alert inhibiting item, or returns ""
+func (in *Inhibitor) inhibitedBy(item *RiskItem, sources [][]*RiskItem) string {
+	labels := item.MatchLabels()
+	for i, rule := range in.rules {
+		if !matchAll(rule.target, labels) {
+			continue
+		}
+		for _, source := range sources[i] {
+			if source.Fingerprint == item.Fingerprint {
+				continue
+			}
+			sourceLabels := source.MatchLabels()
+			equal := true
+			for _, name := range rule.equal {
+				if sourceLabels[name] != labels[name] {
+					equal = false
+					break
+				}
+			}
+			if equal {
+				return fmt.Sprintf("%s (%s, %s)", source.AlertName, source.Fingerprint, rule.name)
+			}
+		}
+	}
+	return ""
+}
+
+func matchAll(matchers []prometheus.SilenceMatcher, labels map[string]string) bool {
+	for _, m := range matchers {
+		if !m.Matches(labels[m.Name]) {
+			return false
+		}
+	}
+	return true
+}
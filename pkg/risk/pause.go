@@ -0,0 +1,114 @@
+package risk
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Pause halts monitoring, of every service or of one, until resumed or until Until. A paused
+// service's data isn't collected or analyzed; its last risk items stay published.
+type Pause struct {
+	Service string
+	By      string
+	Reason  string
+	At      time.Time
+	Until   time.Time // Zero until resumed
+}
+
+// Global reports whether the pause covers every service
+func (p Pause) Global() bool {
+	return p.Service == ""
+}
+
+// Pauses holds the current pauses
+type Pauses struct {
+	mu      sync.Mutex
+	pauses  map[string]Pause // By service, "" for the global pause
+	updates chan struct{}
+}
+
+func NewPauses() *Pauses {
+	return &Pauses{pauses: make(map[string]Pause), updates: make(chan struct{}, 1)}
+}
+
+// Pause starts or replaces the pause of p.Service, or the global pause
+func (ps *Pauses) Pause(p Pause) {
+	ps.mu.Lock()
+	ps.pauses[p.Service] = p
+	ps.mu.Unlock()
+	ps.notify()
+}
+
+// Resume ends the pause of service, or the global pause; ok is false when there was none
+func (ps *Pauses) Resume(service string) (Pause, bool) {
+	ps.mu.Lock()
+	ps.dropEndedLocked(time.Now())
+	p, ok := ps.pauses[service]
+	delete(ps.pauses, service)
+	ps.mu.Unlock()
+	if ok {
+		ps.notify()
+	}
+	return p, ok
+}
+
+// Paused returns the pause covering service: the global pause, else the service's own
+func (ps *Pauses) Paused(service string) (Pause, bool) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.dropEndedLocked(time.Now())
+	if p, ok := ps.pauses[""]; ok {
+		return p, true
+	}
+	p, ok := ps.pauses[service]
+	return p, ok
+}
+
+// GlobalPause returns the pause of every service, if any
+func (ps *Pauses) GlobalPause() (Pause, bool) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.dropEndedLocked(time.Now())
+	p, ok := ps.pauses[""]
+	return p, ok
+}
+
+// List returns the current pauses, the global one first
+func (ps *Pauses) List() []Pause {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.dropEndedLocked(time.Now())
+	list := make([]Pause, 0, len(ps.pauses))
+	for _, p := range ps.pauses {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Service < list[j].Service })
+	return list
+}
+
+// Updates signals when a pause started or ended early, to wake the monitoring loop
+func (ps *Pauses) Updates() <-chan struct{} {
+	return ps.updates
+}
+
+func (ps *Pauses) notify() {
+	select {
+	case ps.updates <- struct{}{}:
+	default:
+	}
+}
+
+func (ps *Pauses) dropEndedLocked(now time.Time) {
+	for service, p := range ps.pauses {
+		if !p.Until.IsZero() && !now.Before(p.Until) {
+			if p.Global() {
+				fmt.Println("[INFO] Monitoring pause ended")
+			} else {
+				fmt.Printf("[INFO] Monitoring pause of %s ended\n", service)
+			}
+			delete(ps.pauses, service)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package risk
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"vigilant/pkg/config"
+)
+
+// Scorer turns an analyzed service into its 0-100 risk score following config.ScoringConfig
+type Scorer struct {
+	cfg config.ScoringConfig
+}
+
+// NewScorer returns a scorer for cfg, typically loaded with config.LoadScoringConfig
+func NewScorer(cfg config.ScoringConfig) *Scorer {
+	return &Scorer{cfg: cfg}
+}
+
+// SymptomInput is one matched log pattern of a scored service
+type SymptomInput struct {
+	Severity string
+	Count    int
+}
+
+// ScoreInput is what a service's score is computed from
+type ScoreInput struct {
+	Severity      string // Alert severity label
+	Risk          string // LLM risk level; empty or "Unknown" before an analysis
+	Confidence    float64
+	Symptoms      []SymptomInput
+	MetricWeights []int // Weights of the triggered metric checks
+	Age           time.Duration
+	BurnRate      float64 // Highest error budget burn rate of the service's SLOs
+}
+
+// Score combines the rules: the higher of the severity and LLM risk points, then the symptom,
+// metric, SLO burn and age points, each filling the headroom left below 100. Until an LLM
+// analysis rated the risk, the health score stands in.
+func (s *Scorer) Score(in ScoreInput) int {
+	if _, rated := s.cfg.LLMRisk[strings.ToLower(in.Risk)]; !rated {
+		return s.HealthScore(in)
+	}
+	score := max(s.cfg.Severity[strings.ToLower(in.Severity)], s.LLMRiskScore(in.Risk, in.Confidence))
+	return s.addPoints(score, s.MetricScore(in.MetricWeights), in)
+}
+
+// HealthScore is the deterministic score of a service: its alert severity points plus the
+// symptom, metric, SLO burn and age points, without the LLM. An alerting service never scores 0.
+func (s *Scorer) HealthScore(in ScoreInput) int {
+	severity := strings.ToLower(in.Severity)
+	points, ok := s.cfg.Severity[severity]
+	if !ok {
+		points, ok = s.cfg.Health.Severity[severity]
+	}
+	if !ok {
+		points = s.cfg.Health.DefaultPoints
+	}
+	return s.addPoints(points, weightPoints(in.MetricWeights, s.cfg.Health.MetricWeightPoints), in)
+}
+
+// addPoints fills the headroom of score with the symptom, metric, SLO burn and age points
+func (s *Scorer) addPoints(score, metricPoints int, in ScoreInput) int {
+	score = combine(score, s.SymptomScore(in.Symptoms))
+	score = combine(score, metricPoints)
+	score = combine(score, s.BurnScore(in.BurnRate))
+	return combine(score, s.AgeScore(in.Age))
+}
+
+// LLMRiskScore maps an LLM risk level and confidence to points; unknown levels score 0
+func (s *Scorer) LLMRiskScore(risk string, confidence float64) int {
+	points, ok := s.cfg.LLMRisk[strings.ToLower(risk)]
+	if !ok {
+		return 0
+	}
+	return points.Base + int(confidence*float64(points.Confidence))
+}
+
+// SymptomScore converts matched symptoms into a 0-100 score. Counts grow the score
+// logarithmically, so a single critical match outranks a hundred warnings. Patterns without
+// a severity count as warnings.
+func (s *Scorer) SymptomScore(symptoms []SymptomInput) int {
+	score := 0.0
+	for _, symptom := range symptoms {
+		if symptom.Count <= 0 {
+			continue
+		}
+		weight, ok := s.cfg.SymptomWeights[strings.ToLower(symptom.Severity)]
+		if !ok {
+			weight = s.cfg.SymptomWeights["warning"]
+		}
+		score += weight * (1 + math.Log10(float64(symptom.Count)))
+	}
+	return int(math.Min(score, 100))
+}
+
+// MetricScore gives metric_weight_points per unit of weight of the triggered checks
+func (s *Scorer) MetricScore(weights []int) int {
+	return weightPoints(weights, s.cfg.MetricWeightPoints)
+}
+
+// weightPoints gives pointsPerWeight per unit of the summed weights, up to 100
+func weightPoints(weights []int, pointsPerWeight int) int {
+	total := 0
+	for _, w := range weights {
+		total += w
+	}
+	return min(total*pointsPerWeight, 100)
+}
+
+// BurnScore returns the points for an error budget burn rate: none up to a rate of 1, which
+// spends the budget exactly over its period, rising linearly to max_points at full_burn_rate
+func (s *Scorer) BurnScore(rate float64) int {
+	burn := s.cfg.SLOBurn
+	if rate <= 1 || burn.FullBurnRate <= 1 {
+		return 0
+	}
+	fraction := (rate - 1) / (burn.FullBurnRate - 1)
+	return int(math.Round(float64(burn.MaxPoints) * math.Min(fraction, 1)))
+}
+
+// AgeScore returns the points for an alert active for age. The log curve rises fastest in the
+// first minutes, the linear one evenly; both reach max_points at full_minutes.
+func (s *Scorer) AgeScore(age time.Duration) int {
+	curve := s.cfg.Age
+	if age <= 0 || curve.MaxPoints == 0 || curve.FullMinutes <= 0 {
+		return 0
+	}
+	var fraction float64
+	switch curve.Curve {
+	case "linear":
+		fraction = age.Minutes() / float64(curve.FullMinutes)
+	case "log":
+		fraction = math.Log1p(age.Minutes()) / math.Log1p(float64(curve.FullMinutes))
+	default:
+		return 0
+	}
+	return int(math.Round(float64(curve.MaxPoints) * math.Min(fraction, 1)))
+}
+
+// combine adds points to score in proportion to the headroom left, so every rule raises the
+// result without exceeding 100
+func combine(score, points int) int {
+	return score + points*(100-score)/100
+}
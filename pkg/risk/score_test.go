@@ -0,0 +1,152 @@
+package risk
+
+import (
+	"testing"
+	"time"
+
+	"vigilant/pkg/config"
+)
+
+// testScoring has round numbers so that the expected scores can be worked out by hand
+func testScoring() config.ScoringConfig {
+	return config.ScoringConfig{
+		Severity: map[string]int{"critical": 80},
+		LLMRisk: map[string]config.LLMRiskPoints{
+			"high": {Base: 70, Confidence: 20},
+			"low":  {Base: 10, Confidence: 30},
+		},
+		SymptomWeights:     map[string]float64{"critical": 30, "warning": 5},
+		MetricWeightPoints: 10,
+		Age:                config.AgeScoring{Curve: "linear", FullMinutes: 100, MaxPoints: 20},
+		SLOBurn:            config.SLOBurnScoring{FullBurnRate: 11, MaxPoints: 30},
+		Health: config.HealthScoring{
+			Severity:           map[string]int{"warning": 25},
+			DefaultPoints:      20,
+			MetricWeightPoints: 5,
+		},
+	}
+}
+
+func TestScorerScore(t *testing.T) {
+	tests := []struct {
+		name string
+		in   ScoreInput
+		want int
+	}{
+		{name: "LLM risk and confidence", in: ScoreInput{Risk: "High", Confidence: 0.5}, want: 80},
+		{name: "LLM risk is case-insensitive", in: ScoreInput{Risk: "HIGH", Confidence: 1}, want: 90},
+		{name: "severity above the LLM risk", in: ScoreInput{Severity: "critical", Risk: "Low"}, want: 80},
+		{name: "metric weights", in: ScoreInput{Risk: "High", MetricWeights: []int{1, 1}}, want: 76},
+		{name: "symptoms", in: ScoreInput{Risk: "High", Symptoms: []SymptomInput{{Severity: "critical", Count: 1}}}, want: 79},
+		{name: "age", in: ScoreInput{Risk: "High", Age: 50 * time.Minute}, want: 73},
+		{name: "SLO burn", in: ScoreInput{Risk: "High", BurnRate: 6}, want: 74},
+		{name: "capped at 100", in: ScoreInput{Severity: "critical", Risk: "High", Confidence: 1, Symptoms: []SymptomInput{{Severity: "critical", Count: 1000}}, Age: time.Hour}, want: 100},
+		{name: "unrated uses health severity", in: ScoreInput{Severity: "warning", Risk: "Unknown"}, want: 25},
+		{name: "unrated prefers the severity rule", in: ScoreInput{Severity: "critical"}, want: 80},
+		{name: "unrated unknown severity", in: ScoreInput{Severity: "page"}, want: 20},
+		{name: "unrated metric weights", in: ScoreInput{Severity: "warning", MetricWeights: []int{2}}, want: 32},
+	}
+
+	s := NewScorer(testScoring())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.Score(tt.in); got != tt.want {
+				t.Errorf("Score() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScorerSymptomScore(t *testing.T) {
+	tests := []struct {
+		name     string
+		symptoms []SymptomInput
+		want     int
+	}{
+		{name: "none", want: 0},
+		{name: "single critical", symptoms: []SymptomInput{{Severity: "critical", Count: 1}}, want: 30},
+		{name: "counts grow logarithmically", symptoms: []SymptomInput{{Severity: "critical", Count: 10}}, want: 60},
+		{name: "many warnings", symptoms: []SymptomInput{{Severity: "warning", Count: 100}}, want: 15},
+		{name: "severity is case-insensitive", symptoms: []SymptomInput{{Severity: "CRITICAL", Count: 1}}, want: 30},
+		{name: "no severity counts as warning", symptoms: []SymptomInput{{Count: 1}}, want: 5},
+		{name: "unknown severity counts as warning", symptoms: []SymptomInput{{Severity: "debug", Count: 1}}, want: 5},
+		{name: "zero count", symptoms: []SymptomInput{{Severity: "critical", Count: 0}}, want: 0},
+		{name: "summed", symptoms: []SymptomInput{{Severity: "critical", Count: 1}, {Severity: "warning", Count: 1}}, want: 35},
+		{name: "capped at 100", symptoms: []SymptomInput{{Severity: "critical", Count: 1000}}, want: 100},
+	}
+
+	s := NewScorer(testScoring())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.SymptomScore(tt.symptoms); got != tt.want {
+				t.Errorf("SymptomScore() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScorerAgeScore(t *testing.T) {
+	tests := []struct {
+		name  string
+		curve string
+		age   time.Duration
+		want  int
+	}{
+		{name: "no age", curve: "linear", age: 0, want: 0},
+		{name: "linear halfway", curve: "linear", age: 50 * time.Minute, want: 10},
+		{name: "linear past full", curve: "linear", age: 200 * time.Minute, want: 20},
+		{name: "log rises early", curve: "log", age: 10 * time.Minute, want: 10},
+		{name: "log at full", curve: "log", age: 100 * time.Minute, want: 20},
+		{name: "off", curve: "off", age: time.Hour, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testScoring()
+			cfg.Age.Curve = tt.curve
+			if got := NewScorer(cfg).AgeScore(tt.age); got != tt.want {
+				t.Errorf("AgeScore(%v) = %d, want %d", tt.age, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScorerBurnScore(t *testing.T) {
+	tests := []struct {
+		name string
+		rate float64
+		want int
+	}{
+		{name: "within budget", rate: 0.5, want: 0},
+		{name: "spending the budget exactly", rate: 1, want: 0},
+		{name: "halfway", rate: 6, want: 15},
+		{name: "full burn rate", rate: 11, want: 30},
+		{name: "past full burn rate", rate: 50, want: 30},
+	}
+
+	s := NewScorer(testScoring())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.BurnScore(tt.rate); got != tt.want {
+				t.Errorf("BurnScore(%v) = %d, want %d", tt.rate, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCombine(t *testing.T) {
+	tests := []struct {
+		score, points, want int
+	}{
+		{score: 0, points: 30, want: 30},
+		{score: 80, points: 50, want: 90},
+		{score: 50, points: 0, want: 50},
+		{score: 100, points: 50, want: 100},
+	}
+
+	for _, tt := range tests {
+		if got := combine(tt.score, tt.points); got != tt.want {
+			t.Errorf("combine(%d, %d) = %d, want %d", tt.score, tt.points, got, tt.want)
+		}
+	}
+}
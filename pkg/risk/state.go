@@ -0,0 +1,166 @@
+package risk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	alertsBucket = []byte("alerts")
+	acksBucket   = []byte("acks")
+	stateBucket  = []byte("state")
+)
+
+// StateStore persists the tracked alerts and acknowledgements, plus any other state saved
+// under a key, in a bbolt database so a restart picks up where the last run stopped
+type StateStore struct {
+	db *bolt.DB
+}
+
+// OpenStateStore opens (or creates) the database at path
+func OpenStateStore(path string) (*StateStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	// Fail fast instead of blocking forever if another instance holds the file lock
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state database %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{alertsBucket, acksBucket, stateBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create state buckets: %w", err)
+	}
+
+	return &StateStore{db: db}, nil
+}
+
+// SaveTracker replaces the stored alerts and acknowledgements with the tracker's
+func (s *StateStore) SaveTracker(rt *RiskTracker) error {
+	rt.Mutex.Lock()
+	items := make(map[string][]byte, len(rt.Items))
+	for key, item := range rt.Items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			rt.Mutex.Unlock()
+			return fmt.Errorf("failed to encode alert %s: %w", key, err)
+		}
+		items[key] = data
+	}
+	acks := make(map[string][]byte, len(rt.acks))
+	for service, ack := range rt.acks {
+		data, err := json.Marshal(ack)
+		if err != nil {
+			rt.Mutex.Unlock()
+			return fmt.Errorf("failed to encode acknowledgement of %s: %w", service, err)
+		}
+		acks[service] = data
+	}
+	rt.Mutex.Unlock()
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := replaceBucket(tx, alertsBucket, items); err != nil {
+			return err
+		}
+		return replaceBucket(tx, acksBucket, acks)
+	})
+}
+
+// replaceBucket empties bucket and stores entries in it
+func replaceBucket(tx *bolt.Tx, bucket []byte, entries map[string][]byte) error {
+	if err := tx.DeleteBucket(bucket); err != nil {
+		return err
+	}
+	b, err := tx.CreateBucket(bucket)
+	if err != nil {
+		return err
+	}
+	for key, data := range entries {
+		if err := b.Put([]byte(key), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RestoreTracker loads the stored alerts and acknowledgements into the tracker, keeping their
+// FirstSeen and LastSeen; alerts that stopped firing meanwhile expire at the next cleanup.
+// Returns the number of alerts restored.
+func (s *StateStore) RestoreTracker(rt *RiskTracker) (int, error) {
+	rt.Mutex.Lock()
+	defer rt.Mutex.Unlock()
+
+	restored := 0
+	err := s.db.View(func(tx *bolt.Tx) error {
+		err := tx.Bucket(alertsBucket).ForEach(func(k, v []byte) error {
+			var item RiskItem
+			if err := json.Unmarshal(v, &item); err != nil {
+				fmt.Printf("[STATE] Skipping unreadable alert %s: %v\n", k, err)
+				return nil
+			}
+			rt.Items[string(k)] = &item
+			restored++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(acksBucket).ForEach(func(k, v []byte) error {
+			var ack Ack
+			if err := json.Unmarshal(v, &ack); err != nil {
+				fmt.Printf("[STATE] Skipping unreadable acknowledgement %s: %v\n", k, err)
+				return nil
+			}
+			rt.acks[string(k)] = ack
+			return nil
+		})
+	})
+	return restored, err
+}
+
+// Put stores v as JSON under key
+func (s *StateStore) Put(key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", key, err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(stateBucket).Put([]byte(key), data)
+	})
+}
+
+// Get decodes the value stored under key into v; ok is false when there is none
+func (s *StateStore) Get(key string, v interface{}) (ok bool, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(stateBucket).Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+		ok = true
+		return json.Unmarshal(data, v)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to decode %s: %w", key, err)
+	}
+	return ok, nil
+}
+
+// Close closes the database
+func (s *StateStore) Close() error {
+	return s.db.Close()
+}
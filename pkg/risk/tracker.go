@@ -12,15 +12,30 @@ type RiskTracker struct {
 	Items map[string]*RiskItem
 	Mutex sync.Mutex
 	TTL   time.Duration
+
+	updates     chan struct{}
+	acks        map[string]Ack           // Service -> acknowledgement or snooze
+	serviceTTLs map[string]time.Duration // Services whose polled alerts outlive or expire before TTL
 }
 
 func NewRiskTracker(ttl time.Duration) *RiskTracker {
 	return &RiskTracker{
-		Items: make(map[string]*RiskItem),
-		TTL:   ttl,
+		Items:   make(map[string]*RiskItem),
+		TTL:     ttl,
+		updates: make(chan struct{}, 1),
+		acks:    make(map[string]Ack),
 	}
 }
 
+// alertKey is the unique key of an alert: the fingerprint of its label set, so alerts that
+// differ in any label are tracked apart and repeats of one alert dedupe
+func alertKey(a prometheus.Alert) string {
+	if a.Fingerprint != "" {
+		return a.Fingerprint
+	}
+	return a.Name + "|" + a.Instance
+}
+
 func (rt *RiskTracker) UpdateFromAlerts(alerts []prometheus.Alert) {
 	rt.Mutex.Lock()
 	defer rt.Mutex.Unlock()
@@ -28,22 +43,101 @@ func (rt *RiskTracker) UpdateFromAlerts(alerts []prometheus.Alert) {
 	now := time.Now()
 
 	for _, a := range alerts {
-		key := a.Name + "|" + a.Instance // Unique key combining alert name and instance
-
-		if item, exists := rt.Items[key]; exists {
-			item.LastSeen = now
-			item.TTL = rt.TTL
-		} else {
-			rt.Items[key] = &RiskItem{
-				Service:   a.Service,
-				AlertName: a.Name,
-				Severity:  a.Severity,
-				FirstSeen: now,
-				LastSeen:  now,
-				TTL:       rt.TTL,
-			}
+		rt.track(a, now, rt.ttlFor(a.Service))
+	}
+}
+
+// SetServiceTTLs overrides TTL for the polled alerts of the given services
+func (rt *RiskTracker) SetServiceTTLs(ttls map[string]time.Duration) {
+	rt.Mutex.Lock()
+	defer rt.Mutex.Unlock()
+	rt.serviceTTLs = ttls
+}
+
+// ttlFor returns how long a polled alert of service stays tracked after it was last seen;
+// the caller holds the mutex
+func (rt *RiskTracker) ttlFor(service string) time.Duration {
+	if ttl, ok := rt.serviceTTLs[service]; ok {
+		return ttl
+	}
+	return rt.TTL
+}
+
+func (rt *RiskTracker) track(a prometheus.Alert, now time.Time, ttl time.Duration) {
+	key := alertKey(a)
+
+	if item, exists := rt.Items[key]; exists {
+		item.LastSeen = now
+		item.TTL = ttl
+		item.Labels = a.Labels
+		if !a.StartsAt.IsZero() {
+			item.StartsAt = a.StartsAt
+		}
+		if item.State != a.State {
+			fmt.Printf("[INFO] %s is now %s\n", key, a.State)
+			item.State = a.State
+		}
+	} else {
+		rt.Items[key] = &RiskItem{
+			Fingerprint: key,
+			Service:     a.Service,
+			AlertName:   a.Name,
+			Severity:    a.Severity,
+			State:       a.State,
+			Labels:      a.Labels,
+			StartsAt:    a.StartsAt,
+			FirstSeen:   now,
+			LastSeen:    now,
+			TTL:         ttl,
+		}
+	}
+}
+
+// Receive applies alerts pushed by an Alertmanager webhook. Alertmanager only repeats a firing
+// alert every repeat_interval, so firing alerts are kept until their endsAt, or for ttl when
+// they have none; resolved alerts are dropped right away.
+func (rt *RiskTracker) Receive(firing, resolved []prometheus.Alert, ttl time.Duration) {
+	rt.Mutex.Lock()
+	now := time.Now()
+	for _, a := range firing {
+		alertTTL := ttl
+		if a.EndsAt.After(now) {
+			alertTTL = a.EndsAt.Sub(now)
 		}
+		rt.track(a, now, alertTTL)
+	}
+	for _, a := range resolved {
+		if _, exists := rt.Items[alertKey(a)]; exists {
+			fmt.Printf("[INFO] Resolved: %s\n", alertKey(a))
+			delete(rt.Items, alertKey(a))
+		}
+	}
+	rt.dropResolvedAcksLocked()
+	rt.Mutex.Unlock()
+
+	// Wake the monitoring loop instead of waiting for its next cycle
+	select {
+	case rt.updates <- struct{}{}:
+	default:
+	}
+}
+
+// Updates signals when pushed alerts changed the tracked items
+func (rt *RiskTracker) Updates() <-chan struct{} {
+	return rt.updates
+}
+
+// Active returns copies of the tracked items, safe to read while alerts are being pushed
+func (rt *RiskTracker) Active() []*RiskItem {
+	rt.Mutex.Lock()
+	defer rt.Mutex.Unlock()
+
+	items := make([]*RiskItem, 0, len(rt.Items))
+	for _, item := range rt.Items {
+		copied := *item
+		items = append(items, &copied)
 	}
+	return items
 }
 
 func (rt *RiskTracker) CleanupExpired() {
@@ -57,6 +151,7 @@ func (rt *RiskTracker) CleanupExpired() {
 			delete(rt.Items, key)
 		}
 	}
+	rt.dropResolvedAcksLocked()
 }
 
 func (rt *RiskTracker) Print() {
@@ -1,11 +1,21 @@
 package risk
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 type RiskItem struct {
+	Fingerprint string
+	IncidentID string // Set by Incidents.Update
 	Service    string
 	AlertName  string
 	Severity   string
+	State      string // "firing" or "pending" (not firing yet)
+	Labels     map[string]string // Of the alert, e.g. for namespace or pod query variables
+	SilencedBy string            // ID of the silence or maintenance window suppressing the alert
+	InhibitedBy string // Alert (name and fingerprint) whose inhibit rule suppresses this one
+	StartsAt   time.Time // When the alert became active; zero when the source didn't say
 	FirstSeen  time.Time
 	LastSeen   time.Time
 	TTL        time.Duration
@@ -13,3 +23,40 @@ type RiskItem struct {
 	Summary string
 	Risk	  string
 }
+
+// LevelRank orders risk levels from Low (1) to Critical (4), case-insensitively; 0 for anything
+// else, e.g. an empty or unknown level
+func LevelRank(level string) int {
+	switch strings.ToLower(level) {
+	case "low":
+		return 1
+	case "medium":
+		return 2
+	case "high":
+		return 3
+	case "critical":
+		return 4
+	}
+	return 0
+}
+
+// ActiveSince returns when the alert started: its StartsAt, else when Vigilant first saw it
+func (r *RiskItem) ActiveSince() time.Time {
+	if !r.StartsAt.IsZero() {
+		return r.StartsAt
+	}
+	return r.FirstSeen
+}
+
+// MatchLabels are the labels silences and inhibit rules match on: the alert's, with service
+// set to the resolved service when the alert has no service label of its own
+func (r *RiskItem) MatchLabels() map[string]string {
+	labels := make(map[string]string, len(r.Labels)+1)
+	for k, v := range r.Labels {
+		labels[k] = v
+	}
+	if _, ok := labels["service"]; !ok {
+		labels["service"] = r.Service
+	}
+	return labels
+}
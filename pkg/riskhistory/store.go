@@ -0,0 +1,93 @@
+package riskhistory
+
+import (
+	"sort"
+	"time"
+
+	"vigilant/pkg/ringstore"
+)
+
+// Trends reported for a service's score
+const (
+	TrendImproving = "improving"
+	TrendWorsening = "worsening"
+	TrendStable    = "stable"
+)
+
+// trendThreshold is how many points the score must move within the trend window to count as
+// a trend rather than noise
+const trendThreshold = 5
+
+// Point is a service's score and risk level in one cycle
+type Point struct {
+	Service string    `json:"service"`
+	Time    time.Time `json:"time"`
+	Score   int       `json:"score"`
+	Risk    string    `json:"risk"`
+	State   string    `json:"state,omitempty"`
+}
+
+// Store keeps the newest points of every service in ring buffers, persisted to a JSONL file
+// (see ringstore.Store)
+type Store struct {
+	points *ringstore.Store[Point]
+}
+
+// NewStore loads previously recorded points from path (created if missing), keeping up to
+// capacity points per service
+func NewStore(path string, capacity int) (*Store, error) {
+	points, err := ringstore.Open("risk history", path, capacity, func(p Point) (string, string) {
+		return p.Service, ""
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{points: points}, nil
+}
+
+// Record persists a batch of points, e.g. one cycle's scores
+func (s *Store) Record(points []Point) error {
+	return s.points.Record(points)
+}
+
+// History returns the points recorded for service since the given time (all when zero)
+func (s *Store) History(service string, since time.Time) []Point {
+	var result []Point
+	s.points.Read(service, func(_ string, points []Point) {
+		start := sort.Search(len(points), func(i int) bool { return !points[i].Time.Before(since) })
+		result = append([]Point(nil), points[start:]...)
+	})
+	return result
+}
+
+// Trend compares the service's latest score with the oldest one recorded within window
+// before it; without an earlier point in the window the service is stable
+func (s *Store) Trend(service string, window time.Duration) string {
+	trend := TrendStable
+	s.points.Read(service, func(_ string, points []Point) {
+		trend = trendOf(points, window)
+	})
+	return trend
+}
+
+// trendOf computes the trend of a service's points, oldest first
+func trendOf(points []Point, window time.Duration) string {
+	if len(points) < 2 {
+		return TrendStable
+	}
+	latest := points[len(points)-1]
+	since := latest.Time.Add(-window)
+	start := sort.Search(len(points), func(i int) bool { return !points[i].Time.Before(since) })
+	if start >= len(points)-1 {
+		return TrendStable
+	}
+
+	switch delta := latest.Score - points[start].Score; {
+	case delta >= trendThreshold:
+		return TrendWorsening
+	case delta <= -trendThreshold:
+		return TrendImproving
+	default:
+		return TrendStable
+	}
+}
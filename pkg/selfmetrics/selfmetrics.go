@@ -0,0 +1,120 @@
+package selfmetrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics describing Vigilant itself, exported on /metrics
+
+// Analysis cycles
+var (
+	CycleDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "vigilant_cycle_duration_seconds",
+		Help:    "Duration of analysis cycles, from fetching alerts to publishing risks.",
+		Buckets: []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300},
+	})
+
+	AlertsFetched = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "vigilant_alerts_fetched_total",
+		Help: "Alerts received, by source (prometheus, grafana or webhook).",
+	}, []string{"source"})
+
+	ActiveAlerts = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "vigilant_active_alerts",
+		Help: "Alerts analyzed in the last cycle, leaving out silenced and inhibited ones.",
+	})
+
+	SymptomsMatched = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "vigilant_symptoms_matched_total",
+		Help: "Log symptom patterns matched while analyzing alerts, by log source.",
+	}, []string{"source"})
+)
+
+// Log queries
+var LogQueryLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "vigilant_log_query_duration_seconds",
+	Help:    "Duration of log symptom scans, by log source (e.g. elasticsearch) and outcome.",
+	Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+}, []string{"source", "outcome"})
+
+// LLM cache
+var (
+	CacheHits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "vigilant_llm_cache_hits_total",
+		Help: "LLM cache lookups answered from the cache, by match type (exact or similarity).",
+	}, []string{"match"})
+
+	CacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "vigilant_llm_cache_misses_total",
+		Help: "LLM cache lookups that required an LLM call.",
+	})
+
+	CacheExpirations = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "vigilant_llm_cache_expirations_total",
+		Help: "LLM cache entries removed because their TTL elapsed.",
+	})
+
+	CacheEntries = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "vigilant_llm_cache_entries",
+		Help: "Entries currently held in the LLM cache.",
+	})
+
+	CacheTokensSaved = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "vigilant_llm_cache_estimated_tokens_saved_total",
+		Help: "Estimated LLM tokens not spent thanks to cache hits.",
+	})
+)
+
+// LLM calls
+var LLMLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "vigilant_llm_request_duration_seconds",
+	Help:    "Duration of LLM analysis rounds triggered by cache misses.",
+	Buckets: []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120},
+}, []string{"outcome"})
+
+// Data sources
+var (
+	PrometheusDegraded = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vigilant_prometheus_degraded",
+		Help: "1 while calls to a Prometheus endpoint are skipped after repeated failures.",
+	}, []string{"endpoint"})
+
+	PrometheusLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "vigilant_prometheus_request_duration_seconds",
+		Help:    "Duration of Prometheus API calls including retries, by outcome (success or error).",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"outcome"})
+)
+
+// Metric query cache
+var (
+	MetricQueryCacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "vigilant_metric_query_cache_hits_total",
+		Help: "Metric queries answered from the query cache instead of the metrics backend.",
+	})
+
+	MetricQueryCacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "vigilant_metric_query_cache_misses_total",
+		Help: "Metric queries sent to the metrics backend.",
+	})
+
+	MetricQueryCacheEntries = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "vigilant_metric_query_cache_entries",
+		Help: "Query results currently held in the metric query cache.",
+	})
+)
+
+// Dashboard
+var WebSocketClients = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "vigilant_websocket_clients",
+	Help: "Dashboard clients connected to the WebSocket or the event stream.",
+})
+
+// Handler serves all registered metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
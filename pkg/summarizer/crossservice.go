@@ -0,0 +1,184 @@
+package summarizer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// CrossServiceGroup is a set of services showing the same failure in the same cycle: a log
+// pattern, or a host their log samples fail to reach. Its services are analyzed together in
+// one LLM call that looks for the shared root cause.
+type CrossServiceGroup struct {
+	Services []string // Sorted
+	Patterns []string // Symptom patterns seen in at least two of the services
+	Targets  []string // Hosts named in the log samples of at least two of the services
+}
+
+// Empty reports whether the service is not part of a group
+func (g CrossServiceGroup) Empty() bool {
+	return len(g.Services) == 0
+}
+
+// Key identifies the group among the analyses of one cycle
+func (g CrossServiceGroup) Key() string {
+	return strings.Join(g.Services, ",")
+}
+
+// targetPatterns extract the hosts a log line talks to, e.g. "dial tcp 10.0.3.7:5432" or
+// "lookup redis.cache.svc.cluster.local"
+var targetPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\b((?:\d{1,3}\.){3}\d{1,3}:\d{2,5})\b`),
+	regexp.MustCompile(`\b((?:[a-zA-Z0-9-]+\.)+[a-zA-Z][a-zA-Z0-9-]*:\d{2,5})\b`),
+	regexp.MustCompile(`\blookup ((?:[a-zA-Z0-9-]+\.)+[a-zA-Z0-9-]+)`),
+}
+
+// sampleTargets returns the distinct hosts named in the samples
+func sampleTargets(samples []string) []string {
+	var targets []string
+	seen := make(map[string]bool)
+	for _, sample := range samples {
+		for _, re := range targetPatterns {
+			for _, m := range re.FindAllStringSubmatch(sample, -1) {
+				if target := strings.ToLower(m[1]); !seen[target] {
+					seen[target] = true
+					targets = append(targets, target)
+				}
+			}
+		}
+	}
+	return targets
+}
+
+// DetectCrossServiceGroups groups the services whose correlations share a symptom pattern or
+// a target host; services linked through any chain of shared signals form one group. Symptoms
+// of info severity are ignored, and groups smaller than minServices are dropped. Returns the
+// group of every grouped service.
+func DetectCrossServiceGroups(correlations []AlertCorrelation, minServices int) map[string]CrossServiceGroup {
+	if minServices < 2 {
+		minServices = 2
+	}
+
+	// Which services show each signal
+	patterns := make(map[string]map[string]bool)
+	targets := make(map[string]map[string]bool)
+	add := func(signals map[string]map[string]bool, signal, service string) {
+		if signals[signal] == nil {
+			signals[signal] = make(map[string]bool)
+		}
+		signals[signal][service] = true
+	}
+	for _, c := range correlations {
+		for _, s := range c.Symptoms {
+			if strings.EqualFold(s.Severity, "info") {
+				continue
+			}
+			add(patterns, s.Pattern, c.Alert.Service)
+			for _, target := range sampleTargets(s.Samples) {
+				add(targets, target, c.Alert.Service)
+			}
+		}
+	}
+
+	// Union the services sharing a signal
+	parent := make(map[string]string)
+	var find func(string) string
+	find = func(s string) string {
+		if parent[s] == "" || parent[s] == s {
+			parent[s] = s
+			return s
+		}
+		root := find(parent[s])
+		parent[s] = root
+		return root
+	}
+	for _, signals := range []map[string]map[string]bool{patterns, targets} {
+		for _, services := range signals {
+			if len(services) < 2 {
+				continue
+			}
+			var first string
+			for _, service := range sortedSet(services) {
+				if first == "" {
+					first = find(service)
+					continue
+				}
+				parent[find(service)] = first
+			}
+		}
+	}
+
+	members := make(map[string][]string)
+	for service := range parent {
+		root := find(service)
+		members[root] = append(members[root], service)
+	}
+
+	groups := make(map[string]CrossServiceGroup)
+	for _, services := range members {
+		if len(services) < minServices {
+			continue
+		}
+		sort.Strings(services)
+		group := CrossServiceGroup{
+			Services: services,
+			Patterns: sharedSignals(patterns, services),
+			Targets:  sharedSignals(targets, services),
+		}
+		for _, service := range services {
+			groups[service] = group
+		}
+	}
+	return groups
+}
+
+// writeCrossService adds the CROSS_SERVICE_CORRELATION section when the input analyzes a
+// cross-service group
+func writeCrossService(sb *strings.Builder, input SummaryInput) {
+	for _, c := range input.Correlations {
+		group := c.CrossService
+		if group.Empty() {
+			continue
+		}
+		sb.WriteString("CROSS_SERVICE_CORRELATION:\n")
+		sb.WriteString(fmt.Sprintf("  - Services: %s\n", strings.Join(group.Services, ", ")))
+		if len(group.Patterns) > 0 {
+			sb.WriteString(fmt.Sprintf("  - Shared_Patterns: %s\n", strings.Join(group.Patterns, ", ")))
+		}
+		if len(group.Targets) > 0 {
+			sb.WriteString(fmt.Sprintf("  - Shared_Targets: %s\n", strings.Join(group.Targets, ", ")))
+		}
+		sb.WriteString("These services fail the same way in the same cycle. Identify the root cause they share, " +
+			"e.g. DNS, a common database or the network path to a shared target, rather than a cause specific to one service; " +
+			"the analysis applies to all of them.\n\n")
+		return
+	}
+}
+
+// sharedSignals returns the signals seen in at least two of the services, sorted
+func sharedSignals(signals map[string]map[string]bool, services []string) []string {
+	var shared []string
+	for signal, seenIn := range signals {
+		count := 0
+		for _, service := range services {
+			if seenIn[service] {
+				count++
+			}
+		}
+		if count >= 2 {
+			shared = append(shared, signal)
+		}
+	}
+	sort.Strings(shared)
+	return shared
+}
+
+func sortedSet(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,136 @@
+package summarizer
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"vigilant/pkg/config"
+	"vigilant/pkg/feedback"
+)
+
+// analysisTier is the resolved model, token budget and prompt depth for one analysis
+type analysisTier struct {
+	Name      string
+	Model     string
+	MaxTokens int
+	Brief     bool
+}
+
+var (
+	analysisPolicy   config.AnalysisPolicy
+	analysisPolicyMu sync.RWMutex
+)
+
+// severityRank orders alert severities so a group is analyzed at the depth of its worst alert
+var severityRank = map[string]int{
+	"info":     1,
+	"low":      1,
+	"warning":  2,
+	"medium":   2,
+	"high":     3,
+	"error":    3,
+	"critical": 4,
+	"page":     4,
+}
+
+// ConfigureAnalysisPolicy sets the severity-to-tier policy used to pick model and prompt depth
+func ConfigureAnalysisPolicy(policy config.AnalysisPolicy) {
+	analysisPolicyMu.Lock()
+	defer analysisPolicyMu.Unlock()
+	analysisPolicy = policy
+}
+
+// resolveAnalysisTier picks the tier for the most severe alert in the input
+func resolveAnalysisTier(input SummaryInput) analysisTier {
+	full := analysisTier{Name: "default", Model: openAIModel(), MaxTokens: 1500}
+
+	analysisPolicyMu.RLock()
+	defer analysisPolicyMu.RUnlock()
+
+	if !analysisPolicy.Enabled {
+		return full
+	}
+
+	severity := ""
+	for _, c := range input.Correlations {
+		s := strings.ToLower(c.Alert.Severity)
+		if severity == "" || severityRank[s] > severityRank[severity] {
+			severity = s
+		}
+	}
+
+	for _, tier := range analysisPolicy.Tiers {
+		for _, s := range tier.Severities {
+			if strings.EqualFold(s, severity) {
+				resolved := analysisTier{
+					Name:      tier.Name,
+					Model:     tier.Model,
+					MaxTokens: tier.MaxTokens,
+					Brief:     tier.Depth == "brief",
+				}
+				if resolved.Model == "" {
+					resolved.Model = full.Model
+				}
+				return resolved
+			}
+		}
+	}
+
+	return full
+}
+
+// buildBriefContextPrompt is a condensed context prompt for low-severity analyses
+func buildBriefContextPrompt(input SummaryInput) string {
+	var sb strings.Builder
+
+	sb.WriteString("=== INCIDENT SUMMARY ===\n")
+	writeCrossService(&sb, input)
+	for _, c := range input.Correlations {
+		sb.WriteString(fmt.Sprintf("SERVICE: %s | ALERT: %s | SEVERITY: %s | DURATION: %v\n",
+			c.Alert.Service, c.Alert.AlertName, c.Alert.Severity, c.Alert.LastSeen.Sub(c.Alert.FirstSeen)))
+
+		if len(c.Symptoms) > 0 {
+			var parts []string
+			for i, s := range c.Symptoms {
+				if i == 5 {
+					parts = append(parts, fmt.Sprintf("+%d more", len(c.Symptoms)-5))
+					break
+				}
+				parts = append(parts, fmt.Sprintf("%s x%d", s.Pattern, s.Count))
+			}
+			sb.WriteString("SYMPTOMS: " + strings.Join(parts, ", ") + "\n")
+		}
+
+		if len(c.Metrics) > 0 {
+			var parts []string
+			for _, m := range c.Metrics {
+				part := fmt.Sprintf("%s=%.3f (%s %.3f)", m.Check.Name, m.Value, m.Check.Operator, m.Check.Threshold)
+				if m.Baseline != nil {
+					part += fmt.Sprintf(" normal %.3f±%.3f", m.Baseline.Mean, m.Baseline.StdDev)
+				}
+				if offender := m.Offender(); offender != "" {
+					part += " worst " + offender
+				}
+				parts = append(parts, part)
+			}
+			sb.WriteString("METRICS: " + strings.Join(parts, ", ") + "\n")
+		}
+
+		if len(c.Context) > 0 {
+			var parts []string
+			for _, cv := range c.Context {
+				parts = append(parts, cv.String())
+			}
+			sb.WriteString("CONTEXT: " + strings.Join(parts, "; ") + "\n")
+		}
+
+		for _, fb := range input.Corrections[feedback.Fingerprint(c.Alert.Service, c.Alert.AlertName)] {
+			sb.WriteString("OPERATOR_CORRECTION: " + fb.Notes + "\n")
+		}
+	}
+	sb.WriteString("=== END ===\n")
+	sb.WriteString("Provide a brief analysis in the specified JSON format: at most 2 immediate actions and 2 investigation steps.")
+
+	return sb.String()
+}
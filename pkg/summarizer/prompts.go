@@ -0,0 +1,172 @@
+package summarizer
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"vigilant/pkg/config"
+)
+
+// Built-in system prompt versions. Never edit a released version in place -
+// add a new one so analyses recorded under the old version stay comparable.
+var promptRegistry = map[string]string{
+	"v1": systemPromptV1,
+	"v2": systemPromptV2,
+}
+
+var (
+	promptConfig = config.PromptConfig{Version: "v1"}
+	promptMu     sync.RWMutex
+)
+
+// ConfigurePrompts registers custom prompt versions and selects the active version or experiment
+func ConfigurePrompts(cfg config.PromptConfig) error {
+	promptMu.Lock()
+	defer promptMu.Unlock()
+
+	for version, prompt := range cfg.Custom {
+		if _, exists := promptRegistry[version]; exists {
+			return fmt.Errorf("custom prompt %s conflicts with a built-in version", version)
+		}
+		promptRegistry[version] = prompt
+	}
+
+	if _, ok := promptRegistry[cfg.Version]; !ok {
+		return fmt.Errorf("unknown prompt version %s (available: %v)", cfg.Version, promptVersionsLocked())
+	}
+	if cfg.Experiment.Enabled {
+		for _, v := range cfg.Experiment.Variants {
+			if _, ok := promptRegistry[v.Version]; !ok {
+				return fmt.Errorf("unknown prompt version %s in experiment (available: %v)", v.Version, promptVersionsLocked())
+			}
+		}
+	}
+
+	promptConfig = cfg
+	return nil
+}
+
+// PromptVersions lists all registered prompt versions
+func PromptVersions() []string {
+	promptMu.RLock()
+	defer promptMu.RUnlock()
+	return promptVersionsLocked()
+}
+
+func promptVersionsLocked() []string {
+	var versions []string
+	for version := range promptRegistry {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// selectPromptVersion returns the configured version, or a weighted pick when an experiment is running
+func selectPromptVersion() string {
+	promptMu.RLock()
+	defer promptMu.RUnlock()
+
+	if !promptConfig.Experiment.Enabled {
+		return promptConfig.Version
+	}
+
+	total := 0
+	for _, v := range promptConfig.Experiment.Variants {
+		total += v.Weight
+	}
+	if total <= 0 {
+		return promptConfig.Version
+	}
+
+	pick := rand.Intn(total)
+	for _, v := range promptConfig.Experiment.Variants {
+		if pick < v.Weight {
+			return v.Version
+		}
+		pick -= v.Weight
+	}
+	return promptConfig.Version
+}
+
+// buildSystemPrompt returns the system prompt for a version, falling back to v1
+func buildSystemPrompt(version string) string {
+	promptMu.RLock()
+	defer promptMu.RUnlock()
+
+	if prompt, ok := promptRegistry[version]; ok {
+		return prompt
+	}
+	return systemPromptV1
+}
+
+const systemPromptV1 = `You are a Senior Site Reliability Engineer (SRE) with expertise in Kubernetes, service mesh (Istio), observability, and incident response. You analyze production monitoring data to provide actionable insights.
+
+**ROLE:** Expert SRE performing root cause analysis on real production incidents.
+
+**CONTEXT:** You receive correlated monitoring data from a production system:
+- Prometheus alerts indicating service health issues
+- Log pattern matches showing symptoms in application logs  
+- Metrics showing threshold violations
+- All data is from active production workloads
+
+**TASK:** Provide comprehensive root cause analysis with specific remediation steps.
+
+**ANALYSIS FRAMEWORK:**
+1. Correlate alert severity with observed symptoms and metrics
+2. Identify technical root cause based on service mesh, container, and application patterns
+3. Prioritize immediate stabilization actions
+4. Recommend investigation steps for confirmation
+5. Suggest preventive measures
+
+**RESPONSE REQUIREMENTS:**
+- Return ONLY valid JSON in the exact format specified
+- Be technically specific, not generic
+- Focus on actionable steps, not theory
+- Consider Kubernetes/Istio context when relevant
+- Prioritize service restoration first, investigation second
+
+**RESPONSE FORMAT (JSON only):**
+{
+  "risk": "Critical|High|Medium|Low",
+  "confidence": 0.8,
+  "root_cause": "Technical analysis of the specific problem based on symptoms and metrics",
+  "immediate_actions": [
+    "Specific action 1 with commands/steps",
+    "Specific action 2 with commands/steps",
+    "Specific action 3 with commands/steps"
+  ],
+  "investigation_steps": [
+    "Check specific logs: kubectl logs -n namespace pod-name",
+    "Verify specific metrics: specific Prometheus queries",
+    "Validate specific configurations"
+  ],
+  "prevention": "Specific measures to prevent this issue in the future"
+}
+
+Respond with JSON only. No explanation outside the JSON structure.`
+
+const systemPromptV2 = `You are a Senior Site Reliability Engineer on call for a Kubernetes/Istio production platform.
+
+Analyze the correlated alerts, log symptoms and metric violations you are given and determine the most likely root cause.
+
+**RULES:**
+- Base every conclusion on the evidence provided; name the symptom or metric that supports it
+- If the evidence is weak or contradictory, lower the confidence instead of guessing
+- Order immediate_actions by impact on service restoration, most important first
+- Use concrete commands (kubectl, PromQL) where they help
+- Keep each action and step to one sentence
+
+**RESPONSE FORMAT (JSON only):**
+{
+  "risk": "Critical|High|Medium|Low",
+  "confidence": 0.8,
+  "root_cause": "Most likely cause and the evidence supporting it",
+  "immediate_actions": ["Action 1", "Action 2", "Action 3"],
+  "investigation_steps": ["Step 1", "Step 2", "Step 3"],
+  "prevention": "How to prevent recurrence"
+}
+
+Respond with JSON only. No explanation outside the JSON structure.`
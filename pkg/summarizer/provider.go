@@ -0,0 +1,229 @@
+package summarizer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+
+	"vigilant/pkg/risk"
+)
+
+// CompletionRequest is one analysis request sent to an LLM provider
+type CompletionRequest struct {
+	Model        string
+	SystemPrompt string
+	UserPrompt   string
+	Temperature  float32
+	MaxTokens    int
+	Input        SummaryInput // Structured data behind the prompts, used by offline providers
+}
+
+// Provider returns the raw completion text for a request
+type Provider interface {
+	Name() string
+	Complete(ctx context.Context, req CompletionRequest) (string, error)
+}
+
+// newProvider selects the provider from LLM_PROVIDER (default "openai")
+func newProvider() (Provider, error) {
+	switch name := strings.ToLower(os.Getenv("LLM_PROVIDER")); name {
+	case "", "openai":
+		apiKey := os.Getenv("OPENAI_API_KEY")
+		if apiKey == "" {
+			return nil, fmt.Errorf("API key not configured")
+		}
+		return &openAIProvider{client: NewOpenAIClient(apiKey)}, nil
+	case "mock":
+		return &mockProvider{}, nil
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q", name)
+	}
+}
+
+// NewOpenAIClient creates a client for OpenAI or any OpenAI-compatible gateway (LiteLLM, vLLM,
+// OpenRouter). OPENAI_BASE_URL overrides the endpoint and OPENAI_HEADERS adds extra request
+// headers as comma-separated Name=Value pairs.
+func NewOpenAIClient(apiKey string) *openai.Client {
+	cfg := openai.DefaultConfig(apiKey)
+
+	if baseURL := os.Getenv("OPENAI_BASE_URL"); baseURL != "" {
+		cfg.BaseURL = strings.TrimSuffix(baseURL, "/")
+	}
+
+	if headers := parseHeaders(os.Getenv("OPENAI_HEADERS")); len(headers) > 0 {
+		cfg.HTTPClient = &http.Client{
+			Transport: &headerTransport{headers: headers, base: http.DefaultTransport},
+		}
+	}
+
+	return openai.NewClientWithConfig(cfg)
+}
+
+// openAIModel returns the chat model, overridable via OPENAI_MODEL for gateways with their own model names
+func openAIModel() string {
+	if model := os.Getenv("OPENAI_MODEL"); model != "" {
+		return model
+	}
+	return "gpt-4o" // Use latest model
+}
+
+// parseHeaders parses "Name=Value,Name2=Value2" into a header set
+func parseHeaders(raw string) http.Header {
+	headers := http.Header{}
+	for _, pair := range strings.Split(raw, ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			continue
+		}
+		headers.Add(name, strings.TrimSpace(value))
+	}
+	return headers
+}
+
+// headerTransport adds fixed headers to every outgoing request
+type headerTransport struct {
+	headers http.Header
+	base    http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, values := range t.headers {
+		for _, v := range values {
+			req.Header.Set(name, v)
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// openAIProvider calls the OpenAI chat completions API
+type openAIProvider struct {
+	client *openai.Client
+}
+
+func (p *openAIProvider) Name() string { return "openai" }
+
+func (p *openAIProvider) Complete(ctx context.Context, req CompletionRequest) (string, error) {
+	resp, err := p.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:       req.Model,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:    "system",
+				Content: req.SystemPrompt,
+			},
+			{
+				Role:    "user",
+				Content: req.UserPrompt,
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("completion returned no choices")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+// mockProvider derives a deterministic analysis from the correlation data without any network calls
+type mockProvider struct{}
+
+func (p *mockProvider) Name() string { return "mock" }
+
+func (p *mockProvider) Complete(ctx context.Context, req CompletionRequest) (string, error) {
+	// Free-form requests (reports, digests) just echo their input data
+	if len(req.Input.Correlations) == 0 {
+		return "_Mock report generated without an LLM._\n\n" + req.UserPrompt, nil
+	}
+
+	result := RootCauseSummary{
+		Risk:       "Low",
+		Confidence: 0.75,
+	}
+
+	var causes []string
+	for _, c := range req.Input.Correlations {
+		result.Risk = higherRisk(result.Risk, mockRiskForSeverity(c.Alert.Severity, len(c.Symptoms), len(c.Metrics)))
+
+		cause := fmt.Sprintf("%s on %s", c.Alert.AlertName, c.Alert.Service)
+		if top := topSymptom(c); top != "" {
+			cause += fmt.Sprintf(" correlates with %d log symptoms (most frequent: %s)", len(c.Symptoms), top)
+		} else {
+			cause += " has no matching log symptoms"
+		}
+		if len(c.Metrics) > 0 {
+			m := c.Metrics[0]
+			cause += fmt.Sprintf(" and %d metric violations (%s %.2f %s %.2f)",
+				len(c.Metrics), m.Check.Name, m.Value, m.Check.Operator, m.Check.Threshold)
+		}
+		causes = append(causes, cause)
+
+		result.ImmediateActions = append(result.ImmediateActions,
+			fmt.Sprintf("Check recent deployments and pod status for %s", c.Alert.Service))
+		result.Investigation = append(result.Investigation,
+			fmt.Sprintf("Review logs of %s around %s", c.Alert.Service, c.Alert.FirstSeen.Format("15:04:05")))
+		for _, r := range c.Runbooks {
+			result.ImmediateActions = append(result.ImmediateActions, "Follow runbook: "+r.Name)
+		}
+	}
+
+	result.RootCause = "[mock analysis] " + strings.Join(causes, "; ")
+	result.Prevention = "Mock provider output - configure a real LLM provider for actual analysis"
+	result.Summary = result.RootCause
+
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+// mockRiskForSeverity maps alert severity and evidence volume to a risk level
+func mockRiskForSeverity(severity string, symptoms, metrics int) string {
+	switch strings.ToLower(severity) {
+	case "critical":
+		if symptoms > 0 && metrics > 0 {
+			return "Critical"
+		}
+		return "High"
+	case "warning":
+		if symptoms > 0 || metrics > 0 {
+			return "Medium"
+		}
+		return "Low"
+	default:
+		return "Low"
+	}
+}
+
+func higherRisk(a, b string) string {
+	if risk.LevelRank(b) > risk.LevelRank(a) {
+		return b
+	}
+	return a
+}
+
+// topSymptom returns the most frequent symptom pattern (ties broken by name for determinism)
+func topSymptom(c AlertCorrelation) string {
+	if len(c.Symptoms) == 0 {
+		return ""
+	}
+	symptoms := append(c.Symptoms[:0:0], c.Symptoms...)
+	sort.Slice(symptoms, func(i, j int) bool {
+		if symptoms[i].Count != symptoms[j].Count {
+			return symptoms[i].Count > symptoms[j].Count
+		}
+		return symptoms[i].Pattern < symptoms[j].Pattern
+	})
+	return fmt.Sprintf("%s x%d", symptoms[0].Pattern, symptoms[0].Count)
+}
@@ -6,23 +6,40 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	openai "github.com/sashabaranov/go-openai"
+	"vigilant/pkg/audit"
+	"vigilant/pkg/config"
+	"vigilant/pkg/feedback"
+	"vigilant/pkg/history"
 	"vigilant/pkg/logs"
 	"vigilant/pkg/prometheus"
 	"vigilant/pkg/risk"
+	"vigilant/pkg/topology"
+	"vigilant/pkg/tracing"
 )
 
 type SummaryInput struct {
-	Correlations []AlertCorrelation
+	Correlations     []AlertCorrelation
+	SimilarIncidents []history.Match
+	Corrections      map[string][]feedback.Entry // Operator corrections keyed by alert fingerprint
+	PromptVersion    string
 }
 
 type AlertCorrelation struct {
-	Alert    risk.RiskItem
-	Symptoms []logs.SymptomMatch
-	Metrics  []prometheus.MetricResult
+	Alert        risk.RiskItem
+	Symptoms     []logs.SymptomMatch
+	Metrics      []prometheus.MetricResult
+	Context      []prometheus.ContextValue // Context metrics, fetched regardless of thresholds
+	SLOs         []prometheus.SLOStatus    // Error budget burn of the service's SLOs
+	Runbooks     []config.Runbook
+	Dependencies risk.DependencyContext // Where the service sits among the other alerting services
+	CrossService CrossServiceGroup      // Services failing the same way this cycle, analyzed together
+	Traces       *tracing.Summary       // Recent error and slow traces, for latency and error alerts
+	Topology     *topology.Position     // Whether the mesh traffic shows the service as victim or origin
 }
 
 type RootCauseSummary struct {
@@ -33,44 +50,94 @@ type RootCauseSummary struct {
 	Investigation     []string `json:"investigation_steps"`
 	Prevention        string   `json:"prevention"`
 	Summary           string   `json:"summary"`  // Keep for backward compatibility
+	Fallback          bool     `json:"fallback,omitempty"` // Set when no real LLM analysis was possible
+	PromptVersion     string   `json:"prompt_version,omitempty"`
+	Model             string   `json:"model,omitempty"`
 }
 
-func Summarize(input SummaryInput) (RootCauseSummary, error) {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		fmt.Println("[LLM FAILSAFE] OpenAI API key not set. Returning fallback summary.")
-		return createFallbackSummary("API key not configured"), nil
+// Past incident retrieval (RAG) - disabled until SetIncidentHistory is called
+var (
+	incidentHistory *history.Store
+	historyTopK     int
+	historyMinScore = 0.35
+)
+
+// Operator feedback - disabled until SetFeedbackStore is called
+var (
+	feedbackStore  *feedback.Store
+	maxCorrections = 3
+)
+
+// SetFeedbackStore appends operator corrections for the same alert fingerprint to future prompts
+func SetFeedbackStore(store *feedback.Store) {
+	feedbackStore = store
+}
+
+// Audit trail of everything sent to and received from the LLM - disabled until SetAuditLog is called
+var auditLog *audit.Log
+
+// SetAuditLog records every LLM request and response to the given audit log
+func SetAuditLog(log *audit.Log) {
+	auditLog = log
+}
+
+// SetIncidentHistory enables injecting the topK most similar past incidents into the context prompt
+func SetIncidentHistory(store *history.Store, topK int) {
+	incidentHistory = store
+	historyTopK = topK
+}
+
+// Summarize analyzes one group of correlations. The call is bounded by LLM_TIMEOUT_SECONDS and
+// aborted (returning the context error) when parent is cancelled, e.g. on shutdown.
+func Summarize(parent context.Context, input SummaryInput) (RootCauseSummary, error) {
+	provider, err := newProvider()
+	if err != nil {
+		fmt.Printf("[LLM FAILSAFE] %v. Returning fallback summary.\n", err)
+		return createFallbackSummary(err.Error()), nil
 	}
 
-	client := openai.NewClient(apiKey)
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(parent, llmTimeout())
 	defer cancel()
 
-	systemPrompt := buildSystemPrompt()
+	input.SimilarIncidents = findSimilarIncidents(ctx, input)
+	input.Corrections = findCorrections(input)
+
+	if input.PromptVersion == "" {
+		input.PromptVersion = selectPromptVersion()
+	}
+	tier := resolveAnalysisTier(input)
+	systemPrompt := buildSystemPrompt(input.PromptVersion)
 	contextPrompt := buildContextPrompt(input)
+	if tier.Brief {
+		contextPrompt = buildBriefContextPrompt(input)
+	}
 
-	fmt.Println("[LLM] Starting OpenAI API call...")
-	resp, err := client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model:       "gpt-4o",  // Use latest model
-		Temperature: 0.1,       // Low temperature for consistent technical analysis
-		MaxTokens:   1500,      // Adequate for detailed response
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    "system",
-				Content: systemPrompt,
-			},
-			{
-				Role:    "user", 
-				Content: contextPrompt,
-			},
-		},
+	model := tier.Model
+	if provider.Name() == "mock" {
+		model = "mock"
+	}
+
+	fmt.Printf("[LLM] Starting %s API call (tier %s, model %s)...\n", provider.Name(), tier.Name, model)
+	started := time.Now()
+	raw, err := provider.Complete(ctx, CompletionRequest{
+		Model:        model,
+		SystemPrompt: systemPrompt,
+		UserPrompt:   contextPrompt,
+		Temperature:  0.1,  // Low temperature for consistent technical analysis
+		MaxTokens:    tier.MaxTokens,
+		Input:        input,
 	})
+	latency := time.Since(started)
 	if err != nil {
-		fmt.Printf("[LLM FAILSAFE] OpenAI API call failed: %v. Returning fallback summary.\n", err)
+		recordAudit(input, provider.Name(), model, systemPrompt, contextPrompt, "", latency, err)
+		if parent.Err() != nil {
+			return RootCauseSummary{}, parent.Err()
+		}
+		fmt.Printf("[LLM FAILSAFE] %s API call failed: %v. Returning fallback summary.\n", provider.Name(), err)
 		return createFallbackSummary("API call failed"), nil
 	}
 
-	raw := resp.Choices[0].Message.Content
+	recordAudit(input, provider.Name(), model, systemPrompt, contextPrompt, raw, latency, nil)
 	var result RootCauseSummary
 	
 	// Clean the response to extract JSON
@@ -95,63 +162,18 @@ func Summarize(input SummaryInput) (RootCauseSummary, error) {
 	if result.Confidence == 0 {
 		result.Confidence = 0.5
 	}
+	result.PromptVersion = input.PromptVersion
+	result.Model = model
 	
 	return result, nil
 }
 
 
-func buildSystemPrompt() string {
-	return `You are a Senior Site Reliability Engineer (SRE) with expertise in Kubernetes, service mesh (Istio), observability, and incident response. You analyze production monitoring data to provide actionable insights.
-
-**ROLE:** Expert SRE performing root cause analysis on real production incidents.
-
-**CONTEXT:** You receive correlated monitoring data from a production system:
-- Prometheus alerts indicating service health issues
-- Log pattern matches showing symptoms in application logs  
-- Metrics showing threshold violations
-- All data is from active production workloads
-
-**TASK:** Provide comprehensive root cause analysis with specific remediation steps.
-
-**ANALYSIS FRAMEWORK:**
-1. Correlate alert severity with observed symptoms and metrics
-2. Identify technical root cause based on service mesh, container, and application patterns
-3. Prioritize immediate stabilization actions
-4. Recommend investigation steps for confirmation
-5. Suggest preventive measures
-
-**RESPONSE REQUIREMENTS:**
-- Return ONLY valid JSON in the exact format specified
-- Be technically specific, not generic
-- Focus on actionable steps, not theory
-- Consider Kubernetes/Istio context when relevant
-- Prioritize service restoration first, investigation second
-
-**RESPONSE FORMAT (JSON only):**
-{
-  "risk": "Critical|High|Medium|Low",
-  "confidence": 0.8,
-  "root_cause": "Technical analysis of the specific problem based on symptoms and metrics",
-  "immediate_actions": [
-    "Specific action 1 with commands/steps",
-    "Specific action 2 with commands/steps",
-    "Specific action 3 with commands/steps"
-  ],
-  "investigation_steps": [
-    "Check specific logs: kubectl logs -n namespace pod-name",
-    "Verify specific metrics: specific Prometheus queries",
-    "Validate specific configurations"
-  ],
-  "prevention": "Specific measures to prevent this issue in the future"
-}
-
-Respond with JSON only. No explanation outside the JSON structure.`
-}
-
 func buildContextPrompt(input SummaryInput) string {
 	var sb strings.Builder
 	
 	sb.WriteString("=== PRODUCTION INCIDENT ANALYSIS ===\n\n")
+	writeCrossService(&sb, input)
 	
 	for i, c := range input.Correlations {
 		if i > 0 {
@@ -162,6 +184,9 @@ func buildContextPrompt(input SummaryInput) string {
 		sb.WriteString(fmt.Sprintf("SERVICE: %s\n", c.Alert.Service))
 		sb.WriteString(fmt.Sprintf("ALERT: %s\n", c.Alert.AlertName))
 		sb.WriteString(fmt.Sprintf("SEVERITY: %s\n", c.Alert.Severity))
+		if c.Alert.State == "pending" {
+			sb.WriteString("STATE: pending (the alert condition holds but has not fired yet)\n")
+		}
 		sb.WriteString(fmt.Sprintf("ALERT_DURATION: %v\n", c.Alert.LastSeen.Sub(c.Alert.FirstSeen)))
 		sb.WriteString(fmt.Sprintf("FIRST_SEEN: %s\n", c.Alert.FirstSeen.Format("2006-01-02 15:04:05 UTC")))
 		sb.WriteString("\n")
@@ -171,6 +196,9 @@ func buildContextPrompt(input SummaryInput) string {
 			sb.WriteString("LOG_SYMPTOMS:\n")
 			for _, s := range c.Symptoms {
 				sb.WriteString(fmt.Sprintf("  - Pattern: %s\n", s.Pattern))
+				if s.Severity != "" {
+					sb.WriteString(fmt.Sprintf("    Severity: %s\n", s.Severity))
+				}
 				sb.WriteString(fmt.Sprintf("    Occurrences: %d times\n", s.Count))
 				sb.WriteString(fmt.Sprintf("    Last_Seen: %s\n", s.LastSeen.Format("15:04:05")))
 			}
@@ -184,15 +212,39 @@ func buildContextPrompt(input SummaryInput) string {
 			sb.WriteString("METRICS_TRIGGERED:\n")
 			for _, m := range c.Metrics {
 				status := "CRITICAL"
-				if m.Check.Operator == ">" && m.Value > m.Check.Threshold {
+				switch m.Check.Operator {
+				case ">", ">=":
 					status = "THRESHOLD_EXCEEDED"
-				} else if m.Check.Operator == "<" && m.Value < m.Check.Threshold {
+				case "<", "<=":
 					status = "THRESHOLD_UNDERRUN"
+				case "absent":
+					status = "NO_DATA"
+				case "rate-of-change":
+					status = "RAPID_CHANGE"
+				case "anomaly":
+					status = "ANOMALY"
 				}
 				
 				sb.WriteString(fmt.Sprintf("  - Metric: %s\n", m.Check.Name))
-				sb.WriteString(fmt.Sprintf("    Current_Value: %.3f\n", m.Value))
-				sb.WriteString(fmt.Sprintf("    Threshold: %s %.3f\n", m.Check.Operator, m.Check.Threshold))
+				switch m.Check.Operator {
+				case "absent":
+					sb.WriteString("    Current_Value: none (the query returned no data, e.g. a dead exporter)\n")
+				case "rate-of-change":
+					sb.WriteString(fmt.Sprintf("    Change: %+.1f%%\n", m.Value))
+					sb.WriteString(fmt.Sprintf("    Threshold: more than %.1f%% change\n", m.Check.Threshold))
+				case "anomaly":
+					sb.WriteString(fmt.Sprintf("    Current_Value: %.3f\n", m.Value))
+					if b := m.Baseline; b != nil {
+						sb.WriteString(fmt.Sprintf("    Normal: %.3f ± %.3f (%s baseline, %+.1f standard deviations)\n",
+							b.Mean, b.StdDev, baselineMode(m.Check.Baseline.Mode), b.ZScore))
+					}
+				default:
+					sb.WriteString(fmt.Sprintf("    Current_Value: %.3f\n", m.Value))
+					sb.WriteString(fmt.Sprintf("    Threshold: %s %.3f\n", m.Check.Operator, m.Check.Threshold))
+				}
+				if offender := m.Offender(); offender != "" {
+					sb.WriteString(fmt.Sprintf("    Worst_Series: %s\n", offender))
+				}
 				sb.WriteString(fmt.Sprintf("    Status: %s\n", status))
 				sb.WriteString(fmt.Sprintf("    Weight: %d\n", m.Check.Weight))
 			}
@@ -201,6 +253,118 @@ func buildContextPrompt(input SummaryInput) string {
 			sb.WriteString("METRICS_TRIGGERED: No metric thresholds violated\n\n")
 		}
 
+		// Context metrics give baseline numbers, not violations
+		if len(c.Context) > 0 {
+			sb.WriteString("CONTEXT_METRICS:\n")
+			for _, cv := range c.Context {
+				sb.WriteString("  - " + cv.String())
+				if cv.Query.Description != "" {
+					sb.WriteString(" (" + cv.Query.Description + ")")
+				}
+				sb.WriteString("\n")
+			}
+			sb.WriteString("\n")
+		}
+
+		// Error budget burn tells how much the incident costs in SLO terms
+		if len(c.SLOs) > 0 {
+			sb.WriteString("SLO_BURN:\n")
+			for _, s := range c.SLOs {
+				sb.WriteString("  - " + s.String() + "\n")
+			}
+			sb.WriteString("A burn rate of 1 spends exactly the error budget over its period; weigh the risk by how fast it burns.\n\n")
+		}
+
+		// Traces show which span, and which dependency behind it, the failures come from
+		if t := c.Traces; t != nil {
+			sb.WriteString("TRACES:\n")
+			if t.FailingSpan != nil {
+				sb.WriteString(fmt.Sprintf("  - Error_Traces: %d\n", t.ErrorTraces))
+				sb.WriteString(fmt.Sprintf("  - Failing_Span: %s\n", t.FailingSpan))
+			}
+			if t.SlowSpan != nil {
+				sb.WriteString(fmt.Sprintf("  - Slow_Traces: %d\n", t.SlowTraces))
+				sb.WriteString(fmt.Sprintf("  - Slowest_Span: %s\n", t.SlowSpan))
+			}
+			if downstream := t.Downstream(); downstream != "" {
+				sb.WriteString(fmt.Sprintf("  - Downstream: %s\n", downstream))
+			}
+			if len(t.TraceIDs) > 0 {
+				sb.WriteString(fmt.Sprintf("  - Trace_IDs: %s\n", strings.Join(t.TraceIDs, ", ")))
+			}
+			sb.WriteString("The failing span is the deepest one in error, where the failure starts; name the downstream it calls when it is the cause.\n\n")
+		}
+
+		// Mesh error rates tell whether the service fails by itself or because of a downstream
+		if t := c.Topology; t != nil && !t.Empty() {
+			sb.WriteString("MESH_TOPOLOGY:\n")
+			sb.WriteString(fmt.Sprintf("  - Role: %s\n", t))
+			sb.WriteString(fmt.Sprintf("  - Inbound: %.1f req/s, %.1f%% errors\n", t.InboundRequestRate, t.InboundErrorRate*100))
+			sb.WriteString(fmt.Sprintf("  - Outbound: %.1f req/s, %.1f%% errors\n", t.OutboundRequestRate, t.OutboundErrorRate*100))
+			if len(t.ErrorCallers) > 0 {
+				sb.WriteString(fmt.Sprintf("  - Callers_Seeing_Errors: %s\n", strings.Join(t.ErrorCallers, ", ")))
+			}
+			switch t.Role {
+			case topology.RoleVictim:
+				sb.WriteString("The service's calls to a downstream fail: treat it as a victim and look for the root cause in that downstream.\n\n")
+			case topology.RoleOrigin:
+				sb.WriteString("The service fails its callers while its own calls succeed: the root cause is most likely in the service itself.\n\n")
+			default:
+				sb.WriteString("\n")
+			}
+		}
+
+		// Runbooks the operators maintain for this situation
+		if len(c.Runbooks) > 0 {
+			sb.WriteString("AVAILABLE_RUNBOOKS:\n")
+			for _, r := range c.Runbooks {
+				sb.WriteString(fmt.Sprintf("  - %s\n", r.Name))
+			}
+			sb.WriteString("Reference the relevant runbook by name in immediate_actions where it applies.\n\n")
+		}
+
+		// Dependencies alerting together usually share one cause upstream
+		if deps := c.Dependencies; !deps.Empty() {
+			sb.WriteString("DEPENDENCIES:\n")
+			if len(deps.DependsOn) > 0 {
+				sb.WriteString(fmt.Sprintf("  - Depends_On: %s\n", strings.Join(deps.DependsOn, ", ")))
+			}
+			if len(deps.ConsumedBy) > 0 {
+				sb.WriteString(fmt.Sprintf("  - Consumed_By: %s\n", strings.Join(deps.ConsumedBy, ", ")))
+			}
+			if len(deps.FiringUpstream) > 0 {
+				sb.WriteString(fmt.Sprintf("  - Alerting_Upstream: %s\n", strings.Join(deps.FiringUpstream, ", ")))
+			}
+			if len(deps.FiringDownstream) > 0 {
+				sb.WriteString(fmt.Sprintf("  - Alerting_Downstream: %s\n", strings.Join(deps.FiringDownstream, ", ")))
+			}
+			if len(deps.BlastRadius) > 0 {
+				sb.WriteString(fmt.Sprintf("  - Blast_Radius: %s\n", strings.Join(deps.BlastRadius, ", ")))
+			}
+			switch deps.LikelyCulprit {
+			case "":
+			case c.Alert.Service:
+				sb.WriteString("  - Likely_Culprit: this service; the other alerting services depend on it\n")
+			default:
+				sb.WriteString(fmt.Sprintf("  - Likely_Culprit: %s (upstream, alerting too)\n", deps.LikelyCulprit))
+				sb.WriteString("Consider whether this service only suffers from the upstream failure before blaming it.\n")
+			}
+			sb.WriteString("\n")
+		}
+
+		// Corrections operators made to earlier analyses of this alert
+		if corrections := input.Corrections[feedback.Fingerprint(c.Alert.Service, c.Alert.AlertName)]; len(corrections) > 0 {
+			sb.WriteString("OPERATOR_CORRECTIONS:\n")
+			for _, fb := range corrections {
+				sb.WriteString(fmt.Sprintf("  - %s: ", fb.Timestamp.Format("2006-01-02")))
+				if fb.RootCause != "" {
+					sb.WriteString(fmt.Sprintf("Previous analysis %q was wrong. ", fb.RootCause))
+				}
+				sb.WriteString(fb.Notes + "\n")
+			}
+			sb.WriteString("These corrections come from operators who investigated this alert; weigh them above your own assumptions.\n\n")
+		}
+
 		// Technical Context
 		sb.WriteString("TECHNICAL_CONTEXT:\n")
 		if strings.Contains(c.Alert.Service, "istio") || strings.Contains(c.Alert.AlertName, "Istio") {
@@ -216,6 +380,26 @@ func buildContextPrompt(input SummaryInput) string {
 		sb.WriteString("  - Monitoring_System: Prometheus + Elasticsearch logs\n")
 		sb.WriteString("  - Alert_Correlation: Real-time multi-source analysis\n")
 	}
+
+	// Similar past incidents and how they were resolved
+	if len(input.SimilarIncidents) > 0 {
+		sb.WriteString("\nSIMILAR_PAST_INCIDENTS:\n")
+		for _, m := range input.SimilarIncidents {
+			inc := m.Incident
+			sb.WriteString(fmt.Sprintf("  - Incident: %s (%s, service %s, alert %s)\n",
+				inc.ID, inc.FirstSeen.Format("2006-01-02"), inc.Service, inc.AlertName))
+			sb.WriteString(fmt.Sprintf("    Similarity: %.2f\n", m.Score))
+			sb.WriteString(fmt.Sprintf("    Risk: %s\n", inc.Risk))
+			sb.WriteString(fmt.Sprintf("    Root_Cause: %s\n", inc.RootCause))
+			if len(inc.ImmediateActions) > 0 {
+				sb.WriteString(fmt.Sprintf("    Resolution: %s\n", strings.Join(inc.ImmediateActions, "; ")))
+			}
+			if inc.Prevention != "" {
+				sb.WriteString(fmt.Sprintf("    Prevention: %s\n", inc.Prevention))
+			}
+		}
+		sb.WriteString("If the current incident matches one of these, reference its incident ID in the root cause.\n")
+	}
 	
 	sb.WriteString("\n=== END INCIDENT DATA ===\n")
 	sb.WriteString("Provide your technical analysis in the specified JSON format.")
@@ -271,34 +455,229 @@ func formatOutput(result RootCauseSummary) string {
 	return fmt.Sprintf("RISK: %s\nSUMMARY: %s", result.Risk, result.Summary)
 }
 
-func SummarizeMany(correlations []AlertCorrelation) (map[string]RootCauseSummary, error) {
+func SummarizeMany(ctx context.Context, correlations []AlertCorrelation) (map[string]RootCauseSummary, error) {
 	results := make(map[string]RootCauseSummary)
 
-	// Group all correlations by service
+	// Group all correlations by service; services failing the same way share one analysis
 	grouped := make(map[string][]AlertCorrelation)
+	members := make(map[string][]string)
 	for _, c := range correlations {
-		grouped[c.Alert.Service] = append(grouped[c.Alert.Service], c)
+		key, services := c.Alert.Service, []string{c.Alert.Service}
+		if !c.CrossService.Empty() {
+			key, services = c.CrossService.Key(), c.CrossService.Services
+		}
+		grouped[key] = append(grouped[key], c)
+		members[key] = services
 	}
 
-	// Summarize each group individually
+	// One prompt version per cycle so A/B arms compare whole cycles
+	promptVersion := selectPromptVersion()
+
+	type serviceResult struct {
+		service string
+		summary RootCauseSummary
+		err     error
+	}
+
+	// Summarize each group individually, at most llmParallelism calls in flight
+	sem := make(chan struct{}, llmParallelism())
+	resultsCh := make(chan serviceResult, len(grouped))
+	var wg sync.WaitGroup
+
 	for service, group := range grouped {
-		input := SummaryInput{Correlations: group}
-		summary, err := Summarize(input)
-		if err != nil {
-			results[service] = RootCauseSummary{
+		wg.Add(1)
+		go func(service string, group []AlertCorrelation) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				resultsCh <- serviceResult{service: service, err: ctx.Err()}
+				return
+			}
+
+			input := SummaryInput{Correlations: group, PromptVersion: promptVersion}
+			summary, err := Summarize(ctx, input)
+			resultsCh <- serviceResult{service: service, summary: summary, err: err}
+		}(service, group)
+	}
+
+	wg.Wait()
+	close(resultsCh)
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	for r := range resultsCh {
+		summary := r.summary
+		if r.err != nil {
+			summary = RootCauseSummary{
 				Risk:    "Unknown",
 				Summary: "LLM error or insufficient data",
 			}
-			continue
 		}
-		results[service] = summary
+		for _, service := range members[r.service] {
+			results[service] = summary
+		}
 	}
 
 	return results, nil
 }
 
+// llmParallelism returns the maximum concurrent LLM calls from LLM_PARALLELISM (default 4)
+func llmParallelism() int {
+	if v, err := strconv.Atoi(os.Getenv("LLM_PARALLELISM")); err == nil && v > 0 {
+		return v
+	}
+	return 4
+}
+
+// GenerateText runs a free-form completion (reports, digests) through the configured provider
+func GenerateText(parent context.Context, systemPrompt, userPrompt string, maxTokens int) (string, error) {
+	provider, err := newProvider()
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(parent, llmTimeout())
+	defer cancel()
+
+	model := openAIModel()
+	if provider.Name() == "mock" {
+		model = "mock"
+	}
+
+	started := time.Now()
+	raw, err := provider.Complete(ctx, CompletionRequest{
+		Model:        model,
+		SystemPrompt: systemPrompt,
+		UserPrompt:   userPrompt,
+		Temperature:  0.2,
+		MaxTokens:    maxTokens,
+	})
+	recordAudit(SummaryInput{}, provider.Name(), model, systemPrompt, userPrompt, raw, time.Since(started), err)
+	if err != nil {
+		return "", fmt.Errorf("%s completion failed: %w", provider.Name(), err)
+	}
+	return raw, nil
+}
+
+// llmTimeout returns the per-call deadline from LLM_TIMEOUT_SECONDS (default 30s)
+func llmTimeout() time.Duration {
+	if v, err := strconv.Atoi(os.Getenv("LLM_TIMEOUT_SECONDS")); err == nil && v > 0 {
+		return time.Duration(v) * time.Second
+	}
+	return 30 * time.Second
+}
+
+// recordAudit writes one LLM exchange to the audit log if auditing is enabled
+func recordAudit(input SummaryInput, provider, model, systemPrompt, userPrompt, response string, latency time.Duration, callErr error) {
+	if auditLog == nil {
+		return
+	}
+
+	var services []string
+	seen := make(map[string]bool)
+	for _, c := range input.Correlations {
+		if !seen[c.Alert.Service] {
+			seen[c.Alert.Service] = true
+			services = append(services, c.Alert.Service)
+		}
+	}
+
+	entry := audit.Entry{
+		Provider:      provider,
+		Model:         model,
+		PromptVersion: input.PromptVersion,
+		Services:      services,
+		SystemPrompt:  systemPrompt,
+		UserPrompt:    userPrompt,
+		Response:      response,
+		LatencyMs:     latency.Milliseconds(),
+	}
+	if callErr != nil {
+		entry.Error = callErr.Error()
+	}
+
+	if err := auditLog.Record(entry); err != nil {
+		fmt.Printf("[AUDIT] Failed to record LLM exchange: %v\n", err)
+	}
+}
+
+// findSimilarIncidents retrieves past incidents resembling the correlations being analyzed
+func findSimilarIncidents(ctx context.Context, input SummaryInput) []history.Match {
+	if incidentHistory == nil || historyTopK <= 0 || len(input.Correlations) == 0 {
+		return nil
+	}
+
+	// Never match an incident against its own earlier analyses
+	exclude := make(map[string]bool)
+	var queries []string
+	for _, c := range input.Correlations {
+		exclude[c.Alert.IncidentID] = true
+		queries = append(queries, history.QueryText(c.Alert.Service, c.Alert.AlertName, c.Alert.Severity,
+			symptomPatterns(c.Symptoms), metricNames(c.Metrics)))
+	}
+
+	matches, err := incidentHistory.Similar(ctx, strings.Join(queries, " "), historyTopK, historyMinScore, exclude)
+	if err != nil {
+		fmt.Printf("[LLM] Similar incident lookup failed: %v\n", err)
+		return nil
+	}
+	if len(matches) > 0 {
+		fmt.Printf("[LLM] Including %d similar past incidents in prompt\n", len(matches))
+	}
+	return matches
+}
+
+// findCorrections collects operator corrections for the alerts being analyzed
+func findCorrections(input SummaryInput) map[string][]feedback.Entry {
+	if feedbackStore == nil {
+		return nil
+	}
+
+	corrections := make(map[string][]feedback.Entry)
+	for _, c := range input.Correlations {
+		fp := feedback.Fingerprint(c.Alert.Service, c.Alert.AlertName)
+		if entries := feedbackStore.Corrections(fp, maxCorrections); len(entries) > 0 {
+			corrections[fp] = entries
+			fmt.Printf("[LLM] Including %d operator corrections for %s/%s\n", len(entries), c.Alert.Service, c.Alert.AlertName)
+		}
+	}
+	return corrections
+}
+
+// symptomPatterns returns the pattern names of the given symptoms
+func symptomPatterns(symptoms []logs.SymptomMatch) []string {
+	var patterns []string
+	for _, s := range symptoms {
+		patterns = append(patterns, s.Pattern)
+	}
+	return patterns
+}
+
+// baselineMode describes an anomaly check's baseline for the prompt
+func baselineMode(mode string) string {
+	if mode == "seasonal" {
+		return "same time in previous weeks"
+	}
+	return "rolling"
+}
+
+// metricNames returns the check names of the given metric results
+func metricNames(metrics []prometheus.MetricResult) []string {
+	var names []string
+	for _, m := range metrics {
+		names = append(names, m.Check.Name)
+	}
+	return names
+}
+
 func createFallbackSummary(reason string) RootCauseSummary {
 	return RootCauseSummary{
+		Fallback:   true,
 		Risk:       "Medium",
 		Confidence: 0.3,
 		RootCause:  fmt.Sprintf("Unable to perform AI analysis (%s). Manual investigation required.", reason),
@@ -0,0 +1,82 @@
+package topology
+
+import (
+	"fmt"
+	"time"
+
+	"vigilant/pkg/prometheus"
+)
+
+// IstioSource builds the service graph from the Istio standard metrics in Prometheus
+// (istio_requests_total, as reported by the calling side)
+type IstioSource struct {
+	metrics prometheus.MetricSource
+}
+
+// NewIstioSource returns a source querying the Prometheus that scrapes the mesh
+func NewIstioSource(metrics prometheus.MetricSource) *IstioSource {
+	return &IstioSource{metrics: metrics}
+}
+
+// Name identifies the source by its Prometheus
+func (s *IstioSource) Name() string {
+	return "istio:" + s.metrics.Name()
+}
+
+// edgeLabels name the canonical services of a request
+const edgeLabels = "source_canonical_service, destination_canonical_service"
+
+// Fetch returns the request and 5xx rates between services over the last window
+func (s *IstioSource) Fetch(window time.Duration) (Graph, error) {
+	seconds := int(window.Seconds())
+	requests, err := s.metrics.Query(fmt.Sprintf(`sum by (%s) (rate(istio_requests_total{reporter="source"}[%ds]))`, edgeLabels, seconds))
+	if err != nil {
+		return Graph{}, fmt.Errorf("failed to query istio requests: %w", err)
+	}
+	errors, err := s.metrics.Query(fmt.Sprintf(`sum by (%s) (rate(istio_requests_total{reporter="source",response_code=~"5.."}[%ds]))`, edgeLabels, seconds))
+	if err != nil {
+		return Graph{}, fmt.Errorf("failed to query istio errors: %w", err)
+	}
+
+	errorRates := make(map[[2]string]float64, len(errors))
+	for _, series := range errors {
+		if v, ok := lastValue(series); ok {
+			errorRates[edgeKey(series)] = v
+		}
+	}
+
+	var graph Graph
+	for _, series := range requests {
+		rate, ok := lastValue(series)
+		if !ok || rate == 0 {
+			continue
+		}
+		key := edgeKey(series)
+		graph.Edges = append(graph.Edges, Edge{
+			Source:      meshName(key[0]),
+			Target:      meshName(key[1]),
+			RequestRate: rate,
+			ErrorRate:   min(1, errorRates[key]/rate),
+		})
+	}
+	return graph, nil
+}
+
+func edgeKey(series prometheus.Series) [2]string {
+	return [2]string{series.Labels["source_canonical_service"], series.Labels["destination_canonical_service"]}
+}
+
+// meshName drops the "unknown" Istio reports for callers outside the mesh
+func meshName(name string) string {
+	if name == "unknown" {
+		return ""
+	}
+	return name
+}
+
+func lastValue(series prometheus.Series) (float64, bool) {
+	if len(series.Values) == 0 {
+		return 0, false
+	}
+	return series.Values[len(series.Values)-1], true
+}
@@ -0,0 +1,137 @@
+package topology
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KialiClient fetches the app graph of the mesh from the Kiali API
+// (/api/namespaces/graph)
+type KialiClient struct {
+	url        string
+	token      string
+	namespaces []string
+	httpClient *http.Client
+}
+
+// NewKialiClient returns a client for the Kiali at baseURL, including its web root
+// (e.g. http://kiali:20001/kiali), graphing the given namespaces
+func NewKialiClient(baseURL, token string, namespaces []string) (*KialiClient, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("kiali needs a URL")
+	}
+	if len(namespaces) == 0 {
+		return nil, fmt.Errorf("kiali needs the namespaces to graph")
+	}
+	return &KialiClient{
+		url:        strings.TrimRight(baseURL, "/"),
+		token:      token,
+		namespaces: namespaces,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}, nil
+}
+
+// Name identifies the client by its URL
+func (c *KialiClient) Name() string {
+	return "kiali:" + c.url
+}
+
+type kialiGraph struct {
+	Elements struct {
+		Nodes []struct {
+			Data struct {
+				ID       string `json:"id"`
+				NodeType string `json:"nodeType"`
+				App      string `json:"app"`
+				Workload string `json:"workload"`
+				Service  string `json:"service"`
+			} `json:"data"`
+		} `json:"nodes"`
+		Edges []struct {
+			Data struct {
+				Source  string `json:"source"`
+				Target  string `json:"target"`
+				Traffic struct {
+					Protocol string            `json:"protocol"`
+					Rates    map[string]string `json:"rates"` // e.g. "http": "12.5", "httpPercentErr": "20.0"
+				} `json:"traffic"`
+			} `json:"data"`
+		} `json:"edges"`
+	} `json:"elements"`
+}
+
+// Fetch returns the request and error rates between apps over the last window
+func (c *KialiClient) Fetch(window time.Duration) (Graph, error) {
+	params := url.Values{}
+	params.Set("namespaces", strings.Join(c.namespaces, ","))
+	params.Set("graphType", "app")
+	params.Set("duration", fmt.Sprintf("%ds", int(window.Seconds())))
+
+	req, err := http.NewRequest(http.MethodGet, c.url+"/api/namespaces/graph?"+params.Encode(), nil)
+	if err != nil {
+		return Graph{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return Graph{}, fmt.Errorf("kiali query failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Graph{}, fmt.Errorf("failed to read kiali response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Graph{}, fmt.Errorf("kiali query failed (HTTP %d): %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	var result kialiGraph
+	if err := json.Unmarshal(data, &result); err != nil {
+		return Graph{}, fmt.Errorf("failed to parse kiali response: %w", err)
+	}
+
+	// Unknown sources (traffic from outside the mesh) have no name
+	names := make(map[string]string, len(result.Elements.Nodes))
+	for _, node := range result.Elements.Nodes {
+		d := node.Data
+		if d.NodeType == "unknown" {
+			continue
+		}
+		for _, name := range []string{d.App, d.Workload, d.Service} {
+			if name != "" && name != "unknown" {
+				names[d.ID] = name
+				break
+			}
+		}
+	}
+
+	var graph Graph
+	for _, edge := range result.Elements.Edges {
+		d := edge.Data
+		protocol := d.Traffic.Protocol
+		if protocol != "http" && protocol != "grpc" {
+			continue // TCP traffic has no error rate
+		}
+		rate, _ := strconv.ParseFloat(d.Traffic.Rates[protocol], 64)
+		percentErr, _ := strconv.ParseFloat(d.Traffic.Rates[protocol+"PercentErr"], 64)
+		if rate == 0 {
+			continue
+		}
+		graph.Edges = append(graph.Edges, Edge{
+			Source:      names[d.Source],
+			Target:      names[d.Target],
+			RequestRate: rate,
+			ErrorRate:   percentErr / 100,
+		})
+	}
+	return graph, nil
+}
@@ -0,0 +1,154 @@
+package topology
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"vigilant/pkg/prometheus"
+)
+
+// Backends lists the supported service graph sources
+var Backends = []string{"kiali", "istio"}
+
+// Roles of an alerting service in the failure, as the mesh traffic shows it
+const (
+	RoleOrigin = "origin" // Returns errors to its callers while its own calls succeed
+	RoleVictim = "victim" // Its calls to a downstream fail
+)
+
+// Edge is the traffic from one service to another over the graph's window
+type Edge struct {
+	Source      string
+	Target      string
+	RequestRate float64 // Requests per second
+	ErrorRate   float64 // Share of the requests that failed, 0 to 1
+}
+
+// Graph is the service graph of the mesh
+type Graph struct {
+	Edges []Edge
+}
+
+// Source fetches the service graph
+type Source interface {
+	Name() string
+	Fetch(window time.Duration) (Graph, error)
+}
+
+// Position is where an alerting service sits in the mesh traffic
+type Position struct {
+	Role                string   // RoleOrigin, RoleVictim, or empty when the mesh shows no errors around it
+	InboundRequestRate  float64  // Requests per second the service receives
+	InboundErrorRate    float64  // Share of the requests to the service that failed
+	OutboundRequestRate float64  // Requests per second the service sends
+	OutboundErrorRate   float64  // Share of the service's own calls that failed
+	FailingDownstream   string   // Downstream whose calls fail the most; set for victims
+	DownstreamErrorRate float64  // Share of the calls to FailingDownstream that failed
+	ErrorCallers        []string // Callers seeing errors from the service, sorted
+}
+
+// Empty reports whether the service has no traffic in the graph
+func (p Position) Empty() bool {
+	return p.InboundRequestRate == 0 && p.OutboundRequestRate == 0
+}
+
+// String describes the position, e.g. "victim of payments (42% of calls failing)"
+func (p Position) String() string {
+	switch p.Role {
+	case RoleVictim:
+		return fmt.Sprintf("victim of %s (%.0f%% of calls failing)", p.FailingDownstream, p.DownstreamErrorRate*100)
+	case RoleOrigin:
+		return fmt.Sprintf("origin (%.0f%% of its requests failing, no failing downstream)", p.InboundErrorRate*100)
+	default:
+		return "no errors in the mesh"
+	}
+}
+
+// Classify places service in the graph. The service is a victim when the calls to one of its
+// downstreams fail at least threshold of the time, as errors propagate up from the deepest
+// failing service; otherwise it is the origin when the requests it receives fail at least
+// threshold of the time.
+func Classify(graph Graph, service string, threshold float64) Position {
+	var pos Position
+	var inRequests, inErrors, outRequests, outErrors float64
+	for _, edge := range graph.Edges {
+		if edge.Source == edge.Target {
+			continue
+		}
+		switch service {
+		case edge.Target:
+			inRequests += edge.RequestRate
+			inErrors += edge.RequestRate * edge.ErrorRate
+			if edge.ErrorRate >= threshold && edge.Source != "" {
+				pos.ErrorCallers = append(pos.ErrorCallers, edge.Source)
+			}
+		case edge.Source:
+			outRequests += edge.RequestRate
+			outErrors += edge.RequestRate * edge.ErrorRate
+			if edge.ErrorRate >= threshold && edge.ErrorRate > pos.DownstreamErrorRate {
+				pos.FailingDownstream, pos.DownstreamErrorRate = edge.Target, edge.ErrorRate
+			}
+		}
+	}
+	sort.Strings(pos.ErrorCallers)
+
+	pos.InboundRequestRate = inRequests
+	if inRequests > 0 {
+		pos.InboundErrorRate = inErrors / inRequests
+	}
+	pos.OutboundRequestRate = outRequests
+	if outRequests > 0 {
+		pos.OutboundErrorRate = outErrors / outRequests
+	}
+	switch {
+	case pos.FailingDownstream != "":
+		pos.Role = RoleVictim
+	case inRequests > 0 && pos.InboundErrorRate >= threshold:
+		pos.Role = RoleOrigin
+	}
+	return pos
+}
+
+// Rename maps the services of the graph to other names, e.g. the mesh's app names to the
+// service profiles; services without a new name keep theirs
+func (g Graph) Rename(names map[string]string) Graph {
+	if len(names) == 0 {
+		return g
+	}
+	renamed := Graph{Edges: make([]Edge, len(g.Edges))}
+	for i, edge := range g.Edges {
+		if name, ok := names[edge.Source]; ok {
+			edge.Source = name
+		}
+		if name, ok := names[edge.Target]; ok {
+			edge.Target = name
+		}
+		renamed.Edges[i] = edge
+	}
+	return renamed
+}
+
+// NewSource returns the service graph source of a backend (see Backends)
+func NewSource(backend string, cfg Config) (Source, error) {
+	switch backend {
+	case "kiali":
+		return NewKialiClient(cfg.URL, cfg.Token, cfg.Namespaces)
+	case "istio":
+		if cfg.Metrics == nil {
+			return nil, fmt.Errorf("istio needs a Prometheus scraping the mesh telemetry")
+		}
+		return NewIstioSource(cfg.Metrics), nil
+	default:
+		return nil, fmt.Errorf("unknown topology backend %q (expected one of %v)", backend, Backends)
+	}
+}
+
+// Config holds what the backends need: the Kiali URL, token and namespaces, or the Prometheus
+// scraping the Istio telemetry
+type Config struct {
+	URL        string
+	Token      string
+	Namespaces []string
+	Metrics    prometheus.MetricSource
+}
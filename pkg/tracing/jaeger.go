@@ -0,0 +1,151 @@
+package tracing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JaegerClient finds traces through the Jaeger query service HTTP API (/api/traces)
+type JaegerClient struct {
+	url        string
+	headers    map[string]string
+	httpClient *http.Client
+}
+
+// NewJaegerClient returns a client for the Jaeger query service at baseURL
+func NewJaegerClient(baseURL string, headers map[string]string) (*JaegerClient, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("jaeger needs a URL")
+	}
+	return &JaegerClient{
+		url:        strings.TrimRight(baseURL, "/"),
+		headers:    headers,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}, nil
+}
+
+// Name identifies the client by its URL
+func (c *JaegerClient) Name() string {
+	return "jaeger:" + c.url
+}
+
+type jaegerTag struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+type jaegerResponse struct {
+	Data []struct {
+		TraceID string `json:"traceID"`
+		Spans   []struct {
+			SpanID        string `json:"spanID"`
+			OperationName string `json:"operationName"`
+			References    []struct {
+				RefType string `json:"refType"`
+				SpanID  string `json:"spanID"`
+			} `json:"references"`
+			Duration  int64       `json:"duration"` // Microseconds
+			Tags      []jaegerTag `json:"tags"`
+			ProcessID string      `json:"processID"`
+		} `json:"spans"`
+		Processes map[string]struct {
+			ServiceName string `json:"serviceName"`
+		} `json:"processes"`
+	} `json:"data"`
+	Errors []struct {
+		Msg string `json:"msg"`
+	} `json:"errors"`
+}
+
+// FindTraces returns the service's traces with an error tag, or at least q.MinDuration long
+func (c *JaegerClient) FindTraces(q Query) ([]Trace, error) {
+	end := time.Now()
+	params := url.Values{}
+	params.Set("service", q.Service)
+	params.Set("start", strconv.FormatInt(end.Add(-q.Lookback).UnixMicro(), 10))
+	params.Set("end", strconv.FormatInt(end.UnixMicro(), 10))
+	params.Set("limit", strconv.Itoa(q.Limit))
+	if q.MinDuration > 0 {
+		params.Set("minDuration", q.MinDuration.String())
+	} else {
+		params.Set("tags", `{"error":"true"}`)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, c.url+"/api/traces?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("jaeger query failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jaeger response: %w", err)
+	}
+	var result jaegerResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("jaeger query failed (HTTP %d): %s", resp.StatusCode, strings.TrimSpace(string(data)))
+		}
+		return nil, fmt.Errorf("failed to parse jaeger response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || len(result.Errors) > 0 {
+		msg := resp.Status
+		if len(result.Errors) > 0 {
+			msg = result.Errors[0].Msg
+		}
+		return nil, fmt.Errorf("jaeger query failed: %s", msg)
+	}
+
+	traces := make([]Trace, 0, len(result.Data))
+	for _, t := range result.Data {
+		trace := Trace{ID: t.TraceID}
+		for _, s := range t.Spans {
+			span := Span{
+				ID:        s.SpanID,
+				Service:   t.Processes[s.ProcessID].ServiceName,
+				Operation: s.OperationName,
+				Duration:  time.Duration(s.Duration) * time.Microsecond,
+			}
+			for _, ref := range s.References {
+				if ref.RefType == "CHILD_OF" || span.ParentID == "" {
+					span.ParentID = ref.SpanID
+				}
+			}
+			attributes := make(map[string]string, len(s.Tags))
+			for _, tag := range s.Tags {
+				attributes[tag.Key] = fmt.Sprint(tag.Value)
+			}
+			span.Error = attributes["error"] == "true" || attributes["otel.status_code"] == "ERROR"
+			span.Peer = peer(attributes)
+			trace.Spans = append(trace.Spans, span)
+		}
+		traces = append(traces, trace)
+	}
+	return traces, nil
+}
+
+// peerAttributes name what a span called, most specific first
+var peerAttributes = []string{"peer.service", "db.system", "messaging.system", "server.address", "net.peer.name", "http.host"}
+
+// peer returns what a span called from its attributes
+func peer(attributes map[string]string) string {
+	for _, key := range peerAttributes {
+		if v := attributes[key]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,186 @@
+package tracing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TempoClient finds traces with TraceQL through the Tempo HTTP API (/api/search), then
+// fetches each match (/api/traces/{id})
+type TempoClient struct {
+	url        string
+	headers    map[string]string
+	httpClient *http.Client
+}
+
+// NewTempoClient returns a client for the Tempo at baseURL
+func NewTempoClient(baseURL string, headers map[string]string) (*TempoClient, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("tempo needs a URL")
+	}
+	return &TempoClient{
+		url:        strings.TrimRight(baseURL, "/"),
+		headers:    headers,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}, nil
+}
+
+// Name identifies the client by its URL
+func (c *TempoClient) Name() string {
+	return "tempo:" + c.url
+}
+
+type tempoSearchResponse struct {
+	Traces []struct {
+		TraceID string `json:"traceID"`
+	} `json:"traces"`
+}
+
+type otlpAttribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue *string     `json:"stringValue"`
+		IntValue    interface{} `json:"intValue"`
+		BoolValue   *bool       `json:"boolValue"`
+	} `json:"value"`
+}
+
+type otlpSpan struct {
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId"`
+	Name              string          `json:"name"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes"`
+	Status            struct {
+		Code interface{} `json:"code"` // "STATUS_CODE_ERROR" or 2
+	} `json:"status"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []struct {
+		Spans []otlpSpan `json:"spans"`
+	} `json:"scopeSpans"`
+	InstrumentationLibrarySpans []struct { // Tempo before 2.0
+		Spans []otlpSpan `json:"spans"`
+	} `json:"instrumentationLibrarySpans"`
+}
+
+type tempoTrace struct {
+	Batches       []otlpResourceSpans `json:"batches"`
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+// FindTraces returns the service's traces with a span in error, or with a span at least
+// q.MinDuration long
+func (c *TempoClient) FindTraces(q Query) ([]Trace, error) {
+	condition := "status = error"
+	if q.MinDuration > 0 {
+		condition = fmt.Sprintf("duration >= %dms", q.MinDuration.Milliseconds())
+	}
+	end := time.Now()
+	params := url.Values{}
+	params.Set("q", fmt.Sprintf("{ resource.service.name = %s && %s }", strconv.Quote(q.Service), condition))
+	params.Set("start", strconv.FormatInt(end.Add(-q.Lookback).Unix(), 10))
+	params.Set("end", strconv.FormatInt(end.Unix(), 10))
+	params.Set("limit", strconv.Itoa(q.Limit))
+
+	var search tempoSearchResponse
+	if err := c.get("/api/search?"+params.Encode(), &search); err != nil {
+		return nil, err
+	}
+
+	traces := make([]Trace, 0, len(search.Traces))
+	for _, t := range search.Traces {
+		var result tempoTrace
+		if err := c.get("/api/traces/"+url.PathEscape(t.TraceID), &result); err != nil {
+			return nil, err
+		}
+		traces = append(traces, otlpTrace(t.TraceID, append(result.Batches, result.ResourceSpans...)))
+	}
+	return traces, nil
+}
+
+// get decodes the JSON response of a GET request into v
+func (c *TempoClient) get(path string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, c.url+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("tempo query failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read tempo response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("tempo query failed (HTTP %d): %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse tempo response: %w", err)
+	}
+	return nil
+}
+
+// otlpTrace converts the OTLP JSON spans of a trace
+func otlpTrace(id string, batches []otlpResourceSpans) Trace {
+	trace := Trace{ID: id}
+	for _, batch := range batches {
+		service := otlpAttributes(batch.Resource.Attributes)["service.name"]
+		var spans []otlpSpan
+		for _, scope := range batch.ScopeSpans {
+			spans = append(spans, scope.Spans...)
+		}
+		for _, scope := range batch.InstrumentationLibrarySpans {
+			spans = append(spans, scope.Spans...)
+		}
+		for _, s := range spans {
+			start, _ := strconv.ParseInt(s.StartTimeUnixNano, 10, 64)
+			end, _ := strconv.ParseInt(s.EndTimeUnixNano, 10, 64)
+			code := fmt.Sprint(s.Status.Code)
+			trace.Spans = append(trace.Spans, Span{
+				ID:        s.SpanID,
+				ParentID:  s.ParentSpanID,
+				Service:   service,
+				Operation: s.Name,
+				Duration:  time.Duration(end - start),
+				Error:     code == "STATUS_CODE_ERROR" || code == "2",
+				Peer:      peer(otlpAttributes(s.Attributes)),
+			})
+		}
+	}
+	return trace
+}
+
+// otlpAttributes flattens OTLP attributes to strings
+func otlpAttributes(attributes []otlpAttribute) map[string]string {
+	result := make(map[string]string, len(attributes))
+	for _, a := range attributes {
+		switch {
+		case a.Value.StringValue != nil:
+			result[a.Key] = *a.Value.StringValue
+		case a.Value.BoolValue != nil:
+			result[a.Key] = strconv.FormatBool(*a.Value.BoolValue)
+		case a.Value.IntValue != nil:
+			result[a.Key] = fmt.Sprint(a.Value.IntValue)
+		}
+	}
+	return result
+}
@@ -0,0 +1,248 @@
+package tracing
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Backends lists the supported tracing backends
+var Backends = []string{"jaeger", "tempo"}
+
+// DefaultAlertPattern matches the alerts whose analysis gets trace context: latency and error alerts
+var DefaultAlertPattern = regexp.MustCompile(`(?i)latency|slow|duration|timeout|error|5xx|fail|availability|apdex`)
+
+// Span is one operation of a trace
+type Span struct {
+	ID        string
+	ParentID  string
+	Service   string
+	Operation string
+	Duration  time.Duration
+	Error     bool
+	Peer      string // What the span called, from attributes such as peer.service or db.system
+}
+
+// Trace is a trace with its spans
+type Trace struct {
+	ID    string
+	Spans []Span
+}
+
+// Query selects the recent traces of a service
+type Query struct {
+	Service     string
+	Lookback    time.Duration
+	Limit       int
+	MinDuration time.Duration // Finds slow traces when set, error traces otherwise
+}
+
+// Source finds traces in a tracing backend
+type Source interface {
+	Name() string
+	FindTraces(q Query) ([]Trace, error)
+}
+
+// NewSource returns the client of a backend (see Backends); headers are sent with every request,
+// e.g. X-Scope-OrgID for a multi-tenant Tempo
+func NewSource(backend, baseURL string, headers map[string]string) (Source, error) {
+	switch backend {
+	case "jaeger":
+		return NewJaegerClient(baseURL, headers)
+	case "tempo":
+		return NewTempoClient(baseURL, headers)
+	default:
+		return nil, fmt.Errorf("unknown tracing backend %q (expected one of %v)", backend, Backends)
+	}
+}
+
+// SpanStat is a span that was to blame in several traces
+type SpanStat struct {
+	Service     string
+	Operation   string
+	Peer        string
+	Traces      int // Traces in which this span was to blame
+	AvgDuration time.Duration
+}
+
+// String describes the span, e.g. "checkout POST /pay calling postgresql in 9 traces, avg 2.1s"
+func (s SpanStat) String() string {
+	desc := s.Service + " " + s.Operation
+	if s.Peer != "" {
+		desc += " calling " + s.Peer
+	}
+	return fmt.Sprintf("%s in %d traces, avg %v", desc, s.Traces, s.AvgDuration.Round(time.Millisecond))
+}
+
+// Summary condenses the recent error and slow traces of a service
+type Summary struct {
+	Service     string
+	ErrorTraces int
+	SlowTraces  int
+	FailingSpan *SpanStat // Deepest failing span most common across the error traces
+	SlowSpan    *SpanStat // Span with the most self time most common across the slow traces
+	TraceIDs    []string  // A few of the traces, to look up in the tracing UI
+}
+
+// Empty reports whether no error or slow trace was found
+func (s Summary) Empty() bool {
+	return s.ErrorTraces == 0 && s.SlowTraces == 0
+}
+
+// Downstream returns the dependency the traces point at: the service of the dominant span when
+// it isn't the queried service, else what that span called
+func (s Summary) Downstream() string {
+	for _, span := range []*SpanStat{s.FailingSpan, s.SlowSpan} {
+		if span == nil {
+			continue
+		}
+		if span.Service != "" && span.Service != s.Service {
+			return span.Service
+		}
+		if span.Peer != "" {
+			return span.Peer
+		}
+	}
+	return ""
+}
+
+// Key identifies the spans to blame, e.g. for cache keys; counts and durations are left out
+func (s Summary) Key() string {
+	var parts []string
+	for _, span := range []*SpanStat{s.FailingSpan, s.SlowSpan} {
+		if span != nil {
+			parts = append(parts, span.Service+"/"+span.Operation+"/"+span.Peer)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// maxTraceIDs is how many trace IDs a summary keeps
+const maxTraceIDs = 3
+
+// Summarize queries the error traces of service, and its slow traces when minDuration is set,
+// and names the spans most often to blame
+func Summarize(source Source, service string, lookback time.Duration, limit int, minDuration time.Duration) (Summary, error) {
+	summary := Summary{Service: service}
+
+	errorTraces, err := source.FindTraces(Query{Service: service, Lookback: lookback, Limit: limit})
+	if err != nil {
+		return summary, fmt.Errorf("failed to find error traces: %w", err)
+	}
+	var failing []Span
+	for _, trace := range errorTraces {
+		if span, ok := deepestError(trace); ok {
+			failing = append(failing, span)
+			summary.addTraceID(trace.ID)
+		}
+	}
+	summary.ErrorTraces = len(failing)
+	summary.FailingSpan = dominant(failing)
+
+	if minDuration > 0 {
+		slowTraces, err := source.FindTraces(Query{Service: service, Lookback: lookback, Limit: limit, MinDuration: minDuration})
+		if err != nil {
+			return summary, fmt.Errorf("failed to find slow traces: %w", err)
+		}
+		var slow []Span
+		for _, trace := range slowTraces {
+			if span, ok := mostSelfTime(trace); ok {
+				slow = append(slow, span)
+				summary.addTraceID(trace.ID)
+			}
+		}
+		summary.SlowTraces = len(slow)
+		summary.SlowSpan = dominant(slow)
+	}
+	return summary, nil
+}
+
+func (s *Summary) addTraceID(id string) {
+	if len(s.TraceIDs) < maxTraceIDs {
+		s.TraceIDs = append(s.TraceIDs, id)
+	}
+}
+
+// deepestError returns the failing span farthest from the root: where the failure started
+// rather than the spans it propagated through
+func deepestError(trace Trace) (Span, bool) {
+	depths := depths(trace)
+	var found Span
+	best := -1
+	for _, span := range trace.Spans {
+		if span.Error && depths[span.ID] > best {
+			found, best = span, depths[span.ID]
+		}
+	}
+	return found, best >= 0
+}
+
+// mostSelfTime returns the span that spent the most time outside its children
+func mostSelfTime(trace Trace) (Span, bool) {
+	children := make(map[string]time.Duration)
+	for _, span := range trace.Spans {
+		children[span.ParentID] += span.Duration
+	}
+	var found Span
+	best := time.Duration(-1)
+	for _, span := range trace.Spans {
+		if self := max(0, span.Duration-children[span.ID]); self > best {
+			found, best = span, self
+		}
+	}
+	return found, best >= 0
+}
+
+// depths returns the distance of every span from its trace's root
+func depths(trace Trace) map[string]int {
+	parents := make(map[string]string, len(trace.Spans))
+	for _, span := range trace.Spans {
+		parents[span.ID] = span.ParentID
+	}
+	result := make(map[string]int, len(trace.Spans))
+	for _, span := range trace.Spans {
+		depth := 0
+		// Bounded walk in case of broken parent references
+		for id := parents[span.ID]; id != "" && depth < len(trace.Spans); id = parents[id] {
+			depth++
+		}
+		result[span.ID] = depth
+	}
+	return result
+}
+
+// dominant returns the service and operation most of the spans share, nil without spans
+func dominant(spans []Span) *SpanStat {
+	if len(spans) == 0 {
+		return nil
+	}
+	stats := make(map[string]*SpanStat)
+	total := make(map[string]time.Duration)
+	for _, span := range spans {
+		key := span.Service + "\x00" + span.Operation + "\x00" + span.Peer
+		stat, ok := stats[key]
+		if !ok {
+			stat = &SpanStat{Service: span.Service, Operation: span.Operation, Peer: span.Peer}
+			stats[key] = stat
+		}
+		stat.Traces++
+		total[key] += span.Duration
+	}
+
+	keys := make([]string, 0, len(stats))
+	for key := range stats {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var best string
+	for _, key := range keys {
+		if best == "" || stats[key].Traces > stats[best].Traces {
+			best = key
+		}
+	}
+	stat := stats[best]
+	stat.AvgDuration = total[best] / time.Duration(stat.Traces)
+	return stat
+}
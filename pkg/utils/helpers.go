@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"vigilant/pkg/config"
 	"vigilant/pkg/logs"
 	"vigilant/pkg/prometheus"
 	"vigilant/pkg/api"
@@ -26,8 +27,10 @@ func ConvertSymptoms(symptoms []logs.SymptomMatch) []api.APISymptom {
 	var out []api.APISymptom
 	for _, s := range symptoms {
 		out = append(out, api.APISymptom{
-			Pattern: s.Pattern,
-			Count:   s.Count,
+			Pattern:  s.Pattern,
+			Severity: s.Severity,
+			Count:    s.Count,
+			Samples:  s.Samples,
 		})
 	}
 	return out
@@ -36,11 +39,53 @@ func ConvertSymptoms(symptoms []logs.SymptomMatch) []api.APISymptom {
 func ConvertMetrics(metrics []prometheus.MetricResult) []api.APIMetric {
 	var out []api.APIMetric
 	for _, m := range metrics {
+		var baseline *api.APIBaseline
+		if m.Baseline != nil {
+			baseline = &api.APIBaseline{Mean: m.Baseline.Mean, StdDev: m.Baseline.StdDev, ZScore: m.Baseline.ZScore}
+		}
 		out = append(out, api.APIMetric{
 			Name:      m.Check.Name,
 			Value:     m.Value,
 			Operator:  m.Check.Operator,
 			Threshold: m.Check.Threshold,
+			Weight:    m.Check.Weight,
+			Labels:    m.Labels,
+			Offenders: m.Offenders,
+			Series:    m.Series,
+			Baseline:  baseline,
+		})
+	}
+	return out
+}
+
+func ConvertSLOs(statuses []prometheus.SLOStatus) []api.APISLO {
+	var out []api.APISLO
+	for _, s := range statuses {
+		slo := api.APISLO{
+			Name:   s.SLO.Name,
+			Target: s.SLO.Target,
+			Window: prometheus.PromDuration(s.SLO.Period()),
+			Fast:   s.Fast,
+		}
+		for _, b := range s.BurnRates {
+			slo.BurnRates = append(slo.BurnRates, api.APIBurnRate{
+				Window:      prometheus.PromDuration(b.Window),
+				ErrorRatio:  b.ErrorRatio,
+				BurnRate:    b.Rate,
+				BudgetSpent: b.BudgetSpent,
+			})
+		}
+		out = append(out, slo)
+	}
+	return out
+}
+
+func ConvertRunbooks(runbooks []config.Runbook) []api.APIRunbook {
+	out := []api.APIRunbook{}
+	for _, r := range runbooks {
+		out = append(out, api.APIRunbook{
+			Name: r.Name,
+			URL:  r.URL,
 		})
 	}
 	return out